@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/util/netpol"
+)
+
+var netpolCmd = &cobra.Command{
+	Use:   "network-policy",
+	Short: "Print a service and network policy that restrict access to the kubedock api",
+	Run:   printNetworkPolicy,
+}
+
+func init() {
+	rootCmd.AddCommand(netpolCmd)
+
+	netpolCmd.Flags().String("name", "kubedock", "Name of the service and network policy")
+	netpolCmd.Flags().StringP("namespace", "n", getContextNamespace(), "Namespace in which kubedock is running")
+	netpolCmd.Flags().Int("port", 2475, "Port the kubedock api is listening on")
+	netpolCmd.Flags().String("selector", "app=kubedock", "Labels that select the kubedock pod")
+	netpolCmd.Flags().String("allowed-cidrs", "", "Comma separated list of cidrs of clients that are allowed to use the api (defaults to pods in the namespace)")
+}
+
+func printNetworkPolicy(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	name, _ := flags.GetString("name")
+	namespace, _ := flags.GetString("namespace")
+	port, _ := flags.GetInt("port")
+	sel, _ := flags.GetString("selector")
+	cidrs, _ := flags.GetString("allowed-cidrs")
+
+	selector, err := k8slabels.ConvertSelectorToLabelsMap(sel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid selector %s: %s\n", sel, err)
+		os.Exit(1)
+	}
+	nets, err := httputil.ParseCIDRs(cidrs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	dat, err := netpol.Generate(netpol.Request{
+		Name:      name,
+		Namespace: namespace,
+		Port:      port,
+		Selector:  selector,
+		CIDRs:     nets,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error generating network policy: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(dat))
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joyrex2001/kubedock/internal/util/recording"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <recording>",
+	Short: "Replay the requests of a recording (see server --record) and compare the responses",
+	Args:  cobra.ExactArgs(1),
+	Run:   replayRecording,
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().String("host", "http://localhost:2475", "Address of the api to replay to (url or unix:///path/to/socket)")
+	replayCmd.Flags().Duration("timeout", 30*time.Second, "Max duration of a single request (e.g. attach requests that wait for a container to exit)")
+}
+
+func replayRecording(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	host, _ := flags.GetString("host")
+	timeout, _ := flags.GetDuration("timeout")
+
+	exs, err := recording.Read(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading recording: %s\n", err)
+		os.Exit(1)
+	}
+	rp, err := recording.NewReplayer(host, timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, ex := range exs {
+		res := rp.Replay(ex)
+		switch {
+		case res.Err != nil:
+			fmt.Printf("FAIL %s %s: %s\n", ex.Method, ex.Path, res.Err)
+		case !res.Match():
+			fmt.Printf("DIFF %s %s: expected %d, got %d\n", ex.Method, ex.Path, ex.Status, res.Status)
+		default:
+			fmt.Printf("OK   %s %s: %d\n", ex.Method, ex.Path, res.Status)
+			continue
+		}
+		failed++
+	}
+	fmt.Printf("replayed %d requests, %d differences\n", len(exs), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
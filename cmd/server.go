@@ -14,6 +14,7 @@ import (
 
 	"github.com/joyrex2001/kubedock/internal"
 	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/events"
 )
 
 var cfgFile string
@@ -24,9 +25,18 @@ var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Start the kubedock api server",
 	Run: func(cmd *cobra.Command, args []string) {
+		if cfgFile != "" {
+			viper.SetConfigFile(cfgFile)
+			if err := viper.ReadInConfig(); err != nil {
+				klog.Fatalf("error reading config file: %s", err)
+			}
+		}
 		flag.Set("v", viper.GetString("verbosity"))
-		addDefaultAnnotations(annotations)
-		addDefaultLabels(labels)
+		if cfgFile != "" {
+			klog.Infof("using config file %s", viper.ConfigFileUsed())
+		}
+		addDefaultAnnotations(append(viper.GetStringSlice("annotation"), annotations...))
+		addDefaultLabels(append(viper.GetStringSlice("label"), labels...))
 		internal.Main()
 	},
 }
@@ -34,7 +44,10 @@ var serverCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(serverCmd)
 
+	serverCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file (yaml, toml or json) with server settings")
 	serverCmd.PersistentFlags().String("listen-addr", ":2475", "Webserver listen address")
+	serverCmd.PersistentFlags().String("backend", "kubernetes", "Backend that runs the containers (kubernetes,docker)")
+	serverCmd.PersistentFlags().String("docker-host", "unix:///var/run/docker.sock", "Docker (or podman) api that runs the containers when using the docker backend")
 	serverCmd.PersistentFlags().String("unix-socket", "", "Unix socket to listen to (instead of port)")
 	serverCmd.PersistentFlags().Bool("tls-enable", false, "Enable TLS on api server")
 	serverCmd.PersistentFlags().String("tls-key-file", "", "TLS keyfile")
@@ -43,30 +56,77 @@ func init() {
 	serverCmd.PersistentFlags().String("initimage", config.Image, "Image to use as initcontainer for volume setup")
 	serverCmd.PersistentFlags().String("dindimage", config.Image, "Image to use as sidecar container for docker-in-docker support")
 	serverCmd.PersistentFlags().Bool("disable-dind", false, "Disable docker-in-docker support")
-	serverCmd.PersistentFlags().String("pull-policy", "ifnotpresent", "Pull policy that should be applied (ifnotpresent,never,always)")
+	serverCmd.PersistentFlags().Bool("selinux-relabel", false, "Set the SELinux level of pods with volumes that have a z or Z option")
+	serverCmd.PersistentFlags().String("security-profile", "privileged", "Security profile to apply to pods (privileged,baseline,openshift-restricted)")
+	serverCmd.PersistentFlags().String("quota-policy", "reject", "Policy for containers that don't fit in the resource quotas of the namespace (reject,queue)")
+	serverCmd.PersistentFlags().Bool("disable-sidecar-injection", false, "Disable service mesh (istio, linkerd) sidecar injection in pods")
+	serverCmd.PersistentFlags().Bool("scoped-rbac", false, "Probe the permissions of the service account at startup, and disable features that are not permitted")
+	serverCmd.PersistentFlags().String("pull-policy", "ifnotpresent", "Pull policy that should be applied (ifnotpresent,never,always,auto)")
 	serverCmd.PersistentFlags().String("service-account", "default", "Service account that should be used for deployed pods")
 	serverCmd.PersistentFlags().String("image-pull-secrets", "", "Comma separated list of image pull secrets that should be used")
+	serverCmd.PersistentFlags().Duration("image-cache-ttl", 5*time.Minute, "Time an image reference is resolved to the same digest (0 = only deduplicate concurrent inspects)")
+	serverCmd.PersistentFlags().Bool("pin-digests", false, "Resolve image tags to a digest at first use, and deploy that digest for all subsequent containers")
+	serverCmd.PersistentFlags().String("image-rewrite", "", "Comma separated list of image rewrite rules (from=to, e.g. docker.io/library/*=mirror/library/*)")
+	serverCmd.PersistentFlags().String("readiness", "running", "Default condition for a container to be considered started (running,ready,tcp)")
+	serverCmd.PersistentFlags().String("prewarm-images", "", "Comma separated list of images that should be pre-pulled on all nodes")
 	serverCmd.PersistentFlags().String("pod-template", "", "Pod file that should be used as the base for creating pods")
 	serverCmd.PersistentFlags().String("pod-name-prefix", "kubedock", "The prefix of the name to be used in the created pods")
 	serverCmd.PersistentFlags().BoolP("inspector", "i", false, "Enable image inspect to fetch container port config from a registry")
+	serverCmd.PersistentFlags().String("buildkit-addr", "", "Address of a buildkit daemon that docker buildx builds are bridged to (e.g. tcp://buildkitd:1234)")
+	serverCmd.PersistentFlags().Bool("registry-proxy", false, "Serve a pull-through proxy for the registry v2 api at /v2/")
 	serverCmd.PersistentFlags().DurationP("timeout", "t", 1*time.Minute, "Container creating/deletion timeout")
+	serverCmd.PersistentFlags().Duration("exec-idle-timeout", 0, "Terminate exec and attach sessions without input or output for this time (0 = never)")
+	serverCmd.PersistentFlags().Duration("exec-max-duration", 0, "Terminate exec and attach sessions that run longer than this time (0 = never)")
 	serverCmd.PersistentFlags().DurationP("reapmax", "r", 60*time.Minute, "Reap all resources older than this time")
+	serverCmd.PersistentFlags().Duration("forward-idle-timeout", 0, "Close port-forwards and reverse proxies that are idle longer than this time (0 = never)")
+	serverCmd.PersistentFlags().Duration("retain-failed", 60*time.Minute, "Time to keep failed pods of containers labelled with retain-on-failure")
 	serverCmd.PersistentFlags().String("request-cpu", "", "Default k8s cpu resource request (optionally add ,limit)")
 	serverCmd.PersistentFlags().String("request-memory", "", "Default k8s memory resource request (optionally add ,limit)")
 	serverCmd.PersistentFlags().String("node-selector", "", "A node selector in the form of key1=value1[,key2=value2]")
 	serverCmd.PersistentFlags().Int64("active-deadline-seconds", -1, "Default value for pod deadline, in seconds (a negative value means no deadline)")
 	serverCmd.PersistentFlags().String("runas-user", "", "Numeric UID to run pods as (defaults to UID in image)")
+	serverCmd.PersistentFlags().String("fs-group", "", "Numeric GID that should own the volumes of pods (fsGroup)")
 	serverCmd.PersistentFlags().Bool("lock", false, "Lock namespace for this instance")
 	serverCmd.PersistentFlags().Duration("lock-timeout", 15*time.Minute, "Max time trying to acquire namespace lock")
 	serverCmd.PersistentFlags().StringP("verbosity", "v", "1", "Log verbosity level")
 	serverCmd.PersistentFlags().BoolP("prune-start", "P", false, "Prune all existing kubedock resources before starting")
 	serverCmd.PersistentFlags().Bool("port-forward", false, "Open port-forwards for all services")
 	serverCmd.PersistentFlags().Bool("reverse-proxy", false, "Reverse proxy all services via 0.0.0.0 on the kubedock host as well")
+	serverCmd.PersistentFlags().Bool("in-cluster-proxy", false, "Reverse proxy all services via the kubedock pod, and report its pod ip as the host ip of published ports")
 	serverCmd.PersistentFlags().Bool("pre-archive", false, "Enable support for copying single files to containers without starting them")
+	serverCmd.PersistentFlags().Bool("archive-helper", false, "Enable copying archives to and from containers that are not running, using helper pods")
+	serverCmd.PersistentFlags().String("archive-helper-volume-size", "1Gi", "Size of the volume claim that keeps the volumes and archives of a container if archive-helper is enabled")
+	serverCmd.PersistentFlags().String("archive-helper-storage-class", "", "Storage class of the volume claim that keeps the volumes and archives of a container (default storage class if empty)")
+	serverCmd.PersistentFlags().String("archive-helper-access-mode", "ReadWriteOnce", "Access mode of the volume claim that keeps the volumes and archives of a container (ReadWriteOnce, ReadWriteOncePod or ReadWriteMany)")
 	serverCmd.PersistentFlags().Bool("disable-services", false, "Disable service creation (requires a network solution such as kubedock-dns)")
 	serverCmd.PersistentFlags().Bool("ignore-container-memory", false, "Ignore container memory setting and use requests/limits from gobal settings or container labels")
+	serverCmd.PersistentFlags().Bool("separate-stderr", false, "Wrap container commands to separate stderr from stdout in logs")
+	serverCmd.PersistentFlags().Bool("allow-host-network", false, "Allow containers to use the host network mode (hostNetwork pods)")
+	serverCmd.PersistentFlags().String("allowed-devices", "", "Comma separated list of devices that containers can use (path, or path=resource to request a device plugin resource)")
+	serverCmd.PersistentFlags().Bool("allow-unsafe-sysctls", false, "Allow containers to set sysctls that are not considered safe by kubernetes")
+	serverCmd.PersistentFlags().Bool("strict-create", false, "Reject containers that use unsupported features instead of returning warnings")
+	serverCmd.PersistentFlags().Bool("lenient-network-create", false, "Return the existing network when creating a network with a name that is already in use, instead of a conflict")
+	serverCmd.PersistentFlags().Duration("start-latency-budget", 0, "Warn when starting a container takes longer than this duration (0 disables)")
+	serverCmd.PersistentFlags().Int("max-streams", 500, "Maximum number of simultaneous log and event streams (0 = unlimited)")
+	serverCmd.PersistentFlags().Int("event-queue-size", events.DefaultQueueSize, "Number of events queued per events stream before the oldest are dropped")
+	serverCmd.PersistentFlags().Duration("list-cache-ttl", 0, "Duration to cache container, image and network list responses (0 disables)")
+	serverCmd.PersistentFlags().String("max-body-size", "0", "Maximum size of a request body, e.g. 2Gi (0 = unlimited)")
+	serverCmd.PersistentFlags().String("max-upload-size", "8Gi", "Maximum total size of a chunked (resumable) archive upload (0 = unlimited)")
+	serverCmd.PersistentFlags().Duration("read-timeout", 0, "Maximum duration to read a request body (0 = unlimited)")
+	serverCmd.PersistentFlags().Duration("write-timeout", 0, "Maximum duration before a (non-blocking) request is responded to (0 = unlimited)")
+	serverCmd.PersistentFlags().Duration("stream-idle-timeout", 0, "Close streaming responses that are idle longer than this duration (0 = never)")
+	serverCmd.PersistentFlags().String("cors-allowed-origins", "", "Comma separated list of origins that browser clients can use the api from (* allows all)")
+	serverCmd.PersistentFlags().String("path-prefix", "", "Path prefix kubedock is served under behind a reverse proxy (e.g. /kubedock-api)")
+	serverCmd.PersistentFlags().Bool("trust-forwarded-headers", false, "Honor the X-Forwarded-Host and X-Forwarded-Prefix headers of a reverse proxy")
+	serverCmd.PersistentFlags().String("record", "", "Directory to record sanitized api requests and responses to, for bug reports")
+	serverCmd.PersistentFlags().String("allowed-cidrs", "", "Comma separated list of cidrs of clients that are allowed to use the api (default all)")
+	serverCmd.PersistentFlags().String("trusted-proxies", "", "Comma separated list of cidrs of reverse proxies of which the X-Forwarded-For header is honored")
+	serverCmd.PersistentFlags().Bool("dashboard", false, "Serve a web dashboard of the tracked containers at /kubedock/dashboard")
+	serverCmd.PersistentFlags().String("admin-token", "", "Bearer token that enables the admin api (/kubedock/admin)")
 
 	viper.BindPFlag("server.listen-addr", serverCmd.PersistentFlags().Lookup("listen-addr"))
+	viper.BindPFlag("backend", serverCmd.PersistentFlags().Lookup("backend"))
+	viper.BindPFlag("docker-host", serverCmd.PersistentFlags().Lookup("docker-host"))
 	viper.BindPFlag("server.socket", serverCmd.PersistentFlags().Lookup("unix-socket"))
 	viper.BindPFlag("server.tls-enable", serverCmd.PersistentFlags().Lookup("tls-enable"))
 	viper.BindPFlag("server.tls-cert-file", serverCmd.PersistentFlags().Lookup("tls-cert-file"))
@@ -75,30 +135,82 @@ func init() {
 	viper.BindPFlag("kubernetes.initimage", serverCmd.PersistentFlags().Lookup("initimage"))
 	viper.BindPFlag("kubernetes.dindimage", serverCmd.PersistentFlags().Lookup("dindimage"))
 	viper.BindPFlag("kubernetes.disable-dind", serverCmd.PersistentFlags().Lookup("disable-dind"))
+	viper.BindPFlag("kubernetes.selinux-relabel", serverCmd.PersistentFlags().Lookup("selinux-relabel"))
+	viper.BindPFlag("kubernetes.security-profile", serverCmd.PersistentFlags().Lookup("security-profile"))
+	viper.BindPFlag("kubernetes.quota-policy", serverCmd.PersistentFlags().Lookup("quota-policy"))
+	viper.BindPFlag("kubernetes.disable-sidecar-injection", serverCmd.PersistentFlags().Lookup("disable-sidecar-injection"))
+	viper.BindPFlag("kubernetes.scoped-rbac", serverCmd.PersistentFlags().Lookup("scoped-rbac"))
 	viper.BindPFlag("kubernetes.pull-policy", serverCmd.PersistentFlags().Lookup("pull-policy"))
 	viper.BindPFlag("kubernetes.service-account", serverCmd.PersistentFlags().Lookup("service-account"))
 	viper.BindPFlag("kubernetes.image-pull-secrets", serverCmd.PersistentFlags().Lookup("image-pull-secrets"))
+	viper.BindPFlag("kubernetes.image-cache-ttl", serverCmd.PersistentFlags().Lookup("image-cache-ttl"))
+	viper.BindPFlag("kubernetes.pin-digests", serverCmd.PersistentFlags().Lookup("pin-digests"))
+	viper.BindPFlag("kubernetes.image-rewrite", serverCmd.PersistentFlags().Lookup("image-rewrite"))
+	viper.BindPFlag("kubernetes.readiness", serverCmd.PersistentFlags().Lookup("readiness"))
+	viper.BindPFlag("kubernetes.prewarm-images", serverCmd.PersistentFlags().Lookup("prewarm-images"))
 	viper.BindPFlag("kubernetes.pod-template", serverCmd.PersistentFlags().Lookup("pod-template"))
 	viper.BindPFlag("kubernetes.pod-name-prefix", serverCmd.PersistentFlags().Lookup("pod-name-prefix"))
 	viper.BindPFlag("kubernetes.timeout", serverCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("kubernetes.exec-idle-timeout", serverCmd.PersistentFlags().Lookup("exec-idle-timeout"))
+	viper.BindPFlag("kubernetes.exec-max-duration", serverCmd.PersistentFlags().Lookup("exec-max-duration"))
 	viper.BindPFlag("kubernetes.request-cpu", serverCmd.PersistentFlags().Lookup("request-cpu"))
 	viper.BindPFlag("kubernetes.request-memory", serverCmd.PersistentFlags().Lookup("request-memory"))
 	viper.BindPFlag("kubernetes.node-selector", serverCmd.PersistentFlags().Lookup("node-selector"))
 	viper.BindPFlag("kubernetes.active-deadline-seconds", serverCmd.PersistentFlags().Lookup("active-deadline-seconds"))
 	viper.BindPFlag("kubernetes.runas-user", serverCmd.PersistentFlags().Lookup("runas-user"))
+	viper.BindPFlag("kubernetes.fs-group", serverCmd.PersistentFlags().Lookup("fs-group"))
 	viper.BindPFlag("registry.inspector", serverCmd.PersistentFlags().Lookup("inspector"))
+	viper.BindPFlag("registry.proxy", serverCmd.PersistentFlags().Lookup("registry-proxy"))
+	viper.BindPFlag("buildkit-addr", serverCmd.PersistentFlags().Lookup("buildkit-addr"))
 	viper.BindPFlag("reaper.reapmax", serverCmd.PersistentFlags().Lookup("reapmax"))
+	viper.BindPFlag("reaper.forward-idle-timeout", serverCmd.PersistentFlags().Lookup("forward-idle-timeout"))
+	viper.BindPFlag("reaper.retain-failed", serverCmd.PersistentFlags().Lookup("retain-failed"))
 	viper.BindPFlag("lock.enabled", serverCmd.PersistentFlags().Lookup("lock"))
 	viper.BindPFlag("lock.timeout", serverCmd.PersistentFlags().Lookup("lock-timeout"))
 	viper.BindPFlag("verbosity", serverCmd.PersistentFlags().Lookup("verbosity"))
 	viper.BindPFlag("prune-start", serverCmd.PersistentFlags().Lookup("prune-start"))
 	viper.BindPFlag("port-forward", serverCmd.PersistentFlags().Lookup("port-forward"))
 	viper.BindPFlag("reverse-proxy", serverCmd.PersistentFlags().Lookup("reverse-proxy"))
+	viper.BindPFlag("in-cluster-proxy", serverCmd.PersistentFlags().Lookup("in-cluster-proxy"))
 	viper.BindPFlag("pre-archive", serverCmd.PersistentFlags().Lookup("pre-archive"))
+	viper.BindPFlag("archive-helper", serverCmd.PersistentFlags().Lookup("archive-helper"))
+	viper.BindPFlag("archive-helper-volume-size", serverCmd.PersistentFlags().Lookup("archive-helper-volume-size"))
+	viper.BindPFlag("archive-helper-storage-class", serverCmd.PersistentFlags().Lookup("archive-helper-storage-class"))
+	viper.BindPFlag("archive-helper-access-mode", serverCmd.PersistentFlags().Lookup("archive-helper-access-mode"))
 	viper.BindPFlag("disable-services", serverCmd.PersistentFlags().Lookup("disable-services"))
 	viper.BindPFlag("ignore-container-memory", serverCmd.PersistentFlags().Lookup("ignore-container-memory"))
+	viper.BindPFlag("separate-stderr", serverCmd.PersistentFlags().Lookup("separate-stderr"))
+	viper.BindPFlag("allow-host-network", serverCmd.PersistentFlags().Lookup("allow-host-network"))
+	viper.BindPFlag("allowed-devices", serverCmd.PersistentFlags().Lookup("allowed-devices"))
+	viper.BindPFlag("allow-unsafe-sysctls", serverCmd.PersistentFlags().Lookup("allow-unsafe-sysctls"))
+	viper.BindPFlag("strict-create", serverCmd.PersistentFlags().Lookup("strict-create"))
+	viper.BindPFlag("lenient-network-create", serverCmd.PersistentFlags().Lookup("lenient-network-create"))
+	viper.BindPFlag("start-latency-budget", serverCmd.PersistentFlags().Lookup("start-latency-budget"))
+	viper.BindPFlag("admin-token", serverCmd.PersistentFlags().Lookup("admin-token"))
+	viper.BindPFlag("dashboard", serverCmd.PersistentFlags().Lookup("dashboard"))
+	viper.BindPFlag("max-streams", serverCmd.PersistentFlags().Lookup("max-streams"))
+	viper.BindPFlag("event-queue-size", serverCmd.PersistentFlags().Lookup("event-queue-size"))
+	viper.BindPFlag("list-cache-ttl", serverCmd.PersistentFlags().Lookup("list-cache-ttl"))
+	viper.BindPFlag("max-body-size", serverCmd.PersistentFlags().Lookup("max-body-size"))
+	viper.BindPFlag("max-upload-size", serverCmd.PersistentFlags().Lookup("max-upload-size"))
+	viper.BindPFlag("read-timeout", serverCmd.PersistentFlags().Lookup("read-timeout"))
+	viper.BindPFlag("write-timeout", serverCmd.PersistentFlags().Lookup("write-timeout"))
+	viper.BindPFlag("stream-idle-timeout", serverCmd.PersistentFlags().Lookup("stream-idle-timeout"))
+	viper.BindPFlag("cors-allowed-origins", serverCmd.PersistentFlags().Lookup("cors-allowed-origins"))
+	viper.BindPFlag("path-prefix", serverCmd.PersistentFlags().Lookup("path-prefix"))
+	viper.BindPFlag("trust-forwarded-headers", serverCmd.PersistentFlags().Lookup("trust-forwarded-headers"))
+	viper.BindPFlag("record", serverCmd.PersistentFlags().Lookup("record"))
+	viper.BindPFlag("allowed-cidrs", serverCmd.PersistentFlags().Lookup("allowed-cidrs"))
+	viper.BindPFlag("trusted-proxies", serverCmd.PersistentFlags().Lookup("trusted-proxies"))
 
 	viper.BindEnv("server.listen-addr", "SERVER_LISTEN_ADDR")
+	viper.BindEnv("backend", "BACKEND")
+	viper.BindEnv("docker-host", "BACKEND_DOCKER_HOST")
+	viper.BindEnv("archive-helper", "ARCHIVE_HELPER")
+	viper.BindEnv("archive-helper-volume-size", "ARCHIVE_HELPER_VOLUME_SIZE")
+	viper.BindEnv("archive-helper-storage-class", "ARCHIVE_HELPER_STORAGE_CLASS")
+	viper.BindEnv("archive-helper-access-mode", "ARCHIVE_HELPER_ACCESS_MODE")
+	viper.BindEnv("in-cluster-proxy", "IN_CLUSTER_PROXY")
 	viper.BindEnv("server.tls-enable", "SERVER_TLS_ENABLE")
 	viper.BindEnv("server.tls-cert-file", "SERVER_TLS_CERT_FILE")
 	viper.BindEnv("server.tls-key-file", "SERVER_TLS_KEY_FILE")
@@ -106,9 +218,21 @@ func init() {
 	viper.BindEnv("kubernetes.initimage", "INIT_IMAGE")
 	viper.BindEnv("kubernetes.dindimage", "DIND_IMAGE")
 	viper.BindEnv("kubernetes.disable-dind", "DISABLE_DIND")
+	viper.BindEnv("kubernetes.selinux-relabel", "SELINUX_RELABEL")
+	viper.BindEnv("kubernetes.security-profile", "SECURITY_PROFILE")
+	viper.BindEnv("kubernetes.quota-policy", "QUOTA_POLICY")
+	viper.BindEnv("kubernetes.disable-sidecar-injection", "DISABLE_SIDECAR_INJECTION")
+	viper.BindEnv("kubernetes.scoped-rbac", "SCOPED_RBAC")
 	viper.BindEnv("kubernetes.pull-policy", "PULL_POLICY")
 	viper.BindEnv("kubernetes.service-account", "SERVICE_ACCOUNT")
 	viper.BindEnv("kubernetes.image-pull-secrets", "IMAGE_PULL_SECRETS")
+	viper.BindEnv("kubernetes.image-cache-ttl", "IMAGE_CACHE_TTL")
+	viper.BindEnv("kubernetes.pin-digests", "PIN_DIGESTS")
+	viper.BindEnv("registry.proxy", "REGISTRY_PROXY")
+	viper.BindEnv("buildkit-addr", "BUILDKIT_ADDR")
+	viper.BindEnv("kubernetes.image-rewrite", "IMAGE_REWRITE")
+	viper.BindEnv("kubernetes.prewarm-images", "PREWARM_IMAGES")
+	viper.BindEnv("kubernetes.readiness", "READINESS")
 	viper.BindEnv("kubernetes.pod-template", "POD_TEMPLATE")
 	viper.BindEnv("kubernetes.pod-name-prefix", "POD_NAME_PREFIX")
 	viper.BindEnv("kubernetes.timeout", "TIME_OUT")
@@ -117,8 +241,36 @@ func init() {
 	viper.BindEnv("kubernetes.node-selector", "K8S_NODE_SELECTOR")
 	viper.BindEnv("kubernetes.active-deadline-seconds", "K8S_ACTIVE_DEADLINE_SECONDS")
 	viper.BindEnv("kubernetes.runas-user", "K8S_RUNAS_USER")
+	viper.BindEnv("kubernetes.fs-group", "K8S_FS_GROUP")
 	viper.BindEnv("kubernetes.timeout", "TIME_OUT")
 	viper.BindEnv("reaper.reapmax", "REAPER_REAPMAX")
+	viper.BindEnv("kubernetes.exec-idle-timeout", "EXEC_IDLE_TIMEOUT")
+	viper.BindEnv("kubernetes.exec-max-duration", "EXEC_MAX_DURATION")
+	viper.BindEnv("reaper.forward-idle-timeout", "REAPER_FORWARD_IDLE_TIMEOUT")
+	viper.BindEnv("reaper.retain-failed", "REAPER_RETAIN_FAILED")
+	viper.BindEnv("separate-stderr", "SEPARATE_STDERR")
+	viper.BindEnv("allow-host-network", "ALLOW_HOST_NETWORK")
+	viper.BindEnv("allowed-devices", "ALLOWED_DEVICES")
+	viper.BindEnv("allow-unsafe-sysctls", "ALLOW_UNSAFE_SYSCTLS")
+	viper.BindEnv("strict-create", "STRICT_CREATE")
+	viper.BindEnv("lenient-network-create", "LENIENT_NETWORK_CREATE")
+	viper.BindEnv("start-latency-budget", "START_LATENCY_BUDGET")
+	viper.BindEnv("admin-token", "ADMIN_TOKEN")
+	viper.BindEnv("dashboard", "DASHBOARD")
+	viper.BindEnv("max-streams", "MAX_STREAMS")
+	viper.BindEnv("event-queue-size", "EVENT_QUEUE_SIZE")
+	viper.BindEnv("list-cache-ttl", "LIST_CACHE_TTL")
+	viper.BindEnv("max-body-size", "MAX_BODY_SIZE")
+	viper.BindEnv("max-upload-size", "MAX_UPLOAD_SIZE")
+	viper.BindEnv("read-timeout", "READ_TIMEOUT")
+	viper.BindEnv("write-timeout", "WRITE_TIMEOUT")
+	viper.BindEnv("stream-idle-timeout", "STREAM_IDLE_TIMEOUT")
+	viper.BindEnv("cors-allowed-origins", "CORS_ALLOWED_ORIGINS")
+	viper.BindEnv("path-prefix", "PATH_PREFIX")
+	viper.BindEnv("trust-forwarded-headers", "TRUST_FORWARDED_HEADERS")
+	viper.BindEnv("record", "RECORD_DIR")
+	viper.BindEnv("allowed-cidrs", "ALLOWED_CIDRS")
+	viper.BindEnv("trusted-proxies", "TRUSTED_PROXIES")
 	viper.BindEnv("verbosity", "VERBOSITY")
 
 	serverCmd.PersistentFlags().Lookup("tls-enable").Hidden = true
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joyrex2001/kubedock/internal/util/stdtag"
+)
+
+var stdtagCmd = &cobra.Command{
+	Use:                "stdtag -- command [args...]",
+	Short:              "Run a command and tag its stderr output (used inside containers)",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 && args[0] == "--" {
+			args = args[1:]
+		}
+		os.Exit(stdtag.Run(args))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stdtagCmd)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
+)
+
+var ulimitCmd = &cobra.Command{
+	Use:                "ulimit name=soft:hard... -- command [args...]",
+	Short:              "Run a command with the given ulimits (used inside containers)",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		limits := []string{}
+		for len(args) > 0 && args[0] != "--" {
+			limits = append(limits, args[0])
+			args = args[1:]
+		}
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		os.Exit(ulimit.Run(limits, args))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ulimitCmd)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/joyrex2001/kubedock/internal/util/unpack"
+)
+
+var unpackCmd = &cobra.Command{
+	Use:                "unpack archive=target... -- command [args...]",
+	Short:              "Extract archives and run a command (used inside containers)",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		archives := []string{}
+		for len(args) > 0 && args[0] != "--" {
+			archives = append(archives, args[0])
+			args = args[1:]
+		}
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		os.Exit(unpack.Run(archives, args))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unpackCmd)
+}
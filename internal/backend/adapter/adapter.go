@@ -0,0 +1,570 @@
+// Package adapter converts between the public backend interface of
+// pkg/backend, which can be implemented outside of kubedock, and the
+// internal backend interface that is used by the api routes and the reaper.
+package adapter
+
+import (
+	"context"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+	pkgbackend "github.com/joyrex2001/kubedock/pkg/backend"
+)
+
+// internal adapts a public Backend to the internal Backend interface.
+type internal struct {
+	kub pkgbackend.Backend
+}
+
+// public adapts an internal Backend to the public Backend interface.
+type public struct {
+	kub    backend.Backend
+	lookup func(string) (*types.Container, error)
+}
+
+var _ backend.Backend = &internal{}
+var _ pkgbackend.Backend = &public{}
+
+// Internal will return an internal Backend that uses given public Backend.
+func Internal(kub pkgbackend.Backend) backend.Backend {
+	if pub, ok := kub.(*public); ok {
+		return pub.kub
+	}
+	return &internal{kub: kub}
+}
+
+// Public will return a public Backend that uses given internal Backend. The
+// optional lookup function returns the internal record of a container, so
+// the runtime state of the container (e.g. its port-forwards) is kept.
+func Public(kub backend.Backend, lookup func(string) (*types.Container, error)) pkgbackend.Backend {
+	if in, ok := kub.(*internal); ok {
+		return in.kub
+	}
+	return &public{kub: kub, lookup: lookup}
+}
+
+// StartContainer will start given container, and store the deploy details
+// that are set by the backend in given container.
+func (in *internal) StartContainer(ctx context.Context, tainr *types.Container) (backend.DeployState, error) {
+	pub := PublicContainer(tainr)
+	state, err := in.kub.StartContainer(ctx, pub)
+	copyToInternal(tainr, pub)
+	return backend.DeployState(state), err
+}
+
+// AttachContainer will attach to given container; the terminal sizes of the
+// container are passed to the backend if a tty is used.
+func (in *internal) AttachContainer(tainr *types.Container, stdin io.Reader, stdout io.Writer, stderr io.Writer, tty bool) error {
+	pub := PublicContainer(tainr)
+	if tty {
+		sizes := tainr.TerminalSizes().Add()
+		defer tainr.TerminalSizes().Remove(sizes)
+		pub.TerminalSizes = sizes
+	}
+	return in.kub.AttachContainer(pub, stdin, stdout, stderr, tty)
+}
+
+// GetContainerStatus will return the state of given container.
+func (in *internal) GetContainerStatus(tainr *types.Container) (backend.DeployState, error) {
+	state, err := in.kub.GetContainerStatus(PublicContainer(tainr))
+	return backend.DeployState(state), err
+}
+
+// CreatePortForwards will create the port-forwards of given container, and
+// store the mapped ports in given container.
+func (in *internal) CreatePortForwards(tainr *types.Container) {
+	pub := PublicContainer(tainr)
+	in.kub.CreatePortForwards(pub)
+	copyToInternal(tainr, pub)
+}
+
+// CreateReverseProxies will create the reverse proxies of given container,
+// and store the mapped ports in given container.
+func (in *internal) CreateReverseProxies(tainr *types.Container) {
+	pub := PublicContainer(tainr)
+	in.kub.CreateReverseProxies(pub)
+	copyToInternal(tainr, pub)
+}
+
+// UpdateServices will update the services of given container.
+func (in *internal) UpdateServices(tainr *types.Container) error {
+	return in.kub.UpdateServices(PublicContainer(tainr))
+}
+
+// CleanForwards will close the idle port-forwards of given containers.
+func (in *internal) CleanForwards(tainrs []*types.Container, idle time.Duration) int {
+	return in.kub.CleanForwards(publicContainers(tainrs), idle)
+}
+
+// GetPodIP will return the ip of given container.
+func (in *internal) GetPodIP(tainr *types.Container) (string, error) {
+	return in.kub.GetPodIP(PublicContainer(tainr))
+}
+
+// DeleteAll will remove all containers.
+func (in *internal) DeleteAll() error {
+	return in.kub.DeleteAll()
+}
+
+// DeleteWithKubedockID will remove all containers of given kubedock id.
+func (in *internal) DeleteWithKubedockID(id string) error {
+	return in.kub.DeleteWithKubedockID(id)
+}
+
+// DeleteProject will remove all containers of given project.
+func (in *internal) DeleteProject(project string) error {
+	return in.kub.DeleteProject(project)
+}
+
+// DeleteContainer will remove given container.
+func (in *internal) DeleteContainer(tainr *types.Container) error {
+	return in.kub.DeleteContainer(PublicContainer(tainr))
+}
+
+// RetainContainer will return true if given container is retained.
+func (in *internal) RetainContainer(tainr *types.Container) (bool, error) {
+	return in.kub.RetainContainer(PublicContainer(tainr))
+}
+
+// DeleteOlderThan will remove all containers older than given duration.
+func (in *internal) DeleteOlderThan(keepmax time.Duration) error {
+	return in.kub.DeleteOlderThan(keepmax)
+}
+
+// WatchDeleteContainer will return a channel that is closed when given
+// container is removed.
+func (in *internal) WatchDeleteContainer(tainr *types.Container) (chan struct{}, error) {
+	return in.kub.WatchDeleteContainer(PublicContainer(tainr))
+}
+
+// WatchContainerExit will return a channel that receives the exit of given
+// container.
+func (in *internal) WatchContainerExit(tainr *types.Container) (chan backend.ContainerExit, error) {
+	exit, err := in.kub.WatchContainerExit(PublicContainer(tainr))
+	if err != nil {
+		return nil, err
+	}
+	res := make(chan backend.ContainerExit, 1)
+	go func() {
+		defer close(res)
+		for ex := range exit {
+			res <- backend.ContainerExit(ex)
+		}
+	}()
+	return res, nil
+}
+
+// CopyFromContainer will write a tar archive of given path in given
+// container to given writer.
+func (in *internal) CopyFromContainer(tainr *types.Container, target string, w io.Writer) error {
+	return in.kub.CopyFromContainer(PublicContainer(tainr), target, w)
+}
+
+// CopyToContainer will extract given tar archive in given container.
+func (in *internal) CopyToContainer(tainr *types.Container, archive io.Reader, target string, compressed bool) error {
+	return in.kub.CopyToContainer(PublicContainer(tainr), archive, target, compressed)
+}
+
+// GetFileStatInContainer will return the details of given path in given
+// container.
+func (in *internal) GetFileStatInContainer(tainr *types.Container, target string) (*backend.FileStat, error) {
+	stat, err := in.kub.GetFileStatInContainer(PublicContainer(tainr), target)
+	return (*backend.FileStat)(stat), err
+}
+
+// FileExistsInContainer will return true if given path exists in given
+// container.
+func (in *internal) FileExistsInContainer(tainr *types.Container, target string) (bool, error) {
+	return in.kub.FileExistsInContainer(PublicContainer(tainr), target)
+}
+
+// StartArchiveHelper will start an archive helper for given container.
+func (in *internal) StartArchiveHelper(tainr *types.Container) (*types.Container, error) {
+	helper, err := in.kub.StartArchiveHelper(PublicContainer(tainr))
+	return InternalContainer(helper), err
+}
+
+// StageArchive will stage given archive with given archive helper.
+func (in *internal) StageArchive(helper *types.Container, archive io.Reader) (string, error) {
+	return in.kub.StageArchive(PublicContainer(helper), archive)
+}
+
+// DeleteContainerVolumes will remove the volumes of given container.
+func (in *internal) DeleteContainerVolumes(tainr *types.Container) error {
+	return in.kub.DeleteContainerVolumes(PublicContainer(tainr))
+}
+
+// ExecContainer will execute given command in given container; the
+// terminal sizes of the exec are passed to the backend if a tty is used.
+func (in *internal) ExecContainer(ctx context.Context, tainr *types.Container, exec *types.Exec, stdin io.Reader, stdout io.Writer) (int, error) {
+	pub := publicExec(exec)
+	if exec.TTY {
+		sizes := exec.TerminalSizes.Add()
+		defer exec.TerminalSizes.Remove(sizes)
+		pub.TerminalSizes = sizes
+	}
+	return in.kub.ExecContainer(ctx, PublicContainer(tainr), pub, stdin, stdout)
+}
+
+// GetLogs will write the logs of given container to given writer.
+func (in *internal) GetLogs(tainr *types.Container, opts *backend.LogOptions, stop chan struct{}, w io.Writer) error {
+	return in.kub.GetLogs(PublicContainer(tainr), (*pkgbackend.LogOptions)(opts), stop, w)
+}
+
+// GetLogsRaw will write the raw logs of given container to given writer.
+func (in *internal) GetLogsRaw(tainr *types.Container, opts *backend.LogOptions, stop chan struct{}, w io.Writer) error {
+	return in.kub.GetLogsRaw(PublicContainer(tainr), (*pkgbackend.LogOptions)(opts), stop, w)
+}
+
+// InspectImage will return the details of given image.
+func (in *internal) InspectImage(name string) (*image.Details, error) {
+	dtl, err := in.kub.InspectImage(name)
+	return (*image.Details)(dtl), err
+}
+
+// GetImageDistribution will return the distribution of given image.
+func (in *internal) GetImageDistribution(name string) (*image.Distribution, error) {
+	dist, err := in.kub.GetImageDistribution(name)
+	return (*image.Distribution)(dist), err
+}
+
+// GetImageManifest will return the manifest of given image.
+func (in *internal) GetImageManifest(repo, ref string) (*image.Manifest, error) {
+	man, err := in.kub.GetImageManifest(repo, ref)
+	return (*image.Manifest)(man), err
+}
+
+// GetImageBlob will return the blob with given digest.
+func (in *internal) GetImageBlob(repo, dgst string) (io.ReadCloser, int64, error) {
+	return in.kub.GetImageBlob(repo, dgst)
+}
+
+// PrewarmImages will prewarm given images.
+func (in *internal) PrewarmImages(images []string) ([]string, error) {
+	return in.kub.PrewarmImages(images)
+}
+
+// SetImageRewrites will set the image rewrite rules.
+func (in *internal) SetImageRewrites(rules []image.RewriteRule) {
+	in.kub.SetImageRewrites(publicRewrites(rules))
+}
+
+// GetPodEvents will return the events of given container.
+func (in *internal) GetPodEvents(tainr *types.Container) ([]corev1.Event, error) {
+	return in.kub.GetPodEvents(PublicContainer(tainr))
+}
+
+// GetContainerStats will return the resource usage of given containers.
+func (in *internal) GetContainerStats(tainrs []*types.Container) (map[string]*backend.ContainerStats, error) {
+	stats, err := in.kub.GetContainerStats(publicContainers(tainrs))
+	if err != nil {
+		return nil, err
+	}
+	return internalStats(stats), nil
+}
+
+// GetCapacity will return the capacity of the backend.
+func (in *internal) GetCapacity() (*backend.Capacity, error) {
+	capa, err := in.kub.GetCapacity()
+	return (*backend.Capacity)(capa), err
+}
+
+// DeployService will deploy given service.
+func (in *internal) DeployService(svc *types.Service) error {
+	return in.kub.DeployService(publicService(svc))
+}
+
+// GetServiceReplicas will return the number of ready replicas of given
+// service.
+func (in *internal) GetServiceReplicas(svc *types.Service) (int, error) {
+	return in.kub.GetServiceReplicas(publicService(svc))
+}
+
+// DeleteService will remove given service.
+func (in *internal) DeleteService(svc *types.Service) error {
+	return in.kub.DeleteService(publicService(svc))
+}
+
+// CheckFeature will return an error if given feature is not available.
+func (in *internal) CheckFeature(feature string) error {
+	return in.kub.CheckFeature(feature)
+}
+
+// container will return the internal record of given container, or a new
+// internal representation if it has no record.
+func (in *public) container(tainr *pkgbackend.Container) *types.Container {
+	if tainr == nil {
+		return nil
+	}
+	if in.lookup != nil {
+		if rec, err := in.lookup(tainr.ID); err == nil {
+			return rec.Clone()
+		}
+	}
+	return InternalContainer(tainr)
+}
+
+// containers will return the internal records of given containers.
+func (in *public) containers(tainrs []*pkgbackend.Container) []*types.Container {
+	res := make([]*types.Container, 0, len(tainrs))
+	for _, tainr := range tainrs {
+		res = append(res, in.container(tainr))
+	}
+	return res
+}
+
+// StartContainer will start given container, and store the deploy details
+// that are set by the backend in given container.
+func (in *public) StartContainer(ctx context.Context, tainr *pkgbackend.Container) (pkgbackend.DeployState, error) {
+	rec := in.container(tainr)
+	state, err := in.kub.StartContainer(ctx, rec)
+	copyToPublic(tainr, rec)
+	return pkgbackend.DeployState(state), err
+}
+
+// AttachContainer will attach to given container; the terminal sizes of
+// given container are passed to the backend if a tty is used.
+func (in *public) AttachContainer(tainr *pkgbackend.Container, stdin io.Reader, stdout io.Writer, stderr io.Writer, tty bool) error {
+	rec := in.container(tainr)
+	if tty && tainr.TerminalSizes != nil {
+		go resize(tainr.TerminalSizes, rec.TerminalSizes().Resize)
+	}
+	return in.kub.AttachContainer(rec, stdin, stdout, stderr, tty)
+}
+
+// GetContainerStatus will return the state of given container.
+func (in *public) GetContainerStatus(tainr *pkgbackend.Container) (pkgbackend.DeployState, error) {
+	state, err := in.kub.GetContainerStatus(in.container(tainr))
+	return pkgbackend.DeployState(state), err
+}
+
+// CreatePortForwards will create the port-forwards of given container, and
+// store the mapped ports in given container.
+func (in *public) CreatePortForwards(tainr *pkgbackend.Container) {
+	rec := in.container(tainr)
+	in.kub.CreatePortForwards(rec)
+	copyToPublic(tainr, rec)
+}
+
+// CreateReverseProxies will create the reverse proxies of given container,
+// and store the mapped ports in given container.
+func (in *public) CreateReverseProxies(tainr *pkgbackend.Container) {
+	rec := in.container(tainr)
+	in.kub.CreateReverseProxies(rec)
+	copyToPublic(tainr, rec)
+}
+
+// UpdateServices will update the services of given container.
+func (in *public) UpdateServices(tainr *pkgbackend.Container) error {
+	return in.kub.UpdateServices(in.container(tainr))
+}
+
+// CleanForwards will close the idle port-forwards of given containers.
+func (in *public) CleanForwards(tainrs []*pkgbackend.Container, idle time.Duration) int {
+	return in.kub.CleanForwards(in.containers(tainrs), idle)
+}
+
+// GetPodIP will return the ip of given container.
+func (in *public) GetPodIP(tainr *pkgbackend.Container) (string, error) {
+	return in.kub.GetPodIP(in.container(tainr))
+}
+
+// DeleteAll will remove all containers.
+func (in *public) DeleteAll() error {
+	return in.kub.DeleteAll()
+}
+
+// DeleteWithKubedockID will remove all containers of given kubedock id.
+func (in *public) DeleteWithKubedockID(id string) error {
+	return in.kub.DeleteWithKubedockID(id)
+}
+
+// DeleteProject will remove all containers of given project.
+func (in *public) DeleteProject(project string) error {
+	return in.kub.DeleteProject(project)
+}
+
+// DeleteContainer will remove given container.
+func (in *public) DeleteContainer(tainr *pkgbackend.Container) error {
+	return in.kub.DeleteContainer(in.container(tainr))
+}
+
+// RetainContainer will return true if given container is retained.
+func (in *public) RetainContainer(tainr *pkgbackend.Container) (bool, error) {
+	return in.kub.RetainContainer(in.container(tainr))
+}
+
+// DeleteOlderThan will remove all containers older than given duration.
+func (in *public) DeleteOlderThan(keepmax time.Duration) error {
+	return in.kub.DeleteOlderThan(keepmax)
+}
+
+// WatchDeleteContainer will return a channel that is closed when given
+// container is removed.
+func (in *public) WatchDeleteContainer(tainr *pkgbackend.Container) (chan struct{}, error) {
+	return in.kub.WatchDeleteContainer(in.container(tainr))
+}
+
+// WatchContainerExit will return a channel that receives the exit of given
+// container.
+func (in *public) WatchContainerExit(tainr *pkgbackend.Container) (chan pkgbackend.ContainerExit, error) {
+	exit, err := in.kub.WatchContainerExit(in.container(tainr))
+	if err != nil {
+		return nil, err
+	}
+	res := make(chan pkgbackend.ContainerExit, 1)
+	go func() {
+		defer close(res)
+		for ex := range exit {
+			res <- pkgbackend.ContainerExit(ex)
+		}
+	}()
+	return res, nil
+}
+
+// CopyFromContainer will write a tar archive of given path in given
+// container to given writer.
+func (in *public) CopyFromContainer(tainr *pkgbackend.Container, target string, w io.Writer) error {
+	return in.kub.CopyFromContainer(in.container(tainr), target, w)
+}
+
+// CopyToContainer will extract given tar archive in given container.
+func (in *public) CopyToContainer(tainr *pkgbackend.Container, archive io.Reader, target string, compressed bool) error {
+	return in.kub.CopyToContainer(in.container(tainr), archive, target, compressed)
+}
+
+// GetFileStatInContainer will return the details of given path in given
+// container.
+func (in *public) GetFileStatInContainer(tainr *pkgbackend.Container, target string) (*pkgbackend.FileStat, error) {
+	stat, err := in.kub.GetFileStatInContainer(in.container(tainr), target)
+	return (*pkgbackend.FileStat)(stat), err
+}
+
+// FileExistsInContainer will return true if given path exists in given
+// container.
+func (in *public) FileExistsInContainer(tainr *pkgbackend.Container, target string) (bool, error) {
+	return in.kub.FileExistsInContainer(in.container(tainr), target)
+}
+
+// StartArchiveHelper will start an archive helper for given container.
+func (in *public) StartArchiveHelper(tainr *pkgbackend.Container) (*pkgbackend.Container, error) {
+	helper, err := in.kub.StartArchiveHelper(in.container(tainr))
+	return PublicContainer(helper), err
+}
+
+// StageArchive will stage given archive with given archive helper.
+func (in *public) StageArchive(helper *pkgbackend.Container, archive io.Reader) (string, error) {
+	return in.kub.StageArchive(InternalContainer(helper), archive)
+}
+
+// DeleteContainerVolumes will remove the volumes of given container.
+func (in *public) DeleteContainerVolumes(tainr *pkgbackend.Container) error {
+	return in.kub.DeleteContainerVolumes(in.container(tainr))
+}
+
+// ExecContainer will execute given command in given container; the
+// terminal sizes of given exec are passed to the backend if a tty is used.
+func (in *public) ExecContainer(ctx context.Context, tainr *pkgbackend.Container, exec *pkgbackend.Exec, stdin io.Reader, stdout io.Writer) (int, error) {
+	rec := internalExec(exec)
+	if exec.TTY && exec.TerminalSizes != nil {
+		go resize(exec.TerminalSizes, rec.TerminalSizes.Resize)
+	}
+	return in.kub.ExecContainer(ctx, in.container(tainr), rec, stdin, stdout)
+}
+
+// GetLogs will write the logs of given container to given writer.
+func (in *public) GetLogs(tainr *pkgbackend.Container, opts *pkgbackend.LogOptions, stop chan struct{}, w io.Writer) error {
+	return in.kub.GetLogs(in.container(tainr), (*backend.LogOptions)(opts), stop, w)
+}
+
+// GetLogsRaw will write the raw logs of given container to given writer.
+func (in *public) GetLogsRaw(tainr *pkgbackend.Container, opts *pkgbackend.LogOptions, stop chan struct{}, w io.Writer) error {
+	return in.kub.GetLogsRaw(in.container(tainr), (*backend.LogOptions)(opts), stop, w)
+}
+
+// InspectImage will return the details of given image.
+func (in *public) InspectImage(name string) (*pkgbackend.ImageDetails, error) {
+	dtl, err := in.kub.InspectImage(name)
+	return (*pkgbackend.ImageDetails)(dtl), err
+}
+
+// GetImageDistribution will return the distribution of given image.
+func (in *public) GetImageDistribution(name string) (*pkgbackend.ImageDistribution, error) {
+	dist, err := in.kub.GetImageDistribution(name)
+	return (*pkgbackend.ImageDistribution)(dist), err
+}
+
+// GetImageManifest will return the manifest of given image.
+func (in *public) GetImageManifest(repo, ref string) (*pkgbackend.ImageManifest, error) {
+	man, err := in.kub.GetImageManifest(repo, ref)
+	return (*pkgbackend.ImageManifest)(man), err
+}
+
+// GetImageBlob will return the blob with given digest.
+func (in *public) GetImageBlob(repo, dgst string) (io.ReadCloser, int64, error) {
+	return in.kub.GetImageBlob(repo, dgst)
+}
+
+// PrewarmImages will prewarm given images.
+func (in *public) PrewarmImages(images []string) ([]string, error) {
+	return in.kub.PrewarmImages(images)
+}
+
+// SetImageRewrites will set the image rewrite rules.
+func (in *public) SetImageRewrites(rules []pkgbackend.RewriteRule) {
+	in.kub.SetImageRewrites(internalRewrites(rules))
+}
+
+// GetPodEvents will return the events of given container.
+func (in *public) GetPodEvents(tainr *pkgbackend.Container) ([]corev1.Event, error) {
+	return in.kub.GetPodEvents(in.container(tainr))
+}
+
+// GetContainerStats will return the resource usage of given containers.
+func (in *public) GetContainerStats(tainrs []*pkgbackend.Container) (map[string]*pkgbackend.ContainerStats, error) {
+	stats, err := in.kub.GetContainerStats(in.containers(tainrs))
+	if err != nil {
+		return nil, err
+	}
+	return publicStats(stats), nil
+}
+
+// GetCapacity will return the capacity of the backend.
+func (in *public) GetCapacity() (*pkgbackend.Capacity, error) {
+	capa, err := in.kub.GetCapacity()
+	return (*pkgbackend.Capacity)(capa), err
+}
+
+// DeployService will deploy given service.
+func (in *public) DeployService(svc *pkgbackend.Service) error {
+	return in.kub.DeployService(internalService(svc))
+}
+
+// GetServiceReplicas will return the number of ready replicas of given
+// service.
+func (in *public) GetServiceReplicas(svc *pkgbackend.Service) (int, error) {
+	return in.kub.GetServiceReplicas(internalService(svc))
+}
+
+// DeleteService will remove given service.
+func (in *public) DeleteService(svc *pkgbackend.Service) error {
+	return in.kub.DeleteService(internalService(svc))
+}
+
+// CheckFeature will return an error if given feature is not available.
+func (in *public) CheckFeature(feature string) error {
+	return in.kub.CheckFeature(feature)
+}
+
+// resize will apply the terminal sizes of given queue, until the queue is
+// closed.
+func resize(sizes pkgbackend.TerminalSizeQueue, apply func(width, height uint16)) {
+	for size := sizes.Next(); size != nil; size = sizes.Next() {
+		apply(size.Width, size.Height)
+	}
+}
@@ -0,0 +1,85 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
+	pkgbackend "github.com/joyrex2001/kubedock/pkg/backend"
+	"github.com/joyrex2001/kubedock/pkg/backend/fake"
+)
+
+func TestContainer(t *testing.T) {
+	tests := []*types.Container{
+		{},
+		{
+			ID:             "9f6fa2ab",
+			ShortID:        "9f6f",
+			Name:           "adapter",
+			Image:          "nginx",
+			Labels:         map[string]string{"app": "nginx"},
+			Cmd:            []string{"nginx", "-g", "daemon off;"},
+			Mounts:         []types.Mount{{Type: "bind", Source: "/src", Target: "/dst", ReadOnly: true}},
+			StagedArchives: []types.PreArchive{{Path: "/tmp", File: "archive-0"}},
+			HostPorts:      map[int]int{8080: 80},
+			Ulimits:        []ulimit.Limit{{Name: "nofile", Soft: 1024, Hard: 2048}},
+			Devices:        []types.Device{{HostPath: "/dev/fuse", ContainerPath: "/dev/fuse"}},
+			StartTimings:   map[string]time.Duration{types.PhaseCreate: time.Second},
+		},
+	}
+	for i, tst := range tests {
+		res := InternalContainer(PublicContainer(tst))
+		if !reflect.DeepEqual(res, tst) {
+			t.Errorf("failed test %d - expected %#v, but got %#v", i, tst, res)
+		}
+	}
+	if PublicContainer(nil) != nil || InternalContainer(nil) != nil {
+		t.Errorf("failed - expected nil containers to be converted to nil")
+	}
+}
+
+// embedded is an internal backend that is not an adapter.
+type embedded struct {
+	backend.Backend
+}
+
+func TestUnwrap(t *testing.T) {
+	kub := fake.New()
+	if res := Public(Internal(kub), nil); res != kub {
+		t.Errorf("failed - expected the public backend to be unwrapped, but got %#v", res)
+	}
+	in := &embedded{Internal(kub)}
+	if res := Internal(Public(in, nil)); res != in {
+		t.Errorf("failed - expected the internal backend to be unwrapped, but got %#v", res)
+	}
+}
+
+func TestStartContainer(t *testing.T) {
+	kub := fake.New()
+	kub.StartState = pkgbackend.DeployCompleted
+	tainr := &types.Container{ID: "5d7a0e4c", ShortID: "5d7a"}
+	state, err := Internal(kub).StartContainer(context.Background(), tainr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if state != backend.DeployCompleted {
+		t.Errorf("failed - expected %v, but got %v", backend.DeployCompleted, state)
+	}
+	if ip, err := kub.GetPodIP(PublicContainer(tainr)); err != nil || ip == "" {
+		t.Errorf("failed - expected container to have an ip, but got %s (%v)", ip, err)
+	}
+
+	exit, err := Internal(kub).WatchContainerExit(tainr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	kub.Disrupt(tainr.ID, "Evicted")
+	ex := <-exit
+	if ex.Code != 137 || ex.Reason != "Evicted" {
+		t.Errorf("failed - expected evicted exit, but got %#v", ex)
+	}
+}
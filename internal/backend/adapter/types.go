@@ -0,0 +1,309 @@
+package adapter
+
+import (
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
+	pkgbackend "github.com/joyrex2001/kubedock/pkg/backend"
+)
+
+// PublicContainer will return the public representation of given container.
+// The maps and slices are shared with the given container.
+func PublicContainer(tainr *types.Container) *pkgbackend.Container {
+	if tainr == nil {
+		return nil
+	}
+	res := &pkgbackend.Container{
+		ID:              tainr.ID,
+		ShortID:         tainr.ShortID,
+		Name:            tainr.Name,
+		Hostname:        tainr.Hostname,
+		Image:           tainr.Image,
+		Labels:          tainr.Labels,
+		Entrypoint:      tainr.Entrypoint,
+		Cmd:             tainr.Cmd,
+		Env:             tainr.Env,
+		SecretEnv:       tainr.SecretEnv,
+		Binds:           tainr.Binds,
+		PreArchives:     publicArchives(tainr.PreArchives),
+		StagedArchives:  publicArchives(tainr.StagedArchives),
+		HostIP:          tainr.HostIP,
+		ExposedPorts:    tainr.ExposedPorts,
+		ImagePorts:      tainr.ImagePorts,
+		HostPorts:       tainr.HostPorts,
+		MappedPorts:     tainr.MappedPorts,
+		Networks:        tainr.Networks,
+		IPAddresses:     tainr.IPAddresses,
+		StaticIPs:       tainr.StaticIPs,
+		NetworkAliases:  tainr.NetworkAliases,
+		EndpointAliases: tainr.EndpointAliases,
+		NetworkOwner:    tainr.NetworkOwner,
+		NetworkPod:      tainr.NetworkPod,
+		PodName:         tainr.PodName,
+		HostNetwork:     tainr.HostNetwork,
+		Sysctls:         tainr.Sysctls,
+		Links:           tainr.Links,
+		LinkEnv:         tainr.LinkEnv,
+		LinkHosts:       tainr.LinkHosts,
+		Initialized:     tainr.Initialized,
+		Running:         tainr.Running,
+		Completed:       tainr.Completed,
+		Failed:          tainr.Failed,
+		Stopped:         tainr.Stopped,
+		Killed:          tainr.Killed,
+		Error:           tainr.Error,
+		ExitStatus:      tainr.ExitStatus,
+		Tty:             tainr.Tty,
+		OpenStdin:       tainr.OpenStdin,
+		Created:         tainr.Created,
+		Started:         tainr.Started,
+		Finished:        tainr.Finished,
+		StartTimings:    tainr.StartTimings,
+	}
+	for _, m := range tainr.Mounts {
+		res.Mounts = append(res.Mounts, pkgbackend.Mount(m))
+	}
+	for _, ul := range tainr.Ulimits {
+		res.Ulimits = append(res.Ulimits, pkgbackend.Ulimit(ul))
+	}
+	for _, dev := range tainr.Devices {
+		res.Devices = append(res.Devices, pkgbackend.Device(dev))
+	}
+	return res
+}
+
+// InternalContainer will return the internal representation of given
+// container. The maps and slices are shared with the given container.
+func InternalContainer(tainr *pkgbackend.Container) *types.Container {
+	if tainr == nil {
+		return nil
+	}
+	res := &types.Container{
+		ID:              tainr.ID,
+		ShortID:         tainr.ShortID,
+		Name:            tainr.Name,
+		Hostname:        tainr.Hostname,
+		Image:           tainr.Image,
+		Labels:          tainr.Labels,
+		Entrypoint:      tainr.Entrypoint,
+		Cmd:             tainr.Cmd,
+		Env:             tainr.Env,
+		SecretEnv:       tainr.SecretEnv,
+		Binds:           tainr.Binds,
+		PreArchives:     internalArchives(tainr.PreArchives),
+		StagedArchives:  internalArchives(tainr.StagedArchives),
+		HostIP:          tainr.HostIP,
+		ExposedPorts:    tainr.ExposedPorts,
+		ImagePorts:      tainr.ImagePorts,
+		HostPorts:       tainr.HostPorts,
+		MappedPorts:     tainr.MappedPorts,
+		Networks:        tainr.Networks,
+		IPAddresses:     tainr.IPAddresses,
+		StaticIPs:       tainr.StaticIPs,
+		NetworkAliases:  tainr.NetworkAliases,
+		EndpointAliases: tainr.EndpointAliases,
+		NetworkOwner:    tainr.NetworkOwner,
+		NetworkPod:      tainr.NetworkPod,
+		PodName:         tainr.PodName,
+		HostNetwork:     tainr.HostNetwork,
+		Sysctls:         tainr.Sysctls,
+		Links:           tainr.Links,
+		LinkEnv:         tainr.LinkEnv,
+		LinkHosts:       tainr.LinkHosts,
+		Initialized:     tainr.Initialized,
+		Running:         tainr.Running,
+		Completed:       tainr.Completed,
+		Failed:          tainr.Failed,
+		Stopped:         tainr.Stopped,
+		Killed:          tainr.Killed,
+		Error:           tainr.Error,
+		ExitStatus:      tainr.ExitStatus,
+		Tty:             tainr.Tty,
+		OpenStdin:       tainr.OpenStdin,
+		Created:         tainr.Created,
+		Started:         tainr.Started,
+		Finished:        tainr.Finished,
+		StartTimings:    tainr.StartTimings,
+	}
+	for _, m := range tainr.Mounts {
+		res.Mounts = append(res.Mounts, types.Mount(m))
+	}
+	for _, ul := range tainr.Ulimits {
+		res.Ulimits = append(res.Ulimits, ulimit.Limit(ul))
+	}
+	for _, dev := range tainr.Devices {
+		res.Devices = append(res.Devices, types.Device(dev))
+	}
+	return res
+}
+
+// copyToInternal will copy the deploy details of given public container to
+// given internal container.
+func copyToInternal(dst *types.Container, src *pkgbackend.Container) {
+	dst.PodName = src.PodName
+	dst.HostIP = src.HostIP
+	dst.MappedPorts = src.MappedPorts
+	dst.LinkEnv = src.LinkEnv
+	dst.LinkHosts = src.LinkHosts
+	dst.StartTimings = src.StartTimings
+}
+
+// copyToPublic will copy the deploy details of given internal container to
+// given public container.
+func copyToPublic(dst *pkgbackend.Container, src *types.Container) {
+	dst.PodName = src.PodName
+	dst.HostIP = src.HostIP
+	dst.MappedPorts = src.MappedPorts
+	dst.LinkEnv = src.LinkEnv
+	dst.LinkHosts = src.LinkHosts
+	dst.StartTimings = src.StartTimings
+}
+
+// publicContainers will return the public representation of given
+// containers.
+func publicContainers(tainrs []*types.Container) []*pkgbackend.Container {
+	res := make([]*pkgbackend.Container, 0, len(tainrs))
+	for _, tainr := range tainrs {
+		res = append(res, PublicContainer(tainr))
+	}
+	return res
+}
+
+// internalContainers will return the internal representation of given
+// containers.
+func internalContainers(tainrs []*pkgbackend.Container) []*types.Container {
+	res := make([]*types.Container, 0, len(tainrs))
+	for _, tainr := range tainrs {
+		res = append(res, InternalContainer(tainr))
+	}
+	return res
+}
+
+// publicArchives will return the public representation of given archives.
+func publicArchives(archives []types.PreArchive) []pkgbackend.PreArchive {
+	if archives == nil {
+		return nil
+	}
+	res := make([]pkgbackend.PreArchive, 0, len(archives))
+	for _, pa := range archives {
+		res = append(res, pkgbackend.PreArchive(pa))
+	}
+	return res
+}
+
+// internalArchives will return the internal representation of given
+// archives.
+func internalArchives(archives []pkgbackend.PreArchive) []types.PreArchive {
+	if archives == nil {
+		return nil
+	}
+	res := make([]types.PreArchive, 0, len(archives))
+	for _, pa := range archives {
+		res = append(res, types.PreArchive(pa))
+	}
+	return res
+}
+
+// publicExec will return the public representation of given exec.
+func publicExec(exec *types.Exec) *pkgbackend.Exec {
+	return &pkgbackend.Exec{
+		ID:          exec.ID,
+		ContainerID: exec.ContainerID,
+		Cmd:         exec.Cmd,
+		TTY:         exec.TTY,
+		Stdin:       exec.Stdin,
+		Stdout:      exec.Stdout,
+		Stderr:      exec.Stderr,
+		DetachKeys:  exec.DetachKeys,
+		ExitCode:    exec.ExitCode,
+		Created:     exec.Created,
+	}
+}
+
+// internalExec will return the internal representation of given exec.
+func internalExec(exec *pkgbackend.Exec) *types.Exec {
+	return &types.Exec{
+		ID:          exec.ID,
+		ContainerID: exec.ContainerID,
+		Cmd:         exec.Cmd,
+		TTY:         exec.TTY,
+		Stdin:       exec.Stdin,
+		Stdout:      exec.Stdout,
+		Stderr:      exec.Stderr,
+		DetachKeys:  exec.DetachKeys,
+		ExitCode:    exec.ExitCode,
+		Created:     exec.Created,
+	}
+}
+
+// publicService will return the public representation of given service.
+func publicService(svc *types.Service) *pkgbackend.Service {
+	return &pkgbackend.Service{
+		ID:         svc.ID,
+		ShortID:    svc.ShortID,
+		Name:       svc.Name,
+		Image:      svc.Image,
+		Labels:     svc.Labels,
+		Entrypoint: svc.Entrypoint,
+		Cmd:        svc.Cmd,
+		Env:        svc.Env,
+		Replicas:   svc.Replicas,
+		Created:    svc.Created,
+		Updated:    svc.Updated,
+	}
+}
+
+// internalService will return the internal representation of given
+// service.
+func internalService(svc *pkgbackend.Service) *types.Service {
+	return &types.Service{
+		ID:         svc.ID,
+		ShortID:    svc.ShortID,
+		Name:       svc.Name,
+		Image:      svc.Image,
+		Labels:     svc.Labels,
+		Entrypoint: svc.Entrypoint,
+		Cmd:        svc.Cmd,
+		Env:        svc.Env,
+		Replicas:   svc.Replicas,
+		Created:    svc.Created,
+		Updated:    svc.Updated,
+	}
+}
+
+// publicStats will return the public representation of given stats.
+func publicStats(stats map[string]*backend.ContainerStats) map[string]*pkgbackend.ContainerStats {
+	res := map[string]*pkgbackend.ContainerStats{}
+	for id, st := range stats {
+		res[id] = (*pkgbackend.ContainerStats)(st)
+	}
+	return res
+}
+
+// internalStats will return the internal representation of given stats.
+func internalStats(stats map[string]*pkgbackend.ContainerStats) map[string]*backend.ContainerStats {
+	res := map[string]*backend.ContainerStats{}
+	for id, st := range stats {
+		res[id] = (*backend.ContainerStats)(st)
+	}
+	return res
+}
+
+// publicRewrites will return the public representation of given rules.
+func publicRewrites(rules []image.RewriteRule) []pkgbackend.RewriteRule {
+	res := make([]pkgbackend.RewriteRule, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, pkgbackend.RewriteRule(rule))
+	}
+	return res
+}
+
+// internalRewrites will return the internal representation of given rules.
+func internalRewrites(rules []pkgbackend.RewriteRule) []image.RewriteRule {
+	res := make([]image.RewriteRule, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, image.RewriteRule(rule))
+	}
+	return res
+}
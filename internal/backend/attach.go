@@ -8,22 +8,28 @@ import (
 	"github.com/joyrex2001/kubedock/internal/model/types"
 	"github.com/joyrex2001/kubedock/internal/util/attach"
 	"github.com/joyrex2001/kubedock/internal/util/ioproxy"
+	"github.com/joyrex2001/kubedock/internal/util/stdtag"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // AttachContainer will attach to a container and stream stdin/stdout/stderr.
 func (in *instance) AttachContainer(tainr *types.Container, stdin io.Reader, stdout io.Writer, stderr io.Writer, tty bool) error {
+	if err := in.CheckFeature(FeatureAttach); err != nil {
+		return err
+	}
 	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), v1.GetOptions{})
 	if err != nil {
 		return err
 	}
 
 	req := attach.Request{
-		Client:     in.cli,
-		RestConfig: in.cfg,
-		Pod:        *pod,
-		Container:  "main",
-		TTY:        tty,
+		Client:      in.cli,
+		RestConfig:  in.cfg,
+		Pod:         *pod,
+		Container:   tainr.GetContainerName(),
+		TTY:         tty,
+		IdleTimeout: in.execIdleTimeout,
+		MaxDuration: in.execMaxDuration,
 	}
 
 	if stdin != nil {
@@ -34,6 +40,9 @@ func (in *instance) AttachContainer(tainr *types.Container, stdin io.Reader, std
 	if tty {
 		req.Stdout = stdout
 		req.Stderr = io.Discard
+		sizes := tainr.TerminalSizes().Add()
+		defer tainr.TerminalSizes().Remove(sizes)
+		req.TerminalSizeQueue = sizes
 	} else {
 		lock := sync.Mutex{}
 		if stdout != nil {
@@ -46,6 +55,12 @@ func (in *instance) AttachContainer(tainr *types.Container, stdin io.Reader, std
 			req.Stderr = iop
 			defer iop.Flush()
 		}
+		if tainr.SeparateStderr() && req.Stderr != nil {
+			// strip the tags that were added by the stdtag wrapper
+			demux := stdtag.NewDemuxer(req.Stderr, req.Stderr, false)
+			defer demux.Flush()
+			req.Stderr = demux
+		}
 	}
 
 	return attach.RemoteAttach(req)
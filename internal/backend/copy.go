@@ -1,23 +1,52 @@
 package backend
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 
 	"github.com/joyrex2001/kubedock/internal/model/types"
 	"github.com/joyrex2001/kubedock/internal/util/exec"
+	"github.com/joyrex2001/kubedock/internal/util/stringid"
+)
+
+const (
+	// archiveHelperTimeout is the maximum time, in seconds, an archive
+	// helper container is kept alive.
+	archiveHelperTimeout = 600
+	// dataVolumeName is the name of the volume of the data volume claim of
+	// a container in its pod.
+	dataVolumeName = "kubedock-data"
+	// dataVolumesFolder is the folder on the data volume claim that
+	// contains the volume folders of the container.
+	dataVolumesFolder = "volumes"
+	// dataArchivesFolder is the folder on the data volume claim that
+	// contains the staged archives of the container.
+	dataArchivesFolder = "archives"
+	// kubedockBinary is the location of the kubedock binary in the init
+	// image.
+	kubedockBinary = "/usr/local/bin/kubedock"
 )
 
 // CopyToContainer will copy given (tar) archive to given path of the container.
 func (in *instance) CopyToContainer(tainr *types.Container, reader io.Reader, target string, compressed bool) error {
+	if err := in.CheckFeature(FeatureExec); err != nil {
+		return err
+	}
 	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
 	if err != nil {
 		return err
@@ -38,7 +67,7 @@ func (in *instance) CopyToContainer(tainr *types.Container, reader io.Reader, ta
 		Client:     in.cli,
 		RestConfig: in.cfg,
 		Pod:        *pod,
-		Container:  "main",
+		Container:  tainr.GetContainerName(),
 		Cmd:        []string{"tar", "-x" + cmpflag + "f", "-", "-C", target},
 		Stdin:      reader,
 	})
@@ -48,6 +77,9 @@ func (in *instance) CopyToContainer(tainr *types.Container, reader io.Reader, ta
 // contents as a tar archive through the given writer. Note that this requires
 // tar to be present on the container.
 func (in *instance) CopyFromContainer(tainr *types.Container, target string, writer io.Writer) error {
+	if err := in.CheckFeature(FeatureExec); err != nil {
+		return err
+	}
 	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
 	if err != nil {
 		return err
@@ -59,60 +91,139 @@ func (in *instance) CopyFromContainer(tainr *types.Container, target string, wri
 		Client:     in.cli,
 		RestConfig: in.cfg,
 		Pod:        *pod,
-		Container:  "main",
+		Container:  tainr.GetContainerName(),
 		Cmd:        []string{"tar", "-cf", "-", "-C", path.Dir(target), path.Base(target)},
 		Stdout:     writer,
 	})
 }
 
-// GetFileModeInContainer will return the file mode (directory or file) of a given path
-// inside the container.
-func (in *instance) GetFileModeInContainer(tainr *types.Container, target string) (fs.FileMode, error) {
+// FileStat contains the details of a file in a container.
+type FileStat struct {
+	// Name is the base name of the file
+	Name string
+	// Size is the size of the file in bytes
+	Size int64
+	// Mode contains the file mode, including the type of the file
+	Mode fs.FileMode
+	// ModTime is the last modification time of the file
+	ModTime time.Time
+	// LinkTarget contains the target if the file is a symbolic link
+	LinkTarget string
+}
+
+// statScript is the script that is used to stat a file in a container. The
+// path is passed as an argument, to prevent injection attacks.
+const statScript = `if [ -e "$1" ] || [ -L "$1" ]; then stat -c "%s %f %Y" "$1" && readlink "$1"; true; else echo missing; fi`
+
+// GetFileStatInContainer will return the details of a given path inside
+// the container. If the path doesn't exist, an error that wraps
+// fs.ErrNotExist is returned.
+func (in *instance) GetFileStatInContainer(tainr *types.Container, target string) (*FileStat, error) {
+	if err := in.CheckFeature(FeatureExec); err != nil {
+		return nil, err
+	}
 	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	var b bytes.Buffer
-	writer := bufio.NewWriter(&b)
-
 	err = exec.RemoteCmd(exec.Request{
 		Client:     in.cli,
 		RestConfig: in.cfg,
 		Pod:        *pod,
-		Container:  "main",
-		Cmd:        []string{"sh", "-c", "if [ -d \"" + sanitizeFilename(target) + "\" ]; then echo folder; else echo file; fi"},
-		Stdout:     writer,
+		Container:  tainr.GetContainerName(),
+		Cmd:        []string{"sh", "-c", statScript, "sh", target},
+		Stdout:     &b,
 	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	mode := fs.FileMode(fs.ModePerm)
-	if strings.Contains(string(b.Bytes()), "folder") {
-		mode |= fs.ModeDir
+	return parseFileStat(target, b.String())
+}
+
+// parseFileStat will parse the output of the stat script for given path.
+func parseFileStat(target, out string) (*FileStat, error) {
+	lines := strings.SplitN(strings.TrimSpace(out), "\n", 2)
+	if lines[0] == "missing" {
+		return nil, fmt.Errorf("%s: %w", target, fs.ErrNotExist)
 	}
+	flds := strings.Fields(lines[0])
+	if len(flds) != 3 {
+		return nil, fmt.Errorf("unexpected stat output for %s: %s", target, out)
+	}
+	size, err := strconv.ParseInt(flds[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size in stat output for %s: %w", target, err)
+	}
+	mode, err := strconv.ParseUint(flds[1], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode in stat output for %s: %w", target, err)
+	}
+	mtime, err := strconv.ParseInt(flds[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mtime in stat output for %s: %w", target, err)
+	}
+	stat := &FileStat{
+		Name:    path.Base(target),
+		Size:    size,
+		Mode:    fileModeFromUnix(uint32(mode)),
+		ModTime: time.Unix(mtime, 0).UTC(),
+	}
+	if len(lines) > 1 && stat.Mode&fs.ModeSymlink != 0 {
+		stat.LinkTarget = strings.TrimSpace(lines[1])
+	}
+	return stat, nil
+}
 
-	return mode, nil
+// fileModeFromUnix will convert a unix st_mode to a fs.FileMode.
+func fileModeFromUnix(mode uint32) fs.FileMode {
+	res := fs.FileMode(mode & 0777)
+	switch mode & 0170000 {
+	case 0040000:
+		res |= fs.ModeDir
+	case 0120000:
+		res |= fs.ModeSymlink
+	case 0020000:
+		res |= fs.ModeDevice | fs.ModeCharDevice
+	case 0060000:
+		res |= fs.ModeDevice
+	case 0010000:
+		res |= fs.ModeNamedPipe
+	case 0140000:
+		res |= fs.ModeSocket
+	}
+	if mode&04000 != 0 {
+		res |= fs.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		res |= fs.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		res |= fs.ModeSticky
+	}
+	return res
 }
 
 // FileExistsInContainer will check if the file exists in the container.
 func (in *instance) FileExistsInContainer(tainr *types.Container, target string) (bool, error) {
+	if err := in.CheckFeature(FeatureExec); err != nil {
+		return false, err
+	}
 	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
 	if err != nil {
 		return false, err
 	}
 
 	var b bytes.Buffer
-	writer := bufio.NewWriter(&b)
-
 	err = exec.RemoteCmd(exec.Request{
 		Client:     in.cli,
 		RestConfig: in.cfg,
 		Pod:        *pod,
-		Container:  "main",
+		Container:  tainr.GetContainerName(),
 		Cmd:        []string{"sh", "-c", "if [ -e \"" + sanitizeFilename(target) + "\" ]; then echo true; else echo false; fi"},
-		Stdout:     writer,
+		Stdout:     &b,
 	})
 
 	if err != nil {
@@ -127,6 +238,262 @@ func (in *instance) FileExistsInContainer(tainr *types.Container, target string)
 	return exists, nil
 }
 
+// StartArchiveHelper will start a short-lived helper container for the
+// given (not running) container, so archive operations can be performed on
+// containers that are not started, or have exited already. The helper runs
+// the init image, and mounts the volumes of the container and its staged
+// archives, which are kept on the data volume claim of the container. The
+// staged archives are extracted in the helper as well. Note that only the
+// volumes and the staged archives of the container are available in the
+// helper, not the filesystem of its image. The helper should be removed
+// with DeleteContainer when done.
+func (in *instance) StartArchiveHelper(tainr *types.Container) (*types.Container, error) {
+	if !in.archiveHelper {
+		return nil, fmt.Errorf("archive helper is not enabled")
+	}
+	if err := in.CheckFeature(FeatureArchiveHelper); err != nil {
+		return nil, err
+	}
+	pulpol, err := tainr.GetImagePullPolicyFor(in.initImage)
+	if err != nil {
+		return nil, err
+	}
+
+	id := stringid.GenerateRandomID()
+	helper := &types.Container{
+		ID:      id,
+		ShortID: stringid.TruncateID(id),
+		Name:    "archive-" + tainr.ShortID,
+		Image:   in.initImage,
+		Labels:  map[string]string{types.LabelReadiness: types.ReadinessRunning},
+		Created: time.Now(),
+	}
+	helper.PodName = helper.GetPodName()
+
+	pod := in.podTemplate.DeepCopy()
+	pod.ObjectMeta.Name = helper.PodName
+	pod.ObjectMeta.Namespace = in.namespace
+	pod.ObjectMeta.Labels = in.getLabels(pod.ObjectMeta.Labels, helper)
+	pod.ObjectMeta.Annotations = in.getAnnotations(pod.ObjectMeta.Annotations, helper)
+	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	seccontext, err := tainr.GetPodSecurityContext(pod.Spec.SecurityContext)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.SecurityContext = seccontext
+
+	if err := in.addDataVolume(tainr, pod); err != nil {
+		return nil, err
+	}
+
+	// a volume claim can only be attached to a single node, run the helper
+	// on the node of the (exited) pod of the container, if any
+	if cur, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{}); err == nil {
+		pod.Spec.NodeName = cur.Spec.NodeName
+	}
+
+	cmd := []string{kubedockBinary, "unpack"}
+	for _, pa := range tainr.StagedArchives {
+		cmd = append(cmd, path.Join(stagedArchivePath, pa.File)+"="+pa.Path)
+	}
+	cmd = append(cmd, "--", "sleep", strconv.Itoa(archiveHelperTimeout))
+
+	container := in.containerTemplate
+	container.Name = helper.GetContainerName()
+	container.Image = in.initImage
+	container.ImagePullPolicy = pulpol
+	container.Command = cmd
+	container.VolumeMounts = append(in.getDataVolumeMounts(tainr), corev1.VolumeMount{
+		Name:      dataVolumeName,
+		MountPath: stagedArchivePath,
+		SubPath:   dataArchivesFolder,
+	})
+	pod.Spec.Containers = []corev1.Container{container}
+
+	in.applySecurityProfile(pod)
+
+	klog.Infof("starting archive helper %s for %s", helper.ShortID, tainr.ShortID)
+	if _, err := in.cli.CoreV1().Pods(in.namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("error starting archive helper: %w", err)
+	}
+	if _, err := in.waitReadyState(context.Background(), helper, in.timeOut); err != nil {
+		if derr := in.DeleteContainer(helper); derr != nil {
+			klog.Warningf("error removing archive helper %s: %s", helper.ShortID, derr)
+		}
+		return nil, fmt.Errorf("error starting archive helper: %w", err)
+	}
+	return helper, nil
+}
+
+// StageArchive will store the given (tar) archive on the data volume claim
+// of a container, using given archive helper of that container. The archive
+// is streamed to the volume, and the name of the staged archive is returned.
+// Staged archives are extracted before the container command is started.
+func (in *instance) StageArchive(helper *types.Container, reader io.Reader) (string, error) {
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), helper.GetPodName(), metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	name := "archive-" + stringid.TruncateID(stringid.GenerateRandomID())
+	klog.Infof("staging archive %s via %s", name, helper.ShortID)
+	return name, exec.RemoteCmd(exec.Request{
+		Client:     in.cli,
+		RestConfig: in.cfg,
+		Pod:        *pod,
+		Container:  helper.GetContainerName(),
+		Cmd:        []string{"sh", "-c", `cat > "$0"`, path.Join(stagedArchivePath, name)},
+		Stdin:      reader,
+	})
+}
+
+// getDataVolumeClaimName will return the name of the persistent volume claim
+// that contains the volumes and staged archives of given container. The name
+// is suffixed with a hash of the full container id, so it won't collide with
+// claims of other containers that happen to share the same short id.
+func (in *instance) getDataVolumeClaimName(tainr *types.Container) string {
+	sum := sha256.Sum256([]byte(tainr.ID))
+	return tainr.ShortID + "-data-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// VolumeClaimConflictError is returned when the data volume claim of a
+// container can't be created, because a claim with the same name exists that
+// is not owned by that container.
+type VolumeClaimConflictError struct {
+	Name  string
+	Owner string
+}
+
+// Error will return a description of the conflicting volume claim.
+func (e *VolumeClaimConflictError) Error() string {
+	if e.Owner == "" {
+		return fmt.Sprintf("persistentvolumeclaim %s already exists and is not managed by kubedock", e.Name)
+	}
+	return fmt.Sprintf("persistentvolumeclaim %s is already in use by container %s", e.Name, e.Owner)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *VolumeClaimConflictError) HTTPStatus() int {
+	return http.StatusConflict
+}
+
+// verifyDataVolumeClaim will check if the existing claim with given name is
+// the data volume claim of given container, so claims of others are never
+// adopted. Claims that are not managed by kubedock, or that belong to another
+// container, result in a VolumeClaimConflictError.
+func (in *instance) verifyDataVolumeClaim(tainr *types.Container, name string) error {
+	cur, err := in.cli.CoreV1().PersistentVolumeClaims(in.namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if cur.Labels["kubedock"] != "true" {
+		return &VolumeClaimConflictError{Name: name}
+	}
+	if owner := cur.Labels["kubedock.containerid"]; owner != tainr.ShortID {
+		return &VolumeClaimConflictError{Name: name, Owner: owner}
+	}
+	return nil
+}
+
+// addDataVolume will add the data volume claim of given container to the
+// given pod, and creates the claim if it doesn't exist yet.
+func (in *instance) addDataVolume(tainr *types.Container, pod *corev1.Pod) error {
+	for _, vol := range pod.Spec.Volumes {
+		if vol.Name == dataVolumeName {
+			return nil
+		}
+	}
+	if err := in.CheckFeature(FeatureArchiveHelper); err != nil {
+		return err
+	}
+	mode, err := in.getDataVolumeAccessMode(tainr)
+	if err != nil {
+		return err
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        in.getDataVolumeClaimName(tainr),
+			Namespace:   in.namespace,
+			Labels:      in.getLabels(nil, tainr),
+			Annotations: in.getAnnotations(nil, tainr),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{mode},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: in.archiveSize},
+			},
+		},
+	}
+	if in.archiveClass != "" {
+		pvc.Spec.StorageClassName = &in.archiveClass
+	}
+	_, err = in.cli.CoreV1().PersistentVolumeClaims(in.namespace).Create(context.Background(), pvc, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		err = in.verifyDataVolumeClaim(tainr, pvc.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating data volume of %s: %w", tainr.ShortID, err)
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: dataVolumeName,
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: pvc.ObjectMeta.Name,
+		}},
+	})
+	return nil
+}
+
+// getDataVolumeAccessMode will return the access mode of the data volume
+// claim of given container, which can be overridden per container with the
+// volume-access-mode label.
+func (in *instance) getDataVolumeAccessMode(tainr *types.Container) (corev1.PersistentVolumeAccessMode, error) {
+	if mode, ok := tainr.Labels[types.LabelVolumeAccessMode]; ok {
+		return parseAccessMode(mode)
+	}
+	if in.archiveMode == "" {
+		return corev1.ReadWriteOnce, nil
+	}
+	return in.archiveMode, nil
+}
+
+// parseAccessMode will parse given access mode of a data volume claim, which
+// is either the name of the mode, or its abbreviation as used by kubectl
+// (e.g. RWOP for ReadWriteOncePod). An empty mode is ReadWriteOnce. As
+// kubedock writes the volumes and archives to the claim, ReadOnlyMany is
+// not supported.
+func parseAccessMode(mode string) (corev1.PersistentVolumeAccessMode, error) {
+	switch strings.ToLower(mode) {
+	case "", "readwriteonce", "rwo":
+		return corev1.ReadWriteOnce, nil
+	case "readwriteoncepod", "rwop":
+		return corev1.ReadWriteOncePod, nil
+	case "readwritemany", "rwx":
+		return corev1.ReadWriteMany, nil
+	}
+	return "", fmt.Errorf("invalid volume access mode %s", mode)
+}
+
+// getDataVolumeMounts will return the mounts of the volume folders of given
+// container, which are sub paths of the data volume claim of the container.
+func (in *instance) getDataVolumeMounts(tainr *types.Container) []corev1.VolumeMount {
+	mounts := []corev1.VolumeMount{}
+	for dst := range tainr.GetVolumeFolders() {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      dataVolumeName,
+			MountPath: dst,
+			SubPath:   path.Join(dataVolumesFolder, in.toKubernetesName(dst)),
+		})
+	}
+	return mounts
+}
+
+// DeleteContainerVolumes will delete the data volume claim of given
+// container, which should be done when the container is removed.
+func (in *instance) DeleteContainerVolumes(tainr *types.Container) error {
+	return in.deleteVolumeClaims("kubedock.containerid=" + tainr.ShortID)
+}
+
 // sanitizeFilename will clean up unwanted characters from the filename to
 // prevent injection attacks.
 func sanitizeFilename(file string) string {
@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestParseFileStat(t *testing.T) {
+	tests := []struct {
+		path string
+		in   string
+		out  *FileStat
+		err  error
+	}{
+		{
+			path: "/etc/hosts",
+			in:   "174 81a4 1700000000\n",
+			out:  &FileStat{Name: "hosts", Size: 174, Mode: 0644, ModTime: time.Unix(1700000000, 0).UTC()},
+		},
+		{
+			path: "/tmp",
+			in:   "4096 43ff 1700000000\n",
+			out:  &FileStat{Name: "tmp", Size: 4096, Mode: fs.ModeDir | fs.ModeSticky | 0777, ModTime: time.Unix(1700000000, 0).UTC()},
+		},
+		{
+			path: "/bin/sh",
+			in:   "7 a1ff 1700000000\nbusybox\n",
+			out:  &FileStat{Name: "sh", Size: 7, Mode: fs.ModeSymlink | 0777, ModTime: time.Unix(1700000000, 0).UTC(), LinkTarget: "busybox"},
+		},
+		{path: "/missing", in: "missing\n", err: fs.ErrNotExist},
+		{path: "/broken", in: "sh: stat: not found\n", err: errors.New("unexpected")},
+	}
+	for i, tst := range tests {
+		res, err := parseFileStat(tst.path, tst.in)
+		if (err != nil) != (tst.err != nil) {
+			t.Errorf("failed test %d - expected error %v, but got %v", i, tst.err, err)
+			continue
+		}
+		if tst.err == fs.ErrNotExist && !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("failed test %d - expected not exist error, but got %v", i, err)
+		}
+		if !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestParseAccessMode(t *testing.T) {
+	tests := []struct {
+		in  string
+		out corev1.PersistentVolumeAccessMode
+		err bool
+	}{
+		{in: "", out: corev1.ReadWriteOnce},
+		{in: "ReadWriteOnce", out: corev1.ReadWriteOnce},
+		{in: "RWOP", out: corev1.ReadWriteOncePod},
+		{in: "readwriteoncepod", out: corev1.ReadWriteOncePod},
+		{in: "rwx", out: corev1.ReadWriteMany},
+		{in: "ReadOnlyMany", err: true},
+		{in: "sometimes", err: true},
+	}
+	for i, tst := range tests {
+		res, err := parseAccessMode(tst.in)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
+
+func TestGetDataVolumeAccessMode(t *testing.T) {
+	tests := []struct {
+		kub   *instance
+		tainr *types.Container
+		out   corev1.PersistentVolumeAccessMode
+		err   bool
+	}{
+		{kub: &instance{}, tainr: &types.Container{}, out: corev1.ReadWriteOnce},
+		{kub: &instance{archiveMode: corev1.ReadWriteMany}, tainr: &types.Container{}, out: corev1.ReadWriteMany},
+		{
+			kub:   &instance{archiveMode: corev1.ReadWriteMany},
+			tainr: &types.Container{Labels: map[string]string{types.LabelVolumeAccessMode: "ReadWriteOncePod"}},
+			out:   corev1.ReadWriteOncePod,
+		},
+		{
+			kub:   &instance{},
+			tainr: &types.Container{Labels: map[string]string{types.LabelVolumeAccessMode: "ROX"}},
+			err:   true,
+		},
+	}
+	for i, tst := range tests {
+		res, err := tst.kub.getDataVolumeAccessMode(tst.tainr)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
@@ -5,13 +5,20 @@ import (
 	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/klog"
 
+	"github.com/joyrex2001/kubedock/internal/config"
 	"github.com/joyrex2001/kubedock/internal/model/types"
 )
 
+// retainUntilAnnotation is the annotation on pods of failed containers that
+// contains the time until the pod is retained.
+const retainUntilAnnotation = "kubedock.retain/until"
+
 // DeleteAll will delete all resources that kubedock=true
 func (in *instance) DeleteAll() error {
 	ok := true
@@ -23,10 +30,22 @@ func (in *instance) DeleteAll() error {
 		klog.Errorf("error deleting configmaps: %s", err)
 		ok = false
 	}
+	if err := in.deleteDeployments("kubedock=true"); err != nil {
+		klog.Errorf("error deleting deployments: %s", err)
+		ok = false
+	}
 	if err := in.deletePods("kubedock=true"); err != nil {
 		klog.Errorf("error deleting pods: %s", err)
 		ok = false
 	}
+	if err := in.deleteDaemonSets("kubedock=true"); err != nil {
+		klog.Errorf("error deleting daemonsets: %s", err)
+		ok = false
+	}
+	if err := in.deleteVolumeClaims("kubedock=true"); err != nil {
+		klog.Errorf("error deleting persistentvolumeclaims: %s", err)
+		ok = false
+	}
 	if !ok {
 		return fmt.Errorf("failed deleting all containers")
 	}
@@ -44,16 +63,55 @@ func (in *instance) DeleteWithKubedockID(id string) error {
 		klog.Errorf("error deleting configmaps: %s", err)
 		ok = false
 	}
+	if err := in.deleteDeployments("kubedock.id=" + id); err != nil {
+		klog.Errorf("error deleting deployments: %s", err)
+		ok = false
+	}
 	if err := in.deletePods("kubedock.id=" + id); err != nil {
 		klog.Errorf("error deleting pods: %s", err)
 		ok = false
 	}
+	if err := in.deleteDaemonSets("kubedock.id=" + id); err != nil {
+		klog.Errorf("error deleting daemonsets: %s", err)
+		ok = false
+	}
+	if err := in.deleteVolumeClaims("kubedock.id=" + id); err != nil {
+		klog.Errorf("error deleting persistentvolumeclaims: %s", err)
+		ok = false
+	}
 	if !ok {
 		return fmt.Errorf("failed deleting container %s", id)
 	}
 	return nil
 }
 
+// DeleteProject will delete all resources of this kubedock instance that
+// belong to given docker compose project.
+func (in *instance) DeleteProject(project string) error {
+	sel := "kubedock.id=" + config.InstanceID + ",kubedock.project=" + in.toKubernetesValue(project)
+	ok := true
+	if err := in.deleteServices(sel); err != nil {
+		klog.Errorf("error deleting services: %s", err)
+		ok = false
+	}
+	if err := in.deleteConfigMaps(sel); err != nil {
+		klog.Errorf("error deleting configmaps: %s", err)
+		ok = false
+	}
+	if err := in.deletePods(sel); err != nil {
+		klog.Errorf("error deleting pods: %s", err)
+		ok = false
+	}
+	if err := in.deleteVolumeClaims(sel); err != nil {
+		klog.Errorf("error deleting persistentvolumeclaims: %s", err)
+		ok = false
+	}
+	if !ok {
+		return fmt.Errorf("failed deleting project %s", project)
+	}
+	return nil
+}
+
 // DeleteContainer will delete given container object in kubernetes.
 func (in *instance) DeleteContainer(tainr *types.Container) error {
 	ok := true
@@ -69,12 +127,132 @@ func (in *instance) DeleteContainer(tainr *types.Container) error {
 		klog.Errorf("error deleting pods: %s", err)
 		ok = false
 	}
+	if err := in.deleteComposeService(tainr); err != nil {
+		klog.Errorf("error deleting compose service: %s", err)
+		ok = false
+	}
 	if !ok {
 		return fmt.Errorf("failed deleting container %s", tainr.ShortID)
 	}
 	return nil
 }
 
+// deleteComposeService will delete the service of the docker compose
+// service of given container, if no other containers of that compose
+// service exist anymore.
+func (in *instance) deleteComposeService(tainr *types.Container) error {
+	project, service := tainr.GetComposeProject(), tainr.GetComposeService()
+	if project == "" || service == "" {
+		return nil
+	}
+	sel := "kubedock.id=" + config.InstanceID + ",kubedock.project=" + in.toKubernetesValue(project) + ",kubedock.service=" + in.toKubernetesValue(service)
+	pods, err := in.cli.CoreV1().Pods(in.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: sel,
+	})
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if pod.ObjectMeta.Labels["kubedock.containerid"] != tainr.ShortID && pod.ObjectMeta.DeletionTimestamp == nil {
+			return nil
+		}
+	}
+	return in.deleteServices(sel + ",!kubedock.containerid")
+}
+
+// RetainContainer will keep the pod of given container if the container is
+// labelled with retain-on-failure and exited non-zero, so its logs can be
+// inspected after the container has been removed. The services and
+// configmaps of the container are deleted, and the pod is annotated with the
+// time until it is retained. The pod is relabelled, so it's no longer found
+// as the pod of the container; it's deleted when the container is started
+// again, as the pod name is reused. It returns true if the pod has been
+// retained, in which case the container should not be deleted.
+func (in *instance) RetainContainer(tainr *types.Container) (bool, error) {
+	if in.retainFailed <= 0 || !tainr.RetainOnFailure() {
+		return false, nil
+	}
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+	if err != nil || !isPodFailed(pod, tainr.GetContainerName()) {
+		return false, nil
+	}
+	until := time.Now().Add(in.retainFailed).Format(time.RFC3339)
+	klog.Infof("retaining failed container %s until %s", tainr.ShortID, until)
+	if err := in.deleteServices("kubedock.containerid=" + tainr.ShortID); err != nil {
+		return false, err
+	}
+	if err := in.deleteConfigMaps("kubedock.containerid=" + tainr.ShortID); err != nil {
+		return false, err
+	}
+	if pod.ObjectMeta.Annotations == nil {
+		pod.ObjectMeta.Annotations = map[string]string{}
+	}
+	pod.ObjectMeta.Annotations[retainUntilAnnotation] = until
+	if pod.ObjectMeta.Labels == nil {
+		pod.ObjectMeta.Labels = map[string]string{}
+	}
+	delete(pod.ObjectMeta.Labels, "kubedock.containerid")
+	pod.ObjectMeta.Labels["kubedock.retained"] = tainr.ShortID
+	if _, err := in.cli.CoreV1().Pods(in.namespace).Update(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteRetainedPod will delete the pod with given name if it's the retained
+// pod of a failed container, and waits until it's gone, so the pod name can
+// be reused when the container is started again. It returns true if a
+// retained pod was deleted.
+func (in *instance) deleteRetainedPod(ctx context.Context, name string, wait int) (bool, error) {
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, nil
+	}
+	if _, ok := pod.ObjectMeta.Annotations[retainUntilAnnotation]; !ok {
+		return false, nil
+	}
+	klog.Infof("deleting retained pod %s, container is started again", name)
+	grace := int64(0)
+	if err := in.cli.CoreV1().Pods(in.namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &grace}); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	for end := time.Now().Add(time.Duration(wait) * time.Second); time.Now().Before(end); {
+		if _, err := in.cli.CoreV1().Pods(in.namespace).Get(ctx, name, metav1.GetOptions{}); errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err := sleep(ctx, time.Second); err != nil {
+			return false, err
+		}
+	}
+	return false, fmt.Errorf("timeout deleting retained pod %s", name)
+}
+
+// isPodFailed will return true if the container with given name in given
+// pod terminated with a non-zero exit code, or if the pod failed.
+func isPodFailed(pod *corev1.Pod, name string) bool {
+	if pod.Status.Phase == corev1.PodFailed {
+		return true
+	}
+	if status := getContainerStatus(pod, name); status != nil {
+		for _, term := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+			if term != nil && term.ExitCode != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRetained will check if given resource metadata is annotated to be
+// retained until a time that has not passed yet.
+func (in *instance) isRetained(met metav1.ObjectMeta) bool {
+	until, err := time.Parse(time.RFC3339, met.Annotations[retainUntilAnnotation])
+	if err != nil {
+		return false
+	}
+	return time.Now().Before(until)
+}
+
 // DeleteOlderThan will delete all kubedock created resources older
 // than the given keepmax duration.
 func (in *instance) DeleteOlderThan(keepmax time.Duration) error {
@@ -87,6 +265,9 @@ func (in *instance) DeleteOlderThan(keepmax time.Duration) error {
 	if err := in.DeletePodsOlderThan(keepmax); err != nil {
 		return err
 	}
+	if err := in.DeleteDeploymentsOlderThan(keepmax); err != nil {
+		return err
+	}
 	return in.DeleteServicesOlderThan(keepmax)
 }
 
@@ -100,13 +281,18 @@ func (in *instance) DeleteContainersOlderThan(keepmax time.Duration) error {
 		return err
 	}
 	for _, pod := range pods.Items {
-		if in.isOlderThan(pod.ObjectMeta, keepmax) {
+		if in.isOlderThan(pod.ObjectMeta, keepmax) && !in.isRetained(pod.ObjectMeta) {
 			klog.V(3).Infof("deleting pod: %s", pod.Name)
-			if err := in.deleteServices("kubedock.containerid=" + pod.Name); err != nil {
-				klog.Errorf("error deleting services: %s", err)
-			}
-			if err := in.deleteConfigMaps("kubedock.containerid=" + pod.Name); err != nil {
-				klog.Errorf("error deleting configmaps: %s", err)
+			if id := pod.Labels["kubedock.containerid"]; id != "" {
+				if err := in.deleteServices("kubedock.containerid=" + id); err != nil {
+					klog.Errorf("error deleting services: %s", err)
+				}
+				if err := in.deleteConfigMaps("kubedock.containerid=" + id); err != nil {
+					klog.Errorf("error deleting configmaps: %s", err)
+				}
+				if err := in.deleteVolumeClaims("kubedock.containerid=" + id); err != nil {
+					klog.Errorf("error deleting persistentvolumeclaims: %s", err)
+				}
 			}
 			if err := in.cli.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{}); err != nil {
 				return err
@@ -119,6 +305,9 @@ func (in *instance) DeleteContainersOlderThan(keepmax time.Duration) error {
 // DeleteServicesOlderThan will delete services than are orchestrated
 // by kubedock and are older than the given keepmax duration.
 func (in *instance) DeleteServicesOlderThan(keepmax time.Duration) error {
+	if err := in.CheckFeature(FeatureServices); err != nil {
+		return nil
+	}
 	svcs, err := in.cli.CoreV1().Services(in.namespace).List(context.Background(), metav1.ListOptions{
 		LabelSelector: "kubedock=true",
 	})
@@ -136,6 +325,30 @@ func (in *instance) DeleteServicesOlderThan(keepmax time.Duration) error {
 	return nil
 }
 
+// DeleteDeploymentsOlderThan will delete deployments of services that are
+// orchestrated by kubedock and are older than the given keepmax duration.
+func (in *instance) DeleteDeploymentsOlderThan(keepmax time.Duration) error {
+	deps, err := in.cli.AppsV1().Deployments(in.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "kubedock=true",
+	})
+	if errors.IsForbidden(err) {
+		klog.V(3).Infof("not allowed to list deployments: %s", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, dep := range deps.Items {
+		if in.isOlderThan(dep.ObjectMeta, keepmax) {
+			klog.V(3).Infof("deleting deployment: %s", dep.Name)
+			if err := in.cli.AppsV1().Deployments(dep.Namespace).Delete(context.Background(), dep.Name, metav1.DeleteOptions{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // DeleteConfigMapsOlderThan will delete configmaps than are orchestrated
 // by kubedock and are older than the given keepmax duration.
 func (in *instance) DeleteConfigMapsOlderThan(keepmax time.Duration) error {
@@ -166,7 +379,7 @@ func (in *instance) DeletePodsOlderThan(keepmax time.Duration) error {
 		return err
 	}
 	for _, pod := range pods.Items {
-		if in.isOlderThan(pod.ObjectMeta, keepmax) {
+		if in.isOlderThan(pod.ObjectMeta, keepmax) && !in.isRetained(pod.ObjectMeta) {
 			klog.V(3).Infof("deleting pod: %s", pod.Name)
 			background := metav1.DeletePropagationBackground
 			if err := in.cli.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{
@@ -191,20 +404,13 @@ func (in *instance) isOlderThan(met metav1.ObjectMeta, keepmax time.Duration) bo
 }
 
 // deleteServices will delete k8s service resources which match the
-// given label selector.
+// given label selector. Services of other containers that are shadowed by
+// these services are restored instead (see releaseServices).
 func (in *instance) deleteServices(selector string) error {
-	svcs, err := in.cli.CoreV1().Services(in.namespace).List(context.Background(), metav1.ListOptions{
-		LabelSelector: selector,
-	})
-	if err != nil {
-		return err
-	}
-	for _, svc := range svcs.Items {
-		if err := in.cli.CoreV1().Services(svc.Namespace).Delete(context.Background(), svc.Name, metav1.DeleteOptions{}); err != nil {
-			return err
-		}
+	if err := in.CheckFeature(FeatureServices); err != nil {
+		return nil
 	}
-	return nil
+	return in.releaseServices(selector, nil)
 }
 
 // deleteConfigMaps will delete k8s configmap resources which match the
@@ -241,11 +447,93 @@ func (in *instance) deletePods(selector string) error {
 	return nil
 }
 
+// deleteDaemonSets will delete k8s daemonset resources which match the
+// given label selector.
+func (in *instance) deleteDaemonSets(selector string) error {
+	if err := in.CheckFeature(FeaturePrewarm); err != nil {
+		return nil
+	}
+	dss, err := in.cli.AppsV1().DaemonSets(in.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if errors.IsForbidden(err) {
+		// daemonsets are only used for optional features, which might
+		// not be permitted by the role kubedock is running with
+		klog.V(3).Infof("not allowed to list daemonsets: %s", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, ds := range dss.Items {
+		if err := in.cli.AppsV1().DaemonSets(ds.Namespace).Delete(context.Background(), ds.Name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteVolumeClaims will delete k8s persistentvolumeclaim resources which
+// match the given label selector. Volume claims are only created when the
+// archive helper is enabled.
+func (in *instance) deleteVolumeClaims(selector string) error {
+	if !in.archiveHelper {
+		return nil
+	}
+	if err := in.CheckFeature(FeatureArchiveHelper); err != nil {
+		return nil
+	}
+	pvcs, err := in.cli.CoreV1().PersistentVolumeClaims(in.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return err
+	}
+	for _, pvc := range pvcs.Items {
+		if err := in.cli.CoreV1().PersistentVolumeClaims(pvc.Namespace).Delete(context.Background(), pvc.Name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteDeployments will delete k8s deployment resources which match the
+// given label selector.
+func (in *instance) deleteDeployments(selector string) error {
+	if err := in.CheckFeature(FeatureDeployments); err != nil {
+		return nil
+	}
+	deps, err := in.cli.AppsV1().Deployments(in.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if errors.IsForbidden(err) {
+		// deployments are only used for swarm services, which might
+		// not be permitted by the role kubedock is running with
+		klog.V(3).Infof("not allowed to list deployments: %s", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, dep := range deps.Items {
+		if err := in.cli.AppsV1().Deployments(dep.Namespace).Delete(context.Background(), dep.Name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WatchDeleteContainer will return a channel which will be closed when
 // the given container is actually deleted from kubernetes.
 func (in *instance) WatchDeleteContainer(tainr *types.Container) (chan struct{}, error) {
 	delch := make(chan struct{}, 1)
 
+	if tainr.IsLinked() {
+		// linked containers don't own a pod, and are gone when deleted
+		close(delch)
+		return delch, nil
+	}
+
 	watcher, err := in.cli.CoreV1().Pods(in.namespace).Watch(context.Background(), metav1.ListOptions{
 		LabelSelector: "kubedock.containerid=" + tainr.ShortID,
 	})
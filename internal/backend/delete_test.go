@@ -9,6 +9,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
+	"github.com/joyrex2001/kubedock/internal/config"
 	"github.com/joyrex2001/kubedock/internal/model/types"
 )
 
@@ -297,6 +298,46 @@ func TestDeleteContainersOlderThan(t *testing.T) {
 	}
 }
 
+func TestDeleteContainersOlderThanResources(t *testing.T) {
+	kub := &instance{
+		namespace:     "default",
+		archiveHelper: true,
+		cli: fake.NewSimpleClientset(
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      "kubedock-msx",
+				Namespace: "default",
+				Labels:    map[string]string{"kubedock": "true", "kubedock.containerid": "msx2"},
+			}},
+			&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Name:      "msx2-data",
+				Namespace: "default",
+				Labels:    map[string]string{"kubedock": "true", "kubedock.containerid": "msx2"},
+			}},
+			&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Name:      "vg8020-data",
+				Namespace: "default",
+				Labels:    map[string]string{"kubedock": "true", "kubedock.containerid": "vg8020"},
+			}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:      "msx2-files",
+				Namespace: "default",
+				Labels:    map[string]string{"kubedock": "true", "kubedock.containerid": "msx2"},
+			}},
+		),
+	}
+	if err := kub.DeleteContainersOlderThan(100 * time.Millisecond); err != nil {
+		t.Fatalf("expected no error, but got %s", err)
+	}
+	pvcs, _ := kub.cli.CoreV1().PersistentVolumeClaims("default").List(context.Background(), metav1.ListOptions{})
+	if len(pvcs.Items) != 1 || pvcs.Items[0].Name != "vg8020-data" {
+		t.Errorf("expected only the claim of the other container to remain, but got %v", pvcs.Items)
+	}
+	cms, _ := kub.cli.CoreV1().ConfigMaps("default").List(context.Background(), metav1.ListOptions{})
+	if len(cms.Items) != 0 {
+		t.Errorf("expected the configmap of the container to be deleted, but got %v", cms.Items)
+	}
+}
+
 func TestDeletePodsOlderThan(t *testing.T) {
 	tests := []struct {
 		cnt int
@@ -508,3 +549,128 @@ func TestWatchDeleteContainer(t *testing.T) {
 		t.Errorf("expected timeout, but no timeout occurred")
 	}
 }
+
+func TestRetainContainer(t *testing.T) {
+	failed := corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+		Name:  "main",
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+	}}}
+	retain := map[string]string{types.LabelRetainOnFailure: "true"}
+	tests := []struct {
+		labels map[string]string
+		status corev1.PodStatus
+		keep   time.Duration
+		out    bool
+	}{
+		{labels: retain, status: failed, keep: time.Hour, out: true},
+		{labels: retain, status: failed, keep: 0, out: false},
+		{labels: nil, status: failed, keep: time.Hour, out: false},
+		{labels: retain, status: corev1.PodStatus{Phase: corev1.PodRunning}, keep: time.Hour, out: false},
+		{labels: retain, status: corev1.PodStatus{Phase: corev1.PodFailed}, keep: time.Hour, out: true},
+	}
+
+	for i, tst := range tests {
+		tainr := &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit", Labels: tst.labels}
+		kub := &instance{
+			namespace:    "default",
+			retainFailed: tst.keep,
+			cli: fake.NewSimpleClientset(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tainr.GetPodName(),
+					Namespace: "default",
+					Labels:    map[string]string{"kubedock.containerid": "tb303"},
+				},
+				Status: tst.status,
+			}),
+		}
+		res, err := kub.RetainContainer(tainr)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %t, but got %t", i, tst.out, res)
+		}
+		pod, _ := kub.cli.CoreV1().Pods("default").Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+		if kub.isRetained(pod.ObjectMeta) != tst.out {
+			t.Errorf("failed test %d - expected retained %t, but got %t", i, tst.out, !tst.out)
+		}
+		if _, ok := pod.Labels["kubedock.containerid"]; ok == tst.out {
+			t.Errorf("failed test %d - expected relabelled pod %t, but got %v", i, tst.out, pod.Labels)
+		}
+	}
+}
+
+func TestDeleteRetainedPod(t *testing.T) {
+	pod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "kubedock-tb303", Namespace: "default", Annotations: annotations}}
+	}
+	until := time.Now().Add(time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		pod *corev1.Pod
+		out bool
+		cnt int
+	}{
+		{pod: pod(map[string]string{retainUntilAnnotation: until}), out: true, cnt: 0},
+		{pod: pod(nil), out: false, cnt: 1},
+		{pod: nil, out: false, cnt: 0},
+	}
+	for i, tst := range tests {
+		cli := fake.NewSimpleClientset()
+		if tst.pod != nil {
+			cli = fake.NewSimpleClientset(tst.pod)
+		}
+		kub := &instance{namespace: "default", cli: cli}
+		res, err := kub.deleteRetainedPod(context.Background(), "kubedock-tb303", 1)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %t, but got %t", i, tst.out, res)
+		}
+		pods, _ := cli.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+		if len(pods.Items) != tst.cnt {
+			t.Errorf("failed test %d - expected %d pods, but got %d", i, tst.cnt, len(pods.Items))
+		}
+	}
+}
+
+func TestDeleteComposeService(t *testing.T) {
+	labels := func(id string) map[string]string {
+		l := map[string]string{"kubedock.id": config.InstanceID, "kubedock.project": "demo", "kubedock.service": "db"}
+		if id != "" {
+			l["kubedock.containerid"] = id
+		}
+		return l
+	}
+	pod := func(id string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: id, Namespace: "default", Labels: labels(id)}}
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: labels("")}}
+	tainr := &types.Container{ShortID: "tb303", Labels: map[string]string{
+		types.LabelComposeProject: "demo",
+		types.LabelComposeService: "db",
+	}}
+
+	tests := []struct {
+		pods []*corev1.Pod
+		cnt  int
+	}{
+		{pods: []*corev1.Pod{pod("tb303")}, cnt: 0},
+		{pods: []*corev1.Pod{pod("tb303"), pod("mc505")}, cnt: 1},
+		{pods: []*corev1.Pod{}, cnt: 0},
+	}
+	for i, tst := range tests {
+		cli := fake.NewSimpleClientset(svc.DeepCopy())
+		for _, p := range tst.pods {
+			cli.CoreV1().Pods("default").Create(context.Background(), p, metav1.CreateOptions{})
+		}
+		kub := &instance{namespace: "default", cli: cli}
+		if err := kub.deleteComposeService(tainr); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		svcs, _ := cli.CoreV1().Services("default").List(context.Background(), metav1.ListOptions{})
+		if len(svcs.Items) != tst.cnt {
+			t.Errorf("failed test %d - expected %d remaining services, but got %d", i, tst.cnt, len(svcs.Items))
+		}
+	}
+}
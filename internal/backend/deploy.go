@@ -3,17 +3,22 @@ package backend
 import (
 	"context"
 	"crypto/md5"
+	goerrors "errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/watch"
@@ -22,6 +27,7 @@ import (
 	"github.com/joyrex2001/kubedock/internal/config"
 	"github.com/joyrex2001/kubedock/internal/model/types"
 	"github.com/joyrex2001/kubedock/internal/util/exec"
+	"github.com/joyrex2001/kubedock/internal/util/image"
 	"github.com/joyrex2001/kubedock/internal/util/portforward"
 	"github.com/joyrex2001/kubedock/internal/util/reverseproxy"
 	"github.com/joyrex2001/kubedock/internal/util/tar"
@@ -41,14 +47,30 @@ const (
 	DeployCompleted
 	// SetupInitContainerName in the name of the container used for setup
 	SetupInitContainerName = "setup"
+	// stagedArchivePath is the location where staged archives are mounted
+	stagedArchivePath = "/kubedock-archives"
+)
+
+const (
+	// dnsRankAnnotation is the annotation on services with their rank in dns
+	// resolution precedence, the lowest rank takes precedence
+	dnsRankAnnotation = "kubedock.dns/rank"
+	// dnsRankName is the rank of a service for a container name
+	dnsRankName = "0"
+	// dnsRankAlias is the rank of a service for a hostname or network alias
+	dnsRankAlias = "1"
 )
 
 // StartContainer will start given container object in kubernetes and
-// waits until it's started, or failed with an error.
-func (in *instance) StartContainer(tainr *types.Container) (DeployState, error) {
-	state, err := in.startContainer(tainr)
+// waits until it's started, or failed with an error. If the given context
+// is cancelled (e.g. the client aborted the request), starting is aborted
+// and the partially created pod is removed.
+func (in *instance) StartContainer(ctx context.Context, tainr *types.Container) (DeployState, error) {
+	state, err := in.startContainer(ctx, tainr)
 	if state == DeployFailed {
-		if klog.V(2) {
+		if ctx.Err() != nil {
+			klog.Infof("aborted starting container %s: %s", tainr.ShortID, ctx.Err())
+		} else if klog.V(2) {
 			klog.Infof("container %s log output:", tainr.ShortID)
 			stop := make(chan struct{}, 1)
 			count := uint64(100)
@@ -56,19 +78,53 @@ func (in *instance) StartContainer(tainr *types.Container) (DeployState, error)
 			_ = in.GetLogs(tainr, &logOpts, stop, os.Stderr)
 			close(stop)
 		}
-		_ = in.cli.CoreV1().Pods(in.namespace).Delete(context.Background(), tainr.GetPodName(), metav1.DeleteOptions{})
+		if !tainr.IsLinked() {
+			_ = in.cli.CoreV1().Pods(in.namespace).Delete(context.Background(), tainr.GetPodName(), metav1.DeleteOptions{})
+		}
 	}
 	return state, err
 }
 
-func (in *instance) startContainer(tainr *types.Container) (DeployState, error) {
+func (in *instance) startContainer(ctx context.Context, tainr *types.Container) (DeployState, error) {
+	if err := in.checkSecurityProfile(tainr); err != nil {
+		return DeployFailed, err
+	}
+
+	if tainr.IsLinked() {
+		return in.startLinkedContainer(ctx, tainr)
+	}
+
+	// volumes and archives are copied into the pod with exec
+	if tainr.HasVolumes() || tainr.HasPreArchives() {
+		if err := in.CheckFeature(FeatureExec); err != nil {
+			return DeployFailed, err
+		}
+	}
+
+	begin := time.Now()
 	pulpol, err := tainr.GetImagePullPolicy()
 	if err != nil {
 		return DeployFailed, err
 	}
 
+	readiness, err := tainr.GetReadiness()
+	if err != nil {
+		return DeployFailed, err
+	}
+
+	timeout, err := in.getStartTimeout(tainr)
+	if err != nil {
+		return DeployFailed, err
+	}
+
+	img, err := in.getImage(tainr.Image)
+	if err != nil {
+		return DeployFailed, err
+	}
+
+	tainr.PodName = tainr.GetPodName()
 	pod := in.podTemplate.DeepCopy()
-	pod.ObjectMeta.Name = tainr.GetPodName()
+	pod.ObjectMeta.Name = tainr.PodName
 	pod.ObjectMeta.Namespace = in.namespace
 	pod.ObjectMeta.Labels = in.getLabels(pod.ObjectMeta.Labels, tainr)
 	pod.ObjectMeta.Annotations = in.getAnnotations(pod.ObjectMeta.Annotations, tainr)
@@ -81,6 +137,11 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 	for i, hostname := range tainr.NetworkAliases {
 		pod.ObjectMeta.Annotations[fmt.Sprintf("kubedock.hostalias/%d", i+1)] = hostname
 	}
+	if in.disableInjection {
+		pod.ObjectMeta.Labels["sidecar.istio.io/inject"] = "false"
+		pod.ObjectMeta.Annotations["sidecar.istio.io/inject"] = "false"
+		pod.ObjectMeta.Annotations["linkerd.io/inject"] = "disabled"
+	}
 	inetwork := 0
 	for network := range tainr.Networks {
 		pod.ObjectMeta.Annotations[fmt.Sprintf("kubedock.network/%d", inetwork)] = network
@@ -88,7 +149,7 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 	}
 
 	container := in.containerTemplate
-	container.Image = tainr.Image
+	container.Image = img
 	container.Name = "main"
 	container.Command = tainr.Entrypoint
 	container.Args = tainr.Cmd
@@ -103,6 +164,7 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 		return DeployFailed, err
 	}
 	container.Resources = reqlimits
+	in.addDevices(tainr, pod, &container)
 
 	nodeSel, err := tainr.GetNodeSelector(pod.Spec.NodeSelector)
 	if err != nil {
@@ -112,9 +174,28 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 
 	pod.Spec.Containers = []corev1.Container{container}
 
+	if tainr.SeparateStderr() || len(tainr.Ulimits) > 0 || tainr.HasStagedArchives() {
+		if err := in.addCommandWrapper(tainr, pod); err != nil {
+			return DeployFailed, err
+		}
+	}
+
 	if tainr.Hostname != "" {
 		pod.Spec.Hostname = tainr.Hostname
 	}
+	ips := []string{}
+	for ip := range tainr.LinkHosts {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	for _, ip := range ips {
+		pod.Spec.HostAliases = append(pod.Spec.HostAliases, corev1.HostAlias{IP: ip, Hostnames: tainr.LinkHosts[ip]})
+	}
+	if tainr.HostNetwork {
+		pod.Spec.HostNetwork = true
+		pod.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	}
+
 	pod.Spec.ServiceAccountName = tainr.GetServiceAccountName(pod.Spec.ServiceAccountName)
 	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
 
@@ -131,6 +212,12 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 		return DeployFailed, err
 	}
 	pod.Spec.SecurityContext = seccontext
+	if len(tainr.Sysctls) > 0 {
+		if pod.Spec.SecurityContext == nil {
+			pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		pod.Spec.SecurityContext.Sysctls = tainr.GetSysctls(pod.Spec.SecurityContext.Sysctls)
+	}
 
 	for _, ps := range in.imagePullSecrets {
 		pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: ps})
@@ -154,24 +241,55 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 		}
 	}
 
+	in.applySecurityProfile(pod)
+
+	admitted, err := in.admitPod(ctx, pod, timeout)
+	if err != nil {
+		// a pod that already exists (duplicate request) is accounted for
+		if _, gerr := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), pod.Name, metav1.GetOptions{}); gerr != nil {
+			return DeployFailed, err
+		}
+	}
+
+	resolved := time.Now()
+	tainr.SetStartTiming(types.PhaseResolve, resolved.Sub(begin))
+
 	duplicateRequest := false
-	if _, err := in.cli.CoreV1().Pods(in.namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+	_, err = in.cli.CoreV1().Pods(in.namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		if retained, rerr := in.deleteRetainedPod(ctx, pod.Name, timeout); rerr != nil {
+			err = rerr
+		} else if retained {
+			_, err = in.cli.CoreV1().Pods(in.namespace).Create(ctx, pod, metav1.CreateOptions{})
+		}
+	}
+	admitted()
+	if err != nil && !errors.IsAlreadyExists(err) {
 		return DeployFailed, err
 	} else if errors.IsAlreadyExists(err) {
 		duplicateRequest = true
 	}
 
+	created := time.Now()
+	tainr.SetStartTiming(types.PhaseCreate, created.Sub(resolved))
+
 	if tainr.HasVolumes() || tainr.HasPreArchives() {
-		if err := in.copyVolumeFolders(tainr, in.timeOut); err != nil {
+		if err := in.copyVolumeFolders(ctx, tainr, timeout); err != nil {
 			return DeployFailed, err
 		}
 	}
 
-	state, err := in.waitReadyState(tainr, in.timeOut)
+	state, err := in.waitReadyState(ctx, tainr, timeout)
 	if err != nil {
 		return state, err
 	}
 
+	if state == DeployRunning && readiness == types.ReadinessReady {
+		if err := in.waitPodReady(ctx, tainr, timeout); err != nil {
+			return DeployFailed, err
+		}
+	}
+
 	if err := in.MapContainerTCPPorts(tainr); err != nil {
 		return DeployFailed, err
 	}
@@ -183,6 +301,8 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 		return state, err
 	}
 
+	in.setReadyTimings(tainr, created, time.Now())
+
 	if tainr.HasDockerSockBinding() {
 		if err := in.handleDindCompleted(tainr); err != nil {
 			return DeployFailed, err
@@ -192,9 +312,51 @@ func (in *instance) startContainer(tainr *types.Container) (DeployState, error)
 	return state, nil
 }
 
+// setReadyTimings will record the scheduled, pulled and ready start phase
+// timings of the given container, based on the pod conditions and status
+// between given created and ready timestamps.
+func (in *instance) setReadyTimings(tainr *types.Container, created, ready time.Time) {
+	clamp := func(t, min, max time.Time) time.Time {
+		if t.Before(min) {
+			return min
+		}
+		if t.After(max) {
+			return max
+		}
+		return t
+	}
+
+	scheduled, started := created, created
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+	if err == nil {
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionTrue {
+				scheduled = clamp(cond.LastTransitionTime.Time, created, ready)
+			}
+		}
+		started = scheduled
+		if status := getContainerStatus(pod, tainr.GetContainerName()); status != nil {
+			if status.State.Running != nil {
+				started = clamp(status.State.Running.StartedAt.Time, scheduled, ready)
+			}
+			if status.State.Terminated != nil {
+				started = clamp(status.State.Terminated.StartedAt.Time, scheduled, ready)
+			}
+		}
+	}
+
+	tainr.SetStartTiming(types.PhaseScheduled, scheduled.Sub(created))
+	tainr.SetStartTiming(types.PhasePulled, started.Sub(scheduled))
+	tainr.SetStartTiming(types.PhaseReady, ready.Sub(started))
+}
+
 // CreatePortForwards sets up port-forwards for all available ports that
 // are configured in the container.
 func (in *instance) CreatePortForwards(tainr *types.Container) {
+	if err := in.CheckFeature(FeaturePortForward); err != nil {
+		klog.Errorf("port-forward failed: %s", err)
+		return
+	}
 	if err := in.portForward(tainr, tainr.HostPorts); err != nil {
 		klog.Errorf("port-forward failed: %s", err)
 	}
@@ -213,16 +375,17 @@ func (in *instance) portForward(tainr *types.Container, ports map[int]int) error
 		if src < 0 {
 			continue
 		}
-		stop := make(chan struct{}, 1)
-		tainr.AddStopChannel(stop)
+		fwd := in.forwards.add(forwardPortForward, tainr, src, dst)
 		go func(src, dst int) {
 			err := portforward.ToPod(portforward.Request{
 				RestConfig: in.cfg,
 				Pod:        *pod,
 				LocalPort:  src,
 				PodPort:    dst,
-				StopCh:     stop,
+				StopCh:     fwd.stop,
 				ReadyCh:    make(chan struct{}, 1),
+				Opened:     fwd.opened,
+				Closed:     fwd.closed,
 			})
 			if err != nil {
 				klog.Errorf("port-forward failed: %s", err)
@@ -251,14 +414,15 @@ func (in *instance) reverseProxy(tainr *types.Container, ports map[int]int) {
 		go func(src, dst int) {
 			defer wg.Done()
 			klog.Infof("reverse proxy for %d to %d", src, dst)
-			stop := make(chan struct{}, 1)
-			tainr.AddStopChannel(stop)
+			fwd := in.forwards.add(forwardReverseProxy, tainr, src, dst)
 			err := reverseproxy.Proxy(reverseproxy.Request{
 				LocalPort:  src,
 				RemotePort: dst,
 				RemoteIP:   tainr.HostIP,
-				StopCh:     stop,
+				StopCh:     fwd.stop,
 				MaxRetry:   30,
+				Opened:     fwd.opened,
+				Closed:     fwd.closed,
 			})
 			if err != nil {
 				klog.Errorf("error setting up reverse-proxy for %d to %d: %s", src, dst, err)
@@ -278,18 +442,100 @@ func (in *instance) GetPodIP(tainr *types.Container) (string, error) {
 }
 
 // createServices will create k8s service objects for each provided
-// external name, mapped with provided hostports ports.
+// external name, mapped with provided hostports ports. The service of a
+// docker compose service is shared by its containers, and is only created
+// by the first container.
 func (in *instance) createServices(tainr *types.Container) error {
 	for _, svc := range in.getServices(tainr) {
-		if _, err := in.cli.CoreV1().Services(in.namespace).Create(context.Background(), &svc, metav1.CreateOptions{}); err != nil {
+		if err := in.createService(&svc); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// ServiceConflictError is returned when the service of a docker compose
+// service can't be created, because a service with the same name is in use
+// by another docker compose project.
+type ServiceConflictError struct {
+	Name  string
+	Owner string
+}
+
+// Error will return a description of the conflicting service.
+func (e *ServiceConflictError) Error() string {
+	return fmt.Sprintf("service %s is already in use by compose service %s", e.Name, e.Owner)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *ServiceConflictError) HTTPStatus() int {
+	return http.StatusConflict
+}
+
+// createService will create given k8s service object. If a service with the
+// same name already exists, the given service is shadowed by it, or shadows
+// it if the given service takes precedence in dns resolution (a container
+// name over a network alias), similar to docker networks where the same
+// alias can be used by multiple containers (see shadowService). The service of a docker compose service is
+// shared by the containers of that compose service, but can't be shared
+// with the same compose service of another project.
+func (in *instance) createService(svc *corev1.Service) error {
+	_, err := in.cli.CoreV1().Services(in.namespace).Create(context.Background(), svc, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	cur, err := in.cli.CoreV1().Services(in.namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if owner, curOwner := getComposeOwner(svc), getComposeOwner(cur); owner != "" && curOwner != "" {
+		if owner != curOwner {
+			return &ServiceConflictError{Name: svc.Name, Owner: curOwner}
+		}
+		klog.V(3).Infof("service %s already exists for compose service %s", svc.Name, owner)
+		return nil
+	}
+	return in.shadowService(cur, svc)
+}
+
+// UpdateServices will make the k8s services of given container match its
+// current hostname and network aliases, by creating the services that are
+// missing and releasing the services of aliases that are no longer in use.
+func (in *instance) UpdateServices(tainr *types.Container) error {
+	if err := in.CheckFeature(FeatureServices); err != nil {
+		return nil
+	}
+	svcs, err := in.cli.CoreV1().Services(in.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "kubedock.containerid=" + tainr.ShortID,
+	})
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, svc := range svcs.Items {
+		existing[svc.Name] = true
+	}
+	keep := map[string]bool{}
+	for _, svc := range in.getServices(tainr) {
+		keep[svc.Name] = true
+		if existing[svc.Name] {
+			continue
+		}
+		klog.V(3).Infof("creating service %s for container %s", svc.Name, tainr.ShortID)
+		if err := in.createService(&svc); err != nil {
+			return err
+		}
+	}
+	return in.releaseServices("kubedock.containerid="+tainr.ShortID, keep)
+}
+
 // getServices will return corev1 services objects for the given
-// container definition.
+// container definition; one for the container name, hostname and each
+// network alias. Containers of a docker compose service get a service named
+// after the compose service, which selects all containers of that compose
+// service, as the dns of docker networks would. The services are annotated
+// with their rank in dns resolution precedence.
 func (in *instance) getServices(tainr *types.Container) []corev1.Service {
 	svcs := []corev1.Service{}
 	if in.disableServices {
@@ -306,44 +552,101 @@ func (in *instance) getServices(tainr *types.Container) []corev1.Service {
 	valid := regexp.MustCompile("^[a-z]([-a-z0-9]*[a-z0-9])?$")
 
 	// gather all aliases, ignore duplicates, convert to lower case
-	aliases := make(map[string]bool)
+	aliases := make(map[string]string)
 	if tainr.Hostname != "" {
-		aliases[strings.ToLower(tainr.Hostname)] = true
+		aliases[strings.ToLower(tainr.Hostname)] = dnsRankAlias
 	}
 	for _, alias := range tainr.NetworkAliases {
-		aliases[strings.ToLower(alias)] = true
+		aliases[strings.ToLower(alias)] = dnsRankAlias
 	}
-	for alias := range aliases {
+	if tainr.Name != "" {
+		aliases[strings.ToLower(tainr.Name)] = dnsRankName
+	}
+	if name := strings.ToLower(tainr.GetComposeService()); name != "" && tainr.GetComposeProject() != "" {
+		delete(aliases, name)
+		if valid.MatchString(name) {
+			svcs = append(svcs, in.getComposeService(tainr, name, ports))
+		} else {
+			klog.Infof("ignoring compose service %s, invalid name", name)
+		}
+	}
+	for alias, rank := range aliases {
 		if ok := valid.MatchString(alias); !ok {
 			klog.Infof("ignoring network alias %s, invalid name", alias)
 			continue
 		}
 		klog.V(4).Infof("Creating service %s", alias)
+		annotations := in.getAnnotations(nil, tainr)
+		annotations[dnsRankAnnotation] = rank
 		svc := corev1.Service{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace:   in.namespace,
 				Name:        alias,
 				Labels:      in.getLabels(nil, tainr),
-				Annotations: in.getAnnotations(nil, tainr),
+				Annotations: annotations,
 			},
 			Spec: corev1.ServiceSpec{
 				Selector: in.getPodMatchLabels(tainr),
-				Ports:    []corev1.ServicePort{},
+				Ports:    getServicePorts(ports),
 			},
 		}
-		for src, dst := range ports {
-			svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
-				Name:       fmt.Sprintf("tcp-%d-%d", src, dst),
-				Protocol:   corev1.ProtocolTCP,
-				Port:       int32(src),
-				TargetPort: intstr.IntOrString{IntVal: int32(dst)},
-			})
-		}
 		svcs = append(svcs, svc)
 	}
 	return svcs
 }
 
+// getComposeService will return the corev1 service object with given name
+// for the docker compose service of given container. The service selects
+// the pods of all containers of the compose service, and is not labelled
+// with the container id, so it's not deleted together with the container.
+func (in *instance) getComposeService(tainr *types.Container, name string, ports map[int]int) corev1.Service {
+	labels := in.getLabels(nil, tainr)
+	delete(labels, "kubedock.containerid")
+	annotations := in.getAnnotations(nil, tainr)
+	annotations[dnsRankAnnotation] = dnsRankAlias
+	return corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   in.namespace,
+			Name:        name,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"kubedock.id":      labels["kubedock.id"],
+				"kubedock.project": labels["kubedock.project"],
+				"kubedock.service": labels["kubedock.service"],
+			},
+			Ports: getServicePorts(ports),
+		},
+	}
+}
+
+// getComposeOwner will return the docker compose project and service (as
+// project/service) that is selected by given service, or an empty string if
+// it's not the service of a docker compose service.
+func getComposeOwner(svc *corev1.Service) string {
+	project := svc.Spec.Selector["kubedock.project"]
+	if project == "" {
+		return ""
+	}
+	return project + "/" + svc.Spec.Selector["kubedock.service"]
+}
+
+// getServicePorts will return the given mapped ports as k8s ServicePorts.
+func getServicePorts(ports map[int]int) []corev1.ServicePort {
+	res := []corev1.ServicePort{}
+	for src, dst := range ports {
+		res = append(res, corev1.ServicePort{
+			Name:       fmt.Sprintf("tcp-%d-%d", src, dst),
+			Protocol:   corev1.ProtocolTCP,
+			Port:       int32(src),
+			TargetPort: intstr.IntOrString{IntVal: int32(dst)},
+		})
+	}
+	return res
+}
+
 // getContainerPorts will return the mapped ports of the container
 // as k8s ContainerPorts.
 func (in *instance) getContainerPorts(tainr *types.Container) []corev1.ContainerPort {
@@ -357,7 +660,8 @@ func (in *instance) getContainerPorts(tainr *types.Container) []corev1.Container
 
 // getLabels will return a map of labels to be added to the container. This
 // map contains the labels that link to the container definition, as well
-// as additional labels which are used internally by kubedock.
+// as additional labels which are used internally by kubedock. Containers of
+// a docker compose project are labelled with the project and service.
 func (in *instance) getLabels(labels map[string]string, tainr *types.Container) map[string]string {
 	if labels == nil {
 		labels = map[string]string{}
@@ -381,13 +685,17 @@ func (in *instance) getLabels(labels map[string]string, tainr *types.Container)
 	for k, v := range config.SystemLabels {
 		labels[k] = v
 	}
+	if project := tainr.GetComposeProject(); project != "" {
+		labels["kubedock.project"] = in.toKubernetesValue(project)
+		labels["kubedock.service"] = in.toKubernetesValue(tainr.GetComposeService())
+	}
 	labels["kubedock.containerid"] = tainr.ShortID
 	return labels
 }
 
 // getAnnotations will return a map of annotations to be added to the
 // container. This map contains the labels as specified in the container
-// definition.
+// definition, and the docker compose project that owns the container.
 func (in *instance) getAnnotations(annotations map[string]string, tainr *types.Container) map[string]string {
 	if annotations == nil {
 		annotations = map[string]string{}
@@ -399,6 +707,9 @@ func (in *instance) getAnnotations(annotations map[string]string, tainr *types.C
 		annotations[k] = v
 	}
 	annotations["kubedock.containername"] = tainr.Name
+	if project := tainr.GetComposeProject(); project != "" {
+		annotations["kubedock.owner"] = "compose/" + project
+	}
 	return annotations
 }
 
@@ -406,20 +717,42 @@ func (in *instance) getAnnotations(annotations map[string]string, tainr *types.C
 // match running pods for this container.
 func (in *instance) getPodMatchLabels(tainr *types.Container) map[string]string {
 	return map[string]string{
-		"kubedock.containerid": tainr.ShortID,
+		"kubedock.containerid": tainr.GetPodShortID(),
 	}
 }
 
-// waitReadyState will wait for the deployment to be ready.
-func (in *instance) waitReadyState(tainr *types.Container, wait int) (DeployState, error) {
+// waitReadyState will wait for the deployment to be ready, or until the
+// given context is done.
+func (in *instance) waitReadyState(ctx context.Context, tainr *types.Container, wait int) (DeployState, error) {
 	for max := 0; max < wait; max++ {
 		status, err := in.GetContainerStatus(tainr)
 		if status != DeployPending || err != nil {
 			return status, err
 		}
-		time.Sleep(time.Second)
+		if err := sleep(ctx, time.Second); err != nil {
+			return DeployFailed, err
+		}
+	}
+	return DeployFailed, in.withDiagnostics(tainr, "timeout starting container")
+}
+
+// getStartTimeout will return the max number of seconds to wait for the
+// given container to start.
+func (in *instance) getStartTimeout(tainr *types.Container) (int, error) {
+	timeout, err := tainr.GetStartTimeout(time.Duration(in.timeOut) * time.Second)
+	if err != nil {
+		return 0, err
+	}
+	return int(timeout.Seconds()), nil
+}
+
+// withDiagnostics will return an error with the given message, extended with
+// the diagnostics of the pod of the given container, if any.
+func (in *instance) withDiagnostics(tainr *types.Container, msg string) error {
+	if diag := in.getPodDiagnostics(tainr); diag != "" {
+		return fmt.Errorf("%s: %s", msg, diag)
 	}
-	return DeployFailed, fmt.Errorf("timeout starting container")
+	return fmt.Errorf("%s", msg)
 }
 
 // GetContainerStatus will return the state of the deployed container.
@@ -428,10 +761,10 @@ func (in *instance) GetContainerStatus(tainr *types.Container) (DeployState, err
 	if err != nil {
 		return DeployFailed, err
 	}
-	for _, status := range pod.Status.ContainerStatuses {
-		if status.Name != "main" {
-			continue
-		}
+	if reason := getPodDisruption(pod); reason != "" {
+		return DeployFailed, &DisruptionError{Reason: reason}
+	}
+	if status := getContainerStatus(pod, tainr.GetContainerName()); status != nil {
 		term := status.State.Terminated
 		ters := status.LastTerminationState.Terminated
 		if (ters != nil && ters.Reason == "Completed") || (term != nil && term.Reason == "Completed") {
@@ -443,24 +776,165 @@ func (in *instance) GetContainerStatus(tainr *types.Container) (DeployState, err
 		if status.RestartCount > 0 {
 			return DeployFailed, fmt.Errorf("failed to start container")
 		}
-		if status.State.Waiting != nil && status.State.Waiting.Reason == "ImagePullBackOff" {
-			return DeployFailed, fmt.Errorf("failed to start container; error pulling image")
+		if wait := status.State.Waiting; wait != nil && (wait.Reason == "ErrImagePull" || wait.Reason == "ImagePullBackOff") {
+			if err := in.getPullError(tainr, wait); err != nil {
+				return DeployFailed, err
+			}
+		}
+		if wait := status.State.Waiting; wait != nil {
+			if err := in.getRunAsRootError(tainr, wait); err != nil {
+				return DeployFailed, err
+			}
 		}
 		if status.State.Running != nil {
 			return DeployRunning, nil
 		}
 	}
 	if pod.Status.Phase == corev1.PodFailed {
+		if pod.Status.Reason != "" {
+			return DeployFailed, fmt.Errorf("failed to start container: %s", formatReason(pod.Status.Reason, pod.Status.Message))
+		}
 		return DeployFailed, fmt.Errorf("failed to start container")
 	}
 	return DeployPending, nil
 }
 
+// getPullError will return the error for a container that is waiting for
+// its image with given state. An image.PullError is returned if the image
+// doesn't exist or access is denied, and an error with the diagnostics if
+// kubernetes backs off pulling for another reason. Otherwise, pulling is
+// retried and nil is returned. Only the state of the container and the pull
+// failures of its image are classified, not those of other containers in
+// the pod (e.g. sidecars).
+func (in *instance) getPullError(tainr *types.Container, wait *corev1.ContainerStateWaiting) error {
+	msgs := append([]string{formatReason(wait.Reason, wait.Message)}, in.getPullFailureEvents(tainr)...)
+	msg := strings.Join(msgs, "; ")
+	var perr *image.PullError
+	if err := image.ClassifyPullError(tainr.Image, goerrors.New(msg)); goerrors.As(err, &perr) {
+		return err
+	}
+	if wait.Reason == "ImagePullBackOff" {
+		return in.withDiagnostics(tainr, "failed to start container; error pulling image")
+	}
+	return nil
+}
+
+// ContainerExit contains the details of a container that has terminated.
+type ContainerExit struct {
+	// Code is the exit code of the container
+	Code int
+	// Reason is the reason why kubernetes terminated the container (e.g. the
+	// pod was evicted), empty if the container exited by itself
+	Reason string
+}
+
+// WatchContainerExit will return a channel that receives the exit code of
+// the given container when it has terminated. If the pod is disrupted (e.g.
+// evicted or preempted), the container is considered terminated as well, and
+// the reason of the disruption is included. The channel is closed without a
+// value if the pod is deleted otherwise before the container terminated, or
+// if the watch could not be re-established after it expired.
+func (in *instance) WatchContainerExit(tainr *types.Container) (chan ContainerExit, error) {
+	pods := in.cli.CoreV1().Pods(in.namespace)
+	opts := metav1.ListOptions{LabelSelector: "kubedock.containerid=" + tainr.GetPodShortID()}
+	watcher, err := pods.Watch(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	exitch := make(chan ContainerExit, 1)
+	go func() {
+		defer close(exitch)
+		for {
+			for event := range watcher.ResultChan() {
+				pod, ok := event.Object.(*corev1.Pod)
+				if ok {
+					if reason := getPodDisruption(pod); reason != "" {
+						watcher.Stop()
+						code, ok := getExitCode(pod, tainr.GetContainerName())
+						if !ok || code == 0 {
+							code = 137
+						}
+						exitch <- ContainerExit{Code: code, Reason: reason}
+						return
+					}
+				}
+				if event.Type == watch.Deleted {
+					watcher.Stop()
+					return
+				}
+				if !ok {
+					continue
+				}
+				if code, ok := getExitCode(pod, tainr.GetContainerName()); ok {
+					watcher.Stop()
+					exitch <- ContainerExit{Code: code}
+					return
+				}
+			}
+			watcher.Stop()
+			watcher, err = pods.Watch(context.Background(), opts)
+			if err != nil {
+				klog.Warningf("error watching container %s: %s", tainr.ShortID, err)
+				return
+			}
+		}
+	}()
+
+	return exitch, nil
+}
+
+// getExitCode will return the exit code of the container with given name in
+// given pod, and true if the container has terminated.
+func getExitCode(pod *corev1.Pod, name string) (int, bool) {
+	status := getContainerStatus(pod, name)
+	if status == nil || status.State.Terminated == nil {
+		return 0, false
+	}
+	return int(status.State.Terminated.ExitCode), true
+}
+
+// getContainerStatus will return the status of the container with given
+// name in given pod, including ephemeral containers, or nil if not found.
+func getContainerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.ContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+		for i := range statuses {
+			if statuses[i].Name == name {
+				return &statuses[i]
+			}
+		}
+	}
+	return nil
+}
+
+// waitPodReady will wait for the container to report ready in the pod of
+// the given container (e.g. all readiness probes succeeded). Only the
+// container itself is considered, so sidecars that are injected in the pod
+// (e.g. by a service mesh) don't affect the readiness of the container.
+func (in *instance) waitPodReady(ctx context.Context, tainr *types.Container, wait int) error {
+	for max := 0; max < wait; max++ {
+		pod, err := in.cli.CoreV1().Pods(in.namespace).Get(ctx, tainr.GetPodName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if pod.Status.Phase == corev1.PodFailed {
+			return fmt.Errorf("failed to start container")
+		}
+		if status := getContainerStatus(pod, tainr.GetContainerName()); status != nil && status.Ready {
+			return nil
+		}
+		if err := sleep(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+	return in.withDiagnostics(tainr, "timeout waiting for container to become ready")
+}
+
 // waitInitContainerRunning will wait for a specific container in the
 // deployment to be ready.
-func (in *instance) waitInitContainerRunning(tainr *types.Container, name string, wait int) error {
+func (in *instance) waitInitContainerRunning(ctx context.Context, tainr *types.Container, name string, wait int) error {
 	for max := 0; max < wait; max++ {
-		pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+		pod, err := in.cli.CoreV1().Pods(in.namespace).Get(ctx, tainr.GetPodName(), metav1.GetOptions{})
 		if err != nil {
 			return err
 		}
@@ -478,7 +952,9 @@ func (in *instance) waitInitContainerRunning(tainr *types.Container, name string
 				return nil
 			}
 		}
-		time.Sleep(time.Second)
+		if err := sleep(ctx, time.Second); err != nil {
+			return err
+		}
 	}
 	return fmt.Errorf("timeout starting container")
 }
@@ -486,7 +962,7 @@ func (in *instance) waitInitContainerRunning(tainr *types.Container, name string
 // createSetupInitContainer creates an init container in order to copy data
 // to the main container before starting it
 func (in *instance) createSetupInitContainer(tainr *types.Container) (*corev1.Container, error) {
-	pulpol, err := tainr.GetImagePullPolicy()
+	pulpol, err := tainr.GetImagePullPolicyFor(in.initImage)
 	if err != nil {
 		return nil, err
 	}
@@ -518,7 +994,11 @@ func (in *instance) addSetupInitContainer(tainr *types.Container, pod *corev1.Po
 // volume mounts in both the init container and "main" container in order
 // to copy data before the container is started. If files are included,
 // rather than folders, it will create a configmap, and mounts the files
-// from this created configmap.
+// from this created configmap. If the archive helper is enabled, the
+// folders are kept on the data volume claim of the container, so they
+// are available to the archive helper after the container has exited; the
+// propagation and relabel options of the volumes are only applied to these
+// mounts, as pod local volumes are not shared with anything else.
 func (in *instance) addVolumes(tainr *types.Container, pod *corev1.Pod) error {
 	initContainer, err := in.addSetupInitContainer(tainr, pod)
 	if err != nil {
@@ -528,11 +1008,21 @@ func (in *instance) addVolumes(tainr *types.Container, pod *corev1.Pod) error {
 	volumes := []corev1.Volume{}
 	mounts := []corev1.VolumeMount{}
 
-	for dst := range tainr.GetVolumeFolders() {
-		id := in.toKubernetesName(dst)
-		volumes = append(volumes,
-			corev1.Volume{Name: id, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
-		mounts = append(mounts, corev1.VolumeMount{Name: id, MountPath: dst})
+	if in.archiveHelper {
+		if err := in.addDataVolume(tainr, pod); err != nil {
+			return err
+		}
+		data := in.getDataVolumeMounts(tainr)
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, data...)
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, in.getMountPropagation(tainr, data)...)
+		in.addSELinuxLevel(tainr, pod)
+	} else {
+		for dst := range tainr.GetVolumeFolders() {
+			id := in.toKubernetesName(dst)
+			volumes = append(volumes,
+				corev1.Volume{Name: id, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
+			mounts = append(mounts, corev1.VolumeMount{Name: id, MountPath: dst})
+		}
 	}
 
 	vfiles := tainr.GetVolumeFiles()
@@ -560,13 +1050,68 @@ func (in *instance) addVolumes(tainr *types.Container, pod *corev1.Pod) error {
 	}
 
 	initContainer.VolumeMounts = append(initContainer.VolumeMounts, mounts...)
-	pod.Spec.InitContainers = []corev1.Container{*initContainer}
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, *initContainer)
 	pod.Spec.Volumes = append(pod.Spec.Volumes, volumes...)
 	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, mounts...)
 
 	return nil
 }
 
+// getMountPropagation will return a copy of given volume mounts, with the
+// mount propagation set as requested in the options of the volumes. As
+// bidirectional propagation is only allowed for privileged containers,
+// shared propagation is mapped to host-to-container propagation.
+func (in *instance) getMountPropagation(tainr *types.Container, mounts []corev1.VolumeMount) []corev1.VolumeMount {
+	opts := tainr.GetVolumeOptions()
+	res := []corev1.VolumeMount{}
+	for _, mount := range mounts {
+		var mode corev1.MountPropagationMode
+		switch opts[mount.MountPath].Propagation {
+		case "private", "rprivate":
+			mode = corev1.MountPropagationNone
+		case "slave", "rslave":
+			mode = corev1.MountPropagationHostToContainer
+		case "shared", "rshared":
+			klog.Infof("using rslave rather than %s propagation for %s, bidirectional propagation requires a privileged container", opts[mount.MountPath].Propagation, mount.MountPath)
+			mode = corev1.MountPropagationHostToContainer
+		}
+		if mode != "" {
+			mount.MountPropagation = &mode
+		}
+		res = append(res, mount)
+	}
+	return res
+}
+
+// addSELinuxLevel will set the SELinux level of the given pod, if the volumes
+// of the container should be relabeled (z or Z option) and relabeling is
+// enabled. A level that is set in the pod template is not overridden, and
+// with the openshift-restricted profile the level that is assigned by the
+// security context constraints is used instead.
+func (in *instance) addSELinuxLevel(tainr *types.Container, pod *corev1.Pod) {
+	level := tainr.GetSELinuxLevel()
+	if level == "" {
+		return
+	}
+	if !in.selinuxRelabel {
+		klog.V(3).Infof("ignoring z/Z volume options of container %s, selinux relabeling is disabled", tainr.ShortID)
+		return
+	}
+	if in.securityProfile == SecurityProfileOpenShiftRestricted {
+		klog.V(3).Infof("ignoring z/Z volume options of container %s, the selinux level is assigned by openshift", tainr.ShortID)
+		return
+	}
+	if pod.Spec.SecurityContext == nil {
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if pod.Spec.SecurityContext.SELinuxOptions == nil {
+		pod.Spec.SecurityContext.SELinuxOptions = &corev1.SELinuxOptions{}
+	}
+	if pod.Spec.SecurityContext.SELinuxOptions.Level == "" {
+		pod.Spec.SecurityContext.SELinuxOptions.Level = level
+	}
+}
+
 // addPreArchives will create configmaps from files, add volume and volume
 // mounts to the setup init container and main container, in order to copy data
 // before the container is started.
@@ -621,10 +1166,114 @@ func (in *instance) addPreArchives(tainr *types.Container, pod *corev1.Pod) erro
 	return nil
 }
 
+// addDevices will pass through the devices of the container, either by
+// requesting the device plugin resource the device is mapped to, or by
+// mounting the device as a hostPath volume. Devices are mounted with
+// host-to-container propagation, so devices that are added to a device
+// folder on the node (e.g. /dev/bus/usb) become visible in the container.
+func (in *instance) addDevices(tainr *types.Container, pod *corev1.Pod, container *corev1.Container) {
+	for i, dev := range tainr.Devices {
+		if dev.Resource != "" {
+			name := corev1.ResourceName(dev.Resource)
+			if container.Resources.Limits == nil {
+				container.Resources.Limits = corev1.ResourceList{}
+			}
+			qty := container.Resources.Limits[name]
+			qty.Add(resource.MustParse("1"))
+			container.Resources.Limits[name] = qty
+			continue
+		}
+		id := fmt.Sprintf("device-%d", i)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name:         id,
+			VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: dev.HostPath}},
+		})
+		mode := corev1.MountPropagationHostToContainer
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: id, MountPath: dev.ContainerPath, MountPropagation: &mode})
+	}
+}
+
+// addCommandWrapper will wrap the command of the main container with the
+// kubedock unpack command, which extracts the staged archives, the kubedock
+// ulimit command, which applies the ulimits of the container, and the
+// kubedock stdtag command, which tags all stderr output so it can be
+// separated from stdout in the (merged) pod logs. The kubedock binary is
+// made available via an init container that copies it to a shared volume.
+func (in *instance) addCommandWrapper(tainr *types.Container, pod *corev1.Pod) error {
+	entrypoint, cmd := tainr.Entrypoint, tainr.Cmd
+	if len(entrypoint) == 0 {
+		img, err := in.InspectImage(tainr.Image)
+		if err != nil {
+			return fmt.Errorf("error resolving entrypoint to wrap command: %w", err)
+		}
+		entrypoint = img.Config.Config.Entrypoint
+		if len(cmd) == 0 {
+			cmd = img.Config.Config.Cmd
+		}
+	}
+	if len(entrypoint) == 0 && len(cmd) == 0 {
+		return fmt.Errorf("no command to wrap")
+	}
+
+	pulpol, err := tainr.GetImagePullPolicyFor(in.initImage)
+	if err != nil {
+		return err
+	}
+
+	mount := corev1.VolumeMount{Name: "kubedock-bin", MountPath: kubedockBinPath}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name:         mount.Name,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	setup := in.containerTemplate
+	setup.Name = "stdtag"
+	setup.Image = in.initImage
+	setup.ImagePullPolicy = pulpol
+	setup.Command = []string{"cp", "/usr/local/bin/kubedock", kubedockBinPath + "/kubedock"}
+	setup.VolumeMounts = []corev1.VolumeMount{mount}
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, setup)
+
+	main := &pod.Spec.Containers[0]
+	wrapper := []string{}
+	if tainr.HasStagedArchives() {
+		if err := in.addDataVolume(tainr, pod); err != nil {
+			return err
+		}
+		main.VolumeMounts = append(main.VolumeMounts, corev1.VolumeMount{
+			Name:      dataVolumeName,
+			MountPath: stagedArchivePath,
+			SubPath:   dataArchivesFolder,
+			ReadOnly:  true,
+		})
+		wrapper = append(wrapper, kubedockBinPath+"/kubedock", "unpack")
+		for _, pa := range tainr.StagedArchives {
+			wrapper = append(wrapper, filepath.Join(stagedArchivePath, pa.File)+"="+pa.Path)
+		}
+		wrapper = append(wrapper, "--")
+	}
+	if len(tainr.Ulimits) > 0 {
+		wrapper = append(wrapper, kubedockBinPath+"/kubedock", "ulimit")
+		for _, l := range tainr.Ulimits {
+			wrapper = append(wrapper, l.String())
+		}
+		wrapper = append(wrapper, "--")
+	}
+	if tainr.SeparateStderr() {
+		wrapper = append(wrapper, kubedockBinPath+"/kubedock", "stdtag", "--")
+	}
+
+	main.Command = append(wrapper, entrypoint...)
+	main.Args = cmd
+	main.VolumeMounts = append(main.VolumeMounts, mount)
+
+	return nil
+}
+
 // addDindSidecar will add a docker-in-docker sidecar, adding a volume
 // with /var/run/docker.sock to support docker-in-docker.
 func (in *instance) addDindSidecar(tainr *types.Container, pod *corev1.Pod) error {
-	pulpol, err := tainr.GetImagePullPolicy()
+	pulpol, err := tainr.GetImagePullPolicyFor(in.dindImage)
 	if err != nil {
 		return err
 	}
@@ -734,8 +1383,8 @@ func (in *instance) createConfigMapFromRaw(tainr *types.Container, files map[str
 // copyVolumeFolders will copy the configured volumes of the container to
 // the running init container, and signal the init container when finished
 // with copying.
-func (in *instance) copyVolumeFolders(tainr *types.Container, wait int) error {
-	if err := in.waitInitContainerRunning(tainr, SetupInitContainerName, wait); err != nil {
+func (in *instance) copyVolumeFolders(ctx context.Context, tainr *types.Container, wait int) error {
+	if err := in.waitInitContainerRunning(ctx, tainr, SetupInitContainerName, wait); err != nil {
 		return err
 	}
 
@@ -772,11 +1421,61 @@ func (in *instance) copyVolumeFolders(tainr *types.Container, wait int) error {
 		}); err != nil {
 			klog.Warningf("error during copy: %s", err)
 		}
+		in.chgrpVolumeFolder(pod, dst)
+	}
+
+	// restore the volume contents that were saved during a checkpoint
+	for dst, file := range tainr.Checkpoint {
+		if _, ok := volumes[dst]; !ok {
+			continue
+		}
+		if err := in.restoreCheckpoint(pod, dst, file); err != nil {
+			klog.Warningf("error during restore of %s: %s", dst, err)
+		}
 	}
 
 	return in.touchFileInContainer(tainr, SetupInitContainerName, "/tmp/done")
 }
 
+// restoreCheckpoint will extract the given checkpoint archive of given
+// volume folder in the given pod.
+func (in *instance) restoreCheckpoint(pod *corev1.Pod, dst, file string) error {
+	archive, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	return exec.RemoteCmd(exec.Request{
+		Client:     in.cli,
+		RestConfig: in.cfg,
+		Pod:        *pod,
+		Container:  SetupInitContainerName,
+		Cmd:        []string{"tar", "-xf", "-", "-C", filepath.Dir(dst)},
+		Stdin:      archive,
+	})
+}
+
+// chgrpVolumeFolder will make the copied contents of given volume folder
+// owned by, and writable for, the fsGroup of the pod, if configured. The
+// fsGroup only applies to the volume itself; files that are extracted keep
+// the ownership of the archive, which would make them unwritable for images
+// that run as an arbitrary (non-root) user.
+func (in *instance) chgrpVolumeFolder(pod *corev1.Pod, dst string) {
+	if pod.Spec.SecurityContext == nil || pod.Spec.SecurityContext.FSGroup == nil {
+		return
+	}
+	gid := strconv.FormatInt(*pod.Spec.SecurityContext.FSGroup, 10)
+	if err := exec.RemoteCmd(exec.Request{
+		Client:     in.cli,
+		RestConfig: in.cfg,
+		Pod:        *pod,
+		Container:  SetupInitContainerName,
+		Cmd:        []string{"sh", "-c", `chgrp -R "$0" "$1" && chmod -R g+rwX "$1"`, gid, dst},
+	}); err != nil {
+		klog.Warningf("error changing group of %s to %s: %s", dst, gid, err)
+	}
+}
+
 // fileID will create an unique k8s compatible id to refer to the given file.
 func (in *instance) fileID(file string) string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(file)))
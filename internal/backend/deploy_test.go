@@ -2,19 +2,27 @@ package backend
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
 
+	"github.com/joyrex2001/kubedock/internal/config"
 	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
 )
 
 var tarSingle = []byte{
@@ -286,7 +294,7 @@ func TestStartContainer(t *testing.T) {
 	}
 
 	for i, tst := range tests {
-		state, err := tst.kub.StartContainer(tst.in)
+		state, err := tst.kub.StartContainer(context.Background(), tst.in)
 		if err != nil && !tst.err {
 			t.Errorf("failed test %d - unexpected error %s", i, err)
 		}
@@ -341,7 +349,7 @@ func TestStartContainerAddsActiveDeadlineSeconds(t *testing.T) {
 		return strconv.FormatInt(*v, 10)
 	}
 	for i, tst := range tests {
-		state, err := tst.kub.StartContainer(tst.in)
+		state, err := tst.kub.StartContainer(context.Background(), tst.in)
 		if err != nil {
 			t.Errorf("failed test %d - unexpected return value %s", i, err)
 		}
@@ -359,6 +367,254 @@ func TestStartContainerAddsActiveDeadlineSeconds(t *testing.T) {
 	}
 }
 
+func TestStartContainerDisableSidecarInjection(t *testing.T) {
+	pt := &corev1.Pod{Status: corev1.PodStatus{
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "main", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+		},
+	}}
+	for i, disable := range []bool{false, true} {
+		kub := &instance{
+			namespace:        "default",
+			cli:              fake.NewSimpleClientset(),
+			podTemplate:      pt,
+			timeOut:          10,
+			disableInjection: disable,
+		}
+		if _, err := kub.StartContainer(context.Background(), &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit"}); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		pod, err := kub.cli.CoreV1().Pods("default").Get(context.Background(), "kubedock-f1spirit-tb303", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error %s", i, err)
+		}
+		if _, ok := pod.Labels["sidecar.istio.io/inject"]; ok != disable {
+			t.Errorf("failed test %d - expected istio inject label %v, but got %v", i, disable, pod.Labels)
+		}
+		if _, ok := pod.Annotations["linkerd.io/inject"]; ok != disable {
+			t.Errorf("failed test %d - expected linkerd inject annotation %v, but got %v", i, disable, pod.Annotations)
+		}
+	}
+}
+
+func TestWaitPodReady(t *testing.T) {
+	tests := []struct {
+		statuses []corev1.ContainerStatus
+		err      bool
+	}{
+		{
+			statuses: []corev1.ContainerStatus{{Name: "main", Ready: true}, {Name: "istio-proxy", Ready: false}},
+			err:      false,
+		},
+		{
+			statuses: []corev1.ContainerStatus{{Name: "main", Ready: false}, {Name: "istio-proxy", Ready: true}},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		kub := &instance{
+			namespace: "default",
+			cli: fake.NewSimpleClientset(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubedock-f1spirit-tb303", Namespace: "default"},
+				Status: corev1.PodStatus{
+					Phase:             corev1.PodRunning,
+					Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+					ContainerStatuses: tst.statuses,
+				},
+			}),
+		}
+		err := kub.waitPodReady(context.Background(), &types.Container{ShortID: "tb303", Name: "f1spirit"}, 1)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %v, but got %v", i, tst.err, err)
+		}
+	}
+}
+
+func TestStartContainerHostNetwork(t *testing.T) {
+	pt := &corev1.Pod{Status: corev1.PodStatus{
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "main", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+		},
+	}}
+	tests := []struct {
+		in      *types.Container
+		hostnet bool
+		dns     corev1.DNSPolicy
+	}{
+		{in: &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit"}, hostnet: false, dns: ""},
+		{in: &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit", HostNetwork: true}, hostnet: true, dns: corev1.DNSClusterFirstWithHostNet},
+	}
+	for i, tst := range tests {
+		kub := &instance{
+			namespace:   "default",
+			cli:         fake.NewSimpleClientset(),
+			podTemplate: pt,
+			timeOut:     10,
+		}
+		if _, err := kub.StartContainer(context.Background(), tst.in); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		o, err := kub.cli.(*fake.Clientset).Tracker().Get(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "default", "kubedock-f1spirit-tb303")
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error %s", i, err)
+		}
+		pod := o.(*corev1.Pod)
+		if pod.Spec.HostNetwork != tst.hostnet {
+			t.Errorf("failed test %d - expected hostNetwork %t, but got %t", i, tst.hostnet, pod.Spec.HostNetwork)
+		}
+		if pod.Spec.DNSPolicy != tst.dns {
+			t.Errorf("failed test %d - expected dnsPolicy %s, but got %s", i, tst.dns, pod.Spec.DNSPolicy)
+		}
+	}
+}
+
+func TestStartContainerUlimits(t *testing.T) {
+	pt := &corev1.Pod{Status: corev1.PodStatus{
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "main", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+		},
+	}}
+	tests := []struct {
+		in      *types.Container
+		command []string
+		init    int
+	}{
+		{
+			in:      &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit", Entrypoint: []string{"/bin/konami"}},
+			command: []string{"/bin/konami"},
+			init:    0,
+		},
+		{
+			in: &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit", Entrypoint: []string{"/bin/konami"},
+				Ulimits: []ulimit.Limit{{Name: "nofile", Soft: 65536, Hard: 65536}}},
+			command: []string{kubedockBinPath + "/kubedock", "ulimit", "nofile=65536:65536", "--", "/bin/konami"},
+			init:    1,
+		},
+		{
+			in: &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit", Entrypoint: []string{"/bin/konami"},
+				Ulimits: []ulimit.Limit{{Name: "nofile", Soft: 1024, Hard: 2048}},
+				Labels:  map[string]string{types.LabelSeparateStderr: "true"}},
+			command: []string{kubedockBinPath + "/kubedock", "ulimit", "nofile=1024:2048", "--", kubedockBinPath + "/kubedock", "stdtag", "--", "/bin/konami"},
+			init:    1,
+		},
+		{
+			in: &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit", Entrypoint: []string{"/bin/konami"},
+				StagedArchives: []types.PreArchive{{Path: "/etc", File: "archive-0"}, {Path: "/opt", File: "archive-1"}}},
+			command: []string{kubedockBinPath + "/kubedock", "unpack", stagedArchivePath + "/archive-0=/etc", stagedArchivePath + "/archive-1=/opt", "--", "/bin/konami"},
+			init:    1,
+		},
+	}
+	for i, tst := range tests {
+		kub := &instance{
+			namespace:   "default",
+			cli:         fake.NewSimpleClientset(),
+			podTemplate: pt,
+			timeOut:     10,
+		}
+		if _, err := kub.StartContainer(context.Background(), tst.in); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		o, err := kub.cli.(*fake.Clientset).Tracker().Get(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "default", "kubedock-f1spirit-tb303")
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error %s", i, err)
+		}
+		pod := o.(*corev1.Pod)
+		if !reflect.DeepEqual(pod.Spec.Containers[0].Command, tst.command) {
+			t.Errorf("failed test %d - expected command %v, but got %v", i, tst.command, pod.Spec.Containers[0].Command)
+		}
+		if len(pod.Spec.InitContainers) != tst.init {
+			t.Errorf("failed test %d - expected %d init containers, but got %d", i, tst.init, len(pod.Spec.InitContainers))
+		}
+	}
+}
+
+func TestAddDevices(t *testing.T) {
+	tainr := &types.Container{Devices: []types.Device{
+		{HostPath: "/dev/kvm", ContainerPath: "/dev/kvm"},
+		{HostPath: "/dev/fuse", ContainerPath: "/dev/fuse", Resource: "smarter-devices/fuse"},
+		{HostPath: "/dev/fuse2", ContainerPath: "/dev/fuse2", Resource: "smarter-devices/fuse"},
+	}}
+	pod := &corev1.Pod{}
+	container := &corev1.Container{}
+	kub := &instance{}
+	kub.addDevices(tainr, pod, container)
+	vols := []corev1.Volume{{Name: "device-0", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/dev/kvm"}}}}
+	if !reflect.DeepEqual(pod.Spec.Volumes, vols) {
+		t.Errorf("failed - expected volumes %v, but got %v", vols, pod.Spec.Volumes)
+	}
+	slave := corev1.MountPropagationHostToContainer
+	mounts := []corev1.VolumeMount{{Name: "device-0", MountPath: "/dev/kvm", MountPropagation: &slave}}
+	if !reflect.DeepEqual(container.VolumeMounts, mounts) {
+		t.Errorf("failed - expected mounts %v, but got %v", mounts, container.VolumeMounts)
+	}
+	qty := container.Resources.Limits["smarter-devices/fuse"]
+	if qty.Value() != 2 {
+		t.Errorf("failed - expected 2 fuse devices, but got %s", qty.String())
+	}
+}
+
+func TestStartContainerCancelled(t *testing.T) {
+	kub := &instance{
+		namespace:   "default",
+		cli:         fake.NewSimpleClientset(),
+		podTemplate: &corev1.Pod{},
+		timeOut:     300,
+	}
+
+	container := &types.Container{
+		ID:       "rc752",
+		ShortID:  "abc123",
+		Name:     "test",
+		Networks: map[string]any{"bridge": true},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	begin := time.Now()
+	state, err := kub.StartContainer(ctx, container)
+	if state != DeployFailed || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected failed state with cancelled error, got: %d, %v", state, err)
+	}
+	if time.Since(begin) > 5*time.Second {
+		t.Errorf("expected start to be aborted promptly, took %s", time.Since(begin))
+	}
+
+	pods, _ := kub.cli.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 0 {
+		t.Errorf("expected pod to be removed, but found %d pods", len(pods.Items))
+	}
+}
+
+func TestStartContainerExecDisabled(t *testing.T) {
+	kub := &instance{
+		namespace:   "default",
+		cli:         fake.NewSimpleClientset(),
+		podTemplate: &corev1.Pod{},
+		timeOut:     300,
+		disabled:    map[string]*FeatureError{FeatureExec: {Feature: FeatureExec, Missing: []string{"create pods/exec"}}},
+	}
+
+	tests := []struct {
+		in  *types.Container
+		err bool
+	}{
+		{in: &types.Container{ID: "rc752", ShortID: "tb303", Binds: []string{"/tmp:/data"}}, err: true},
+		{in: &types.Container{ID: "rc753", ShortID: "mc505", PreArchives: []types.PreArchive{{Path: "/data"}}}, err: true},
+	}
+	for i, tst := range tests {
+		_, err := kub.StartContainer(context.Background(), tst.in)
+		var ferr *FeatureError
+		if errors.As(err, &ferr) != tst.err {
+			t.Errorf("failed test %d - expected feature error %t, but got %v", i, tst.err, err)
+		}
+	}
+	pods, _ := kub.cli.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 0 {
+		t.Errorf("expected no pods, but found %d pods", len(pods.Items))
+	}
+}
+
 func TestStartContainerIdempotency(t *testing.T) {
 	// Test that calling StartContainer twice doesn't delete the pod
 	existingPod := &corev1.Pod{
@@ -390,7 +646,7 @@ func TestStartContainerIdempotency(t *testing.T) {
 	}
 
 	// Call StartContainer when pod already exists
-	state, err := kub.StartContainer(container)
+	state, err := kub.StartContainer(context.Background(), container)
 
 	// Should not return error
 	if err != nil {
@@ -483,10 +739,33 @@ func TestWaitReadyState(t *testing.T) {
 			state: DeployCompleted,
 			out:   false,
 		},
+		{
+			kub: &instance{
+				namespace: "default",
+				cli: fake.NewSimpleClientset(&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kubedock-f1spirit-tr909",
+						Namespace: "default",
+						Labels:    map[string]string{"kubedock.containerid": "tr909"},
+					},
+					Status: corev1.PodStatus{
+						ContainerStatuses: []corev1.ContainerStatus{
+							{Name: "main", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+						},
+						EphemeralContainerStatuses: []corev1.ContainerStatus{
+							{Name: "linked-sh101", LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+						},
+					},
+				}),
+			},
+			in:    &types.Container{ID: "rc753", ShortID: "sh101", Name: "gradius", NetworkOwner: "rc752", NetworkPod: "kubedock-f1spirit-tr909"},
+			state: DeployCompleted,
+			out:   false,
+		},
 	}
 
 	for i, tst := range tests {
-		state, err := tst.kub.waitReadyState(tst.in, 1)
+		state, err := tst.kub.waitReadyState(context.Background(), tst.in, 1)
 		if (err != nil && !tst.out) || (err == nil && tst.out) {
 			t.Errorf("failed test %d - unexpected return value %s", i, err)
 		}
@@ -640,7 +919,7 @@ func TestWaitInitContainerRunning(t *testing.T) {
 	}
 
 	for i, tst := range tests {
-		res := tst.kub.waitInitContainerRunning(tst.in, tst.name, 1)
+		res := tst.kub.waitInitContainerRunning(context.Background(), tst.in, tst.name, 1)
 		if (res != nil && !tst.out) || (res == nil && tst.out) {
 			t.Errorf("failed test %d - unexpected return value %s", i, res)
 		}
@@ -704,6 +983,136 @@ func TestAddVolumes(t *testing.T) {
 	}
 }
 
+func TestAddVolumesArchiveHelper(t *testing.T) {
+	tainr := &types.Container{ShortID: "tb303", Binds: []string{".:/remote:rw"}}
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{}},
+		},
+	}
+	kub := &instance{
+		namespace:     "default",
+		cli:           fake.NewSimpleClientset(),
+		archiveHelper: true,
+		archiveSize:   resource.MustParse("1Gi"),
+	}
+	if err := kub.addVolumes(tainr, pod); err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].PersistentVolumeClaim == nil {
+		t.Fatalf("expected a single volume claim volume, but got %v", pod.Spec.Volumes)
+	}
+	claim := kub.getDataVolumeClaimName(tainr)
+	if name := pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName; name != claim || !strings.HasPrefix(name, "tb303-data-") {
+		t.Errorf("expected claim %s, but got %s", claim, name)
+	}
+	mount := pod.Spec.Containers[0].VolumeMounts[0]
+	if sub := dataVolumesFolder + "/" + kub.toKubernetesName("/remote"); mount.MountPath != "/remote" || mount.SubPath != sub {
+		t.Errorf("expected /remote mounted from %s, but got %s from %s", sub, mount.MountPath, mount.SubPath)
+	}
+	if _, err := kub.cli.CoreV1().PersistentVolumeClaims("default").Get(context.Background(), claim, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected volume claim to be created, but got: %v", err)
+	}
+}
+
+func TestAddDataVolumeExisting(t *testing.T) {
+	tainr := &types.Container{ID: "tb303tb303tb303", ShortID: "tb303"}
+	kub := &instance{namespace: "default", archiveHelper: true, archiveSize: resource.MustParse("1Gi")}
+	name := kub.getDataVolumeClaimName(tainr)
+	tests := []struct {
+		labels map[string]string
+		err    bool
+		owner  string
+	}{
+		{labels: map[string]string{"kubedock": "true", "kubedock.containerid": "tb303"}, err: false},
+		{labels: map[string]string{"kubedock": "true", "kubedock.containerid": "sh101"}, err: true, owner: "sh101"},
+		{labels: map[string]string{"app": "tb303", "kubedock.containerid": "tb303"}, err: true},
+	}
+	for i, tst := range tests {
+		kub.cli = fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: tst.labels},
+		})
+		pod := &corev1.Pod{}
+		err := kub.addDataVolume(tainr, pod)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+		var cerr *VolumeClaimConflictError
+		if err != nil && (!errors.As(err, &cerr) || cerr.Owner != tst.owner || cerr.HTTPStatus() != http.StatusConflict) {
+			t.Errorf("failed test %d - expected a conflict with owner '%s', but got %v", i, tst.owner, err)
+		}
+		if err == nil && len(pod.Spec.Volumes) != 1 {
+			t.Errorf("failed test %d - expected the data volume to be added, but got %v", i, pod.Spec.Volumes)
+		}
+	}
+}
+
+func TestAddVolumesOptions(t *testing.T) {
+	slave := corev1.MountPropagationHostToContainer
+	tests := []struct {
+		in          *types.Container
+		helper      bool
+		relabel     bool
+		profile     string
+		template    *corev1.PodSecurityContext
+		propagation *corev1.MountPropagationMode
+		level       string
+	}{
+		{in: &types.Container{ID: "tb303", Binds: []string{".:/remote:rw"}}, helper: true},
+		{in: &types.Container{ID: "tb303", Binds: []string{".:/remote:rslave"}}, helper: true, propagation: &slave},
+		{in: &types.Container{ID: "tb303", Binds: []string{".:/remote:rshared"}}, helper: true, propagation: &slave},
+		{in: &types.Container{ID: "tb303", Binds: []string{".:/remote:rslave,z"}}, relabel: true},
+		{in: &types.Container{ID: "tb303", Binds: []string{".:/remote:z"}}, helper: true},
+		{in: &types.Container{ID: "tb303", Binds: []string{".:/remote:z"}}, helper: true, relabel: true, level: "s0"},
+		{
+			in:      &types.Container{ID: "tb303", Binds: []string{".:/remote:z"}},
+			helper:  true,
+			relabel: true,
+			profile: SecurityProfileOpenShiftRestricted,
+		},
+		{
+			in:       &types.Container{ID: "tb303", Binds: []string{".:/remote:Z"}},
+			helper:   true,
+			relabel:  true,
+			template: &corev1.PodSecurityContext{SELinuxOptions: &corev1.SELinuxOptions{Level: "s0:c1,c2"}},
+			level:    "s0:c1,c2",
+		},
+	}
+
+	for i, tst := range tests {
+		pod := &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers:      []corev1.Container{{}},
+				SecurityContext: tst.template,
+			},
+		}
+		kub := &instance{
+			namespace:       "default",
+			cli:             fake.NewSimpleClientset(),
+			archiveHelper:   tst.helper,
+			archiveSize:     resource.MustParse("1Gi"),
+			selinuxRelabel:  tst.relabel,
+			securityProfile: tst.profile,
+		}
+		if err := kub.addVolumes(tst.in, pod); err != nil {
+			t.Errorf("expected no error but got: %v", err)
+		}
+		if pg := pod.Spec.Containers[0].VolumeMounts[0].MountPropagation; !reflect.DeepEqual(pg, tst.propagation) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.propagation, pg)
+		}
+		if pg := pod.Spec.InitContainers[0].VolumeMounts[0].MountPropagation; pg != nil {
+			t.Errorf("failed test %d - expected no propagation for init container, but got %v", i, pg)
+		}
+		level := ""
+		if sc := pod.Spec.SecurityContext; sc != nil && sc.SELinuxOptions != nil {
+			level = sc.SELinuxOptions.Level
+		}
+		if level != tst.level {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.level, level)
+		}
+	}
+}
+
 func TestAddVolumesAndPreArchives(t *testing.T) {
 	tests := []struct {
 		in    *types.Container
@@ -773,6 +1182,7 @@ func TestGetLabels(t *testing.T) {
 		{in: &types.Container{Labels: map[string]string{"/": "abc"}}, labels: nil, count: 3},
 		{in: &types.Container{Labels: map[string]string{"computer": "msx"}}, labels: map[string]string{"computer": "msx"}, count: 4},
 		{in: &types.Container{Labels: map[string]string{"computer": "msx"}}, labels: map[string]string{"game": "on"}, count: 5},
+		{in: &types.Container{Labels: map[string]string{types.LabelComposeProject: "demo", types.LabelComposeService: "db"}}, labels: nil, count: 7},
 	}
 
 	for i, tst := range tests {
@@ -804,6 +1214,12 @@ func TestGetServices(t *testing.T) {
 		{in: &types.Container{NetworkAliases: []string{"tb303", "tr909"}, ExposedPorts: map[string]interface{}{"100/tcp": 1}, HostPorts: map[int]int{200: 200}}, svcs: 2, ports: 2},
 		{in: &types.Container{NetworkAliases: []string{"tb303_"}, ExposedPorts: map[string]interface{}{"100/tcp": 1}}, svcs: 0, ports: 0},
 		{in: &types.Container{NetworkAliases: []string{"303"}, ExposedPorts: map[string]interface{}{"100/tcp": 1}}, svcs: 0, ports: 0},
+		{in: &types.Container{Labels: map[string]string{types.LabelComposeProject: "demo", types.LabelComposeService: "db"}, ExposedPorts: map[string]interface{}{"5432/tcp": 1}}, svcs: 1, ports: 1},
+		{in: &types.Container{Labels: map[string]string{types.LabelComposeProject: "demo", types.LabelComposeService: "db"}, NetworkAliases: []string{"db", "demo-db-1"}, ExposedPorts: map[string]interface{}{"5432/tcp": 1}}, svcs: 2, ports: 1},
+		{in: &types.Container{Labels: map[string]string{types.LabelComposeService: "db"}, ExposedPorts: map[string]interface{}{"5432/tcp": 1}}, svcs: 0, ports: 0},
+		{in: &types.Container{Name: "db", NetworkAliases: []string{"database", "DB"}, ExposedPorts: map[string]interface{}{"5432/tcp": 1}}, svcs: 2, ports: 1},
+		{in: &types.Container{Name: "elegant_turing", ExposedPorts: map[string]interface{}{"5432/tcp": 1}}, svcs: 0, ports: 0},
+		{in: &types.Container{Labels: map[string]string{types.LabelComposeProject: "demo", types.LabelComposeService: "db_1"}, ExposedPorts: map[string]interface{}{"5432/tcp": 1}}, svcs: 0, ports: 0},
 	}
 	for i, tst := range tests {
 		kub := &instance{}
@@ -836,6 +1252,19 @@ func TestGetServices(t *testing.T) {
 	if !reflect.DeepEqual(res[0].Spec.Ports, exp) {
 		t.Errorf("failed detail ports test - expected %#v, but got %#v", res[0].Spec.Ports, exp)
 	}
+
+	res = kub.getServices(&types.Container{
+		ShortID:      "tb303",
+		Labels:       map[string]string{types.LabelComposeProject: "demo", types.LabelComposeService: "db"},
+		ExposedPorts: map[string]interface{}{"5432/tcp": 1},
+	})
+	sel := map[string]string{"kubedock.id": config.InstanceID, "kubedock.project": "demo", "kubedock.service": "db"}
+	if res[0].Name != "db" || !reflect.DeepEqual(res[0].Spec.Selector, sel) {
+		t.Errorf("failed compose service test - expected db with %v, but got %s with %v", sel, res[0].Name, res[0].Spec.Selector)
+	}
+	if _, ok := res[0].Labels["kubedock.containerid"]; ok {
+		t.Errorf("failed compose service test - expected no kubedock.containerid label, but got %v", res[0].Labels)
+	}
 }
 
 func TestGetAnnotations(t *testing.T) {
@@ -848,6 +1277,7 @@ func TestGetAnnotations(t *testing.T) {
 		{in: &types.Container{Labels: map[string]string{"computer": "msx"}}, annotations: nil, count: 2},
 		{in: &types.Container{Labels: map[string]string{"computer": "msx"}}, annotations: map[string]string{"computer": "msx"}, count: 2},
 		{in: &types.Container{Labels: map[string]string{"computer": "msx"}}, annotations: map[string]string{"game": "on"}, count: 3},
+		{in: &types.Container{Labels: map[string]string{types.LabelComposeProject: "demo", types.LabelComposeService: "db"}}, annotations: nil, count: 4},
 	}
 
 	for i, tst := range tests {
@@ -858,3 +1288,313 @@ func TestGetAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateServices(t *testing.T) {
+	kub := &instance{
+		namespace: "default",
+		cli:       fake.NewSimpleClientset(),
+	}
+	tainr := &types.Container{
+		ShortID:      "abcdef123456",
+		ExposedPorts: map[string]interface{}{"100/tcp": 1},
+	}
+	tests := []struct {
+		aliases []string
+		svcs    []string
+	}{
+		{aliases: []string{"tb303"}, svcs: []string{"tb303"}},
+		{aliases: []string{"tb303", "tr909"}, svcs: []string{"tb303", "tr909"}},
+		{aliases: []string{"tr808"}, svcs: []string{"tr808"}},
+		{aliases: []string{}, svcs: []string{}},
+	}
+	for i, tst := range tests {
+		tainr.NetworkAliases = tst.aliases
+		if err := kub.UpdateServices(tainr); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		svcs, _ := kub.cli.CoreV1().Services("default").List(context.Background(), metav1.ListOptions{})
+		names := []string{}
+		for _, svc := range svcs.Items {
+			names = append(names, svc.Name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, tst.svcs) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.svcs, names)
+		}
+	}
+}
+
+func TestWatchContainerExitCode(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kubedock-vg8020",
+			Namespace: "default",
+			Labels:    map[string]string{"kubedock.containerid": "vg8020"},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+	cli := fake.NewSimpleClientset(pod)
+	kub := &instance{namespace: "default", cli: cli}
+	tainr := &types.Container{ID: "vg8020", ShortID: "vg8020", Name: "vg8020"}
+
+	exitch, err := kub.WatchContainerExit(tainr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	select {
+	case <-exitch:
+		t.Errorf("failed running - container reported exited while running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 3}}
+	if _, err := cli.CoreV1().Pods("default").UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	select {
+	case exit, ok := <-exitch:
+		if !ok || exit.Code != 3 || exit.Reason != "" {
+			t.Errorf("failed exit - expected exit code 3, but got %v (%t)", exit, ok)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("failed exit - container exit not reported")
+	}
+
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kubedock-hx20",
+			Namespace: "default",
+			Labels:    map[string]string{"kubedock.containerid": "hx20"},
+		},
+	}
+	if _, err := cli.CoreV1().Pods("default").Create(context.Background(), running, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	exitch, err = kub.WatchContainerExit(&types.Container{ID: "hx20", ShortID: "hx20", Name: "hx20"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := cli.CoreV1().Pods("default").Delete(context.Background(), running.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	select {
+	case exit, ok := <-exitch:
+		if ok {
+			t.Errorf("failed delete - expected no exit code, but got %d", exit.Code)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("failed delete - channel not closed after delete")
+	}
+
+	evicted := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kubedock-ev20",
+			Namespace: "default",
+			Labels:    map[string]string{"kubedock.containerid": "ev20"},
+		},
+	}
+	if _, err := cli.CoreV1().Pods("default").Create(context.Background(), evicted, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	exitch, err = kub.WatchContainerExit(&types.Container{ID: "ev20", ShortID: "ev20", Name: "ev20"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	evicted.Status = corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", Message: "node was low on memory"}
+	if _, err := cli.CoreV1().Pods("default").UpdateStatus(context.Background(), evicted, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	select {
+	case exit, ok := <-exitch:
+		if !ok || exit.Code != 137 || exit.Reason != "Evicted: node was low on memory" {
+			t.Errorf("failed evict - expected exit code 137 with reason, but got %v (%t)", exit, ok)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("failed evict - container exit not reported")
+	}
+}
+
+func TestGetPodDisruption(t *testing.T) {
+	tests := []struct {
+		in  corev1.PodStatus
+		out string
+	}{
+		{in: corev1.PodStatus{Phase: corev1.PodRunning}, out: ""},
+		{in: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "DeadlineExceeded"}, out: ""},
+		{in: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", Message: "node was low on memory"}, out: "Evicted: node was low on memory"},
+		{in: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Preempting"}, out: "Preempting"},
+		{in: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+			{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue, Reason: "PreemptionByScheduler", Message: "preempted by high priority pod"},
+		}}, out: "PreemptionByScheduler: preempted by high priority pod"},
+		{in: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+			{Type: corev1.DisruptionTarget, Status: corev1.ConditionFalse, Reason: "EvictionByEvictionAPI"},
+		}}, out: ""},
+	}
+	for i, tst := range tests {
+		res := getPodDisruption(&corev1.Pod{Status: tst.in})
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+		kub := &instance{namespace: "default", cli: fake.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubedock-dx20", Namespace: "default"},
+			Status:     tst.in,
+		})}
+		_, err := kub.GetContainerStatus(&types.Container{ID: "dx20", ShortID: "dx20", PodName: "kubedock-dx20"})
+		var disruption *DisruptionError
+		if errors.As(err, &disruption) != (tst.out != "") {
+			t.Errorf("failed test %d - expected disruption error %t, but got %v", i, tst.out != "", err)
+		}
+	}
+}
+
+func TestCreateServicePrecedence(t *testing.T) {
+	svc := func(id, rank string) *corev1.Service {
+		labels := map[string]string{}
+		if id != "" {
+			labels["kubedock.containerid"] = id
+		}
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:        "db",
+			Namespace:   "default",
+			Labels:      labels,
+			Annotations: map[string]string{dnsRankAnnotation: rank},
+		}}
+	}
+	tests := []struct {
+		cur      *corev1.Service
+		in       *corev1.Service
+		out      string
+		restored string
+	}{
+		{cur: svc("tb303", dnsRankAlias), in: svc("mc505", dnsRankName), out: "mc505", restored: "tb303"},
+		{cur: svc("tb303", dnsRankName), in: svc("mc505", dnsRankAlias), out: "tb303", restored: "mc505"},
+		{cur: svc("tb303", dnsRankAlias), in: svc("mc505", dnsRankAlias), out: "tb303", restored: "mc505"},
+		{cur: svc("", dnsRankAlias), in: svc("mc505", dnsRankName), out: "mc505", restored: ""},
+		{cur: svc("tb303", dnsRankName), in: svc("", dnsRankAlias), out: "tb303", restored: ""},
+		{cur: svc("tb303", dnsRankName), in: svc("tb303", dnsRankAlias), out: "tb303"},
+	}
+	for i, tst := range tests {
+		kub := &instance{namespace: "default", cli: fake.NewSimpleClientset(tst.cur)}
+		if err := kub.createService(tst.in); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+			continue
+		}
+		res, _ := kub.cli.CoreV1().Services("default").Get(context.Background(), "db", metav1.GetOptions{})
+		if res.Labels["kubedock.containerid"] != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res.Labels["kubedock.containerid"])
+		}
+
+		// releasing the service restores the shadowed service, if any
+		if err := kub.deleteServices("kubedock.containerid=" + tst.out); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+			continue
+		}
+		res, err := kub.cli.CoreV1().Services("default").Get(context.Background(), "db", metav1.GetOptions{})
+		if tst.out == tst.in.Labels["kubedock.containerid"] && tst.out == tst.cur.Labels["kubedock.containerid"] {
+			if err == nil {
+				t.Errorf("failed test %d - expected service to be deleted, but got %v", i, res.Labels)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("failed test %d - expected restored service, but got %s", i, err)
+			continue
+		}
+		if res.Labels["kubedock.containerid"] != tst.restored {
+			t.Errorf("failed test %d - expected restored %s, but got %s", i, tst.restored, res.Labels["kubedock.containerid"])
+		}
+		if _, ok := res.Annotations[dnsShadowedAnnotation]; ok {
+			t.Errorf("failed test %d - expected no shadowed services, but got %s", i, res.Annotations[dnsShadowedAnnotation])
+		}
+	}
+}
+
+func TestCreateServiceCompose(t *testing.T) {
+	kub := &instance{namespace: "default", cli: fake.NewSimpleClientset()}
+	tainr := func(id, project string) *types.Container {
+		return &types.Container{
+			ShortID:      id,
+			Labels:       map[string]string{types.LabelComposeProject: project, types.LabelComposeService: "db"},
+			ExposedPorts: map[string]interface{}{"5432/tcp": 1},
+		}
+	}
+	tests := []struct {
+		in  *types.Container
+		err bool
+	}{
+		{in: tainr("tb303", "demo")},
+		{in: tainr("mc505", "demo")},
+		{in: tainr("tr909", "other"), err: true},
+	}
+	for i, tst := range tests {
+		err := kub.createServices(tst.in)
+		var conflict *ServiceConflictError
+		if errors.As(err, &conflict) != tst.err {
+			t.Errorf("failed test %d - expected conflict %t, but got %v", i, tst.err, err)
+		}
+	}
+	res, _ := kub.cli.CoreV1().Services("default").Get(context.Background(), "db", metav1.GetOptions{})
+	if res.Spec.Selector["kubedock.project"] != "demo" {
+		t.Errorf("failed test - expected service of project demo, but got %v", res.Spec.Selector)
+	}
+}
+
+func TestGetContainerStatusPullError(t *testing.T) {
+	tainr := &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit", Image: "alpine:nope"}
+	event := func(path, msg string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "evt-" + path, Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: tainr.GetPodName(), FieldPath: path},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "Failed",
+			Message:        msg,
+		}
+	}
+	tests := []struct {
+		reason string
+		msg    string
+		other  string
+		events []*corev1.Event
+		state  DeployState
+		pull   bool
+		denied bool
+		err    bool
+	}{
+		{reason: "ErrImagePull", msg: "failed to resolve reference \"docker.io/library/alpine:nope\": not found", state: DeployFailed, pull: true, err: true},
+		{reason: "ErrImagePull", msg: "pull access denied, repository does not exist or may require authorization", state: DeployFailed, pull: true, denied: true, err: true},
+		{reason: "ErrImagePull", msg: "dial tcp: i/o timeout", state: DeployPending},
+		{reason: "ImagePullBackOff", msg: "Back-off pulling image", state: DeployFailed, err: true},
+		{reason: "ErrImagePull", msg: "dial tcp: i/o timeout", other: "failed to resolve reference \"docker.io/library/sidecar:nope\": not found", state: DeployPending},
+		{reason: "ErrImagePull", msg: "dial tcp: i/o timeout", events: []*corev1.Event{event("spec.containers{sidecar}", "Failed to pull image \"sidecar:nope\": not found")}, state: DeployPending},
+		{reason: "ErrImagePull", msg: "dial tcp: i/o timeout", events: []*corev1.Event{event("spec.containers{main}", "Failed to pull image \"alpine:nope\": not found")}, state: DeployFailed, pull: true, err: true},
+	}
+	for i, tst := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: tainr.GetPodName(), Namespace: "default"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "alpine:nope"}, {Name: "sidecar", Image: "sidecar:nope"}}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: tst.reason, Message: tst.msg}}},
+			}},
+		}
+		if tst.other != "" {
+			pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+				Name: "sidecar", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: tst.other}},
+			})
+		}
+		cli := fake.NewSimpleClientset(pod)
+		for _, evt := range tst.events {
+			cli.CoreV1().Events("default").Create(context.Background(), evt, metav1.CreateOptions{})
+		}
+		kub := &instance{namespace: "default", cli: cli}
+		state, err := kub.GetContainerStatus(tainr)
+		var perr *image.PullError
+		pull := errors.As(err, &perr)
+		if state != tst.state || pull != tst.pull || (err != nil) != tst.err || (pull && perr.Denied != tst.denied) {
+			t.Errorf("failed test %d - expected %v/%v/%v, but got %v/%v/%v", i, tst.state, tst.pull, tst.err, state, pull, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// maxDiagnosticEvents is the maximum number of warning events that are
+// included in the diagnostics of a pod.
+const maxDiagnosticEvents = 3
+
+// getPodDiagnostics will return a description of why the pod of the given
+// container is not running, based on the status of the pod and the warning
+// events of the pod (e.g. FailedScheduling or pull errors). It will return
+// an empty string if no reason could be determined.
+func (in *instance) getPodDiagnostics(tainr *types.Container) string {
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	diag := getPodStatusDiagnostics(pod)
+	for _, msg := range in.getPodWarningEvents(pod) {
+		if !slices.Contains(diag, msg) {
+			diag = append(diag, msg)
+		}
+	}
+	return strings.Join(diag, "; ")
+}
+
+// getPodStatusDiagnostics will return the reasons why containers in the given
+// pod are waiting, and the reason why the pod is not scheduled, if any.
+func getPodStatusDiagnostics(pod *corev1.Pod) []string {
+	diag := []string{}
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+		for _, status := range statuses {
+			wait := status.State.Waiting
+			if wait == nil || wait.Reason == "" || wait.Reason == "ContainerCreating" || wait.Reason == "PodInitializing" {
+				continue
+			}
+			diag = append(diag, formatReason(wait.Reason, wait.Message))
+		}
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			diag = append(diag, formatReason(cond.Reason, cond.Message))
+		}
+	}
+	return diag
+}
+
+// getPodWarningEvents will return the messages of the most recent warning
+// events of the given pod. If kubedock is not allowed to list events, no
+// events are returned.
+func (in *instance) getPodWarningEvents(pod *corev1.Pod) []string {
+	if err := in.CheckFeature(FeatureEvents); err != nil {
+		return []string{}
+	}
+	evts, err := in.listPodEvents(pod)
+	if err != nil {
+		if !errors.IsForbidden(err) {
+			klog.Warningf("error listing events of pod %s: %s", pod.Name, err)
+		}
+		return []string{}
+	}
+	res := []string{}
+	for _, evt := range evts {
+		if evt.Type != corev1.EventTypeWarning {
+			continue
+		}
+		res = append(res, formatReason(evt.Reason, evt.Message))
+		if len(res) == maxDiagnosticEvents {
+			break
+		}
+	}
+	return res
+}
+
+// getPullFailureEvents will return the messages of the most recent warning
+// events of the pod of the given container, about failing to pull the image
+// of that container. If kubedock is not allowed to list events, no events
+// are returned.
+func (in *instance) getPullFailureEvents(tainr *types.Container) []string {
+	if err := in.CheckFeature(FeatureEvents); err != nil {
+		return []string{}
+	}
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+	if err != nil {
+		return []string{}
+	}
+	img := tainr.Image
+	for _, cont := range pod.Spec.Containers {
+		if cont.Name == tainr.GetContainerName() {
+			img = cont.Image
+		}
+	}
+	evts, err := in.listPodEvents(pod)
+	if err != nil {
+		return []string{}
+	}
+	path := "spec.containers{" + tainr.GetContainerName() + "}"
+	res := []string{}
+	for _, evt := range evts {
+		if evt.Type != corev1.EventTypeWarning || (evt.InvolvedObject.FieldPath != "" && evt.InvolvedObject.FieldPath != path) {
+			continue
+		}
+		if !strings.Contains(evt.Message, `image "`+img+`"`) {
+			continue
+		}
+		res = append(res, formatReason(evt.Reason, evt.Message))
+		if len(res) == maxDiagnosticEvents {
+			break
+		}
+	}
+	return res
+}
+
+// GetPodEvents will return the events of the pod of the given container,
+// newest first.
+func (in *instance) GetPodEvents(tainr *types.Container) ([]corev1.Event, error) {
+	if err := in.CheckFeature(FeatureEvents); err != nil {
+		return nil, err
+	}
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return in.listPodEvents(pod)
+}
+
+// listPodEvents will return the events of the given pod, newest first.
+func (in *instance) listPodEvents(pod *corev1.Pod) ([]corev1.Event, error) {
+	evts, err := in.cli.CoreV1().Events(in.namespace).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + pod.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := []corev1.Event{}
+	for _, evt := range evts.Items {
+		if evt.InvolvedObject.UID == pod.UID {
+			items = append(items, evt)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[j].LastTimestamp.Before(&items[i].LastTimestamp)
+	})
+	return items, nil
+}
+
+// formatReason will combine the given reason and message.
+func formatReason(reason, msg string) string {
+	if msg == "" {
+		return reason
+	}
+	return fmt.Sprintf("%s: %s", reason, msg)
+}
@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestGetPodDiagnostics(t *testing.T) {
+	tainr := &types.Container{ID: "rc765", ShortID: "rc765", Name: "salamander"}
+	meta := metav1.ObjectMeta{Name: tainr.GetPodName(), Namespace: "default", UID: "uid765"}
+	event := func(name, typ, reason, msg string, age time.Duration) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: meta.Name, UID: meta.UID},
+			Type:           typ,
+			Reason:         reason,
+			Message:        msg,
+			LastTimestamp:  metav1.NewTime(time.Now().Add(-age)),
+		}
+	}
+	tests := []struct {
+		pod    *corev1.Pod
+		events []*corev1.Event
+		out    string
+	}{
+		{
+			pod: &corev1.Pod{ObjectMeta: meta},
+			out: "",
+		},
+		{
+			pod: &corev1.Pod{ObjectMeta: meta, Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available: 3 Insufficient memory."}},
+			}},
+			out: "Unschedulable: 0/3 nodes are available: 3 Insufficient memory.",
+		},
+		{
+			pod: &corev1.Pod{ObjectMeta: meta, Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "main", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}},
+				},
+			}},
+			events: []*corev1.Event{
+				event("e1", corev1.EventTypeNormal, "Pulling", "Pulling image", 3*time.Second),
+				event("e2", corev1.EventTypeWarning, "Failed", "Failed to pull image: unauthorized", 2*time.Second),
+				event("e3", corev1.EventTypeWarning, "Failed", "Error: ErrImagePull", time.Second),
+			},
+			out: "ImagePullBackOff: Back-off pulling image; Failed: Error: ErrImagePull; Failed: Failed to pull image: unauthorized",
+		},
+	}
+	for i, tst := range tests {
+		cli := fake.NewSimpleClientset(tst.pod)
+		for _, evt := range tst.events {
+			cli.Tracker().Add(evt)
+		}
+		kub := &instance{namespace: "default", cli: cli}
+		if res := kub.getPodDiagnostics(tainr); res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package backend
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DisruptionError is returned when the pod of a container has been evicted,
+// preempted or otherwise disrupted by kubernetes.
+type DisruptionError struct {
+	Reason string
+}
+
+// Error will return a description of the disruption.
+func (e *DisruptionError) Error() string {
+	return "container was terminated by kubernetes: " + e.Reason
+}
+
+// getPodDisruption will return the reason why given pod was disrupted (e.g.
+// evicted because of node pressure, preempted by a higher priority pod or
+// deleted by a node drain), or an empty string if it wasn't disrupted.
+func getPodDisruption(pod *corev1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.DisruptionTarget && cond.Status == corev1.ConditionTrue {
+			return formatReason(cond.Reason, cond.Message)
+		}
+	}
+	if pod.Status.Phase == corev1.PodFailed && (pod.Status.Reason == "Evicted" || pod.Status.Reason == "Preempting") {
+		return formatReason(pod.Status.Reason, pod.Status.Message)
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// CopyFromContainer will write a tar archive of given path in the docker
+// container of given container to given writer.
+func (in *instance) CopyFromContainer(tainr *types.Container, target string, w io.Writer) error {
+	ctx := context.Background()
+	id, err := in.getContainerID(ctx, tainr)
+	if err != nil {
+		return err
+	}
+	reader, _, err := in.cli.CopyFromContainer(ctx, id, target)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// CopyToContainer will extract given (optionally compressed) tar archive at
+// given path in the docker container of given container. Docker detects
+// the compression itself.
+func (in *instance) CopyToContainer(tainr *types.Container, archive io.Reader, target string, compressed bool) error {
+	ctx := context.Background()
+	id, err := in.getContainerID(ctx, tainr)
+	if err != nil {
+		return err
+	}
+	return in.cli.CopyToContainer(ctx, id, target, archive, container.CopyToContainerOptions{})
+}
+
+// GetFileStatInContainer will return the details of a given path inside
+// the docker container of given container. If the path doesn't exist, an
+// error that wraps fs.ErrNotExist is returned.
+func (in *instance) GetFileStatInContainer(tainr *types.Container, target string) (*backend.FileStat, error) {
+	ctx := context.Background()
+	id, err := in.getContainerID(ctx, tainr)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := in.cli.ContainerStatPath(ctx, id, target)
+	if errdefs.IsNotFound(err) {
+		return nil, fmt.Errorf("%s: %w", target, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &backend.FileStat{
+		Name:       stat.Name,
+		Size:       stat.Size,
+		Mode:       stat.Mode,
+		ModTime:    stat.Mtime,
+		LinkTarget: stat.LinkTarget,
+	}, nil
+}
+
+// FileExistsInContainer will return true if given path exists in the docker
+// container of given container.
+func (in *instance) FileExistsInContainer(tainr *types.Container, target string) (bool, error) {
+	_, err := in.GetFileStatInContainer(tainr, target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// StartArchiveHelper will return an UnsupportedError, as docker supports
+// archive operations on containers that are not running.
+func (in *instance) StartArchiveHelper(tainr *types.Container) (*types.Container, error) {
+	return nil, &UnsupportedError{Feature: backend.FeatureArchiveHelper}
+}
+
+// StageArchive will return an UnsupportedError, as docker supports archive
+// operations on containers that are not running.
+func (in *instance) StageArchive(helper *types.Container, reader io.Reader) (string, error) {
+	return "", &UnsupportedError{Feature: backend.FeatureArchiveHelper}
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// DeleteAll will delete all docker containers and networks that are
+// labelled kubedock=true.
+func (in *instance) DeleteAll() error {
+	if err := in.removeContainers(context.Background(), "kubedock=true"); err != nil {
+		return err
+	}
+	return in.removeNetworks(context.Background(), "kubedock=true")
+}
+
+// DeleteWithKubedockID will delete all docker containers and networks of
+// the kubedock instance with given id.
+func (in *instance) DeleteWithKubedockID(id string) error {
+	if err := in.removeContainers(context.Background(), "kubedock.id="+id); err != nil {
+		return err
+	}
+	return in.removeNetworks(context.Background(), "kubedock.id="+id)
+}
+
+// DeleteProject will delete all docker containers of this kubedock instance
+// that belong to given docker compose project.
+func (in *instance) DeleteProject(project string) error {
+	return in.removeContainers(context.Background(), "kubedock.id="+config.InstanceID, "kubedock.project="+project)
+}
+
+// DeleteContainer will delete the docker container of given container.
+func (in *instance) DeleteContainer(tainr *types.Container) error {
+	if err := in.removeContainers(context.Background(), "kubedock.containerid="+tainr.ShortID); err != nil {
+		return fmt.Errorf("failed deleting container %s: %w", tainr.ShortID, err)
+	}
+	return nil
+}
+
+// RetainContainer will return false, as failed containers are not retained
+// by the docker backend.
+func (in *instance) RetainContainer(tainr *types.Container) (bool, error) {
+	return false, nil
+}
+
+// DeleteContainerVolumes is a no-op, as volumes are managed by docker.
+func (in *instance) DeleteContainerVolumes(tainr *types.Container) error {
+	return nil
+}
+
+// DeleteOlderThan will delete all docker containers that are labelled
+// kubedock=true and are older than the given keepmax duration.
+func (in *instance) DeleteOlderThan(keepmax time.Duration) error {
+	ctx := context.Background()
+	res, err := in.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "kubedock=true")),
+	})
+	if err != nil {
+		return err
+	}
+	for _, tainr := range res {
+		if time.Since(time.Unix(tainr.Created, 0)) <= keepmax {
+			continue
+		}
+		klog.V(3).Infof("deleting container: %s", tainr.ID)
+		if err := in.removeContainer(ctx, tainr.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeContainers will remove all docker containers that match all given
+// labels.
+func (in *instance) removeContainers(ctx context.Context, labels ...string) error {
+	args := filters.NewArgs()
+	for _, label := range labels {
+		args.Add("label", label)
+	}
+	res, err := in.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return err
+	}
+	for _, tainr := range res {
+		if err := in.removeContainer(ctx, tainr.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeContainer will forcefully remove the docker container with given
+// id, including its anonymous volumes.
+func (in *instance) removeContainer(ctx context.Context, id string) error {
+	err := in.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true, RemoveVolumes: true})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// removeNetworks will remove all docker networks that match given label.
+func (in *instance) removeNetworks(ctx context.Context, label string) error {
+	in.networkLock.Lock()
+	defer in.networkLock.Unlock()
+	nets, err := in.cli.NetworkList(ctx, network.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", label)),
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range nets {
+		if err := in.cli.NetworkRemove(ctx, n.ID); err != nil && !errdefs.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
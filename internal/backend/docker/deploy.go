@@ -0,0 +1,327 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+// StartContainer will create a docker container for given container, copy
+// its pre-archives and start it. The container is connected to the network
+// of this kubedock instance, unless it's linked to another container, in
+// which case it joins the network of that container. The host ports of the
+// published ports are stored in the given container.
+func (in *instance) StartContainer(ctx context.Context, tainr *types.Container) (backend.DeployState, error) {
+	start := time.Now()
+	if !tainr.IsLinked() {
+		if err := in.ensureNetwork(ctx); err != nil {
+			return backend.DeployFailed, err
+		}
+	}
+
+	name := tainr.GetPodName()
+	if tainr.IsLinked() {
+		name += "-" + tainr.ShortID
+	} else {
+		tainr.PodName = name
+	}
+	if err := in.removeContainers(ctx, "kubedock.containerid="+tainr.ShortID); err != nil {
+		return backend.DeployFailed, err
+	}
+
+	img := image.Rewrite(tainr.Image, in.getImageRewrites())
+	if err := in.pullImage(ctx, tainr, img); err != nil {
+		return backend.DeployFailed, err
+	}
+	tainr.SetStartTiming(types.PhaseResolve, time.Since(start))
+
+	start = time.Now()
+	cfg, hcfg, ncfg := in.getContainerConfig(tainr, img)
+	res, err := in.cli.ContainerCreate(ctx, cfg, hcfg, ncfg, nil, name)
+	if err != nil {
+		return backend.DeployFailed, err
+	}
+	for _, pa := range tainr.PreArchives {
+		if err := in.cli.CopyToContainer(ctx, res.ID, pa.Path, bytes.NewReader(pa.Archive), container.CopyToContainerOptions{}); err != nil {
+			return backend.DeployFailed, fmt.Errorf("error copying archive to %s: %w", pa.Path, err)
+		}
+	}
+	tainr.SetStartTiming(types.PhaseCreate, time.Since(start))
+
+	start = time.Now()
+	if err := in.cli.ContainerStart(ctx, res.ID, container.StartOptions{}); err != nil {
+		return backend.DeployFailed, err
+	}
+	tainr.SetStartTiming(types.PhasePulled, time.Since(start))
+
+	info, err := in.cli.ContainerInspect(ctx, res.ID)
+	if err != nil {
+		return backend.DeployFailed, err
+	}
+	mapPublishedPorts(tainr, info)
+	return getDeployState(info.State), nil
+}
+
+// pullImage will pull given image, unless it's present already and the
+// pull policy of the container doesn't require it to be pulled.
+func (in *instance) pullImage(ctx context.Context, tainr *types.Container, img string) error {
+	policy, err := tainr.GetImagePullPolicyFor(img)
+	if err != nil {
+		return err
+	}
+	if policy != corev1.PullAlways {
+		if _, err := in.cli.ImageInspect(ctx, img); err == nil {
+			return nil
+		}
+		if policy == corev1.PullNever {
+			return fmt.Errorf("image %s is not present", img)
+		}
+	}
+	return in.pull(ctx, img)
+}
+
+// getContainerConfig will return the docker configuration of given
+// container, which runs given image.
+func (in *instance) getContainerConfig(tainr *types.Container, img string) (*container.Config, *container.HostConfig, *network.NetworkingConfig) {
+	cfg := &container.Config{
+		Image:        img,
+		Hostname:     tainr.Hostname,
+		Entrypoint:   tainr.Entrypoint,
+		Cmd:          tainr.Cmd,
+		Env:          append(append([]string{}, tainr.LinkEnv...), tainr.Env...),
+		Labels:       in.getLabels(tainr),
+		Tty:          tainr.Tty,
+		OpenStdin:    tainr.OpenStdin,
+		AttachStdin:  tainr.OpenStdin,
+		AttachStdout: true,
+		AttachStderr: true,
+		ExposedPorts: nat.PortSet{},
+	}
+	if len(tainr.SecretEnv) > 0 {
+		klog.Warningf("secret environment variables of container %s are not supported by the docker backend", tainr.ShortID)
+	}
+
+	hcfg := &container.HostConfig{
+		Binds:        tainr.Binds,
+		Sysctls:      tainr.Sysctls,
+		PortBindings: nat.PortMap{},
+	}
+	for _, m := range tainr.Mounts {
+		mnt := mount.Mount{
+			Type:     mount.Type(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		}
+		if m.Propagation != "" && mnt.Type == mount.TypeBind {
+			mnt.BindOptions = &mount.BindOptions{Propagation: mount.Propagation(m.Propagation)}
+		}
+		hcfg.Mounts = append(hcfg.Mounts, mnt)
+	}
+	for _, dev := range tainr.Devices {
+		if dev.HostPath == "" {
+			klog.Warningf("device resource %s of container %s is not supported by the docker backend", dev.Resource, tainr.ShortID)
+			continue
+		}
+		hcfg.Devices = append(hcfg.Devices, container.DeviceMapping{
+			PathOnHost:        dev.HostPath,
+			PathInContainer:   dev.ContainerPath,
+			CgroupPermissions: "rwm",
+		})
+	}
+	for _, ul := range tainr.Ulimits {
+		hcfg.Ulimits = append(hcfg.Ulimits, &container.Ulimit{Name: ul.Name, Soft: ul.Soft, Hard: ul.Hard})
+	}
+	for ip, hosts := range tainr.LinkHosts {
+		for _, host := range hosts {
+			hcfg.ExtraHosts = append(hcfg.ExtraHosts, host+":"+ip)
+		}
+	}
+
+	if tainr.IsLinked() {
+		hcfg.NetworkMode = container.NetworkMode("container:" + tainr.GetPodName())
+		return cfg, hcfg, nil
+	}
+
+	for _, pp := range tainr.GetContainerTCPPorts() {
+		cfg.ExposedPorts[nat.Port(strconv.Itoa(pp)+"/tcp")] = struct{}{}
+	}
+	for src, dst := range tainr.HostPorts {
+		port := nat.Port(strconv.Itoa(dst) + "/tcp")
+		cfg.ExposedPorts[port] = struct{}{}
+		binding := nat.PortBinding{}
+		if src > 0 {
+			binding.HostPort = strconv.Itoa(src)
+		}
+		hcfg.PortBindings[port] = append(hcfg.PortBindings[port], binding)
+	}
+	for port := range cfg.ExposedPorts {
+		if _, ok := hcfg.PortBindings[port]; !ok {
+			hcfg.PortBindings[port] = []nat.PortBinding{{}}
+		}
+	}
+
+	if tainr.HostNetwork {
+		hcfg.NetworkMode = network.NetworkHost
+		hcfg.PortBindings = nil
+		return cfg, hcfg, nil
+	}
+	hcfg.NetworkMode = container.NetworkMode(in.network)
+	ncfg := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			in.network: {Aliases: tainr.GetDNSNames()},
+		},
+	}
+	return cfg, hcfg, ncfg
+}
+
+// mapPublishedPorts will store the host ports that docker assigned to the
+// published ports of given container, which are not bound to a fixed host
+// port.
+func mapPublishedPorts(tainr *types.Container, info container.InspectResponse) {
+	if info.NetworkSettings == nil {
+		return
+	}
+	fixed := map[int]bool{}
+	for src, dst := range tainr.HostPorts {
+		if src > 0 {
+			fixed[dst] = true
+		}
+	}
+	for port, bindings := range info.NetworkSettings.Ports {
+		if port.Proto() != "tcp" || fixed[port.Int()] || len(bindings) == 0 {
+			continue
+		}
+		if hp, err := strconv.Atoi(bindings[0].HostPort); err == nil {
+			tainr.MapPort(hp, port.Int())
+		}
+	}
+}
+
+// getDeployState will return the deploy state for given docker container
+// state.
+func getDeployState(state *container.State) backend.DeployState {
+	switch {
+	case state == nil:
+		return backend.DeployPending
+	case state.Running:
+		return backend.DeployRunning
+	case state.Status == container.StateExited || state.Status == container.StateDead:
+		if state.ExitCode == 0 {
+			return backend.DeployCompleted
+		}
+		return backend.DeployFailed
+	}
+	return backend.DeployPending
+}
+
+// GetContainerStatus will return the state of the docker container of
+// given container.
+func (in *instance) GetContainerStatus(tainr *types.Container) (backend.DeployState, error) {
+	ctx := context.Background()
+	id, err := in.getContainerID(ctx, tainr)
+	if err != nil {
+		return backend.DeployFailed, err
+	}
+	info, err := in.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return backend.DeployFailed, err
+	}
+	return getDeployState(info.State), nil
+}
+
+// GetPodIP will return the ip of the docker container of given container on
+// the network of this kubedock instance. Linked containers share the ip of
+// the container they are linked to.
+func (in *instance) GetPodIP(tainr *types.Container) (string, error) {
+	ctx := context.Background()
+	id := tainr.GetPodName()
+	if !tainr.IsLinked() {
+		var err error
+		if id, err = in.getContainerID(ctx, tainr); err != nil {
+			return "", err
+		}
+	}
+	info, err := in.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if info.NetworkSettings == nil {
+		return "", fmt.Errorf("container %s has no network", tainr.ShortID)
+	}
+	if ep, ok := info.NetworkSettings.Networks[in.network]; ok && ep.IPAddress != "" {
+		return ep.IPAddress, nil
+	}
+	for _, ep := range info.NetworkSettings.Networks {
+		if ep.IPAddress != "" {
+			return ep.IPAddress, nil
+		}
+	}
+	return "", fmt.Errorf("container %s has no ip address", tainr.ShortID)
+}
+
+// WatchContainerExit will return a channel that receives the exit code of
+// the given container when it has terminated. The channel is closed without
+// a value if the container is removed, or if the container can't be waited
+// for.
+func (in *instance) WatchContainerExit(tainr *types.Container) (chan backend.ContainerExit, error) {
+	id, err := in.getContainerID(context.Background(), tainr)
+	if err != nil {
+		return nil, err
+	}
+	exit := make(chan backend.ContainerExit, 1)
+	go func() {
+		defer close(exit)
+		resc, errc := in.cli.ContainerWait(context.Background(), id, container.WaitConditionNotRunning)
+		select {
+		case res := <-resc:
+			if _, err := in.cli.ContainerInspect(context.Background(), id); errdefs.IsNotFound(err) {
+				return
+			}
+			exit <- backend.ContainerExit{Code: int(res.StatusCode)}
+		case err := <-errc:
+			if !errdefs.IsNotFound(err) {
+				klog.V(3).Infof("error waiting for container %s: %s", tainr.ShortID, err)
+			}
+		}
+	}()
+	return exit, nil
+}
+
+// WatchDeleteContainer will return a channel that is closed when the docker
+// container of given container is removed.
+func (in *instance) WatchDeleteContainer(tainr *types.Container) (chan struct{}, error) {
+	deleted := make(chan struct{}, 1)
+	id, err := in.getContainerID(context.Background(), tainr)
+	if err != nil {
+		if errors.Is(err, errNotDeployed) {
+			close(deleted)
+			return deleted, nil
+		}
+		return nil, err
+	}
+	go func() {
+		defer close(deleted)
+		resc, errc := in.cli.ContainerWait(context.Background(), id, container.WaitConditionRemoved)
+		select {
+		case <-resc:
+		case <-errc:
+		}
+	}()
+	return deleted, nil
+}
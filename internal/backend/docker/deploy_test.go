@@ -0,0 +1,158 @@
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestGetContainerConfig(t *testing.T) {
+	tests := []struct {
+		in       *types.Container
+		bindings nat.PortMap
+		network  string
+		aliases  []string
+	}{
+		{
+			in: &types.Container{ShortID: "tb303", Name: "Bassline", ExposedPorts: map[string]interface{}{"80/tcp": nil}},
+			bindings: nat.PortMap{
+				"80/tcp": {{}},
+			},
+			network: "kubedock-test",
+			aliases: []string{"bassline"},
+		},
+		{
+			in: &types.Container{ShortID: "tb303", Name: "bassline", NetworkAliases: []string{"acid"}, HostPorts: map[int]int{8080: 80, -90: 90}},
+			bindings: nat.PortMap{
+				"80/tcp": {{HostPort: "8080"}},
+				"90/tcp": {{}},
+			},
+			network: "kubedock-test",
+			aliases: []string{"bassline", "acid"},
+		},
+		{
+			in:       &types.Container{ShortID: "sh101", NetworkOwner: "tb303", NetworkPod: "kubedock-bassline-tb303", HostPorts: map[int]int{8080: 80}},
+			bindings: nat.PortMap{},
+			network:  "container:kubedock-bassline-tb303",
+		},
+	}
+
+	kub := &instance{network: "kubedock-test"}
+	for i, tst := range tests {
+		cfg, hcfg, ncfg := kub.getContainerConfig(tst.in, "alpine:latest")
+		if cfg.Image != "alpine:latest" || cfg.Labels["kubedock.containerid"] != tst.in.ShortID {
+			t.Errorf("failed test %d - unexpected config %v", i, cfg)
+		}
+		if !reflect.DeepEqual(hcfg.PortBindings, tst.bindings) {
+			t.Errorf("failed test %d - expected bindings %v, but got %v", i, tst.bindings, hcfg.PortBindings)
+		}
+		if string(hcfg.NetworkMode) != tst.network {
+			t.Errorf("failed test %d - expected network %s, but got %s", i, tst.network, hcfg.NetworkMode)
+		}
+		if tst.aliases == nil {
+			if ncfg != nil {
+				t.Errorf("failed test %d - expected no endpoints, but got %v", i, ncfg)
+			}
+			continue
+		}
+		if ep := ncfg.EndpointsConfig[kub.network]; ep == nil || !reflect.DeepEqual(ep.Aliases, tst.aliases) {
+			t.Errorf("failed test %d - expected aliases %v, but got %v", i, tst.aliases, ep)
+		}
+	}
+}
+
+func TestMapPublishedPorts(t *testing.T) {
+	tainr := &types.Container{HostPorts: map[int]int{8080: 80}}
+	info := container.InspectResponse{NetworkSettings: &container.NetworkSettings{}}
+	info.NetworkSettings.Ports = nat.PortMap{
+		"80/tcp":   {{HostIP: "0.0.0.0", HostPort: "8080"}},
+		"90/tcp":   {{HostIP: "0.0.0.0", HostPort: "32768"}},
+		"53/udp":   {{HostIP: "0.0.0.0", HostPort: "32769"}},
+		"5432/tcp": {},
+	}
+	mapPublishedPorts(tainr, info)
+	exp := map[int]int{32768: 90}
+	if !reflect.DeepEqual(tainr.MappedPorts, exp) {
+		t.Errorf("expected %v, but got %v", exp, tainr.MappedPorts)
+	}
+}
+
+func TestGetDeployState(t *testing.T) {
+	tests := []struct {
+		in  *container.State
+		out backend.DeployState
+	}{
+		{in: nil, out: backend.DeployPending},
+		{in: &container.State{Status: container.StateCreated}, out: backend.DeployPending},
+		{in: &container.State{Status: container.StateRunning, Running: true}, out: backend.DeployRunning},
+		{in: &container.State{Status: container.StateExited}, out: backend.DeployCompleted},
+		{in: &container.State{Status: container.StateExited, ExitCode: 1}, out: backend.DeployFailed},
+		{in: &container.State{Status: container.StateDead, ExitCode: 137}, out: backend.DeployFailed},
+	}
+	for i, tst := range tests {
+		if res := getDeployState(tst.in); res != tst.out {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.out, res)
+		}
+	}
+}
+
+func TestGetContainerStatus(t *testing.T) {
+	removed := []string{}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/json"):
+			if strings.Contains(r.URL.Query().Get("filters"), "tb303") {
+				json.NewEncoder(w).Encode([]container.Summary{{ID: "d0cker"}})
+				return
+			}
+			json.NewEncoder(w).Encode([]container.Summary{})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/containers/d0cker/json"):
+			json.NewEncoder(w).Encode(container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{
+				ID:    "d0cker",
+				State: &container.State{Status: container.StateExited, ExitCode: 2},
+			}})
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/containers/d0cker"):
+			removed = append(removed, "d0cker")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer svr.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(svr.URL, "http://")), client.WithVersion("1.45"))
+	if err != nil {
+		t.Fatalf("unexpected error creating client: %s", err)
+	}
+	kub, err := New(Config{Client: cli})
+	if err != nil {
+		t.Fatalf("unexpected error creating backend: %s", err)
+	}
+
+	state, err := kub.GetContainerStatus(&types.Container{ShortID: "tb303"})
+	if err != nil || state != backend.DeployFailed {
+		t.Errorf("expected failed state, but got %d: %v", state, err)
+	}
+	if _, err := kub.GetContainerStatus(&types.Container{ShortID: "mc909"}); err == nil {
+		t.Errorf("expected an error for a container that is not deployed")
+	}
+	if err := kub.DeleteContainer(&types.Container{ShortID: "tb303"}); err != nil {
+		t.Errorf("unexpected error deleting container: %s", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"d0cker"}) {
+		t.Errorf("expected docker container to be removed, but got %v", removed)
+	}
+	if err := kub.CheckFeature(backend.FeatureServices); err == nil {
+		t.Errorf("expected services to be unsupported")
+	}
+}
@@ -0,0 +1,201 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/ioproxy"
+	"github.com/joyrex2001/kubedock/internal/util/termsize"
+)
+
+// ExecContainer will execute given exec object in the docker container of
+// given container. The output is written to given writer, multiplexed if
+// the exec doesn't have a tty, as it's returned by docker. The exec is
+// terminated when the given context is done.
+func (in *instance) ExecContainer(ctx context.Context, tainr *types.Container, ex *types.Exec, stdin io.Reader, stdout io.Writer) (int, error) {
+	id, err := in.getContainerID(ctx, tainr)
+	if err != nil {
+		return 0, err
+	}
+	res, err := in.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		Cmd:          ex.Cmd,
+		Tty:          ex.TTY,
+		AttachStdin:  ex.Stdin,
+		AttachStdout: ex.Stdout,
+		AttachStderr: ex.Stderr,
+	})
+	if err != nil {
+		return 0, err
+	}
+	conn, err := in.cli.ContainerExecAttach(ctx, res.ID, container.ExecAttachOptions{Tty: ex.TTY})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if ex.TTY {
+		sizes := ex.TerminalSizes.Add()
+		defer ex.TerminalSizes.Remove(sizes)
+		go resize(sizes, func(size container.ResizeOptions) error {
+			return in.cli.ContainerExecResize(ctx, res.ID, size)
+		})
+	}
+	if ex.Stdin && stdin != nil {
+		go func() {
+			if _, err := io.Copy(conn.Conn, stdin); err != nil {
+				klog.V(3).Infof("error writing stdin of exec %s: %s", ex.ID, err)
+			}
+			conn.CloseWrite()
+		}()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(stdout, conn.Reader)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+	info, err := in.cli.ContainerExecInspect(context.Background(), res.ID)
+	if err != nil {
+		return 0, err
+	}
+	return info.ExitCode, nil
+}
+
+// AttachContainer will attach to the docker container of given container
+// and stream stdin/stdout/stderr. Without a tty, stdout and stderr are
+// written multiplexed to stdout, as it's returned by docker.
+func (in *instance) AttachContainer(tainr *types.Container, stdin io.Reader, stdout io.Writer, stderr io.Writer, tty bool) error {
+	ctx := context.Background()
+	id, err := in.getContainerID(ctx, tainr)
+	if err != nil {
+		return err
+	}
+	conn, err := in.cli.ContainerAttach(ctx, id, container.AttachOptions{
+		Stream: true,
+		Stdin:  stdin != nil,
+		Stdout: stdout != nil,
+		Stderr: stderr != nil,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if tty {
+		sizes := tainr.TerminalSizes().Add()
+		defer tainr.TerminalSizes().Remove(sizes)
+		go resize(sizes, func(size container.ResizeOptions) error {
+			return in.cli.ContainerResize(ctx, id, size)
+		})
+	}
+	if stdin != nil {
+		go func() {
+			if _, err := io.Copy(conn.Conn, stdin); err != nil {
+				klog.V(3).Infof("error writing stdin of container %s: %s", tainr.ShortID, err)
+			}
+			conn.CloseWrite()
+		}()
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	_, err = io.Copy(stdout, conn.Reader)
+	return err
+}
+
+// resize will apply the terminal sizes of given queue with given function,
+// until the queue is closed.
+func resize(sizes *termsize.Queue, apply func(container.ResizeOptions) error) {
+	for {
+		size := sizes.Next()
+		if size == nil {
+			return
+		}
+		if err := apply(container.ResizeOptions{Height: uint(size.Height), Width: uint(size.Width)}); err != nil {
+			klog.V(3).Infof("error resizing terminal: %s", err)
+		}
+	}
+}
+
+// GetLogs will write the logs for given container to given writer using
+// stdout/stderr multiplexing.
+func (in *instance) GetLogs(tainr *types.Container, opts *backend.LogOptions, stop chan struct{}, w io.Writer) error {
+	if !tainr.Tty {
+		return in.getLogs(tainr, opts, stop, w)
+	}
+	out := ioproxy.New(w, ioproxy.Stdout, &sync.Mutex{})
+	defer out.Flush()
+	return in.getLogs(tainr, opts, stop, out)
+}
+
+// GetLogsRaw will write the unprocessed logs for given container to given
+// writer.
+func (in *instance) GetLogsRaw(tainr *types.Container, opts *backend.LogOptions, stop chan struct{}, w io.Writer) error {
+	if tainr.Tty {
+		return in.getLogs(tainr, opts, stop, w)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(in.getLogs(tainr, opts, stop, pw))
+	}()
+	_, err := stdcopy.StdCopy(w, w, pr)
+	return err
+}
+
+// getLogs will write the logs of the docker container of given container
+// to given writer, as they are returned by docker. Following the logs stops
+// when the container exits, or the given stop channel is signalled.
+func (in *instance) getLogs(tainr *types.Container, opts *backend.LogOptions, stop chan struct{}, w io.Writer) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	id, err := in.getContainerID(ctx, tainr)
+	if err != nil {
+		return err
+	}
+	options := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+	}
+	if opts.SinceTime != nil {
+		options.Since = opts.SinceTime.Format(time.RFC3339Nano)
+	}
+	if opts.TailLines != nil {
+		options.Tail = strconv.FormatUint(*opts.TailLines, 10)
+	}
+	reader, err := in.cli.ContainerLogs(ctx, id, options)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	_, err = io.Copy(w, reader)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
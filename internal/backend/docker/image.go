@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	dockerimage "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+// InspectImage will return the details of given image as it's known by
+// docker; the image is pulled if it's not present.
+func (in *instance) InspectImage(img string) (*image.Details, error) {
+	ctx := context.Background()
+	ref := image.Rewrite(img, in.getImageRewrites())
+	res, err := in.cli.ImageInspect(ctx, ref)
+	if errdefs.IsNotFound(err) {
+		if err := in.pull(ctx, ref); err != nil {
+			return nil, image.ClassifyPullError(img, err)
+		}
+		res, err = in.cli.ImageInspect(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return getImageDetails(res), nil
+}
+
+// getImageDetails will convert given docker image to image details.
+func getImageDetails(res dockerimage.InspectResponse) *image.Details {
+	cfg := &v1.Image{
+		Platform: v1.Platform{
+			Architecture: res.Architecture,
+			OS:           res.Os,
+			OSVersion:    res.OsVersion,
+			Variant:      res.Variant,
+		},
+		Author: res.Author,
+	}
+	if created, err := time.Parse(time.RFC3339Nano, res.Created); err == nil {
+		cfg.Created = &created
+	}
+	if res.Config != nil {
+		cfg.Config = res.Config.ImageConfig
+	}
+	dgst := res.ID
+	for _, rd := range res.RepoDigests {
+		if _, d, ok := strings.Cut(rd, "@"); ok {
+			dgst = d
+			break
+		}
+	}
+	return &image.Details{Config: cfg, Digest: dgst, Size: res.Size}
+}
+
+// GetImageDistribution will return the manifest descriptor and supported
+// platforms of given image, as reported by the registry via docker.
+func (in *instance) GetImageDistribution(img string) (*image.Distribution, error) {
+	res, err := in.cli.DistributionInspect(context.Background(), image.Rewrite(img, in.getImageRewrites()), "")
+	if err != nil {
+		return nil, err
+	}
+	return &image.Distribution{Descriptor: res.Descriptor, Platforms: res.Platforms}, nil
+}
+
+// GetImageManifest will fetch the manifest of given reference (a tag or a
+// digest) of given repository from the registry. The image is rewritten with
+// the image rewrite rules, and its blobs can be fetched via GetImageBlob.
+func (in *instance) GetImageManifest(repo, ref string) (*image.Manifest, error) {
+	sep := ":"
+	if strings.Contains(ref, ":") {
+		sep = "@"
+	}
+	return in.registry.GetManifest(repo, "docker://"+image.Rewrite(repo+sep+ref, in.getImageRewrites()))
+}
+
+// GetImageBlob will fetch the blob with given digest of given repository
+// from the registry, and return it together with its size (-1 if unknown).
+// The repository is rewritten with the image rewrite rules.
+func (in *instance) GetImageBlob(repo, dgst string) (io.ReadCloser, int64, error) {
+	return in.registry.GetBlob(repo, "docker://"+image.Rewrite(repo, in.getImageRewrites()), dgst)
+}
+
+// PrewarmImages will pull given images, and returns the images that were
+// pulled.
+func (in *instance) PrewarmImages(images []string) ([]string, error) {
+	res := []string{}
+	for _, img := range images {
+		if img = strings.TrimSpace(img); img == "" {
+			continue
+		}
+		if err := in.pull(context.Background(), image.Rewrite(img, in.getImageRewrites())); err != nil {
+			return res, fmt.Errorf("error pulling image %s: %w", img, err)
+		}
+		res = append(res, img)
+	}
+	return res, nil
+}
+
+// SetImageRewrites will replace the rules that are used to rewrite image
+// references before they are pulled.
+func (in *instance) SetImageRewrites(rules []image.RewriteRule) {
+	in.rewritesLock.Lock()
+	defer in.rewritesLock.Unlock()
+	in.imageRewrites = rules
+}
+
+// getImageRewrites will return the rules that are used to rewrite image
+// references before they are pulled.
+func (in *instance) getImageRewrites() []image.RewriteRule {
+	in.rewritesLock.RLock()
+	defer in.rewritesLock.RUnlock()
+	return in.imageRewrites
+}
+
+// pull will pull given image, and wait until it has been pulled. An error
+// that is reported in the progress of the pull is returned as well.
+func (in *instance) pull(ctx context.Context, img string) error {
+	klog.V(2).Infof("pulling image %s", img)
+	reader, err := in.cli.ImagePull(ctx, img, dockerimage.PullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	dec := json.NewDecoder(reader)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("%s", msg.Error)
+		}
+	}
+}
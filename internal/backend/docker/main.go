@@ -0,0 +1,212 @@
+// Package docker provides a backend that runs the containers on a docker (or
+// podman) api, instead of orchestrating them on kubernetes. This allows the
+// same kubedock endpoint to be used for local development and in a cluster.
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+// DefaultHost is the docker api that is used if no host is configured.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// errNotDeployed is returned if there is no docker container for a
+// container.
+var errNotDeployed = errors.New("no docker container")
+
+// instance is the internal representation of the docker Backend object.
+type instance struct {
+	cli           client.APIClient
+	network       string
+	networkLock   sync.Mutex
+	imageRewrites []image.RewriteRule
+	rewritesLock  sync.RWMutex
+	registry      *image.Proxy
+}
+
+// Config is the structure to instantiate a docker Backend object
+type Config struct {
+	// Host is the docker (or podman) api on which the containers are run,
+	// e.g. unix:///var/run/docker.sock or tcp://localhost:2375
+	Host string
+	// Client is an optional docker api client, which is used instead of
+	// connecting to Host
+	Client client.APIClient
+	// ImageRewrites is an optional list of rules to rewrite image references
+	// before they are pulled (e.g. to use a mirror registry)
+	ImageRewrites []image.RewriteRule
+}
+
+// instance should implement the complete Backend interface.
+var _ backend.Backend = &instance{}
+
+// New will return a Backend instance that runs the containers on the
+// configured docker api.
+func New(cfg Config) (backend.Backend, error) {
+	cli := cfg.Client
+	if cli == nil {
+		host := cfg.Host
+		if host == "" {
+			host = DefaultHost
+		}
+		var err error
+		cli, err = client.NewClientWithOpts(client.WithHost(host), client.WithAPIVersionNegotiation())
+		if err != nil {
+			return nil, fmt.Errorf("error instantiating docker client for %s: %w", host, err)
+		}
+	}
+	return &instance{
+		cli:           cli,
+		network:       "kubedock-" + config.InstanceID,
+		imageRewrites: cfg.ImageRewrites,
+		registry:      image.NewProxy(),
+	}, nil
+}
+
+// UnsupportedError is returned when a feature is used that is specific to
+// kubernetes, and not available with the docker backend.
+type UnsupportedError struct {
+	Feature string
+}
+
+// Error will return a description of the unsupported feature.
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported by the docker backend", e.Feature)
+}
+
+// HTTPStatus will return the http status that should be used to report
+// the unsupported feature to api clients.
+func (e *UnsupportedError) HTTPStatus() int {
+	return http.StatusNotImplemented
+}
+
+// CheckFeature will return an UnsupportedError for the features that are
+// specific to kubernetes, or nil if the feature is available.
+func (in *instance) CheckFeature(feature string) error {
+	switch feature {
+	case backend.FeatureServices, backend.FeatureDeployments, backend.FeatureArchiveHelper, backend.FeatureEvents:
+		return &UnsupportedError{Feature: feature}
+	}
+	return nil
+}
+
+// CreatePortForwards is a no-op, as the ports are published by docker.
+func (in *instance) CreatePortForwards(tainr *types.Container) {}
+
+// CreateReverseProxies is a no-op, as the ports are published by docker.
+func (in *instance) CreateReverseProxies(tainr *types.Container) {}
+
+// UpdateServices is a no-op, as the containers resolve each other by name
+// on the network of this kubedock instance.
+func (in *instance) UpdateServices(tainr *types.Container) error {
+	return nil
+}
+
+// CleanForwards is a no-op, as there are no port-forwards.
+func (in *instance) CleanForwards(tainrs []*types.Container, idle time.Duration) int {
+	return 0
+}
+
+// GetPodEvents will return no events, as there are no pods.
+func (in *instance) GetPodEvents(tainr *types.Container) ([]corev1.Event, error) {
+	return []corev1.Event{}, nil
+}
+
+// DeployService will return an UnsupportedError.
+func (in *instance) DeployService(svc *types.Service) error {
+	return &UnsupportedError{Feature: backend.FeatureServices}
+}
+
+// GetServiceReplicas will return an UnsupportedError.
+func (in *instance) GetServiceReplicas(svc *types.Service) (int, error) {
+	return 0, &UnsupportedError{Feature: backend.FeatureServices}
+}
+
+// DeleteService will return an UnsupportedError.
+func (in *instance) DeleteService(svc *types.Service) error {
+	return &UnsupportedError{Feature: backend.FeatureServices}
+}
+
+// ensureNetwork will create the network of this kubedock instance, if it
+// doesn't exist yet. All containers are connected to this network, so they
+// can resolve each other by name and network alias.
+func (in *instance) ensureNetwork(ctx context.Context) error {
+	in.networkLock.Lock()
+	defer in.networkLock.Unlock()
+	nets, err := in.cli.NetworkList(ctx, network.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("name", in.network)),
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range nets {
+		if n.Name == in.network {
+			return nil
+		}
+	}
+	klog.V(3).Infof("creating network %s", in.network)
+	_, err = in.cli.NetworkCreate(ctx, in.network, network.CreateOptions{
+		Labels: in.getLabels(nil),
+	})
+	return err
+}
+
+// getContainerID will return the id of the docker container of given
+// container.
+func (in *instance) getContainerID(ctx context.Context, tainr *types.Container) (string, error) {
+	res, err := in.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "kubedock.containerid="+tainr.ShortID)),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(res) == 0 {
+		return "", fmt.Errorf("%w for container %s", errNotDeployed, tainr.ShortID)
+	}
+	return res[0].ID, nil
+}
+
+// getLabels will return the labels that are added to the docker resources
+// of given container, which is nil for resources that are shared by all
+// containers.
+func (in *instance) getLabels(tainr *types.Container) map[string]string {
+	labels := map[string]string{}
+	for k, v := range config.DefaultLabels {
+		labels[k] = v
+	}
+	if tainr != nil {
+		for k, v := range tainr.Labels {
+			labels[k] = v
+		}
+	}
+	for k, v := range config.SystemLabels {
+		labels[k] = v
+	}
+	if tainr == nil {
+		return labels
+	}
+	if project := tainr.GetComposeProject(); project != "" {
+		labels["kubedock.project"] = project
+		labels["kubedock.service"] = tainr.GetComposeService()
+	}
+	labels["kubedock.containerid"] = tainr.ShortID
+	return labels
+}
@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/docker/docker/api/types/container"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// GetContainerStats will return the resource usage of the given containers,
+// keyed by container id. Containers of which the usage can't be determined
+// (e.g. because they are not running) are not included.
+func (in *instance) GetContainerStats(tainrs []*types.Container) (map[string]*backend.ContainerStats, error) {
+	ctx := context.Background()
+	res := map[string]*backend.ContainerStats{}
+	for _, tainr := range tainrs {
+		id, err := in.getContainerID(ctx, tainr)
+		if err != nil {
+			continue
+		}
+		reader, err := in.cli.ContainerStatsOneShot(ctx, id)
+		if err != nil {
+			klog.V(3).Infof("error getting stats of container %s: %s", tainr.ShortID, err)
+			continue
+		}
+		stats := container.StatsResponse{}
+		err = json.NewDecoder(reader.Body).Decode(&stats)
+		reader.Body.Close()
+		if err != nil {
+			klog.V(3).Infof("error decoding stats of container %s: %s", tainr.ShortID, err)
+			continue
+		}
+		res[tainr.ID] = getContainerStats(stats)
+	}
+	return res, nil
+}
+
+// getContainerStats will convert given docker stats to container stats.
+// The cpu usage is the average over the interval of the previous and the
+// current sample, and the memory usage excludes the inactive file cache,
+// similar to the working set that is reported by the metrics api.
+func getContainerStats(stats container.StatsResponse) *backend.ContainerStats {
+	res := &backend.ContainerStats{
+		MemoryUsage: int64(stats.MemoryStats.Usage),
+		MemoryLimit: int64(stats.MemoryStats.Limit),
+		Timestamp:   stats.Read,
+	}
+	if inactive, ok := stats.MemoryStats.Stats["inactive_file"]; ok && inactive < stats.MemoryStats.Usage {
+		res.MemoryUsage -= int64(inactive)
+	}
+	interval := stats.Read.Sub(stats.PreRead)
+	used := stats.CPUStats.CPUUsage.TotalUsage
+	prev := stats.PreCPUStats.CPUUsage.TotalUsage
+	if !stats.PreRead.IsZero() && interval > 0 && used > prev {
+		res.CPUNanoCores = int64(float64(used-prev) / interval.Seconds())
+	}
+	return res
+}
+
+// GetCapacity will return the number of cpus and amount of memory of the
+// docker host.
+func (in *instance) GetCapacity() (*backend.Capacity, error) {
+	info, err := in.cli.Info(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &backend.Capacity{NCPU: info.NCPU, MemTotal: info.MemTotal}, nil
+}
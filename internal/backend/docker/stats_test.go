@@ -0,0 +1,44 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestGetContainerStats(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		in  container.StatsResponse
+		cpu int64
+		mem int64
+	}{
+		{
+			in: container.StatsResponse{
+				Read:        now,
+				PreRead:     now.Add(-time.Second),
+				CPUStats:    container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 1250000000}},
+				PreCPUStats: container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 1000000000}},
+				MemoryStats: container.MemoryStats{Usage: 2048, Limit: 4096, Stats: map[string]uint64{"inactive_file": 1024}},
+			},
+			cpu: 250000000,
+			mem: 1024,
+		},
+		{
+			in: container.StatsResponse{
+				Read:        now,
+				CPUStats:    container.CPUStats{CPUUsage: container.CPUUsage{TotalUsage: 1250000000}},
+				MemoryStats: container.MemoryStats{Usage: 2048, Limit: 4096},
+			},
+			cpu: 0,
+			mem: 2048,
+		},
+	}
+	for i, tst := range tests {
+		res := getContainerStats(tst.in)
+		if res.CPUNanoCores != tst.cpu || res.MemoryUsage != tst.mem || res.MemoryLimit != 4096 {
+			t.Errorf("failed test %d - expected %d/%d, but got %d/%d/%d", i, tst.cpu, tst.mem, res.CPUNanoCores, res.MemoryUsage, res.MemoryLimit)
+		}
+	}
+}
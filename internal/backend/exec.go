@@ -14,20 +14,27 @@ import (
 	"github.com/joyrex2001/kubedock/internal/util/ioproxy"
 )
 
-// ExecContainer will execute given exec object in kubernetes.
-func (in *instance) ExecContainer(tainr *types.Container, ex *types.Exec, stdin io.Reader, stdout io.Writer) (int, error) {
-	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+// ExecContainer will execute given exec object in kubernetes. The exec is
+// terminated when the given context is done.
+func (in *instance) ExecContainer(ctx context.Context, tainr *types.Container, ex *types.Exec, stdin io.Reader, stdout io.Writer) (int, error) {
+	if err := in.CheckFeature(FeatureExec); err != nil {
+		return 0, err
+	}
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(ctx, tainr.GetPodName(), metav1.GetOptions{})
 	if err != nil {
 		return 0, err
 	}
 
 	req := exec.Request{
-		Client:     in.cli,
-		RestConfig: in.cfg,
-		Pod:        *pod,
-		Container:  "main",
-		Cmd:        ex.Cmd,
-		TTY:        ex.TTY,
+		Context:     ctx,
+		Client:      in.cli,
+		RestConfig:  in.cfg,
+		Pod:         *pod,
+		Container:   tainr.GetContainerName(),
+		Cmd:         ex.Cmd,
+		TTY:         ex.TTY,
+		IdleTimeout: in.execIdleTimeout,
+		MaxDuration: in.execMaxDuration,
 	}
 
 	if ex.Stdin {
@@ -36,15 +43,18 @@ func (in *instance) ExecContainer(tainr *types.Container, ex *types.Exec, stdin
 	if ex.TTY {
 		req.Stdout = stdout
 		req.Stderr = io.Discard
+		sizes := ex.TerminalSizes.Add()
+		defer ex.TerminalSizes.Remove(sizes)
+		req.TerminalSizeQueue = sizes
 	} else {
 		lock := sync.Mutex{}
 		if ex.Stdout {
-			iop := ioproxy.New(stdout, ioproxy.Stdout, &lock)
+			iop := ioproxy.NewUnbuffered(stdout, ioproxy.Stdout, &lock)
 			req.Stdout = iop
 			defer iop.Flush()
 		}
 		if ex.Stderr {
-			iop := ioproxy.New(stdout, ioproxy.Stderr, &lock)
+			iop := ioproxy.NewUnbuffered(stdout, ioproxy.Stderr, &lock)
 			req.Stderr = iop
 			defer iop.Flush()
 		}
@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/metrics"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+const (
+	// forwardPortForward is the kind of forwards that are port-forwards.
+	forwardPortForward = "port-forward"
+	// forwardReverseProxy is the kind of forwards that are reverse proxies.
+	forwardReverseProxy = "reverse-proxy"
+)
+
+// forward is an active port-forward or reverse proxy of a container.
+type forward struct {
+	kind   string
+	owner  string
+	local  int
+	remote int
+	stop   chan struct{}
+	once   sync.Once
+	active atomic.Int64
+	conns  atomic.Int64
+}
+
+// touch will register activity on the forward.
+func (f *forward) touch() {
+	f.active.Store(time.Now().UnixNano())
+}
+
+// opened will register a new connection on the forward.
+func (f *forward) opened() {
+	f.conns.Add(1)
+	f.touch()
+}
+
+// closed will register a closed connection on the forward.
+func (f *forward) closed() {
+	f.conns.Add(-1)
+	f.touch()
+}
+
+// idle will return the duration since the last activity on the forward; a
+// forward with open connections is never idle.
+func (f *forward) idle() time.Duration {
+	if f.conns.Load() > 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, f.active.Load()))
+}
+
+// close will stop the forward; it is safe to call this multiple times.
+func (f *forward) close() {
+	f.once.Do(func() { close(f.stop) })
+}
+
+// forwards keeps track of all active forwards.
+type forwards struct {
+	items map[*forward]struct{}
+	lock  sync.Mutex
+}
+
+// newForwards will return a new forwards tracker.
+func newForwards() *forwards {
+	return &forwards{items: map[*forward]struct{}{}}
+}
+
+// add will start tracking a new forward of given kind for given container.
+// The returned forward is stopped when the container is stopped, or when it
+// is closed by the garbage collector.
+func (in *forwards) add(kind string, tainr *types.Container, local, remote int) *forward {
+	f := &forward{
+		kind:   kind,
+		owner:  tainr.ID,
+		local:  local,
+		remote: remote,
+		stop:   make(chan struct{}),
+	}
+	f.touch()
+
+	stop := make(chan struct{}, 1)
+	tainr.AddStopChannel(stop)
+
+	in.lock.Lock()
+	in.items[f] = struct{}{}
+	in.lock.Unlock()
+	metrics.AddForward(kind, 1)
+
+	go func() {
+		select {
+		case <-stop:
+			f.close()
+		case <-f.stop:
+		}
+		in.lock.Lock()
+		delete(in.items, f)
+		in.lock.Unlock()
+		metrics.AddForward(kind, -1)
+	}()
+	return f
+}
+
+// CleanForwards will close all port-forwards and reverse proxies that are
+// owned by containers that are not in given list of containers, or that have
+// been idle for longer than given idle duration (if non-zero). It returns
+// the number of forwards that were closed.
+func (in *instance) CleanForwards(tainrs []*types.Container, idle time.Duration) int {
+	owners := map[string]bool{}
+	for _, tainr := range tainrs {
+		owners[tainr.ID] = true
+	}
+	in.forwards.lock.Lock()
+	defer in.forwards.lock.Unlock()
+	n := 0
+	for f := range in.forwards.items {
+		if !owners[f.owner] {
+			klog.Infof("closing %s %d->%d of removed container %s", f.kind, f.local, f.remote, f.owner)
+		} else if idle > 0 && f.idle() > idle {
+			klog.Infof("closing idle %s %d->%d of container %s", f.kind, f.local, f.remote, f.owner)
+		} else {
+			continue
+		}
+		f.close()
+		n++
+	}
+	return n
+}
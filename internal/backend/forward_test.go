@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestCleanForwards(t *testing.T) {
+	tests := []struct {
+		owner  bool
+		idle   time.Duration
+		active time.Duration
+		conns  bool
+		closed bool
+	}{
+		{owner: true, idle: 0, active: time.Hour, closed: false},
+		{owner: false, idle: 0, active: 0, closed: true},
+		{owner: true, idle: time.Minute, active: time.Hour, closed: true},
+		{owner: true, idle: time.Minute, active: time.Second, closed: false},
+		{owner: true, idle: time.Minute, active: time.Hour, conns: true, closed: false},
+		{owner: false, idle: 0, active: 0, conns: true, closed: true},
+	}
+	for i, tst := range tests {
+		kub := &instance{forwards: newForwards()}
+		tainr := &types.Container{ID: "rc752"}
+		fwd := kub.forwards.add(forwardPortForward, tainr, 8080, 80)
+		if tst.conns {
+			fwd.opened()
+		}
+		fwd.active.Store(time.Now().Add(-tst.active).UnixNano())
+		tainrs := []*types.Container{}
+		if tst.owner {
+			tainrs = append(tainrs, tainr)
+		}
+		n := kub.CleanForwards(tainrs, tst.idle)
+		if (n == 1) != tst.closed {
+			t.Errorf("failed test %d - expected closed %t, but got %d closed", i, tst.closed, n)
+		}
+		select {
+		case <-fwd.stop:
+			if !tst.closed {
+				t.Errorf("failed test %d - unexpected closed forward", i)
+			}
+		default:
+			if tst.closed {
+				t.Errorf("failed test %d - expected closed forward", i)
+			}
+		}
+		tainr.SignalStop()
+		<-fwd.stop
+	}
+}
+
+func TestForwardConnections(t *testing.T) {
+	fwd := &forward{}
+	fwd.opened()
+	fwd.active.Store(time.Now().Add(-time.Hour).UnixNano())
+	if fwd.idle() != 0 {
+		t.Errorf("expected forward with open connection to be active")
+	}
+	fwd.closed()
+	if fwd.idle() > time.Minute {
+		t.Errorf("expected forward to be active after closing connection")
+	}
+	fwd.active.Store(time.Now().Add(-time.Hour).UnixNano())
+	if fwd.idle() < time.Hour {
+		t.Errorf("expected forward to be idle")
+	}
+}
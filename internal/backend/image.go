@@ -1,15 +1,193 @@
 package backend
 
 import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/klog"
+
 	"github.com/joyrex2001/kubedock/internal/util/image"
 )
 
-// GetImageExposedPorts will inspect the image in the registry and return the
-// configured exposed ports from the image, or will return an error if failed.
-func (in *instance) GetImageExposedPorts(img string) (map[string]struct{}, error) {
-	cfg, err := image.InspectConfig("docker://" + img)
+// imageCache keeps track of the details of images that have been inspected
+// in the registry, so containers that use the same image don't all inspect
+// it again. The details are keyed by the digest of the image; image
+// references are resolved to a digest for the configured ttl, unless they
+// already contain a digest, or digests are pinned. Concurrent inspects of
+// the same image result in a single registry call.
+type imageCache struct {
+	ttl     time.Duration
+	pin     bool
+	group   singleflight.Group
+	lock    sync.Mutex
+	digests map[string]resolvedImage
+	details map[string]*image.Details
+}
+
+// resolvedImage is an image reference that has been resolved to a digest.
+type resolvedImage struct {
+	digest   string
+	resolved time.Time
+}
+
+// newImageCache will return a new image cache that resolves image
+// references to digests for the given duration, or for as long as kubedock
+// is running if pin is set.
+func newImageCache(ttl time.Duration, pin bool) *imageCache {
+	return &imageCache{
+		ttl:     ttl,
+		pin:     pin,
+		digests: map[string]resolvedImage{},
+		details: map[string]*image.Details{},
+	}
+}
+
+// inspect will return the details of the given image reference from the
+// cache, or inspect it with given function if it's not cached (yet).
+// Concurrent calls for the same reference wait for a single inspect.
+func (c *imageCache) inspect(ref string, fn func() (*image.Details, error)) (*image.Details, error) {
+	if c == nil {
+		return fn()
+	}
+	if dtl := c.get(ref); dtl != nil {
+		return dtl, nil
+	}
+	res, err, shared := c.group.Do(ref, func() (interface{}, error) {
+		dtl, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		c.put(ref, dtl)
+		return dtl, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return cfg.Config.ExposedPorts, nil
+	if shared {
+		klog.V(3).Infof("shared inspect of image %s", ref)
+	}
+	return res.(*image.Details), nil
+}
+
+// get will return the cached details of the given image reference, or nil
+// if not cached, or if its digest was resolved longer than ttl ago.
+func (c *imageCache) get(ref string) *image.Details {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	res, ok := c.digests[ref]
+	if !ok || c.isExpired(ref, res) {
+		return nil
+	}
+	return c.details[res.digest]
+}
+
+// put will add the given details of given image reference to the cache, and
+// removes the references that are expired, and the details that are no
+// longer referenced.
+func (c *imageCache) put(ref string, dtl *image.Details) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.ttl <= 0 && !c.pin && !strings.Contains(ref, "@") {
+		return
+	}
+	if c.pin && !strings.Contains(ref, "@") {
+		klog.Infof("pinned image %s to digest %s", strings.TrimPrefix(ref, "docker://"), dtl.Digest)
+	}
+	c.digests[ref] = resolvedImage{digest: dtl.Digest, resolved: time.Now()}
+	c.details[dtl.Digest] = dtl
+	used := map[string]bool{}
+	for r, res := range c.digests {
+		if c.isExpired(r, res) {
+			delete(c.digests, r)
+			continue
+		}
+		used[res.digest] = true
+	}
+	for dgst := range c.details {
+		if !used[dgst] {
+			delete(c.details, dgst)
+		}
+	}
+}
+
+// isExpired will return true if the given resolved image reference should be
+// resolved again. References that contain a digest, and pinned references,
+// never expire.
+func (c *imageCache) isExpired(ref string, res resolvedImage) bool {
+	if c.pin || strings.Contains(ref, "@") {
+		return false
+	}
+	return time.Since(res.resolved) > c.ttl
+}
+
+// InspectImage will inspect the image in the registry and return the
+// configuration, digest and size of the image, or will return an error if
+// failed (an image.PullError if the image doesn't exist or access is
+// denied). Inspects are cached and deduplicated.
+func (in *instance) InspectImage(img string) (*image.Details, error) {
+	ref := "docker://" + image.Rewrite(img, in.getImageRewrites())
+	dtl, err := in.images.inspect(ref, func() (*image.Details, error) {
+		return image.Inspect(ref)
+	})
+	return dtl, image.ClassifyPullError(img, err)
+}
+
+// getImage will return the image reference that should be deployed for the
+// given image; the image rewritten with the image rewrite rules, and pinned
+// to its digest if digest pinning is enabled.
+func (in *instance) getImage(img string) (string, error) {
+	ref := image.Rewrite(img, in.getImageRewrites())
+	if !in.pinDigests || strings.Contains(ref, "@") {
+		return ref, nil
+	}
+	dtl, err := in.InspectImage(img)
+	if err != nil {
+		return "", fmt.Errorf("error pinning digest of image %s: %w", img, err)
+	}
+	return image.Pin(ref, dtl.Digest), nil
+}
+
+// GetImageDistribution will inspect the image in the registry and return the
+// manifest descriptor and supported platforms, or will return an error if
+// failed.
+func (in *instance) GetImageDistribution(img string) (*image.Distribution, error) {
+	return image.InspectDistribution("docker://" + image.Rewrite(img, in.getImageRewrites()))
+}
+
+// GetImageManifest will fetch the manifest of given reference (a tag or a
+// digest) of given repository from the registry. The image is rewritten with
+// the image rewrite rules, and its blobs can be fetched via GetImageBlob.
+func (in *instance) GetImageManifest(repo, ref string) (*image.Manifest, error) {
+	sep := ":"
+	if strings.Contains(ref, ":") {
+		sep = "@"
+	}
+	return in.registry.GetManifest(repo, "docker://"+image.Rewrite(repo+sep+ref, in.getImageRewrites()))
+}
+
+// GetImageBlob will fetch the blob with given digest of given repository
+// from the registry, and return it together with its size (-1 if unknown).
+// The repository is rewritten with the image rewrite rules.
+func (in *instance) GetImageBlob(repo, dgst string) (io.ReadCloser, int64, error) {
+	return in.registry.GetBlob(repo, "docker://"+image.Rewrite(repo, in.getImageRewrites()), dgst)
+}
+
+// SetImageRewrites will replace the rules that are used to rewrite image
+// references before they are deployed.
+func (in *instance) SetImageRewrites(rules []image.RewriteRule) {
+	in.rewritesLock.Lock()
+	defer in.rewritesLock.Unlock()
+	in.imageRewrites = rules
+}
+
+// getImageRewrites will return the rules that are used to rewrite image
+// references before they are deployed.
+func (in *instance) getImageRewrites() []image.RewriteRule {
+	in.rewritesLock.RLock()
+	defer in.rewritesLock.RUnlock()
+	return in.imageRewrites
 }
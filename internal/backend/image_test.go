@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+func TestImageCacheInspect(t *testing.T) {
+	tests := []struct {
+		ref   string
+		ttl   time.Duration
+		wait  time.Duration
+		calls int32
+	}{
+		{ref: "docker://docker.io/library/redis:7", ttl: time.Minute, calls: 1},
+		{ref: "docker://docker.io/library/redis:7", ttl: 10 * time.Millisecond, wait: 20 * time.Millisecond, calls: 2},
+		{ref: "docker://docker.io/library/redis:7", ttl: 0, calls: 2},
+		{ref: "docker://docker.io/library/redis@sha256:1234", ttl: 0, calls: 1},
+	}
+	for i, tst := range tests {
+		cache := newImageCache(tst.ttl, false)
+		calls := int32(0)
+		release := make(chan struct{})
+		fn := func() (*image.Details, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return &image.Details{Digest: "sha256:1234"}, nil
+		}
+
+		// concurrent inspects of the same image should result in a single call
+		var wg sync.WaitGroup
+		for j := 0; j < 20; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := cache.inspect(tst.ref, fn); err != nil {
+					t.Errorf("failed test %d - unexpected error: %s", i, err)
+				}
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		time.Sleep(tst.wait)
+		dtl, err := cache.inspect(tst.ref, fn)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			continue
+		}
+		if dtl.Digest != "sha256:1234" {
+			t.Errorf("failed test %d - expected %v, but got %v", i, "sha256:1234", dtl.Digest)
+		}
+		if calls != tst.calls {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.calls, calls)
+		}
+	}
+}
+
+func TestGetImage(t *testing.T) {
+	tests := []struct {
+		img string
+		pin bool
+		out string
+	}{
+		{img: "redis:7", pin: false, out: "redis:7"},
+		{img: "redis:7", pin: true, out: "redis@sha256:1234"},
+		{img: "redis@sha256:abcd", pin: true, out: "redis@sha256:abcd"},
+		{img: "mirror.local/redis:7", pin: true, out: "mirror.example.com/redis@sha256:5678"},
+	}
+	for i, tst := range tests {
+		kub := &instance{
+			images:        newImageCache(0, tst.pin),
+			pinDigests:    tst.pin,
+			imageRewrites: []image.RewriteRule{{From: "mirror.local/*", To: "mirror.example.com/*"}},
+		}
+		kub.images.put("docker://redis:7", &image.Details{Digest: "sha256:1234"})
+		kub.images.put("docker://mirror.example.com/redis:7", &image.Details{Digest: "sha256:5678"})
+		res, err := kub.getImage(tst.img)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			continue
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
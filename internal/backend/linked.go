@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// startLinkedContainer will start given container in the pod of the
+// container it's linked to (container:<id> network mode). As containers
+// can't be added to an existing pod, the container is added as an ephemeral
+// container, which shares the network namespace of the pod.
+func (in *instance) startLinkedContainer(ctx context.Context, tainr *types.Container) (DeployState, error) {
+	begin := time.Now()
+
+	if tainr.HasVolumes() || tainr.HasPreArchives() || tainr.HasDockerSockBinding() {
+		return DeployFailed, fmt.Errorf("volumes are not supported for containers sharing the network of another container")
+	}
+
+	pulpol, err := tainr.GetImagePullPolicy()
+	if err != nil {
+		return DeployFailed, err
+	}
+
+	img, err := in.getImage(tainr.Image)
+	if err != nil {
+		return DeployFailed, err
+	}
+
+	pod, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
+	if err != nil {
+		return DeployFailed, fmt.Errorf("container %s is not running: %w", tainr.NetworkOwner, err)
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return DeployFailed, fmt.Errorf("container %s is not running", tainr.NetworkOwner)
+	}
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if ec.Name == tainr.GetContainerName() {
+			return DeployFailed, fmt.Errorf("containers sharing the network of another container can't be restarted")
+		}
+	}
+
+	if tainr.SeparateStderr() {
+		klog.Infof("separating stderr is not supported for container %s, ignoring", tainr.ShortID)
+	}
+
+	container := corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:            tainr.GetContainerName(),
+			Image:           img,
+			Command:         tainr.Entrypoint,
+			Args:            tainr.Cmd,
+			Env:             tainr.GetEnvVar(),
+			ImagePullPolicy: pulpol,
+			TTY:             tainr.Tty,
+			Stdin:           tainr.OpenStdin,
+		},
+	}
+	if in.securityProfile == SecurityProfileOpenShiftRestricted {
+		container.SecurityContext = getRestrictedSecurityContext(nil)
+	}
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, container)
+
+	resolved := time.Now()
+	tainr.SetStartTiming(types.PhaseResolve, resolved.Sub(begin))
+
+	if _, err := in.cli.CoreV1().Pods(in.namespace).UpdateEphemeralContainers(context.Background(), pod.Name, pod, metav1.UpdateOptions{}); err != nil {
+		return DeployFailed, err
+	}
+
+	created := time.Now()
+	tainr.SetStartTiming(types.PhaseCreate, created.Sub(resolved))
+
+	timeout, err := in.getStartTimeout(tainr)
+	if err != nil {
+		return DeployFailed, err
+	}
+	state, err := in.waitReadyState(ctx, tainr, timeout)
+	if err != nil {
+		return state, err
+	}
+
+	if err := in.MapContainerTCPPorts(tainr); err != nil {
+		return DeployFailed, err
+	}
+
+	if err := in.createServices(tainr); err != nil {
+		return state, err
+	}
+
+	in.setReadyTimings(tainr, created, time.Now())
+
+	return state, nil
+}
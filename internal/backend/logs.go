@@ -8,11 +8,17 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 
 	"github.com/joyrex2001/kubedock/internal/model/types"
 	"github.com/joyrex2001/kubedock/internal/util/ioproxy"
+	"github.com/joyrex2001/kubedock/internal/util/stdtag"
 )
 
+// logFlushGrace is the time given to a followed log stream to send the
+// final log lines, after the container has exited.
+const logFlushGrace = 2 * time.Second
+
 // LogOptions describe the supported log options
 type LogOptions struct {
 	// Keep connection after returning logs.
@@ -27,8 +33,16 @@ type LogOptions struct {
 
 // GetLogs will write the logs for given container to given writer using stdout/stderr multiplexing.
 func (in *instance) GetLogs(tainr *types.Container, opts *LogOptions, stop chan struct{}, w io.Writer) error {
-	out := ioproxy.New(w, ioproxy.Stdout, &sync.Mutex{})
+	lock := &sync.Mutex{}
+	out := ioproxy.New(w, ioproxy.Stdout, lock)
 	defer out.Flush()
+	if tainr.SeparateStderr() {
+		errout := ioproxy.New(w, ioproxy.Stderr, lock)
+		defer errout.Flush()
+		demux := stdtag.NewDemuxer(out, errout, opts.Timestamps)
+		defer demux.Flush()
+		return in.getLogs(tainr, opts, stop, demux)
+	}
 	return in.getLogs(tainr, opts, stop, out)
 }
 
@@ -38,7 +52,10 @@ func (in *instance) GetLogsRaw(tainr *types.Container, opts *LogOptions, stop ch
 }
 
 func (in *instance) getLogs(tainr *types.Container, opts *LogOptions, stop chan struct{}, out io.Writer) error {
-	options := newPodLogOptions(opts)
+	options := newPodLogOptions(tainr, opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	_, err := in.cli.CoreV1().Pods(in.namespace).Get(context.Background(), tainr.GetPodName(), metav1.GetOptions{})
 	if err != nil {
@@ -55,8 +72,24 @@ func (in *instance) getLogs(tainr *types.Container, opts *LogOptions, stop chan
 	stopL := make(chan struct{}, 1)
 
 	if opts.Follow {
+		exited, err := in.watchContainerExit(ctx, tainr)
+		if err != nil {
+			return err
+		}
 		go func() {
-			<-stop
+			select {
+			case <-stop:
+			case <-exited:
+				// give the log stream the opportunity to send the final lines
+				select {
+				case <-stop:
+				case <-ctx.Done():
+					return
+				case <-time.After(logFlushGrace):
+				}
+			case <-ctx.Done():
+				return
+			}
 			stopL <- struct{}{}
 			stream.Close()
 		}()
@@ -77,6 +110,12 @@ func (in *instance) getLogs(tainr *types.Container, opts *LogOptions, stop chan
 			break
 		}
 		if err != nil {
+			select {
+			case <-stopL:
+				// stream closed because of a stop or exited container
+				return nil
+			default:
+			}
 			return err
 		}
 		if n == 0 {
@@ -94,7 +133,51 @@ func (in *instance) getLogs(tainr *types.Container, opts *LogOptions, stop chan
 	return nil
 }
 
-func newPodLogOptions(opts *LogOptions) v1.PodLogOptions {
+// watchContainerExit will return a channel that is closed when the given
+// container has terminated in its pod, or the pod has been
+// deleted. The watch is stopped when the given context is done.
+func (in *instance) watchContainerExit(ctx context.Context, tainr *types.Container) (chan struct{}, error) {
+	exited := make(chan struct{})
+
+	watcher, err := in.cli.CoreV1().Pods(in.namespace).Watch(ctx, metav1.ListOptions{
+		LabelSelector: "kubedock.containerid=" + tainr.GetPodShortID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer watcher.Stop()
+		for {
+			select {
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				pod, isPod := event.Object.(*v1.Pod)
+				if event.Type == watch.Deleted || (isPod && isTerminated(pod, tainr.GetContainerName())) {
+					close(exited)
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return exited, nil
+}
+
+// isTerminated will return true if the container with given name in given
+// pod has terminated.
+func isTerminated(pod *v1.Pod, name string) bool {
+	if status := getContainerStatus(pod, name); status != nil && status.State.Terminated != nil {
+		return true
+	}
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
+
+func newPodLogOptions(tainr *types.Container, opts *LogOptions) v1.PodLogOptions {
 	var sinceTime *metav1.Time = nil
 	if opts.SinceTime != nil {
 		t := metav1.NewTime(*opts.SinceTime)
@@ -108,7 +191,7 @@ func newPodLogOptions(opts *LogOptions) v1.PodLogOptions {
 	}
 
 	return v1.PodLogOptions{
-		Container:  "main",
+		Container:  tainr.GetContainerName(),
 		Follow:     opts.Follow,
 		TailLines:  tailLines,
 		SinceTime:  sinceTime,
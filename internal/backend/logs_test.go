@@ -1,9 +1,13 @@
 package backend
 
 import (
+	"context"
 	"io"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/joyrex2001/kubedock/internal/model/types"
@@ -52,3 +56,45 @@ func TestGetLogs(t *testing.T) {
 		w.Close()
 	}
 }
+
+func TestWatchContainerExit(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "f1spirit",
+			Namespace: "default",
+			Labels:    map[string]string{"kubedock.containerid": "tb303"},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+	cli := fake.NewSimpleClientset(pod)
+	kub := &instance{namespace: "default", cli: cli}
+	tainr := &types.Container{ID: "rc752", ShortID: "tb303", Name: "f1spirit"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exited, err := kub.watchContainerExit(ctx, tainr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	select {
+	case <-exited:
+		t.Errorf("failed running - container reported exited while running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}
+	if _, err := cli.CoreV1().Pods("default").UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Errorf("failed terminated - container exit not reported")
+	}
+}
@@ -1,40 +1,63 @@
 package backend
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"io/fs"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
 	"github.com/joyrex2001/kubedock/internal/util/podtemplate"
 )
 
 // Backend is the interface to orchestrate and manage kubernetes objects.
 type Backend interface {
-	StartContainer(*types.Container) (DeployState, error)
+	StartContainer(context.Context, *types.Container) (DeployState, error)
 	AttachContainer(*types.Container, io.Reader, io.Writer, io.Writer, bool) error
 	GetContainerStatus(*types.Container) (DeployState, error)
 	CreatePortForwards(*types.Container)
 	CreateReverseProxies(*types.Container)
+	UpdateServices(*types.Container) error
+	CleanForwards([]*types.Container, time.Duration) int
 	GetPodIP(*types.Container) (string, error)
 	DeleteAll() error
 	DeleteWithKubedockID(string) error
+	DeleteProject(string) error
 	DeleteContainer(*types.Container) error
+	RetainContainer(*types.Container) (bool, error)
 	DeleteOlderThan(time.Duration) error
 	WatchDeleteContainer(*types.Container) (chan struct{}, error)
+	WatchContainerExit(*types.Container) (chan ContainerExit, error)
 	CopyFromContainer(*types.Container, string, io.Writer) error
 	CopyToContainer(*types.Container, io.Reader, string, bool) error
-	GetFileModeInContainer(tainr *types.Container, path string) (fs.FileMode, error)
+	GetFileStatInContainer(tainr *types.Container, path string) (*FileStat, error)
 	FileExistsInContainer(tainr *types.Container, path string) (bool, error)
-	ExecContainer(*types.Container, *types.Exec, io.Reader, io.Writer) (int, error)
+	StartArchiveHelper(*types.Container) (*types.Container, error)
+	StageArchive(*types.Container, io.Reader) (string, error)
+	DeleteContainerVolumes(*types.Container) error
+	ExecContainer(context.Context, *types.Container, *types.Exec, io.Reader, io.Writer) (int, error)
 	GetLogs(*types.Container, *LogOptions, chan struct{}, io.Writer) error
 	GetLogsRaw(*types.Container, *LogOptions, chan struct{}, io.Writer) error
-	GetImageExposedPorts(string) (map[string]struct{}, error)
+	InspectImage(string) (*image.Details, error)
+	GetImageDistribution(string) (*image.Distribution, error)
+	GetImageManifest(string, string) (*image.Manifest, error)
+	GetImageBlob(string, string) (io.ReadCloser, int64, error)
+	PrewarmImages([]string) ([]string, error)
+	SetImageRewrites([]image.RewriteRule)
+	GetPodEvents(*types.Container) ([]corev1.Event, error)
+	GetContainerStats([]*types.Container) (map[string]*ContainerStats, error)
+	GetCapacity() (*Capacity, error)
+	DeployService(*types.Service) error
+	GetServiceReplicas(*types.Service) (int, error)
+	DeleteService(*types.Service) error
+	CheckFeature(string) error
 }
 
 // instance is the internal representation of the Backend object.
@@ -46,11 +69,33 @@ type instance struct {
 	initImage         string
 	dindImage         string
 	disableDind       bool
+	selinuxRelabel    bool
+	securityProfile   string
+	disableInjection  bool
 	imagePullSecrets  []string
+	imageRewrites     []image.RewriteRule
+	rewritesLock      sync.RWMutex
+	images            *imageCache
+	registry          *image.Proxy
+	pinDigests        bool
 	namespace         string
 	timeOut           int
 	kuburl            string
 	disableServices   bool
+	archiveHelper     bool
+	archiveSize       resource.Quantity
+	archiveClass      string
+	archiveMode       corev1.PersistentVolumeAccessMode
+	retainFailed      time.Duration
+	forwards          *forwards
+	execIdleTimeout   time.Duration
+	execMaxDuration   time.Duration
+	capacity          *Capacity
+	capacityTime      time.Time
+	capacityLock      sync.Mutex
+	quotaPolicy       string
+	quotaLock         sync.Mutex
+	disabled          map[string]*FeatureError
 }
 
 // Config is the structure to instantiate a Backend object
@@ -64,6 +109,15 @@ type Config struct {
 	// ImagePullSecrets is an optional list of image pull secrets that need
 	// to be added to the used pod templates
 	ImagePullSecrets []string
+	// ImageRewrites is an optional list of rules to rewrite image references
+	// before they are deployed (e.g. to use a mirror registry)
+	ImageRewrites []image.RewriteRule
+	// ImageCacheTTL is the time an inspected image reference is resolved to
+	// the same digest (0 = only deduplicate concurrent inspects)
+	ImageCacheTTL time.Duration
+	// PinDigests will resolve image tags to a digest at first use, and use
+	// that digest for all subsequent containers of the image
+	PinDigests bool
 	// InitImage is the image that is used as init container to prepare vols
 	InitImage string
 	// DindImage is the image that is used as a sidecar container to
@@ -71,6 +125,19 @@ type Config struct {
 	DindImage string
 	// DisableDind will disable docker-in-docker support when set to true
 	DisableDind bool
+	// SELinuxRelabel will set the SELinux level of pods that have volumes
+	// with a z or Z option, so the volumes are relabeled accordingly.
+	SELinuxRelabel bool
+	// SecurityProfile is the security profile that is applied to the pods
+	// (privileged, baseline or openshift-restricted)
+	SecurityProfile string
+	// DisableSidecarInjection will add the labels and annotations to the pods
+	// that disable sidecar injection of istio and linkerd
+	DisableSidecarInjection bool
+	// QuotaPolicy is the policy for containers that don't fit in the
+	// resource quotas of the namespace when they are started (reject or
+	// queue)
+	QuotaPolicy string
 	// TimeOut is the max amount of time to wait until a container started
 	// or deleted.
 	TimeOut time.Duration
@@ -84,31 +151,109 @@ type Config struct {
 	// Disable the creation of services. A networking solution such as kubedock-dns
 	// should be used.
 	DisableServices bool
+
+	// ArchiveHelper will keep the volumes and the staged archives of the
+	// containers on a persistent volume claim, so archive helper pods can
+	// access them while the container is not running.
+	ArchiveHelper bool
+	// ArchiveVolumeSize is the size of the persistent volume claims of the
+	// containers if ArchiveHelper is enabled (default 1Gi).
+	ArchiveVolumeSize string
+	// ArchiveStorageClass is the storage class of the persistent volume
+	// claims of the containers (empty is the default storage class).
+	ArchiveStorageClass string
+	// ArchiveAccessMode is the access mode of the persistent volume claims
+	// of the containers (default ReadWriteOnce).
+	ArchiveAccessMode string
+
+	// ScopedRBAC will probe the permissions of the service account at
+	// startup, and disable the features that require permissions that are
+	// missing.
+	ScopedRBAC bool
+
+	// RetainFailed is the duration that failed containers which are labelled
+	// with retain-on-failure are kept after they are removed.
+	RetainFailed time.Duration
+
+	// ExecIdleTimeout is the max time an exec or attach session can be idle
+	// before it is terminated (0 = never).
+	ExecIdleTimeout time.Duration
+	// ExecMaxDuration is the max time an exec or attach session can run
+	// before it is terminated (0 = never).
+	ExecMaxDuration time.Duration
 }
 
+// instance should implement the complete Backend interface.
+var _ Backend = &instance{}
+
 // New will return a Backend instance.
 func New(cfg Config) (Backend, error) {
+	if cfg.SecurityProfile != "" {
+		if err := ValidateSecurityProfile(cfg.SecurityProfile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.QuotaPolicy != "" {
+		if err := ValidateQuotaPolicy(cfg.QuotaPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	size := cfg.ArchiveVolumeSize
+	if size == "" {
+		size = "1Gi"
+	}
+	archsize, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive volume size %s: %w", size, err)
+	}
+	archmode, err := parseAccessMode(cfg.ArchiveAccessMode)
+	if err != nil {
+		return nil, err
+	}
+
 	pod := &corev1.Pod{}
 	if cfg.PodTemplate != "" {
-		var err error
 		pod, err = podtemplate.PodFromFile(cfg.PodTemplate)
 		if err != nil {
 			return nil, fmt.Errorf("error opening podtemplate: %w", err)
 		}
 	}
 
-	return &instance{
+	kub := &instance{
 		cli:               cfg.Client,
 		cfg:               cfg.RestConfig,
 		initImage:         cfg.InitImage,
 		dindImage:         cfg.DindImage,
 		disableDind:       cfg.DisableDind,
+		selinuxRelabel:    cfg.SELinuxRelabel,
+		securityProfile:   cfg.SecurityProfile,
+		quotaPolicy:       cfg.QuotaPolicy,
+		disableInjection:  cfg.DisableSidecarInjection,
 		namespace:         cfg.Namespace,
 		imagePullSecrets:  cfg.ImagePullSecrets,
+		imageRewrites:     cfg.ImageRewrites,
+		images:            newImageCache(cfg.ImageCacheTTL, cfg.PinDigests),
+		pinDigests:        cfg.PinDigests,
+		registry:          image.NewProxy(),
 		podTemplate:       pod,
 		containerTemplate: podtemplate.ContainerFromPod(pod),
 		kuburl:            cfg.KubedockURL,
 		timeOut:           int(cfg.TimeOut.Seconds()),
 		disableServices:   cfg.DisableServices,
-	}, nil
+		archiveHelper:     cfg.ArchiveHelper,
+		archiveSize:       archsize,
+		archiveClass:      cfg.ArchiveStorageClass,
+		archiveMode:       archmode,
+		retainFailed:      cfg.RetainFailed,
+		forwards:          newForwards(),
+		execIdleTimeout:   cfg.ExecIdleTimeout,
+		execMaxDuration:   cfg.ExecMaxDuration,
+	}
+	if cfg.ScopedRBAC {
+		if err := kub.probePermissions(); err != nil {
+			return nil, err
+		}
+	}
+	return kub, nil
 }
@@ -0,0 +1,163 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+const (
+	// prewarmImagesAnnotation is the annotation on the prewarm daemonset that
+	// contains the list of images that are pre-pulled.
+	prewarmImagesAnnotation = "kubedock.prewarm/images"
+	// kubedockBinPath is the location where the kubedock binary is copied to
+	// inside containers that use it (e.g. the prewarm init containers).
+	kubedockBinPath = "/kubedock"
+)
+
+// PrewarmImages will make sure the given images are pulled on all nodes, by
+// deploying (or updating) a daemonset that contains an init container for
+// each image. It will return the complete list of images that are currently
+// pre-pulled by this kubedock instance.
+func (in *instance) PrewarmImages(images []string) ([]string, error) {
+	if err := in.CheckFeature(FeaturePrewarm); err != nil {
+		return nil, err
+	}
+	dss := in.cli.AppsV1().DaemonSets(in.namespace)
+	name := in.getPrewarmName()
+
+	ds, err := dss.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+
+	all := map[string]bool{}
+	if exists {
+		for _, img := range strings.Split(ds.ObjectMeta.Annotations[prewarmImagesAnnotation], ",") {
+			if img != "" {
+				all[img] = true
+			}
+		}
+	}
+	for _, img := range images {
+		if img = strings.TrimSpace(img); img != "" {
+			all[img] = true
+		}
+	}
+	res := []string{}
+	for img := range all {
+		res = append(res, img)
+	}
+	sort.Strings(res)
+
+	nds := in.getPrewarmDaemonSet(name, res)
+	if !exists {
+		klog.Infof("pre-pulling images %v on all nodes", res)
+		_, err = dss.Create(context.Background(), nds, metav1.CreateOptions{})
+		return res, err
+	}
+	if ds.ObjectMeta.Annotations[prewarmImagesAnnotation] == nds.ObjectMeta.Annotations[prewarmImagesAnnotation] {
+		return res, nil
+	}
+	klog.Infof("updating pre-pulled images to %v", res)
+	ds.ObjectMeta.Annotations = nds.ObjectMeta.Annotations
+	ds.Spec.Template = nds.Spec.Template
+	_, err = dss.Update(context.Background(), ds, metav1.UpdateOptions{})
+	return res, err
+}
+
+// getPrewarmName will return the name of the prewarm daemonset of this
+// kubedock instance.
+func (in *instance) getPrewarmName() string {
+	return "kubedock-prewarm-" + config.InstanceID
+}
+
+// getPrewarmDaemonSet will return a daemonset that pre-pulls the given images.
+// Each image is pulled by an init container that runs a copy of the (static)
+// kubedock binary, so the images don't require a shell to be present.
+func (in *instance) getPrewarmDaemonSet(name string, images []string) *appsv1.DaemonSet {
+	labels := map[string]string{}
+	for k, v := range config.DefaultLabels {
+		labels[k] = v
+	}
+	for k, v := range config.SystemLabels {
+		labels[k] = v
+	}
+	labels["kubedock.prewarm"] = "true"
+
+	annotations := map[string]string{}
+	for k, v := range config.DefaultAnnotations {
+		annotations[k] = v
+	}
+	annotations[prewarmImagesAnnotation] = strings.Join(images, ",")
+
+	mount := corev1.VolumeMount{Name: "kubedock-bin", MountPath: kubedockBinPath}
+
+	spec := in.podTemplate.DeepCopy().Spec
+	spec.RestartPolicy = corev1.RestartPolicyAlways
+	spec.Volumes = []corev1.Volume{{
+		Name:         mount.Name,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}}
+	for _, ps := range in.imagePullSecrets {
+		spec.ImagePullSecrets = append(spec.ImagePullSecrets, corev1.LocalObjectReference{Name: ps})
+	}
+
+	setup := in.containerTemplate
+	setup.Name = "setup"
+	setup.Image = in.initImage
+	setup.Command = []string{"cp", "/usr/local/bin/kubedock", kubedockBinPath + "/kubedock"}
+	setup.VolumeMounts = []corev1.VolumeMount{mount}
+	spec.InitContainers = []corev1.Container{setup}
+
+	for i, img := range images {
+		container := in.containerTemplate
+		container.Name = fmt.Sprintf("prewarm-%d", i)
+		container.Image = image.Rewrite(img, in.getImageRewrites())
+		container.ImagePullPolicy = corev1.PullIfNotPresent
+		container.Command = []string{kubedockBinPath + "/kubedock", "version"}
+		container.VolumeMounts = []corev1.VolumeMount{mount}
+		spec.InitContainers = append(spec.InitContainers, container)
+	}
+
+	pause := in.containerTemplate
+	pause.Name = "main"
+	pause.Image = in.initImage
+	pause.Command = []string{"sh", "-c", "while true; do sleep 3600; done"}
+	spec.Containers = []corev1.Container{pause}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   in.namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"kubedock.prewarm": "true",
+					"kubedock.id":      config.InstanceID,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: annotations,
+				},
+				Spec: spec,
+			},
+		},
+	}
+}
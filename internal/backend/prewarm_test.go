@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+func TestPrewarmImages(t *testing.T) {
+	kub := &instance{
+		namespace:     "default",
+		cli:           fake.NewSimpleClientset(),
+		podTemplate:   &corev1.Pod{},
+		initImage:     "joyrex2001/kubedock:latest",
+		imageRewrites: []image.RewriteRule{{From: "docker.io/library/*", To: "mirror.local/*"}},
+	}
+
+	tests := []struct {
+		in   []string
+		out  []string
+		imgs []string
+	}{
+		{
+			in:   []string{"redis:7", " postgres:16", ""},
+			out:  []string{"postgres:16", "redis:7"},
+			imgs: []string{"joyrex2001/kubedock:latest", "mirror.local/postgres:16", "mirror.local/redis:7"},
+		},
+		{
+			in:   []string{"redis:7", "quay.io/keycloak/keycloak:21"},
+			out:  []string{"postgres:16", "quay.io/keycloak/keycloak:21", "redis:7"},
+			imgs: []string{"joyrex2001/kubedock:latest", "mirror.local/postgres:16", "quay.io/keycloak/keycloak:21", "mirror.local/redis:7"},
+		},
+	}
+
+	for i, tst := range tests {
+		res, err := kub.PrewarmImages(tst.in)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+		dss, _ := kub.cli.AppsV1().DaemonSets("default").List(context.Background(), metav1.ListOptions{})
+		if len(dss.Items) != 1 {
+			t.Errorf("failed test %d - expected 1 daemonset, but got %d", i, len(dss.Items))
+			continue
+		}
+		imgs := []string{}
+		for _, c := range dss.Items[0].Spec.Template.Spec.InitContainers {
+			imgs = append(imgs, c.Image)
+		}
+		if !reflect.DeepEqual(imgs, tst.imgs) {
+			t.Errorf("failed test %d - expected init images %v, but got %v", i, tst.imgs, imgs)
+		}
+	}
+
+	if err := kub.DeleteAll(); err != nil {
+		t.Errorf("unexpected error deleting all resources: %s", err)
+	}
+	dss, _ := kub.cli.AppsV1().DaemonSets("default").List(context.Background(), metav1.ListOptions{})
+	if len(dss.Items) != 0 {
+		t.Errorf("expected daemonset to be deleted, but got %d", len(dss.Items))
+	}
+}
@@ -0,0 +1,301 @@
+package backend
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// QuotaPolicyReject rejects containers that don't fit in the resource
+	// quotas of the namespace when they are started
+	QuotaPolicyReject = "reject"
+	// QuotaPolicyQueue queues containers that don't fit in the resource
+	// quotas of the namespace until the quota is available, or the start
+	// timeout expires
+	QuotaPolicyQueue = "queue"
+)
+
+// quotaRetryInterval is the interval in which queued containers check if
+// the quota is available, which is also the retry-after of rejected
+// containers.
+var quotaRetryInterval = 10 * time.Second
+
+// QuotaExceededError is returned when a pod can't be created because it
+// would exceed a resource quota of the namespace.
+type QuotaExceededError struct {
+	Quota     string
+	Resource  string
+	Need      resource.Quantity
+	Available resource.Quantity
+}
+
+// Error will return a description of the exceeded quota.
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: need %s, %s available (%s in resourcequota %s)", e.Need.String(), e.Available.String(), e.Resource, e.Quota)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *QuotaExceededError) HTTPStatus() int {
+	return http.StatusTooManyRequests
+}
+
+// RetryAfter will return the duration after which the client can retry.
+func (e *QuotaExceededError) RetryAfter() time.Duration {
+	return quotaRetryInterval
+}
+
+// ValidateQuotaPolicy will check if the given quota policy is a supported
+// policy.
+func ValidateQuotaPolicy(policy string) error {
+	switch policy {
+	case QuotaPolicyReject, QuotaPolicyQueue:
+		return nil
+	}
+	return fmt.Errorf("invalid quota policy %s, supported policies are %s and %s", policy, QuotaPolicyReject, QuotaPolicyQueue)
+}
+
+// admitPod will verify if the given pod fits in the resource quotas of the
+// namespace. With the queue policy, it will wait until the pod fits, or the
+// given timeout (in seconds) expires. Only the check and the creation of the
+// pod are serialized; queued pods don't hold back pods that fit. The
+// returned function should be called once the pod is created, so the next
+// pod is checked with the quota usage of this pod. Waiting is aborted when
+// the given context is done.
+func (in *instance) admitPod(ctx context.Context, pod *corev1.Pod, timeout int) (func(), error) {
+	if in.quotaPolicy != QuotaPolicyQueue {
+		return func() {}, in.checkQuota(pod)
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for {
+		in.quotaLock.Lock()
+		err := in.checkQuota(pod)
+		if err == nil {
+			return in.quotaLock.Unlock, nil
+		}
+		in.quotaLock.Unlock()
+		var qerr *QuotaExceededError
+		if !goerrors.As(err, &qerr) || time.Now().Add(quotaRetryInterval).After(deadline) {
+			return func() {}, err
+		}
+		klog.V(2).Infof("queueing pod %s: %s", pod.Name, err)
+		if err := sleep(ctx, quotaRetryInterval); err != nil {
+			return func() {}, err
+		}
+	}
+}
+
+// checkQuota will verify if the given pod fits in the resource quotas of the
+// namespace, so pods that would be rejected (or never be scheduled) fail
+// early with a clear error. Quotas with scopes are not taken into account.
+// If kubedock is not allowed to read resource quotas, the check is skipped.
+func (in *instance) checkQuota(pod *corev1.Pod) error {
+	quotas, err := in.cli.CoreV1().ResourceQuotas(in.namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsForbidden(err) {
+			klog.V(3).Infof("not allowed to list resource quotas, skipping quota check")
+			return nil
+		}
+		return err
+	}
+
+	need := getPodResources(pod)
+	for _, quota := range quotas.Items {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+		names := []string{}
+		for name := range quota.Status.Hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			qty, ok := need[corev1.ResourceName(name)]
+			if !ok {
+				continue
+			}
+			avail := quota.Status.Hard[corev1.ResourceName(name)].DeepCopy()
+			avail.Sub(quota.Status.Used[corev1.ResourceName(name)])
+			if qty.Cmp(avail) > 0 {
+				if avail.Sign() < 0 {
+					avail = resource.Quantity{Format: avail.Format}
+				}
+				return &QuotaExceededError{Quota: quota.Name, Resource: name, Need: qty, Available: avail}
+			}
+		}
+	}
+	return nil
+}
+
+// getPodResources will return the amount of quota resources the given pod
+// will consume. Init containers run sequentially, so for each resource the
+// maximum of the largest init container and the sum of the containers is used.
+func getPodResources(pod *corev1.Pod) corev1.ResourceList {
+	res := corev1.ResourceList{
+		corev1.ResourcePods:                    resource.MustParse("1"),
+		corev1.ResourceName("count/pods"):      resource.MustParse("1"),
+		corev1.ResourceName("requests.cpu"):    resource.Quantity{Format: resource.DecimalSI},
+		corev1.ResourceName("requests.memory"): resource.Quantity{Format: resource.BinarySI},
+		corev1.ResourceName("limits.cpu"):      resource.Quantity{Format: resource.DecimalSI},
+		corev1.ResourceName("limits.memory"):   resource.Quantity{Format: resource.BinarySI},
+	}
+	add := func(dst corev1.ResourceList, name corev1.ResourceName, qty resource.Quantity) {
+		cur := dst[name]
+		cur.Add(qty)
+		dst[name] = cur
+	}
+	containers := corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		for _, typ := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			add(containers, "requests."+typ, getRequest(c.Resources, typ))
+			add(containers, "limits."+typ, c.Resources.Limits[typ])
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for _, typ := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if qty := getRequest(c.Resources, typ); qty.Cmp(containers["requests."+typ]) > 0 {
+				containers["requests."+typ] = qty
+			}
+			if qty := c.Resources.Limits[typ]; qty.Cmp(containers["limits."+typ]) > 0 {
+				containers["limits."+typ] = qty
+			}
+		}
+	}
+	for name, qty := range containers {
+		add(res, name, qty)
+	}
+	res[corev1.ResourceCPU] = res["requests.cpu"]
+	res[corev1.ResourceMemory] = res["requests.memory"]
+	return res
+}
+
+// getRequest will return the request of the given resource, which defaults
+// to the limit if no request is specified.
+func getRequest(req corev1.ResourceRequirements, typ corev1.ResourceName) resource.Quantity {
+	if qty, ok := req.Requests[typ]; ok {
+		return qty
+	}
+	return req.Limits[typ]
+}
+
+// Capacity contains the amount of cpu and memory that is available to the
+// containers that are deployed by kubedock.
+type Capacity struct {
+	// NCPU is the number of cpus, rounded up
+	NCPU int
+	// MemTotal is the amount of memory in bytes
+	MemTotal int64
+}
+
+// capacityTTL is the duration the capacity of the namespace is cached.
+const capacityTTL = time.Minute
+
+// GetCapacity will return the cpu and memory capacity of the namespace. This
+// is based on the hard limits of the resource quotas of the namespace (the
+// smallest quota wins, quotas with scopes are ignored), and falls back to
+// the aggregated allocatable capacity of the schedulable nodes. If neither
+// can be read, the capacity is reported as 0. The result is cached for a
+// minute, as it is typically requested by every client that connects.
+func (in *instance) GetCapacity() (*Capacity, error) {
+	in.capacityLock.Lock()
+	defer in.capacityLock.Unlock()
+	if in.capacity != nil && time.Since(in.capacityTime) < capacityTTL {
+		return in.capacity, nil
+	}
+
+	cpu, mem, err := in.getQuotaCapacity()
+	if err != nil {
+		return nil, err
+	}
+	if cpu == nil || mem == nil {
+		ncpu, nmem, err := in.getNodeCapacity()
+		if err != nil {
+			return nil, err
+		}
+		if cpu == nil {
+			cpu = ncpu
+		}
+		if mem == nil {
+			mem = nmem
+		}
+	}
+
+	res := &Capacity{}
+	if cpu != nil {
+		res.NCPU = int(cpu.Value())
+	}
+	if mem != nil {
+		res.MemTotal = mem.Value()
+	}
+	in.capacity = res
+	in.capacityTime = time.Now()
+	return res, nil
+}
+
+// getQuotaCapacity will return the cpu and memory hard limits of the resource
+// quotas in the namespace, or nil if these are not limited (or kubedock is
+// not allowed to read the resource quotas). Limits take precedence over
+// requests.
+func (in *instance) getQuotaCapacity() (*resource.Quantity, *resource.Quantity, error) {
+	quotas, err := in.cli.CoreV1().ResourceQuotas(in.namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsForbidden(err) {
+			klog.V(3).Infof("not allowed to list resource quotas, ignoring quotas for capacity")
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	smallest := func(cur *resource.Quantity, hard corev1.ResourceList, names ...corev1.ResourceName) *resource.Quantity {
+		for _, name := range names {
+			if qty, ok := hard[name]; ok {
+				if cur == nil || qty.Cmp(*cur) < 0 {
+					return &qty
+				}
+				return cur
+			}
+		}
+		return cur
+	}
+	var cpu, mem *resource.Quantity
+	for _, quota := range quotas.Items {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+		cpu = smallest(cpu, quota.Status.Hard, "limits.cpu", "requests.cpu", corev1.ResourceCPU)
+		mem = smallest(mem, quota.Status.Hard, "limits.memory", "requests.memory", corev1.ResourceMemory)
+	}
+	return cpu, mem, nil
+}
+
+// getNodeCapacity will return the aggregated allocatable cpu and memory of
+// the schedulable nodes, or nil if kubedock is not allowed to list nodes.
+func (in *instance) getNodeCapacity() (*resource.Quantity, *resource.Quantity, error) {
+	nodes, err := in.cli.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsForbidden(err) {
+			klog.V(3).Infof("not allowed to list nodes, ignoring nodes for capacity")
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	cpu := resource.Quantity{Format: resource.DecimalSI}
+	mem := resource.Quantity{Format: resource.BinarySI}
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		cpu.Add(node.Status.Allocatable[corev1.ResourceCPU])
+		mem.Add(node.Status.Allocatable[corev1.ResourceMemory])
+	}
+	return &cpu, &mem, nil
+}
@@ -0,0 +1,216 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckQuota(t *testing.T) {
+	quota := func(hard, used corev1.ResourceList, scopes ...corev1.ResourceQuotaScope) *corev1.ResourceQuota {
+		return &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "default"},
+			Spec:       corev1.ResourceQuotaSpec{Hard: hard, Scopes: scopes},
+			Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+		}
+	}
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{"memory": resource.MustParse("2Gi")},
+			Limits:   corev1.ResourceList{"cpu": resource.MustParse("500m")},
+		}}},
+		InitContainers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{"cpu": resource.MustParse("1")},
+		}}},
+	}}
+	tests := []struct {
+		quota *corev1.ResourceQuota
+		err   string
+	}{
+		{quota: nil, err: ""},
+		{
+			quota: quota(corev1.ResourceList{"requests.memory": resource.MustParse("4Gi")}, corev1.ResourceList{"requests.memory": resource.MustParse("1Gi")}),
+			err:   "",
+		},
+		{
+			quota: quota(corev1.ResourceList{"requests.memory": resource.MustParse("4Gi")}, corev1.ResourceList{"requests.memory": resource.MustParse("3584Mi")}),
+			err:   "quota exceeded: need 2Gi, 512Mi available (requests.memory in resourcequota compute)",
+		},
+		{
+			quota: quota(corev1.ResourceList{"requests.cpu": resource.MustParse("1500m")}, corev1.ResourceList{"requests.cpu": resource.MustParse("1")}),
+			err:   "quota exceeded: need 1, 500m available (requests.cpu in resourcequota compute)",
+		},
+		{
+			quota: quota(corev1.ResourceList{"pods": resource.MustParse("2")}, corev1.ResourceList{"pods": resource.MustParse("2")}),
+			err:   "quota exceeded: need 1, 0 available (pods in resourcequota compute)",
+		},
+		{
+			quota: quota(corev1.ResourceList{"pods": resource.MustParse("2")}, corev1.ResourceList{"pods": resource.MustParse("2")}, corev1.ResourceQuotaScopeBestEffort),
+			err:   "",
+		},
+	}
+	for i, tst := range tests {
+		cli := fake.NewSimpleClientset()
+		if tst.quota != nil {
+			cli = fake.NewSimpleClientset(tst.quota)
+		}
+		kub := &instance{namespace: "default", cli: cli}
+		err := kub.checkQuota(pod)
+		if tst.err == "" && err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if tst.err != "" {
+			var qerr *QuotaExceededError
+			if !errors.As(err, &qerr) || err.Error() != tst.err {
+				t.Errorf("failed test %d - expected %s, but got %v", i, tst.err, err)
+			}
+		}
+	}
+}
+
+func TestAdmitPod(t *testing.T) {
+	defer func(interval time.Duration) { quotaRetryInterval = interval }(quotaRetryInterval)
+	quotaRetryInterval = 10 * time.Millisecond
+	quota := func(used string) *corev1.ResourceQuota {
+		return &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "default"},
+			Status: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{"pods": resource.MustParse("1")},
+				Used: corev1.ResourceList{"pods": resource.MustParse(used)},
+			},
+		}
+	}
+	tests := []struct {
+		policy  string
+		timeout int
+		free    bool
+		err     bool
+	}{
+		{policy: QuotaPolicyReject, timeout: 5, free: true, err: true},
+		{policy: QuotaPolicyQueue, timeout: 5, free: true, err: false},
+		{policy: QuotaPolicyQueue, timeout: 0, free: true, err: true},
+	}
+	for i, tst := range tests {
+		cli := fake.NewSimpleClientset(quota("1"))
+		kub := &instance{namespace: "default", cli: cli, quotaPolicy: tst.policy}
+		if tst.free {
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				cli.CoreV1().ResourceQuotas("default").Update(context.Background(), quota("0"), metav1.UpdateOptions{})
+			}()
+		}
+		done, err := kub.admitPod(context.Background(), &corev1.Pod{}, tst.timeout)
+		done()
+		var qerr *QuotaExceededError
+		if tst.err != errors.As(err, &qerr) {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+		if err != nil && qerr.HTTPStatus() != http.StatusTooManyRequests {
+			t.Errorf("failed test %d - expected status %d, but got %d", i, http.StatusTooManyRequests, qerr.HTTPStatus())
+		}
+	}
+
+	// a queued pod should not hold back pods that fit
+	cli := fake.NewSimpleClientset(&corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "memory", Namespace: "default"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{"requests.memory": resource.MustParse("1Gi")},
+			Used: corev1.ResourceList{"requests.memory": resource.MustParse("0")},
+		},
+	})
+	kub := &instance{namespace: "default", cli: cli, quotaPolicy: QuotaPolicyQueue}
+	pod := func(mem string) *corev1.Pod {
+		return &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(mem)}},
+		}}}}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan error)
+	go func() {
+		done, err := kub.admitPod(ctx, pod("2Gi"), 5)
+		done()
+		queued <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	admitted := make(chan error)
+	go func() {
+		done, err := kub.admitPod(context.Background(), pod("512Mi"), 5)
+		done()
+		admitted <- err
+	}()
+	select {
+	case err := <-admitted:
+		if err != nil {
+			t.Errorf("expected pod that fits to be admitted, but got %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected pod that fits to be admitted, but it was held back by a queued pod")
+	}
+	cancel()
+	if err := <-queued; err == nil {
+		t.Errorf("expected queued pod to fail after cancel")
+	}
+}
+
+func TestGetCapacity(t *testing.T) {
+	quota := func(name string, hard corev1.ResourceList) *corev1.ResourceQuota {
+		return &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     corev1.ResourceQuotaStatus{Hard: hard},
+		}
+	}
+	node := func(name string, unsched bool, cpu, mem string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Unschedulable: unsched},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				"cpu":    resource.MustParse(cpu),
+				"memory": resource.MustParse(mem),
+			}},
+		}
+	}
+	tests := []struct {
+		objs []runtime.Object
+		out  Capacity
+	}{
+		{objs: []runtime.Object{}, out: Capacity{}},
+		{
+			objs: []runtime.Object{node("n1", false, "4", "8Gi"), node("n2", false, "3500m", "8Gi"), node("n3", true, "8", "16Gi")},
+			out:  Capacity{NCPU: 8, MemTotal: 16 * 1024 * 1024 * 1024},
+		},
+		{
+			objs: []runtime.Object{
+				node("n1", false, "4", "8Gi"),
+				quota("compute", corev1.ResourceList{"limits.memory": resource.MustParse("4Gi"), "requests.cpu": resource.MustParse("1500m")}),
+				quota("small", corev1.ResourceList{"requests.memory": resource.MustParse("2Gi")}),
+			},
+			out: Capacity{NCPU: 2, MemTotal: 2 * 1024 * 1024 * 1024},
+		},
+		{
+			objs: []runtime.Object{
+				node("n1", false, "4", "8Gi"),
+				quota("compute", corev1.ResourceList{"limits.memory": resource.MustParse("4Gi")}),
+			},
+			out: Capacity{NCPU: 4, MemTotal: 4 * 1024 * 1024 * 1024},
+		},
+	}
+	for i, tst := range tests {
+		kub := &instance{namespace: "default", cli: fake.NewSimpleClientset(tst.objs...)}
+		res, err := kub.GetCapacity()
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			continue
+		}
+		if *res != tst.out {
+			t.Errorf("failed test %d - expected %+v, but got %+v", i, tst.out, *res)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// FeatureServices is the creation of services for the containers
+	FeatureServices = "services"
+	// FeatureExec is executing commands in, and copying files to and from,
+	// running containers
+	FeatureExec = "exec"
+	// FeatureAttach is attaching to running containers
+	FeatureAttach = "attach"
+	// FeaturePortForward is port-forwarding published ports of containers
+	FeaturePortForward = "port-forward"
+	// FeatureMetrics is reporting the resource usage of containers
+	FeatureMetrics = "metrics"
+	// FeatureEvents is reporting the kubernetes events of containers
+	FeatureEvents = "events"
+	// FeatureDeployments is deploying (swarm) services as deployments
+	FeatureDeployments = "deployments"
+	// FeaturePrewarm is pre-pulling images on all nodes with a daemonset
+	FeaturePrewarm = "prewarm"
+	// FeatureArchiveHelper is copying archives to and from containers that
+	// are not running, using persistent volume claims
+	FeatureArchiveHelper = "archive-helper"
+)
+
+// permission is a verb on a (sub)resource in the namespace of kubedock.
+type permission struct {
+	verb        string
+	group       string
+	resource    string
+	subresource string
+}
+
+// String will return a readable representation of the permission (e.g.
+// create pods/exec).
+func (p permission) String() string {
+	res := p.resource
+	if p.subresource != "" {
+		res += "/" + p.subresource
+	}
+	if p.group != "" {
+		res += "." + p.group
+	}
+	return p.verb + " " + res
+}
+
+// requiredPermissions are the permissions kubedock needs to run containers.
+var requiredPermissions = []permission{
+	{verb: "create", resource: "pods"},
+	{verb: "get", resource: "pods"},
+	{verb: "list", resource: "pods"},
+	{verb: "watch", resource: "pods"},
+	{verb: "delete", resource: "pods"},
+	{verb: "get", resource: "pods", subresource: "log"},
+	{verb: "create", resource: "configmaps"},
+	{verb: "list", resource: "configmaps"},
+	{verb: "delete", resource: "configmaps"},
+}
+
+// featurePermissions are the additional permissions the optional features
+// of kubedock need.
+var featurePermissions = []struct {
+	feature string
+	perms   []permission
+}{
+	{feature: FeatureServices, perms: []permission{
+		{verb: "create", resource: "services"},
+		{verb: "list", resource: "services"},
+		{verb: "delete", resource: "services"},
+	}},
+	{feature: FeatureExec, perms: []permission{{verb: "create", resource: "pods", subresource: "exec"}}},
+	{feature: FeatureAttach, perms: []permission{{verb: "create", resource: "pods", subresource: "attach"}}},
+	{feature: FeaturePortForward, perms: []permission{{verb: "create", resource: "pods", subresource: "portforward"}}},
+	{feature: FeatureMetrics, perms: []permission{{verb: "list", group: "metrics.k8s.io", resource: "pods"}}},
+	{feature: FeatureEvents, perms: []permission{{verb: "list", resource: "events"}}},
+	{feature: FeatureDeployments, perms: []permission{
+		{verb: "create", group: "apps", resource: "deployments"},
+		{verb: "get", group: "apps", resource: "deployments"},
+		{verb: "update", group: "apps", resource: "deployments"},
+		{verb: "list", group: "apps", resource: "deployments"},
+		{verb: "delete", group: "apps", resource: "deployments"},
+	}},
+	{feature: FeaturePrewarm, perms: []permission{
+		{verb: "create", group: "apps", resource: "daemonsets"},
+		{verb: "get", group: "apps", resource: "daemonsets"},
+		{verb: "update", group: "apps", resource: "daemonsets"},
+		{verb: "list", group: "apps", resource: "daemonsets"},
+		{verb: "delete", group: "apps", resource: "daemonsets"},
+	}},
+	{feature: FeatureArchiveHelper, perms: []permission{
+		{verb: "create", resource: "persistentvolumeclaims"},
+		{verb: "list", resource: "persistentvolumeclaims"},
+		{verb: "delete", resource: "persistentvolumeclaims"},
+	}},
+}
+
+// FeatureError is returned when a feature is used that has been disabled,
+// because kubedock is missing the permissions it requires.
+type FeatureError struct {
+	Feature string
+	Missing []string
+}
+
+// Error will return a description of the disabled feature, including the
+// missing permissions.
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("%s is disabled, the service account of kubedock is missing permission to %s", e.Feature, strings.Join(e.Missing, ", "))
+}
+
+// HTTPStatus will return the http status that should be used to report
+// the disabled feature to api clients.
+func (e *FeatureError) HTTPStatus() int {
+	return http.StatusNotImplemented
+}
+
+// CheckFeature will return a FeatureError if the given feature is disabled
+// because of missing permissions, or nil if the feature is available.
+func (in *instance) CheckFeature(feature string) error {
+	if err, ok := in.disabled[feature]; ok {
+		return err
+	}
+	return nil
+}
+
+// probePermissions will check the permissions of the service account of
+// kubedock in its namespace, and disable the features that require any
+// permission that is missing. Disabling services is the same as running
+// with --disable-services. It returns an error if permissions are missing
+// that are required to run containers at all.
+func (in *instance) probePermissions() error {
+	missing, err := in.getMissingPermissions(requiredPermissions)
+	if err != nil {
+		return fmt.Errorf("error probing permissions: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the service account of kubedock is missing required permission to %s", strings.Join(missing, ", "))
+	}
+
+	in.disabled = map[string]*FeatureError{}
+	for _, fp := range featurePermissions {
+		missing, err := in.getMissingPermissions(fp.perms)
+		if err != nil {
+			return fmt.Errorf("error probing permissions: %w", err)
+		}
+		if len(missing) > 0 {
+			in.disabled[fp.feature] = &FeatureError{Feature: fp.feature, Missing: missing}
+			klog.Warningf("%s", in.disabled[fp.feature])
+		}
+	}
+	if _, ok := in.disabled[FeatureServices]; ok {
+		in.disableServices = true
+	}
+	return nil
+}
+
+// getMissingPermissions will return the permissions of the given list that
+// are not granted to kubedock.
+func (in *instance) getMissingPermissions(perms []permission) ([]string, error) {
+	missing := []string{}
+	for _, perm := range perms {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   in.namespace,
+					Verb:        perm.verb,
+					Group:       perm.group,
+					Resource:    perm.resource,
+					Subresource: perm.subresource,
+				},
+			},
+		}
+		res, err := in.cli.AuthorizationV1().SelfSubjectAccessReviews().Create(context.Background(), review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if !res.Status.Allowed {
+			missing = append(missing, perm.String())
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestProbePermissions(t *testing.T) {
+	tests := []struct {
+		denied   []string
+		err      bool
+		disabled []string
+	}{
+		{denied: []string{}, disabled: []string{}},
+		{denied: []string{"create pods"}, err: true},
+		{denied: []string{"get pods/log"}, err: true},
+		{denied: []string{"create pods/exec"}, disabled: []string{FeatureExec}},
+		{denied: []string{"delete services", "list pods.metrics.k8s.io", "update daemonsets.apps"}, disabled: []string{FeatureServices, FeatureMetrics, FeaturePrewarm}},
+	}
+	for i, tst := range tests {
+		cli := fake.NewSimpleClientset()
+		cli.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			attr := review.Spec.ResourceAttributes
+			perm := permission{verb: attr.Verb, group: attr.Group, resource: attr.Resource, subresource: attr.Subresource}.String()
+			review.Status.Allowed = true
+			for _, d := range tst.denied {
+				if d == perm {
+					review.Status.Allowed = false
+				}
+			}
+			return true, review, nil
+		})
+		kub := &instance{namespace: "default", cli: cli}
+		err := kub.probePermissions()
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %v, but got %v", i, tst.err, err)
+			continue
+		}
+		if tst.err {
+			continue
+		}
+		if len(kub.disabled) != len(tst.disabled) {
+			t.Errorf("failed test %d - expected %v disabled, but got %v", i, tst.disabled, kub.disabled)
+		}
+		for _, feature := range tst.disabled {
+			err := kub.CheckFeature(feature)
+			var ferr *FeatureError
+			if !errors.As(err, &ferr) || ferr.HTTPStatus() != http.StatusNotImplemented || !strings.Contains(err.Error(), "missing permission to ") {
+				t.Errorf("failed test %d - expected feature error for %s, but got %v", i, feature, err)
+			}
+		}
+		if svcs := kub.CheckFeature(FeatureServices) != nil; kub.disableServices != svcs {
+			t.Errorf("failed test %d - expected services disabled %t, but got %t", i, svcs, kub.disableServices)
+		}
+	}
+}
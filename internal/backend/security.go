@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+const (
+	// SecurityProfilePrivileged doesn't restrict or adjust the pods
+	SecurityProfilePrivileged = "privileged"
+	// SecurityProfileBaseline rejects containers that are not allowed by the
+	// baseline pod security standard
+	SecurityProfileBaseline = "baseline"
+	// SecurityProfileOpenShiftRestricted rejects containers that are not
+	// allowed by the restricted pod security standard (and the restricted
+	// scc of openshift), and adjusts the pods to comply with it
+	SecurityProfileOpenShiftRestricted = "openshift-restricted"
+)
+
+// baselineSysctls contains the sysctls that are allowed by the baseline
+// pod security standard.
+var baselineSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+	"net.ipv4.tcp_fin_timeout":            true,
+}
+
+// ValidateSecurityProfile will check if the given security profile is a
+// supported profile.
+func ValidateSecurityProfile(profile string) error {
+	switch profile {
+	case SecurityProfilePrivileged, SecurityProfileBaseline, SecurityProfileOpenShiftRestricted:
+		return nil
+	}
+	return fmt.Errorf("invalid security profile %s, supported profiles are %s, %s and %s", profile,
+		SecurityProfilePrivileged, SecurityProfileBaseline, SecurityProfileOpenShiftRestricted)
+}
+
+// checkSecurityProfile will check if the given container can be deployed
+// with the configured security profile, and returns an error that explains
+// which setting is not allowed if it can't.
+func (in *instance) checkSecurityProfile(tainr *types.Container) error {
+	if in.securityProfile == "" || in.securityProfile == SecurityProfilePrivileged {
+		return nil
+	}
+	errs := []string{}
+	if tainr.HostNetwork {
+		errs = append(errs, "host network mode is not allowed, use a bridge network instead")
+	}
+	for _, dev := range tainr.Devices {
+		if dev.Resource == "" {
+			errs = append(errs, fmt.Sprintf("device %s requires a hostPath volume, which is not allowed; map it onto a device plugin resource with --allowed-devices instead", dev.ContainerPath))
+		}
+	}
+	for name := range tainr.Sysctls {
+		if !baselineSysctls[name] {
+			errs = append(errs, fmt.Sprintf("sysctl %s is not allowed, remove it from the container", name))
+		}
+	}
+	if in.securityProfile == SecurityProfileOpenShiftRestricted {
+		if user := tainr.Labels[types.LabelRunasUser]; user == "0" || strings.Split(user, ":")[0] == "root" {
+			errs = append(errs, fmt.Sprintf("running as root is not allowed, run the container as a non-root user or remove the %s label", types.LabelRunasUser))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("container %s is not allowed by the %s security profile: %s (or start kubedock with a less strict --security-profile)", tainr.ShortID, in.securityProfile, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// getRunAsRootError will return an error that explains that the given
+// container is not allowed by the openshift-restricted security profile if
+// kubernetes refuses to create it, with given waiting state, because its
+// image runs as root. This is only known once the pod is scheduled, as the
+// image user is checked by the kubelet (and on OpenShift, a non-root uid is
+// assigned by the scc instead).
+func (in *instance) getRunAsRootError(tainr *types.Container, wait *corev1.ContainerStateWaiting) error {
+	if in.securityProfile != SecurityProfileOpenShiftRestricted || wait.Reason != "CreateContainerConfigError" || !strings.Contains(wait.Message, "runAsNonRoot") {
+		return nil
+	}
+	return fmt.Errorf("container %s is not allowed by the %s security profile: %s; run the container as a non-root numeric user, e.g. with the %s label (or start kubedock with a less strict --security-profile)", tainr.ShortID, in.securityProfile, wait.Message, types.LabelRunasUser)
+}
+
+// applySecurityProfile will adjust the given pod to comply with the
+// configured security profile. With the openshift-restricted profile, all
+// containers run as non-root, without privilege escalation, without any
+// capabilities and with the runtime default seccomp profile. Settings of
+// the pod template are kept.
+func (in *instance) applySecurityProfile(pod *corev1.Pod) {
+	if in.securityProfile != SecurityProfileOpenShiftRestricted {
+		return
+	}
+	nonroot := true
+	seccomp := &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	if pod.Spec.SecurityContext == nil {
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if pod.Spec.SecurityContext.RunAsNonRoot == nil {
+		pod.Spec.SecurityContext.RunAsNonRoot = &nonroot
+	}
+	if pod.Spec.SecurityContext.SeccompProfile == nil {
+		pod.Spec.SecurityContext.SeccompProfile = seccomp
+	}
+	for i := range pod.Spec.InitContainers {
+		pod.Spec.InitContainers[i].SecurityContext = getRestrictedSecurityContext(pod.Spec.InitContainers[i].SecurityContext)
+	}
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].SecurityContext = getRestrictedSecurityContext(pod.Spec.Containers[i].SecurityContext)
+	}
+}
+
+// getRestrictedSecurityContext will return given container security context,
+// adjusted to comply with the restricted pod security standard.
+func getRestrictedSecurityContext(sc *corev1.SecurityContext) *corev1.SecurityContext {
+	if sc == nil {
+		sc = &corev1.SecurityContext{}
+	}
+	escalation := false
+	sc.AllowPrivilegeEscalation = &escalation
+	sc.Privileged = nil
+	if sc.Capabilities == nil {
+		sc.Capabilities = &corev1.Capabilities{}
+	}
+	sc.Capabilities.Drop = []corev1.Capability{"ALL"}
+	sc.Capabilities.Add = nil
+	return sc
+}
@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestValidateSecurityProfile(t *testing.T) {
+	tests := []struct {
+		in  string
+		err bool
+	}{
+		{in: "privileged"},
+		{in: "baseline"},
+		{in: "openshift-restricted"},
+		{in: "restricted", err: true},
+		{in: "", err: true},
+	}
+	for i, tst := range tests {
+		if err := ValidateSecurityProfile(tst.in); (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %v, but got %v", i, tst.err, err)
+		}
+	}
+}
+
+func TestCheckSecurityProfile(t *testing.T) {
+	tests := []struct {
+		profile string
+		in      *types.Container
+		err     bool
+	}{
+		{profile: "", in: &types.Container{HostNetwork: true}},
+		{profile: "privileged", in: &types.Container{HostNetwork: true}},
+		{profile: "baseline", in: &types.Container{}},
+		{profile: "baseline", in: &types.Container{HostNetwork: true}, err: true},
+		{profile: "baseline", in: &types.Container{Devices: []types.Device{{HostPath: "/dev/kvm", ContainerPath: "/dev/kvm"}}}, err: true},
+		{profile: "baseline", in: &types.Container{Devices: []types.Device{{ContainerPath: "/dev/kvm", Resource: "devices.kubevirt.io/kvm"}}}},
+		{profile: "baseline", in: &types.Container{Sysctls: map[string]string{"net.ipv4.tcp_syncookies": "1"}}},
+		{profile: "baseline", in: &types.Container{Sysctls: map[string]string{"kernel.msgmax": "65536"}}, err: true},
+		{profile: "baseline", in: &types.Container{Labels: map[string]string{types.LabelRunasUser: "0"}}},
+		{profile: "openshift-restricted", in: &types.Container{Labels: map[string]string{types.LabelRunasUser: "0"}}, err: true},
+		{profile: "openshift-restricted", in: &types.Container{Labels: map[string]string{types.LabelRunasUser: "root:root"}}, err: true},
+		{profile: "openshift-restricted", in: &types.Container{Labels: map[string]string{types.LabelRunasUser: "1000"}}},
+		{profile: "openshift-restricted", in: &types.Container{HostNetwork: true}, err: true},
+	}
+	for i, tst := range tests {
+		kub := &instance{securityProfile: tst.profile}
+		if err := kub.checkSecurityProfile(tst.in); (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %v, but got %v", i, tst.err, err)
+		}
+	}
+}
+
+func TestApplySecurityProfile(t *testing.T) {
+	privileged := true
+	root := false
+	tests := []struct {
+		profile  string
+		in       *corev1.Pod
+		adjusted bool
+	}{
+		{
+			profile: "baseline",
+			in:      &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{}}}},
+		},
+		{
+			profile:  "openshift-restricted",
+			in:       &corev1.Pod{Spec: corev1.PodSpec{InitContainers: []corev1.Container{{}}, Containers: []corev1.Container{{}, {}}}},
+			adjusted: true,
+		},
+		{
+			profile: "openshift-restricted",
+			in: &corev1.Pod{Spec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &root},
+				Containers: []corev1.Container{{SecurityContext: &corev1.SecurityContext{
+					Privileged:   &privileged,
+					Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN"}},
+				}}},
+			}},
+			adjusted: true,
+		},
+	}
+	for i, tst := range tests {
+		kub := &instance{securityProfile: tst.profile}
+		kub.applySecurityProfile(tst.in)
+		if !tst.adjusted {
+			if tst.in.Spec.SecurityContext != nil {
+				t.Errorf("failed test %d - expected no pod security context, but got %v", i, tst.in.Spec.SecurityContext)
+			}
+			continue
+		}
+		psc := tst.in.Spec.SecurityContext
+		if psc == nil || psc.RunAsNonRoot == nil || psc.SeccompProfile == nil || psc.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+			t.Errorf("failed test %d - expected restricted pod security context, but got %v", i, psc)
+		}
+		for _, c := range append(tst.in.Spec.InitContainers, tst.in.Spec.Containers...) {
+			sc := c.SecurityContext
+			if sc == nil || sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation || sc.Privileged != nil ||
+				sc.Capabilities == nil || len(sc.Capabilities.Add) != 0 || len(sc.Capabilities.Drop) != 1 || sc.Capabilities.Drop[0] != "ALL" {
+				t.Errorf("failed test %d - expected restricted container security context, but got %v", i, sc)
+			}
+		}
+	}
+}
+
+func TestGetRunAsRootError(t *testing.T) {
+	tests := []struct {
+		profile string
+		reason  string
+		msg     string
+		err     bool
+	}{
+		{profile: "openshift-restricted", reason: "CreateContainerConfigError", msg: "container has runAsNonRoot and image will run as root (pod: \"tb303\", container: main)", err: true},
+		{profile: "openshift-restricted", reason: "CreateContainerConfigError", msg: "container has runAsNonRoot and image has non-numeric user (postgres), cannot verify user is non-root", err: true},
+		{profile: "openshift-restricted", reason: "CreateContainerConfigError", msg: "configmap \"tb303\" not found"},
+		{profile: "openshift-restricted", reason: "ContainerCreating"},
+		{profile: "baseline", reason: "CreateContainerConfigError", msg: "container has runAsNonRoot and image will run as root"},
+	}
+	for i, tst := range tests {
+		kub := &instance{securityProfile: tst.profile}
+		err := kub.getRunAsRootError(&types.Container{ShortID: "tb303"}, &corev1.ContainerStateWaiting{Reason: tst.reason, Message: tst.msg})
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %v, but got %v", i, tst.err, err)
+		}
+	}
+}
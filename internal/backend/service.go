@@ -0,0 +1,150 @@
+package backend
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// DeployService will create the deployment of given (swarm) service, or
+// update the existing deployment if it was already created before.
+func (in *instance) DeployService(svc *types.Service) error {
+	if err := in.CheckFeature(FeatureDeployments); err != nil {
+		return err
+	}
+	deps := in.cli.AppsV1().Deployments(in.namespace)
+	ndep, err := in.getServiceDeployment(svc)
+	if err != nil {
+		return err
+	}
+
+	dep, err := deps.Get(context.Background(), ndep.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		klog.Infof("creating deployment %s with %d replicas", ndep.Name, svc.Replicas)
+		_, err = deps.Create(context.Background(), ndep, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	klog.Infof("updating deployment %s to %d replicas", ndep.Name, svc.Replicas)
+	dep.ObjectMeta.Labels = ndep.ObjectMeta.Labels
+	dep.Spec.Replicas = ndep.Spec.Replicas
+	dep.Spec.Template = ndep.Spec.Template
+	_, err = deps.Update(context.Background(), dep, metav1.UpdateOptions{})
+	return err
+}
+
+// GetServiceReplicas will return the number of ready replicas of the
+// deployment of given service.
+func (in *instance) GetServiceReplicas(svc *types.Service) (int, error) {
+	if err := in.CheckFeature(FeatureDeployments); err != nil {
+		return 0, err
+	}
+	dep, err := in.cli.AppsV1().Deployments(in.namespace).Get(context.Background(), svc.GetDeploymentName(), metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return int(dep.Status.ReadyReplicas), nil
+}
+
+// DeleteService will delete the deployment of given service.
+func (in *instance) DeleteService(svc *types.Service) error {
+	if err := in.CheckFeature(FeatureDeployments); err != nil {
+		return err
+	}
+	err := in.cli.AppsV1().Deployments(in.namespace).Delete(context.Background(), svc.GetDeploymentName(), metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// getServiceDeployment will return the deployment for given service. The
+// pods of the deployment are not labelled with kubedock=true, so they are
+// not considered to be (lingering) containers when cleaning up.
+func (in *instance) getServiceDeployment(svc *types.Service) (*appsv1.Deployment, error) {
+	labels := map[string]string{}
+	for k, v := range svc.Labels {
+		kk := in.toKubernetesKey(k)
+		kv := in.toKubernetesValue(v)
+		if (kk == "" && k != "") || (kv == "" && v != "") {
+			klog.V(3).Infof("not adding `%s` with value `%s` as a label: incompatible", k, v)
+			continue
+		}
+		labels[kk] = kv
+	}
+	labels["kubedock.id"] = config.InstanceID
+	labels["kubedock.serviceid"] = svc.ShortID
+
+	annotations := map[string]string{}
+	for k, v := range config.DefaultAnnotations {
+		annotations[k] = v
+	}
+	for k, v := range svc.Labels {
+		annotations[k] = v
+	}
+	annotations["kubedock.servicename"] = svc.Name
+
+	spec := in.podTemplate.DeepCopy().Spec
+	spec.RestartPolicy = corev1.RestartPolicyAlways
+	for _, ps := range in.imagePullSecrets {
+		spec.ImagePullSecrets = append(spec.ImagePullSecrets, corev1.LocalObjectReference{Name: ps})
+	}
+
+	img, err := in.getImage(svc.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	container := in.containerTemplate
+	container.Name = "main"
+	container.Image = img
+	container.Command = svc.Entrypoint
+	container.Args = svc.Cmd
+	container.Env = svc.GetEnvVar()
+	spec.Containers = []corev1.Container{container}
+
+	deplabels := map[string]string{}
+	for k, v := range config.DefaultLabels {
+		deplabels[k] = v
+	}
+	for k, v := range labels {
+		deplabels[k] = v
+	}
+	for k, v := range config.SystemLabels {
+		deplabels[k] = v
+	}
+
+	replicas := int32(svc.Replicas)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        svc.GetDeploymentName(),
+			Namespace:   in.namespace,
+			Labels:      deplabels,
+			Annotations: annotations,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"kubedock.serviceid": svc.ShortID,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: annotations,
+				},
+				Spec: spec,
+			},
+		},
+	}, nil
+}
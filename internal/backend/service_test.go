@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestDeployService(t *testing.T) {
+	kub := &instance{
+		namespace:   "default",
+		cli:         fake.NewSimpleClientset(),
+		podTemplate: &corev1.Pod{},
+	}
+	svc := &types.Service{
+		ShortID:  "abcdef123456",
+		Name:     "web",
+		Image:    "nginx:1.25",
+		Env:      []string{"A=1"},
+		Labels:   map[string]string{"app": "web"},
+		Replicas: 2,
+	}
+
+	tests := []struct {
+		replicas int
+		image    string
+	}{
+		{replicas: 2, image: "nginx:1.25"},
+		{replicas: 5, image: "nginx:1.26"},
+		{replicas: 0, image: "nginx:1.26"},
+	}
+
+	for i, tst := range tests {
+		svc.Replicas = tst.replicas
+		svc.Image = tst.image
+		if err := kub.DeployService(svc); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		deps, _ := kub.cli.AppsV1().Deployments("default").List(context.Background(), metav1.ListOptions{LabelSelector: "kubedock=true"})
+		if len(deps.Items) != 1 {
+			t.Errorf("failed test %d - expected 1 deployment, but got %d", i, len(deps.Items))
+			continue
+		}
+		dep := deps.Items[0]
+		if int(*dep.Spec.Replicas) != tst.replicas {
+			t.Errorf("failed test %d - expected %d replicas, but got %d", i, tst.replicas, *dep.Spec.Replicas)
+		}
+		if img := dep.Spec.Template.Spec.Containers[0].Image; img != tst.image {
+			t.Errorf("failed test %d - expected image %s, but got %s", i, tst.image, img)
+		}
+		if _, ok := dep.Spec.Template.ObjectMeta.Labels["kubedock"]; ok {
+			t.Errorf("failed test %d - expected pods not to be labelled with kubedock=true", i)
+		}
+		if dep.Spec.Template.ObjectMeta.Labels["app"] != "web" {
+			t.Errorf("failed test %d - expected pods to be labelled with app=web", i)
+		}
+	}
+
+	if err := kub.DeleteService(svc); err != nil {
+		t.Errorf("unexpected error deleting service: %s", err)
+	}
+	if err := kub.DeleteService(svc); err != nil {
+		t.Errorf("unexpected error deleting removed service: %s", err)
+	}
+}
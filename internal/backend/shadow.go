@@ -0,0 +1,175 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+)
+
+// dnsShadowedAnnotation is the annotation on services with the services of
+// other containers that use the same name, but are shadowed by it. These
+// are restored once the service is released by its container.
+const dnsShadowedAnnotation = "kubedock.dns/shadowed"
+
+// shadowedService is a service that is shadowed by another service with
+// the same name.
+type shadowedService struct {
+	Labels      map[string]string    `json:"labels"`
+	Annotations map[string]string    `json:"annotations"`
+	Selector    map[string]string    `json:"selector"`
+	Ports       []corev1.ServicePort `json:"ports"`
+}
+
+// toShadowed will return given service as a shadowed service.
+func toShadowed(svc *corev1.Service) shadowedService {
+	annotations := map[string]string{}
+	for k, v := range svc.Annotations {
+		if k != dnsShadowedAnnotation {
+			annotations[k] = v
+		}
+	}
+	return shadowedService{
+		Labels:      svc.Labels,
+		Annotations: annotations,
+		Selector:    svc.Spec.Selector,
+		Ports:       svc.Spec.Ports,
+	}
+}
+
+// apply will make given service match the shadowed service.
+func (sh shadowedService) apply(svc *corev1.Service) {
+	annotations := map[string]string{}
+	for k, v := range sh.Annotations {
+		annotations[k] = v
+	}
+	svc.Labels = sh.Labels
+	svc.Annotations = annotations
+	svc.Spec.Selector = sh.Selector
+	svc.Spec.Ports = sh.Ports
+}
+
+// getShadowed will return the services that are shadowed by given service.
+func getShadowed(svc *corev1.Service) []shadowedService {
+	res := []shadowedService{}
+	dat, ok := svc.Annotations[dnsShadowedAnnotation]
+	if !ok {
+		return res
+	}
+	if err := json.Unmarshal([]byte(dat), &res); err != nil {
+		klog.Warningf("ignoring shadowed services of service %s: %s", svc.Name, err)
+		return []shadowedService{}
+	}
+	return res
+}
+
+// setShadowed will set the services that are shadowed by given service.
+func setShadowed(svc *corev1.Service, shadowed []shadowedService) {
+	if len(shadowed) == 0 {
+		delete(svc.Annotations, dnsShadowedAnnotation)
+		return
+	}
+	dat, err := json.Marshal(shadowed)
+	if err != nil {
+		klog.Errorf("error storing shadowed services of service %s: %s", svc.Name, err)
+		return
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	svc.Annotations[dnsShadowedAnnotation] = string(dat)
+}
+
+// shadowService will make the given existing service shadow the given
+// service, or the other way around if the given service takes precedence in
+// dns resolution (a container name over a network alias). The shadowed
+// service is restored once the existing service is released.
+func (in *instance) shadowService(cur, svc *corev1.Service) error {
+	id := svc.Labels["kubedock.containerid"]
+	if id != "" && cur.Labels["kubedock.containerid"] == id {
+		return nil
+	}
+	shadowed := []shadowedService{}
+	for _, sh := range getShadowed(cur) {
+		if id == "" || sh.Labels["kubedock.containerid"] != id {
+			shadowed = append(shadowed, sh)
+		}
+	}
+	if svc.Annotations[dnsRankAnnotation] >= cur.Annotations[dnsRankAnnotation] {
+		klog.Infof("service %s is in use by container %s, shadowing container %s", svc.Name, cur.Labels["kubedock.containerid"], id)
+		shadowed = append(shadowed, toShadowed(svc))
+	} else {
+		klog.Infof("service %s is in use by container %s, shadowing it by container %s, as container name takes precedence over network alias", svc.Name, cur.Labels["kubedock.containerid"], id)
+		shadowed = append([]shadowedService{toShadowed(cur)}, shadowed...)
+		toShadowed(svc).apply(cur)
+	}
+	setShadowed(cur, shadowed)
+	_, err := in.cli.CoreV1().Services(in.namespace).Update(context.Background(), cur, metav1.UpdateOptions{})
+	return err
+}
+
+// releaseServices will release the services that match the given label
+// selector, except the services with the given names. A released service
+// that shadows the services of other containers is replaced by the shadowed
+// service that takes precedence, and is deleted otherwise. Shadowed services
+// that match the selector are removed from the services that shadow them.
+func (in *instance) releaseServices(selector string, keep map[string]bool) error {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return err
+	}
+	svcs := []corev1.Service{}
+	seen := map[string]bool{}
+	for _, ls := range []string{selector, "kubedock=true"} {
+		res, err := in.cli.CoreV1().Services(in.namespace).List(context.Background(), metav1.ListOptions{
+			LabelSelector: ls,
+		})
+		if err != nil {
+			return err
+		}
+		for _, svc := range res.Items {
+			if !seen[svc.Name] {
+				seen[svc.Name] = true
+				svcs = append(svcs, svc)
+			}
+		}
+	}
+	for _, svc := range svcs {
+		if keep[svc.Name] {
+			continue
+		}
+		shadowed := getShadowed(&svc)
+		remaining := []shadowedService{}
+		for _, sh := range shadowed {
+			if !sel.Matches(labels.Set(sh.Labels)) {
+				remaining = append(remaining, sh)
+			}
+		}
+		if sel.Matches(labels.Set(svc.Labels)) {
+			if len(remaining) == 0 {
+				if err := in.cli.CoreV1().Services(svc.Namespace).Delete(context.Background(), svc.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+					return err
+				}
+				continue
+			}
+			sort.SliceStable(remaining, func(i, j int) bool {
+				return remaining[i].Annotations[dnsRankAnnotation] < remaining[j].Annotations[dnsRankAnnotation]
+			})
+			klog.Infof("restoring service %s of container %s", svc.Name, remaining[0].Labels["kubedock.containerid"])
+			remaining[0].apply(&svc)
+			remaining = remaining[1:]
+		} else if len(remaining) == len(shadowed) {
+			continue
+		}
+		setShadowed(&svc, remaining)
+		if _, err := in.cli.CoreV1().Services(svc.Namespace).Update(context.Background(), &svc, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// ContainerStats contains the resource usage of a container, as reported
+// by the metrics api.
+type ContainerStats struct {
+	// CPUNanoCores is the cpu usage in billionths of a core
+	CPUNanoCores int64
+	// MemoryUsage is the working set of the container in bytes
+	MemoryUsage int64
+	// MemoryLimit is the memory limit of the container in bytes, or 0 if
+	// the container has no memory limit
+	MemoryLimit int64
+	// Timestamp is the time at which the usage was measured
+	Timestamp time.Time
+}
+
+// podMetricsList is the subset of the metrics.k8s.io PodMetricsList that is
+// used to determine the container stats.
+type podMetricsList struct {
+	Items []struct {
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Timestamp  metav1.Time       `json:"timestamp"`
+		Containers []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// GetContainerStats will return the resource usage of the given containers,
+// keyed by container id, using a single query on the metrics api. Containers
+// for which no metrics are available (yet) are not included.
+func (in *instance) GetContainerStats(tainrs []*types.Container) (map[string]*ContainerStats, error) {
+	if err := in.CheckFeature(FeatureMetrics); err != nil {
+		return nil, err
+	}
+	if len(tainrs) == 0 {
+		return map[string]*ContainerStats{}, nil
+	}
+	ids := map[string]bool{}
+	for _, tainr := range tainrs {
+		ids[tainr.GetPodShortID()] = true
+	}
+	sel := []string{}
+	for id := range ids {
+		sel = append(sel, id)
+	}
+	sort.Strings(sel)
+
+	dat, err := in.cli.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", in.namespace, "pods").
+		Param("labelSelector", fmt.Sprintf("kubedock.containerid in (%s)", strings.Join(sel, ","))).
+		DoRaw(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving metrics (is metrics-server available?): %w", err)
+	}
+	return in.parsePodMetrics(dat, tainrs)
+}
+
+// parsePodMetrics will return the stats of given containers as found in
+// the given metrics.k8s.io PodMetricsList.
+func (in *instance) parsePodMetrics(dat []byte, tainrs []*types.Container) (map[string]*ContainerStats, error) {
+	list := podMetricsList{}
+	if err := json.Unmarshal(dat, &list); err != nil {
+		return nil, err
+	}
+	res := map[string]*ContainerStats{}
+	for _, tainr := range tainrs {
+		for _, item := range list.Items {
+			if item.Metadata.Labels["kubedock.containerid"] != tainr.GetPodShortID() {
+				continue
+			}
+			for _, cm := range item.Containers {
+				if cm.Name != tainr.GetContainerName() {
+					continue
+				}
+				st := &ContainerStats{
+					CPUNanoCores: cm.Usage.Cpu().ScaledValue(-9),
+					MemoryUsage:  cm.Usage.Memory().Value(),
+					Timestamp:    item.Timestamp.Time,
+				}
+				if req, err := tainr.GetResourceRequirements(*in.containerTemplate.Resources.DeepCopy()); err == nil {
+					st.MemoryLimit = req.Limits.Memory().Value()
+				}
+				res[tainr.ID] = st
+			}
+		}
+	}
+	return res, nil
+}
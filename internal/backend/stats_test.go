@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestParsePodMetrics(t *testing.T) {
+	dat := []byte(`{"items":[
+		{"metadata":{"name":"kubedock-a","labels":{"kubedock.containerid":"tb303"}},"timestamp":"2024-01-01T00:00:00Z",
+		 "containers":[{"name":"main","usage":{"cpu":"250m","memory":"64Mi"}},{"name":"linked-sh101","usage":{"cpu":"12500n","memory":"1Ki"}}]},
+		{"metadata":{"name":"kubedock-b","labels":{"kubedock.containerid":"mc909"}},"timestamp":"2024-01-01T00:00:00Z",
+		 "containers":[{"name":"main","usage":{"cpu":"1","memory":"1Gi"}}]}
+	]}`)
+	tests := []struct {
+		in    *types.Container
+		found bool
+		cpu   int64
+		mem   int64
+		limit int64
+	}{
+		{
+			in:    &types.Container{ID: "tb303", ShortID: "tb303", Labels: map[string]string{types.LabelRequestMemory: "64Mi,128Mi"}},
+			found: true, cpu: 250000000, mem: 64 * 1024 * 1024, limit: 128 * 1024 * 1024,
+		},
+		{
+			in:    &types.Container{ID: "sh101", ShortID: "sh101", NetworkOwner: "tb303"},
+			found: true, cpu: 12500, mem: 1024,
+		},
+		{
+			in:    &types.Container{ID: "mc909", ShortID: "mc909"},
+			found: true, cpu: 1000000000, mem: 1024 * 1024 * 1024,
+		},
+		{
+			in: &types.Container{ID: "tr909", ShortID: "tr909"},
+		},
+	}
+
+	kub := &instance{}
+	for i, tst := range tests {
+		res, err := kub.parsePodMetrics(dat, []*types.Container{tst.in})
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error: %s", i, err)
+		}
+		st, ok := res[tst.in.ID]
+		if ok != tst.found {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.found, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if st.CPUNanoCores != tst.cpu || st.MemoryUsage != tst.mem || st.MemoryLimit != tst.limit {
+			t.Errorf("failed test %d - expected %d/%d/%d, but got %d/%d/%d", i, tst.cpu, tst.mem, tst.limit, st.CPUNanoCores, st.MemoryUsage, st.MemoryLimit)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package backend
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"net"
 	"os"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/joyrex2001/kubedock/internal/model/types"
 )
@@ -21,10 +26,26 @@ func (in *instance) toKubernetesValue(v string) string {
 	return in.replaceValueWithPatterns(v, "", `^[^A-Za-z0-9]+`, `[^A-Za-z0-9-\.]`, `-*$`)
 }
 
-// toKubernetesNamewill create a nice kubernetes string that can be used as a
-// value out of given random string.
+// toKubernetesName will create a valid kubernetes name (dns label) out of
+// given random string. Characters that are not allowed (e.g. colons, dots
+// and slashes) are replaced with dashes. If the name had to be altered or
+// truncated, a hash of the original string is appended, so different
+// strings will not collide after conversion.
 func (in *instance) toKubernetesName(v string) string {
-	return in.replaceValueWithPatterns(v, "undef", `^[^A-Za-z0-9]+`, `[^A-Za-z0-9-]`, `-*$`)
+	name := strings.ToLower(v)
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-")
+	if name == v && len(name) <= 63 && name != "" {
+		return name
+	}
+	if len(name) > 54 {
+		name = strings.TrimRight(name[:54], "-")
+	}
+	if name == "" {
+		name = "undef"
+	}
+	sum := sha256.Sum256([]byte(v))
+	return name + "-" + hex.EncodeToString(sum[:])[:8]
 }
 
 func (in *instance) replaceValueWithPatterns(v, def string, pt ...string) string {
@@ -82,3 +103,14 @@ OUTER:
 	}
 	return nil
 }
+
+// sleep will wait for the given duration, or until the given context is
+// done, in which case the error of the context is returned.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -11,57 +11,48 @@ func TestToKubernetesValue(t *testing.T) {
 		in    string
 		key   string
 		value string
-		name  string
 	}{
-		{in: "__-abc", key: "abc", value: "abc", name: "abc"},
-		{in: "/a/b/c", key: "a/b/c", value: "abc", name: "abc"},
+		{in: "__-abc", key: "abc", value: "abc"},
+		{in: "/a/b/c", key: "a/b/c", value: "abc"},
 		{
 			in:    "StrategicMars",
 			key:   "StrategicMars",
 			value: "StrategicMars",
-			name:  "StrategicMars",
 		},
 		{
 			in:    "2107007e-b7c8-df23-18fb-6a6f79726578",
 			key:   "2107007e-b7c8-df23-18fb-6a6f79726578",
 			value: "2107007e-b7c8-df23-18fb-6a6f79726578",
-			name:  "2107007e-b7c8-df23-18fb-6a6f79726578",
 		},
 		{
 			in:    "0123456789012345678901234567890123456789012345678901234567890123456789",
 			key:   "012345678901234567890123456789012345678901234567890123456789012",
 			value: "012345678901234567890123456789012345678901234567890123456789012",
-			name:  "012345678901234567890123456789012345678901234567890123456789012",
 		},
 		{
 			in:    "StrategicMars-",
 			key:   "StrategicMars",
 			value: "StrategicMars",
-			name:  "StrategicMars",
 		},
 		{
 			in:    "StrategicMars/-",
 			key:   "StrategicMars",
 			value: "StrategicMars",
-			name:  "StrategicMars",
 		},
 		{
 			in:    "2107007e-b7c8-df23-18fb-6a6f79726578",
 			key:   "2107007e-b7c8-df23-18fb-6a6f79726578",
 			value: "2107007e-b7c8-df23-18fb-6a6f79726578",
-			name:  "2107007e-b7c8-df23-18fb-6a6f79726578",
 		},
 		{
 			in:    "app.kubernetes.io/name",
 			key:   "app.kubernetes.io/name",
 			value: "app.kubernetes.ioname",
-			name:  "appkubernetesioname",
 		},
 		{
 			in:    "",
 			key:   "",
 			value: "",
-			name:  "undef",
 		},
 	}
 
@@ -75,9 +66,36 @@ func TestToKubernetesValue(t *testing.T) {
 		if value != tst.value {
 			t.Errorf("failed test %d - expected value %s, but got %s", i, tst.value, value)
 		}
+	}
+}
+
+func TestToKubernetesName(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "data", out: "data"},
+		{in: "2107007e-b7c8-df23-18fb-6a6f79726578", out: "2107007e-b7c8-df23-18fb-6a6f79726578"},
+		{in: "/data", out: "data-bd47413b"},
+		{in: "/Data", out: "data-d33637c1"},
+		{in: "/a/b_c", out: "a-b-c-df04c200"},
+		{in: "/a/b.c", out: "a-b-c-a603d805"},
+		{in: "app:v1", out: "app-v1-41b008cb"},
+		{in: "", out: "undef-e3b0c442"},
+		{
+			in:  "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
+			out: "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx-c71bd109",
+		},
+	}
+
+	for i, tst := range tests {
+		kub := &instance{}
 		name := kub.toKubernetesName(tst.in)
-		if name != tst.name {
-			t.Errorf("failed test %d - expected name %s, but got %s", i, tst.name, name)
+		if name != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, name)
+		}
+		if len(name) > 63 {
+			t.Errorf("failed test %d - name %s exceeds 63 characters", i, name)
 		}
 	}
 }
@@ -17,8 +17,14 @@ const (
 	DockerMinAPIVersion = "1.25"
 	// DockerAPIVersion is the api version as advertised when calling /version
 	DockerAPIVersion = "1.25"
+	// DockerPlatform is the platform name as advertised when calling /version
+	DockerPlatform = "Docker Engine - kubedock"
 	// LibpodAPIVersion is the api version as advertised in libpod rest calls
 	LibpodAPIVersion = "4.2.0"
+	// LibpodMinAPIVersion is the minimum api version as advertised when calling /libpod/version
+	LibpodMinAPIVersion = "4.0.0"
+	// LibpodPlatform is the platform name as advertised when calling /libpod/version
+	LibpodPlatform = "kubedock"
 )
 
 var (
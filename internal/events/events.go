@@ -14,12 +14,25 @@ type Events interface {
 	Subscribe() (<-chan Message, string)
 	Unsubscribe(string)
 	Publish(string, string, string)
+	PublishWithAttributes(string, string, string, map[string]string)
 }
 
+// DefaultQueueSize is the default number of events that are queued for a
+// subscriber before the oldest events are dropped.
+const DefaultQueueSize = 64
+
 // instance is the internal representation of the Events object.
 type instance struct {
 	mu        sync.Mutex
-	observers map[string]chan Message
+	observers map[string]*subscriber
+	queueSize int
+}
+
+// subscriber contains the queue of a subscriber and the number of events
+// that were dropped since the subscriber last kept up.
+type subscriber struct {
+	out     chan Message
+	dropped int
 }
 
 var singleton *instance
@@ -28,30 +41,73 @@ var once sync.Once
 // New will create return the singleton Events instance.
 func New() Events {
 	once.Do(func() {
-		singleton = &instance{}
-		singleton.observers = map[string]chan Message{}
+		singleton = &instance{queueSize: DefaultQueueSize}
+		singleton.observers = map[string]*subscriber{}
 	})
 	return singleton
 }
 
 // Publish will publish an event for given resource id and type for given action.
 func (e *instance) Publish(id, typ, action string) {
-	msg := Message{ID: id, Type: typ, Action: action}
+	e.PublishWithAttributes(id, typ, action, map[string]string{})
+}
+
+// PublishWithAttributes will publish an event for given resource id and type
+// for given action, with given attributes of the resource (e.g. name).
+func (e *instance) PublishWithAttributes(id, typ, action string, attrs map[string]string) {
+	msg := Message{ID: id, Type: typ, Action: action, Attributes: attrs}
 	msg.Time = time.Now().Unix()
 	msg.TimeNano = time.Now().UnixNano()
-	for _, ob := range e.observers {
-		ob <- msg
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id, sub := range e.observers {
+		select {
+		case sub.out <- msg:
+			sub.dropped = 0
+			continue
+		default:
+		}
+		// the queue is full, drop the oldest event to make room; if the
+		// subscriber doesn't consume at all, it will be disconnected
+		sub.dropped++
+		if sub.dropped > e.queueSize {
+			klog.Warningf("disconnecting events subscriber %s, dropped %d events", id, sub.dropped-1)
+			close(sub.out)
+			delete(e.observers, id)
+			continue
+		}
+		klog.V(3).Infof("events subscriber %s is not keeping up, dropped oldest event", id)
+		select {
+		case <-sub.out:
+		default:
+		}
+		sub.out <- msg
 	}
 }
 
+// SetQueueSize will set the number of events that are queued for new
+// subscribers. When the queue of a subscriber is full, the oldest event is
+// dropped, and if the subscriber keeps falling behind for more than the size
+// of the queue, the subscriber is disconnected by closing its channel.
+func SetQueueSize(size int) {
+	e := New().(*instance)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if size < 1 {
+		size = 1
+	}
+	e.queueSize = size
+}
+
 // Subscribe will subscribe to the events and will return a channel and an
-// unique identifier than can be used to unsubscribe when done.
+// unique identifier than can be used to unsubscribe when done. The channel
+// is closed when the subscriber is disconnected because it didn't keep up.
 func (e *instance) Subscribe() (<-chan Message, string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	out := make(chan Message, 1)
+	out := make(chan Message, e.queueSize)
 	id := stringid.GenerateRandomID()
-	e.observers[id] = out
+	e.observers[id] = &subscriber{out: out}
 	klog.V(5).Infof("subscribing %s to events", id)
 	return out, id
 }
@@ -70,6 +126,13 @@ func (m *Message) Match(typ string, key string, val string) (bool, error) {
 	if typ == Type {
 		return m.Type == key, nil
 	}
+	if typ == "label" {
+		v, ok := m.Attributes[key]
+		if !ok {
+			return false, nil
+		}
+		return val == "" || v == val, nil
+	}
 	if m.Type == typ {
 		return m.ID == key, nil
 	}
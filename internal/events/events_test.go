@@ -52,6 +52,26 @@ func TestMatch(t *testing.T) {
 			msg:    Message{ID: "5678-1234", Type: "container", Action: "create"},
 			match:  false,
 		},
+		{
+			filter: `{"label":{"com.docker.compose.project=demo":true}}`,
+			msg:    Message{ID: "1234-5678", Type: "container", Action: "create", Attributes: map[string]string{"com.docker.compose.project": "demo"}},
+			match:  true,
+		},
+		{
+			filter: `{"label":{"com.docker.compose.project=demo":true}}`,
+			msg:    Message{ID: "1234-5678", Type: "container", Action: "create", Attributes: map[string]string{"com.docker.compose.project": "other"}},
+			match:  false,
+		},
+		{
+			filter: `{"label":{"com.docker.compose.project":true}}`,
+			msg:    Message{ID: "1234-5678", Type: "container", Action: "create", Attributes: map[string]string{"com.docker.compose.project": "demo"}},
+			match:  true,
+		},
+		{
+			filter: `{"label":{"com.docker.compose.project":true}}`,
+			msg:    Message{ID: "1234-5678", Type: "container", Action: "create"},
+			match:  false,
+		},
 	}
 	for i, tst := range tests {
 		filtr, _ := filter.New(tst.filter)
@@ -60,3 +80,29 @@ func TestMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestQueue(t *testing.T) {
+	SetQueueSize(2)
+	defer SetQueueSize(DefaultQueueSize)
+	events := New()
+	el, id := events.Subscribe()
+	defer events.Unsubscribe(id)
+	for _, act := range []string{Create, Start, Die} {
+		events.Publish("1234-5678", Container, act)
+	}
+	for _, act := range []string{Start, Die} {
+		if msg := <-el; msg.Action != act {
+			t.Errorf("invalid action %s - expected %s", msg.Action, act)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		events.Publish("1234-5678", Container, Create)
+	}
+	n := 0
+	for range el {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("expected 2 queued events before disconnect, but got %d", n)
+	}
+}
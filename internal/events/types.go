@@ -2,11 +2,12 @@ package events
 
 // Message is the structure that defines the details of the event.
 type Message struct {
-	ID       string
-	Type     string
-	Action   string
-	Time     int64
-	TimeNano int64
+	ID         string
+	Type       string
+	Action     string
+	Attributes map[string]string
+	Time       int64
+	TimeNano   int64
 }
 
 const (
@@ -22,8 +23,30 @@ const (
 	Start = "start"
 	// Die defines the event action die (container)
 	Die = "die"
+	// Destroy defines the event action destroy (container)
+	Destroy = "destroy"
 	// Detach defines the event action detach (container)
 	Detach = "detach"
+	// Init defines the event action init (container)
+	Init = "init"
+	// Rename defines the event action rename (container)
+	Rename = "rename"
+	// Checkpoint defines the event action checkpoint (container)
+	Checkpoint = "checkpoint"
+	// Restore defines the event action restore (container)
+	Restore = "restore"
+	// HealthStatus defines the event action health_status (container)
+	HealthStatus = "health_status"
+	// ExecCreate defines the event action exec_create (container)
+	ExecCreate = "exec_create"
+	// ExecStart defines the event action exec_start (container)
+	ExecStart = "exec_start"
+	// ExecDie defines the event action exec_die (container)
+	ExecDie = "exec_die"
 	// Pull defines the event action image (container)
 	Pull = "pull"
+	// Untag defines the event action untag (image)
+	Untag = "untag"
+	// Delete defines the event action delete (image)
+	Delete = "delete"
 )
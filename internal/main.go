@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
@@ -18,9 +19,11 @@ import (
 	"k8s.io/klog"
 
 	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/backend/docker"
 	"github.com/joyrex2001/kubedock/internal/config"
 	"github.com/joyrex2001/kubedock/internal/reaper"
 	"github.com/joyrex2001/kubedock/internal/server"
+	"github.com/joyrex2001/kubedock/internal/util/image"
 	"github.com/joyrex2001/kubedock/internal/util/myip"
 )
 
@@ -28,6 +31,15 @@ import (
 func Main() {
 	klog.Infof("%s / kubedock.id=%s", config.VersionString(), config.InstanceID)
 
+	switch be := viper.GetString("backend"); be {
+	case "", "kubernetes":
+	case "docker":
+		runDocker()
+		return
+	default:
+		klog.Fatalf("unsupported backend: %s", be)
+	}
+
 	cfg, err := config.GetKubernetes()
 	if err != nil {
 		klog.Fatalf("error instantiating kubernetes client: %s", err)
@@ -95,10 +107,32 @@ func getBackend(cfg *rest.Config, cli kubernetes.Interface) (backend.Backend, er
 	initimg := viper.GetString("kubernetes.initimage")
 	dindimg := viper.GetString("kubernetes.dindimage")
 	disdind := viper.GetBool("kubernetes.disable-dind")
+	selinux := viper.GetBool("kubernetes.selinux-relabel")
+	secprof := viper.GetString("kubernetes.security-profile")
+	quotapol := viper.GetString("kubernetes.quota-policy")
+	noinject := viper.GetBool("kubernetes.disable-sidecar-injection")
+	scoped := viper.GetBool("kubernetes.scoped-rbac")
 	timeout := viper.GetDuration("kubernetes.timeout")
 	podtmpl := viper.GetString("kubernetes.pod-template")
 	imgpsr := strings.ReplaceAll(viper.GetString("kubernetes.image-pull-secrets"), " ", "")
 	dissvcs := viper.GetBool("disable-services")
+	retain := viper.GetDuration("reaper.retain-failed")
+	execidle := viper.GetDuration("kubernetes.exec-idle-timeout")
+	execmax := viper.GetDuration("kubernetes.exec-max-duration")
+	imgttl := viper.GetDuration("kubernetes.image-cache-ttl")
+	pindgst := viper.GetBool("kubernetes.pin-digests")
+	archh := viper.GetBool("archive-helper")
+	archsize := viper.GetString("archive-helper-volume-size")
+	archclass := viper.GetString("archive-helper-storage-class")
+	archmode := viper.GetString("archive-helper-access-mode")
+
+	imgrw, err := image.ParseRewriteRules(viper.GetString("kubernetes.image-rewrite"))
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range imgrw {
+		klog.Infof("rewriting image references %s to %s", rule.From, rule.To)
+	}
 
 	optlog := ""
 	imgps := []string{}
@@ -111,6 +145,33 @@ func getBackend(cfg *rest.Config, cli kubernetes.Interface) (backend.Backend, er
 	if disdind {
 		klog.Infof("docker-in-docker support disabled")
 	}
+	if selinux {
+		klog.Infof("selinux relabeling of volumes enabled")
+	}
+	if secprof != backend.SecurityProfilePrivileged {
+		klog.Infof("security profile: %s", secprof)
+	}
+	if quotapol == backend.QuotaPolicyQueue {
+		klog.Infof("queueing containers that exceed the resource quota enabled")
+	}
+	if noinject {
+		klog.Infof("sidecar injection disabled")
+	}
+	if scoped {
+		klog.Infof("probing permissions of the service account")
+	}
+	if archh {
+		klog.Infof("keeping volumes and archives of containers on volume claims of %s", archsize)
+	}
+	if execidle > 0 || execmax > 0 {
+		klog.Infof("exec and attach sessions: idle timeout=%s, max duration=%s", execidle, execmax)
+	}
+	if pindgst {
+		klog.Infof("pinning image tags to their digest at first use")
+	} else {
+		klog.Infof("caching resolved image digests for %s", imgttl)
+	}
+	klog.Infof("retaining failed containers with retain-on-failure label for %s", retain)
 
 	kuburl, err := getKubedockURL()
 	if err != nil {
@@ -119,18 +180,57 @@ func getBackend(cfg *rest.Config, cli kubernetes.Interface) (backend.Backend, er
 	klog.V(3).Infof("kubedock url: %s", kuburl)
 
 	return backend.New(backend.Config{
-		Client:           cli,
-		RestConfig:       cfg,
-		Namespace:        ns,
-		InitImage:        initimg,
-		DindImage:        dindimg,
-		DisableDind:      disdind,
-		ImagePullSecrets: imgps,
-		PodTemplate:      podtmpl,
-		KubedockURL:      kuburl,
-		TimeOut:          timeout,
-		DisableServices:  dissvcs,
+		Client:                  cli,
+		RestConfig:              cfg,
+		Namespace:               ns,
+		InitImage:               initimg,
+		DindImage:               dindimg,
+		DisableDind:             disdind,
+		SELinuxRelabel:          selinux,
+		SecurityProfile:         secprof,
+		QuotaPolicy:             quotapol,
+		DisableSidecarInjection: noinject,
+		ImagePullSecrets:        imgps,
+		ImageRewrites:           imgrw,
+		ImageCacheTTL:           imgttl,
+		PinDigests:              pindgst,
+		PodTemplate:             podtmpl,
+		KubedockURL:             kuburl,
+		TimeOut:                 timeout,
+		DisableServices:         dissvcs,
+		ArchiveHelper:           archh,
+		ArchiveVolumeSize:       archsize,
+		ArchiveStorageClass:     archclass,
+		ArchiveAccessMode:       archmode,
+		ScopedRBAC:              scoped,
+		RetainFailed:            retain,
+		ExecIdleTimeout:         execidle,
+		ExecMaxDuration:         execmax,
+	})
+}
+
+// runDocker will start all components with the docker backend, which runs
+// the containers on a docker (or podman) api instead of on kubernetes.
+func runDocker() {
+	host := viper.GetString("docker-host")
+	imgrw, err := image.ParseRewriteRules(viper.GetString("kubernetes.image-rewrite"))
+	if err != nil {
+		klog.Fatalf("error instantiating backend: %s", err)
+	}
+	klog.Infof("using docker backend at %s", host)
+	kub, err := docker.New(docker.Config{
+		Host:          host,
+		ImageRewrites: imgrw,
 	})
+	if err != nil {
+		klog.Fatalf("error instantiating backend: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exitHandler(kub, cancel)
+	run(ctx, kub)
+	select {}
 }
 
 // getKubedockURL returns the uri that can be used externally to reach
@@ -156,15 +256,20 @@ func getKubedockURL() (string, error) {
 // run will start all components, based the settings initiated by cmd.
 func run(ctx context.Context, kub backend.Backend) {
 	reapmax := viper.GetDuration("reaper.reapmax")
+	fwdidle := viper.GetDuration("reaper.forward-idle-timeout")
 	rpr, err := reaper.New(reaper.Config{
-		KeepMax: reapmax,
-		Backend: kub,
+		KeepMax:     reapmax,
+		Backend:     kub,
+		ForwardIdle: fwdidle,
 	})
 	if err != nil {
 		klog.Fatalf("error instantiating reaper: %s", err)
 	}
 
 	klog.Infof("reaper started with max container age %s", reapmax)
+	if fwdidle > 0 {
+		klog.Infof("closing port-forwards and reverse proxies idle for %s", fwdidle)
+	}
 	rpr.Start()
 
 	if viper.GetBool("prune-start") {
@@ -174,12 +279,44 @@ func run(ctx context.Context, kub backend.Backend) {
 		}
 	}
 
+	if imgs := viper.GetString("kubernetes.prewarm-images"); imgs != "" {
+		if _, err := kub.PrewarmImages(strings.Split(imgs, ",")); err != nil {
+			klog.Errorf("error pre-pulling images: %s", err)
+		}
+	}
+
 	svr := server.New(kub)
+	reloadHandler(svr)
 	if err := svr.Run(ctx); err != nil {
 		klog.Errorf("error instantiating server: %s", err)
 	}
 }
 
+// reloadHandler will re-read the config file and apply the settings that can
+// be changed at runtime, when a SIGHUP signal is received.
+func reloadHandler(svr *server.Server) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP)
+	go func() {
+		for range sigc {
+			file := viper.ConfigFileUsed()
+			if file == "" {
+				klog.Warning("reload requested, but no config file is configured")
+				continue
+			}
+			klog.Infof("reloading configuration from %s", file)
+			if err := viper.ReadInConfig(); err != nil {
+				klog.Errorf("error reading config file: %s", err)
+				continue
+			}
+			flag.Set("v", viper.GetString("verbosity"))
+			if err := svr.Reload(); err != nil {
+				klog.Errorf("error reloading configuration: %s", err)
+			}
+		}
+	}()
+}
+
 // lockTimeoutHandler will wait until the return channel recieved a message,
 // if this is not done within configured lock.timeout, it will exit the
 // process.
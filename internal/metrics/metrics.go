@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// registry is the prometheus registry containing all kubedock metrics.
+	registry = prometheus.NewRegistry()
+
+	// startPhaseSeconds contains the duration of the individual phases of
+	// starting a container.
+	startPhaseSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kubedock",
+		Name:      "container_start_phase_seconds",
+		Help:      "Duration of the individual phases of starting a container.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"phase"})
+
+	// startSeconds contains the total duration of starting a container.
+	startSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "kubedock",
+		Name:      "container_start_seconds",
+		Help:      "Total duration of starting a container.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	})
+
+	// activeForwards contains the number of active port-forwards and
+	// reverse proxies.
+	activeForwards = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kubedock",
+		Name:      "active_forwards",
+		Help:      "Number of active port-forwards and reverse proxies.",
+	}, []string{"kind"})
+)
+
+func init() {
+	registry.MustRegister(startPhaseSeconds, startSeconds, activeForwards)
+}
+
+// AddForward will add given delta to the number of active forwards of
+// given kind.
+func AddForward(kind string, delta int) {
+	activeForwards.WithLabelValues(kind).Add(float64(delta))
+}
+
+// ObserveStartTimings will record the given container start phase timings.
+func ObserveStartTimings(timings map[string]time.Duration) {
+	total := time.Duration(0)
+	for phase, d := range timings {
+		startPhaseSeconds.WithLabelValues(phase).Observe(d.Seconds())
+		total += d
+	}
+	startSeconds.Observe(total.Seconds())
+}
+
+// Handler returns the http handler that exposes the metrics in the
+// prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
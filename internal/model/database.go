@@ -1,7 +1,9 @@
 package model
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -12,9 +14,80 @@ import (
 	"github.com/joyrex2001/kubedock/internal/util/stringid"
 )
 
+// AmbiguousError is the error returned when a given id prefix matches
+// multiple records.
+type AmbiguousError struct {
+	Prefix string
+}
+
+// Error will return the error message, which is the same as docker uses.
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("multiple IDs found with provided prefix: %s", e.Prefix)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *AmbiguousError) HTTPStatus() int {
+	return http.StatusConflict
+}
+
+// ConflictError is the error returned when a container is saved that has
+// been updated since it was read.
+type ConflictError struct {
+	ID string
+}
+
+// Error will return the error message.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("container %s has been modified concurrently", e.ID)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *ConflictError) HTTPStatus() int {
+	return http.StatusConflict
+}
+
+// IdempotencyKeyInUseError is the error returned when a new container is
+// saved with an idempotency key that is already used by another container.
+type IdempotencyKeyInUseError struct {
+	Key string
+	ID  string
+}
+
+// Error will return the error message.
+func (e *IdempotencyKeyInUseError) Error() string {
+	return fmt.Sprintf("idempotency key %s is already used by container %s", e.Key, e.ID)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *IdempotencyKeyInUseError) HTTPStatus() int {
+	return http.StatusConflict
+}
+
+// NameInUseError is the error returned when a container is saved with a
+// name that is already used by another container.
+type NameInUseError struct {
+	Name string
+	ID   string
+}
+
+// Error will return the error message, which is the same as docker uses.
+func (e *NameInUseError) Error() string {
+	return fmt.Sprintf("Conflict. The container name \"/%s\" is already in use by container \"%s\". You have to remove (or rename) that container to be able to reuse that name.", e.Name, e.ID)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *NameInUseError) HTTPStatus() int {
+	return http.StatusConflict
+}
+
 // Database is the object contains the in-memory database.
 type Database struct {
-	db *memdb.MemDB
+	db       *memdb.MemDB
+	ipamLock sync.Mutex
 }
 
 var instance *Database
@@ -42,6 +115,11 @@ func (in *Database) createSchema() (*memdb.MemDB, error) {
 			"container": {
 				Name: "container",
 				Indexes: map[string]*memdb.IndexSchema{
+					"label": {
+						Name:         "label",
+						AllowMissing: true,
+						Indexer:      &labelIndex{},
+					},
 					"id": {
 						Name:    "id",
 						Unique:  true,
@@ -57,6 +135,11 @@ func (in *Database) createSchema() (*memdb.MemDB, error) {
 						AllowMissing: true,
 						Indexer:      &memdb.StringFieldIndex{Field: "Name"},
 					},
+					"idempotencykey": {
+						Name:         "idempotencykey",
+						AllowMissing: true,
+						Indexer:      &memdb.StringFieldIndex{Field: "IdempotencyKey"},
+					},
 				},
 			},
 			"exec": {
@@ -71,6 +154,31 @@ func (in *Database) createSchema() (*memdb.MemDB, error) {
 			},
 			"network": {
 				Name: "network",
+				Indexes: map[string]*memdb.IndexSchema{
+					"label": {
+						Name:         "label",
+						AllowMissing: true,
+						Indexer:      &labelIndex{},
+					},
+					"id": {
+						Name:    "id",
+						Unique:  true,
+						Indexer: &memdb.StringFieldIndex{Field: "ID"},
+					},
+					"shortid": {
+						Name:    "shortid",
+						Unique:  true,
+						Indexer: &memdb.StringFieldIndex{Field: "ShortID"},
+					},
+					"name": {
+						Name:    "name",
+						Unique:  true,
+						Indexer: &memdb.StringFieldIndex{Field: "Name"},
+					},
+				},
+			},
+			"service": {
+				Name: "service",
 				Indexes: map[string]*memdb.IndexSchema{
 					"id": {
 						Name:    "id",
@@ -140,6 +248,12 @@ func (in *Database) GetContainer(id string) (*types.Container, error) {
 			return nil, err
 		}
 	}
+	if raw == nil {
+		raw, err = in.getByPrefix(txn, "container", id)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if raw == nil {
 		return nil, fmt.Errorf("container %s not found", id)
 	}
@@ -174,8 +288,8 @@ func (in *Database) getContainerByName(name string) (*types.Container, error) {
 // if the instance does not exist.
 func (in *Database) GetContainerByNameOrID(id string) (*types.Container, error) {
 	con, err := in.GetContainer(id)
-	if err == nil {
-		return con, nil
+	if err == nil || isAmbiguous(err) {
+		return con, err
 	}
 	return in.GetContainerByName(id)
 }
@@ -195,9 +309,25 @@ func (in *Database) GetContainers() ([]*types.Container, error) {
 	return rec, nil
 }
 
+// GetContainersByLabel will return all containers that have a label with
+// given key, and given value if the value is not empty.
+func (in *Database) GetContainersByLabel(key, val string) ([]*types.Container, error) {
+	rec := []*types.Container{}
+	raws, err := in.getByLabel("container", key, val)
+	for _, raw := range raws {
+		rec = append(rec, raw.(*types.Container))
+	}
+	return rec, err
+}
+
 // SaveContainer will either update the given container, or create a new
 // record. If ID is not provided, it will generate an ID and adds the
-// current time in Created.
+// current time in Created. An existing record is only replaced if it has
+// the same version as the given container, otherwise a ConflictError is
+// returned; use UpdateContainer to modify existing containers. The
+// idempotency key is reserved in the same transaction; if it is already used
+// by another container, an IdempotencyKeyInUseError is returned. Likewise, a
+// NameInUseError is returned if the name is used by another container.
 func (in *Database) SaveContainer(con *types.Container) error {
 	if con.ID == "" {
 		id := stringid.GenerateRandomID()
@@ -205,12 +335,115 @@ func (in *Database) SaveContainer(con *types.Container) error {
 		con.ShortID = stringid.TruncateID(id)
 		con.Created = time.Now()
 	}
-	return in.save("container", con)
+	in.ipamLock.Lock()
+	defer in.ipamLock.Unlock()
+	txn := in.db.Txn(true)
+	defer txn.Abort()
+	raw, err := txn.First("container", "id", con.ID)
+	if err != nil {
+		return err
+	}
+	if raw != nil && raw.(*types.Container).Version != con.Version {
+		return &ConflictError{ID: con.ID}
+	}
+	if con.IdempotencyKey != "" {
+		raw, err := txn.First("container", "idempotencykey", con.IdempotencyKey)
+		if err != nil {
+			return err
+		}
+		if raw != nil && raw.(*types.Container).ID != con.ID {
+			return &IdempotencyKeyInUseError{Key: con.IdempotencyKey, ID: raw.(*types.Container).ID}
+		}
+	}
+	if err := checkContainerName(txn, con); err != nil {
+		return err
+	}
+	if err := in.allocateIPs(txn, con); err != nil {
+		return err
+	}
+	con.Version++
+	if err := txn.Insert("container", con); err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// UpdateContainer will apply given update function to a copy of the
+// container with given id and store the result, within a single write
+// transaction. As write transactions are serialized, concurrent updates of
+// the same container (e.g. a network connect during a rename) can't
+// overwrite each other, and readers never see a partially updated record.
+// If the update function returns an error, or the container is renamed to a
+// name that is used by another container, the record is left as is. The
+// updated container is returned.
+func (in *Database) UpdateContainer(id string, fn func(*types.Container) error) (*types.Container, error) {
+	in.ipamLock.Lock()
+	defer in.ipamLock.Unlock()
+	txn := in.db.Txn(true)
+	defer txn.Abort()
+	raw, err := txn.First("container", "id", id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("container %s not found", id)
+	}
+	con := raw.(*types.Container).Clone()
+	if err := fn(con); err != nil {
+		return nil, err
+	}
+	if err := checkContainerName(txn, con); err != nil {
+		return nil, err
+	}
+	if err := in.allocateIPs(txn, con); err != nil {
+		return nil, err
+	}
+	con.Version++
+	if err := txn.Insert("container", con); err != nil {
+		return nil, err
+	}
+	txn.Commit()
+	return con, nil
+}
+
+// checkContainerName will return a NameInUseError if the name of the given
+// container is used by another container in the given transaction. Names
+// are compared case insensitive, as they are used as (case insensitive) dns
+// names as well.
+func checkContainerName(txn *memdb.Txn, con *types.Container) error {
+	if con.Name == "" {
+		return nil
+	}
+	it, err := txn.Get("container", "id")
+	if err != nil {
+		return err
+	}
+	for raw := it.Next(); raw != nil; raw = it.Next() {
+		other := raw.(*types.Container)
+		if other.ID != con.ID && strings.EqualFold(other.Name, con.Name) {
+			return &NameInUseError{Name: con.Name, ID: other.ID}
+		}
+	}
+	return nil
+}
+
+// RenameContainer will rename given container, and returns the renamed
+// container.
+func (in *Database) RenameContainer(con *types.Container, name string) (*types.Container, error) {
+	return in.UpdateContainer(con.ID, func(c *types.Container) error {
+		c.Name = name
+		return nil
+	})
 }
 
 // DeleteContainer will delete provided container.
 func (in *Database) DeleteContainer(con *types.Container) error {
-	return in.delete("container", con)
+	if err := in.delete("container", con); err != nil {
+		return err
+	}
+	con.Checkpoint.Remove()
+	return nil
 }
 
 // GetExec will return a exec with given id, or an error if the
@@ -301,7 +534,14 @@ func (in *Database) GetNetworkByNameOrID(id string) (*types.Network, error) {
 	if err == nil {
 		return netw, nil
 	}
-	return in.GetNetworkByName(id)
+	if netw, err = in.GetNetworkByName(id); err == nil {
+		return netw, nil
+	}
+	raw, err := in.findByPrefix("network", id)
+	if err != nil {
+		return nil, err
+	}
+	return raw.(*types.Network), nil
 }
 
 // GetNetworks will return all stored networks.
@@ -338,6 +578,17 @@ func (in *Database) GetNetworksByIDs(ids map[string]interface{}) ([]*types.Netwo
 	return rec, nil
 }
 
+// GetNetworksByLabel will return all networks that have a label with given
+// key, and given value if the value is not empty.
+func (in *Database) GetNetworksByLabel(key, val string) ([]*types.Network, error) {
+	rec := []*types.Network{}
+	raws, err := in.getByLabel("network", key, val)
+	for _, raw := range raws {
+		rec = append(rec, raw.(*types.Network))
+	}
+	return rec, err
+}
+
 // SaveNetwork will either update the given network, or create a new
 // record. If ID is not provided, it will generate an ID and adds the
 // current time in Created.
@@ -348,6 +599,11 @@ func (in *Database) SaveNetwork(netw *types.Network) error {
 		netw.ShortID = stringid.TruncateID(id)
 		netw.Created = time.Now()
 	}
+	in.ipamLock.Lock()
+	defer in.ipamLock.Unlock()
+	if err := in.allocateSubnet(netw); err != nil {
+		return err
+	}
 	return in.save("network", netw)
 }
 
@@ -417,13 +673,15 @@ func (in *Database) GetImages() ([]*types.Image, error) {
 
 // SaveImage will either update the given image, or create a new
 // record. If ID is not provided, it will generate an ID and adds the
-// current time in Created.
+// current time in Created, unless already set.
 func (in *Database) SaveImage(img *types.Image) error {
 	if img.ID == "" {
 		id := stringid.GenerateRandomID()
 		img.ID = id
 		img.ShortID = stringid.TruncateID(id)
-		img.Created = time.Now()
+		if img.Created.IsZero() {
+			img.Created = time.Now()
+		}
 	}
 	return in.save("image", img)
 }
@@ -433,6 +691,153 @@ func (in *Database) DeleteImage(img *types.Image) error {
 	return in.delete("image", img)
 }
 
+// GetService will return a service with given id, or an error if the
+// instance does not exist.
+func (in *Database) GetService(id string) (*types.Service, error) {
+	txn := in.db.Txn(false)
+	defer txn.Abort()
+	idx := "id"
+	if stringid.IsShortID(id) {
+		idx = "shortid"
+	}
+	raw, err := txn.First("service", idx, id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("service %s not found", id)
+	}
+	return raw.(*types.Service), nil
+}
+
+// GetServiceByName will return a service with given name, or an error if
+// the instance does not exist.
+func (in *Database) GetServiceByName(name string) (*types.Service, error) {
+	txn := in.db.Txn(false)
+	defer txn.Abort()
+	raw, err := txn.First("service", "name", name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+	return raw.(*types.Service), nil
+}
+
+// GetServiceByNameOrID will return a service with given name or id, or
+// an error if the instance does not exist.
+func (in *Database) GetServiceByNameOrID(id string) (*types.Service, error) {
+	svc, err := in.GetService(id)
+	if err == nil {
+		return svc, nil
+	}
+	if svc, err = in.GetServiceByName(id); err == nil {
+		return svc, nil
+	}
+	raw, err := in.findByPrefix("service", id)
+	if err != nil {
+		return nil, err
+	}
+	return raw.(*types.Service), nil
+}
+
+// GetServices will return all stored services.
+func (in *Database) GetServices() ([]*types.Service, error) {
+	rec := []*types.Service{}
+	txn := in.db.Txn(false)
+	defer txn.Abort()
+	it, err := txn.Get("service", "id")
+	if err != nil {
+		return rec, err
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		rec = append(rec, obj.(*types.Service))
+	}
+	return rec, nil
+}
+
+// SaveService will either update the given service, or create a new
+// record. If ID is not provided, it will generate an ID and adds the
+// current time in Created. The version of the service is incremented
+// on every save.
+func (in *Database) SaveService(svc *types.Service) error {
+	if svc.ID == "" {
+		id := stringid.GenerateRandomID()
+		svc.ID = id
+		svc.ShortID = stringid.TruncateID(id)
+		svc.Created = time.Now()
+	}
+	svc.Updated = time.Now()
+	svc.Version++
+	return in.save("service", svc)
+}
+
+// DeleteService will delete provided service.
+func (in *Database) DeleteService(svc *types.Service) error {
+	return in.delete("service", svc)
+}
+
+// getByLabel will return all records in given table that have a label with
+// given key, and given value if the value is not empty.
+func (in *Database) getByLabel(table, key, val string) ([]interface{}, error) {
+	rec := []interface{}{}
+	txn := in.db.Txn(false)
+	defer txn.Abort()
+	args := []interface{}{key}
+	if val != "" {
+		args = append(args, val)
+	}
+	it, err := txn.Get(table, "label", args...)
+	if err != nil {
+		return rec, err
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		rec = append(rec, obj)
+	}
+	return rec, nil
+}
+
+// findByPrefix will return the record in given table of which the id
+// starts with given prefix, or an error if no record, or multiple records
+// match the prefix.
+func (in *Database) findByPrefix(table, prefix string) (interface{}, error) {
+	txn := in.db.Txn(false)
+	defer txn.Abort()
+	raw, err := in.getByPrefix(txn, table, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%s %s not found", table, prefix)
+	}
+	return raw, nil
+}
+
+// getByPrefix will return the record in given table of which the id starts
+// with given prefix, nil if no record matches, or an AmbiguousError if the
+// prefix matches multiple records.
+func (in *Database) getByPrefix(txn *memdb.Txn, table, prefix string) (interface{}, error) {
+	if prefix == "" {
+		return nil, nil
+	}
+	it, err := txn.Get(table, "id_prefix", prefix)
+	if err != nil {
+		return nil, err
+	}
+	raw := it.Next()
+	if raw != nil && it.Next() != nil {
+		return nil, &AmbiguousError{Prefix: prefix}
+	}
+	return raw, nil
+}
+
+// isAmbiguous will return true if given error is an AmbiguousError.
+func isAmbiguous(err error) bool {
+	var aerr *AmbiguousError
+	return errors.As(err, &aerr)
+}
+
 // save is a generic save method to store or update a record in the
 // database.
 func (in *Database) save(table string, rec interface{}) error {
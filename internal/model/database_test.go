@@ -1,7 +1,10 @@
 package model
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/joyrex2001/kubedock/internal/model/types"
@@ -270,3 +273,375 @@ func TestImage(t *testing.T) {
 	}
 
 }
+
+func TestRenameContainer(t *testing.T) {
+	db, _ := New()
+
+	con := &types.Container{Name: "tb303"}
+	if err := db.SaveContainer(con); err != nil {
+		t.Errorf("Unexpected error when creating container %s", err)
+	}
+	renamed, err := db.RenameContainer(con, "tb03")
+	if err != nil {
+		t.Errorf("Unexpected error when renaming container %s", err)
+	}
+	if renamed.Name != "tb03" || con.Name != "tb303" {
+		t.Errorf("Expected renamed copy %s, and unmodified original %s", renamed.Name, con.Name)
+	}
+	if _, err := db.GetContainerByName("tb303"); err == nil {
+		t.Errorf("Expected an error when loading container by its old name")
+	}
+	if res, err := db.GetContainerByName("tb03"); err != nil || res.ID != con.ID {
+		t.Errorf("Expected container %s when loading by its new name, but got %v: %s", con.ID, res, err)
+	}
+}
+
+func TestUpdateContainer(t *testing.T) {
+	db, _ := New()
+
+	con := &types.Container{Name: "sh101", Labels: map[string]string{}}
+	if err := db.SaveContainer(con); err != nil {
+		t.Errorf("Unexpected error when creating container %s", err)
+	}
+	version := con.Version
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := db.UpdateContainer(con.ID, func(c *types.Container) error {
+				c.Labels[fmt.Sprintf("label%d", i)] = "set"
+				return nil
+			}); err != nil {
+				t.Errorf("Unexpected error when updating container %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	res, err := db.GetContainer(con.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error when loading container %s", err)
+	}
+	if len(res.Labels) != 50 {
+		t.Errorf("Expected 50 labels after concurrent updates, but got %d", len(res.Labels))
+	}
+	if res.Version != version+50 {
+		t.Errorf("Expected version %d, but got %d", version+50, res.Version)
+	}
+
+	if _, err := db.UpdateContainer(con.ID, func(c *types.Container) error {
+		return fmt.Errorf("failed")
+	}); err == nil {
+		t.Errorf("Expected an error when update function fails")
+	}
+	if _, err := db.UpdateContainer(con.ID, func(c *types.Container) error {
+		c.Labels["partial"] = "set"
+		return fmt.Errorf("failed")
+	}); err == nil {
+		t.Errorf("Expected an error when update function fails")
+	}
+	if res, _ = db.GetContainer(con.ID); res.Version != version+50 || res.Labels["partial"] != "" {
+		t.Errorf("Expected unmodified version %d after failed update, but got %d: %v", version+50, res.Version, res.Labels)
+	}
+	if _, err := db.UpdateContainer("doesnotexist", func(c *types.Container) error {
+		return nil
+	}); err == nil {
+		t.Errorf("Expected an error when updating a non existing container")
+	}
+}
+
+func TestSaveContainerConflict(t *testing.T) {
+	db, _ := New()
+
+	con := &types.Container{Name: "mc202"}
+	if err := db.SaveContainer(con); err != nil {
+		t.Errorf("Unexpected error when creating container %s", err)
+	}
+	if _, err := db.UpdateContainer(con.ID, func(c *types.Container) error {
+		c.Running = true
+		return nil
+	}); err != nil {
+		t.Errorf("Unexpected error when updating container %s", err)
+	}
+	con.Stopped = true
+	err := db.SaveContainer(con)
+	var cerr *ConflictError
+	if !errors.As(err, &cerr) {
+		t.Errorf("Expected a conflict when saving a stale container, but got %v", err)
+	}
+	res, _ := db.GetContainer(con.ID)
+	if !res.Running || res.Stopped {
+		t.Errorf("Expected stale save to be rejected, but got %v", res)
+	}
+	res.Stopped = true
+	if err := db.SaveContainer(res); err != nil {
+		t.Errorf("Unexpected error when saving current container %s", err)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	db, _ := New()
+
+	// concurrent saves with the same key only store a single container
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	saved, failed := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.SaveContainer(&types.Container{IdempotencyKey: "mc303"})
+			lock.Lock()
+			defer lock.Unlock()
+			var kerr *IdempotencyKeyInUseError
+			if errors.As(err, &kerr) {
+				failed++
+			} else if err == nil {
+				saved++
+			}
+		}()
+	}
+	wg.Wait()
+	if saved != 1 || failed != 9 {
+		t.Errorf("Expected a single container with the key, but got %d saved and %d failed", saved, failed)
+	}
+
+	con := &types.Container{IdempotencyKey: "mc505"}
+	if err := db.SaveContainer(con); err != nil {
+		t.Errorf("Unexpected error when creating container %s", err)
+	}
+	if err := db.SaveContainer(con); err != nil {
+		t.Errorf("Unexpected error when saving container with its own key %s", err)
+	}
+}
+
+func TestContainerNameInUse(t *testing.T) {
+	db, _ := New()
+
+	// concurrent saves with the same name only store a single container
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	saved, failed := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.SaveContainer(&types.Container{Name: "tr808"})
+			lock.Lock()
+			defer lock.Unlock()
+			var nerr *NameInUseError
+			if errors.As(err, &nerr) {
+				failed++
+			} else if err == nil {
+				saved++
+			}
+		}()
+	}
+	wg.Wait()
+	if saved != 1 || failed != 9 {
+		t.Errorf("Expected a single container with the name, but got %d saved and %d failed", saved, failed)
+	}
+
+	con := &types.Container{Name: "mc909"}
+	if err := db.SaveContainer(con); err != nil {
+		t.Errorf("Unexpected error when creating container %s", err)
+	}
+	if err := db.SaveContainer(&types.Container{Name: "MC909"}); err == nil {
+		t.Errorf("Expected error when creating container with a name that differs in case only")
+	} else if !strings.HasPrefix(err.Error(), `Conflict. The container name "/MC909" is already in use by container "`+con.ID+`"`) {
+		t.Errorf("Expected docker conflict message, but got %s", err)
+	}
+	if _, err := db.RenameContainer(con, "tr808"); err == nil {
+		t.Errorf("Expected error when renaming container to a name in use")
+	}
+	if res, err := db.GetContainer(con.ID); err != nil || res.Name != "mc909" {
+		t.Errorf("Expected container to keep its name after a failed rename, but got %v %v", res, err)
+	}
+	if _, err := db.RenameContainer(con, "MC909"); err != nil {
+		t.Errorf("Unexpected error when renaming container to its own name %s", err)
+	}
+}
+
+func TestPrefixResolution(t *testing.T) {
+	db, _ := New()
+
+	for _, id := range []string{"aa11bb22cc33dd44", "aa11bb22ff55ee66"} {
+		if err := db.SaveContainer(&types.Container{ID: id, ShortID: id[:12]}); err != nil {
+			t.Errorf("Unexpected error when creating container %s", err)
+		}
+		if err := db.SaveNetwork(&types.Network{ID: id, ShortID: id[:12], Name: "prefix-" + id}); err != nil {
+			t.Errorf("Unexpected error when creating network %s", err)
+		}
+	}
+
+	tests := []struct {
+		id        string
+		match     string
+		ambiguous bool
+	}{
+		{id: "aa11bb22cc", match: "aa11bb22cc33dd44"},
+		{id: "aa11bb22f", match: "aa11bb22ff55ee66"},
+		{id: "aa11bb22", ambiguous: true},
+		{id: "ab", match: ""},
+	}
+	for i, tst := range tests {
+		con, err := db.GetContainerByNameOrID(tst.id)
+		if _, ok := err.(*AmbiguousError); ok != tst.ambiguous {
+			t.Errorf("failed test %d - expected ambiguous %v, but got %v", i, tst.ambiguous, err)
+		}
+		if tst.match != "" && (err != nil || con.ID != tst.match) {
+			t.Errorf("failed test %d - expected container %s, but got %v: %s", i, tst.match, con, err)
+		}
+		netw, err := db.GetNetworkByNameOrID(tst.id)
+		if _, ok := err.(*AmbiguousError); ok != tst.ambiguous {
+			t.Errorf("failed test %d - expected ambiguous %v, but got %v", i, tst.ambiguous, err)
+		}
+		if tst.match != "" && (err != nil || netw.ID != tst.match) {
+			t.Errorf("failed test %d - expected network %s, but got %v: %s", i, tst.match, netw, err)
+		}
+	}
+}
+
+func TestGetByLabel(t *testing.T) {
+	db, _ := New()
+
+	for i, lbls := range []map[string]string{
+		{"bylabel.session": "a", "bylabel.role": "db"},
+		{"bylabel.session": "a"},
+		{"bylabel.session": "b"},
+		{},
+	} {
+		if err := db.SaveContainer(&types.Container{Name: fmt.Sprintf("bylabel-%d", i), Labels: lbls}); err != nil {
+			t.Errorf("Unexpected error when creating container %s", err)
+		}
+	}
+
+	tests := []struct {
+		key   string
+		val   string
+		count int
+	}{
+		{key: "bylabel.session", count: 3},
+		{key: "bylabel.session", val: "a", count: 2},
+		{key: "bylabel.session", val: "c", count: 0},
+		{key: "bylabel.role", count: 1},
+		{key: "bylabel.other", count: 0},
+	}
+	for i, tst := range tests {
+		res, err := db.GetContainersByLabel(tst.key, tst.val)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if len(res) != tst.count {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.count, len(res))
+		}
+	}
+}
+
+func TestIPAllocation(t *testing.T) {
+	db, _ := New()
+
+	bridge, err := db.GetNetworkByName("bridge")
+	if err != nil || bridge.Subnet != "172.17.0.0/16" {
+		t.Errorf("Expected default subnet for bridge network, but got %v", bridge)
+	}
+	host, _ := db.GetNetworkByName("host")
+	if host.Subnet != "" {
+		t.Errorf("Expected no subnet for host network, but got %s", host.Subnet)
+	}
+
+	neta := &types.Network{Name: "ipam-a"}
+	netb := &types.Network{Name: "ipam-b"}
+	for _, netw := range []*types.Network{neta, netb} {
+		if err := db.SaveNetwork(netw); err != nil {
+			t.Errorf("Unexpected error when creating network %s: %s", netw.Name, err)
+		}
+	}
+	if neta.Subnet == "" || neta.Subnet == netb.Subnet || neta.Subnet == bridge.Subnet {
+		t.Errorf("Expected unique subnets, but got %s and %s", neta.Subnet, netb.Subnet)
+	}
+
+	con1 := &types.Container{}
+	con1.ConnectNetwork(neta.ID)
+	con1.ConnectNetwork(host.ID)
+	con2 := &types.Container{}
+	con2.ConnectNetwork(neta.ID)
+	for _, con := range []*types.Container{con1, con2} {
+		if err := db.SaveContainer(con); err != nil {
+			t.Errorf("Unexpected error when saving container: %s", err)
+		}
+	}
+	ip1 := con1.IPAddresses[neta.ID]
+	if ip1 == "" || ip1 == con2.IPAddresses[neta.ID] {
+		t.Errorf("Expected unique ip addresses, but got %s and %s", ip1, con2.IPAddresses[neta.ID])
+	}
+	if _, ok := con1.IPAddresses[host.ID]; ok {
+		t.Errorf("Expected no ip address in host network")
+	}
+
+	// addresses are stable over updates, and released on disconnect
+	if err := db.SaveContainer(con1); err != nil || con1.IPAddresses[neta.ID] != ip1 {
+		t.Errorf("Expected stable ip address %s, but got %s", ip1, con1.IPAddresses[neta.ID])
+	}
+	con1.ConnectNetwork(netb.ID)
+	if err := con1.DisconnectNetwork(neta.ID); err != nil {
+		t.Errorf("Unexpected error when disconnecting network: %s", err)
+	}
+	if err := db.SaveContainer(con1); err != nil || con1.IPAddresses[netb.ID] == "" {
+		t.Errorf("Expected ip address in network %s, but got %v", netb.Name, con1.IPAddresses)
+	}
+	con3 := &types.Container{}
+	con3.ConnectNetwork(neta.ID)
+	if err := db.SaveContainer(con3); err != nil || con3.IPAddresses[neta.ID] != ip1 {
+		t.Errorf("Expected released ip address %s to be reused, but got %s", ip1, con3.IPAddresses[neta.ID])
+	}
+}
+
+func TestStaticIPAllocation(t *testing.T) {
+	db, _ := New()
+
+	netw := &types.Network{Name: "ipam-static"}
+	if err := db.SaveNetwork(netw); err != nil {
+		t.Fatalf("Unexpected error when creating network: %s", err)
+	}
+	static := strings.Split(netw.Subnet, "/")[0]
+	static = static[:strings.LastIndex(static, ".")] + ".42"
+
+	// concurrent requests for the same address only assign it once
+	ids := []string{}
+	for i := 0; i < 10; i++ {
+		con := &types.Container{}
+		if err := db.SaveContainer(con); err != nil {
+			t.Fatalf("Unexpected error when saving container: %s", err)
+		}
+		ids = append(ids, con.ID)
+	}
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	assigned, failed := 0, 0
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			_, err := db.UpdateContainer(id, func(con *types.Container) error {
+				con.ConnectNetwork(netw.ID)
+				con.RequestIP(netw.ID, static)
+				return nil
+			})
+			lock.Lock()
+			defer lock.Unlock()
+			var serr *StaticIPError
+			if errors.As(err, &serr) {
+				failed++
+			} else if err == nil {
+				assigned++
+			}
+		}(id)
+	}
+	wg.Wait()
+	if assigned != 1 || failed != len(ids)-1 {
+		t.Errorf("Expected a single assignment of %s, but got %d assigned and %d failed", static, assigned, failed)
+	}
+}
@@ -0,0 +1,212 @@
+package model
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/hashicorp/go-memdb"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// bridgeSubnet is the subnet of the default bridge network, which is the
+// same as the one docker uses by default.
+const bridgeSubnet = "172.17.0.0/16"
+
+// SubnetOverlapError is the error returned when a network is created with a
+// subnet that overlaps with the subnet of another network.
+type SubnetOverlapError struct {
+	Subnet string
+}
+
+// Error will return the error message, which is the same as docker uses.
+func (e *SubnetOverlapError) Error() string {
+	return fmt.Sprintf("Pool overlaps with other one on this address space (%s)", e.Subnet)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *SubnetOverlapError) HTTPStatus() int {
+	return http.StatusForbidden
+}
+
+// allocateSubnet will assign a free synthetic subnet to the given network,
+// unless it is a network without addresses. If the network already has a
+// subnet, it will verify it doesn't overlap with other networks. Like
+// docker, subnets are taken from 172.18.0.0/16 up to 172.31.0.0/16 first,
+// and from 192.168.0.0/20 up to 192.168.240.0/20 when these are exhausted.
+// The caller should hold ipamLock.
+func (in *Database) allocateSubnet(netw *types.Network) error {
+	if !netw.HasAddresses() {
+		return nil
+	}
+	if netw.Name == "bridge" && netw.Subnet == "" {
+		netw.Subnet = bridgeSubnet
+		return nil
+	}
+	netws, err := in.GetNetworks()
+	if err != nil {
+		return err
+	}
+	used := map[string]bool{bridgeSubnet: true}
+	for _, n := range netws {
+		if n.ID != netw.ID {
+			used[n.Subnet] = true
+		}
+	}
+	if netw.Subnet != "" {
+		_, req, err := net.ParseCIDR(netw.Subnet)
+		if err != nil {
+			return err
+		}
+		for subnet := range used {
+			if _, cur, err := net.ParseCIDR(subnet); err == nil && (cur.Contains(req.IP) || req.Contains(cur.IP)) {
+				return &SubnetOverlapError{Subnet: netw.Subnet}
+			}
+		}
+		return nil
+	}
+	candidates := []string{}
+	for i := 18; i < 32; i++ {
+		candidates = append(candidates, fmt.Sprintf("172.%d.0.0/16", i))
+	}
+	for i := 0; i < 256; i += 16 {
+		candidates = append(candidates, fmt.Sprintf("192.168.%d.0/20", i))
+	}
+	for _, subnet := range candidates {
+		if !used[subnet] {
+			netw.Subnet = subnet
+			return nil
+		}
+	}
+	return fmt.Errorf("no free subnet available for network %s", netw.Name)
+}
+
+// StaticIPError is the error returned when a requested (static) ip address
+// can't be assigned to a container.
+type StaticIPError struct {
+	Network string
+	Err     error
+}
+
+// Error will return the error message.
+func (e *StaticIPError) Error() string {
+	return fmt.Sprintf("could not assign ip in network %s: %s", e.Network, e.Err)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *StaticIPError) HTTPStatus() int {
+	return http.StatusBadRequest
+}
+
+// allocateIPs will assign a free ip address, or the requested (static) ip
+// address, to the given container for every connected network it doesn't
+// have an address for yet, and release the addresses of networks it is no
+// longer connected to. Addresses are assigned sequentially, starting after
+// the gateway address. The used addresses are read from given write
+// transaction in which the container is stored, and the caller should hold
+// ipamLock, so the check and the reservation of an address are atomic.
+func (in *Database) allocateIPs(txn *memdb.Txn, con *types.Container) error {
+	for id := range con.IPAddresses {
+		if _, ok := con.Networks[id]; !ok {
+			delete(con.IPAddresses, id)
+		}
+	}
+	if len(con.Networks) == len(con.IPAddresses) {
+		return nil
+	}
+	netws := []*types.Network{}
+	it, err := txn.Get("network", "id")
+	if err != nil {
+		return err
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		netw := obj.(*types.Network)
+		if _, ok := con.Networks[netw.ID]; ok {
+			netws = append(netws, netw)
+		}
+	}
+	tainrs := []*types.Container{}
+	it, err = txn.Get("container", "id")
+	if err != nil {
+		return err
+	}
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		tainrs = append(tainrs, obj.(*types.Container))
+	}
+	for _, netw := range netws {
+		if _, ok := con.IPAddresses[netw.ID]; ok || netw.Subnet == "" {
+			continue
+		}
+		used := map[string]bool{}
+		for _, tainr := range tainrs {
+			if tainr.ID != con.ID {
+				used[tainr.IPAddresses[netw.ID]] = true
+			}
+		}
+		var ip string
+		if static := con.StaticIPs[netw.ID]; static != "" {
+			if ip, err = checkStaticIP(netw.Subnet, netw.GetGateway(), static, used); err != nil {
+				return &StaticIPError{Network: netw.Name, Err: err}
+			}
+		} else if ip, err = nextFreeIP(netw.Subnet, netw.GetGateway(), used); err != nil {
+			return fmt.Errorf("could not allocate ip in network %s: %w", netw.Name, err)
+		}
+		if con.IPAddresses == nil {
+			con.IPAddresses = map[string]string{}
+		}
+		con.IPAddresses[netw.ID] = ip
+	}
+	return nil
+}
+
+// nextFreeIP will return the first address in the given subnet that is not
+// in the used set, skipping the network, gateway and broadcast addresses.
+func nextFreeIP(subnet, gateway string, used map[string]bool) (string, error) {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", err
+	}
+	ones, bits := ipnet.Mask.Size()
+	base := ipnet.IP.To4()
+	if base == nil {
+		return "", fmt.Errorf("only ipv4 subnets are supported")
+	}
+	start := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+	size := uint32(1) << (bits - ones)
+	for n := uint32(1); n < size-1; n++ {
+		v := start + n
+		ip := net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).String()
+		if !used[ip] && ip != gateway {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("subnet %s exhausted", subnet)
+}
+
+// checkStaticIP will return the given ip address if it is an available
+// address in the given subnet, which excludes the network, gateway and
+// broadcast addresses and the addresses in the used set.
+func checkStaticIP(subnet, gateway, ip string, used map[string]bool) (string, error) {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", err
+	}
+	addr := net.ParseIP(ip).To4()
+	if addr == nil || !ipnet.Contains(addr) {
+		return "", fmt.Errorf("requested ip %s is not in subnet %s", ip, subnet)
+	}
+	ones, bits := ipnet.Mask.Size()
+	base := ipnet.IP.To4()
+	n := (uint32(addr[0])<<24 | uint32(addr[1])<<16 | uint32(addr[2])<<8 | uint32(addr[3])) -
+		(uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3]))
+	if n < 1 || n >= uint32(1)<<(bits-ones)-1 || addr.String() == gateway {
+		return "", fmt.Errorf("requested ip %s is a reserved address in subnet %s", ip, subnet)
+	}
+	if used[addr.String()] {
+		return "", fmt.Errorf("requested ip %s is already in use", ip)
+	}
+	return addr.String(), nil
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNextFreeIP(t *testing.T) {
+	tests := []struct {
+		subnet  string
+		gateway string
+		used    map[string]bool
+		out     string
+		err     bool
+	}{
+		{subnet: "172.18.0.0/16", gateway: "172.18.0.1", used: map[string]bool{}, out: "172.18.0.2"},
+		{subnet: "172.18.0.0/16", gateway: "172.18.0.1", used: map[string]bool{"172.18.0.2": true}, out: "172.18.0.3"},
+		{subnet: "172.18.0.0/16", gateway: "172.18.0.254", used: map[string]bool{}, out: "172.18.0.1"},
+		{subnet: "192.168.16.0/30", gateway: "192.168.16.1", used: map[string]bool{"192.168.16.2": true}, err: true},
+		{subnet: "fd00::/64", used: map[string]bool{}, err: true},
+		{subnet: "invalid", used: map[string]bool{}, err: true},
+	}
+	for i, tst := range tests {
+		res, err := nextFreeIP(tst.subnet, tst.gateway, tst.used)
+		if (err != nil) != tst.err || res != tst.out {
+			t.Errorf("failed test %d - expected %s (error %t), but got %s (%v)", i, tst.out, tst.err, res, err)
+		}
+	}
+}
+
+func TestCheckStaticIP(t *testing.T) {
+	tests := []struct {
+		subnet  string
+		gateway string
+		ip      string
+		used    map[string]bool
+		err     bool
+	}{
+		{subnet: "172.18.0.0/16", ip: "172.18.0.10", used: map[string]bool{}},
+		{subnet: "172.18.0.0/16", gateway: "172.18.0.10", ip: "172.18.0.10", used: map[string]bool{}, err: true},
+		{subnet: "172.18.0.0/16", ip: "172.18.0.10", used: map[string]bool{"172.18.0.10": true}, err: true},
+		{subnet: "172.18.0.0/16", ip: "172.19.0.10", used: map[string]bool{}, err: true},
+		{subnet: "172.18.0.0/16", gateway: "172.18.0.1", ip: "172.18.0.1", used: map[string]bool{}, err: true},
+		{subnet: "172.18.0.0/16", ip: "172.18.255.255", used: map[string]bool{}, err: true},
+		{subnet: "172.18.0.0/16", ip: "invalid", used: map[string]bool{}, err: true},
+	}
+	for i, tst := range tests {
+		res, err := checkStaticIP(tst.subnet, tst.gateway, tst.ip, tst.used)
+		if (err != nil) != tst.err || (err == nil && res != tst.ip) {
+			t.Errorf("failed test %d - expected %s (error %t), but got %s (%v)", i, tst.ip, tst.err, res, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// labelIndex is a memdb index on the Labels field of records, which allows
+// looking up records by the key of a label, as well as by key and value.
+// Unlike memdb.StringMapFieldIndex, records are indexed by key as well, so
+// filters that only require a label to exist can use the index too.
+type labelIndex struct{}
+
+// FromObject will return the index values of all labels of given record.
+func (li *labelIndex) FromObject(obj interface{}) (bool, [][]byte, error) {
+	fv := reflect.Indirect(reflect.ValueOf(obj)).FieldByName("Labels")
+	if !fv.IsValid() {
+		return false, nil, fmt.Errorf("field Labels for %#v is invalid", obj)
+	}
+	lbls, ok := fv.Interface().(map[string]string)
+	if !ok {
+		return false, nil, fmt.Errorf("field Labels is not a map[string]string")
+	}
+	vals := [][]byte{}
+	for k, v := range lbls {
+		if k == "" {
+			continue
+		}
+		vals = append(vals, []byte("k\x00"+k+"\x00"), []byte("v\x00"+k+"\x00"+v+"\x00"))
+	}
+	return len(vals) > 0, vals, nil
+}
+
+// FromArgs will return the index value for given label key, or label key
+// and value.
+func (li *labelIndex) FromArgs(args ...interface{}) ([]byte, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("must provide one or two arguments")
+	}
+	// key only lookups and key/value lookups use a separate prefix, as
+	// lookups on non-unique indexes match all values with given prefix
+	key := "k\x00"
+	if len(args) == 2 {
+		key = "v\x00"
+	}
+	for _, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument must be a string: %#v", arg)
+		}
+		key += s + "\x00"
+	}
+	return []byte(key), nil
+}
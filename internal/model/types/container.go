@@ -3,14 +3,23 @@ package types
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/docker/go-units"
+	"github.com/joyrex2001/kubedock/internal/util/stringid"
 	"github.com/joyrex2001/kubedock/internal/util/tar"
+	"github.com/joyrex2001/kubedock/internal/util/termsize"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog"
@@ -18,53 +27,143 @@ import (
 
 // Container describes the details of a container.
 type Container struct {
-	ID             string
-	ShortID        string
-	Name           string
-	Hostname       string
-	Image          string
-	Labels         map[string]string
-	Entrypoint     []string
-	Cmd            []string
-	Env            []string
-	Binds          []string
-	Mounts         []Mount
-	PreArchives    []PreArchive
-	HostIP         string
-	ExposedPorts   map[string]interface{}
-	ImagePorts     map[string]interface{}
-	HostPorts      map[int]int
-	MappedPorts    map[int]int
-	Networks       map[string]interface{}
-	NetworkAliases []string
-	StopChannels   []chan struct{}
-	AttachChannels []chan struct{}
-	Running        bool
-	Completed      bool
-	Failed         bool
-	Stopped        bool
-	Killed         bool
-	Tty            bool
-	OpenStdin      bool
-	Created        time.Time
-	Finished       time.Time
+	ID              string
+	ShortID         string
+	Name            string
+	Hostname        string
+	Image           string
+	Labels          map[string]string
+	Entrypoint      []string
+	Cmd             []string
+	Env             []string
+	SecretEnv       map[string]string
+	Binds           []string
+	Mounts          []Mount
+	PreArchives     []PreArchive
+	StagedArchives  []PreArchive
+	Checkpoint      Checkpoint
+	HostIP          string
+	ExposedPorts    map[string]interface{}
+	ImagePorts      map[string]interface{}
+	HostPorts       map[int]int
+	MappedPorts     map[int]int
+	Networks        map[string]interface{}
+	IPAddresses     map[string]string
+	StaticIPs       map[string]string
+	NetworkAliases  []string
+	EndpointAliases map[string][]string
+	NetworkOwner    string
+	NetworkPod      string
+	PodName         string
+	HostNetwork     bool
+	Sysctls         map[string]string
+	Ulimits         []ulimit.Limit
+	Devices         []Device
+	Links           map[string]string
+	LinkEnv         []string
+	LinkHosts       map[string][]string
+	Initialized     bool
+	Running         bool
+	Completed       bool
+	Failed          bool
+	Stopped         bool
+	Killed          bool
+	Error           string
+	ExitStatus      int
+	Tty             bool
+	OpenStdin       bool
+	Version         uint64
+	Created         time.Time
+	Started         time.Time
+	IdempotencyKey  string
+	CreateDigest    string
+	Finished        time.Time
+	StartTimings    map[string]time.Duration
+	handles         *handles
 }
 
+// handles contains the runtime handles of a container; the channels of the
+// active port-forwards, logs followers and attach sessions, and the terminal
+// sizes of the tty sessions. These are shared by all copies of a container
+// record (see Clone), so they are not lost when the record is updated.
+type handles struct {
+	lock   sync.Mutex
+	stop   []chan struct{}
+	attach []chan struct{}
+	sizes  termsize.Broadcaster
+}
+
+// handlesLock serializes the lazy creation of the handles of containers.
+var handlesLock sync.Mutex
+
 // PreArchive contains the path and contents of archives (tar) that need to be
-// copied over to the container before it has been started.
+// copied over to the container before it has been started. Staged archives
+// are not kept in memory, but refer to the File on the data volume of the
+// container instead.
 type PreArchive struct {
 	Path    string
 	Archive []byte
+	File    string
+}
+
+// Checkpoint contains the volume folders that were saved during a checkpoint,
+// mapped to the file that contains the archive (tar) of their contents. The
+// archives are not kept in memory, but spooled to disk instead.
+type Checkpoint map[string]string
+
+// Remove will remove the archives of the checkpoint.
+func (cp Checkpoint) Remove() {
+	for _, file := range cp {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			klog.Warningf("error removing checkpoint archive %s: %s", file, err)
+		}
+	}
 }
 
 // Mount contains the details of a mounted volume/binding.
 type Mount struct {
-	Type     string
-	Source   string
-	Target   string
-	ReadOnly bool
+	Type        string
+	Source      string
+	Target      string
+	ReadOnly    bool
+	Propagation string
+}
+
+// VolumeOptions contains the mount options of a volume, as given in the
+// options of a bind (e.g. /src:/dst:ro,Z,rslave), or in a mount.
+type VolumeOptions struct {
+	// Relabel is the SELinux relabel option; z (shared) or Z (private)
+	Relabel string
+	// Propagation is the mount propagation mode (e.g. rslave)
+	Propagation string
+}
+
+// Device contains the details of a device that is passed through to the
+// container, either as a hostPath volume, or as a device plugin resource.
+type Device struct {
+	HostPath      string
+	ContainerPath string
+	Resource      string
 }
 
+const (
+	// PhaseResolve is the start phase in which the pod spec is prepared
+	PhaseResolve = "resolve"
+	// PhaseCreate is the start phase in which the pod is created
+	PhaseCreate = "create"
+	// PhaseScheduled is the start phase until the pod is scheduled on a node
+	PhaseScheduled = "scheduled"
+	// PhasePulled is the start phase until the image is pulled and started
+	PhasePulled = "pulled"
+	// PhaseReady is the start phase until the container is considered ready
+	PhaseReady = "ready"
+	// PhaseForwards is the start phase in which port-forwards are set up
+	PhaseForwards = "forwards"
+)
+
+// StartPhases contains all start phases in chronological order.
+var StartPhases = []string{PhaseResolve, PhaseCreate, PhaseScheduled, PhasePulled, PhaseReady, PhaseForwards}
+
 const (
 	// LabelRequestCPU is the label to be used to specify cpu request/limits
 	LabelRequestCPU = "com.joyrex2001.kubedock.request-cpu"
@@ -81,17 +180,57 @@ const (
 	// LabelRunasUser is the label to be used to enforce a specific user (uid) that
 	// runs inside the container can also be enforced w
 	LabelRunasUser = "com.joyrex2001.kubedock.runas-user"
+	// LabelFSGroup is the label to be used to specify the group (gid) that owns
+	// the volumes of the container
+	LabelFSGroup = "com.joyrex2001.kubedock.fs-group"
 	// LabelNodeSelector is a comma-separated list of key-value pairs for node selection
 	LabelNodeSelector = "com.joyrex2001.kubedock.node-selector"
 	// LabelActiveDeadlineSeconds is the label to be used to specify active deadline in seconds
 	LabelActiveDeadlineSeconds = "com.joyrex2001.kubedock.active-deadline-seconds"
+	// LabelReadiness is the label to be used to configure when a container is
+	// considered started (running, ready or tcp)
+	LabelReadiness = "com.joyrex2001.kubedock.readiness"
+	// LabelSeparateStderr is the label to be used to enable the separation of
+	// stderr from stdout in logs and attach streams
+	LabelSeparateStderr = "com.joyrex2001.kubedock.separate-stderr"
+	// LabelStartTimeout is the label to be used to configure the max time to
+	// wait for a container to start (overrides --timeout)
+	LabelStartTimeout = "com.joyrex2001.kubedock.start-timeout"
+	// LabelRetainOnFailure is the label to be used to keep the pod of a
+	// container that exited non-zero for post-mortem debugging
+	LabelRetainOnFailure = "com.joyrex2001.kubedock.retain-on-failure"
+	// LabelVolumeAccessMode is the label to be used to configure the access
+	// mode of the volume claim of a container (overrides
+	// --archive-helper-access-mode)
+	LabelVolumeAccessMode = "com.joyrex2001.kubedock.volume-access-mode"
+)
+
+const (
+	// LabelComposeProject is the label docker compose uses to specify the
+	// project a container belongs to
+	LabelComposeProject = "com.docker.compose.project"
+	// LabelComposeService is the label docker compose uses to specify the
+	// service a container belongs to
+	LabelComposeService = "com.docker.compose.service"
+)
+
+const (
+	// ReadinessRunning considers a container started as soon as it's running
+	ReadinessRunning = "running"
+	// ReadinessReady considers a container started as soon as the pod is ready
+	ReadinessReady = "ready"
+	// ReadinessTCP considers a container started as soon as all published
+	// ports accept tcp connections
+	ReadinessTCP = "tcp"
 )
 
 // GetEnvVar will return the environment variables of the container
-// as k8s EnvVars.
+// as k8s EnvVars. Secret environment variables refer to a key in a
+// k8s secret, formatted as secret/key; the key defaults to the name
+// of the variable.
 func (co *Container) GetEnvVar() []corev1.EnvVar {
 	env := []corev1.EnvVar{}
-	for _, e := range co.Env {
+	for _, e := range append(co.LinkEnv, co.Env...) {
 		key, value, found := strings.Cut(e, "=")
 		if !found {
 			klog.Errorf("could not parse env %s", e)
@@ -99,28 +238,164 @@ func (co *Container) GetEnvVar() []corev1.EnvVar {
 		}
 		env = append(env, corev1.EnvVar{Name: key, Value: value})
 	}
+	keys := []string{}
+	for key := range co.SecretEnv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, skey, found := strings.Cut(co.SecretEnv[key], "/")
+		if !found {
+			skey = key
+		}
+		env = append(env, corev1.EnvVar{Name: key, ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  skey,
+			},
+		}})
+	}
 	return env
 }
 
+// pullPolicies contains the supported values for the pull policy label.
+var pullPolicies = map[string]corev1.PullPolicy{
+	"default":      corev1.PullIfNotPresent,
+	"notpresent":   corev1.PullIfNotPresent,
+	"ifnotpresent": corev1.PullIfNotPresent,
+	"always":       corev1.PullAlways,
+	"allways":      corev1.PullAlways,
+	"never":        corev1.PullNever,
+}
+
+// PullPolicyAuto is the pull policy value that follows the kubernetes
+// convention of always pulling :latest (or untagged) images, and pulling
+// other images only if not present.
+const PullPolicyAuto = "auto"
+
+// ValidatePullPolicy will return an error if the given pull policy value
+// is not supported.
+func ValidatePullPolicy(p string) error {
+	if _, ok := pullPolicies[strings.ToLower(p)]; ok || strings.ToLower(p) == PullPolicyAuto {
+		return nil
+	}
+	return fmt.Errorf("invalid pull policy: %s", p)
+}
+
+// ValidateReadiness will return an error if the given readiness value is not
+// supported.
+func ValidateReadiness(r string) error {
+	switch strings.ToLower(r) {
+	case ReadinessRunning, ReadinessReady, ReadinessTCP:
+		return nil
+	}
+	return fmt.Errorf("invalid readiness: %s", r)
+}
+
+// GetReadiness will return when this container should be considered started,
+// which is either running (default), ready or tcp.
+func (co *Container) GetReadiness() (string, error) {
+	r := strings.ToLower(co.Labels[LabelReadiness])
+	if r == "" {
+		return ReadinessRunning, nil
+	}
+	if err := ValidateReadiness(r); err != nil {
+		return "", err
+	}
+	return r, nil
+}
+
+// ParseStartTimeout will parse the given start timeout, which is either a
+// duration (e.g. 5m) or a number of seconds. Timeouts are in whole seconds;
+// durations with a fraction of a second are rounded up, and durations of
+// less than a second are rejected.
+func ParseStartTimeout(val string) (time.Duration, error) {
+	if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid start timeout: %s", val)
+	}
+	if d < time.Second {
+		return 0, fmt.Errorf("invalid start timeout: %s, should be at least 1s", val)
+	}
+	return (d + time.Second - 1).Truncate(time.Second), nil
+}
+
+// GetStartTimeout will return the max time to wait for this container to
+// start, which defaults to the given timeout.
+func (co *Container) GetStartTimeout(def time.Duration) (time.Duration, error) {
+	val, ok := co.Labels[LabelStartTimeout]
+	if !ok || val == "" {
+		return def, nil
+	}
+	return ParseStartTimeout(val)
+}
+
+// SetStartTimeout will override the max time to wait for this container to
+// start with given value, after validating it.
+func (co *Container) SetStartTimeout(val string) error {
+	if _, err := ParseStartTimeout(val); err != nil {
+		return err
+	}
+	if co.Labels == nil {
+		co.Labels = map[string]string{}
+	}
+	co.Labels[LabelStartTimeout] = val
+	return nil
+}
+
+// SeparateStderr will return true if the stderr output of this container
+// should be tagged, so it can be separated from stdout. This is not applicable
+// for tty containers, which have a single output stream.
+func (co *Container) SeparateStderr() bool {
+	sep, _ := strconv.ParseBool(co.Labels[LabelSeparateStderr])
+	return sep && !co.Tty
+}
+
+// RetainOnFailure returns true if the pod of this container should be kept
+// when the container failed, as configured with the retain-on-failure label.
+func (co *Container) RetainOnFailure() bool {
+	retain, _ := strconv.ParseBool(co.Labels[LabelRetainOnFailure])
+	return retain
+}
+
 // GetImagePullPolicy will return the image pull policy that should be applied
 // for this container.
 func (co *Container) GetImagePullPolicy() (corev1.PullPolicy, error) {
-	ps := map[string]corev1.PullPolicy{
-		"default":      corev1.PullIfNotPresent,
-		"notpresent":   corev1.PullIfNotPresent,
-		"ifnotpresent": corev1.PullIfNotPresent,
-		"always":       corev1.PullAlways,
-		"allways":      corev1.PullAlways,
-		"never":        corev1.PullNever,
+	return co.GetImagePullPolicyFor(co.Image)
+}
+
+// GetImagePullPolicyFor will return the image pull policy that should be
+// applied for given image within this container's pod (e.g. sidecars).
+func (co *Container) GetImagePullPolicyFor(image string) (corev1.PullPolicy, error) {
+	p := strings.ToLower(co.Labels[LabelPullPolicy])
+	if p == "" {
+		return pullPolicies["default"], nil
 	}
-	p := co.Labels[LabelPullPolicy]
-	if p != "" {
-		if c, ok := ps[strings.ToLower(p)]; ok {
-			return c, nil
+	if p == PullPolicyAuto {
+		if isLatestImage(image) {
+			return corev1.PullAlways, nil
 		}
-		return ps["default"], fmt.Errorf("invalid pull policy: %s", p)
+		return corev1.PullIfNotPresent, nil
+	}
+	if c, ok := pullPolicies[p]; ok {
+		return c, nil
+	}
+	return pullPolicies["default"], fmt.Errorf("invalid pull policy: %s", co.Labels[LabelPullPolicy])
+}
+
+// isLatestImage will return true if given image reference refers to the
+// latest tag, either explicitly or implicitly. Digest references are never
+// considered latest.
+func isLatestImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
 	}
-	return ps["default"], nil
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, found := strings.Cut(name, ":")
+	return !found || tag == "latest"
 }
 
 // GetResourceRequirements will return a k8s request/limits configuration
@@ -220,6 +495,18 @@ func (co *Container) GetServiceAccountName(current string) string {
 	return current
 }
 
+// GetComposeProject will return the docker compose project the container
+// belongs to, or an empty string if not created by docker compose.
+func (co *Container) GetComposeProject() string {
+	return co.Labels[LabelComposeProject]
+}
+
+// GetComposeService will return the docker compose service the container
+// belongs to, or an empty string if not created by docker compose.
+func (co *Container) GetComposeService() string {
+	return co.Labels[LabelComposeService]
+}
+
 // GetActiveDeadlineSeconds will return the active deadline seconds to be used for containers
 // that are deployed.
 func (co *Container) GetActiveDeadlineSeconds() (*int64, error) {
@@ -233,16 +520,113 @@ func (co *Container) GetActiveDeadlineSeconds() (*int64, error) {
 	return nil, nil
 }
 
+// LinkToContainer will configure this container to run in the pod of the
+// given container, sharing its network namespace (container:<id> network
+// mode).
+func (co *Container) LinkToContainer(owner *Container) {
+	co.NetworkOwner = owner.ID
+	co.NetworkPod = owner.GetPodName()
+}
+
+// IsLinked will return true if this container runs in the pod of another
+// container, as configured with the container:<id> network mode.
+func (co *Container) IsLinked() bool {
+	return co.NetworkOwner != ""
+}
+
+// AddLink will add a legacy link (HostConfig.Links) to given container,
+// which will be reachable with given alias.
+func (co *Container) AddLink(linked *Container, alias string) {
+	if co.Links == nil {
+		co.Links = map[string]string{}
+	}
+	co.Links[alias] = linked.ID
+}
+
+// GetLinkAliases will return the aliases of the legacy links of this
+// container in sorted order.
+func (co *Container) GetLinkAliases() []string {
+	aliases := []string{}
+	for alias := range co.Links {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// GetLinkEnv will return the environment variables that docker injects for
+// a legacy link to the given container with given alias, of which the pod
+// is reachable on the given ip.
+func (co *Container) GetLinkEnv(linked *Container, alias, ip string) []string {
+	prefix := strings.ToUpper(strings.ReplaceAll(alias, "-", "_"))
+	env := []string{fmt.Sprintf("%s_NAME=/%s/%s", prefix, co.Name, alias)}
+
+	seen := map[int]bool{}
+	ports := []int{}
+	for _, pp := range append(linked.GetContainerTCPPorts(), linked.GetImageTCPPorts()...) {
+		if !seen[pp] {
+			seen[pp] = true
+			ports = append(ports, pp)
+		}
+	}
+	sort.Ints(ports)
+	for i, pp := range ports {
+		if i == 0 {
+			env = append(env, fmt.Sprintf("%s_PORT=tcp://%s:%d", prefix, ip, pp))
+		}
+		pf := fmt.Sprintf("%s_PORT_%d_TCP", prefix, pp)
+		env = append(env,
+			fmt.Sprintf("%s=tcp://%s:%d", pf, ip, pp),
+			fmt.Sprintf("%s_ADDR=%s", pf, ip),
+			fmt.Sprintf("%s_PORT=%d", pf, pp),
+			fmt.Sprintf("%s_PROTO=tcp", pf))
+	}
+
+	for _, e := range linked.Env {
+		key, value, found := strings.Cut(e, "=")
+		if !found || key == "HOME" || key == "PATH" {
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s_ENV_%s=%s", prefix, key, value))
+	}
+	return env
+}
+
+// GetPodShortID will return the short id of the container that owns the pod
+// in which this container is running.
+func (co *Container) GetPodShortID() string {
+	if co.IsLinked() {
+		return stringid.TruncateID(co.NetworkOwner)
+	}
+	return co.ShortID
+}
+
+// GetContainerName will return the name of the kubernetes container within
+// the pod that runs this container.
+func (co *Container) GetContainerName() string {
+	if co.IsLinked() {
+		return "linked-" + co.ShortID
+	}
+	return "main"
+}
+
 // GetPodName will return a human friendly name that can be used for the
-// container deployments.
+// container deployments. Once the container is deployed, the name of the
+// pod is stored, so it remains the same if the container is renamed.
 func (co *Container) GetPodName() string {
+	if co.IsLinked() {
+		return co.NetworkPod
+	}
+	if co.PodName != "" {
+		return co.PodName
+	}
 	name := co.Name
 	if prefix, ok := co.Labels[LabelNamePrefix]; ok {
 		name = prefix + "-" + co.Name
 	} else {
 		name = "kubedock-" + co.Name
 	}
-	name = strings.ReplaceAll(name, "_", "-")
+	name = regexp.MustCompile("[_:]").ReplaceAllString(name, "-")
 	re := regexp.MustCompile("[^A-Za-z0-9-]")
 	name = re.ReplaceAllString(name, "")
 	if len(name) > 32 {
@@ -258,8 +642,20 @@ func (co *Container) GetPodName() string {
 
 // GetPodSecurityContext will create a security context for the Pod that implements
 // the relevant features of the Docker API. Right now this only covers the ability
-// to specify the numeric user a container should run as.
+// to specify the numeric user a container should run as, and the group that owns
+// the volumes (fsGroup).
 func (co *Container) GetPodSecurityContext(context *corev1.PodSecurityContext) (*corev1.PodSecurityContext, error) {
+	if group := co.Labels[LabelFSGroup]; group != "" {
+		parsed, err := strconv.ParseInt(group, 10, 64)
+		if err != nil {
+			return context, fmt.Errorf("failed to parse fs group %s to Int64", group)
+		}
+		if context == nil {
+			context = &corev1.PodSecurityContext{}
+		}
+		context.FSGroup = &parsed
+	}
+
 	user, ok := co.Labels[LabelRunasUser]
 	if !ok || user == "" {
 		if context == nil || context.RunAsUser == nil {
@@ -288,6 +684,26 @@ func (co *Container) GetPodSecurityContext(context *corev1.PodSecurityContext) (
 	return context, nil
 }
 
+// GetSysctls will return the given sysctls (e.g. as configured in the pod
+// template), merged with the sysctls of the container.
+func (co *Container) GetSysctls(sysctls []corev1.Sysctl) []corev1.Sysctl {
+	res := []corev1.Sysctl{}
+	for _, s := range sysctls {
+		if _, ok := co.Sysctls[s.Name]; !ok {
+			res = append(res, s)
+		}
+	}
+	names := []string{}
+	for name := range co.Sysctls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		res = append(res, corev1.Sysctl{Name: name, Value: co.Sysctls[name]})
+	}
+	return res
+}
+
 // MapPort will map a pod port to a local port.
 func (co *Container) MapPort(pod, local int) {
 	if co.MappedPorts == nil {
@@ -407,6 +823,63 @@ func (co *Container) GetVolumes() map[string]string {
 	return mounts
 }
 
+// GetVolumeOptions will return a map of the mount options of the volumes
+// that should be mounted on the target container. The key is the target
+// location. Options that are not relevant for the mount itself (e.g. ro)
+// are ignored.
+func (co *Container) GetVolumeOptions() map[string]VolumeOptions {
+	opts := map[string]VolumeOptions{}
+	for _, bind := range co.Binds {
+		f := strings.Split(bind, ":")
+		if len(f) < 3 {
+			continue
+		}
+		vo := VolumeOptions{}
+		for _, opt := range strings.Split(f[2], ",") {
+			switch opt {
+			case "z", "Z":
+				vo.Relabel = opt
+			case "private", "rprivate", "shared", "rshared", "slave", "rslave":
+				vo.Propagation = opt
+			}
+		}
+		opts[f[1]] = vo
+	}
+	for _, mount := range co.Mounts {
+		if mount.Propagation != "" {
+			opts[mount.Target] = VolumeOptions{Propagation: mount.Propagation}
+		}
+	}
+	return opts
+}
+
+// GetSELinuxLevel will return the SELinux level that should be used for the
+// pod, so the volumes are relabeled as requested with the z and Z options.
+// A private (Z) label results in a level with categories that are unique for
+// this container; a shared (z) label results in a level without categories.
+// It returns an empty string if no relabeling is requested.
+func (co *Container) GetSELinuxLevel() string {
+	level := ""
+	for _, vo := range co.GetVolumeOptions() {
+		switch vo.Relabel {
+		case "Z":
+			h := fnv.New32a()
+			h.Write([]byte(co.ID))
+			sum := h.Sum32()
+			c1, c2 := sum%1024, (sum/1024)%1023
+			if c2 >= c1 {
+				c2++
+			} else {
+				c1, c2 = c2, c1
+			}
+			return fmt.Sprintf("s0:c%d,c%d", c1, c2)
+		case "z":
+			level = "s0"
+		}
+	}
+	return level
+}
+
 // GetVolumeFolders will return a map of volumes that are pointing to a
 // folder and should be mounted on the target container. The key
 // is the target location, and the value is the local location.
@@ -479,46 +952,130 @@ func (co *Container) HasVolumes() bool {
 	return len(co.Binds) > 0
 }
 
+// HasCheckpoint will return true if the container has been checkpointed.
+func (co *Container) HasCheckpoint() bool {
+	return co.Checkpoint != nil
+}
+
 // HasPreArchives will return true if the container has pre archives configured.
 func (co *Container) HasPreArchives() bool {
 	return len(co.PreArchives) > 0
 }
 
+// HasStagedArchives will return true if the container has archives that
+// should be extracted before the container command is started.
+func (co *Container) HasStagedArchives() bool {
+	return len(co.StagedArchives) > 0
+}
+
+// getHandles will return the runtime handles of the container, and creates
+// them if the container doesn't have them yet.
+func (co *Container) getHandles() *handles {
+	handlesLock.Lock()
+	defer handlesLock.Unlock()
+	if co.handles == nil {
+		co.handles = &handles{}
+	}
+	return co.handles
+}
+
 // AddStopChannel will add channels that should be notified when
 // SignalStop is called.
 func (co *Container) AddStopChannel(stop chan struct{}) {
-	if co.StopChannels == nil {
-		co.StopChannels = []chan struct{}{}
-	}
-	co.StopChannels = append(co.StopChannels, stop)
+	h := co.getHandles()
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.stop = append(h.stop, stop)
 }
 
 // SignalStop will signal all stop channels.
 func (co *Container) SignalStop() {
-	for _, stop := range co.StopChannels {
+	h := co.getHandles()
+	h.lock.Lock()
+	stops := h.stop
+	h.stop = nil
+	h.lock.Unlock()
+	for _, stop := range stops {
 		stop <- struct{}{}
 		close(stop)
 	}
-	co.StopChannels = []chan struct{}{}
 }
 
 // AddAttachChannel will add channels that should be notified when
 // SignalDetach is called.
 func (co *Container) AddAttachChannel(stop chan struct{}) {
-	if co.AttachChannels == nil {
-		co.AttachChannels = []chan struct{}{}
-	}
-	co.AttachChannels = append(co.AttachChannels, stop)
+	h := co.getHandles()
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.attach = append(h.attach, stop)
 }
 
 // SignalDetach will signal all stop channels.
 func (co *Container) SignalDetach() {
 	klog.Infof("Detaching container: %s", co.ID)
-	for _, stop := range co.AttachChannels {
+	h := co.getHandles()
+	h.lock.Lock()
+	stops := h.attach
+	h.attach = nil
+	h.lock.Unlock()
+	for _, stop := range stops {
 		stop <- struct{}{}
 		close(stop)
 	}
-	co.AttachChannels = []chan struct{}{}
+}
+
+// TerminalSizes will return the broadcaster that distributes the terminal
+// sizes of resize requests to the tty sessions of the container.
+func (co *Container) TerminalSizes() *termsize.Broadcaster {
+	return &co.getHandles().sizes
+}
+
+// Clone will return a copy of the container that can be modified without
+// affecting the original; all maps and slices are copied as well. The copy
+// shares the runtime handles (e.g. the stop channels) with the original.
+func (co *Container) Clone() *Container {
+	h := co.getHandles()
+	res := *co
+	res.handles = h
+	res.Labels = maps.Clone(co.Labels)
+	res.Entrypoint = slices.Clone(co.Entrypoint)
+	res.Cmd = slices.Clone(co.Cmd)
+	res.Env = slices.Clone(co.Env)
+	res.SecretEnv = maps.Clone(co.SecretEnv)
+	res.Binds = slices.Clone(co.Binds)
+	res.Mounts = slices.Clone(co.Mounts)
+	res.PreArchives = slices.Clone(co.PreArchives)
+	res.StagedArchives = slices.Clone(co.StagedArchives)
+	res.Checkpoint = maps.Clone(co.Checkpoint)
+	res.ExposedPorts = maps.Clone(co.ExposedPorts)
+	res.ImagePorts = maps.Clone(co.ImagePorts)
+	res.HostPorts = maps.Clone(co.HostPorts)
+	res.MappedPorts = maps.Clone(co.MappedPorts)
+	res.Networks = maps.Clone(co.Networks)
+	res.IPAddresses = maps.Clone(co.IPAddresses)
+	res.StaticIPs = maps.Clone(co.StaticIPs)
+	res.NetworkAliases = slices.Clone(co.NetworkAliases)
+	res.EndpointAliases = cloneListMap(co.EndpointAliases)
+	res.Sysctls = maps.Clone(co.Sysctls)
+	res.Ulimits = slices.Clone(co.Ulimits)
+	res.Devices = slices.Clone(co.Devices)
+	res.Links = maps.Clone(co.Links)
+	res.LinkEnv = slices.Clone(co.LinkEnv)
+	res.LinkHosts = cloneListMap(co.LinkHosts)
+	res.StartTimings = maps.Clone(co.StartTimings)
+	return &res
+}
+
+// cloneListMap will return a copy of given map, including its lists.
+func cloneListMap(in map[string][]string) map[string][]string {
+	if in == nil {
+		return nil
+	}
+	res := make(map[string][]string, len(in))
+	for k, v := range in {
+		res[k] = slices.Clone(v)
+	}
+	return res
 }
 
 // ConnectNetwork will attach a network to the container.
@@ -535,9 +1092,79 @@ func (co *Container) DisconnectNetwork(id string) error {
 		return fmt.Errorf("container is not connected to network %s", id)
 	}
 	delete(co.Networks, id)
+	delete(co.IPAddresses, id)
+	delete(co.StaticIPs, id)
+	co.removeNetworkAliases(id)
 	return nil
 }
 
+// RequestIP will request given (static) ip address for the container in the
+// network with given id, which will be assigned instead of a free address.
+func (co *Container) RequestIP(id, ip string) {
+	if co.StaticIPs == nil {
+		co.StaticIPs = map[string]string{}
+	}
+	co.StaticIPs[id] = ip
+	delete(co.IPAddresses, id)
+}
+
+// AddNetworkAliases will add given aliases to the network aliases of the
+// container, in lower case and ignoring duplicates. If a network id is given,
+// the aliases are registered for that network as well, so they can be
+// removed when the container is disconnected from that network.
+func (co *Container) AddNetworkAliases(id string, aliases []string) {
+	for _, alias := range aliases {
+		alias = strings.ToLower(alias)
+		if alias == co.ShortID {
+			continue
+		}
+		if !slices.Contains(co.NetworkAliases, alias) {
+			co.NetworkAliases = append(co.NetworkAliases, alias)
+		}
+		if id == "" {
+			continue
+		}
+		if co.EndpointAliases == nil {
+			co.EndpointAliases = map[string][]string{}
+		}
+		if !slices.Contains(co.EndpointAliases[id], alias) {
+			co.EndpointAliases[id] = append(co.EndpointAliases[id], alias)
+		}
+	}
+}
+
+// removeNetworkAliases will remove the network aliases that were registered
+// for given network, unless they are registered for another network as well.
+func (co *Container) removeNetworkAliases(id string) {
+	obsolete, ok := co.EndpointAliases[id]
+	if !ok {
+		return
+	}
+	delete(co.EndpointAliases, id)
+	for _, aliases := range co.EndpointAliases {
+		obsolete = slices.DeleteFunc(obsolete, func(alias string) bool {
+			return slices.Contains(aliases, alias)
+		})
+	}
+	co.NetworkAliases = slices.DeleteFunc(co.NetworkAliases, func(alias string) bool {
+		return slices.Contains(obsolete, alias)
+	})
+}
+
+// GetDNSNames will return the names given container can be resolved with
+// in order of precedence; the container name, followed by its network
+// aliases.
+func (co *Container) GetDNSNames() []string {
+	names := []string{}
+	for _, name := range append([]string{co.Name}, co.NetworkAliases...) {
+		name = strings.ToLower(name)
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // Match will match given type with given key value pair.
 func (co *Container) Match(typ string, key string, val string) (bool, error) {
 	if typ == "name" {
@@ -569,6 +1196,28 @@ func (co *Container) nameMatch(key string) (bool, error) {
 	return false, nil
 }
 
+// SetStartTiming will record the duration of given start phase. It modifies
+// the container in place, so it should only be called on a copy of a stored
+// container, which is stored with UpdateContainer afterwards.
+func (co *Container) SetStartTiming(phase string, d time.Duration) {
+	if co.StartTimings == nil {
+		co.StartTimings = map[string]time.Duration{}
+	}
+	if d < 0 {
+		d = 0
+	}
+	co.StartTimings[phase] = d
+}
+
+// GetStartDuration will return the total duration of starting the container.
+func (co *Container) GetStartDuration() time.Duration {
+	total := time.Duration(0)
+	for _, d := range co.StartTimings {
+		total += d
+	}
+	return total
+}
+
 // StateString returns a string that describes the state.
 func (co *Container) StateString() string {
 	if co.Running {
@@ -577,15 +1226,78 @@ func (co *Container) StateString() string {
 	if co.Stopped || co.Killed {
 		return "dead"
 	}
+	if co.Completed || co.HasExited() {
+		return "exited"
+	}
 	if co.Failed {
 		return "dead"
 	}
-	if co.Completed {
-		return "exited"
-	}
 	return "created"
 }
 
+// StatusDescription returns a human readable description of the state of
+// the container, similar to the status column of docker ps.
+func (co *Container) StatusDescription() string {
+	switch co.StateString() {
+	case "running":
+		return "Up " + units.HumanDuration(time.Since(co.Created))
+	case "exited":
+		return fmt.Sprintf("Exited (%d) %s ago", co.ExitCode(), units.HumanDuration(time.Since(co.Finished)))
+	case "dead":
+		return "Dead"
+	}
+	return "Created"
+}
+
+// HasExited will return true if the container terminated by itself, either
+// successfully or with a non-zero exit code.
+func (co *Container) HasExited() bool {
+	return (co.Completed || co.Failed) && !co.Running && !co.Finished.IsZero()
+}
+
+// SetExited will mark the container as terminated with given exit code; a
+// non-zero exit code marks the container as failed.
+func (co *Container) SetExited(code int) {
+	co.Running = false
+	co.Completed = code == 0
+	co.Failed = code != 0
+	co.ExitStatus = code
+	co.Finished = time.Now()
+}
+
+// ExitCode returns the exit code of the container. The observed exit code
+// takes precedence; if the container was killed, or failed without a known
+// exit code, the exit code is an approximation.
+func (co *Container) ExitCode() int {
+	switch {
+	case co.ExitStatus != 0 || co.hasExitStatus():
+		return co.ExitStatus
+	case co.Killed:
+		return 137
+	case co.Failed:
+		return 1
+	}
+	return 0
+}
+
+// hasExitStatus will return true if the termination of the container was
+// observed since it was last started, and its exit status is known.
+func (co *Container) hasExitStatus() bool {
+	return !co.Finished.IsZero() && !co.Finished.Before(co.Started)
+}
+
+// EventAttributes returns the attributes of the container that are added to
+// its events; the labels of the container, its name and its image.
+func (co *Container) EventAttributes() map[string]string {
+	attrs := map[string]string{}
+	for k, v := range co.Labels {
+		attrs[k] = v
+	}
+	attrs["name"] = strings.TrimPrefix(co.Name, "/")
+	attrs["image"] = co.Image
+	return attrs
+}
+
 // StatusString returns a string that describes the status.
 func (co *Container) StatusString() string {
 	if co.Running {
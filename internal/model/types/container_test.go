@@ -1,9 +1,11 @@
 package types
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -48,6 +50,19 @@ func TestGetEnvVar(t *testing.T) {
 				{Name: "MULTIPLE_EQUALS", Value: "abc123==aa=bb=cc=="},
 			},
 		},
+		{
+			in: &Container{
+				Env:       []string{"rc749=Usas"},
+				SecretEnv: map[string]string{"TOKEN": "msx", "PASSWORD": "msx/pwd"},
+			},
+			out: []corev1.EnvVar{
+				{Name: "rc749", Value: "Usas"},
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "msx"}, Key: "pwd"}}},
+				{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "msx"}, Key: "TOKEN"}}},
+			},
+		},
 	}
 	for i, tst := range tests {
 		res := tst.in.GetEnvVar()
@@ -315,6 +330,41 @@ func TestGetImagePullPolicy(t *testing.T) {
 			policy: corev1.PullIfNotPresent,
 			err:    true,
 		},
+		{ // 3
+			in: &Container{Image: "redis", Labels: map[string]string{
+				"com.joyrex2001.kubedock.pull-policy": "auto",
+			}},
+			policy: corev1.PullAlways,
+			err:    false,
+		},
+		{ // 4
+			in: &Container{Image: "registry:5000/redis:latest", Labels: map[string]string{
+				"com.joyrex2001.kubedock.pull-policy": "auto",
+			}},
+			policy: corev1.PullAlways,
+			err:    false,
+		},
+		{ // 5
+			in: &Container{Image: "registry:5000/redis:7", Labels: map[string]string{
+				"com.joyrex2001.kubedock.pull-policy": "Auto",
+			}},
+			policy: corev1.PullIfNotPresent,
+			err:    false,
+		},
+		{ // 6
+			in: &Container{Image: "redis@sha256:0123", Labels: map[string]string{
+				"com.joyrex2001.kubedock.pull-policy": "auto",
+			}},
+			policy: corev1.PullIfNotPresent,
+			err:    false,
+		},
+		{ // 7
+			in: &Container{Labels: map[string]string{
+				"com.joyrex2001.kubedock.pull-policy": "IfNotPresent",
+			}},
+			policy: corev1.PullIfNotPresent,
+			err:    false,
+		},
 	}
 	for i, tst := range tests {
 		res, err := tst.in.GetImagePullPolicy()
@@ -411,6 +461,14 @@ func TestGetPodName(t *testing.T) {
 			}},
 			name: "space-mycontainer-1234",
 		},
+		{ // 7
+			in:   &Container{ShortID: "1234", Name: "app:v1", Labels: map[string]string{}},
+			name: "kubedock-app-v1-1234",
+		},
+		{ // 8
+			in:   &Container{ShortID: "1234", Name: "renamed", PodName: "kubedock-original-1234", Labels: map[string]string{}},
+			name: "kubedock-original-1234",
+		},
 	}
 	for i, tst := range tests {
 		name := tst.in.GetPodName()
@@ -499,6 +557,29 @@ func TestGetRunasUser(t *testing.T) {
 			outsc: corev1.PodSecurityContext{RunAsUser: makeIntPointer(0)},
 			err:   false,
 		},
+		{ // 10
+			in: &Container{Labels: map[string]string{
+				"com.joyrex2001.kubedock.fs-group": "1000",
+			}},
+			outsc: corev1.PodSecurityContext{FSGroup: makeIntPointer(1000)},
+			err:   false,
+		},
+		{ // 11
+			in: &Container{Labels: map[string]string{
+				"com.joyrex2001.kubedock.runas-user": "999",
+				"com.joyrex2001.kubedock.fs-group":   "1000",
+			}},
+			insc:  &corev1.PodSecurityContext{FSGroup: makeIntPointer(500)},
+			outsc: corev1.PodSecurityContext{RunAsUser: makeIntPointer(999), FSGroup: makeIntPointer(1000)},
+			err:   false,
+		},
+		{ // 12
+			in: &Container{Labels: map[string]string{
+				"com.joyrex2001.kubedock.fs-group": "wheel",
+			}},
+			outsc: corev1.PodSecurityContext{},
+			err:   true,
+		},
 	}
 	for i, tst := range tests {
 		res, err := tst.in.GetPodSecurityContext(tst.insc)
@@ -517,6 +598,9 @@ func TestGetRunasUser(t *testing.T) {
 		if res != nil && res.RunAsUser != nil && tst.outsc.RunAsUser != nil && *res.RunAsUser != *tst.outsc.RunAsUser {
 			t.Errorf("failed test %d - expected %d, but got %d", i, *tst.outsc.RunAsUser, *res.RunAsUser)
 		}
+		if !tst.err && tst.outsc.FSGroup != nil && (res == nil || !reflect.DeepEqual(res.FSGroup, tst.outsc.FSGroup)) {
+			t.Errorf("failed test %d - expected fs group %d, but got %v", i, *tst.outsc.FSGroup, res)
+		}
 	}
 }
 
@@ -686,7 +770,7 @@ func TestStop(t *testing.T) {
 	if res != 1 {
 		t.Errorf("failed stop channels")
 	}
-	if len(tainr.StopChannels) != 0 {
+	if len(tainr.handles.stop) != 0 {
 		t.Errorf("expected stop channels to be erased")
 	}
 }
@@ -710,7 +794,7 @@ func TestDetach(t *testing.T) {
 	if res != 1 {
 		t.Errorf("failed attach channels")
 	}
-	if len(tainr.AttachChannels) != 0 {
+	if len(tainr.handles.attach) != 0 {
 		t.Errorf("expected attach channels to be erased")
 	}
 }
@@ -784,6 +868,41 @@ func TestVolumes(t *testing.T) {
 	}
 }
 
+func TestGetVolumeOptions(t *testing.T) {
+	tests := []struct {
+		in    *Container
+		opts  map[string]VolumeOptions
+		level string
+	}{
+		{ // 0
+			in:   &Container{ID: "tb303", Binds: []string{"/src:/dst", "/src:/ro:ro"}},
+			opts: map[string]VolumeOptions{"/ro": {}},
+		},
+		{ // 1
+			in:    &Container{ID: "tb303", Binds: []string{"/src:/dst:ro,z,rslave"}},
+			opts:  map[string]VolumeOptions{"/dst": {Relabel: "z", Propagation: "rslave"}},
+			level: "s0",
+		},
+		{ // 2
+			in:    &Container{ID: "tb303", Binds: []string{"/src:/dst:z", "/src:/private:Z"}},
+			opts:  map[string]VolumeOptions{"/dst": {Relabel: "z"}, "/private": {Relabel: "Z"}},
+			level: "s0:c223,c569",
+		},
+		{ // 3
+			in:   &Container{ID: "tb303", Mounts: []Mount{{Type: "bind", Source: "/src", Target: "/dst", Propagation: "private"}, {Target: "/none"}}},
+			opts: map[string]VolumeOptions{"/dst": {Propagation: "private"}},
+		},
+	}
+	for i, tst := range tests {
+		if opts := tst.in.GetVolumeOptions(); !reflect.DeepEqual(opts, tst.opts) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.opts, opts)
+		}
+		if level := tst.in.GetSELinuxLevel(); level != tst.level {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.level, level)
+		}
+	}
+}
+
 func TestConnectNetwork(t *testing.T) {
 	var err error
 	in := &Container{}
@@ -811,6 +930,50 @@ func TestConnectNetwork(t *testing.T) {
 	}
 }
 
+func TestNetworkAliases(t *testing.T) {
+	in := &Container{ShortID: "abc123", NetworkAliases: []string{"static"}}
+	in.ConnectNetwork("front")
+	in.ConnectNetwork("back")
+	in.AddNetworkAliases("front", []string{"Web", "shared", "abc123"})
+	in.AddNetworkAliases("back", []string{"db", "shared"})
+	exp := []string{"static", "web", "shared", "db"}
+	if !reflect.DeepEqual(in.NetworkAliases, exp) {
+		t.Errorf("failed add test - expected %v, but got %v", exp, in.NetworkAliases)
+	}
+
+	tests := []struct {
+		id  string
+		out []string
+	}{
+		{id: "front", out: []string{"static", "shared", "db"}},
+		{id: "back", out: []string{"static"}},
+	}
+	for i, tst := range tests {
+		if err := in.DisconnectNetwork(tst.id); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if !reflect.DeepEqual(in.NetworkAliases, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, in.NetworkAliases)
+		}
+	}
+}
+
+func TestGetDNSNames(t *testing.T) {
+	tests := []struct {
+		in  *Container
+		out []string
+	}{
+		{in: &Container{Name: "db", NetworkAliases: []string{"database", "db"}}, out: []string{"db", "database"}},
+		{in: &Container{NetworkAliases: []string{"web"}}, out: []string{"web"}},
+		{in: &Container{Name: "Web"}, out: []string{"web"}},
+	}
+	for i, tst := range tests {
+		if res := tst.in.GetDNSNames(); !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
 func TestMatch(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -949,3 +1112,215 @@ func TestGetActiveDeadlineSeconds(t *testing.T) {
 		}
 	}
 }
+
+func TestStartTimings(t *testing.T) {
+	tests := []struct {
+		timings map[string]time.Duration
+		total   time.Duration
+	}{
+		{timings: map[string]time.Duration{}, total: 0},
+		{timings: map[string]time.Duration{PhaseCreate: time.Second, PhaseReady: 2 * time.Second}, total: 3 * time.Second},
+		{timings: map[string]time.Duration{PhaseCreate: time.Second, PhaseScheduled: -time.Second}, total: time.Second},
+	}
+	for i, tst := range tests {
+		tainr := &Container{}
+		for phase, d := range tst.timings {
+			tainr.SetStartTiming(phase, d)
+		}
+		if res := tainr.GetStartDuration(); res != tst.total {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.total, res)
+		}
+	}
+}
+
+func TestGetReadiness(t *testing.T) {
+	tests := []struct {
+		in  *Container
+		out string
+		err bool
+	}{
+		{in: &Container{}, out: ReadinessRunning},
+		{in: &Container{Labels: map[string]string{LabelReadiness: "Ready"}}, out: ReadinessReady},
+		{in: &Container{Labels: map[string]string{LabelReadiness: "tcp"}}, out: ReadinessTCP},
+		{in: &Container{Labels: map[string]string{LabelReadiness: "healthy"}}, err: true},
+	}
+	for i, tst := range tests {
+		res, err := tst.in.GetReadiness()
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected error, but succeeded without error", i)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		in  *Container
+		out int
+	}{
+		{in: &Container{Running: true}, out: 0},
+		{in: &Container{Completed: true}, out: 0},
+		{in: &Container{Failed: true}, out: 1},
+		{in: &Container{Killed: true, Stopped: true}, out: 137},
+		{in: &Container{Failed: true, ExitStatus: 42}, out: 42},
+		{in: &Container{Killed: true, Failed: true, ExitStatus: 3, Finished: time.Now()}, out: 3},
+		{in: &Container{Killed: true, Finished: time.Now()}, out: 0},
+		{in: &Container{Killed: true, Finished: time.Now().Add(-time.Hour), Started: time.Now()}, out: 137},
+	}
+	for i, tst := range tests {
+		if res := tst.in.ExitCode(); res != tst.out {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.out, res)
+		}
+	}
+}
+
+func TestSetExited(t *testing.T) {
+	tests := []struct {
+		code      int
+		state     string
+		completed bool
+		failed    bool
+	}{
+		{code: 0, state: "exited", completed: true},
+		{code: 3, state: "exited", failed: true},
+	}
+	for i, tst := range tests {
+		tainr := &Container{Running: true}
+		tainr.SetExited(tst.code)
+		if tainr.StateString() != tst.state || tainr.ExitCode() != tst.code || tainr.Completed != tst.completed || tainr.Failed != tst.failed || tainr.Running {
+			t.Errorf("failed test %d - expected %s (%d), but got %s (%d)", i, tst.state, tst.code, tainr.StateString(), tainr.ExitCode())
+		}
+		if !strings.HasPrefix(tainr.StatusDescription(), fmt.Sprintf("Exited (%d)", tst.code)) {
+			t.Errorf("failed test %d - unexpected status description %s", i, tainr.StatusDescription())
+		}
+	}
+	if res := (&Container{Failed: true}).StateString(); res != "dead" {
+		t.Errorf("failed - expected a failed start to be dead, but got %s", res)
+	}
+}
+
+func TestLinkToContainer(t *testing.T) {
+	owner := &Container{ID: "0123456789abcdef", ShortID: "0123456789ab", Name: "db"}
+	tainr := &Container{ID: "fedcba9876543210", ShortID: "fedcba987654", Name: "sidecar"}
+	if tainr.IsLinked() {
+		t.Errorf("failed - expected container not to be linked")
+	}
+	if res := tainr.GetContainerName(); res != "main" {
+		t.Errorf("failed - expected main, but got %s", res)
+	}
+	tainr.LinkToContainer(owner)
+	if !tainr.IsLinked() {
+		t.Errorf("failed - expected container to be linked")
+	}
+	if res := tainr.GetPodName(); res != owner.GetPodName() {
+		t.Errorf("failed - expected pod %s, but got %s", owner.GetPodName(), res)
+	}
+	if res := tainr.GetPodShortID(); res != owner.ShortID {
+		t.Errorf("failed - expected short id %s, but got %s", owner.ShortID, res)
+	}
+	if res := tainr.GetContainerName(); res != "linked-fedcba987654" {
+		t.Errorf("failed - expected linked-fedcba987654, but got %s", res)
+	}
+}
+
+func TestGetLinkEnv(t *testing.T) {
+	tests := []struct {
+		linked *Container
+		alias  string
+		out    []string
+	}{
+		{
+			linked: &Container{ID: "db01", Name: "db"},
+			alias:  "db",
+			out:    []string{"DB_NAME=/web/db"},
+		},
+		{
+			linked: &Container{
+				ID:           "db01",
+				Name:         "postgres",
+				Env:          []string{"PATH=/bin", "POSTGRES_USER=test"},
+				ExposedPorts: map[string]interface{}{"5432/tcp": 0},
+				ImagePorts:   map[string]interface{}{"5432/tcp": 0, "80/tcp": 0},
+			},
+			alias: "my-db",
+			out: []string{
+				"MY_DB_NAME=/web/my-db",
+				"MY_DB_PORT=tcp://10.0.0.5:80",
+				"MY_DB_PORT_80_TCP=tcp://10.0.0.5:80",
+				"MY_DB_PORT_80_TCP_ADDR=10.0.0.5",
+				"MY_DB_PORT_80_TCP_PORT=80",
+				"MY_DB_PORT_80_TCP_PROTO=tcp",
+				"MY_DB_PORT_5432_TCP=tcp://10.0.0.5:5432",
+				"MY_DB_PORT_5432_TCP_ADDR=10.0.0.5",
+				"MY_DB_PORT_5432_TCP_PORT=5432",
+				"MY_DB_PORT_5432_TCP_PROTO=tcp",
+				"MY_DB_ENV_POSTGRES_USER=test",
+			},
+		},
+	}
+	for i, tst := range tests {
+		tainr := &Container{ID: "web01", Name: "web"}
+		tainr.AddLink(tst.linked, tst.alias)
+		if res := tainr.Links[tst.alias]; res != tst.linked.ID {
+			t.Errorf("failed test %d - expected link to %s, but got %s", i, tst.linked.ID, res)
+		}
+		if res := tainr.GetLinkEnv(tst.linked, tst.alias, "10.0.0.5"); !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestGetSysctls(t *testing.T) {
+	tainr := &Container{Sysctls: map[string]string{"net.core.somaxconn": "1024", "kernel.shm_rmid_forced": "1"}}
+	in := []corev1.Sysctl{{Name: "net.core.somaxconn", Value: "128"}, {Name: "net.ipv4.tcp_syncookies", Value: "1"}}
+	out := []corev1.Sysctl{
+		{Name: "net.ipv4.tcp_syncookies", Value: "1"},
+		{Name: "kernel.shm_rmid_forced", Value: "1"},
+		{Name: "net.core.somaxconn", Value: "1024"},
+	}
+	if res := tainr.GetSysctls(in); !reflect.DeepEqual(res, out) {
+		t.Errorf("failed - expected %v, but got %v", out, res)
+	}
+}
+
+func TestGetStartTimeout(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		out    time.Duration
+		err    bool
+	}{
+		{labels: nil, out: time.Minute},
+		{labels: map[string]string{LabelStartTimeout: "300"}, out: 5 * time.Minute},
+		{labels: map[string]string{LabelStartTimeout: "10m"}, out: 10 * time.Minute},
+		{labels: map[string]string{LabelStartTimeout: "1500ms"}, out: 2 * time.Second},
+		{labels: map[string]string{LabelStartTimeout: "500ms"}, err: true},
+		{labels: map[string]string{LabelStartTimeout: "-1"}, err: true},
+		{labels: map[string]string{LabelStartTimeout: "soon"}, err: true},
+	}
+	for i, tst := range tests {
+		tainr := &Container{Labels: tst.labels}
+		res, err := tainr.GetStartTimeout(time.Minute)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error %v", i, err)
+		}
+		if !tst.err && res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+
+	tainr := &Container{}
+	if err := tainr.SetStartTimeout("never"); err == nil {
+		t.Errorf("failed - expected error for invalid timeout")
+	}
+	if err := tainr.SetStartTimeout("90s"); err != nil {
+		t.Errorf("failed - unexpected error %s", err)
+	}
+	if res, _ := tainr.GetStartTimeout(time.Minute); res != 90*time.Second {
+		t.Errorf("failed - expected 90s, but got %s", res)
+	}
+}
@@ -2,17 +2,21 @@ package types
 
 import (
 	"time"
+
+	"github.com/joyrex2001/kubedock/internal/util/termsize"
 )
 
 // Exec describes the details of an execute command.
 type Exec struct {
-	ID          string
-	ContainerID string
-	Cmd         []string
-	TTY         bool
-	Stdin       bool
-	Stdout      bool
-	Stderr      bool
-	ExitCode    int
-	Created     time.Time
+	ID            string
+	ContainerID   string
+	Cmd           []string
+	TTY           bool
+	Stdin         bool
+	Stdout        bool
+	Stderr        bool
+	DetachKeys    string
+	TerminalSizes termsize.Broadcaster
+	ExitCode      int
+	Created       time.Time
 }
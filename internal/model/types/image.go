@@ -10,5 +10,8 @@ type Image struct {
 	ShortID      string
 	Name         string
 	ExposedPorts map[string]struct{}
+	Env          []string
+	Digest       string
+	Size         int64
 	Created      time.Time
 }
@@ -1,7 +1,9 @@
 package types
 
 import (
+	"net"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -10,6 +12,8 @@ type Network struct {
 	ID      string
 	ShortID string
 	Name    string
+	Subnet  string
+	Gateway string
 	Labels  map[string]string
 	Created time.Time
 }
@@ -19,19 +23,52 @@ func (nw *Network) IsPredefined() bool {
 	return nw.Name == "bridge" || nw.Name == "null" || nw.Name == "host"
 }
 
+// HasAddresses will return if containers get an ip address in this network.
+func (nw *Network) HasAddresses() bool {
+	return nw.Name != "null" && nw.Name != "host"
+}
+
+// GetGateway will return the gateway of the network, which is the first
+// address in the subnet of the network, unless configured otherwise.
+func (nw *Network) GetGateway() string {
+	if nw.Gateway != "" {
+		return nw.Gateway
+	}
+	_, ipnet, err := net.ParseCIDR(nw.Subnet)
+	if err != nil {
+		return ""
+	}
+	ip := ipnet.IP.To4()
+	if ip == nil {
+		return ""
+	}
+	ip[3]++
+	return ip.String()
+}
+
 // Match will match given type with given key value pair.
 func (nw *Network) Match(typ string, key string, val string) (bool, error) {
-	if typ == "name" {
+	switch typ {
+	case "name":
 		return nw.nameMatch(key)
+	case "id":
+		return strings.HasPrefix(nw.ID, key), nil
+	case "driver":
+		return key == "bridge", nil
+	case "type":
+		if nw.IsPredefined() {
+			return key == "builtin", nil
+		}
+		return key == "custom", nil
+	case "label":
+		v, ok := nw.Labels[key]
+		if !ok {
+			return false, nil
+		}
+		// a label filter without value only requires the label to exist
+		return val == "" || v == val, nil
 	}
-	if typ != "label" {
-		return true, nil
-	}
-	v, ok := nw.Labels[key]
-	if !ok {
-		return false, nil
-	}
-	return v == val, nil
+	return true, nil
 }
 
 func (nw *Network) nameMatch(key string) (bool, error) {
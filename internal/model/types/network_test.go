@@ -0,0 +1,42 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestNetworkMatch(t *testing.T) {
+	netw := &Network{
+		ID:     "a8f9c8e1b3d2",
+		Name:   "myproject_default",
+		Labels: map[string]string{"com.docker.compose.project": "myproject"},
+	}
+	tests := []struct {
+		typ string
+		key string
+		val string
+		out bool
+	}{
+		{typ: "name", key: "myproject_default", out: true},
+		{typ: "name", key: "other", out: false},
+		{typ: "id", key: "a8f9", out: true},
+		{typ: "id", key: "b8f9", out: false},
+		{typ: "label", key: "com.docker.compose.project", val: "myproject", out: true},
+		{typ: "label", key: "com.docker.compose.project", val: "other", out: false},
+		{typ: "label", key: "com.docker.compose.project", out: true},
+		{typ: "label", key: "com.docker.compose.network", out: false},
+		{typ: "driver", key: "bridge", out: true},
+		{typ: "driver", key: "overlay", out: false},
+		{typ: "type", key: "custom", out: true},
+		{typ: "type", key: "builtin", out: false},
+		{typ: "scope", key: "local", out: true},
+	}
+	for i, tst := range tests {
+		res, err := netw.Match(tst.typ, tst.key, tst.val)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %t, but got %t", i, tst.out, res)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+// Service describes the details of a swarm service, which is deployed as a
+// kubernetes deployment.
+type Service struct {
+	ID         string
+	ShortID    string
+	Name       string
+	Image      string
+	Labels     map[string]string
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	Replicas   int
+	Version    uint64
+	Created    time.Time
+	Updated    time.Time
+}
+
+// GetDeploymentName will return the name of the deployment of this service.
+func (sv *Service) GetDeploymentName() string {
+	return "kubedock-svc-" + sv.ShortID
+}
+
+// GetEnvVar will return the environment variables of the service in a
+// kubernetes compatible format.
+func (sv *Service) GetEnvVar() []corev1.EnvVar {
+	env := []corev1.EnvVar{}
+	for _, e := range sv.Env {
+		key, value, found := strings.Cut(e, "=")
+		if !found {
+			klog.Errorf("could not parse env %s", e)
+			continue
+		}
+		env = append(env, corev1.EnvVar{Name: key, Value: value})
+	}
+	return env
+}
+
+// Match will match given type with given key value pair.
+func (sv *Service) Match(typ string, key string, val string) (bool, error) {
+	switch typ {
+	case "name":
+		return strings.HasPrefix(sv.Name, key), nil
+	case "id":
+		return strings.HasPrefix(sv.ID, key), nil
+	case "mode":
+		return key == "replicated", nil
+	case "label":
+		v, ok := sv.Labels[key]
+		if !ok {
+			return false, nil
+		}
+		return val == "" || v == val, nil
+	}
+	return true, nil
+}
@@ -1,11 +1,24 @@
 package reaper
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
 )
 
+// deleteRetries is the number of attempts to delete the kubernetes resources
+// of a container before giving up until the next reaper run.
+var deleteRetries = 3
+
+// deleteRetryDelay is the delay between attempts to delete the kubernetes
+// resources of a container.
+var deleteRetryDelay = time.Second
+
 // CleanContainers will clean all lingering containers that are
 // older than the configured keepMax duration, and stored locally
 // in the in memory database.
@@ -16,20 +29,76 @@ func (in *Reaper) CleanContainers() error {
 	}
 	for _, tainr := range tainrs {
 		if tainr.Created.Before(time.Now().Add(-in.keepMax)) {
-			klog.V(3).Infof("deleting container: %s", tainr.ID)
-			if err := in.kub.DeleteContainer(tainr); err != nil {
-				// inform only, if deleting somehow failed, the
-				// CleanContainersKubernetes will pick it up anyways
-				klog.Warningf("error deleting deployment: %s", err)
-			}
-			if err := in.db.DeleteContainer(tainr); err != nil {
-				return err
+			if err := in.reapContainer(tainr); err != nil {
+				// inform only, the container is retained in the database
+				// and will be picked up again at the next run
+				klog.Warningf("error reaping container %s: %s", tainr.ID, err)
 			}
 		}
 	}
 	return nil
 }
 
+// reapContainer will remove given container and all its dependent resources
+// in an ordered way. First the attached streams and port-forwards are
+// stopped, then the kubernetes resources (pod, services and configmaps) are
+// deleted (unless retained because of retain-on-failure), and only if that succeeded, the container is removed from the
+// database and the die and destroy events are published. If the kubernetes
+// resources could not be deleted, the container is kept in the database, so
+// the next run will retry instead of leaving dangling resources behind.
+func (in *Reaper) reapContainer(tainr *types.Container) error {
+	klog.V(3).Infof("deleting container: %s", tainr.ID)
+	tainr.SignalDetach()
+	tainr.SignalStop()
+
+	retained, err := in.kub.RetainContainer(tainr)
+	if err != nil {
+		klog.Warningf("error retaining container %s: %s", tainr.ID, err)
+	}
+	for i := 0; !retained && i < deleteRetries; i++ {
+		if i > 0 {
+			time.Sleep(deleteRetryDelay)
+		}
+		if err = in.kub.DeleteContainer(tainr); err == nil {
+			break
+		}
+		klog.V(3).Infof("error deleting container %s (attempt %d): %s", tainr.ID, i+1, err)
+	}
+	if !retained && err != nil {
+		return fmt.Errorf("failed deleting kubernetes resources: %w", err)
+	}
+	if err := in.kub.DeleteContainerVolumes(tainr); err != nil {
+		klog.Warningf("error deleting volumes of container %s: %s", tainr.ID, err)
+	}
+
+	if err := in.db.DeleteContainer(tainr); err != nil {
+		return err
+	}
+
+	evts := events.New()
+	if !tainr.Stopped && !tainr.Killed {
+		attrs := tainr.EventAttributes()
+		attrs["exitCode"] = strconv.Itoa(tainr.ExitCode())
+		evts.PublishWithAttributes(tainr.ID, events.Container, events.Die, attrs)
+	}
+	evts.PublishWithAttributes(tainr.ID, events.Container, events.Destroy, tainr.EventAttributes())
+	return nil
+}
+
+// CleanForwards will close all port-forwards and reverse proxies of containers
+// that are no longer present in the in memory database, or that have been
+// idle longer than the configured forwardIdle duration.
+func (in *Reaper) CleanForwards() error {
+	tainrs, err := in.db.GetContainers()
+	if err != nil {
+		return err
+	}
+	if n := in.kub.CleanForwards(tainrs, in.forwardIdle); n > 0 {
+		klog.V(2).Infof("closed %d stale forwards", n)
+	}
+	return nil
+}
+
 // CleanContainersKubernetes will clean all lingering containers
 // that are older than the configured keepMax duration, and stored
 // not stored in the local in memory database.
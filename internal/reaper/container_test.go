@@ -1,6 +1,7 @@
 package reaper
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -8,7 +9,11 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model"
 	"github.com/joyrex2001/kubedock/internal/model/types"
+	kfake "github.com/joyrex2001/kubedock/pkg/backend/fake"
 )
 
 func TestCleanContainers(t *testing.T) {
@@ -44,3 +49,50 @@ func TestCleanContainers(t *testing.T) {
 		}
 	}
 }
+
+func TestReapContainer(t *testing.T) {
+	deleteRetryDelay = 0
+	kub := kfake.New()
+	db, _ := model.New()
+	rp := &Reaper{db: db, kub: adapter.Internal(kub), keepMax: 0}
+	tests := []struct {
+		err     error
+		actions []string
+		keep    bool
+	}{
+		{err: fmt.Errorf("failed"), keep: true},
+		{actions: []string{events.Die, events.Destroy}},
+	}
+	el, id := events.New().Subscribe()
+	defer events.New().Unsubscribe(id)
+	msgs := make(chan events.Message, 10)
+	go func() {
+		for msg := range el {
+			msgs <- msg
+		}
+	}()
+	for i, tst := range tests {
+		kub.DeleteError = tst.err
+		tainr := &types.Container{Name: "reaped", Image: "alpine"}
+		if err := db.SaveContainer(tainr); err != nil {
+			t.Fatalf("failed test %d - unexpected error: %s", i, err)
+		}
+		err := rp.reapContainer(tainr)
+		if (err != nil) != tst.keep {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+		_, err = db.GetContainer(tainr.ID)
+		if (err == nil) != tst.keep {
+			t.Errorf("failed test %d - expected kept %t, but got %t", i, tst.keep, err == nil)
+		}
+		for _, act := range tst.actions {
+			msg := <-msgs
+			if msg.ID != tainr.ID || msg.Action != act || msg.Attributes["name"] != "reaped" {
+				t.Errorf("failed test %d - expected %s, but got %s", i, act, msg.Action)
+			}
+		}
+		if tst.keep {
+			db.DeleteContainer(tainr)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package reaper
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -8,14 +9,18 @@ import (
 
 	"github.com/joyrex2001/kubedock/internal/backend"
 	"github.com/joyrex2001/kubedock/internal/model"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
 )
 
 // Reaper is the object handles reaping of resources.
 type Reaper struct {
-	db      *model.Database
-	keepMax time.Duration
-	kub     backend.Backend
-	quit    chan struct{}
+	db          *model.Database
+	keepMax     time.Duration
+	forwardIdle time.Duration
+	kub         backend.Backend
+	uploads     []*httputil.Uploads
+	quit        chan struct{}
+	lock        sync.Mutex
 }
 
 var instance *Reaper
@@ -27,6 +32,9 @@ type Config struct {
 	KeepMax time.Duration
 	// Backend is the kubedock backend object.
 	Backend backend.Backend
+	// ForwardIdle is the maximum time a port-forward or reverse proxy can be
+	// idle before it is closed (0 disables closing idle forwards).
+	ForwardIdle time.Duration
 }
 
 // New will create return the singleton Reaper instance.
@@ -39,6 +47,7 @@ func New(cfg Config) (*Reaper, error) {
 		instance.db = db
 		instance.kub = cfg.Backend
 		instance.keepMax = cfg.KeepMax
+		instance.forwardIdle = cfg.ForwardIdle
 	})
 	return instance, err
 }
@@ -54,6 +63,30 @@ func (in *Reaper) Stop() {
 	in.quit <- struct{}{}
 }
 
+// Reap will run all cleaners of the running reaper immediately, instead of
+// waiting for the next interval.
+func Reap() error {
+	if instance == nil {
+		return fmt.Errorf("reaper is not running")
+	}
+	klog.V(2).Info("start cleaning lingering objects on request...")
+	instance.clean()
+	klog.V(2).Info("finished cleaning lingering objects...")
+	return nil
+}
+
+// AddUploads will register given uploads with the running reaper, so
+// abandoned uploads are removed at every interval.
+func AddUploads(uploads *httputil.Uploads) error {
+	if instance == nil {
+		return fmt.Errorf("reaper is not running")
+	}
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+	instance.uploads = append(instance.uploads, uploads)
+	return nil
+}
+
 // runloop will reap all lingering resources at a steady interval.
 func (in *Reaper) runloop() {
 	go func() {
@@ -73,13 +106,24 @@ func (in *Reaper) runloop() {
 
 // clean will run all cleaners.
 func (in *Reaper) clean() {
+	in.lock.Lock()
+	defer in.lock.Unlock()
 	if err := in.CleanExecs(); err != nil {
 		klog.Errorf("error cleaning execs: %s", err)
 	}
 	if err := in.CleanContainers(); err != nil {
 		klog.Errorf("error cleaning containers: %s", err)
 	}
+	if err := in.CleanServices(); err != nil {
+		klog.Errorf("error cleaning services: %s", err)
+	}
 	if err := in.CleanContainersKubernetes(); err != nil {
 		klog.Errorf("error cleaning k8s containers: %s", err)
 	}
+	if err := in.CleanForwards(); err != nil {
+		klog.Errorf("error cleaning forwards: %s", err)
+	}
+	for _, uploads := range in.uploads {
+		uploads.Clean()
+	}
 }
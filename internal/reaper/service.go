@@ -0,0 +1,31 @@
+package reaper
+
+import (
+	"time"
+
+	"k8s.io/klog"
+)
+
+// CleanServices will clean all lingering services that are older than the
+// configured keepMax duration, and stored locally in the in memory database.
+// If the deployment of a service could not be deleted, the service is kept
+// in the database and will be picked up again at the next run.
+func (in *Reaper) CleanServices() error {
+	svcs, err := in.db.GetServices()
+	if err != nil {
+		return err
+	}
+	for _, svc := range svcs {
+		if svc.Created.Before(time.Now().Add(-in.keepMax)) {
+			klog.V(3).Infof("deleting service: %s", svc.ID)
+			if err := in.kub.DeleteService(svc); err != nil {
+				klog.Warningf("error deleting service %s: %s", svc.ID, err)
+				continue
+			}
+			if err := in.db.DeleteService(svc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
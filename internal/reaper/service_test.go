@@ -0,0 +1,38 @@
+package reaper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/model"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	kfake "github.com/joyrex2001/kubedock/pkg/backend/fake"
+)
+
+func TestCleanServices(t *testing.T) {
+	kub := adapter.Internal(kfake.New())
+	db, _ := model.New()
+	rp := &Reaper{db: db, kub: kub, keepMax: 20 * time.Millisecond}
+	svc := &types.Service{Name: "reaper-svc", Image: "nginx", Replicas: 2}
+	if err := db.SaveService(svc); err != nil {
+		t.Fatalf("unexpected error saving service: %s", err)
+	}
+	kub.DeployService(svc)
+	if err := rp.CleanServices(); err != nil {
+		t.Errorf("unexpected error while cleaning services: %s", err)
+	}
+	if _, err := db.GetService(svc.ID); err != nil {
+		t.Errorf("expected service to be retained, but got %s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := rp.CleanServices(); err != nil {
+		t.Errorf("unexpected error while cleaning services: %s", err)
+	}
+	if _, err := db.GetService(svc.ID); err == nil {
+		t.Errorf("expected service to be removed")
+	}
+	if _, err := kub.GetServiceReplicas(svc); err == nil {
+		t.Errorf("expected deployment of service to be removed")
+	}
+}
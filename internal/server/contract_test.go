@@ -0,0 +1,501 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"sigs.k8s.io/yaml"
+
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// contractSpec is a swagger 2.0 specification against which responses are
+// validated. The documented fields that kubedock is known not to return are
+// kept in an allowlist, so gaps that are introduced fail the tests.
+type contractSpec struct {
+	doc      map[string]interface{}
+	known    map[string]bool
+	missing  map[string]bool
+	seen     map[string]bool
+	knownDat string
+}
+
+// contractResult contains the findings of validating a response against
+// its schema. Errors are type violations and missing required fields;
+// missing fields are documented fields that are absent (or null).
+type contractResult struct {
+	errors  []string
+	missing []string
+}
+
+// contractCase is a request of which the response is validated against
+// the response schema of the operation at the given spec path.
+type contractCase struct {
+	method string
+	url    string
+	path   string
+	body   string
+	code   int
+}
+
+// updateMissing will rewrite the known-missing allowlists in testdata with
+// the fields that are currently missing, instead of comparing them.
+var updateMissing = flag.Bool("update-missing", false, "rewrite the known-missing field allowlists of the contract tests")
+
+// contractSkip contains the operations that are implemented, but can't be
+// validated by the contract tests, as they stream, hijack the connection
+// or don't return json.
+var contractSkip = map[string]string{
+	"GET /_ping":                  "plain text",
+	"HEAD /_ping":                 "plain text",
+	"GET /events":                 "streaming",
+	"POST /containers/{}/attach":  "hijacked",
+	"GET /containers/{}/logs":     "streaming",
+	"HEAD /containers/{}/archive": "tar archive",
+	"GET /containers/{}/archive":  "tar archive",
+	"PUT /containers/{}/archive":  "tar archive",
+	"POST /exec/{}/start":         "hijacked",
+	"POST /images/create":         "streaming",
+
+	"GET /libpod/_ping":                     "plain text",
+	"HEAD /libpod/_ping":                    "plain text",
+	"GET /libpod/events":                    "streaming",
+	"POST /libpod/containers/{}/attach":     "hijacked",
+	"GET /libpod/containers/{}/logs":        "streaming",
+	"POST /libpod/containers/{}/checkpoint": "tar archive",
+	"POST /libpod/containers/{}/restore":    "tar archive",
+	"HEAD /libpod/containers/{}/archive":    "tar archive",
+	"GET /libpod/containers/{}/archive":     "tar archive",
+	"PUT /libpod/containers/{}/archive":     "tar archive",
+	"POST /libpod/exec/{}/start":            "hijacked",
+	"POST /libpod/images/pull":              "streaming",
+	"GET /libpod/images/{}/{}":              "catch-all, covered as /libpod/images/{name}/json",
+}
+
+// loadContractSpec will load the swagger specification at given path.
+func loadContractSpec(t *testing.T, path string) *contractSpec {
+	t.Helper()
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading spec %s: %s", path, err)
+	}
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal(dat, &doc); err != nil {
+		t.Fatalf("failed parsing spec %s: %s", path, err)
+	}
+	return &contractSpec{doc: doc, known: map[string]bool{}, missing: map[string]bool{}, seen: map[string]bool{}}
+}
+
+// loadKnownMissing will load the allowlist of documented fields that are
+// known to be missing in the responses. Each line contains the method, the
+// spec path and the location of the field, separated by a space.
+func (cs *contractSpec) loadKnownMissing(t *testing.T, path string) {
+	t.Helper()
+	cs.knownDat = path
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("failed reading known missing fields %s: %s", path, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cs.known[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed reading known missing fields %s: %s", path, err)
+	}
+}
+
+// verifyKnownMissing will fail for every documented field that is missing
+// in a response, but not in the allowlist, and for every field in the
+// allowlist of a validated operation that is no longer missing. With
+// -update-missing, the allowlist is rewritten instead.
+func (cs *contractSpec) verifyKnownMissing(t *testing.T) {
+	t.Helper()
+	if *updateMissing {
+		keys := []string{}
+		for key := range cs.missing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dat := "# Documented fields that kubedock does not return, see contract_test.go.\n" + strings.Join(keys, "\n") + "\n"
+		if err := os.WriteFile(cs.knownDat, []byte(dat), 0644); err != nil {
+			t.Fatalf("failed writing known missing fields %s: %s", cs.knownDat, err)
+		}
+		return
+	}
+	for key := range cs.missing {
+		if !cs.known[key] {
+			t.Errorf("%s - documented field is missing, and not in %s", key, cs.knownDat)
+		}
+	}
+	for key := range cs.known {
+		fields := strings.SplitN(key, " ", 3)
+		if len(fields) == 3 && cs.seen[fields[0]+" "+fields[1]] && !cs.missing[key] {
+			t.Errorf("%s - field is no longer missing, remove it from %s", key, cs.knownDat)
+		}
+	}
+}
+
+// dockerSpecPath will return the path of the docker engine api spec, which
+// is part of the docker module that kubedock depends on.
+func dockerSpecPath(t *testing.T) string {
+	t.Helper()
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/docker/docker").Output()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		t.Skipf("docker module not available: %v", err)
+	}
+	return filepath.Join(strings.TrimSpace(string(out)), "api", "swagger.yaml")
+}
+
+// operation will return the operation for given method at given path, or
+// nil if the spec does not contain it.
+func (cs *contractSpec) operation(method, path string) map[string]interface{} {
+	paths, _ := cs.doc["paths"].(map[string]interface{})
+	for p, ops := range paths {
+		if normalizeContractPath(p) != normalizeContractPath(path) {
+			continue
+		}
+		ops, _ := ops.(map[string]interface{})
+		op, _ := ops[strings.ToLower(method)].(map[string]interface{})
+		return op
+	}
+	return nil
+}
+
+// resolve will return the schema or response referred to if the given
+// schema is a reference, or the given schema otherwise.
+func (cs *contractSpec) resolve(schema map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 10; i++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		parts := strings.SplitN(strings.TrimPrefix(ref, "#/"), "/", 2)
+		if len(parts) != 2 {
+			return nil
+		}
+		sect, _ := cs.doc[parts[0]].(map[string]interface{})
+		schema, _ = sect[parts[1]].(map[string]interface{})
+	}
+	return schema
+}
+
+// validate will validate given value against given schema, and adds the
+// findings to the given result.
+func (cs *contractSpec) validate(schema map[string]interface{}, val interface{}, loc string, res *contractResult) {
+	schema = cs.resolve(schema)
+	if schema == nil {
+		return
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			sub, _ := sub.(map[string]interface{})
+			cs.validate(sub, val, loc, res)
+		}
+		return
+	}
+	if val == nil {
+		if nullable, _ := schema["x-nullable"].(bool); !nullable && schema["type"] != nil {
+			res.missing = append(res.missing, loc)
+		}
+		return
+	}
+	typ, _ := schema["type"].(string)
+	if typ == "" && schema["properties"] != nil {
+		typ = "object"
+	}
+	switch typ {
+	case "object":
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			res.errors = append(res.errors, fmt.Sprintf("%s: expected object, but got %T", loc, val))
+			return
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		required := map[string]bool{}
+		if req, ok := schema["required"].([]interface{}); ok {
+			for _, r := range req {
+				required[fmt.Sprint(r)] = true
+			}
+		}
+		for name, prop := range props {
+			prop, _ := prop.(map[string]interface{})
+			v, ok := obj[name]
+			if !ok {
+				if required[name] {
+					res.errors = append(res.errors, fmt.Sprintf("%s.%s: required field is missing", loc, name))
+				} else {
+					res.missing = append(res.missing, loc+"."+name)
+				}
+				continue
+			}
+			cs.validate(prop, v, loc+"."+name, res)
+		}
+		if add, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			for name, v := range obj {
+				if _, ok := props[name]; !ok {
+					cs.validate(add, v, loc+"["+strconv.Quote(name)+"]", res)
+				}
+			}
+		}
+	case "array":
+		arr, ok := val.([]interface{})
+		if !ok {
+			res.errors = append(res.errors, fmt.Sprintf("%s: expected array, but got %T", loc, val))
+			return
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			cs.validate(items, v, loc+"["+strconv.Itoa(i)+"]", res)
+		}
+	case "string":
+		if _, ok := val.(string); !ok {
+			res.errors = append(res.errors, fmt.Sprintf("%s: expected string, but got %T", loc, val))
+		}
+	case "integer":
+		if f, ok := val.(float64); !ok || f != math.Trunc(f) {
+			res.errors = append(res.errors, fmt.Sprintf("%s: expected integer, but got %v", loc, val))
+		}
+	case "number":
+		if _, ok := val.(float64); !ok {
+			res.errors = append(res.errors, fmt.Sprintf("%s: expected number, but got %T", loc, val))
+		}
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			res.errors = append(res.errors, fmt.Sprintf("%s: expected boolean, but got %T", loc, val))
+		}
+	}
+}
+
+// check will validate the response of given case against the spec. The
+// status code must be the expected one and documented for the operation,
+// and the body must match the documented schema. Documented fields that are
+// missing in the response are collected, and verified against the allowlist
+// of known missing fields by verifyKnownMissing.
+func (cs *contractSpec) check(t *testing.T, router *gin.Engine, tst contractCase) {
+	t.Helper()
+	op := cs.operation(tst.method, tst.path)
+	if op == nil {
+		t.Errorf("%s %s is not part of the spec", tst.method, tst.path)
+		return
+	}
+	w := doRequest(router, tst.method, tst.url, strings.NewReader(tst.body))
+	if w.Code != tst.code {
+		t.Errorf("%s %s - expected status %d, but got %d: %s", tst.method, tst.url, tst.code, w.Code, w.Body.String())
+		return
+	}
+	responses, _ := op["responses"].(map[string]interface{})
+	resp, ok := responses[strconv.Itoa(w.Code)].(map[string]interface{})
+	if resp = cs.resolve(resp); !ok || resp == nil {
+		t.Errorf("%s %s - status %d is not documented", tst.method, tst.path, w.Code)
+		return
+	}
+	schema, ok := resp["schema"].(map[string]interface{})
+	if !ok || w.Code >= 300 {
+		return
+	}
+	var val interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &val); err != nil {
+		t.Errorf("%s %s - response is not valid json: %s", tst.method, tst.url, err)
+		return
+	}
+	res := &contractResult{}
+	cs.validate(schema, val, "$", res)
+	for _, err := range res.errors {
+		t.Errorf("%s %s - %s", tst.method, tst.path, err)
+	}
+	key := tst.method + " " + tst.path
+	cs.seen[key] = true
+	for _, loc := range res.missing {
+		cs.missing[key+" "+contractIndex.ReplaceAllString(loc, "[]")] = true
+	}
+}
+
+var contractIndex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// normalizeContractPath will replace the path parameters of both gin
+// routes and spec paths with {}, so they can be compared.
+func normalizeContractPath(path string) string {
+	return contractParam.ReplaceAllString(path, "/{}")
+}
+
+var contractParam = regexp.MustCompile(`/(:[^/]+|\*[^/]+|\{[^/]+\})`)
+
+// checkCoverage will verify that every implemented route that is part of
+// the spec is either covered by given cases, or explicitly skipped.
+// Only routes starting with the given prefix are considered. If strict is
+// set, implemented routes that are not part of the spec fail as well.
+func (cs *contractSpec) checkCoverage(t *testing.T, router *gin.Engine, prefix string, strict bool, cases []contractCase) {
+	t.Helper()
+	covered := map[string]bool{}
+	for _, tst := range cases {
+		covered[tst.method+" "+normalizeContractPath(tst.path)] = true
+	}
+	for _, route := range router.Routes() {
+		if !strings.HasPrefix(route.Path, prefix) || strings.HasSuffix(route.Handler, "httputil.NotImplemented") {
+			continue
+		}
+		key := route.Method + " " + normalizeContractPath(route.Path)
+		if cs.operation(route.Method, route.Path) == nil {
+			if _, ok := contractSkip[key]; strict && !ok {
+				t.Errorf("%s %s is implemented, but not part of the spec", route.Method, route.Path)
+			}
+			continue
+		}
+		if _, ok := contractSkip[key]; !ok && !covered[key] {
+			t.Errorf("%s %s is implemented, but not covered by the contract tests", route.Method, route.Path)
+		}
+	}
+}
+
+func TestDockerContract(t *testing.T) {
+	spec := loadContractSpec(t, dockerSpecPath(t))
+	spec.loadKnownMissing(t, filepath.Join("testdata", "docker-swagger.missing"))
+	router, _ := newTestRouter(t, common.Config{})
+
+	id := createContainerWithBody(t, router, `{"Image":"alpine:latest","Cmd":["sleep","60"],"ExposedPorts":{"80/tcp":{}}}`)
+	if w := doRequest(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container: %d %s", w.Code, w.Body.String())
+	}
+	execID := createContract(t, router, "/containers/"+id+"/exec", `{"Cmd":["ls"]}`)
+	netw := createContract(t, router, "/networks/create", `{"Name":"contract-net"}`)
+	svc := createContract(t, router, "/services/create", `{"Name":"contract-svc","TaskTemplate":{"ContainerSpec":{"Image":"nginx:alpine"}}}`)
+
+	cases := []contractCase{
+		{method: http.MethodGet, url: "/info", path: "/info", code: http.StatusOK},
+		{method: http.MethodGet, url: "/version", path: "/version", code: http.StatusOK},
+		{method: http.MethodPost, url: "/containers/create?name=contract-create", path: "/containers/create", body: `{"Image":"alpine:latest"}`, code: http.StatusCreated},
+		{method: http.MethodGet, url: "/containers/json?all=true", path: "/containers/json", code: http.StatusOK},
+		{method: http.MethodGet, url: "/containers/" + id + "/json", path: "/containers/{id}/json", code: http.StatusOK},
+		{method: http.MethodPost, url: "/containers/" + id + "/resize?h=24&w=80", path: "/containers/{id}/resize", code: http.StatusOK},
+		{method: http.MethodPost, url: "/containers/" + id + "/rename?name=contract-renamed", path: "/containers/{id}/rename", code: http.StatusNoContent},
+		{method: http.MethodGet, url: "/exec/" + execID + "/json", path: "/exec/{id}/json", code: http.StatusOK},
+		{method: http.MethodPost, url: "/exec/" + execID + "/resize?h=24&w=80", path: "/exec/{id}/resize", code: http.StatusOK},
+		{method: http.MethodPost, url: "/containers/" + id + "/exec", path: "/containers/{id}/exec", body: `{"Cmd":["ls"]}`, code: http.StatusCreated},
+		{method: http.MethodPost, url: "/networks/" + netw + "/connect", path: "/networks/{id}/connect", body: `{"Container":"` + id + `"}`, code: http.StatusOK},
+		{method: http.MethodGet, url: "/networks", path: "/networks", code: http.StatusOK},
+		{method: http.MethodGet, url: "/networks/" + netw, path: "/networks/{id}", code: http.StatusOK},
+		{method: http.MethodPost, url: "/networks/" + netw + "/disconnect", path: "/networks/{id}/disconnect", body: `{"Container":"` + id + `"}`, code: http.StatusOK},
+		{method: http.MethodPost, url: "/networks/create", path: "/networks/create", body: `{"Name":"contract-create"}`, code: http.StatusCreated},
+		{method: http.MethodGet, url: "/services", path: "/services", code: http.StatusOK},
+		{method: http.MethodGet, url: "/services/" + svc, path: "/services/{id}", code: http.StatusOK},
+		{method: http.MethodPost, url: "/services/" + svc + "/update", path: "/services/{id}/update", body: `{"TaskTemplate":{"ContainerSpec":{"Image":"nginx:alpine"}}}`, code: http.StatusOK},
+		{method: http.MethodPost, url: "/services/create", path: "/services/create", body: `{"Name":"contract-create","TaskTemplate":{"ContainerSpec":{"Image":"nginx:alpine"}}}`, code: http.StatusCreated},
+		{method: http.MethodDelete, url: "/services/" + svc, path: "/services/{id}", code: http.StatusOK},
+		{method: http.MethodGet, url: "/images/json", path: "/images/json", code: http.StatusOK},
+		{method: http.MethodGet, url: "/images/alpine:latest/json", path: "/images/{name}/json", code: http.StatusOK},
+		{method: http.MethodGet, url: "/distribution/alpine:latest/json", path: "/distribution/{name}/json", code: http.StatusOK},
+		{method: http.MethodPost, url: "/images/prune", path: "/images/prune", code: http.StatusOK},
+		{method: http.MethodGet, url: "/volumes", path: "/volumes", code: http.StatusOK},
+		{method: http.MethodPost, url: "/volumes/prune", path: "/volumes/prune", code: http.StatusOK},
+		{method: http.MethodPost, url: "/containers/" + id + "/stop", path: "/containers/{id}/stop", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/containers/" + id + "/start", path: "/containers/{id}/start", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/containers/" + id + "/restart", path: "/containers/{id}/restart", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/containers/" + id + "/kill", path: "/containers/{id}/kill", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/containers/" + id + "/wait", path: "/containers/{id}/wait", code: http.StatusOK},
+		{method: http.MethodDelete, url: "/containers/" + id + "?force=true", path: "/containers/{id}", code: http.StatusNoContent},
+		{method: http.MethodDelete, url: "/networks/" + netw, path: "/networks/{id}", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/networks/prune", path: "/networks/prune", code: http.StatusOK},
+		{method: http.MethodDelete, url: "/images/alpine:latest?force=true", path: "/images/{name}", code: http.StatusOK},
+	}
+	for _, tst := range cases {
+		spec.check(t, router, tst)
+	}
+	spec.verifyKnownMissing(t)
+	spec.checkCoverage(t, router, "/", false, cases)
+}
+
+// TestLibpodContract will validate the libpod endpoints against the
+// upstream libpod swagger spec in testdata, which is vendored unmodified
+// with make libpod-swagger. If it is not vendored, the hand-maintained
+// subset of the spec in testdata is used instead.
+func TestLibpodContract(t *testing.T) {
+	path := filepath.Join("testdata", "libpod-swagger.yaml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Logf("%s not vendored, validating against the subset of the spec", path)
+		path = filepath.Join("testdata", "libpod-swagger-subset.yaml")
+	}
+	spec := loadContractSpec(t, path)
+	spec.loadKnownMissing(t, strings.TrimSuffix(path, ".yaml")+".missing")
+	router, _ := newTestRouter(t, common.Config{})
+
+	id := createContract(t, router, "/libpod/containers/create", `{"image":"alpine:latest","name":"contract-libpod","command":["sleep","60"]}`)
+	if w := doRequest(router, http.MethodPost, "/libpod/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container: %d %s", w.Code, w.Body.String())
+	}
+	execID := createContract(t, router, "/libpod/containers/"+id+"/exec", `{"Cmd":["ls"]}`)
+
+	cases := []contractCase{
+		{method: http.MethodGet, url: "/libpod/info", path: "/libpod/info", code: http.StatusOK},
+		{method: http.MethodGet, url: "/libpod/version", path: "/libpod/version", code: http.StatusOK},
+		{method: http.MethodPost, url: "/libpod/containers/create", path: "/libpod/containers/create", body: `{"image":"alpine:latest","name":"contract-libpod-create"}`, code: http.StatusCreated},
+		{method: http.MethodGet, url: "/libpod/containers/" + id + "/exists", path: "/libpod/containers/{name}/exists", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/init", path: "/libpod/containers/{name}/init", code: http.StatusNotModified},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/mount", path: "/libpod/containers/{name}/mount", code: http.StatusInternalServerError},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/unmount", path: "/libpod/containers/{name}/unmount", code: http.StatusNoContent},
+		{method: http.MethodGet, url: "/libpod/containers/json?all=true", path: "/libpod/containers/json", code: http.StatusOK},
+		{method: http.MethodGet, url: "/libpod/containers/stats?stream=false", path: "/libpod/containers/stats", code: http.StatusOK},
+		{method: http.MethodGet, url: "/libpod/containers/" + id + "/json", path: "/libpod/containers/{name}/json", code: http.StatusOK},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/resize?h=24&w=80", path: "/libpod/containers/{name}/resize", code: http.StatusOK},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/rename?name=contract-libpod-renamed", path: "/libpod/containers/{name}/rename", code: http.StatusNoContent},
+		{method: http.MethodGet, url: "/libpod/generate/" + id + "/systemd", path: "/libpod/generate/{name}/systemd", code: http.StatusOK},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/exec", path: "/libpod/containers/{name}/exec", body: `{"Cmd":["ls"]}`, code: http.StatusCreated},
+		{method: http.MethodGet, url: "/libpod/exec/" + execID + "/json", path: "/libpod/exec/{id}/json", code: http.StatusOK},
+		{method: http.MethodPost, url: "/libpod/exec/" + execID + "/resize?h=24&w=80", path: "/libpod/exec/{id}/resize", code: http.StatusOK},
+		{method: http.MethodPost, url: "/libpod/networks/create", path: "/libpod/networks/create", body: `{"name":"contract-libpod-net"}`, code: http.StatusOK},
+		{method: http.MethodGet, url: "/libpod/volumes/json", path: "/libpod/volumes/json", code: http.StatusOK},
+		{method: http.MethodGet, url: "/libpod/images/json", path: "/libpod/images/json", code: http.StatusOK},
+		{method: http.MethodGet, url: "/libpod/images/alpine:latest/json", path: "/libpod/images/{name}/json", code: http.StatusOK},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/stop", path: "/libpod/containers/{name}/stop", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/start", path: "/libpod/containers/{name}/start", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/restart", path: "/libpod/containers/{name}/restart", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/kill", path: "/libpod/containers/{name}/kill", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/wait", path: "/libpod/containers/{name}/wait", code: http.StatusOK},
+		{method: http.MethodDelete, url: "/libpod/containers/" + id + "?force=true", path: "/libpod/containers/{name}", code: http.StatusOK},
+		{method: http.MethodDelete, url: "/libpod/images/alpine:latest?force=true", path: "/libpod/images/{name}", code: http.StatusOK},
+	}
+	for _, tst := range cases {
+		spec.check(t, router, tst)
+	}
+	spec.verifyKnownMissing(t)
+	spec.checkCoverage(t, router, "/libpod/", true, cases)
+}
+
+// createContract will create a resource with given request and returns the
+// id of the created resource.
+func createContract(t *testing.T, router *gin.Engine, url, body string) string {
+	t.Helper()
+	w := doRequest(router, http.MethodPost, url, strings.NewReader(body))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed creating %s - expected %d, but got %d: %s", url, http.StatusCreated, w.Code, w.Body.String())
+	}
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected error parsing create response: %s", err)
+	}
+	if id, ok := res["Id"].(string); ok {
+		return id
+	}
+	return fmt.Sprint(res["ID"])
+}
@@ -94,3 +94,16 @@ func (in *Filter) Match(matcher Matcher) bool {
 	// all filters had a match
 	return true
 }
+
+// Label will return the key and value of a label the matching objects are
+// required to have, which can be used to pre-select objects via an index.
+// The value is empty if only the key is required. It returns false if the
+// filter doesn't require a label.
+func (in *Filter) Label() (string, string, bool) {
+	for _, f := range in.filters["label"] {
+		if f.P {
+			return f.K, f.V, true
+		}
+	}
+	return "", "", false
+}
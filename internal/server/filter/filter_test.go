@@ -99,3 +99,25 @@ func TestFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		filter string
+		key    string
+		val    string
+		ok     bool
+	}{
+		{filter: `{"label":{"org.testcontainers.sessionId=abc":true}}`, key: "org.testcontainers.sessionId", val: "abc", ok: true},
+		{filter: `{"label":["org.testcontainers"]}`, key: "org.testcontainers", ok: true},
+		{filter: `{"label":{"org.testcontainers":false}}`, ok: false},
+		{filter: `{"name":{"mycontainer":true}}`, ok: false},
+		{filter: ``, ok: false},
+	}
+	for i, tst := range tests {
+		filtr, _ := New(tst.filter)
+		key, val, ok := filtr.Label()
+		if key != tst.key || val != tst.val || ok != tst.ok {
+			t.Errorf("failed test %d - expected %s=%s (%v), but got %s=%s (%v)", i, tst.key, tst.val, tst.ok, key, val, ok)
+		}
+	}
+}
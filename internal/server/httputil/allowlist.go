@@ -0,0 +1,104 @@
+package httputil
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseCIDRs will parse the given comma separated list of cidrs. Plain ip
+// addresses are accepted as well, and are considered a single host.
+func ParseCIDRs(val string) ([]*net.IPNet, error) {
+	res := []*net.IPNet{}
+	for _, cidr := range strings.Split(val, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address %s", cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			res = append(res, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %s", cidr)
+		}
+		res = append(res, ipnet)
+	}
+	return res, nil
+}
+
+// AllowlistMiddleware is a gin-gonic middleware that will reject requests
+// of clients whose ip is not in any of the given cidrs with a 403. If no
+// cidrs are given, all clients are allowed. Requests on the unix socket are
+// always allowed. The X-Forwarded-For header is only honored for requests
+// of the given trusted proxies, in which case the last address in it that
+// is not a trusted proxy is considered the ip of the client.
+func AllowlistMiddleware(cidrs, proxies []*net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(cidrs) == 0 {
+			c.Next()
+			return
+		}
+		addr := clientAddr(c.Request, proxies)
+		if addr == "" || addr == "@" {
+			c.Next()
+			return
+		}
+		ip := net.ParseIP(addr)
+		for _, cidr := range cidrs {
+			if ip != nil && cidr.Contains(ip) {
+				c.Next()
+				return
+			}
+		}
+		Error(c, http.StatusForbidden, fmt.Errorf("client %s is not allowed to use this api", addr))
+		c.Abort()
+	}
+}
+
+// clientAddr will return the ip address of the client of the given request,
+// without the port. If the request is made by a trusted proxy, the
+// X-Forwarded-For header is followed from the right, until an address is
+// found that is not a trusted proxy.
+func clientAddr(r *http.Request, proxies []*net.IPNet) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	fwd := []string{}
+	for _, val := range r.Header.Values("X-Forwarded-For") {
+		fwd = append(fwd, strings.Split(val, ",")...)
+	}
+	for i := len(fwd) - 1; i >= 0 && isTrusted(addr, proxies); i-- {
+		addr = strings.TrimSpace(fwd[i])
+	}
+	return addr
+}
+
+// isTrusted will return true if the given address is in any of the given
+// cidrs of trusted proxies.
+func isTrusted(addr string, proxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range proxies {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
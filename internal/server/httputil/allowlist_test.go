@@ -0,0 +1,93 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+		err bool
+	}{
+		{in: "", out: []string{}},
+		{in: "10.0.0.0/8", out: []string{"10.0.0.0/8"}},
+		{in: "10.1.2.3/8, 192.168.1.5,fd00::/8", out: []string{"10.0.0.0/8", "192.168.1.5/32", "fd00::/8"}},
+		{in: "::1", out: []string{"::1/128"}},
+		{in: "10.0.0.0/33", err: true},
+		{in: "kubedock", err: true},
+	}
+	for i, tst := range tests {
+		res, err := ParseCIDRs(tst.in)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %v, but got %v", i, tst.err, err)
+			continue
+		}
+		if tst.err {
+			continue
+		}
+		out := []string{}
+		for _, cidr := range res {
+			out = append(out, cidr.String())
+		}
+		if len(out) != len(tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, out)
+			continue
+		}
+		for j := range out {
+			if out[j] != tst.out[j] {
+				t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, out)
+			}
+		}
+	}
+}
+
+func TestAllowlistMiddleware(t *testing.T) {
+	tests := []struct {
+		cidrs   string
+		proxies string
+		remote  string
+		fwd     string
+		code    int
+	}{
+		{cidrs: "", remote: "192.0.2.1:1234", code: http.StatusOK},
+		{cidrs: "10.0.0.0/8", remote: "10.1.2.3:1234", code: http.StatusOK},
+		{cidrs: "10.0.0.0/8", remote: "192.0.2.1:1234", code: http.StatusForbidden},
+		{cidrs: "10.0.0.0/8", remote: "@", code: http.StatusOK},
+		{cidrs: "10.0.0.0/8", remote: "192.0.2.1:1234", fwd: "10.1.2.3", code: http.StatusForbidden},
+		{cidrs: "10.0.0.0/8", proxies: "192.0.2.1", remote: "192.0.2.1:1234", fwd: "192.0.2.8, 10.1.2.3", code: http.StatusOK},
+		{cidrs: "10.0.0.0/8", proxies: "192.0.2.1", remote: "192.0.2.2:1234", fwd: "192.0.2.8, 10.1.2.3", code: http.StatusForbidden},
+		{cidrs: "10.0.0.0/8", proxies: "10.1.2.3", remote: "10.1.2.3:1234", fwd: "10.1.2.3, 192.0.2.8", code: http.StatusForbidden},
+		{cidrs: "10.0.0.0/8", proxies: "192.0.2.0/24", remote: "192.0.2.1:1234", fwd: "10.1.2.3, 192.0.2.8", code: http.StatusOK},
+		{cidrs: "10.0.0.0/8", proxies: "192.0.2.0/24", remote: "192.0.2.1:1234", fwd: "192.0.2.9, 192.0.2.8", code: http.StatusForbidden},
+		{cidrs: "::1", remote: "[::1]:1234", code: http.StatusOK},
+	}
+	gin.SetMode(gin.TestMode)
+	for i, tst := range tests {
+		cidrs, err := ParseCIDRs(tst.cidrs)
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error %s", i, err)
+		}
+		router := gin.New()
+		proxies, err := ParseCIDRs(tst.proxies)
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error %s", i, err)
+		}
+		router.Use(AllowlistMiddleware(cidrs, proxies))
+		router.GET("/info", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		req := httptest.NewRequest(http.MethodGet, "/info", nil)
+		req.RemoteAddr = tst.remote
+		if tst.fwd != "" {
+			req.Header.Set("X-Forwarded-For", tst.fwd)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package httputil
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseCache is a small cache for responses of read endpoints that are
+// polled frequently (e.g. container lists). Responses are cached for a short
+// time, keyed by the request uri (including the filters), and the complete
+// cache is invalidated by every request that could modify state.
+type ResponseCache struct {
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is a cached response.
+type cacheEntry struct {
+	status  int
+	ctype   string
+	body    []byte
+	expires time.Time
+}
+
+// cacheWriter is a gin.ResponseWriter that keeps a copy of the response
+// body that is written.
+type cacheWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write will write given data to the response and keep a copy.
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString will write given string to the response and keep a copy.
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// NewResponseCache will return a ResponseCache that caches responses for
+// the given duration; a duration of 0 disables caching.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{ttl: ttl, entries: map[string]*cacheEntry{}}
+}
+
+// Handler will return a middleware that serves the response from the cache
+// if available, and caches successful responses otherwise.
+func (rc *ResponseCache) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rc == nil || rc.ttl <= 0 || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		key := c.Request.URL.RequestURI()
+		if ent := rc.get(key); ent != nil {
+			c.Data(ent.status, ent.ctype, ent.body)
+			c.Abort()
+			return
+		}
+		w := &cacheWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		if w.Status() == http.StatusOK {
+			rc.put(key, &cacheEntry{
+				status:  w.Status(),
+				ctype:   w.Header().Get("Content-Type"),
+				body:    w.body.Bytes(),
+				expires: time.Now().Add(rc.ttl),
+			})
+		}
+	}
+}
+
+// InvalidateMiddleware will return a middleware that clears the cache for
+// every request that could modify state (i.e. all requests except GET and
+// HEAD requests).
+func (rc *ResponseCache) InvalidateMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rc == nil || rc.ttl <= 0 || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		rc.Invalidate()
+		c.Next()
+		rc.Invalidate()
+	}
+}
+
+// Invalidate will remove all cached responses.
+func (rc *ResponseCache) Invalidate() {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	rc.entries = map[string]*cacheEntry{}
+}
+
+// get will return the cached response for given key, or nil if not cached
+// or expired.
+func (rc *ResponseCache) get(key string) *cacheEntry {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	ent, ok := rc.entries[key]
+	if !ok || time.Now().After(ent.expires) {
+		return nil
+	}
+	return ent
+}
+
+// put will store given response in the cache, and removes expired entries.
+func (rc *ResponseCache) put(key string, ent *cacheEntry) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	now := time.Now()
+	for k, e := range rc.entries {
+		if now.After(e.expires) {
+			delete(rc.entries, k)
+		}
+	}
+	rc.entries[key] = ent
+}
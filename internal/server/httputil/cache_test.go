@@ -0,0 +1,54 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResponseCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		ttl   time.Duration
+		reqs  []string
+		sleep time.Duration
+		calls int
+	}{
+		{ttl: 0, reqs: []string{"GET /list", "GET /list"}, calls: 2},
+		{ttl: time.Minute, reqs: []string{"GET /list", "GET /list"}, calls: 1},
+		{ttl: time.Minute, reqs: []string{"GET /list?filters=a", "GET /list?filters=b", "GET /list?filters=a"}, calls: 2},
+		{ttl: time.Minute, reqs: []string{"GET /list", "POST /create", "GET /list"}, calls: 2},
+		{ttl: time.Minute, reqs: []string{"GET /list", "GET /error", "GET /error"}, calls: 3},
+		{ttl: 10 * time.Millisecond, reqs: []string{"GET /list", "GET /list"}, sleep: 50 * time.Millisecond, calls: 2},
+	}
+	for i, tst := range tests {
+		calls := 0
+		rc := NewResponseCache(tst.ttl)
+		router := gin.New()
+		router.Use(rc.InvalidateMiddleware())
+		router.GET("/list", rc.Handler(), func(c *gin.Context) {
+			calls++
+			c.JSON(http.StatusOK, []string{"a"})
+		})
+		router.GET("/error", rc.Handler(), func(c *gin.Context) {
+			calls++
+			c.JSON(http.StatusInternalServerError, gin.H{})
+		})
+		router.POST("/create", func(c *gin.Context) {})
+		for j, req := range tst.reqs {
+			if j > 0 {
+				time.Sleep(tst.sleep)
+			}
+			method, url, _ := strings.Cut(req, " ")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		}
+		if calls != tst.calls {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.calls, calls)
+		}
+	}
+}
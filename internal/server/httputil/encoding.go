@@ -0,0 +1,110 @@
+package httputil
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DecodeMiddleware is a gin-gonic middleware that will decompress request
+// bodies that are sent with a gzip or zstd Content-Encoding (e.g. archive
+// uploads), so handlers always read the plain body. Requests with an
+// unsupported encoding are rejected. The decompressed body is limited to
+// the given max size (0 disables the limit), so small compressed bodies
+// can't expand beyond the max body size.
+func DecodeMiddleware(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enc := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+		if enc == "" || enc == "identity" {
+			c.Next()
+			return
+		}
+		body, err := newDecoder(enc, c.Request.Body)
+		if err != nil {
+			Error(c, http.StatusUnsupportedMediaType, err)
+			c.Abort()
+			return
+		}
+		defer body.Close()
+		c.Request.Body = body
+		if maxSize > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, body, maxSize)
+		}
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// newDecoder will return a reader that decompresses the given body with
+// the given encoding.
+func newDecoder(enc string, body io.Reader) (io.ReadCloser, error) {
+	switch enc {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "zstd":
+		dec, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding: %s", enc)
+}
+
+// AcceptedEncoding will return the compression that should be used for
+// the response, based on the Accept-Encoding of the given request. It
+// returns zstd or gzip (in that order of preference), or an empty string
+// if the client doesn't accept either.
+func AcceptedEncoding(r *http.Request) string {
+	accepted := map[string]bool{}
+	for _, val := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(val, ",") {
+			name, params, _ := strings.Cut(enc, ";")
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				if f, err := strconv.ParseFloat(q, 64); err == nil && f == 0 {
+					continue
+				}
+			}
+			accepted[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+	for _, enc := range []string{"zstd", "gzip"} {
+		if accepted[enc] {
+			return enc
+		}
+	}
+	return ""
+}
+
+// NewEncoder will return a writer that compresses to the given writer with
+// the given encoding (as returned by AcceptedEncoding). The writer should be
+// closed to flush the compressed data. If no encoding is given, the data is
+// written as-is.
+func NewEncoder(enc string, w io.Writer) (io.WriteCloser, error) {
+	switch enc {
+	case "":
+		return nopWriteCloser{w}, nil
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported content encoding: %s", enc)
+}
+
+// nopWriteCloser is a io.WriteCloser of which Close is a no-op.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close is a no-op.
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,82 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		in  []string
+		out string
+	}{
+		{in: nil, out: ""},
+		{in: []string{"gzip"}, out: "gzip"},
+		{in: []string{"gzip, deflate, br"}, out: "gzip"},
+		{in: []string{"gzip", "zstd"}, out: "zstd"},
+		{in: []string{"ZSTD;q=0.5, gzip"}, out: "zstd"},
+		{in: []string{"zstd;q=0, gzip;q=1.0"}, out: "gzip"},
+		{in: []string{"gzip;q=0.0"}, out: ""},
+		{in: []string{"identity"}, out: ""},
+	}
+	for i, tst := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		for _, val := range tst.in {
+			req.Header.Add("Accept-Encoding", val)
+		}
+		if res := AcceptedEncoding(req); res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
+
+func TestDecodeMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(DecodeMiddleware(10))
+	router.PUT("/upload", func(c *gin.Context) {
+		dat, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.String(http.StatusOK, "%s", dat)
+	})
+
+	tests := []struct {
+		enc  string
+		body string
+		code int
+	}{
+		{enc: "gzip", body: "0123456789", code: http.StatusOK},
+		{enc: "zstd", body: "0123456789", code: http.StatusOK},
+		{enc: "gzip", body: strings.Repeat("0", 1000), code: http.StatusRequestEntityTooLarge},
+		{enc: "zstd", body: strings.Repeat("0", 1000), code: http.StatusRequestEntityTooLarge},
+		{enc: "br", body: "0123456789", code: http.StatusUnsupportedMediaType},
+	}
+	for i, tst := range tests {
+		buf := &bytes.Buffer{}
+		enc, err := NewEncoder(tst.enc, buf)
+		if err != nil {
+			enc, _ = NewEncoder("", buf)
+		}
+		enc.Write([]byte(tst.body))
+		enc.Close()
+		req := httptest.NewRequest(http.MethodPut, "/upload", buf)
+		req.Header.Set("Content-Encoding", tst.enc)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if tst.code == http.StatusOK && w.Body.String() != tst.body {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.body, w.Body.String())
+		}
+	}
+}
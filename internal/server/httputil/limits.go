@@ -0,0 +1,306 @@
+package httputil
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+)
+
+// Limits contains the limits that are applied to requests, to protect the
+// server against (accidental) huge uploads and stalled connections. A
+// value of 0 disables the limit.
+type Limits struct {
+	// MaxBodySize is the max size of a request body in bytes; for chunked
+	// uploads (Content-Range), this is the max total size
+	MaxBodySize int64
+	// ReadTimeout is the max duration to read the request body
+	ReadTimeout time.Duration
+	// WriteTimeout is the max duration before the response is written, and
+	// the max duration of a single write after that; this doesn't apply to
+	// streaming responses, once they are flushed
+	WriteTimeout time.Duration
+	// StreamIdleTimeout is the max duration a streaming response can be idle
+	StreamIdleTimeout time.Duration
+}
+
+// limitWriterKey is the gin context key of the limitWriter of a request.
+const limitWriterKey = "httputil.limitWriter"
+
+// limitWriter is a gin.ResponseWriter that applies the write timeout. If
+// the handler didn't respond within the write timeout, a 408 is returned
+// and the request context is cancelled. Once a response is written, every
+// write extends the write deadline with the write timeout, or with the
+// stream idle timeout once the response is flushed. The headers are kept
+// apart until the response is written, as the timeout response is written
+// from another go-routine.
+type limitWriter struct {
+	gin.ResponseWriter
+	rc        *http.ResponseController
+	limits    Limits
+	mu        sync.Mutex
+	header    http.Header
+	timer     *time.Timer
+	cancel    context.CancelFunc
+	streaming bool
+	disabled  bool
+	timedOut  bool
+	done      bool
+}
+
+// Header will return the headers of the response.
+func (w *limitWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.header != nil {
+		return w.header
+	}
+	return w.ResponseWriter.Header()
+}
+
+// WriteHeader will set the status of the response.
+func (w *limitWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// WriteHeaderNow will write the headers of the response.
+func (w *limitWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.commit()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write will write given data to the response.
+func (w *limitWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.commit()
+	w.extend()
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString will write given string to the response.
+func (w *limitWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.commit()
+	w.extend()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush will flush the response, and switches the response to streaming
+// mode, in which the stream idle timeout applies.
+func (w *limitWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.commit()
+	w.streaming = true
+	w.extend()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack will hijack the connection; hijacked connections are not subject
+// to the write timeout.
+func (w *limitWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	w.commit()
+	return w.ResponseWriter.Hijack()
+}
+
+// commit will stop the write timeout timer and copy the headers to the
+// actual response. It should be called with the lock held.
+func (w *limitWriter) commit() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	if w.header == nil {
+		return
+	}
+	hdr := w.ResponseWriter.Header()
+	for k := range hdr {
+		delete(hdr, k)
+	}
+	for k, v := range w.header {
+		hdr[k] = v
+	}
+	w.header = nil
+}
+
+// extend will extend the write deadline of a written response. It should
+// be called with the lock held.
+func (w *limitWriter) extend() {
+	timeout := w.limits.WriteTimeout
+	if w.streaming {
+		timeout = w.limits.StreamIdleTimeout
+	}
+	deadline := time.Time{}
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	_ = w.rc.SetWriteDeadline(deadline)
+}
+
+// progress will restart the write timeout timer, as long as no response
+// has been written (e.g. while a request body is still being read).
+func (w *limitWriter) progress() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil && !w.disabled && w.header != nil {
+		w.timer.Reset(w.limits.WriteTimeout)
+	}
+}
+
+// disable will disable the write timeout for requests that block until
+// something happens, e.g. waiting for a container to exit.
+func (w *limitWriter) disable() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.disabled = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// timeout will write a 408 if no response has been written yet, and will
+// cancel the request.
+func (w *limitWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done || w.disabled || w.header == nil {
+		return
+	}
+	w.timedOut = true
+	w.cancel()
+	klog.Errorf("error during request[%d]: no response within %s", http.StatusRequestTimeout, w.limits.WriteTimeout)
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusRequestTimeout)
+	w.ResponseWriter.WriteHeaderNow()
+	_, _ = w.ResponseWriter.WriteString(fmt.Sprintf(`{"message":"no response within %s"}`, w.limits.WriteTimeout))
+}
+
+// finish will stop the write timeout timer, and copy the headers to the
+// actual response when the handler only set a status.
+func (w *limitWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+	if !w.timedOut {
+		w.commit()
+	}
+}
+
+// NoWriteTimeout is a gin-gonic handler that disables the write timeout for
+// routes that block until something happens (e.g. waiting for a container
+// to exit, or following logs).
+func NoWriteTimeout(c *gin.Context) {
+	if w, ok := c.Get(limitWriterKey); ok {
+		w.(*limitWriter).disable()
+	}
+	c.Next()
+}
+
+// limitBody is a request body that clears the read deadline once the body
+// has been read completely. The connection is read in the background after
+// that, to detect clients that disconnect, which should not time out. Any
+// progress reading the body is reported to the limitWriter, so uploads that
+// are still progressing don't time out.
+type limitBody struct {
+	io.ReadCloser
+	rc       *http.ResponseController
+	deadline bool
+	w        *limitWriter
+}
+
+// Read will read from the request body.
+func (b *limitBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.w.progress()
+	}
+	if err == io.EOF && b.deadline {
+		_ = b.rc.SetReadDeadline(time.Time{})
+	}
+	return n, err
+}
+
+// LimitMiddleware is a gin-gonic middleware that will apply the given limits
+// to requests. Requests that exceed the max body size are rejected with a
+// 413, bodies that are not read within the read timeout result in a 408
+// (see Error), as do requests that are not responded to within the write
+// timeout. Routes that block by design should use NoWriteTimeout. Hijacked
+// connections (e.g. attach and exec) are not subject to the timeouts.
+func LimitMiddleware(limits Limits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limits.MaxBodySize > 0 {
+			size := c.Request.ContentLength
+			if rng := c.GetHeader("Content-Range"); rng != "" {
+				if _, _, total, err := ParseContentRange(rng); err == nil {
+					size = total
+				}
+			}
+			if size > limits.MaxBodySize {
+				Error(c, http.StatusRequestEntityTooLarge, fmt.Errorf("request body of %d bytes exceeds the max size of %d bytes", size, limits.MaxBodySize))
+				c.Abort()
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodySize)
+		}
+
+		if limits.ReadTimeout <= 0 && limits.WriteTimeout <= 0 && limits.StreamIdleTimeout <= 0 {
+			c.Next()
+			return
+		}
+
+		rc := http.NewResponseController(c.Writer)
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		w := &limitWriter{ResponseWriter: c.Writer, rc: rc, limits: limits, cancel: cancel, header: c.Writer.Header().Clone()}
+		body := &limitBody{ReadCloser: c.Request.Body, rc: rc, w: w}
+		if limits.ReadTimeout > 0 && c.Request.ContentLength != 0 {
+			if err := rc.SetReadDeadline(time.Now().Add(limits.ReadTimeout)); err != nil {
+				klog.V(5).Infof("not setting read deadline: %s", err)
+			} else {
+				body.deadline = true
+			}
+		}
+		c.Request.Body = body
+		if limits.WriteTimeout > 0 {
+			w.timer = time.AfterFunc(limits.WriteTimeout, w.timeout)
+		}
+		c.Writer = w
+		c.Set(limitWriterKey, w)
+		c.Next()
+		w.finish()
+	}
+}
@@ -0,0 +1,151 @@
+package httputil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newLimitServer(limits Limits) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LimitMiddleware(limits))
+	router.PUT("/upload", func(c *gin.Context) {
+		dat, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(dat))
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Writer.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			c.Writer.WriteString("tick\n")
+			c.Writer.Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+	router.GET("/wait", func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	router.GET("/wait/blocking", NoWriteTimeout, func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	return httptest.NewServer(router)
+}
+
+func TestLimitMiddlewareBodySize(t *testing.T) {
+	srv := newLimitServer(Limits{MaxBodySize: 10})
+	defer srv.Close()
+
+	tests := []struct {
+		body    io.Reader
+		length  int64
+		rng     string
+		code    int
+		content string
+	}{
+		{body: strings.NewReader("0123456789"), length: 10, code: http.StatusOK, content: "10"},
+		{body: strings.NewReader("0123456789a"), length: 11, code: http.StatusRequestEntityTooLarge},
+		{body: io.MultiReader(strings.NewReader("01234"), strings.NewReader("56789a")), length: -1, code: http.StatusRequestEntityTooLarge},
+		{body: strings.NewReader("01234"), length: 5, rng: "bytes 0-4/20", code: http.StatusRequestEntityTooLarge},
+		{body: strings.NewReader("01234"), length: 5, rng: "bytes 0-4/10", code: http.StatusOK, content: "5"},
+	}
+	for i, tst := range tests {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/upload", tst.body)
+		req.ContentLength = tst.length
+		if tst.rng != "" {
+			req.Header.Set("Content-Range", tst.rng)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error: %s", i, err)
+		}
+		dat, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, res.StatusCode, dat)
+		}
+		if tst.content != "" && string(dat) != tst.content {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.content, dat)
+		}
+	}
+}
+
+func TestLimitMiddlewareReadTimeout(t *testing.T) {
+	srv := newLimitServer(Limits{ReadTimeout: 50 * time.Millisecond})
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer conn.Close()
+	// send only part of the announced body
+	fmt.Fprintf(conn, "PUT /upload HTTP/1.1\r\nHost: localhost\r\nContent-Length: 10\r\n\r\n01234")
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("failed test - expected %d, but got %d", http.StatusRequestTimeout, res.StatusCode)
+	}
+}
+
+func TestLimitMiddlewareWriteTimeout(t *testing.T) {
+	srv := newLimitServer(Limits{WriteTimeout: 20 * time.Millisecond})
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		code    int
+		content string
+	}{
+		{path: "/wait", code: http.StatusRequestTimeout, content: "no response within"},
+		{path: "/wait/blocking", code: http.StatusOK, content: "done"},
+	}
+	for i, tst := range tests {
+		res, err := http.Get(srv.URL + tst.path)
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error: %s", i, err)
+		}
+		dat, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, res.StatusCode, dat)
+		}
+		if !strings.Contains(string(dat), tst.content) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.content, dat)
+		}
+	}
+}
+
+func TestLimitMiddlewareStreaming(t *testing.T) {
+	// the write timeout doesn't apply once a streaming response is flushed
+	srv := newLimitServer(Limits{WriteTimeout: 20 * time.Millisecond, StreamIdleTimeout: time.Second})
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer res.Body.Close()
+	dat, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(dat) != "tick\ntick\ntick\n" {
+		t.Errorf("failed test - expected 3 ticks, but got %s", dat)
+	}
+}
@@ -0,0 +1,88 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsExposedHeaders are the response headers that browser clients are
+// allowed to read.
+var corsExposedHeaders = []string{
+	"Api-Version", "Libpod-Api-Version", "Docker-Experimental", "Ostype",
+	"X-Docker-Container-Path-Stat", "Content-Encoding", "Range",
+}
+
+// CORSMiddleware is a gin-gonic middleware that will add CORS headers to
+// the responses of requests of the given origins (or all origins if * is
+// allowed), so browser based clients can use the api. Preflight requests
+// are answered directly.
+func CORSMiddleware(origins []string) gin.HandlerFunc {
+	allowed := map[string]bool{}
+	for _, origin := range origins {
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || len(allowed) == 0 || (!allowed["*"] && !allowed[origin]) {
+			c.Next()
+			return
+		}
+		h := c.Writer.Header()
+		if allowed["*"] {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		h.Add("Vary", "Origin")
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, DELETE, OPTIONS")
+			if hdrs := c.GetHeader("Access-Control-Request-Headers"); hdrs != "" {
+				h.Set("Access-Control-Allow-Headers", hdrs)
+			}
+			h.Set("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// PathPrefixMiddleware is a gin-gonic middleware that will remove the given
+// path prefix from the url path, so kubedock can be served under a path
+// behind an ingress or reverse proxy. If trustForwarded is set, the prefix
+// in the X-Forwarded-Prefix header is removed as well.
+func PathPrefixMiddleware(router *gin.Engine, prefix string, trustForwarded bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		prefixes := []string{prefix}
+		if trustForwarded {
+			prefixes = append(prefixes, c.GetHeader("X-Forwarded-Prefix"))
+		}
+		for _, pfx := range prefixes {
+			pfx = strings.TrimSuffix(pfx, "/")
+			if pfx == "" || !strings.HasPrefix(c.Request.URL.Path, pfx+"/") {
+				continue
+			}
+			c.Request.URL.Path = strings.TrimPrefix(c.Request.URL.Path, pfx)
+			router.HandleContext(c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ExternalHost will return the host (and port) that the client used to
+// connect to kubedock. If trustForwarded is set, the X-Forwarded-Host header
+// of a reverse proxy takes precedence over the host of the request.
+func ExternalHost(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			host, _, _ = strings.Cut(host, ",")
+			return strings.TrimSpace(host)
+		}
+	}
+	return r.Host
+}
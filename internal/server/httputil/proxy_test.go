@@ -0,0 +1,105 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	tests := []struct {
+		origins []string
+		method  string
+		origin  string
+		reqmeth string
+		code    int
+		allow   string
+	}{
+		{origins: []string{}, method: http.MethodGet, origin: "https://che.example.com", code: http.StatusOK, allow: ""},
+		{origins: []string{"https://che.example.com"}, method: http.MethodGet, origin: "https://che.example.com", code: http.StatusOK, allow: "https://che.example.com"},
+		{origins: []string{"https://che.example.com/"}, method: http.MethodGet, origin: "https://evil.example.com", code: http.StatusOK, allow: ""},
+		{origins: []string{"*"}, method: http.MethodGet, origin: "https://evil.example.com", code: http.StatusOK, allow: "*"},
+		{origins: []string{"*"}, method: http.MethodGet, code: http.StatusOK, allow: ""},
+		{origins: []string{"https://che.example.com"}, method: http.MethodOptions, origin: "https://che.example.com", reqmeth: "POST", code: http.StatusNoContent, allow: "https://che.example.com"},
+		{origins: []string{"https://che.example.com"}, method: http.MethodOptions, origin: "https://evil.example.com", reqmeth: "POST", code: http.StatusNotFound, allow: ""},
+	}
+	gin.SetMode(gin.TestMode)
+	for i, tst := range tests {
+		router := gin.New()
+		router.Use(CORSMiddleware(tst.origins))
+		router.GET("/info", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		req := httptest.NewRequest(tst.method, "/info", nil)
+		if tst.origin != "" {
+			req.Header.Set("Origin", tst.origin)
+		}
+		if tst.reqmeth != "" {
+			req.Header.Set("Access-Control-Request-Method", tst.reqmeth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+		if allow := w.Header().Get("Access-Control-Allow-Origin"); allow != tst.allow {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.allow, allow)
+		}
+	}
+}
+
+func TestPathPrefixMiddleware(t *testing.T) {
+	tests := []struct {
+		prefix string
+		trust  bool
+		fwd    string
+		path   string
+		code   int
+	}{
+		{prefix: "", path: "/info", code: http.StatusOK},
+		{prefix: "/docker/", path: "/docker/info", code: http.StatusOK},
+		{prefix: "/docker", path: "/docker/v1.41/info", code: http.StatusOK},
+		{prefix: "/docker", path: "/info", code: http.StatusOK},
+		{prefix: "/docker", path: "/dockerinfo", code: http.StatusNotFound},
+		{prefix: "", fwd: "/che", path: "/che/info", code: http.StatusNotFound},
+		{prefix: "", trust: true, fwd: "/che", path: "/che/info", code: http.StatusOK},
+	}
+	gin.SetMode(gin.TestMode)
+	for i, tst := range tests {
+		router := gin.New()
+		router.Use(PathPrefixMiddleware(router, tst.prefix, tst.trust))
+		router.Use(VersionAliasMiddleware(router))
+		router.GET("/info", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		req := httptest.NewRequest(http.MethodGet, tst.path, nil)
+		if tst.fwd != "" {
+			req.Header.Set("X-Forwarded-Prefix", tst.fwd)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+	}
+}
+
+func TestExternalHost(t *testing.T) {
+	tests := []struct {
+		fwd   string
+		trust bool
+		out   string
+	}{
+		{fwd: "", trust: true, out: "kubedock:2475"},
+		{fwd: "che.example.com", trust: false, out: "kubedock:2475"},
+		{fwd: "che.example.com", trust: true, out: "che.example.com"},
+		{fwd: "che.example.com, proxy.local", trust: true, out: "che.example.com"},
+	}
+	for i, tst := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://kubedock:2475/info", nil)
+		if tst.fwd != "" {
+			req.Header.Set("X-Forwarded-Host", tst.fwd)
+		}
+		if res := ExternalHost(req, tst.trust); res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
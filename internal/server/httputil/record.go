@@ -0,0 +1,96 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/util/recording"
+)
+
+// recordBody is a request body that captures the data that is read by the
+// handler, up to the max recorded body size.
+type recordBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	size int64
+}
+
+// Read will read from the request body.
+func (b *recordBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if room := recording.MaxBodySize - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+// recordWriter is a gin.ResponseWriter that captures the response, up to
+// the max recorded body size.
+type recordWriter struct {
+	gin.ResponseWriter
+	buf  bytes.Buffer
+	size int64
+}
+
+// Write will write given data to the response.
+func (w *recordWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString will write given string to the response.
+func (w *recordWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture will add given data to the recorded response.
+func (w *recordWriter) capture(data []byte) {
+	w.size += int64(len(data))
+	if room := recording.MaxBodySize - w.buf.Len(); room > 0 {
+		w.buf.Write(data[:min(len(data), room)])
+	}
+}
+
+// RecordMiddleware is a gin-gonic middleware that will record all requests
+// and their responses with the given recorder, in a recording per client
+// session (the address and user agent of the client). Recordings are
+// sanitized (see recording.Exchange.Sanitize). Only the part of the request body that
+// is read by the handler is recorded, and data that is sent over hijacked
+// connections (e.g. attach and exec) is not recorded.
+func RecordMiddleware(rec *recording.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rec == nil {
+			c.Next()
+			return
+		}
+		begin := time.Now()
+		ex := &recording.Exchange{
+			Time:          begin,
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			Query:         c.Request.URL.RawQuery,
+			RequestHeader: c.Request.Header.Clone(),
+		}
+		body := &recordBody{ReadCloser: c.Request.Body}
+		c.Request.Body = body
+		w := &recordWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		c.Next()
+
+		ex.Duration = time.Since(begin)
+		ex.RequestBody, ex.RequestSize = body.buf.String(), body.size
+		ex.Status = w.Status()
+		ex.ResponseHeader = w.Header().Clone()
+		ex.ResponseBody, ex.ResponseSize = w.buf.String(), w.size
+		if err := rec.Record(c.ClientIP()+" "+c.Request.UserAgent(), ex); err != nil {
+			klog.Warningf("error recording request: %s", err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package httputil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/util/recording"
+)
+
+func TestRecordMiddleware(t *testing.T) {
+	rec, err := recording.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error creating recorder: %s", err)
+	}
+	defer rec.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RecordMiddleware(rec))
+	router.POST("/containers/create", func(c *gin.Context) {
+		_, _ = io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusCreated, gin.H{"Id": "tb303"})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/containers/create?name=f1spirit", strings.NewReader(`{"Env":["TOKEN=abc"]}`))
+	req.Header.Set("X-Registry-Auth", "s3cr3t")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	exs, err := recording.Read(rec.Files()[0])
+	if err != nil || len(exs) != 1 {
+		t.Fatalf("failed reading recording - expected 1 exchange, but got %d: %v", len(exs), err)
+	}
+	ex := exs[0]
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: ex.Method + " " + ex.Path + "?" + ex.Query, out: "POST /containers/create?name=f1spirit"},
+		{in: ex.RequestBody, out: `{"Env":["TOKEN=<redacted>"]}`},
+		{in: ex.RequestHeader.Get("X-Registry-Auth"), out: recording.Redacted},
+		{in: ex.ResponseBody, out: `{"Id":"tb303"}`},
+	}
+	for i, tst := range tests {
+		if tst.in != tst.out {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, tst.in)
+		}
+	}
+	if ex.Status != http.StatusCreated {
+		t.Errorf("expected status %d, but got %d", http.StatusCreated, ex.Status)
+	}
+}
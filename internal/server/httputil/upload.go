@@ -0,0 +1,267 @@
+package httputil
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// Uploads keeps track of resumable uploads. A large request body can be
+// uploaded in chunks (or resumed after a failure) by sending parts of it
+// with a Content-Range header. The received data is stored in a temporary
+// file, until the upload is complete. Uploads that are not updated within
+// the ttl are removed by Clean, and uploads that exceed the max size are
+// rejected.
+type Uploads struct {
+	ttl     time.Duration
+	maxSize int64
+	lock    sync.Mutex
+	uploads map[string]*upload
+}
+
+// upload is a resumable upload that is in progress.
+type upload struct {
+	lock    sync.Mutex
+	file    *os.File
+	size    int64
+	total   int64
+	updated time.Time
+	removed bool
+}
+
+// RangeError is returned when the Content-Range of an upload chunk doesn't
+// match the data that has been received so far.
+type RangeError struct {
+	// Received is the number of bytes that have been received
+	Received int64
+	// Start is the offset of the chunk that was sent
+	Start int64
+}
+
+// Error will return a description of the range error.
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("upload chunk starts at %d, but %d bytes have been received", e.Start, e.Received)
+}
+
+// HTTPStatus will return the http status of a range error.
+func (e *RangeError) HTTPStatus() int {
+	return http.StatusRequestedRangeNotSatisfiable
+}
+
+// UploadSizeError is returned when the total size of an upload exceeds the
+// max size of uploads.
+type UploadSizeError struct {
+	// Size is the total size of the upload
+	Size int64
+	// MaxSize is the max size of uploads
+	MaxSize int64
+}
+
+// Error will return a description of the size error.
+func (e *UploadSizeError) Error() string {
+	return fmt.Sprintf("upload of %d bytes exceeds the max size of %d bytes", e.Size, e.MaxSize)
+}
+
+// HTTPStatus will return the http status of a size error.
+func (e *UploadSizeError) HTTPStatus() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// contentRange matches "bytes start-end/total" and "bytes */total".
+var contentRange = regexp.MustCompile(`^bytes (?:(\d+)-(\d+)|\*)/(\d+)$`)
+
+// NewUploads will return a Uploads object, of which incomplete uploads are
+// removed if they are not updated within the given duration. Uploads with
+// a total size larger than given max size are rejected (0 is unlimited).
+func NewUploads(ttl time.Duration, maxSize int64) *Uploads {
+	return &Uploads{ttl: ttl, maxSize: maxSize, uploads: map[string]*upload{}}
+}
+
+// ParseContentRange will parse the given Content-Range header value. The
+// start and end are -1 for a status request (bytes */total).
+func ParseContentRange(val string) (int64, int64, int64, error) {
+	m := contentRange.FindStringSubmatch(val)
+	if m == nil {
+		return 0, 0, 0, fmt.Errorf("invalid content range: %s", val)
+	}
+	total, _ := strconv.ParseInt(m[3], 10, 64)
+	if m[1] == "" {
+		return -1, -1, total, nil
+	}
+	start, _ := strconv.ParseInt(m[1], 10, 64)
+	end, _ := strconv.ParseInt(m[2], 10, 64)
+	if start > end || end >= total {
+		return 0, 0, 0, fmt.Errorf("invalid content range: %s", val)
+	}
+	return start, end, total, nil
+}
+
+// Append will add the chunk in the given body to the upload with given key,
+// as described by the given Content-Range header value. It returns the
+// number of bytes received so far, and a reader of the complete upload if
+// all data has been received; this reader will remove the upload when it's
+// closed. If the chunk doesn't start where the previous chunk ended, a
+// RangeError is returned. A chunk without data (bytes */total) can be used
+// to query the number of received bytes. If a chunk is interrupted, the data
+// that was received is kept, so the upload can be resumed from there.
+func (u *Uploads) Append(key, rng string, body io.Reader) (int64, io.ReadCloser, error) {
+	start, _, total, err := ParseContentRange(rng)
+	if err != nil {
+		return 0, nil, err
+	}
+	if u.maxSize > 0 && total > u.maxSize {
+		return 0, nil, &UploadSizeError{Size: total, MaxSize: u.maxSize}
+	}
+
+	u.Clean()
+	up, err := u.get(key, start, total)
+	if err != nil || up == nil {
+		return 0, nil, err
+	}
+	defer up.lock.Unlock()
+
+	if start == -1 {
+		return up.size, nil, nil
+	}
+	if start != up.size {
+		return up.size, nil, &RangeError{Start: start, Received: up.size}
+	}
+
+	n, err := io.Copy(up.file, io.LimitReader(body, up.total-up.size))
+	up.size += n
+	up.updated = time.Now()
+	if err != nil {
+		return up.size, nil, err
+	}
+	klog.V(3).Infof("received %d of %d bytes for upload %s", up.size, up.total, key)
+	if up.size < up.total {
+		return up.size, nil, nil
+	}
+
+	u.lock.Lock()
+	if u.uploads[key] == up {
+		delete(u.uploads, key)
+	}
+	u.lock.Unlock()
+	up.removed = true
+	if _, err := up.file.Seek(0, io.SeekStart); err != nil {
+		closeUpload(up)
+		return 0, nil, err
+	}
+	return up.size, &uploadReader{up}, nil
+}
+
+// get will return the locked upload with given key, and creates a new
+// upload if the chunk starts at 0. An existing upload with a different total
+// size is replaced. It returns nil if there is no upload for a status request.
+func (u *Uploads) get(key string, start, total int64) (*upload, error) {
+	for {
+		up, err := u.lookup(key, start, total)
+		if err != nil || up == nil {
+			return nil, err
+		}
+		up.lock.Lock()
+		if !up.removed {
+			return up, nil
+		}
+		// the upload was completed or removed while waiting
+		up.lock.Unlock()
+	}
+}
+
+// lookup will return the upload with given key, or create a new upload if
+// the chunk starts at 0.
+func (u *Uploads) lookup(key string, start, total int64) (*upload, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	up, ok := u.uploads[key]
+	if ok && up.total != total {
+		u.remove(key)
+		ok = false
+	}
+	if ok {
+		return up, nil
+	}
+	if start == -1 {
+		return nil, nil
+	}
+	if start != 0 {
+		return nil, &RangeError{Start: start}
+	}
+	file, err := os.CreateTemp("", "kubedock-upload-")
+	if err != nil {
+		return nil, err
+	}
+	up = &upload{file: file, total: total, updated: time.Now()}
+	u.uploads[key] = up
+	return up, nil
+}
+
+// Clean will remove the uploads that have not been updated within the ttl.
+// Uploads that are receiving data are skipped. This is done when a chunk is
+// received, and should be done periodically as well, so abandoned uploads
+// don't keep their data when no new uploads are received.
+func (u *Uploads) Clean() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for key, up := range u.uploads {
+		if !up.lock.TryLock() {
+			continue
+		}
+		stale := time.Since(up.updated) > u.ttl
+		up.lock.Unlock()
+		if stale {
+			klog.V(2).Infof("removing stale upload %s", key)
+			u.remove(key)
+		}
+	}
+}
+
+// remove will remove the upload with given key from the administration,
+// and removes its data as soon as it's not receiving data anymore.
+func (u *Uploads) remove(key string) {
+	up := u.uploads[key]
+	delete(u.uploads, key)
+	go func() {
+		up.lock.Lock()
+		defer up.lock.Unlock()
+		if up.removed {
+			// the upload was completed in the meantime
+			return
+		}
+		up.removed = true
+		closeUpload(up)
+	}()
+}
+
+// closeUpload will close and remove the temporary file of given upload.
+func closeUpload(up *upload) {
+	up.file.Close()
+	if err := os.Remove(up.file.Name()); err != nil {
+		klog.Warningf("error removing upload %s: %s", up.file.Name(), err)
+	}
+}
+
+// uploadReader is a reader of a complete upload, which removes the upload
+// when it's closed.
+type uploadReader struct {
+	*upload
+}
+
+// Read will read from the uploaded data.
+func (r *uploadReader) Read(p []byte) (int, error) {
+	return r.file.Read(p)
+}
+
+// Close will remove the upload.
+func (r *uploadReader) Close() error {
+	closeUpload(r.upload)
+	return nil
+}
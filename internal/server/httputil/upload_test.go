@@ -0,0 +1,107 @@
+package httputil
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in    string
+		start int64
+		end   int64
+		total int64
+		err   bool
+	}{
+		{in: "bytes 0-99/1000", start: 0, end: 99, total: 1000},
+		{in: "bytes 900-999/1000", start: 900, end: 999, total: 1000},
+		{in: "bytes */1000", start: -1, end: -1, total: 1000},
+		{in: "bytes 0-1000/1000", err: true},
+		{in: "bytes 10-5/1000", err: true},
+		{in: "bytes=0-99/1000", err: true},
+		{in: "0-99", err: true},
+	}
+	for i, tst := range tests {
+		start, end, total, err := ParseContentRange(tst.in)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+			continue
+		}
+		if !tst.err && (start != tst.start || end != tst.end || total != tst.total) {
+			t.Errorf("failed test %d - expected %d-%d/%d, but got %d-%d/%d", i, tst.start, tst.end, tst.total, start, end, total)
+		}
+	}
+}
+
+// failingReader returns the given data, followed by an error.
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestUploadsAppend(t *testing.T) {
+	u := NewUploads(time.Hour, 100)
+	tests := []struct {
+		rng  string
+		body io.Reader
+		size int64
+		done bool
+		err  bool
+	}{
+		{rng: "bytes */10", size: 0},
+		{rng: "bytes 0-4/101", body: strings.NewReader("01234"), err: true},
+		{rng: "bytes 3-9/10", body: strings.NewReader("3456789"), err: true},
+		{rng: "bytes 0-4/10", body: strings.NewReader("01234"), size: 5},
+		{rng: "bytes */10", size: 5},
+		{rng: "bytes 0-4/10", body: strings.NewReader("01234"), size: 5, err: true},
+		{rng: "bytes 5-9/10", body: &failingReader{data: "56"}, size: 7, err: true},
+		{rng: "bytes */10", size: 7},
+		{rng: "bytes 7-9/10", body: strings.NewReader("789"), size: 10, done: true},
+		{rng: "bytes */10", size: 0},
+	}
+	for i, tst := range tests {
+		size, rd, err := u.Append("key", tst.rng, tst.body)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+		if size != tst.size {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.size, size)
+		}
+		if (rd != nil) != tst.done {
+			t.Errorf("failed test %d - expected done %t, but got %t", i, tst.done, rd != nil)
+		}
+		if rd != nil {
+			dat, _ := io.ReadAll(rd)
+			rd.Close()
+			if string(dat) != "0123456789" {
+				t.Errorf("failed test %d - expected 0123456789, but got %s", i, dat)
+			}
+		}
+	}
+}
+
+func TestUploadsClean(t *testing.T) {
+	u := NewUploads(0, 0)
+	if _, _, err := u.Append("key", "bytes 0-4/10", strings.NewReader("01234")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	time.Sleep(time.Millisecond)
+	u.Clean()
+	u.lock.Lock()
+	n := len(u.uploads)
+	u.lock.Unlock()
+	if n != 0 {
+		t.Errorf("failed test - expected stale upload to be removed, but got %d uploads", n)
+	}
+}
@@ -2,18 +2,38 @@ package httputil
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/klog"
 )
 
-// Error will return an error response in json.
+// Error will return an error response in json. If the error defines its
+// own http status (e.g. a conflict for an ambiguous id prefix), that status
+// is used instead of the given status. If the error defines after how long
+// the request can be retried, this is added as Retry-After header.
 func Error(c *gin.Context, status int, err error) {
+	var serr interface{ HTTPStatus() int }
+	var rerr interface{ RetryAfter() time.Duration }
+	var merr *http.MaxBytesError
+	var nerr net.Error
+	if errors.As(err, &rerr) {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(rerr.RetryAfter().Seconds())))
+	}
+	if errors.As(err, &serr) {
+		status = serr.HTTPStatus()
+	} else if errors.As(err, &merr) {
+		status = http.StatusRequestEntityTooLarge
+	} else if errors.As(err, &nerr) && nerr.Timeout() {
+		status = http.StatusRequestTimeout
+	}
 	klog.Errorf("error during request[%d]: %s", status, err)
 	c.JSON(status, gin.H{
 		"message": err.Error(),
@@ -37,17 +57,35 @@ func HijackConnection(w http.ResponseWriter) (io.ReadCloser, io.Writer, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	// Clear the timeouts of the request, as hijacked connections are
+	// interactive sessions
+	_ = conn.SetDeadline(time.Time{})
 	// Flush the options to make sure the client sets the raw mode
 	_, _ = conn.Write([]byte{})
 	return conn, conn, nil
 }
 
-// UpgradeConnection will upgrade the Hijacked connection.
-func UpgradeConnection(r *http.Request, out io.Writer) {
-	if _, ok := r.Header["Upgrade"]; ok {
-		fmt.Fprint(out, "HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n")
+// StreamContentType will return the content type of a stream, which is a
+// raw stream for tty sessions, and a stdcopy multiplexed stream otherwise.
+func StreamContentType(tty bool) string {
+	if tty {
+		return "application/vnd.docker.raw-stream"
+	}
+	return "application/vnd.docker.multiplexed-stream"
+}
+
+// UpgradeConnection will upgrade the Hijacked connection. The upgrade
+// response uses the protocol that was requested by the client, as some
+// reverse proxies only pass on upgrades of specific protocols.
+func UpgradeConnection(r *http.Request, out io.Writer, tty bool) {
+	if proto, ok := r.Header["Upgrade"]; ok {
+		upgrade := "tcp"
+		if len(proto) > 0 && proto[0] != "" {
+			upgrade = proto[0]
+		}
+		fmt.Fprintf(out, "HTTP/1.1 101 UPGRADED\r\nContent-Type: %s\r\nConnection: Upgrade\r\nUpgrade: %s\r\n", StreamContentType(tty), upgrade)
 	} else {
-		fmt.Fprint(out, "HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\n")
+		fmt.Fprintf(out, "HTTP/1.1 200 OK\r\nContent-Type: %s\r\n", StreamContentType(tty))
 	}
 	fmt.Fprint(out, "\r\n")
 }
@@ -66,19 +104,39 @@ func CloseStreams(streams ...interface{}) {
 }
 
 // RequestLoggerMiddleware is a gin-gonic middleware that will log the
-// raw request.
+// raw request. The body is only read if it's logged, and errors while
+// reading the body (e.g. exceeding the max body size) are passed on to the
+// handler.
 func RequestLoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		klog.V(5).Infof("Request Headers: %#v", c.Request.Header)
+		if !klog.V(4) {
+			c.Next()
+			return
+		}
 		var buf bytes.Buffer
 		tee := io.TeeReader(c.Request.Body, &buf)
-		body, _ := io.ReadAll(tee)
-		c.Request.Body = io.NopCloser(&buf)
-		klog.V(5).Infof("Request Headers: %#v", c.Request.Header)
+		body, err := io.ReadAll(tee)
+		if err != nil {
+			c.Request.Body = io.NopCloser(io.MultiReader(&buf, &errReader{err}))
+		} else {
+			c.Request.Body = io.NopCloser(&buf)
+		}
 		klog.V(4).Infof("Request Body: %s", string(body))
 		c.Next()
 	}
 }
 
+// errReader is a reader that always returns the given error.
+type errReader struct {
+	err error
+}
+
+// Read will return the error of the reader.
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
 // reponseWriter is the writer interface used by the ResponseLoggerMiddleware
 type reponseWriter struct {
 	gin.ResponseWriter
@@ -2,21 +2,31 @@ package server
 
 import (
 	"context"
+	"net"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog"
 
 	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/reaper"
 	"github.com/joyrex2001/kubedock/internal/server/httputil"
 	"github.com/joyrex2001/kubedock/internal/server/routes"
 	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/buildkit"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+	"github.com/joyrex2001/kubedock/internal/util/myip"
 )
 
 // Server is the API server.
 type Server struct {
 	kub backend.Backend
+	cr  *common.ContextRouter
 }
 
 // New will instantiate a Server object.
@@ -31,7 +41,28 @@ func (s *Server) Run(ctx context.Context) error {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := s.getGinEngine()
+	cidrs, err := httputil.ParseCIDRs(viper.GetString("allowed-cidrs"))
+	if err != nil {
+		return err
+	}
+	if len(cidrs) > 0 {
+		klog.Infof("allowing api requests from: %s", viper.GetString("allowed-cidrs"))
+	}
+	proxies, err := httputil.ParseCIDRs(viper.GetString("trusted-proxies"))
+	if err != nil {
+		return err
+	}
+	if len(proxies) > 0 {
+		klog.Infof("trusting x-forwarded-for of proxies: %s", viper.GetString("trusted-proxies"))
+	}
+
+	if bkaddr := viper.GetString("buildkit-addr"); bkaddr != "" {
+		if _, err := buildkit.New(bkaddr); err != nil {
+			return err
+		}
+	}
+
+	router := s.getGinEngine(cidrs, proxies)
 	router.SetTrustedProxies(nil)
 
 	socket := viper.GetString("server.socket")
@@ -59,7 +90,6 @@ func (s *Server) Run(ctx context.Context) error {
 		klog.Infof("api server started listening on %s", socket)
 	}
 
-	var err error
 	select {
 	case err = <-errch:
 		break
@@ -77,20 +107,17 @@ func (s *Server) Run(ctx context.Context) error {
 }
 
 // getGinEngine will return a gin.Engine router and configure the
-// appropriate middleware.
-func (s *Server) getGinEngine() *gin.Engine {
-	router := gin.New()
-	router.Use(httputil.VersionAliasMiddleware(router))
-	router.Use(gin.Logger())
-	router.Use(httputil.RequestLoggerMiddleware())
-	router.Use(httputil.ResponseLoggerMiddleware())
-	router.Use(gin.Recovery())
-
+// appropriate middleware. Only clients in the given cidrs are allowed to
+// use the api, unless no cidrs are given. The X-Forwarded-For header is
+// only honored for requests of the given trusted proxies.
+func (s *Server) getGinEngine(cidrs, proxies []*net.IPNet) *gin.Engine {
 	insp := viper.GetBool("registry.inspector")
 	if insp {
 		klog.Infof("image inspector enabled")
 	}
 
+	pindgst := viper.GetBool("kubernetes.pin-digests")
+
 	pfwrd := viper.GetBool("port-forward")
 	if pfwrd {
 		klog.Infof("port-forwarding services to 127.0.0.1")
@@ -105,11 +132,210 @@ func (s *Server) getGinEngine() *gin.Engine {
 		revprox = false
 	}
 
+	proxyip := ""
+	if viper.GetBool("in-cluster-proxy") {
+		if pfwrd {
+			klog.Infof("ignored in-cluster-proxy as port-forward is enabled")
+		} else if ip, err := myip.Get(); err != nil {
+			klog.Errorf("error determining ip of kubedock: %s, ignoring in-cluster-proxy", err)
+		} else {
+			klog.Infof("enabled reverse-proxy services via the kubedock pod at %s", ip)
+			revprox = true
+			proxyip = ip
+		}
+	}
+
 	prea := viper.GetBool("pre-archive")
 	if prea {
 		klog.Infof("copying archives without starting containers enabled")
 	}
 
+	archh := viper.GetBool("archive-helper")
+	if archh {
+		klog.Infof("archive operations on containers that are not running enabled")
+	}
+
+	podprfx := viper.GetString("kubernetes.pod-name-prefix")
+	klog.Infof("pod name prefix: %s", podprfx)
+
+	icm := viper.GetBool("ignore-container-memory")
+
+	sepstderr := viper.GetBool("separate-stderr")
+	if sepstderr {
+		klog.Infof("separating stderr from stdout in container logs enabled")
+	}
+
+	hostnet := viper.GetBool("allow-host-network")
+	if hostnet {
+		klog.Infof("host network mode for containers enabled")
+	}
+
+	devices := common.ParseAllowedDevices(viper.GetString("allowed-devices"))
+	if len(devices) > 0 {
+		klog.Infof("allowed devices: %s", viper.GetString("allowed-devices"))
+	}
+
+	unsafesys := viper.GetBool("allow-unsafe-sysctls")
+	if unsafesys {
+		klog.Infof("unsafe sysctls for containers enabled")
+	}
+
+	strict := viper.GetBool("strict-create")
+	if strict {
+		klog.Infof("rejecting containers with unsupported features enabled")
+	}
+
+	lenient := viper.GetBool("lenient-network-create")
+	if lenient {
+		klog.Infof("returning existing networks on duplicate network create enabled")
+	}
+
+	budget := viper.GetDuration("start-latency-budget")
+	if budget > 0 {
+		klog.Infof("container start latency budget: %s", budget)
+	}
+
+	klog.Infof("using namespace: %s", viper.GetString("kubernetes.namespace"))
+
+	admtok := viper.GetString("admin-token")
+	if admtok != "" {
+		klog.Infof("admin api enabled")
+	}
+
+	regprox := viper.GetBool("registry.proxy")
+	if regprox {
+		klog.Infof("registry pull-through proxy enabled at /v2/")
+	}
+
+	bkaddr := viper.GetString("buildkit-addr")
+	if bkaddr != "" {
+		klog.Infof("bridging builds to buildkit daemon at %s", bkaddr)
+	}
+
+	dashboard := viper.GetBool("dashboard")
+	if dashboard {
+		klog.Infof("dashboard enabled at /kubedock/dashboard")
+	}
+
+	maxstrms := viper.GetInt("max-streams")
+	evtqs := viper.GetInt("event-queue-size")
+	klog.Infof("max streams: %d, event queue size: %d", maxstrms, evtqs)
+	events.SetQueueSize(evtqs)
+
+	cachettl := viper.GetDuration("list-cache-ttl")
+	if cachettl > 0 {
+		klog.Infof("caching list responses for %s", cachettl)
+	}
+
+	info := common.InfoConfig{}
+	if err := viper.UnmarshalKey("info", &info); err != nil {
+		klog.Errorf("error parsing info settings: %s, ignoring info settings", err)
+		info = common.InfoConfig{}
+	} else if err := common.ValidateInfoConfig(info); err != nil {
+		klog.Errorf("%s, ignoring info settings", err)
+		info = common.InfoConfig{}
+	}
+	klog.Infof("reported system info: %+v", info.WithDefaults())
+
+	maxbody := int64(0)
+	if val := viper.GetString("max-body-size"); val != "" && val != "0" {
+		qty, err := resource.ParseQuantity(val)
+		if err != nil {
+			klog.Errorf("invalid max body size %s: %s, not limiting request bodies", val, err)
+		} else {
+			maxbody = qty.Value()
+			klog.Infof("max request body size: %s", val)
+		}
+	}
+
+	maxupload := int64(0)
+	if val := viper.GetString("max-upload-size"); val != "" && val != "0" {
+		qty, err := resource.ParseQuantity(val)
+		if err != nil {
+			klog.Errorf("invalid max upload size %s: %s, not limiting uploads", val, err)
+		} else {
+			maxupload = qty.Value()
+		}
+	}
+	rdtmo := viper.GetDuration("read-timeout")
+	wrtmo := viper.GetDuration("write-timeout")
+	idletmo := viper.GetDuration("stream-idle-timeout")
+	if rdtmo > 0 || wrtmo > 0 || idletmo > 0 {
+		klog.Infof("read timeout: %s, write timeout: %s, stream idle timeout: %s", rdtmo, wrtmo, idletmo)
+	}
+
+	origins := []string{}
+	for _, origin := range strings.Split(viper.GetString("cors-allowed-origins"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) > 0 {
+		klog.Infof("allowing cors requests from: %s", strings.Join(origins, ", "))
+	}
+	pathpfx := viper.GetString("path-prefix")
+	if pathpfx != "" {
+		klog.Infof("serving api under path prefix %s", pathpfx)
+	}
+	trustfwd := viper.GetBool("trust-forwarded-headers")
+	if trustfwd {
+		klog.Infof("honoring x-forwarded headers")
+	}
+	recdir := viper.GetString("record")
+
+	cfg := getContainerDefaults()
+	cfg.Inspector = insp
+	cfg.PinDigests = pindgst
+	cfg.PortForward = pfwrd
+	cfg.ReverseProxy = revprox
+	cfg.ProxyHostIP = proxyip
+	cfg.AllowedCIDRs = cidrs
+	cfg.TrustedProxies = proxies
+	cfg.PreArchive = prea
+	cfg.ArchiveHelper = archh
+	cfg.NamePrefix = podprfx
+	cfg.IgnoreContainerMemory = icm
+	cfg.ReadinessTimeout = viper.GetDuration("kubernetes.timeout")
+	cfg.SeparateStderr = sepstderr
+	cfg.AllowHostNetwork = hostnet
+	cfg.AllowUnsafeSysctls = unsafesys
+	cfg.AllowedDevices = devices
+	cfg.StrictCreate = strict
+	cfg.LenientNetworkCreate = lenient
+	cfg.StartLatencyBudget = budget
+	cfg.Socket = viper.GetString("server.socket")
+	cfg.AdminToken = admtok
+	cfg.Dashboard = dashboard
+	cfg.RegistryProxy = regprox
+	cfg.BuildkitAddr = bkaddr
+	cfg.MaxStreams = maxstrms
+	cfg.ListCacheTTL = cachettl
+	cfg.Info = info
+	cfg.MaxBodySize = maxbody
+	cfg.MaxUploadSize = maxupload
+	cfg.ReadTimeout = rdtmo
+	cfg.WriteTimeout = wrtmo
+	cfg.StreamIdleTimeout = idletmo
+	cfg.CORSAllowedOrigins = origins
+	cfg.PathPrefix = pathpfx
+	cfg.TrustForwardedHeaders = trustfwd
+	cfg.RecordDir = recdir
+
+	cr, err := common.NewContextRouter(s.kub, cfg)
+	if err != nil {
+		klog.Errorf("error setting up context: %s", err)
+	}
+	s.cr = cr
+	if err := reaper.AddUploads(cr.Uploads); err != nil {
+		klog.Warningf("not removing abandoned uploads: %s", err)
+	}
+
+	return NewRouter(cr)
+}
+
+// getContainerDefaults will return a configuration with the defaults for
+// containers that can be reloaded at runtime.
+func getContainerDefaults() common.Config {
 	reqcpu := viper.GetString("kubernetes.request-cpu")
 	if reqcpu != "" {
 		klog.Infof("default cpu request: %s", reqcpu)
@@ -124,47 +350,104 @@ func (s *Server) getGinEngine() *gin.Engine {
 		klog.Infof("default runas user: %s", runasuid)
 	}
 
+	fsgroup := viper.GetString("kubernetes.fs-group")
+	if fsgroup != "" {
+		klog.Infof("default fs group: %s", fsgroup)
+	}
+
 	nodesel := viper.GetString("kubernetes.node-selector")
 	if nodesel != "" {
 		klog.Infof("default node selector: %s", nodesel)
 	}
 
 	pulpol := viper.GetString("kubernetes.pull-policy")
+	if err := types.ValidatePullPolicy(pulpol); err != nil {
+		klog.Errorf("%s, using ifnotpresent instead", err)
+		pulpol = "ifnotpresent"
+	}
 	klog.Infof("default image pull policy: %s", pulpol)
 
 	sa := viper.GetString("kubernetes.service-account")
 	klog.Infof("service account used in deployments: %s", sa)
 
-	podprfx := viper.GetString("kubernetes.pod-name-prefix")
-	klog.Infof("pod name prefix: %s", podprfx)
-
-	ads := viper.GetInt64("kubernetes.active-deadline-seconds")
-
-	icm := viper.GetBool("ignore-container-memory")
-
-	klog.Infof("using namespace: %s", viper.GetString("kubernetes.namespace"))
+	readiness := viper.GetString("kubernetes.readiness")
+	if err := types.ValidateReadiness(readiness); err != nil {
+		klog.Errorf("%s, using running instead", err)
+		readiness = types.ReadinessRunning
+	}
+	klog.Infof("default container readiness: %s", readiness)
+
+	imgdefs := []common.ImageDefaults{}
+	if err := viper.UnmarshalKey("image-defaults", &imgdefs); err != nil {
+		klog.Errorf("error parsing image defaults: %s, ignoring image defaults", err)
+		imgdefs = []common.ImageDefaults{}
+	} else if err := common.ValidateImageDefaults(imgdefs); err != nil {
+		klog.Errorf("%s, ignoring image defaults", err)
+		imgdefs = []common.ImageDefaults{}
+	}
+	for _, def := range imgdefs {
+		klog.Infof("image defaults for %s: %+v", def.Image, def)
+	}
 
-	cr, err := common.NewContextRouter(s.kub, common.Config{
-		Inspector:             insp,
+	return common.Config{
+		ImageDefaults:         imgdefs,
 		RequestCPU:            reqcpu,
 		RequestMemory:         reqmem,
 		ServiceAccount:        sa,
 		RunasUser:             runasuid,
+		FSGroup:               fsgroup,
 		NodeSelector:          nodesel,
 		PullPolicy:            pulpol,
-		PortForward:           pfwrd,
-		ReverseProxy:          revprox,
-		PreArchive:            prea,
-		NamePrefix:            podprfx,
-		ActiveDeadlineSeconds: ads,
-		IgnoreContainerMemory: icm,
-	})
+		ActiveDeadlineSeconds: viper.GetInt64("kubernetes.active-deadline-seconds"),
+		Readiness:             readiness,
+	}
+}
+
+// Reload will apply the settings that can be changed at runtime, such as
+// the container defaults and the image rewrite rules, using the current
+// configuration.
+func (s *Server) Reload() error {
+	if s.cr == nil {
+		return nil
+	}
+	imgrw, err := image.ParseRewriteRules(viper.GetString("kubernetes.image-rewrite"))
 	if err != nil {
-		klog.Errorf("error setting up context: %s", err)
+		return err
 	}
+	for _, rule := range imgrw {
+		klog.Infof("rewriting image references %s to %s", rule.From, rule.To)
+	}
+	s.kub.SetImageRewrites(imgrw)
+	s.cr.ReloadDefaults(getContainerDefaults())
+	return nil
+}
+
+// NewRouter will return a gin.Engine router with the appropriate middleware
+// that serves all api routes using the given context. This can be used to
+// test the routes in combination with a fake backend.
+func NewRouter(cr *common.ContextRouter) *gin.Engine {
+	router := gin.New()
+	router.Use(httputil.AllowlistMiddleware(cr.Config.AllowedCIDRs, cr.Config.TrustedProxies))
+	router.Use(httputil.PathPrefixMiddleware(router, cr.Config.PathPrefix, cr.Config.TrustForwardedHeaders))
+	router.Use(httputil.VersionAliasMiddleware(router))
+	router.Use(httputil.RecordMiddleware(cr.Recorder))
+	router.Use(gin.Logger())
+	router.Use(httputil.CORSMiddleware(cr.Config.CORSAllowedOrigins))
+	router.Use(httputil.LimitMiddleware(httputil.Limits{
+		MaxBodySize:       cr.Config.MaxBodySize,
+		ReadTimeout:       cr.Config.ReadTimeout,
+		WriteTimeout:      cr.Config.WriteTimeout,
+		StreamIdleTimeout: cr.Config.StreamIdleTimeout,
+	}))
+	router.Use(httputil.DecodeMiddleware(cr.Config.MaxBodySize))
+	router.Use(httputil.RequestLoggerMiddleware())
+	router.Use(httputil.ResponseLoggerMiddleware())
+	router.Use(gin.Recovery())
+	router.Use(cr.Cache.InvalidateMiddleware())
 
 	routes.RegisterDockerRoutes(router, cr)
 	routes.RegisterLibpodRoutes(router, cr)
+	routes.RegisterKubedockRoutes(router, cr)
 
 	return router
 }
@@ -0,0 +1,15 @@
+package server_test
+
+import (
+	"github.com/joyrex2001/kubedock/internal/server/servertest"
+)
+
+// The tests of the api routes are kept with the route packages (see
+// routes/common, routes/docker, routes/libpod and routes/kubedock), and
+// share the helpers of servertest. The tests of this package use the same
+// helpers.
+var (
+	newTestRouter           = servertest.NewRouter
+	doRequest               = servertest.Do
+	createContainerWithBody = servertest.CreateContainerWithBody
+)
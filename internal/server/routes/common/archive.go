@@ -5,21 +5,32 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/klog"
 
+	"github.com/joyrex2001/kubedock/internal/backend"
 	"github.com/joyrex2001/kubedock/internal/model/types"
 	"github.com/joyrex2001/kubedock/internal/server/httputil"
 	"github.com/joyrex2001/kubedock/internal/util/tar"
 )
 
+// maxPreArchiveSize is the max size of an archive that is copied as a
+// pre-archive, which is limited by the max size of a configmap.
+const maxPreArchiveSize = 1024 * 1024
+
 // PutArchive - extract an archive of files or folders to a directory in a container.
+// Large archives can be uploaded in chunks with a Content-Range header (e.g.
+// bytes 0-1048575/4194304), which allows resuming an interrupted upload. An
+// incomplete upload is responded with 308 and a Range header that contains
+// the received bytes, which can also be queried with bytes */total.
 // https://docs.docker.com/engine/api/v1.41/#operation/PutContainerArchive
 // https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/PutContainerArchiveLibpod
 // PUT "/containers/:id/archive"
@@ -49,39 +60,84 @@ func PutArchive(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 
-	archive, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		httputil.Error(c, http.StatusNotFound, err)
-		return
+	body := c.Request.Body
+	if rng := c.GetHeader("Content-Range"); rng != "" {
+		size, upload, err := cr.Uploads.Append(tainr.ID+":"+path, rng, body)
+		if size > 0 && upload == nil {
+			c.Header("Range", fmt.Sprintf("bytes=0-%d", size-1))
+		}
+		if err != nil {
+			httputil.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		if upload == nil {
+			c.Status(http.StatusPermanentRedirect)
+			return
+		}
+		defer upload.Close()
+		body = upload
 	}
 
-	if !tainr.Running && !tainr.Completed && cr.Config.PreArchive && tar.IsSingleFileArchive(archive) {
-		tainr.PreArchives = append(tainr.PreArchives, types.PreArchive{Path: path, Archive: archive})
-		klog.V(2).Infof("adding prearchive: %v", tainr.PreArchives)
-		if err := cr.DB.SaveContainer(tainr); err != nil {
+	if !tainr.Running && !tainr.IsLinked() && cr.Config.ArchiveHelper {
+		helper, done, err := getArchiveContainer(cr, tainr)
+		if err != nil {
+			httputil.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		defer done()
+		file, err := cr.Backend.StageArchive(helper, body)
+		if err != nil {
 			httputil.Error(c, http.StatusInternalServerError, err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"message": "planned archive to be copied to container",
-		})
+		klog.V(2).Infof("staged archive for %s in %s", path, tainr.ShortID)
+		if _, err := cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+			rec.StagedArchives = append(rec.StagedArchives, types.PreArchive{Path: path, File: file})
+			return nil
+		}); err != nil {
+			httputil.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.Status(http.StatusOK)
 		return
 	}
 
-	if !tainr.Running && !tainr.Completed && !cr.Config.PreArchive {
-		if err := StartContainer(cr, tainr); err != nil {
-			httputil.Error(c, http.StatusInternalServerError, err)
+	var reader io.Reader = body
+	if !tainr.Running && !tainr.Completed && cr.Config.PreArchive {
+		// pre-archives are stored in a configmap, so only small archives
+		// are read in memory to check if they qualify
+		archive, err := io.ReadAll(io.LimitReader(body, maxPreArchiveSize+1))
+		if err != nil {
+			httputil.Error(c, http.StatusNotFound, err)
+			return
+		}
+		if len(archive) <= maxPreArchiveSize && tar.IsSingleFileArchive(archive) {
+			klog.V(2).Infof("adding prearchive for %s in %s", path, tainr.ShortID)
+			if _, err := cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+				rec.PreArchives = append(rec.PreArchives, types.PreArchive{Path: path, Archive: archive})
+				return nil
+			}); err != nil {
+				httputil.Error(c, http.StatusInternalServerError, err)
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message": "planned archive to be copied to container",
+			})
 			return
 		}
+		reader = io.MultiReader(bytes.NewReader(archive), body)
 	}
 
-	reader, writer := io.Pipe()
-	go func() {
-		writer.Write(archive)
-		writer.Close()
-	}()
+	if !tainr.Running && !tainr.Completed && !cr.Config.PreArchive {
+		if err := StartContainer(c.Request.Context(), cr, tainr); err != nil {
+			httputil.Error(c, StartErrorStatus(err), err)
+			return
+		}
+	}
 
-	if err := cr.Backend.CopyToContainer(tainr, reader, path, tar.IsCompressed(archive[:5])); err != nil {
+	br := bufio.NewReader(reader)
+	head, _ := br.Peek(5)
+	if err := cr.Backend.CopyToContainer(tainr, br, path, tar.IsCompressed(head)); err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
@@ -107,27 +163,21 @@ func HeadArchive(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 
-	exists, err := cr.Backend.FileExistsInContainer(tainr, path)
+	tainr, done, err := getArchiveContainer(cr, tainr)
 	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
+	defer done()
 
-	if !exists {
-		httputil.Error(c, http.StatusNotFound, fmt.Errorf("file not found"))
-		return
-	}
-
-	mode, err := cr.Backend.GetFileModeInContainer(tainr, path)
+	stat, err := cr.Backend.GetFileStatInContainer(tainr, path)
 	if err != nil {
-		httputil.Error(c, http.StatusInternalServerError, err)
+		httputil.Error(c, getStatErrorStatus(err), err)
 		return
 	}
 
-	stat, _ := json.Marshal(gin.H{"name": path, "linkTarget": path, "mode": mode})
-
+	c.Writer.Header().Set("X-Docker-Container-Path-Stat", getPathStatHeader(stat))
 	c.Writer.WriteHeader(http.StatusOK)
-	c.Writer.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
 }
 
 // GetArchive - get a tar archive of a resource in the filesystem of container id.
@@ -148,19 +198,21 @@ func GetArchive(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 
-	exists, err := cr.Backend.FileExistsInContainer(tainr, path)
+	tainr, done, err := getArchiveContainer(cr, tainr)
 	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
+	defer done()
 
-	if !exists {
-		httputil.Error(c, http.StatusNotFound, fmt.Errorf("file not found"))
+	stat, err := cr.Backend.GetFileStatInContainer(tainr, path)
+	if err != nil {
+		httputil.Error(c, getStatErrorStatus(err), err)
 		return
 	}
 
 	var b bytes.Buffer
-	if err := cr.Backend.CopyFromContainer(tainr, path, bufio.NewWriter(&b)); err != nil {
+	if err := cr.Backend.CopyFromContainer(tainr, path, &b); err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
@@ -172,10 +224,65 @@ func GetArchive(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 
-	stat, _ := json.Marshal(gin.H{"name": path, "size": size, "mode": fs.ModePerm, "linkTarget": path, "mtime": "2021-01-01T20:00:00Z"})
+	enc := httputil.AcceptedEncoding(c.Request)
+	w, err := httputil.NewEncoder(enc, c.Writer)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
 
-	c.Writer.WriteHeader(http.StatusOK)
 	c.Writer.Header().Set("Content-Type", "application/x-tar")
-	c.Writer.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
-	c.Writer.Write(dat[:size])
+	c.Writer.Header().Set("X-Docker-Container-Path-Stat", getPathStatHeader(stat))
+	if enc != "" {
+		c.Writer.Header().Set("Content-Encoding", enc)
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+	if _, err := w.Write(dat[:size]); err != nil {
+		klog.Errorf("error writing archive: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		klog.Errorf("error writing archive: %s", err)
+	}
+}
+
+// getPathStatHeader will return the X-Docker-Container-Path-Stat header
+// value for the given file stat.
+func getPathStatHeader(stat *backend.FileStat) string {
+	dat, _ := json.Marshal(gin.H{
+		"name":       stat.Name,
+		"size":       stat.Size,
+		"mode":       uint32(stat.Mode),
+		"mtime":      stat.ModTime.Format(time.RFC3339Nano),
+		"linkTarget": stat.LinkTarget,
+	})
+	return base64.StdEncoding.EncodeToString(dat)
+}
+
+// getStatErrorStatus will return the http status for the given error that
+// was returned when retrieving the stat of a file.
+func getStatErrorStatus(err error) int {
+	if errors.Is(err, fs.ErrNotExist) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// getArchiveContainer will return the container that should be used for
+// reading files of the given container. If the container is not running and
+// the archive helper is enabled, this is a helper container, which will be
+// removed when the returned done function is called.
+func getArchiveContainer(cr *ContextRouter, tainr *types.Container) (*types.Container, func(), error) {
+	if tainr.Running || tainr.IsLinked() || !cr.Config.ArchiveHelper {
+		return tainr, func() {}, nil
+	}
+	helper, err := cr.Backend.StartArchiveHelper(tainr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return helper, func() {
+		if err := cr.Backend.DeleteContainer(helper); err != nil {
+			klog.Warningf("error removing archive helper %s: %s", helper.ShortID, err)
+		}
+	}, nil
 }
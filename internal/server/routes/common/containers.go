@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,7 +14,10 @@ import (
 
 	"github.com/joyrex2001/kubedock/internal/backend"
 	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/filter"
 	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/util/detach"
 )
 
 // ContainerStart - start a container.
@@ -28,16 +32,21 @@ func ContainerStart(cr *ContextRouter, c *gin.Context) {
 		httputil.Error(c, http.StatusNotFound, err)
 		return
 	}
+	tainr, err = UpdateStartTimeout(cr, c, tainr)
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
 	if !tainr.Running && !tainr.Completed {
-		if err := StartContainer(cr, tainr); err != nil {
-			httputil.Error(c, http.StatusInternalServerError, err)
+		if err := StartContainer(c.Request.Context(), cr, tainr); err != nil {
+			httputil.Error(c, StartErrorStatus(err), err)
 			return
 		}
 	} else {
 		klog.Warningf("container %s already running", id)
 	}
 
-	cr.Events.Publish(tainr.ID, events.Container, events.Start)
+	PublishContainerEvent(cr, tainr, events.Start)
 
 	c.Writer.WriteHeader(http.StatusNoContent)
 }
@@ -66,25 +75,23 @@ func ContainerRestart(cr *ContextRouter, c *gin.Context) {
 		klog.Warningf("error while watching k8s container delete: %s", err)
 	}
 
-	if err := cr.Backend.DeleteContainer(tainr); err != nil {
+	if err := DeleteContainer(cr, tainr); err != nil {
 		klog.Warningf("error while deleting k8s container: %s", err)
 	}
 	tainr.SignalDetach()
 	tainr.SignalStop()
+	StopLinkedContainers(cr, tainr)
 
-	tainr.Running = false
-	tainr.Completed = false
-	tainr.Stopped = true
-
-	if err := cr.DB.SaveContainer(tainr); err != nil {
+	tainr, err = SetContainerStopped(cr, tainr)
+	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	<-deleted
 
-	if err := StartContainer(cr, tainr); err != nil {
-		httputil.Error(c, http.StatusInternalServerError, err)
+	if err := StartContainer(c.Request.Context(), cr, tainr); err != nil {
+		httputil.Error(c, StartErrorStatus(err), err)
 		return
 	}
 
@@ -108,21 +115,19 @@ func ContainerStop(cr *ContextRouter, c *gin.Context) {
 	tainr.SignalStop()
 
 	if !tainr.Stopped && !tainr.Killed {
-		if err := cr.Backend.DeleteContainer(tainr); err != nil {
+		if err := DeleteContainer(cr, tainr); err != nil {
 			klog.Warningf("error while deleting k8s container: %s", err)
 		}
+		StopLinkedContainers(cr, tainr)
 	}
 
-	tainr.Running = false
-	tainr.Completed = false
-	tainr.Stopped = true
-
-	if err := cr.DB.SaveContainer(tainr); err != nil {
+	tainr, err = SetContainerStopped(cr, tainr)
+	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	cr.Events.Publish(tainr.ID, events.Container, events.Die)
+	PublishContainerEvent(cr, tainr, events.Die)
 
 	c.Writer.WriteHeader(http.StatusNoContent)
 }
@@ -159,21 +164,24 @@ func ContainerKill(cr *ContextRouter, c *gin.Context) {
 	tainr.SignalStop()
 
 	if !tainr.Stopped && !tainr.Killed {
-		if err := cr.Backend.DeleteContainer(tainr); err != nil {
+		if err := DeleteContainer(cr, tainr); err != nil {
 			klog.Warningf("error while deleting k8s container: %s", err)
 		}
+		StopLinkedContainers(cr, tainr)
 	}
 
-	tainr.Killed = true
-	tainr.Running = false
-	tainr.Completed = false
-
-	if err := cr.DB.SaveContainer(tainr); err != nil {
+	tainr, err = cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		rec.Killed = true
+		rec.Running = false
+		rec.Completed = false
+		return nil
+	})
+	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	cr.Events.Publish(tainr.ID, events.Container, events.Die)
+	PublishContainerEvent(cr, tainr, events.Die)
 
 	c.Writer.WriteHeader(http.StatusNoContent)
 }
@@ -200,14 +208,27 @@ func ContainerAttach(cr *ContextRouter, c *gin.Context) {
 	// TTY is not a query param available in the containerAttachRequest so it is retrieved from the containerCreate req
 	tty := tainr.Tty
 
+	keys, err := detach.ParseKeys(c.Query("detachKeys"))
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
 	if !stream {
 		c.Writer.WriteHeader(http.StatusNoContent)
 		return
 	}
 
 	if !tainr.Running && !tainr.Completed {
-		if err := StartContainer(cr, tainr); err != nil {
-			httputil.Error(c, http.StatusInternalServerError, err)
+		if err := StartContainer(c.Request.Context(), cr, tainr); err != nil {
+			httputil.Error(c, StartErrorStatus(err), err)
+			return
+		}
+	}
+
+	if !tainr.Completed && !tainr.Stopped {
+		if err := cr.Backend.CheckFeature(backend.FeatureAttach); err != nil {
+			httputil.Error(c, http.StatusNotImplemented, err)
 			return
 		}
 	}
@@ -222,13 +243,13 @@ func ContainerAttach(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 	defer httputil.CloseStreams(in, out)
-	httputil.UpgradeConnection(r, out)
+	httputil.UpgradeConnection(r, out, tty)
 
 	stop := make(chan struct{}, 1)
 	tainr.AddAttachChannel(stop)
 
 	defer tainr.SignalDetach()
-	defer cr.Events.Publish(tainr.ID, events.Container, events.Detach)
+	defer PublishContainerEvent(cr, tainr, events.Detach)
 
 	if tainr.Completed || tainr.Stopped {
 		count := uint64(100)
@@ -247,6 +268,14 @@ func ContainerAttach(cr *ContextRouter, c *gin.Context) {
 
 	attachDone := make(chan struct{}, 1)
 
+	// detach keys are only applicable for interactive tty sessions
+	var stdinr io.Reader = in
+	var detached <-chan struct{}
+	if stdin && tty {
+		dr := detach.NewReader(in, keys)
+		stdinr, detached = dr, dr.Detached()
+	}
+
 	// Start streaming to/from the container
 	go func() {
 		defer close(attachDone)
@@ -254,7 +283,7 @@ func ContainerAttach(cr *ContextRouter, c *gin.Context) {
 			tainr,
 			func() io.Reader {
 				if stdin {
-					return in
+					return stdinr
 				}
 				return nil
 			}(),
@@ -265,12 +294,10 @@ func ContainerAttach(cr *ContextRouter, c *gin.Context) {
 				return nil
 			}(),
 			func() io.Writer {
+				// stderr is merged into stdout for tty sessions, and framed
+				// as stderr on the same stream otherwise
 				if stderr {
-					// Docker expects stderr merged if TTY is enabled
-					if tty {
-						return out
-					}
-					return out // or multiplex if you implement it separately
+					return out
 				}
 				return nil
 			}(),
@@ -287,6 +314,8 @@ func ContainerAttach(cr *ContextRouter, c *gin.Context) {
 		klog.Infof("detach signal received for container %s", tainr.ID)
 	case <-attachDone:
 		klog.Infof("attach session finished for container %s", tainr.ID)
+	case <-detached:
+		klog.Infof("detached from container %s", tainr.ID)
 	}
 }
 
@@ -297,13 +326,18 @@ func ContainerAttach(cr *ContextRouter, c *gin.Context) {
 // POST "/libpod/containers/:id/rezise"
 func ContainerResize(cr *ContextRouter, c *gin.Context) {
 	id := c.Param("id")
-	_, err := cr.DB.GetContainerByNameOrID(id)
+	tainr, err := cr.DB.GetContainerByNameOrID(id)
 	if err != nil {
 		httputil.Error(c, http.StatusNotFound, err)
 		return
 	}
+	width, height, err := parseTerminalSize(c)
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	tainr.TerminalSizes().Resize(width, height)
 	c.JSON(http.StatusOK, gin.H{})
-	return
 }
 
 // ContainerRename - rename a container.
@@ -319,14 +353,78 @@ func ContainerRename(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 	name := c.Query("name")
-	if _, err := cr.DB.GetContainerByName(name); err == nil {
-		httputil.Error(c, http.StatusConflict, fmt.Errorf("name `%s` already in used", name))
+	if name == "" {
+		httputil.Error(c, http.StatusBadRequest, fmt.Errorf("no new name specified for container %s", tainr.ShortID))
+		return
+	}
+	name, err = ContainerName(cr, tainr.ID, name)
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
 		return
 	}
-	tainr.Name = name
-	if err := cr.DB.SaveContainer(tainr); err != nil {
+	old, aliased := "", false
+	tainr, err = cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		old = rec.Name
+		aliased = renameNetworkAlias(rec, old, name)
+		rec.Name = name
+		return nil
+	})
+	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
+	if aliased && tainr.Running {
+		if err := cr.Backend.UpdateServices(tainr); err != nil {
+			klog.Warningf("error updating services of container %s: %s", tainr.ShortID, err)
+		}
+	}
+	attrs := getContainerEventAttributes(tainr, events.Rename)
+	attrs["oldName"] = "/" + old
+	cr.Events.PublishWithAttributes(tainr.ID, events.Container, events.Rename, attrs)
 	c.Writer.WriteHeader(http.StatusNoContent)
 }
+
+// FilterContainers will return all containers that match given filter. If
+// the filter requires a label, the containers are pre-selected using the
+// label index, instead of matching all containers.
+func FilterContainers(cr *ContextRouter, filtr *filter.Filter) ([]*types.Container, error) {
+	var tainrs []*types.Container
+	var err error
+	if key, val, ok := filtr.Label(); ok {
+		tainrs, err = cr.DB.GetContainersByLabel(key, val)
+	} else {
+		tainrs, err = cr.DB.GetContainers()
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := []*types.Container{}
+	for _, tainr := range tainrs {
+		if filtr.Match(tainr) {
+			res = append(res, tainr)
+		}
+	}
+	return res, nil
+}
+
+// renameNetworkAlias will replace the network alias of given container that
+// refers to its old name with the new name, so the container remains
+// resolvable by its name. It returns true if the aliases were changed.
+func renameNetworkAlias(tainr *types.Container, old, name string) bool {
+	if old == "" {
+		return false
+	}
+	aliases := []string{}
+	changed := false
+	for _, alias := range tainr.NetworkAliases {
+		if strings.EqualFold(alias, old) {
+			changed = true
+			alias = strings.ToLower(name)
+		}
+		if !slices.Contains(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	tainr.NetworkAliases = aliases
+	return changed
+}
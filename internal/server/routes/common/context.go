@@ -1,11 +1,19 @@
 package common
 
 import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
 	"golang.org/x/time/rate"
+	"k8s.io/klog"
 
 	"github.com/joyrex2001/kubedock/internal/backend"
 	"github.com/joyrex2001/kubedock/internal/events"
 	"github.com/joyrex2001/kubedock/internal/model"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/util/recording"
 )
 
 const (
@@ -13,26 +21,40 @@ const (
 	PollRate = 1
 	// PollBurst defines maximum burst poll requests towards the backend
 	PollBurst = 3
+	// UploadTTL defines how long incomplete resumable uploads are kept
+	UploadTTL = time.Hour
 )
 
 // Config is the structure to instantiate a Router object
 type Config struct {
 	// Inspector specifies if the image inspect feature is enabled
 	Inspector bool
+	// PinDigests specifies if image tags are pinned to their digest, which
+	// is then reported when inspecting images
+	PinDigests bool
 	// PortForward specifies if the the services should be port-forwarded
 	PortForward bool
 	// ReverseProxy enables a reverse-proxy to the services via 0.0.0.0 on the kubedock host
 	ReverseProxy bool
+	// ProxyHostIP contains the ip that is reported as host ip of reverse
+	// proxied ports (e.g. the ip of the kubedock pod); if empty, the ip of
+	// the pod of the container is reported
+	ProxyHostIP string
 	// RequestCPU contains an optional default k8s cpu request
 	RequestCPU string
 	// RequestMemory contains an optional default k8s memory request
 	RequestMemory string
 	// RunasUser contains the UID to run pods as
 	RunasUser string
+	// FSGroup contains the GID that owns the volumes of the pods
+	FSGroup string
 	// PullPolicy contains the default pull policy for images
 	PullPolicy string
 	// PreArchive will enable copying files without starting containers
 	PreArchive bool
+	// ArchiveHelper will enable archive operations on containers that are
+	// not running, by staging archives and using helper pods
+	ArchiveHelper bool
 	// ServiceAccount contains the service account name to be used for running containers
 	ServiceAccount string
 	// ActiveDeadlineSeconds contains the active deadline seconds to be used for running containers
@@ -43,15 +65,91 @@ type Config struct {
 	NodeSelector string
 	// IgnoreContainerMemory is used to ignore Docker memory settings and use requests/limits from Kubedock config
 	IgnoreContainerMemory bool
+	// Readiness contains the default condition for a container to be considered started
+	Readiness string
+	// ReadinessTimeout contains the max amount of time to wait for published ports to be reachable
+	ReadinessTimeout time.Duration
+	// SeparateStderr enables tagging stderr to separate it from stdout in logs
+	SeparateStderr bool
+	// AllowHostNetwork enables the host network mode for containers
+	AllowHostNetwork bool
+	// AllowedDevices contains the devices that containers can use, mapped
+	// to a device plugin resource (or empty for a hostPath volume)
+	AllowedDevices map[string]string
+	// AllowUnsafeSysctls enables sysctls that are not considered safe by k8s
+	AllowUnsafeSysctls bool
+	// StrictCreate will reject containers that use unsupported features
+	StrictCreate bool
+	// LenientNetworkCreate will return the existing network when a network
+	// is created with a name that is already in use, instead of a conflict
+	LenientNetworkCreate bool
+	// StartLatencyBudget contains the duration after which a slow container start is reported (optional)
+	StartLatencyBudget time.Duration
+	// Socket contains the unix socket kubedock is listening on (optional)
+	Socket string
+	// ImageDefaults contains defaults for containers that use specific images
+	ImageDefaults []ImageDefaults
+	// AdminToken contains the bearer token for the admin api (optional)
+	AdminToken string
+	// Dashboard enables the web dashboard
+	Dashboard bool
+	// RegistryProxy enables the pull-through proxy for the registry v2 api
+	RegistryProxy bool
+	// BuildkitAddr is the address of the buildkit daemon builds are
+	// bridged to (optional)
+	BuildkitAddr string
+	// MaxStreams contains the maximum number of simultaneous streaming
+	// connections (followed logs and events); 0 is unlimited
+	MaxStreams int
+	// ListCacheTTL contains the duration list responses are cached; 0
+	// disables caching
+	ListCacheTTL time.Duration
+	// Info contains the system information reported by the info endpoints
+	Info InfoConfig
+	// MaxBodySize contains the max size of request bodies; 0 is unlimited
+	MaxBodySize int64
+	// MaxUploadSize contains the max total size of chunked uploads; 0 is
+	// unlimited
+	MaxUploadSize int64
+	// ReadTimeout contains the max duration to read a request body; 0 is
+	// unlimited
+	ReadTimeout time.Duration
+	// WriteTimeout contains the max duration to write a (non-streaming)
+	// response; 0 is unlimited
+	WriteTimeout time.Duration
+	// StreamIdleTimeout contains the max duration a streaming response can
+	// be idle; 0 is unlimited
+	StreamIdleTimeout time.Duration
+	// CORSAllowedOrigins contains the origins browser clients can use the
+	// api from (* allows all origins)
+	CORSAllowedOrigins []string
+	// PathPrefix contains the path prefix kubedock is served under (optional)
+	PathPrefix string
+	// TrustForwardedHeaders enables the X-Forwarded-* headers of a proxy
+	TrustForwardedHeaders bool
+	// AllowedCIDRs contains the networks of the clients that are allowed to
+	// use the api; if empty, all clients are allowed
+	AllowedCIDRs []*net.IPNet
+	// TrustedProxies contains the networks of the reverse proxies of which
+	// the X-Forwarded-For header is honored
+	TrustedProxies []*net.IPNet
+	// RecordDir contains the directory requests and responses are recorded
+	// to (optional)
+	RecordDir string
 }
 
 // ContextRouter is the object that contains shared context for the kubedock API endpoints.
 type ContextRouter struct {
-	Config  Config
-	DB      *model.Database
-	Backend backend.Backend
-	Events  events.Events
-	Limiter *rate.Limiter
+	Config   Config
+	DB       *model.Database
+	Backend  backend.Backend
+	Events   events.Events
+	Limiter  *rate.Limiter
+	Cache    *httputil.ResponseCache
+	Uploads  *httputil.Uploads
+	Recorder *recording.Recorder
+	lock     sync.RWMutex
+	streams  chan struct{}
 }
 
 // NewContextRouter will instantiate a ContextRouter object.
@@ -66,6 +164,61 @@ func NewContextRouter(kub backend.Backend, cfg Config) (*ContextRouter, error) {
 		Backend: kub,
 		Events:  events.New(),
 		Limiter: rate.NewLimiter(PollRate, PollBurst),
+		Cache:   httputil.NewResponseCache(cfg.ListCacheTTL),
+		Uploads: httputil.NewUploads(UploadTTL, cfg.MaxUploadSize),
+	}
+	if cfg.MaxStreams > 0 {
+		cr.streams = make(chan struct{}, cfg.MaxStreams)
+	}
+	if cfg.RecordDir != "" {
+		rec, err := recording.New(cfg.RecordDir)
+		if err != nil {
+			klog.Errorf("error creating recording: %s, not recording requests", err)
+		} else {
+			klog.Infof("recording requests to %s", rec.Dir())
+			cr.Recorder = rec
+		}
 	}
 	return cr, nil
 }
+
+// AcquireStream will reserve a slot for a streaming connection and returns
+// a function that releases the slot again. It will return an error if the
+// maximum number of streaming connections has been reached.
+func (cr *ContextRouter) AcquireStream() (func(), error) {
+	if cr.streams == nil {
+		return func() {}, nil
+	}
+	select {
+	case cr.streams <- struct{}{}:
+		return func() { <-cr.streams }, nil
+	default:
+		return nil, fmt.Errorf("too many streaming connections (max %d)", cap(cr.streams))
+	}
+}
+
+// GetConfig will return a copy of the current configuration. This should be
+// used when reading settings that can be reloaded at runtime.
+func (cr *ContextRouter) GetConfig() Config {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+	return cr.Config
+}
+
+// ReloadDefaults will update the container defaults that can be changed at
+// runtime (resources, pull policy, readiness, image defaults, etc.) with the values of the
+// given configuration.
+func (cr *ContextRouter) ReloadDefaults(cfg Config) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	cr.Config.RequestCPU = cfg.RequestCPU
+	cr.Config.RequestMemory = cfg.RequestMemory
+	cr.Config.RunasUser = cfg.RunasUser
+	cr.Config.FSGroup = cfg.FSGroup
+	cr.Config.PullPolicy = cfg.PullPolicy
+	cr.Config.ServiceAccount = cfg.ServiceAccount
+	cr.Config.ActiveDeadlineSeconds = cfg.ActiveDeadlineSeconds
+	cr.Config.NodeSelector = cfg.NodeSelector
+	cr.Config.Readiness = cfg.Readiness
+	cr.Config.ImageDefaults = cfg.ImageDefaults
+}
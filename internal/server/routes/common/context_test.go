@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestAcquireStream(t *testing.T) {
+	tests := []struct {
+		max int
+		ok  int
+	}{
+		{max: 0, ok: 3},
+		{max: 2, ok: 2},
+	}
+	for i, tst := range tests {
+		cr, _ := NewContextRouter(nil, Config{MaxStreams: tst.max})
+		releases := []func(){}
+		for j := 0; j < 3; j++ {
+			if release, err := cr.AcquireStream(); err == nil {
+				releases = append(releases, release)
+			}
+		}
+		if len(releases) != tst.ok {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.ok, len(releases))
+		}
+		releases[0]()
+		if _, err := cr.AcquireStream(); err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+	}
+}
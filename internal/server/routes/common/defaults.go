@@ -0,0 +1,103 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+// ImageDefaults contains the defaults for containers of which the image
+// matches the Image pattern (e.g. elasticsearch:*).
+type ImageDefaults struct {
+	// Image is the glob pattern that is matched against the image
+	Image string `mapstructure:"image"`
+	// RequestCPU contains the default k8s cpu request
+	RequestCPU string `mapstructure:"request-cpu"`
+	// RequestMemory contains the default k8s memory request
+	RequestMemory string `mapstructure:"request-memory"`
+	// PullPolicy contains the default pull policy
+	PullPolicy string `mapstructure:"pull-policy"`
+	// RunasUser contains the default UID to run the container as
+	RunasUser string `mapstructure:"runas-user"`
+	// FSGroup contains the default GID that owns the volumes of the pod
+	FSGroup string `mapstructure:"fs-group"`
+	// Env contains additional environment variables (key=value)
+	Env []string `mapstructure:"env"`
+}
+
+// ValidateImageDefaults will validate the given image defaults.
+func ValidateImageDefaults(defs []ImageDefaults) error {
+	for _, def := range defs {
+		if def.Image == "" {
+			return fmt.Errorf("missing image pattern in image defaults")
+		}
+		if _, err := path.Match(def.Image, ""); err != nil {
+			return fmt.Errorf("invalid image pattern %s: %w", def.Image, err)
+		}
+		if def.PullPolicy != "" {
+			if err := types.ValidatePullPolicy(def.PullPolicy); err != nil {
+				return fmt.Errorf("invalid image defaults for %s: %w", def.Image, err)
+			}
+		}
+		for _, env := range def.Env {
+			if k, _, ok := strings.Cut(env, "="); !ok || k == "" {
+				return fmt.Errorf("invalid environment variable %s in image defaults for %s, expected key=value", env, def.Image)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyImageDefaults will add the labels of the image defaults that match
+// the given image to the given labels, unless these labels are already
+// present. It returns the environment variables of the matching defaults.
+// If multiple entries match, the first entry takes precedence.
+func ApplyImageDefaults(cfg Config, img string, labels map[string]string) []string {
+	env := []string{}
+	seen := map[string]bool{}
+	for _, def := range cfg.ImageDefaults {
+		if !image.Match(def.Image, img) {
+			continue
+		}
+		for label, val := range map[string]string{
+			types.LabelRequestCPU:    def.RequestCPU,
+			types.LabelRequestMemory: def.RequestMemory,
+			types.LabelPullPolicy:    def.PullPolicy,
+			types.LabelRunasUser:     def.RunasUser,
+			types.LabelFSGroup:       def.FSGroup,
+		} {
+			if _, ok := labels[label]; !ok && val != "" {
+				labels[label] = val
+			}
+		}
+		for _, e := range def.Env {
+			k, _, _ := strings.Cut(e, "=")
+			if !seen[k] {
+				seen[k] = true
+				env = append(env, e)
+			}
+		}
+	}
+	return env
+}
+
+// MergeEnv will add the given environment variables (key=value) to env,
+// unless env already contains a variable with the same key.
+func MergeEnv(env []string, add []string) []string {
+	keys := map[string]bool{}
+	for _, e := range env {
+		k, _, _ := strings.Cut(e, "=")
+		keys[k] = true
+	}
+	for _, e := range add {
+		k, _, _ := strings.Cut(e, "=")
+		if !keys[k] {
+			keys[k] = true
+			env = append(env, e)
+		}
+	}
+	return env
+}
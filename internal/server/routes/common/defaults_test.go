@@ -0,0 +1,88 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestValidateImageDefaults(t *testing.T) {
+	tests := []struct {
+		in  []ImageDefaults
+		err bool
+	}{
+		{in: []ImageDefaults{}, err: false},
+		{in: []ImageDefaults{{Image: "elasticsearch:*", RequestMemory: "2Gi", Env: []string{"ES_JAVA_OPTS=-Xmx1g"}}}, err: false},
+		{in: []ImageDefaults{{RequestMemory: "2Gi"}}, err: true},
+		{in: []ImageDefaults{{Image: "["}}, err: true},
+		{in: []ImageDefaults{{Image: "postgres:*", PullPolicy: "sometimes"}}, err: true},
+		{in: []ImageDefaults{{Image: "postgres:*", Env: []string{"PGDATA"}}}, err: true},
+	}
+	for i, tst := range tests {
+		if err := ValidateImageDefaults(tst.in); (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+	}
+}
+
+func TestApplyImageDefaults(t *testing.T) {
+	cfg := Config{ImageDefaults: []ImageDefaults{
+		{Image: "elasticsearch:*", RequestMemory: "2Gi", Env: []string{"ES_JAVA_OPTS=-Xmx1g"}},
+		{Image: "postgres:*", FSGroup: "999"},
+		{Image: "*", PullPolicy: "always", RequestMemory: "256Mi", Env: []string{"ES_JAVA_OPTS=-Xmx256m", "TZ=UTC"}},
+	}}
+	tests := []struct {
+		image  string
+		labels map[string]string
+		outlab map[string]string
+		outenv []string
+	}{
+		{
+			image:  "elasticsearch:8.11.1",
+			labels: map[string]string{},
+			outlab: map[string]string{types.LabelRequestMemory: "2Gi", types.LabelPullPolicy: "always"},
+			outenv: []string{"ES_JAVA_OPTS=-Xmx1g", "TZ=UTC"},
+		},
+		{
+			image:  "elasticsearch:8.11.1",
+			labels: map[string]string{types.LabelRequestMemory: "4Gi"},
+			outlab: map[string]string{types.LabelRequestMemory: "4Gi", types.LabelPullPolicy: "always"},
+			outenv: []string{"ES_JAVA_OPTS=-Xmx1g", "TZ=UTC"},
+		},
+		{
+			image:  "docker.io/library/postgres:16",
+			labels: map[string]string{},
+			outlab: map[string]string{types.LabelFSGroup: "999", types.LabelRequestMemory: "256Mi", types.LabelPullPolicy: "always"},
+			outenv: []string{"ES_JAVA_OPTS=-Xmx256m", "TZ=UTC"},
+		},
+		{
+			image:  "quay.io/sclorg/postgresql-15-c9s",
+			labels: map[string]string{},
+			outlab: map[string]string{},
+			outenv: []string{},
+		},
+		{
+			image:  "postgres",
+			labels: map[string]string{},
+			outlab: map[string]string{types.LabelRequestMemory: "256Mi", types.LabelPullPolicy: "always"},
+			outenv: []string{"ES_JAVA_OPTS=-Xmx256m", "TZ=UTC"},
+		},
+	}
+	for i, tst := range tests {
+		env := ApplyImageDefaults(cfg, tst.image, tst.labels)
+		if !reflect.DeepEqual(tst.labels, tst.outlab) {
+			t.Errorf("failed test %d - expected labels %v, but got %v", i, tst.outlab, tst.labels)
+		}
+		if !reflect.DeepEqual(env, tst.outenv) {
+			t.Errorf("failed test %d - expected env %v, but got %v", i, tst.outenv, env)
+		}
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	res := MergeEnv([]string{"A=1", "B=2"}, []string{"B=3", "C=4", "C=5"})
+	if exp := []string{"A=1", "B=2", "C=4"}; !reflect.DeepEqual(res, exp) {
+		t.Errorf("failed test - expected %v, but got %v", exp, res)
+	}
+}
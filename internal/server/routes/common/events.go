@@ -0,0 +1,100 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/filter"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+)
+
+// PublishContainerEvent will publish an event for given action on given
+// container, including the name, image and labels of the container as
+// attributes.
+func PublishContainerEvent(cr *ContextRouter, tainr *types.Container, action string) {
+	cr.Events.PublishWithAttributes(tainr.ID, events.Container, action, getContainerEventAttributes(tainr, action))
+}
+
+// getContainerEventAttributes will return the attributes of given container
+// that are added to its events. Similar to docker, the labels are added as
+// is, and die events include the exit code of the container.
+func getContainerEventAttributes(tainr *types.Container, action string) map[string]string {
+	attrs := tainr.EventAttributes()
+	if action == events.Die {
+		attrs["exitCode"] = strconv.Itoa(tainr.ExitCode())
+	}
+	return attrs
+}
+
+// PublishExecEvent will publish an exec event for given action on given
+// container. Similar to docker, the create and start actions include the
+// command that is executed, and the die action includes its exit code.
+func PublishExecEvent(cr *ContextRouter, tainr *types.Container, exec *types.Exec, action string) {
+	attrs := getContainerEventAttributes(tainr, action)
+	attrs["execID"] = exec.ID
+	if action == events.ExecDie {
+		attrs["exitCode"] = strconv.Itoa(exec.ExitCode)
+	} else {
+		action = action + ": " + strings.Join(exec.Cmd, " ")
+	}
+	cr.Events.PublishWithAttributes(tainr.ID, events.Container, action, attrs)
+}
+
+// PublishHealthStatusEvent will publish a health_status event for given
+// container if its health changed compared to the given previous health.
+// As the health of a container follows its running state, this allows
+// clients to wait for a healthy container using the events stream.
+func PublishHealthStatusEvent(cr *ContextRouter, tainr *types.Container, prev string) {
+	if health := tainr.StatusString(); health != prev {
+		PublishContainerEvent(cr, tainr, events.HealthStatus+": "+health)
+	}
+}
+
+// StreamEvents will stream all events that match the filters of the request
+// as json, using the given format function to convert the events, until the
+// request is cancelled.
+func StreamEvents(cr *ContextRouter, c *gin.Context, format func(events.Message) gin.H) {
+	release, err := cr.AcquireStream()
+	if err != nil {
+		httputil.Error(c, http.StatusTooManyRequests, err)
+		return
+	}
+	defer release()
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	filtr, err := filter.New(c.Query("filters"))
+	if err != nil {
+		klog.V(5).Infof("unsupported filter: %s", err)
+	}
+
+	enc := json.NewEncoder(w)
+	el, id := cr.Events.Subscribe()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			cr.Events.Unsubscribe(id)
+			return
+		case msg, ok := <-el:
+			if !ok {
+				klog.Warningf("events subscriber %s disconnected, not keeping up with events", id)
+				return
+			}
+			if filtr.Match(&msg) {
+				klog.V(5).Infof("sending message to %s", id)
+				enc.Encode(format(msg))
+				w.Flush()
+			}
+		}
+	}
+}
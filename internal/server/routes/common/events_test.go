@@ -0,0 +1,103 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestGetContainerEventAttributes(t *testing.T) {
+	tests := []struct {
+		tainr  *types.Container
+		action string
+		out    map[string]string
+	}{
+		{
+			tainr:  &types.Container{Name: "/tainr", Image: "alpine"},
+			action: events.Start,
+			out:    map[string]string{"name": "tainr", "image": "alpine"},
+		},
+		{
+			tainr:  &types.Container{Name: "tainr", Image: "alpine", Labels: map[string]string{"org.testcontainers": "true", "name": "label"}},
+			action: events.Create,
+			out:    map[string]string{"name": "tainr", "image": "alpine", "org.testcontainers": "true"},
+		},
+		{
+			tainr:  &types.Container{Name: "tainr", Image: "alpine", Killed: true},
+			action: events.Die,
+			out:    map[string]string{"name": "tainr", "image": "alpine", "exitCode": "137"},
+		},
+		{
+			tainr:  &types.Container{Name: "tainr", Image: "alpine"},
+			action: events.Die,
+			out:    map[string]string{"name": "tainr", "image": "alpine", "exitCode": "0"},
+		},
+	}
+	for i, tst := range tests {
+		res := getContainerEventAttributes(tst.tainr, tst.action)
+		if !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestPublishExecEvent(t *testing.T) {
+	tainr := &types.Container{ID: "tainr", Name: "tainr", Image: "alpine"}
+	exec := &types.Exec{ID: "exec", Cmd: []string{"sh", "-c", "exit 3"}, ExitCode: 3}
+	tests := []struct {
+		action string
+		out    string
+		code   string
+	}{
+		{action: events.ExecCreate, out: "exec_create: sh -c exit 3"},
+		{action: events.ExecStart, out: "exec_start: sh -c exit 3"},
+		{action: events.ExecDie, out: "exec_die", code: "3"},
+	}
+	cr := &ContextRouter{Events: events.New()}
+	el, id := cr.Events.Subscribe()
+	defer cr.Events.Unsubscribe(id)
+	for i, tst := range tests {
+		PublishExecEvent(cr, tainr, exec, tst.action)
+		msg := <-el
+		if msg.ID != tainr.ID || msg.Action != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, msg.Action)
+		}
+		if msg.Attributes["execID"] != exec.ID {
+			t.Errorf("failed test %d - expected %s, but got %s", i, exec.ID, msg.Attributes["execID"])
+		}
+		if msg.Attributes["exitCode"] != tst.code {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.code, msg.Attributes["exitCode"])
+		}
+	}
+}
+
+func TestPublishHealthStatusEvent(t *testing.T) {
+	tests := []struct {
+		running bool
+		prev    string
+		out     string
+	}{
+		{running: true, prev: "unhealthy", out: "health_status: healthy"},
+		{running: false, prev: "healthy", out: "health_status: unhealthy"},
+		{running: true, prev: "healthy", out: ""},
+		{running: false, prev: "unhealthy", out: ""},
+	}
+	cr := &ContextRouter{Events: events.New()}
+	el, id := cr.Events.Subscribe()
+	defer cr.Events.Unsubscribe(id)
+	for i, tst := range tests {
+		tainr := &types.Container{ID: "tainr", Running: tst.running}
+		PublishHealthStatusEvent(cr, tainr, tst.prev)
+		res := ""
+		select {
+		case msg := <-el:
+			res = msg.Action
+		default:
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,8 +10,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"k8s.io/klog"
 
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/events"
 	"github.com/joyrex2001/kubedock/internal/model/types"
 	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/util/detach"
 )
 
 // ContainerExec - create an exec instance.
@@ -34,13 +38,23 @@ func ContainerExec(cr *ContextRouter, c *gin.Context) {
 		in.Stdout = true
 	}
 
+	if _, err := detach.ParseKeys(in.DetachKeys); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
 	id := c.Param("id")
-	_, err := cr.DB.GetContainer(id)
+	tainr, err := cr.DB.GetContainer(id)
 	if err != nil {
 		httputil.Error(c, http.StatusNotFound, err)
 		return
 	}
 
+	if err := cr.Backend.CheckFeature(backend.FeatureExec); err != nil {
+		httputil.Error(c, http.StatusNotImplemented, err)
+		return
+	}
+
 	exec := &types.Exec{
 		ContainerID: id,
 		Cmd:         in.Cmd,
@@ -48,12 +62,15 @@ func ContainerExec(cr *ContextRouter, c *gin.Context) {
 		Stderr:      in.Stderr,
 		Stdout:      in.Stdout,
 		Stdin:       in.Stdin,
+		DetachKeys:  in.DetachKeys,
 	}
 	if err := cr.DB.SaveExec(exec); err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	PublishExecEvent(cr, tainr, exec, events.ExecCreate)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"Id": exec.ID,
 	})
@@ -111,17 +128,7 @@ func ExecStart(cr *ContextRouter, c *gin.Context) {
 	}
 
 	if req.Detach {
-		go func() {
-			code, err := cr.Backend.ExecContainer(tainr, exec, nil, io.Discard)
-			if err != nil {
-				klog.Errorf("error during exec: %s", err)
-				return
-			}
-			exec.ExitCode = code
-			if err := cr.DB.SaveExec(exec); err != nil {
-				klog.Errorf("error during exec: %s", err)
-			}
-		}()
+		go runExec(context.Background(), cr, tainr, exec, nil, io.Discard)
 		c.JSON(http.StatusOK, gin.H{})
 		return
 	}
@@ -136,31 +143,70 @@ func ExecStart(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 	defer httputil.CloseStreams(in, out)
-	httputil.UpgradeConnection(r, out)
+	httputil.UpgradeConnection(r, out, exec.TTY)
+
+	var stdin io.Reader = in
+	var detached <-chan struct{}
+	if exec.TTY && exec.Stdin {
+		keys, _ := detach.ParseKeys(exec.DetachKeys)
+		dr := detach.NewReader(in, keys)
+		stdin, detached = dr, dr.Detached()
+	}
+
+	// a detached exec keeps running after the request is finished
+	ctx := r.Context()
+	if detached != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runExec(ctx, cr, tainr, exec, stdin, out)
+	}()
 
-	code, err := cr.Backend.ExecContainer(tainr, exec, in, out)
+	select {
+	case <-done:
+	case <-detached:
+		klog.V(3).Infof("detached from exec %s", exec.ID)
+	}
+}
+
+// runExec will execute given exec instance in given container, and stores
+// the exit code in the exec instance. The start and die events are published
+// for the exec, where the die event is published even if the exec failed.
+// The exec is terminated when the given context is done.
+func runExec(ctx context.Context, cr *ContextRouter, tainr *types.Container, exec *types.Exec, stdin io.Reader, out io.Writer) {
+	PublishExecEvent(cr, tainr, exec, events.ExecStart)
+	code, err := cr.Backend.ExecContainer(ctx, tainr, exec, stdin, out)
 	if err != nil {
 		klog.Errorf("error during exec: %s", err)
-		return
+		code = 126
 	}
 	exec.ExitCode = code
 	if err := cr.DB.SaveExec(exec); err != nil {
-		httputil.Error(c, http.StatusInternalServerError, err)
-		return
+		klog.Errorf("error during exec: %s", err)
 	}
+	PublishExecEvent(cr, tainr, exec, events.ExecDie)
 }
 
-// ExecResize - start an exec instance.
+// ExecResize - resize the tty of an exec instance.
 // https://docs.docker.com/engine/api/v1.41/#operation/ExecResize
 // https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/exec/operation/ExecResizeLibpod
 // POST "/exec/:id/resize"
 // POST "/libpod/exec/:id/resize"
 func ExecResize(cr *ContextRouter, c *gin.Context) {
 	id := c.Param("id")
-	_, err := cr.DB.GetExec(id)
+	exec, err := cr.DB.GetExec(id)
 	if err != nil {
 		httputil.Error(c, http.StatusNotFound, err)
 		return
 	}
+	width, height, err := parseTerminalSize(c)
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	exec.TerminalSizes.Resize(width, height)
 	c.JSON(http.StatusOK, gin.H{})
 }
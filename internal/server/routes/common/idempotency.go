@@ -0,0 +1,105 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/model"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// IdempotencyKeyHeader is the header clients can use to make create requests
+// safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyKeyError is the error returned when an idempotency key is
+// reused for a different request.
+type IdempotencyKeyError struct {
+	Key string
+}
+
+// Error will return the error message.
+func (e *IdempotencyKeyError) Error() string {
+	return fmt.Sprintf("idempotency key %s is already used for a different request", e.Key)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *IdempotencyKeyError) HTTPStatus() int {
+	return http.StatusUnprocessableEntity
+}
+
+// GetCreateDigest will return a digest of the body of the given create
+// request, which is used to recognize retried requests. The body is restored
+// so it can be decoded afterwards.
+func GetCreateDigest(c *gin.Context) (string, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// FindCreatedContainer will return the container that was created earlier by
+// a request that is retried, or nil if the request is not a retry. If an
+// idempotency key is given, the container created with that key is returned,
+// or an IdempotencyKeyError if the key was used for a different request.
+// Without a key, a container with the given name is returned if it was
+// created with the same request and was not started yet.
+func FindCreatedContainer(cr *ContextRouter, key, name, digest string) (*types.Container, error) {
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimPrefix(name, "/")
+	for _, tainr := range tainrs {
+		if key != "" {
+			if tainr.IdempotencyKey != key {
+				continue
+			}
+			if tainr.CreateDigest != digest {
+				return nil, &IdempotencyKeyError{Key: key}
+			}
+			return tainr, nil
+		}
+		if name != "" && strings.EqualFold(tainr.Name, name) && tainr.CreateDigest == digest && !hasStarted(tainr) {
+			return tainr, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveCreatedContainer will save the given newly created container. If a
+// concurrent request with the same idempotency key saved its container
+// first, that container is returned instead, or an IdempotencyKeyError if
+// that request was different. Otherwise the given container is returned.
+func SaveCreatedContainer(cr *ContextRouter, tainr *types.Container) (*types.Container, error) {
+	err := cr.DB.SaveContainer(tainr)
+	var kerr *model.IdempotencyKeyInUseError
+	if !errors.As(err, &kerr) {
+		return tainr, err
+	}
+	cur, err := cr.DB.GetContainer(kerr.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cur.CreateDigest != tainr.CreateDigest {
+		return nil, &IdempotencyKeyError{Key: kerr.Key}
+	}
+	return cur, nil
+}
+
+// hasStarted will return true if given container has been started.
+func hasStarted(tainr *types.Container) bool {
+	return tainr.Running || tainr.Completed || tainr.Failed || tainr.Stopped || !tainr.Finished.IsZero()
+}
@@ -1,14 +1,17 @@
 package common
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/events"
 	"github.com/joyrex2001/kubedock/internal/model/types"
 	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/util/image"
 )
 
 // ImageList - list Images. Stubbed, not relevant on k8s.
@@ -28,7 +31,15 @@ func ImageList(cr *ContextRouter, c *gin.Context) {
 		if !strings.Contains(name, ":") {
 			name = name + ":latest"
 		}
-		res = append(res, gin.H{"ID": img.ID, "Size": 0, "Created": img.Created.Unix(), "RepoTags": []string{name}})
+		res = append(res, gin.H{
+			"ID":          img.ID,
+			"Size":        img.Size,
+			"VirtualSize": img.Size,
+			"Created":     img.Created.Unix(),
+			"RepoTags":    []string{name},
+			"RepoDigests": getRepoDigests(img),
+			"Digest":      img.Digest,
+		})
 	}
 	c.JSON(http.StatusOK, res)
 }
@@ -41,13 +52,9 @@ func ImageJSON(cr *ContextRouter, c *gin.Context) {
 	img, err := cr.DB.GetImageByNameOrID(id)
 	if err != nil {
 		img = &types.Image{Name: id}
-		if cr.Config.Inspector {
-			pts, err := cr.Backend.GetImageExposedPorts(id)
-			if err != nil {
-				httputil.Error(c, http.StatusInternalServerError, err)
-				return
-			}
-			img.ExposedPorts = pts
+		if err := InspectImage(cr, img); err != nil {
+			httputil.Error(c, http.StatusInternalServerError, err)
+			return
 		}
 		if err := cr.DB.SaveImage(img); err != nil {
 			httputil.Error(c, http.StatusNotFound, err)
@@ -58,12 +65,118 @@ func ImageJSON(cr *ContextRouter, c *gin.Context) {
 		"Id":           img.Name,
 		"Architecture": config.GOARCH,
 		"Created":      img.Created.Format("2006-01-02T15:04:05Z"),
-		"Size":         0,
+		"Size":         img.Size,
+		"RepoDigests":  getRepoDigests(img),
+		"Digest":       img.Digest,
 		"ContainerConfig": gin.H{
 			"Image": img.Name,
 		},
 		"Config": gin.H{
-			"Env": []string{},
+			"Env": getImageEnv(img),
 		},
 	})
 }
+
+// InspectImage will update given image with the exposed ports, creation
+// time, digest and size of the image in the registry, if the image inspector
+// is enabled. If only digest pinning is enabled, only the (pinned) digest
+// is updated.
+func InspectImage(cr *ContextRouter, img *types.Image) error {
+	if !cr.Config.Inspector && !cr.Config.PinDigests {
+		return nil
+	}
+	dtl, err := cr.Backend.InspectImage(img.Name)
+	if err != nil {
+		return err
+	}
+	if !cr.Config.Inspector {
+		img.Digest = dtl.Digest
+		return nil
+	}
+	img.ExposedPorts = dtl.Config.Config.ExposedPorts
+	img.Env = dtl.Config.Config.Env
+	img.Digest = dtl.Digest
+	img.Size = dtl.Size
+	if dtl.Config.Created != nil {
+		img.Created = *dtl.Config.Created
+	}
+	return nil
+}
+
+// getImageEnv will return the environment variables that are configured
+// in the given image.
+func getImageEnv(img *types.Image) []string {
+	if img.Env == nil {
+		return []string{}
+	}
+	return img.Env
+}
+
+// getRepoDigests will return the repository digests of given image, which
+// is the repository name combined with the digest of the manifest.
+func getRepoDigests(img *types.Image) []string {
+	if img.Digest == "" {
+		return []string{}
+	}
+	return []string{image.Pin(img.Name, img.Digest)}
+}
+
+// GetImage will return the tracked image with given name or id. Names
+// without a tag will match the :latest tag as well, and vice versa.
+func GetImage(cr *ContextRouter, name string) (*types.Image, error) {
+	img, err := cr.DB.GetImageByNameOrID(name)
+	if err == nil {
+		return img, nil
+	}
+	if strings.HasSuffix(name, ":latest") {
+		if img, err := cr.DB.GetImageByName(strings.TrimSuffix(name, ":latest")); err == nil {
+			return img, nil
+		}
+	} else if img, err := cr.DB.GetImageByName(name + ":latest"); err == nil {
+		return img, nil
+	}
+	return nil, err
+}
+
+// RemoveImage will remove the tracked image with given name or id. It will
+// return the removed image and a http status code in case of errors. Images
+// that are referenced by containers are only removed if force is set.
+func RemoveImage(cr *ContextRouter, name string, force bool) (*types.Image, int, error) {
+	img, err := GetImage(cr, name)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+
+	if !force {
+		tainrs, err := cr.DB.GetContainers()
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		for _, tainr := range tainrs {
+			if isSameImage(tainr.Image, img.Name) {
+				return nil, http.StatusConflict, fmt.Errorf("unable to remove image %s: image is in use by container %s", name, tainr.ShortID)
+			}
+		}
+	}
+
+	if err := cr.DB.DeleteImage(img); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	cr.Events.PublishWithAttributes(img.Name, events.Image, events.Untag, map[string]string{"name": img.Name})
+	cr.Events.PublishWithAttributes(img.Name, events.Image, events.Delete, map[string]string{"name": img.Name})
+
+	return img, http.StatusOK, nil
+}
+
+// isSameImage will return true if both image names refer to the same image,
+// taking an implicit :latest tag into account.
+func isSameImage(a, b string) bool {
+	normalize := func(name string) string {
+		if i := strings.LastIndex(name, "/"); !strings.Contains(name[i+1:], ":") && !strings.Contains(name, "@") {
+			return name + ":latest"
+		}
+		return name
+	}
+	return normalize(a) == normalize(b)
+}
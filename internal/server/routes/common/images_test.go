@@ -0,0 +1,82 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/pkg/backend"
+	"github.com/joyrex2001/kubedock/pkg/backend/fake"
+)
+
+func TestIsSameImage(t *testing.T) {
+	tests := []struct {
+		a   string
+		b   string
+		out bool
+	}{
+		{a: "nginx", b: "nginx", out: true},
+		{a: "nginx", b: "nginx:latest", out: true},
+		{a: "nginx:1.25", b: "nginx", out: false},
+		{a: "localhost:5000/nginx", b: "localhost:5000/nginx:latest", out: true},
+		{a: "localhost:5000/nginx", b: "localhost:5000", out: false},
+		{a: "nginx@sha256:abc", b: "nginx:latest", out: false},
+	}
+	for i, tst := range tests {
+		if res := isSameImage(tst.a, tst.b); res != tst.out {
+			t.Errorf("failed test %d - expected %t, but got %t", i, tst.out, res)
+		}
+	}
+}
+
+func TestGetRepoDigests(t *testing.T) {
+	tests := []struct {
+		img *types.Image
+		out []string
+	}{
+		{img: &types.Image{Name: "nginx"}, out: []string{}},
+		{img: &types.Image{Name: "nginx", Digest: "sha256:abc"}, out: []string{"nginx@sha256:abc"}},
+		{img: &types.Image{Name: "nginx:1.25", Digest: "sha256:abc"}, out: []string{"nginx@sha256:abc"}},
+		{img: &types.Image{Name: "localhost:5000/nginx", Digest: "sha256:abc"}, out: []string{"localhost:5000/nginx@sha256:abc"}},
+		{img: &types.Image{Name: "nginx@sha256:def", Digest: "sha256:abc"}, out: []string{"nginx@sha256:abc"}},
+	}
+	for i, tst := range tests {
+		if res := getRepoDigests(tst.img); !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestInspectImage(t *testing.T) {
+	tests := []struct {
+		cfg    Config
+		digest string
+		ports  bool
+	}{
+		{cfg: Config{}, digest: ""},
+		{cfg: Config{PinDigests: true}, digest: "sha256:abc"},
+		{cfg: Config{Inspector: true}, digest: "sha256:abc", ports: true},
+	}
+	for i, tst := range tests {
+		kub := fake.New()
+		kub.Images["nginx:1.25"] = &backend.ImageDetails{
+			Config: &v1.Image{Config: v1.ImageConfig{ExposedPorts: map[string]struct{}{"80/tcp": {}}}},
+			Digest: "sha256:abc",
+		}
+		cr := &ContextRouter{Config: tst.cfg, Backend: adapter.Internal(kub)}
+		img := &types.Image{Name: "nginx:1.25"}
+		if err := InspectImage(cr, img); err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			continue
+		}
+		if img.Digest != tst.digest {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.digest, img.Digest)
+		}
+		if (len(img.ExposedPorts) > 0) != tst.ports {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.ports, img.ExposedPorts)
+		}
+	}
+}
@@ -31,11 +31,21 @@ func ContainerLogs(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 
+	follow, _ := strconv.ParseBool(c.Query("follow"))
+	if follow {
+		release, err := cr.AcquireStream()
+		if err != nil {
+			httputil.Error(c, http.StatusTooManyRequests, err)
+			return
+		}
+		defer release()
+	}
+
 	r := c.Request
 	w := c.Writer
+	w.Header().Set("Content-Type", httputil.StreamContentType(tainr.Tty))
 	w.WriteHeader(http.StatusOK)
 
-	follow, _ := strconv.ParseBool(c.Query("follow"))
 	tailLines, _ := parseUint64(c.Query("tail"))
 	sinceTime, _ := parseUnix(c.Query("since"))
 	timestamps, _ := strconv.ParseBool(c.Query("timestamps"))
@@ -47,9 +57,15 @@ func ContainerLogs(cr *ContextRouter, c *gin.Context) {
 		TailLines:  tailLines,
 	}
 
+	// tty containers have a raw stream, others are stdcopy multiplexed
+	getLogs := cr.Backend.GetLogs
+	if tainr.Tty {
+		getLogs = cr.Backend.GetLogsRaw
+	}
+
 	if !follow {
 		stop := make(chan struct{}, 1)
-		if err := cr.Backend.GetLogs(tainr, &logOpts, stop, w); err != nil {
+		if err := getLogs(tainr, &logOpts, stop, w); err != nil {
 			httputil.Error(c, http.StatusInternalServerError, err)
 			return
 		}
@@ -63,12 +79,12 @@ func ContainerLogs(cr *ContextRouter, c *gin.Context) {
 		return
 	}
 	defer httputil.CloseStreams(in, out)
-	httputil.UpgradeConnection(r, out)
+	httputil.UpgradeConnection(r, out, tainr.Tty)
 
 	stop := make(chan struct{}, 1)
 	tainr.AddStopChannel(stop)
 
-	if err := cr.Backend.GetLogs(tainr, &logOpts, stop, out); err != nil {
+	if err := getLogs(tainr, &logOpts, stop, out); err != nil {
 		klog.V(3).Infof("error retrieving logs: %s", err)
 		return
 	}
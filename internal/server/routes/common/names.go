@@ -0,0 +1,70 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/pkg/namesgenerator"
+
+	"github.com/joyrex2001/kubedock/internal/model"
+)
+
+// validContainerName is the pattern container names should match, which is
+// the same as docker uses.
+var validContainerName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// ContainerName will return the name to be used for the container with
+// given id. If no name is given, a docker-style name (adjective_surname) is
+// generated. An error is returned if the name is invalid, or if the name is
+// already in use by another container, in which case the error is a
+// model.NameInUseError. As the name is only reserved when the container is
+// saved, saving the container can fail with a NameInUseError as well.
+func ContainerName(cr *ContextRouter, id, name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return generateContainerName(cr)
+	}
+	if !validContainerName.MatchString(name) {
+		return "", fmt.Errorf("invalid container name (%s), only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	if err := checkNameAvailable(cr, id, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// generateContainerName will return a random docker-style name that is not
+// in use yet. Similar to docker, a number is appended to the name if the
+// generated name is already taken.
+func generateContainerName(cr *ContextRouter) (string, error) {
+	for i := 0; i < 10; i++ {
+		name := namesgenerator.GetRandomName(i)
+		err := checkNameAvailable(cr, "", name)
+		if err == nil {
+			return name, nil
+		}
+		var nerr *model.NameInUseError
+		if !errors.As(err, &nerr) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("could not generate an unused container name")
+}
+
+// checkNameAvailable will return an error if given name is already used by
+// another container than the container with given id. Names are compared
+// case insensitive, as they are used as (case insensitive) dns names as well.
+func checkNameAvailable(cr *ContextRouter, id, name string) error {
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		return err
+	}
+	for _, other := range tainrs {
+		if other.ID != id && strings.EqualFold(other.Name, name) {
+			return &model.NameInUseError{Name: name, ID: other.ID}
+		}
+	}
+	return nil
+}
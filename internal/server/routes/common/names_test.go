@@ -0,0 +1,55 @@
+package common
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/model"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestContainerName(t *testing.T) {
+	cr, _ := NewContextRouter(nil, Config{})
+	tainr := &types.Container{Name: "names-taken"}
+	if err := cr.DB.SaveContainer(tainr); err != nil {
+		t.Fatalf("unexpected error saving container: %s", err)
+	}
+	tests := []struct {
+		id       string
+		name     string
+		out      string
+		err      bool
+		conflict bool
+	}{
+		{name: "names-free", out: "names-free"},
+		{name: "/names.free_1", out: "names.free_1"},
+		{name: "names-taken", err: true, conflict: true},
+		{name: "Names-Taken", err: true, conflict: true},
+		{id: tainr.ID, name: "names-taken", out: "names-taken"},
+		{name: "-names", err: true},
+		{name: "names/free", err: true},
+		{name: "n", err: true},
+	}
+	for i, tst := range tests {
+		res, err := ContainerName(cr, tst.id, tst.name)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error %v", i, err)
+		}
+		var nerr *model.NameInUseError
+		if errors.As(err, &nerr) != tst.conflict {
+			t.Errorf("failed test %d - expected conflict %v, but got %v", i, tst.conflict, err)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+
+	res, err := ContainerName(cr, "", "")
+	if err != nil {
+		t.Errorf("unexpected error generating name: %s", err)
+	}
+	if !regexp.MustCompile(`^[a-z]+_[a-z]+$`).MatchString(res) {
+		t.Errorf("expected a generated adjective_surname name, but got %s", res)
+	}
+}
@@ -0,0 +1,191 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+// NetworkExistsError is the error returned when a network is created with a
+// name that is already in use.
+type NetworkExistsError struct {
+	Network *types.Network
+}
+
+// Error will return the error message, which is the same as docker uses.
+func (e *NetworkExistsError) Error() string {
+	return fmt.Sprintf("network with name %s already exists", e.Network.Name)
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *NetworkExistsError) HTTPStatus() int {
+	return http.StatusConflict
+}
+
+// InvalidSubnetError is the error returned when a network is created with
+// an invalid subnet or gateway.
+type InvalidSubnetError struct {
+	Message string
+}
+
+// Error will return the error message.
+func (e *InvalidSubnetError) Error() string {
+	return e.Message
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *InvalidSubnetError) HTTPStatus() int {
+	return http.StatusBadRequest
+}
+
+// CreateNetwork will create a network with given name, labels and
+// (optional) subnet and gateway. If no subnet is given, a free subnet is
+// allocated. If a network with the same name already exists, a
+// NetworkExistsError is returned if check is set and kubedock is not
+// configured to be lenient. Otherwise the existing network is returned, in
+// which case the returned bool is true.
+func CreateNetwork(cr *ContextRouter, name string, labels map[string]string, subnet, gateway string, check bool) (*types.Network, bool, error) {
+	if netw, err := cr.DB.GetNetworkByName(name); err == nil {
+		if check && !cr.Config.LenientNetworkCreate {
+			return nil, true, &NetworkExistsError{Network: netw}
+		}
+		return netw, true, nil
+	}
+	subnet, gateway, err := parseSubnet(subnet, gateway)
+	if err != nil {
+		return nil, false, err
+	}
+	netw := &types.Network{
+		Name:    name,
+		Labels:  labels,
+		Subnet:  subnet,
+		Gateway: gateway,
+	}
+	if err := cr.DB.SaveNetwork(netw); err != nil {
+		return nil, false, err
+	}
+	return netw, false, nil
+}
+
+// parseSubnet will validate the given subnet and gateway, and return the
+// normalized subnet and gateway. A gateway requires a subnet, and should be
+// an address in the subnet; only ipv4 subnets are supported.
+func parseSubnet(subnet, gateway string) (string, string, error) {
+	if subnet == "" {
+		if gateway != "" {
+			return "", "", &InvalidSubnetError{Message: "a gateway requires a subnet"}
+		}
+		return "", "", nil
+	}
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", "", &InvalidSubnetError{Message: fmt.Sprintf("invalid subnet %s", subnet)}
+	}
+	ones, bits := ipnet.Mask.Size()
+	if ipnet.IP.To4() == nil || bits-ones < 2 {
+		return "", "", &InvalidSubnetError{Message: fmt.Sprintf("unsupported subnet %s, only ipv4 subnets of at least 4 addresses are supported", subnet)}
+	}
+	if gateway == "" {
+		return ipnet.String(), "", nil
+	}
+	gw := net.ParseIP(gateway).To4()
+	bcast := net.IP(slices.Clone(ipnet.IP.To4()))
+	for i := range bcast {
+		bcast[i] |= ^ipnet.Mask[i]
+	}
+	if gw == nil || !ipnet.Contains(gw) || gw.Equal(ipnet.IP) || gw.Equal(bcast) {
+		return "", "", &InvalidSubnetError{Message: fmt.Sprintf("invalid gateway %s for subnet %s", gateway, ipnet)}
+	}
+	return ipnet.String(), gw.String(), nil
+}
+
+// GetNetworkEndpoints will return the endpoint settings of all networks the
+// given container is connected to, keyed by network name, as used in the
+// NetworkSettings.Networks of a container inspect.
+func GetNetworkEndpoints(cr *ContextRouter, tainr *types.Container) (gin.H, error) {
+	netws, err := cr.DB.GetNetworksByIDs(tainr.Networks)
+	if err != nil {
+		return gin.H{}, err
+	}
+	res := gin.H{}
+	for _, netw := range netws {
+		ip, gw, mac := "", "", ""
+		prefix := 0
+		if ip = tainr.IPAddresses[netw.ID]; ip != "" {
+			gw, prefix = GetNetworkGateway(netw)
+			mac = getMacAddress(ip)
+		}
+		var ipam gin.H
+		if static := tainr.StaticIPs[netw.ID]; static != "" {
+			ipam = gin.H{"IPv4Address": static}
+		}
+		res[netw.Name] = gin.H{
+			"IPAMConfig":          ipam,
+			"Links":               nil,
+			"Aliases":             tainr.NetworkAliases,
+			"DNSNames":            getDNSNames(tainr),
+			"NetworkID":           netw.ID,
+			"EndpointID":          getEndpointID(tainr, netw),
+			"Gateway":             gw,
+			"IPAddress":           ip,
+			"IPPrefixLen":         prefix,
+			"IPv6Gateway":         "",
+			"GlobalIPv6Address":   "",
+			"GlobalIPv6PrefixLen": 0,
+			"MacAddress":          mac,
+			"DriverOpts":          nil,
+		}
+	}
+	return res, nil
+}
+
+// GetNetworkGateway will return the gateway of the given network, and the
+// prefix length of the subnet.
+func GetNetworkGateway(netw *types.Network) (string, int) {
+	_, ipnet, err := net.ParseCIDR(netw.Subnet)
+	if err != nil || ipnet.IP.To4() == nil {
+		return "", 0
+	}
+	prefix, _ := ipnet.Mask.Size()
+	return netw.GetGateway(), prefix
+}
+
+// getMacAddress will return the mac address for the given ip address, in
+// the same way docker generates mac addresses for its bridge networks.
+func getMacAddress(ip string) string {
+	ip4 := net.ParseIP(ip).To4()
+	if ip4 == nil {
+		return ""
+	}
+	return fmt.Sprintf("02:42:%02x:%02x:%02x:%02x", ip4[0], ip4[1], ip4[2], ip4[3])
+}
+
+// getEndpointID will return a stable endpoint id for the given container in
+// the given network.
+func getEndpointID(tainr *types.Container, netw *types.Network) string {
+	sum := sha256.Sum256([]byte(tainr.ID + "/" + netw.ID))
+	return hex.EncodeToString(sum[:])
+}
+
+// getDNSNames will return the names the given container can be resolved
+// with by other containers.
+func getDNSNames(tainr *types.Container) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range append([]string{tainr.Name, tainr.ShortID}, tainr.NetworkAliases...) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
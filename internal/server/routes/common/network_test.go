@@ -0,0 +1,84 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestGetNetworkGateway(t *testing.T) {
+	tests := []struct {
+		in     *types.Network
+		gw     string
+		prefix int
+	}{
+		{in: &types.Network{Name: "bridge", Subnet: "172.17.0.0/16"}, gw: "172.17.0.1", prefix: 16},
+		{in: &types.Network{Name: "custom", Subnet: "192.168.16.0/20"}, gw: "192.168.16.1", prefix: 20},
+		{in: &types.Network{Name: "custom", Subnet: "10.10.0.0/24", Gateway: "10.10.0.254"}, gw: "10.10.0.254", prefix: 24},
+		{in: &types.Network{Name: "host"}, gw: "", prefix: 0},
+	}
+	for i, tst := range tests {
+		gw, prefix := GetNetworkGateway(tst.in)
+		if gw != tst.gw || prefix != tst.prefix {
+			t.Errorf("failed test %d - expected %s/%d, but got %s/%d", i, tst.gw, tst.prefix, gw, prefix)
+		}
+	}
+}
+
+func TestParseSubnet(t *testing.T) {
+	tests := []struct {
+		subnet  string
+		gateway string
+		outsub  string
+		outgw   string
+		err     bool
+	}{
+		{},
+		{subnet: "10.10.0.0/24", outsub: "10.10.0.0/24"},
+		{subnet: "10.10.0.7/24", gateway: "10.10.0.254", outsub: "10.10.0.0/24", outgw: "10.10.0.254"},
+		{subnet: "10.10.0.0/24", gateway: "10.10.1.1", err: true},
+		{subnet: "10.10.0.0/24", gateway: "10.10.0.0", err: true},
+		{subnet: "10.10.0.0/24", gateway: "10.10.0.255", err: true},
+		{gateway: "10.10.0.1", err: true},
+		{subnet: "fd00::/64", err: true},
+		{subnet: "10.10.0.0/31", err: true},
+		{subnet: "invalid", err: true},
+	}
+	for i, tst := range tests {
+		sub, gw, err := parseSubnet(tst.subnet, tst.gateway)
+		if (err != nil) != tst.err || sub != tst.outsub || gw != tst.outgw {
+			t.Errorf("failed test %d - expected %s %s (error %t), but got %s %s (%v)", i, tst.outsub, tst.outgw, tst.err, sub, gw, err)
+		}
+	}
+}
+
+func TestGetMacAddress(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "172.17.0.2", out: "02:42:ac:11:00:02"},
+		{in: "", out: ""},
+	}
+	for i, tst := range tests {
+		if res := getMacAddress(tst.in); res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
+
+func TestGetDNSNames(t *testing.T) {
+	tests := []struct {
+		in  *types.Container
+		out []string
+	}{
+		{in: &types.Container{Name: "db", ShortID: "0123456789ab"}, out: []string{"db", "0123456789ab"}},
+		{in: &types.Container{ShortID: "0123456789ab", NetworkAliases: []string{"db", "db"}}, out: []string{"0123456789ab", "db"}},
+	}
+	for i, tst := range tests {
+		if res := getDNSNames(tst.in); !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
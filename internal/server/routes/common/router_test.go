@@ -0,0 +1,576 @@
+package common_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/server/servertest"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+	pkgbackend "github.com/joyrex2001/kubedock/pkg/backend"
+)
+
+func TestContainerStartError(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{})
+	kub.StartError = errors.New("image pull failed")
+	id := servertest.CreateContainer(t, router)
+
+	w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("failed test - expected %d, but got %d", http.StatusInternalServerError, w.Code)
+	}
+	w = servertest.Do(router, http.MethodGet, "/containers/"+id+"/json", nil)
+	if !strings.Contains(w.Body.String(), `"Error":"image pull failed"`) {
+		t.Errorf("failed test - expected start error in inspect, but got %s", w.Body.String())
+	}
+}
+
+func TestContainerStartTimeout(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainer(t, router)
+
+	tests := []struct {
+		method string
+		url    string
+		code   int
+		match  string
+	}{
+		{method: http.MethodPost, url: "/containers/" + id + "/start?timeout=500ms", code: http.StatusBadRequest, match: "at least 1s"},
+		{method: http.MethodPost, url: "/containers/" + id + "/start?timeout=90s", code: http.StatusNoContent},
+		{method: http.MethodGet, url: "/containers/" + id + "/json", code: http.StatusOK, match: `"com.joyrex2001.kubedock.start-timeout":"90s"`},
+		{method: http.MethodGet, url: `/containers/json?filters={"label":["com.joyrex2001.kubedock.start-timeout=90s"]}`, code: http.StatusOK, match: id},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, tst.method, tst.url, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
+
+func TestDisabledFeature(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{})
+	kub.Disabled = map[string]error{
+		backend.FeatureExec: &backend.FeatureError{Feature: backend.FeatureExec, Missing: []string{"create pods/exec"}},
+	}
+	id := servertest.CreateContainer(t, router)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+
+	w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/exec", strings.NewReader(`{"Cmd":["ls"]}`))
+	if w.Code != http.StatusNotImplemented || !strings.Contains(w.Body.String(), "missing permission to create pods/exec") {
+		t.Errorf("failed test - expected %d with missing permission, but got %d: %s", http.StatusNotImplemented, w.Code, w.Body.String())
+	}
+}
+
+func TestContainerRename(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"Rename-Taken"}`)
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"rename-orig","NetworkingConfig":{"EndpointsConfig":{"bridge":{"Aliases":["rename-orig","db"]}}}}`)
+
+	tests := []struct {
+		method string
+		url    string
+		code   int
+		match  string
+	}{
+		{method: http.MethodPost, url: "/containers/" + id + "/rename?name=rename-taken", code: http.StatusConflict},
+		{method: http.MethodPost, url: "/containers/" + id + "/rename?name=Rename-Orig", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/containers/" + id + "/rename?name=/rename-new", code: http.StatusNoContent},
+		{method: http.MethodGet, url: "/containers/rename-new/json", code: http.StatusOK, match: `"Aliases":["rename-new","db"]`},
+		{method: http.MethodGet, url: "/containers/rename-new/json", code: http.StatusOK, match: `"PodName":"kubedock-rename-new-`},
+		{method: http.MethodGet, url: "/containers/rename-orig/json", code: http.StatusNotFound},
+	}
+
+	for i, tst := range tests {
+		w := servertest.Do(router, tst.method, tst.url, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
+
+func TestNetworkCreateDuplicate(t *testing.T) {
+	tests := []struct {
+		lenient bool
+		url     string
+		body    string
+		code    int
+		match   string
+	}{
+		{url: "/networks/create", body: `{"Name":"dup-net","CheckDuplicate":true}`, code: http.StatusCreated, match: `"Warning":""`},
+		{url: "/networks/create", body: `{"Name":"dup-net","CheckDuplicate":true}`, code: http.StatusConflict, match: `already exists`},
+		{url: "/networks/create", body: `{"Name":"dup-net"}`, code: http.StatusCreated, match: `"Warning":"Network with name dup-net`},
+		{lenient: true, url: "/networks/create", body: `{"Name":"dup-net","CheckDuplicate":true}`, code: http.StatusCreated, match: `"Warning":"Network with name dup-net`},
+		{url: "/libpod/networks/create", body: `{"name":"dup-net"}`, code: http.StatusConflict, match: `already exists`},
+		{url: "/libpod/networks/create", body: `{"name":"dup-net","ignore":true}`, code: http.StatusOK, match: `"name":"dup-net"`},
+		{lenient: true, url: "/libpod/networks/create", body: `{"name":"dup-net"}`, code: http.StatusOK, match: `"name":"dup-net"`},
+		{url: "/libpod/networks/create", body: `{"name":"dup-libpod","labels":{"app":"demo"}}`, code: http.StatusOK, match: `"labels":{"app":"demo"}`},
+	}
+	for i, tst := range tests {
+		router, _ := servertest.NewRouter(t, common.Config{LenientNetworkCreate: tst.lenient})
+		w := servertest.Do(router, http.MethodPost, tst.url, strings.NewReader(tst.body))
+		if w.Code != tst.code || !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %d with %s, but got %d: %s", i, tst.code, tst.match, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestNetworkCreateSubnet(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		code   int
+		match  string
+	}{
+		{method: http.MethodPost, url: "/networks/create", body: `{"Name":"ipam-net","IPAM":{"Config":[{"Subnet":"10.20.0.0/24","Gateway":"10.20.0.254"}]}}`, code: http.StatusCreated},
+		{method: http.MethodGet, url: "/networks/ipam-net", code: http.StatusOK, match: `"Config":[{"Gateway":"10.20.0.254","Subnet":"10.20.0.0/24"}]`},
+		{method: http.MethodPost, url: "/networks/create", body: `{"Name":"overlap-net","IPAM":{"Config":[{"Subnet":"10.20.0.0/16"}]}}`, code: http.StatusForbidden, match: `Pool overlaps`},
+		{method: http.MethodPost, url: "/networks/create", body: `{"Name":"gateway-net","IPAM":{"Config":[{"Subnet":"10.21.0.0/24","Gateway":"10.22.0.1"}]}}`, code: http.StatusBadRequest, match: `invalid gateway`},
+		{method: http.MethodPost, url: "/libpod/networks/create", body: `{"name":"libpod-ipam-net","subnets":[{"subnet":"10.30.0.0/24"}]}`, code: http.StatusOK, match: `"subnets":[{"gateway":"10.30.0.1","subnet":"10.30.0.0/24"}]`},
+	}
+	router, _ := servertest.NewRouter(t, common.Config{})
+	for i, tst := range tests {
+		var body io.Reader
+		if tst.body != "" {
+			body = strings.NewReader(tst.body)
+		}
+		w := servertest.Do(router, tst.method, tst.url, body)
+		if w.Code != tst.code || !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %d with %s, but got %d: %s", i, tst.code, tst.match, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{Info: common.InfoConfig{
+		StorageDriver:   "vfs",
+		CgroupVersion:   "1",
+		SecurityOptions: []string{"name=seccomp,profile=default"},
+		MemTotal:        "2Gi",
+		NCPU:            2,
+	}})
+
+	tests := []struct {
+		url   string
+		match []string
+	}{
+		{
+			url:   "/info",
+			match: []string{`"Driver":"vfs"`, `"CgroupVersion":"1"`, `"SecurityOptions":["name=seccomp,profile=default"]`, `"MemTotal":2147483648`, `"NCPU":2`},
+		},
+		{
+			url:   "/libpod/info",
+			match: []string{`"graphDriverName":"vfs"`, `"cgroupVersion":"v1"`, `"seccompEnabled":true`, `"memTotal":2147483648`, `"cpus":2`},
+		},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodGet, tst.url, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("failed test %d - expected %d, but got %d", i, http.StatusOK, w.Code)
+		}
+		for _, m := range tst.match {
+			if !strings.Contains(w.Body.String(), m) {
+				t.Errorf("failed test %d - expected %s, but got %s", i, m, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestInfoCapacity(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{Info: common.InfoConfig{NCPU: 3}})
+	kub.Capacity = pkgbackend.Capacity{NCPU: 8, MemTotal: 4096}
+
+	tests := []struct {
+		url   string
+		match []string
+	}{
+		{url: "/info", match: []string{`"NCPU":3`, `"MemTotal":4096`}},
+		{url: "/libpod/info", match: []string{`"cpus":3`, `"memTotal":4096`}},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodGet, tst.url, nil)
+		for _, m := range tst.match {
+			if !strings.Contains(w.Body.String(), m) {
+				t.Errorf("failed test %d - expected %s, but got %s", i, m, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+
+	tests := []struct {
+		url   string
+		match []string
+	}{
+		{
+			url:   "/version",
+			match: []string{`"ApiVersion":"1.25"`, `"MinAPIVersion":"1.25"`, `"Platform":{"Name":"Docker Engine - kubedock"}`, `"Name":"Engine"`},
+		},
+		{
+			url:   "/libpod/version",
+			match: []string{`"ApiVersion":"1.25"`, `"APIVersion":"4.2.0"`, `"MinAPIVersion":"4.0.0"`, `"Name":"Podman Engine"`, `"Version":"4.2.0"`},
+		},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodGet, tst.url, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("failed test %d - expected %d, but got %d", i, http.StatusOK, w.Code)
+		}
+		for _, m := range tst.match {
+			if !strings.Contains(w.Body.String(), m) {
+				t.Errorf("failed test %d - expected %s, but got %s", i, m, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestVolumesList(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+
+	tests := []struct {
+		url  string
+		body string
+	}{
+		{url: "/volumes", body: `{"Volumes":[],"Warnings":[]}`},
+		{url: "/libpod/volumes/json", body: `[]`},
+	}
+
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodGet, tst.url, nil)
+		if w.Code != http.StatusOK || w.Body.String() != tst.body {
+			t.Errorf("failed test %d - expected %v, but got %v: %s", i, tst.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestImagePullErrors(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{Inspector: true})
+	id := servertest.CreateContainer(t, router)
+
+	tests := []struct {
+		method  string
+		url     string
+		inspect error
+		start   error
+		code    int
+		match   string
+	}{
+		{method: http.MethodPost, url: "/images/create?fromImage=alpine&tag=nope", inspect: image.ClassifyPullError("alpine:nope", errors.New("manifest unknown")), code: http.StatusNotFound, match: "manifest for alpine:nope not found"},
+		{method: http.MethodPost, url: "/libpod/images/pull?reference=secret/app", inspect: image.ClassifyPullError("secret/app", errors.New("unauthorized: authentication required")), code: http.StatusNotFound, match: "pull access denied for secret/app"},
+		{method: http.MethodPost, url: "/images/create?fromImage=alpine&tag=latest", inspect: errors.New("dial tcp: i/o timeout"), code: http.StatusInternalServerError, match: "i/o timeout"},
+		{method: http.MethodPost, url: "/containers/" + id + "/start", start: image.ClassifyPullError("alpine:latest", errors.New("ErrImagePull: not found")), code: http.StatusNotFound, match: "manifest for alpine:latest not found"},
+	}
+
+	for i, tst := range tests {
+		kub.InspectError, kub.StartError = tst.inspect, tst.start
+		w := servertest.Do(router, tst.method, tst.url, nil)
+		if w.Code != tst.code || !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %v, but got %v: %s", i, tst.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestContainerCreateIdempotency(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+
+	create := func(url, body, key string) (int, string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(common.IdempotencyKeyHeader, key)
+		}
+		router.ServeHTTP(w, req)
+		res := map[string]interface{}{}
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		id, _ := res["Id"].(string)
+		return w.Code, id
+	}
+
+	body := `{"Image":"alpine:latest","Cmd":["sleep","60"]}`
+	_, keyed := create("/containers/create", body, "tb303-retry")
+	_, named := create("/containers/create?name=tr808-retry", body, "")
+	_, started := create("/containers/create?name=tr909-retry", body, "")
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+started+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container: %s", w.Body.String())
+	}
+
+	tests := []struct {
+		url  string
+		body string
+		key  string
+		code int
+		id   string
+	}{
+		{url: "/containers/create", body: body, key: "tb303-retry", code: http.StatusCreated, id: keyed},
+		{url: "/libpod/containers/create", body: body, key: "tb303-retry", code: http.StatusCreated, id: keyed},
+		{url: "/containers/create", body: `{"Image":"busybox:latest"}`, key: "tb303-retry", code: http.StatusUnprocessableEntity},
+		{url: "/containers/create?name=tr808-retry", body: body, code: http.StatusCreated, id: named},
+		{url: "/containers/create?name=tr808-retry", body: `{"Image":"busybox:latest"}`, code: http.StatusConflict},
+		{url: "/containers/create?name=tr909-retry", body: body, code: http.StatusConflict},
+	}
+
+	for i, tst := range tests {
+		code, id := create(tst.url, tst.body, tst.key)
+		if code != tst.code {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.code, code)
+		}
+		if tst.id != "" && id != tst.id {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.id, id)
+		}
+	}
+
+	// concurrent retries with the same key only create a single container
+	var wg sync.WaitGroup
+	ids := make([]string, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, ids[i] = create("/containers/create", body, "sh101-race")
+		}(i)
+	}
+	wg.Wait()
+	for i, id := range ids {
+		if id == "" || id != ids[0] {
+			t.Errorf("failed test %d - expected %v, but got %v", i, ids[0], id)
+		}
+	}
+}
+
+func TestContainerArchiveEncoding(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainer(t, router)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+
+	tests := []struct {
+		upload   string
+		download string
+		code     int
+	}{
+		{upload: "gzip", download: "zstd", code: http.StatusOK},
+		{upload: "zstd", download: "gzip, deflate", code: http.StatusOK},
+		{upload: "", download: "zstd;q=0, gzip", code: http.StatusOK},
+		{upload: "br", code: http.StatusUnsupportedMediaType},
+	}
+	for i, tst := range tests {
+		name := fmt.Sprintf("file%d.txt", i)
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 5})
+		tw.Write([]byte("hello"))
+		tw.Close()
+
+		body := &bytes.Buffer{}
+		enc, err := httputil.NewEncoder(tst.upload, body)
+		if err != nil {
+			enc, _ = httputil.NewEncoder("", body)
+		}
+		enc.Write(buf.Bytes())
+		enc.Close()
+
+		req := httptest.NewRequest(http.MethodPut, "/containers/"+id+"/archive?path=/tmp", body)
+		req.Header.Set("Content-Encoding", tst.upload)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if tst.code != http.StatusOK {
+			continue
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/containers/"+id+"/archive?path=/tmp/"+name, nil)
+		req.Header.Set("Accept-Encoding", tst.download)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		exp := httputil.AcceptedEncoding(req)
+		if ce := w.Header().Get("Content-Encoding"); ce != exp {
+			t.Errorf("failed test %d - expected encoding %s, but got %s", i, exp, ce)
+		}
+		var rd io.Reader = w.Body
+		switch exp {
+		case "gzip":
+			rd, err = gzip.NewReader(w.Body)
+		case "zstd":
+			rd, err = zstd.NewReader(w.Body)
+		}
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error decoding archive: %s", i, err)
+		}
+		tr := tar.NewReader(rd)
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatalf("failed test %d - unexpected error reading archive: %s", i, err)
+		}
+		dat, _ := io.ReadAll(tr)
+		if hdr.Name != name || string(dat) != "hello" {
+			t.Errorf("failed test %d - expected %s with hello, but got %s with %s", i, name, hdr.Name, dat)
+		}
+	}
+}
+
+func TestContainerArchiveResume(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainer(t, router)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "resumed.txt", Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.Close()
+	dat := buf.Bytes()
+	total := len(dat)
+
+	tests := []struct {
+		rng  string
+		body []byte
+		code int
+		resp string
+	}{
+		{rng: fmt.Sprintf("bytes 0-999/%d", total), body: dat[:1000], code: http.StatusPermanentRedirect, resp: "bytes=0-999"},
+		{rng: fmt.Sprintf("bytes 500-999/%d", total), body: dat[500:1000], code: http.StatusRequestedRangeNotSatisfiable, resp: "bytes=0-999"},
+		{rng: fmt.Sprintf("bytes */%d", total), code: http.StatusPermanentRedirect, resp: "bytes=0-999"},
+		{rng: "bytes 0-10", code: http.StatusBadRequest},
+		{rng: fmt.Sprintf("bytes 1000-%d/%d", total-1, total), body: dat[1000:], code: http.StatusOK},
+	}
+	for i, tst := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/containers/"+id+"/archive?path=/tmp", bytes.NewReader(tst.body))
+		req.Header.Set("Content-Range", tst.rng)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if rng := w.Header().Get("Range"); rng != tst.resp {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.resp, rng)
+		}
+	}
+
+	w := servertest.Do(router, http.MethodHead, "/containers/"+id+"/archive?path=/tmp/resumed.txt", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("failed test - expected %d, but got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestContainerArchive(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainer(t, router)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "hello.txt", Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.Close()
+
+	w := servertest.Do(router, http.MethodPut, "/containers/"+id+"/archive?path=/tmp", buf)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed test - expected %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = servertest.Do(router, http.MethodGet, "/containers/"+id+"/archive?path=/tmp/hello.txt", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed test - expected %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	tr := tar.NewReader(w.Body)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("unexpected error reading archive: %s", err)
+	}
+	dat, _ := io.ReadAll(tr)
+	if hdr.Name != "hello.txt" || string(dat) != "hello" {
+		t.Errorf("failed test - expected hello.txt with hello, but got %s with %s", hdr.Name, dat)
+	}
+
+	tests := []struct {
+		path string
+		code int
+		stat string
+	}{
+		{path: "/tmp/hello.txt", code: http.StatusOK, stat: `{"linkTarget":"","mode":420,"mtime":"1970-01-01T00:00:00Z","name":"hello.txt","size":5}`},
+		{path: "/tmp", code: http.StatusOK, stat: `{"linkTarget":"","mode":2147484141,"mtime":"0001-01-01T00:00:00Z","name":"tmp","size":4096}`},
+		{path: "/tmp/missing.txt", code: http.StatusNotFound},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodHead, "/containers/"+id+"/archive?path="+tst.path, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.code, w.Code)
+		}
+		stat, _ := base64.StdEncoding.DecodeString(w.Header().Get("X-Docker-Container-Path-Stat"))
+		if string(stat) != tst.stat {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.stat, stat)
+		}
+	}
+}
+
+func TestContainerArchiveHelper(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{ArchiveHelper: true})
+	id := servertest.CreateContainer(t, router)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "conf/app.conf", Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.WriteHeader(&tar.Header{Name: "conf/other.conf", Mode: 0644, Size: 5})
+	tw.Write([]byte("world"))
+	tw.Close()
+
+	w := servertest.Do(router, http.MethodPut, "/containers/"+id+"/archive?path=/etc", buf)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed test - expected %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// the archive is available before, and after starting the container
+	for i, action := range []string{"", "start"} {
+		if action != "" {
+			if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/"+action, nil); w.Code != http.StatusNoContent {
+				t.Fatalf("failed test %d - expected %d, but got %d", i, http.StatusNoContent, w.Code)
+			}
+		}
+		w = servertest.Do(router, http.MethodGet, "/containers/"+id+"/archive?path=/etc/conf/other.conf", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("failed test %d - expected %d, but got %d: %s", i, http.StatusOK, w.Code, w.Body.String())
+		}
+		tr := tar.NewReader(w.Body)
+		if _, err := tr.Next(); err != nil {
+			t.Fatalf("failed test %d - unexpected error reading archive: %s", i, err)
+		}
+		if dat, _ := io.ReadAll(tr); string(dat) != "world" {
+			t.Errorf("failed test %d - expected world, but got %s", i, dat)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/config"
+)
+
+// VersionInfo contains the versions that are advertised by a version
+// endpoint.
+type VersionInfo struct {
+	// Platform is the name of the platform (e.g. Docker Engine)
+	Platform string
+	// Component is the name of the engine component (e.g. Engine)
+	Component string
+	// Version is the version of the engine
+	Version string
+	// APIVersion is the (max) docker api version of the engine
+	APIVersion string
+	// MinAPIVersion is the minimum docker api version of the engine
+	MinAPIVersion string
+	// Details contains additional details of the engine component, which
+	// are added to the generic build details
+	Details gin.H
+}
+
+// GetVersion will return the version response in the format as used by
+// docker and the podman compat api. Clients use ApiVersion and MinAPIVersion
+// for version negotiation, and read the engine details from the Components.
+func GetVersion(vi VersionInfo) gin.H {
+	details := gin.H{
+		"GitCommit":     config.Build,
+		"BuildTime":     config.Date,
+		"GoVersion":     config.GoVersion,
+		"Os":            config.GOOS,
+		"Arch":          config.GOARCH,
+		"KernelVersion": config.OS,
+		"Experimental":  "false",
+	}
+	for k, v := range vi.Details {
+		details[k] = v
+	}
+	return gin.H{
+		"Platform": gin.H{"Name": vi.Platform},
+		"Components": []gin.H{
+			{
+				"Name":    vi.Component,
+				"Version": vi.Version,
+				"Details": details,
+			},
+			{
+				"Name":    config.Name,
+				"Version": config.Version,
+				"Details": gin.H{"GitCommit": config.Build},
+			},
+		},
+		"Version":       vi.Version,
+		"ApiVersion":    vi.APIVersion,
+		"MinAPIVersion": vi.MinAPIVersion,
+		"GitCommit":     config.Build,
+		"BuildTime":     config.Date,
+		"GoVersion":     config.GoVersion,
+		"Os":            config.GOOS,
+		"Arch":          config.GOARCH,
+		"KernelVersion": config.OS,
+		"Experimental":  false,
+	}
+}
+
+// InfoConfig contains the system information that is reported by the info
+// endpoints. As containers don't run on the host kubedock is running on,
+// these values are not detected, but can be configured per deployment, as
+// some clients make decisions based on them.
+type InfoConfig struct {
+	// OperatingSystem is the reported operating system
+	OperatingSystem string `mapstructure:"operating-system"`
+	// KernelVersion is the reported kernel version
+	KernelVersion string `mapstructure:"kernel-version"`
+	// StorageDriver is the reported storage driver (e.g. overlay2)
+	StorageDriver string `mapstructure:"storage-driver"`
+	// CgroupVersion is the reported cgroup version (1 or 2)
+	CgroupVersion string `mapstructure:"cgroup-version"`
+	// CgroupDriver is the reported cgroup driver (e.g. systemd)
+	CgroupDriver string `mapstructure:"cgroup-driver"`
+	// SecurityOptions are the reported security options, in the docker
+	// format (e.g. name=seccomp,profile=default)
+	SecurityOptions []string `mapstructure:"security-options"`
+	// MemTotal is the reported total memory, as a k8s quantity (e.g. 8Gi)
+	MemTotal string `mapstructure:"mem-total"`
+	// NCPU is the reported number of cpus
+	NCPU int `mapstructure:"ncpu"`
+}
+
+// ValidateInfoConfig will validate the given info configuration.
+func ValidateInfoConfig(ic InfoConfig) error {
+	if ic.CgroupVersion != "" && ic.CgroupVersion != "1" && ic.CgroupVersion != "2" {
+		return fmt.Errorf("invalid cgroup version %s, expected 1 or 2", ic.CgroupVersion)
+	}
+	if ic.MemTotal != "" {
+		if _, err := resource.ParseQuantity(ic.MemTotal); err != nil {
+			return fmt.Errorf("invalid mem-total %s: %w", ic.MemTotal, err)
+		}
+	}
+	if ic.NCPU < 0 {
+		return fmt.Errorf("invalid ncpu %d", ic.NCPU)
+	}
+	for _, opt := range ic.SecurityOptions {
+		if !strings.HasPrefix(opt, "name=") {
+			return fmt.Errorf("invalid security option %s, expected name=...", opt)
+		}
+	}
+	return nil
+}
+
+// WithDefaults will return a copy of the info configuration, of which the
+// values that are not configured are set to their defaults.
+func (ic InfoConfig) WithDefaults() InfoConfig {
+	if ic.OperatingSystem == "" {
+		ic.OperatingSystem = config.OS
+	}
+	if ic.StorageDriver == "" {
+		ic.StorageDriver = "overlay2"
+	}
+	if ic.CgroupVersion == "" {
+		ic.CgroupVersion = "2"
+	}
+	if ic.CgroupDriver == "" {
+		ic.CgroupDriver = "systemd"
+	}
+	if ic.SecurityOptions == nil {
+		ic.SecurityOptions = []string{}
+	}
+	return ic
+}
+
+// GetMemTotal will return the configured total memory in bytes, or 0 if
+// this is not configured.
+func (ic InfoConfig) GetMemTotal() int64 {
+	if ic.MemTotal == "" {
+		return 0
+	}
+	qty, err := resource.ParseQuantity(ic.MemTotal)
+	if err != nil {
+		return 0
+	}
+	return qty.Value()
+}
+
+// GetCapacity will return the number of cpus and the total memory that is
+// reported by the info endpoints. Values that are not configured are based
+// on the capacity of the namespace.
+func GetCapacity(cr *ContextRouter, ic InfoConfig) (int, int64) {
+	ncpu, mem := ic.NCPU, ic.GetMemTotal()
+	if ncpu > 0 && mem > 0 {
+		return ncpu, mem
+	}
+	capa, err := cr.Backend.GetCapacity()
+	if err != nil {
+		klog.Warningf("error retrieving namespace capacity: %s", err)
+		return ncpu, mem
+	}
+	if ncpu == 0 {
+		ncpu = capa.NCPU
+	}
+	if mem == 0 {
+		mem = capa.MemTotal
+	}
+	return ncpu, mem
+}
+
+// HasSecurityOption will return true if a security option with the given
+// name (e.g. seccomp) is configured.
+func (ic InfoConfig) HasSecurityOption(name string) bool {
+	for _, opt := range ic.SecurityOptions {
+		for _, kv := range strings.Split(opt, ",") {
+			if kv == "name="+name {
+				return true
+			}
+		}
+	}
+	return false
+}
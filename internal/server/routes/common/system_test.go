@@ -0,0 +1,52 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestValidateInfoConfig(t *testing.T) {
+	tests := []struct {
+		in  InfoConfig
+		err bool
+	}{
+		{in: InfoConfig{}, err: false},
+		{in: InfoConfig{CgroupVersion: "1", MemTotal: "8Gi", NCPU: 4, SecurityOptions: []string{"name=seccomp,profile=default"}}, err: false},
+		{in: InfoConfig{CgroupVersion: "3"}, err: true},
+		{in: InfoConfig{MemTotal: "lots"}, err: true},
+		{in: InfoConfig{NCPU: -1}, err: true},
+		{in: InfoConfig{SecurityOptions: []string{"seccomp"}}, err: true},
+	}
+	for i, tst := range tests {
+		if err := ValidateInfoConfig(tst.in); (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+	}
+}
+
+func TestInfoConfig(t *testing.T) {
+	tests := []struct {
+		in       InfoConfig
+		mem      int64
+		seccomp  bool
+		apparmor bool
+	}{
+		{in: InfoConfig{}, mem: 0},
+		{in: InfoConfig{MemTotal: "1Gi"}, mem: 1073741824},
+		{in: InfoConfig{SecurityOptions: []string{"name=seccomp,profile=default", "name=cgroupns"}}, seccomp: true},
+		{in: InfoConfig{SecurityOptions: []string{"name=apparmor"}}, apparmor: true},
+	}
+	for i, tst := range tests {
+		if mem := tst.in.GetMemTotal(); mem != tst.mem {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.mem, mem)
+		}
+		if res := tst.in.HasSecurityOption("seccomp"); res != tst.seccomp {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.seccomp, res)
+		}
+		if res := tst.in.HasSecurityOption("apparmor"); res != tst.apparmor {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.apparmor, res)
+		}
+		if def := tst.in.WithDefaults(); def.StorageDriver != "overlay2" || def.CgroupVersion != "2" {
+			t.Errorf("failed test %d - expected defaults, but got %+v", i, def)
+		}
+	}
+}
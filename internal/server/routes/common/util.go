@@ -1,22 +1,53 @@
 package common
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"k8s.io/klog"
 
 	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/metrics"
 	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
 )
 
 // StartContainer will start given container and saves the appropriate state
-// in the database.
-func StartContainer(cr *ContextRouter, tainr *types.Container) error {
-	state, err := cr.Backend.StartContainer(tainr)
-	if err != nil {
+// in the database. Starting is aborted when the given context is done. The
+// container is started using a copy of the given container; the state that
+// is set while starting is stored when the start completes.
+func StartContainer(ctx context.Context, cr *ContextRouter, tainr *types.Container) error {
+	tainr = tainr.Clone()
+	if tainr.IsLinked() {
+		owner, err := cr.DB.GetContainer(tainr.NetworkOwner)
+		if err != nil || !owner.Running {
+			return fmt.Errorf("cannot join network of a non running container: %s", tainr.NetworkOwner)
+		}
+	}
+
+	if err := resolveLinks(cr, tainr); err != nil {
 		return err
 	}
 
+	health := tainr.StatusString()
+	state, err := cr.Backend.StartContainer(ctx, tainr)
+	if err != nil {
+		return failStart(cr, tainr, err)
+	}
+
+	forwards := time.Now()
 	tainr.HostIP = "0.0.0.0"
 	if cr.Config.PortForward {
 		cr.Backend.CreatePortForwards(tainr)
@@ -24,42 +55,606 @@ func StartContainer(cr *ContextRouter, tainr *types.Container) error {
 		if len(tainr.GetServicePorts()) > 0 {
 			ip, err := cr.Backend.GetPodIP(tainr)
 			if err != nil {
-				return err
+				return abortStart(cr, tainr, err)
 			}
 			tainr.HostIP = ip
 			if cr.Config.ReverseProxy {
 				cr.Backend.CreateReverseProxies(tainr)
+				if cr.Config.ProxyHostIP != "" {
+					tainr.HostIP = cr.Config.ProxyHostIP
+				}
 			}
 		}
 	}
+	if state == backend.DeployRunning {
+		if readiness, _ := tainr.GetReadiness(); readiness == types.ReadinessTCP {
+			if err := waitPortsReachable(cr, tainr); err != nil {
+				return abortStart(cr, tainr, err)
+			}
+		}
+	}
+	tainr.SetStartTiming(types.PhaseForwards, time.Since(forwards))
+	reportStartTimings(cr, tainr)
+
+	tainr, err = cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		copyStartState(rec, tainr)
+		rec.Error = ""
+		rec.Stopped = false
+		rec.Killed = false
+		rec.Initialized = false
+		rec.ExitStatus = 0
+		rec.Failed = (state == backend.DeployFailed)
+		rec.Completed = (state == backend.DeployCompleted)
+		rec.Running = (state == backend.DeployRunning)
+		rec.Started = time.Now()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	PublishHealthStatusEvent(cr, tainr, health)
+	if state == backend.DeployRunning {
+		watchContainerExit(cr, tainr)
+	}
+	return nil
+}
 
-	tainr.Stopped = false
-	tainr.Killed = false
-	tainr.Failed = (state == backend.DeployFailed)
-	tainr.Completed = (state == backend.DeployCompleted)
-	tainr.Running = (state == backend.DeployRunning)
+// failStart will store the state that was set while starting the given
+// container, together with the given start error, and publishes a die
+// event. The given error is returned.
+func failStart(cr *ContextRouter, tainr *types.Container, err error) error {
+	if _, uerr := cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		copyStartState(rec, tainr)
+		rec.Error = err.Error()
+		return nil
+	}); uerr != nil {
+		klog.Warningf("error saving container state: %s", uerr)
+	}
+	PublishContainerEvent(cr, tainr, events.Die)
+	return err
+}
 
-	return cr.DB.SaveContainer(tainr)
+// abortStart will remove the pod of the given container, which was deployed
+// but failed to become available, and stops its port-forwards and reverse
+// proxies, so the cluster doesn't diverge from the stored (failed) state.
+func abortStart(cr *ContextRouter, tainr *types.Container, err error) error {
+	tainr.SignalStop()
+	if derr := cr.Backend.DeleteContainer(tainr); derr != nil {
+		klog.Warningf("error removing container %s after failed start: %s", tainr.ShortID, derr)
+	}
+	return failStart(cr, tainr, err)
+}
+
+// copyStartState will copy the state that is set while starting the given
+// started container (e.g. the pod name and the port mappings) to the given
+// container record. The record gets its own copies, so the started container
+// can't modify the stored record (e.g. while it's being inspected).
+func copyStartState(rec, started *types.Container) {
+	rec.PodName = started.PodName
+	rec.HostIP = started.HostIP
+	rec.MappedPorts = maps.Clone(started.MappedPorts)
+	rec.LinkEnv = slices.Clone(started.LinkEnv)
+	rec.LinkHosts = maps.Clone(started.LinkHosts)
+	rec.StartTimings = maps.Clone(started.StartTimings)
+}
+
+// watchContainerExit will watch given container until it terminates, and
+// update its state with the exit code as soon as it does, so the container
+// is reported as exited without having to poll its status. If the pod of the
+// container was evicted or otherwise disrupted, the reason is reported as
+// the error of the container. Containers that were stopped or killed in the
+// meantime are left as is.
+func watchContainerExit(cr *ContextRouter, tainr *types.Container) {
+	exitch, err := cr.Backend.WatchContainerExit(tainr)
+	if err != nil {
+		klog.Warningf("error watching container %s: %s", tainr.ShortID, err)
+		return
+	}
+	go func() {
+		exit, ok := <-exitch
+		if !ok {
+			return
+		}
+		setContainerExited(cr, tainr, exit.Code, exit.Reason)
+	}()
+}
+
+// setContainerExited will update the state of given running container to
+// exited with given exit code, and publishes the die event. A non-empty
+// reason (e.g. of an eviction) is stored as the error of the container.
+// Containers that were stopped or killed in the meantime are left as is.
+// The updated container is returned.
+func setContainerExited(cr *ContextRouter, tainr *types.Container, code int, reason string) *types.Container {
+	health := tainr.StatusString()
+	exited := false
+	res, err := cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		if !rec.Running || rec.Stopped || rec.Killed {
+			return nil
+		}
+		rec.SetExited(code)
+		if reason != "" {
+			rec.Error = reason
+		}
+		exited = true
+		return nil
+	})
+	if err != nil {
+		klog.V(3).Infof("not updating exit code of container %s: %s", tainr.ShortID, err)
+		return tainr
+	}
+	if !exited {
+		return res
+	}
+	if reason != "" {
+		klog.Warningf("container %s was terminated by kubernetes: %s", tainr.ShortID, reason)
+	} else {
+		klog.V(2).Infof("container %s exited with code %d", tainr.ShortID, code)
+	}
+	PublishContainerEvent(cr, res, events.Die)
+	PublishHealthStatusEvent(cr, res, health)
+	return res
+}
+
+// ApplyStartTimeout will validate the start timeout of the given container,
+// which is not saved yet, and override it with the timeout query parameter,
+// if given.
+func ApplyStartTimeout(c *gin.Context, tainr *types.Container) error {
+	if val := c.Query("timeout"); val != "" {
+		return tainr.SetStartTimeout(val)
+	}
+	_, err := tainr.GetStartTimeout(0)
+	return err
+}
+
+// UpdateStartTimeout will validate the start timeout of the given (saved)
+// container and override it with the timeout query parameter, if given. It
+// returns the updated container.
+func UpdateStartTimeout(cr *ContextRouter, c *gin.Context, tainr *types.Container) (*types.Container, error) {
+	val := c.Query("timeout")
+	if val == "" {
+		_, err := tainr.GetStartTimeout(0)
+		return tainr, err
+	}
+	if _, err := types.ParseStartTimeout(val); err != nil {
+		return nil, err
+	}
+	return cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		return rec.SetStartTimeout(val)
+	})
+}
+
+// StartErrorStatus will return the http status code that should be returned
+// for the given error that occurred while starting a container.
+func StartErrorStatus(err error) int {
+	var quota *backend.QuotaExceededError
+	if errors.As(err, &quota) {
+		return http.StatusTooManyRequests
+	}
+	var pull *image.PullError
+	if errors.As(err, &pull) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// LinkContainer will configure given container to share the network of the
+// container with given name or id (container:<id> network mode).
+func LinkContainer(cr *ContextRouter, tainr *types.Container, ref string) error {
+	owner, err := cr.DB.GetContainerByNameOrID(ref)
+	if err != nil {
+		return err
+	}
+	if owner.IsLinked() {
+		if owner, err = cr.DB.GetContainer(owner.NetworkOwner); err != nil {
+			return err
+		}
+	}
+	tainr.LinkToContainer(owner)
+	return nil
+}
+
+// AddLink will add a legacy link (HostConfig.Links) to given container. The
+// link is in the name:alias format, where the alias is optional.
+func AddLink(cr *ContextRouter, tainr *types.Container, link string) error {
+	name, alias, _ := strings.Cut(link, ":")
+	name = strings.TrimPrefix(name, "/")
+	if alias == "" {
+		alias = name
+	}
+	alias = alias[strings.LastIndex(alias, "/")+1:]
+	linked, err := cr.DB.GetContainerByNameOrID(name)
+	if err != nil {
+		return err
+	}
+	tainr.AddLink(linked, alias)
+	return nil
+}
+
+// resolveLinks will determine the environment variables and host aliases
+// for the legacy links of given container. Linked containers should be
+// running, similar to docker. Container names and network aliases take
+// precedence over link aliases in dns resolution; host aliases are not
+// added for link aliases that resolve to another container on a network
+// shared with given container.
+func resolveLinks(cr *ContextRouter, tainr *types.Container) error {
+	tainr.LinkEnv = []string{}
+	tainr.LinkHosts = map[string][]string{}
+	if len(tainr.Links) == 0 {
+		return nil
+	}
+	names := getNetworkDNSNames(cr, tainr)
+	shadowed := func(name, id string) bool {
+		if owner, ok := names[strings.ToLower(name)]; ok && owner != id {
+			klog.Infof("not adding host alias for link %s, resolves to container %s", name, owner)
+			return true
+		}
+		return false
+	}
+	for _, alias := range tainr.GetLinkAliases() {
+		linked, err := cr.DB.GetContainer(tainr.Links[alias])
+		if err != nil || !linked.Running {
+			return fmt.Errorf("cannot link to a non running container: %s", tainr.Links[alias])
+		}
+		ip, err := cr.Backend.GetPodIP(linked)
+		if err != nil {
+			return err
+		}
+		tainr.LinkEnv = append(tainr.LinkEnv, tainr.GetLinkEnv(linked, alias, ip)...)
+		if !shadowed(alias, linked.ID) {
+			tainr.LinkHosts[ip] = append(tainr.LinkHosts[ip], alias)
+		}
+		if linked.Name != alias && !slices.Contains(tainr.LinkHosts[ip], linked.Name) && !shadowed(linked.Name, linked.ID) {
+			tainr.LinkHosts[ip] = append(tainr.LinkHosts[ip], linked.Name)
+		}
+		if len(tainr.LinkHosts[ip]) == 0 {
+			delete(tainr.LinkHosts, ip)
+		}
+	}
+	return nil
+}
+
+// getNetworkDNSNames will return the names that can be resolved on the
+// networks given container is connected to, with the id of the container
+// they resolve to. Container names take precedence over network aliases.
+func getNetworkDNSNames(cr *ContextRouter, tainr *types.Container) map[string]string {
+	res := map[string]string{}
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		return res
+	}
+	aliases := map[string]string{}
+	for _, other := range tainrs {
+		if other.ID == tainr.ID || !other.Running || !sharesNetwork(tainr, other) {
+			continue
+		}
+		for i, name := range other.GetDNSNames() {
+			if i == 0 && name == strings.ToLower(other.Name) {
+				res[name] = other.ID
+			} else if _, ok := aliases[name]; !ok {
+				aliases[name] = other.ID
+			}
+		}
+	}
+	for name, id := range aliases {
+		if _, ok := res[name]; !ok {
+			res[name] = id
+		}
+	}
+	return res
+}
+
+// sharesNetwork will return true if given containers are connected to the
+// same network.
+func sharesNetwork(tainr, other *types.Container) bool {
+	for id := range tainr.Networks {
+		if _, ok := other.Networks[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAllowedDevices will parse the given comma separated list of allowed
+// devices. Each device is either a path, which is mounted as a hostPath
+// volume, or a path=resource, which will request given device plugin
+// resource instead.
+func ParseAllowedDevices(val string) map[string]string {
+	res := map[string]string{}
+	for _, dev := range strings.Split(val, ",") {
+		dev = strings.TrimSpace(dev)
+		if dev == "" {
+			continue
+		}
+		path, resource, _ := strings.Cut(dev, "=")
+		res[path] = resource
+	}
+	return res
+}
+
+// GetDevices will return the given devices that can be passed through to
+// the container, and warnings for the devices that are ignored. Only devices
+// that are allowed with --allowed-devices are passed through.
+func GetDevices(cr *ContextRouter, devices []types.Device) ([]types.Device, []string) {
+	res := []types.Device{}
+	warnings := []string{}
+	for _, dev := range devices {
+		resource, ok := cr.Config.AllowedDevices[dev.HostPath]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("device '%s' is not allowed and is ignored, start kubedock with --allowed-devices to enable it", dev.HostPath))
+			continue
+		}
+		if dev.ContainerPath == "" {
+			dev.ContainerPath = dev.HostPath
+		}
+		dev.Resource = resource
+		res = append(res, dev)
+	}
+	return res, warnings
+}
+
+// safeSysctls contains the sysctls that are considered safe by kubernetes,
+// and are allowed by default.
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+	"net.ipv4.tcp_fin_timeout":            true,
+}
+
+// GetSysctls will return the given sysctls that can be applied on the pod,
+// and warnings for the sysctls that are ignored. Unsafe sysctls are only
+// allowed if enabled with --allow-unsafe-sysctls, and require the kubelet
+// to allow them as well.
+func GetSysctls(cr *ContextRouter, sysctls map[string]string) (map[string]string, []string) {
+	res := map[string]string{}
+	names := []string{}
+	for name := range sysctls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	warnings := []string{}
+	for _, name := range names {
+		if !safeSysctls[name] && !cr.Config.AllowUnsafeSysctls {
+			warnings = append(warnings, fmt.Sprintf("sysctl '%s' is unsafe and is ignored, start kubedock with --allow-unsafe-sysctls to enable it", name))
+			continue
+		}
+		res[name] = sysctls[name]
+	}
+	return res, warnings
+}
+
+// GetUlimits will return the given ulimits that can be applied on the
+// container, and warnings for the ulimits that are ignored. Names can be
+// specified with or without the RLIMIT_ prefix.
+func GetUlimits(limits []ulimit.Limit) ([]ulimit.Limit, []string) {
+	res := []ulimit.Limit{}
+	warnings := []string{}
+	for _, l := range limits {
+		l.Name = strings.ToLower(strings.TrimPrefix(strings.ToUpper(l.Name), "RLIMIT_"))
+		if !ulimit.Names[l.Name] {
+			warnings = append(warnings, fmt.Sprintf("ulimit '%s' is not supported and is ignored", l.Name))
+			continue
+		}
+		if l.Hard >= 0 && (l.Soft < 0 || l.Soft > l.Hard) {
+			warnings = append(warnings, fmt.Sprintf("ulimit '%s' has a soft limit above the hard limit and is ignored", l.Name))
+			continue
+		}
+		res = append(res, l)
+	}
+	return res, warnings
+}
+
+// ValidateCreate will check the warnings about unsupported features that
+// were collected while creating a container. In strict mode, an error is
+// returned if there are any; otherwise the warnings are logged.
+func ValidateCreate(cr *ContextRouter, warnings []string) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	if cr.Config.StrictCreate {
+		return fmt.Errorf("unsupported container configuration: %s", strings.Join(warnings, "; "))
+	}
+	for _, w := range warnings {
+		klog.Warning(w)
+	}
+	return nil
+}
+
+// UseHostNetwork will configure given container to use the network of the
+// node it's running on (host network mode), which requires this to be
+// explicitly allowed.
+func UseHostNetwork(cr *ContextRouter, tainr *types.Container) error {
+	if !cr.Config.AllowHostNetwork {
+		return fmt.Errorf("host network mode is not allowed, start kubedock with --allow-host-network to enable it")
+	}
+	netw, err := cr.DB.GetNetworkByName("host")
+	if err != nil {
+		return err
+	}
+	tainr.HostNetwork = true
+	tainr.ConnectNetwork(netw.ID)
+	return nil
+}
+
+// DeleteContainer will delete the kubernetes resources of given container,
+// unless the container failed and should be retained for post-mortem
+// debugging (retain-on-failure label).
+func DeleteContainer(cr *ContextRouter, tainr *types.Container) error {
+	retained, err := cr.Backend.RetainContainer(tainr)
+	if err != nil {
+		klog.Warningf("error while retaining k8s container: %s", err)
+	}
+	if retained {
+		return nil
+	}
+	return cr.Backend.DeleteContainer(tainr)
+}
+
+// StopLinkedContainers will stop all containers that share the network of
+// the given container, as these are stopped together with the pod of the
+// given container.
+func StopLinkedContainers(cr *ContextRouter, owner *types.Container) {
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		klog.Warningf("error while fetching linked containers: %s", err)
+		return
+	}
+	for _, tainr := range tainrs {
+		if tainr.NetworkOwner != owner.ID || tainr.Stopped || tainr.Killed {
+			continue
+		}
+		tainr.SignalDetach()
+		tainr.SignalStop()
+		if err := cr.Backend.DeleteContainer(tainr); err != nil {
+			klog.Warningf("error while deleting k8s container: %s", err)
+		}
+		tainr, err = SetContainerStopped(cr, tainr)
+		if err != nil {
+			klog.Warningf("error while saving linked container: %s", err)
+			continue
+		}
+		PublishContainerEvent(cr, tainr, events.Die)
+	}
+}
+
+// waitPortsReachable will wait until all published ports of the given
+// container accept tcp connections, or until the readiness timeout expired.
+func waitPortsReachable(cr *ContextRouter, tainr *types.Container) error {
+	addrs := []string{}
+	if cr.Config.PortForward || cr.Config.ReverseProxy {
+		for _, prts := range []map[int]int{tainr.HostPorts, tainr.MappedPorts} {
+			for src := range prts {
+				if src > 0 {
+					addrs = append(addrs, net.JoinHostPort("127.0.0.1", strconv.Itoa(src)))
+				}
+			}
+		}
+	} else {
+		for _, dst := range tainr.GetServicePorts() {
+			addrs = append(addrs, net.JoinHostPort(tainr.HostIP, strconv.Itoa(dst)))
+		}
+	}
+
+	timeout, err := tainr.GetStartTimeout(cr.Config.ReadinessTimeout)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for _, addr := range addrs {
+		for {
+			conn, err := net.DialTimeout("tcp", addr, time.Second)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("timeout waiting for %s to become reachable: %w", addr, err)
+			}
+			klog.V(2).Infof("waiting for %s to become reachable: %s", addr, err)
+			time.Sleep(250 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// reportStartTimings will record the start phase timings of the given
+// container in the metrics, and will warn if the start took longer than the
+// configured latency budget.
+func reportStartTimings(cr *ContextRouter, tainr *types.Container) {
+	metrics.ObserveStartTimings(tainr.StartTimings)
+	total := tainr.GetStartDuration()
+	if cr.Config.StartLatencyBudget <= 0 || total <= cr.Config.StartLatencyBudget {
+		return
+	}
+	phases := []string{}
+	for _, phase := range types.StartPhases {
+		phases = append(phases, fmt.Sprintf("%s=%s", phase, tainr.StartTimings[phase].Round(time.Millisecond)))
+	}
+	klog.Warningf("container %s took %s to start, exceeding budget of %s (%s)", tainr.ShortID, total.Round(time.Millisecond), cr.Config.StartLatencyBudget, strings.Join(phases, ", "))
+}
+
+// GetKubedockInfo will return the kubedock extension of the container
+// details; the name of the pod that runs the container, and the start phase
+// timings of the given container in milliseconds.
+func GetKubedockInfo(tainr *types.Container) map[string]interface{} {
+	timings := map[string]int64{}
+	for phase, d := range tainr.StartTimings {
+		timings[phase] = d.Milliseconds()
+	}
+	return map[string]interface{}{
+		"PodName":       tainr.GetPodName(),
+		"StartTimings":  timings,
+		"StartDuration": tainr.GetStartDuration().Milliseconds(),
+	}
 }
 
 // UpdateContainerStatus will check if the started container is finished and will
-// update the container database record accordingly.
-func UpdateContainerStatus(cr *ContextRouter, tainr *types.Container) {
+// update the container database record accordingly. The updated container
+// is returned.
+func UpdateContainerStatus(cr *ContextRouter, tainr *types.Container) *types.Container {
 	if tainr.Completed {
-		return
+		return tainr
 	}
 	if !cr.Limiter.Allow() {
 		klog.V(2).Infof("rate-limited status request for container: %s", tainr.ID)
-		return
+		return tainr
 	}
+	health := tainr.StatusString()
 	status, err := cr.Backend.GetContainerStatus(tainr)
+	var disruption *backend.DisruptionError
+	if errors.As(err, &disruption) {
+		return setContainerExited(cr, tainr, 137, disruption.Reason)
+	}
 	if err != nil {
 		klog.Warningf("container status error: %s", err)
-		tainr.Failed = true
 	}
-	if status == backend.DeployCompleted {
-		tainr.Finished = time.Now()
-		tainr.Completed = true
-		tainr.Running = false
+	if err == nil && status != backend.DeployCompleted {
+		return tainr
+	}
+	res, uerr := cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		if err != nil {
+			rec.Failed = true
+		}
+		if status == backend.DeployCompleted {
+			rec.SetExited(0)
+		}
+		return nil
+	})
+	if uerr != nil {
+		klog.Warningf("error saving container status: %s", uerr)
+		return tainr
+	}
+	PublishHealthStatusEvent(cr, res, health)
+	return res
+}
+
+// SetContainerStopped will mark given container as stopped in the database,
+// and returns the updated container.
+func SetContainerStopped(cr *ContextRouter, tainr *types.Container) (*types.Container, error) {
+	return cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		rec.Running = false
+		rec.Completed = false
+		rec.Stopped = true
+		return nil
+	})
+}
+
+// parseTerminalSize will return the terminal width and height as given in
+// the w and h query parameters of a resize request.
+func parseTerminalSize(c *gin.Context) (uint16, uint16, error) {
+	width, err := strconv.ParseUint(c.Query("w"), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid terminal width: %w", err)
+	}
+	height, err := strconv.ParseUint(c.Query("h"), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid terminal height: %w", err)
 	}
+	return uint16(width), uint16(height), nil
 }
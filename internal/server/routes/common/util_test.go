@@ -0,0 +1,210 @@
+package common
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/model"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
+	"github.com/joyrex2001/kubedock/pkg/backend/fake"
+)
+
+func TestValidateCreate(t *testing.T) {
+	tests := []struct {
+		warnings []string
+		strict   bool
+		err      bool
+	}{
+		{warnings: []string{}, strict: true, err: false},
+		{warnings: []string{"device '/dev/kvm' is not supported and is ignored"}, strict: false, err: false},
+		{warnings: []string{"device '/dev/kvm' is not supported and is ignored"}, strict: true, err: true},
+	}
+	for i, tst := range tests {
+		cr := &ContextRouter{Config: Config{StrictCreate: tst.strict}}
+		if err := ValidateCreate(cr, tst.warnings); (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+	}
+}
+
+func TestGetSysctls(t *testing.T) {
+	sysctls := map[string]string{"net.ipv4.tcp_syncookies": "1", "net.core.somaxconn": "1024"}
+	tests := []struct {
+		unsafe   bool
+		out      map[string]string
+		warnings int
+	}{
+		{unsafe: false, out: map[string]string{"net.ipv4.tcp_syncookies": "1"}, warnings: 1},
+		{unsafe: true, out: sysctls, warnings: 0},
+	}
+	for i, tst := range tests {
+		cr := &ContextRouter{Config: Config{AllowUnsafeSysctls: tst.unsafe}}
+		res, warnings := GetSysctls(cr, sysctls)
+		if !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+		if len(warnings) != tst.warnings {
+			t.Errorf("failed test %d - expected %d warnings, but got %v", i, tst.warnings, warnings)
+		}
+	}
+}
+
+func TestGetUlimits(t *testing.T) {
+	in := []ulimit.Limit{
+		{Name: "nofile", Soft: 65536, Hard: 65536},
+		{Name: "RLIMIT_NPROC", Soft: 1024, Hard: 2048},
+		{Name: "core", Soft: -1, Hard: -1},
+		{Name: "memlock", Soft: 2048, Hard: 1024},
+		{Name: "rtprio", Soft: 1, Hard: 1},
+	}
+	out := []ulimit.Limit{
+		{Name: "nofile", Soft: 65536, Hard: 65536},
+		{Name: "nproc", Soft: 1024, Hard: 2048},
+		{Name: "core", Soft: -1, Hard: -1},
+	}
+	res, warnings := GetUlimits(in)
+	if !reflect.DeepEqual(res, out) {
+		t.Errorf("failed - expected %v, but got %v", out, res)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("failed - expected 2 warnings, but got %v", warnings)
+	}
+}
+
+func TestGetDevices(t *testing.T) {
+	cr := &ContextRouter{Config: Config{AllowedDevices: ParseAllowedDevices("/dev/kvm, /dev/fuse=smarter-devices/fuse")}}
+	in := []types.Device{
+		{HostPath: "/dev/kvm"},
+		{HostPath: "/dev/fuse", ContainerPath: "/dev/myfuse"},
+		{HostPath: "/dev/mem"},
+	}
+	out := []types.Device{
+		{HostPath: "/dev/kvm", ContainerPath: "/dev/kvm"},
+		{HostPath: "/dev/fuse", ContainerPath: "/dev/myfuse", Resource: "smarter-devices/fuse"},
+	}
+	res, warnings := GetDevices(cr, in)
+	if !reflect.DeepEqual(res, out) {
+		t.Errorf("failed - expected %v, but got %v", out, res)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("failed - expected 1 warning, but got %v", warnings)
+	}
+}
+
+func TestResolveLinks(t *testing.T) {
+	db, err := model.New()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	kub := adapter.Internal(fake.New())
+	cr := &ContextRouter{DB: db, Backend: kub}
+	netws := map[string]interface{}{"net463": nil}
+	linked := &types.Container{Name: "db463", Networks: netws, Running: true}
+	other := &types.Container{Name: "cache463", NetworkAliases: []string{"kv463"}, Networks: netws, Running: true}
+	for _, tainr := range []*types.Container{linked, other} {
+		if err := db.SaveContainer(tainr); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer db.DeleteContainer(tainr)
+		kub.StartContainer(context.Background(), tainr)
+	}
+	ip, _ := kub.GetPodIP(linked)
+
+	tests := []struct {
+		links map[string]string
+		netws map[string]interface{}
+		out   map[string][]string
+	}{
+		{
+			links: map[string]string{"database463": linked.ID},
+			netws: netws,
+			out:   map[string][]string{ip: {"database463", "db463"}},
+		},
+		{
+			links: map[string]string{"database463": linked.ID, "kv463": linked.ID},
+			netws: netws,
+			out:   map[string][]string{ip: {"database463", "db463"}},
+		},
+		{
+			links: map[string]string{"cache463": linked.ID},
+			netws: netws,
+			out:   map[string][]string{ip: {"db463"}},
+		},
+		{
+			links: map[string]string{"cache463": linked.ID, "kv463": linked.ID},
+			netws: map[string]interface{}{"other463": nil},
+			out:   map[string][]string{ip: {"cache463", "db463", "kv463"}},
+		},
+	}
+	for i, tst := range tests {
+		tainr := &types.Container{Name: "app463", Links: tst.links, Networks: tst.netws}
+		if err := resolveLinks(cr, tainr); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(tainr.LinkHosts, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, tainr.LinkHosts)
+		}
+	}
+}
+
+func TestCopyStartState(t *testing.T) {
+	started := &types.Container{PodName: "tb303", MappedPorts: map[int]int{8080: 80}}
+	started.SetStartTiming(types.PhaseCreate, time.Second)
+	rec := &types.Container{}
+	copyStartState(rec, started)
+	if rec.PodName != "tb303" || rec.MappedPorts[8080] != 80 || rec.StartTimings[types.PhaseCreate] != time.Second {
+		t.Errorf("failed test - expected start state to be copied, but got %v", rec)
+	}
+	started.SetStartTiming(types.PhaseReady, time.Second)
+	started.MappedPorts[8443] = 443
+	if _, ok := rec.StartTimings[types.PhaseReady]; ok || len(rec.MappedPorts) != 1 {
+		t.Errorf("failed test - expected record not to share state with started container, but got %v", rec)
+	}
+}
+
+func TestStartContainerNotReachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	kub := adapter.Internal(fake.New())
+	cr, err := NewContextRouter(kub, Config{PortForward: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	db := cr.DB
+	tainr := &types.Container{
+		Name:      "tcp373",
+		HostPorts: map[int]int{port: 80},
+		Labels:    map[string]string{types.LabelReadiness: types.ReadinessTCP, types.LabelStartTimeout: "1s"},
+	}
+	if err := db.SaveContainer(tainr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer db.DeleteContainer(tainr)
+
+	if err := StartContainer(context.Background(), cr, tainr); err == nil {
+		t.Fatalf("expected error starting container with unreachable ports")
+	}
+	res, err := db.GetContainer(tainr.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Running || !strings.Contains(res.Error, "timeout waiting for") {
+		t.Errorf("expected container with start error, but got running %t and error %q", res.Running, res.Error)
+	}
+	if state, _ := kub.GetContainerStatus(tainr); state != backend.DeployPending {
+		t.Errorf("expected pod of container to be removed, but got state %v", state)
+	}
+}
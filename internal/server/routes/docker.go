@@ -17,47 +17,57 @@ func RegisterDockerRoutes(router *gin.Engine, cr *common.ContextRouter) {
 	}
 
 	router.GET("/info", wrap(docker.Info))
-	router.GET("/events", wrap(docker.Events))
+	router.GET("/events", httputil.NoWriteTimeout, wrap(docker.Events))
 	router.GET("/version", wrap(docker.Version))
 	router.GET("/_ping", wrap(docker.Ping))
 	router.HEAD("/_ping", wrap(docker.Ping))
 
 	router.POST("/containers/create", wrap(docker.ContainerCreate))
-	router.POST("/containers/:id/start", wrap(common.ContainerStart))
-	router.POST("/containers/:id/attach", wrap(common.ContainerAttach))
-	router.POST("/containers/:id/stop", wrap(common.ContainerStop))
-	router.POST("/containers/:id/restart", wrap(common.ContainerRestart))
-	router.POST("/containers/:id/kill", wrap(common.ContainerKill))
-	router.POST("/containers/:id/wait", wrap(docker.ContainerWait))
+	router.POST("/containers/:id/start", httputil.NoWriteTimeout, wrap(common.ContainerStart))
+	router.POST("/containers/:id/attach", httputil.NoWriteTimeout, wrap(common.ContainerAttach))
+	router.POST("/containers/:id/stop", httputil.NoWriteTimeout, wrap(common.ContainerStop))
+	router.POST("/containers/:id/restart", httputil.NoWriteTimeout, wrap(common.ContainerRestart))
+	router.POST("/containers/:id/kill", httputil.NoWriteTimeout, wrap(common.ContainerKill))
+	router.POST("/containers/:id/wait", httputil.NoWriteTimeout, wrap(docker.ContainerWait))
 	router.POST("/containers/:id/rename", wrap(common.ContainerRename))
 	router.POST("/containers/:id/resize", wrap(common.ContainerResize))
 	router.DELETE("/containers/:id", wrap(docker.ContainerDelete))
-	router.GET("/containers/json", wrap(docker.ContainerList))
+	router.GET("/containers/json", cr.Cache.Handler(), wrap(docker.ContainerList))
 	router.GET("/containers/:id/json", wrap(docker.ContainerInfo))
-	router.GET("/containers/:id/logs", wrap(common.ContainerLogs))
+	router.GET("/containers/:id/logs", httputil.NoWriteTimeout, wrap(common.ContainerLogs))
 
 	router.HEAD("/containers/:id/archive", wrap(common.HeadArchive))
 	router.GET("/containers/:id/archive", wrap(common.GetArchive))
 	router.PUT("/containers/:id/archive", wrap(common.PutArchive))
 
 	router.POST("/containers/:id/exec", wrap(common.ContainerExec))
-	router.POST("/exec/:id/start", wrap(common.ExecStart))
+	router.POST("/exec/:id/start", httputil.NoWriteTimeout, wrap(common.ExecStart))
 	router.POST("/exec/:id/resize", wrap(common.ExecResize))
 	router.GET("/exec/:id/json", wrap(common.ExecInfo))
 
 	router.POST("/networks/create", wrap(docker.NetworksCreate))
 	router.POST("/networks/:id/connect", wrap(docker.NetworksConnect))
 	router.POST("/networks/:id/disconnect", wrap(docker.NetworksDisconnect))
-	router.GET("/networks", wrap(docker.NetworksList))
+	router.GET("/networks", cr.Cache.Handler(), wrap(docker.NetworksList))
 	router.GET("/networks/:id", wrap(docker.NetworksInfo))
 	router.DELETE("/networks/:id", wrap(docker.NetworksDelete))
 	router.POST("/networks/prune", wrap(docker.NetworksPrune))
 
-	router.POST("/images/create", wrap(docker.ImageCreate))
-	router.GET("/images/json", wrap(common.ImageList))
+	router.POST("/services/create", wrap(docker.ServiceCreate))
+	router.POST("/services/:id/update", wrap(docker.ServiceUpdate))
+	router.GET("/services", wrap(docker.ServiceList))
+	router.GET("/services/:id", wrap(docker.ServiceInfo))
+	router.DELETE("/services/:id", wrap(docker.ServiceDelete))
+
+	router.POST("/images/create", httputil.NoWriteTimeout, wrap(docker.ImageCreate))
+	router.GET("/images/json", cr.Cache.Handler(), wrap(common.ImageList))
 	router.GET("/images/:image/*json", wrap(common.ImageJSON))
 	router.POST("/images/prune", wrap(docker.ImagesPrune))
+	router.DELETE("/images/*name", wrap(docker.ImageDelete))
+
+	router.GET("/distribution/*name", wrap(docker.DistributionInspect))
 
+	router.GET("/volumes", wrap(docker.VolumesList))
 	router.POST("/volumes/prune", wrap(docker.VolumesPrune))
 
 	// not supported docker api at the moment
@@ -71,7 +81,11 @@ func RegisterDockerRoutes(router *gin.Engine, cr *common.ContextRouter) {
 	router.GET("/containers/:id/attach/ws", httputil.NotImplemented)
 	router.POST("/containers/prune", httputil.NotImplemented)
 	router.POST("/build", httputil.NotImplemented)
-	router.GET("/volumes", httputil.NotImplemented)
+	if cr.Config.BuildkitAddr != "" {
+		router.POST("/grpc", wrap(docker.BuildkitControl))
+		router.POST("/session", wrap(docker.BuildkitSession))
+		router.POST("/build/prune", wrap(docker.BuildPrune))
+	}
 	router.GET("/volumes/:id", httputil.NotImplemented)
 	router.DELETE("/volumes/:id", httputil.NotImplemented)
 	router.POST("/volumes/create", httputil.NotImplemented)
@@ -0,0 +1,145 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/buildkit"
+)
+
+// BuildkitControl - proxy the buildkit control api to the configured
+// buildkit daemon.
+// POST "/grpc"
+func BuildkitControl(cr *common.ContextRouter, c *gin.Context) {
+	bk, err := buildkit.New(cr.Config.BuildkitAddr)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	r := c.Request
+	w := c.Writer
+	w.WriteHeader(http.StatusOK)
+
+	in, out, err := httputil.HijackConnection(w)
+	if err != nil {
+		klog.Errorf("error during hijack connection: %s", err)
+		return
+	}
+	defer httputil.CloseStreams(in, out)
+	httputil.UpgradeConnection(r, out, true)
+
+	conn, ok := in.(net.Conn)
+	if !ok {
+		klog.Errorf("unsupported buildkit control connection %T", in)
+		return
+	}
+	if err := bk.Control(conn); err != nil {
+		klog.V(3).Infof("buildkit control connection closed: %s", err)
+	}
+}
+
+// BuildPrune - prune the build cache of the configured buildkit daemon.
+// https://docs.docker.com/engine/api/v1.44/#tag/Image/operation/BuildPrune
+// POST "/build/prune"
+func BuildPrune(cr *common.ContextRouter, c *gin.Context) {
+	bk, err := buildkit.New(cr.Config.BuildkitAddr)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	opts, err := getPruneOptions(c)
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := bk.Prune(c.Request.Context(), opts)
+	if err != nil {
+		httputil.Error(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"CachesDeleted":  res.CachesDeleted,
+		"SpaceReclaimed": res.SpaceReclaimed,
+	})
+}
+
+// getPruneOptions will return the buildkit prune options of the given build
+// prune request. The until filter is the duration records that have been
+// used recently are kept; the other filters are passed on to buildkit.
+func getPruneOptions(c *gin.Context) (buildkit.PruneOptions, error) {
+	opts := buildkit.PruneOptions{}
+	opts.All, _ = strconv.ParseBool(c.Query("all"))
+	for key, val := range map[string]*int64{
+		"keep-storage":   &opts.ReservedSpace,
+		"reserved-space": &opts.ReservedSpace,
+		"max-used-space": &opts.MaxUsedSpace,
+		"min-free-space": &opts.MinFreeSpace,
+	} {
+		if q := c.Query(key); q != "" {
+			n, err := strconv.ParseInt(q, 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s %s: %w", key, q, err)
+			}
+			*val = n
+		}
+	}
+	if q := c.Query("filters"); q != "" {
+		filters := map[string]map[string]bool{}
+		if err := json.Unmarshal([]byte(q), &filters); err != nil {
+			return opts, fmt.Errorf("invalid filters %s: %w", q, err)
+		}
+		for key, vals := range filters {
+			for val := range vals {
+				if key != "until" {
+					opts.Filters = append(opts.Filters, key+"=="+val)
+					continue
+				}
+				d, err := time.ParseDuration(val)
+				if err != nil {
+					return opts, fmt.Errorf("invalid until filter %s: %w", val, err)
+				}
+				opts.KeepDuration = d
+			}
+		}
+	}
+	return opts, nil
+}
+
+// BuildkitSession - tunnel the session of a build client to the configured
+// buildkit daemon.
+// POST "/session"
+func BuildkitSession(cr *common.ContextRouter, c *gin.Context) {
+	bk, err := buildkit.New(cr.Config.BuildkitAddr)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	r := c.Request
+	w := c.Writer
+	w.WriteHeader(http.StatusOK)
+
+	in, out, err := httputil.HijackConnection(w)
+	if err != nil {
+		klog.Errorf("error during hijack connection: %s", err)
+		return
+	}
+	defer httputil.CloseStreams(in, out)
+	httputil.UpgradeConnection(r, out, true)
+
+	if err := bk.Session(r.Context(), r.Header, in, out); err != nil {
+		klog.V(3).Infof("buildkit session closed: %s", err)
+	}
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/util/buildkit"
+)
+
+func TestGetPruneOptions(t *testing.T) {
+	tests := []struct {
+		query string
+		opts  buildkit.PruneOptions
+		err   bool
+	}{
+		{query: "", opts: buildkit.PruneOptions{}},
+		{query: "all=1&keep-storage=1024", opts: buildkit.PruneOptions{All: true, ReservedSpace: 1024}},
+		{query: "max-used-space=2048&min-free-space=512", opts: buildkit.PruneOptions{MaxUsedSpace: 2048, MinFreeSpace: 512}},
+		{
+			query: "filters=" + url.QueryEscape(`{"until":{"24h":true},"type":{"regular":true},"id":{"msx":true}}`),
+			opts:  buildkit.PruneOptions{KeepDuration: 24 * time.Hour, Filters: []string{"id==msx", "type==regular"}},
+		},
+		{query: "keep-storage=lots", err: true},
+		{query: "filters=" + url.QueryEscape(`{"until":{"yesterday":true}}`), err: true},
+	}
+	for i, tst := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/build/prune?"+tst.query, nil)
+		opts, err := getPruneOptions(c)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		sort.Strings(opts.Filters)
+		if !reflect.DeepEqual(opts, tst.opts) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.opts, opts)
+		}
+	}
+}
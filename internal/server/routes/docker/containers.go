@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,47 +17,100 @@ import (
 	"github.com/joyrex2001/kubedock/internal/server/filter"
 	"github.com/joyrex2001/kubedock/internal/server/httputil"
 	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
 )
 
 // ContainerCreate - create a container.
 // https://docs.docker.com/engine/api/v1.41/#operation/ContainerCreate
 // POST "/containers/create"
 func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
+	digest, err := common.GetCreateDigest(c)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
 	in, err := getContainerCreateRequest(c, cr)
 	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	key := c.GetHeader(common.IdempotencyKeyHeader)
+	if tainr, err := common.FindCreatedContainer(cr, key, in.Name, digest); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	} else if tainr != nil {
+		klog.V(2).Infof("returning container %s for retried create request", tainr.ShortID)
+		c.JSON(http.StatusCreated, gin.H{
+			"Id":       tainr.ID,
+			"Warnings": []string{},
+		})
+		return
+	}
+
+	in.Name, err = common.ContainerName(cr, "", in.Name)
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sysctls, swarns := common.GetSysctls(cr, in.HostConfig.Sysctls)
+	warnings := append(getCreateWarnings(in), swarns...)
+	limits := []ulimit.Limit{}
+	for _, l := range in.HostConfig.Ulimits {
+		limits = append(limits, ulimit.Limit{Name: l.Name, Soft: l.Soft, Hard: l.Hard})
+	}
+	limits, uwarns := common.GetUlimits(limits)
+	warnings = append(warnings, uwarns...)
+	devices := []types.Device{}
+	for _, d := range in.HostConfig.Devices {
+		devices = append(devices, types.Device{HostPath: d.PathOnHost, ContainerPath: d.PathInContainer})
+	}
+	devices, dwarns := common.GetDevices(cr, devices)
+	warnings = append(warnings, dwarns...)
+	if err := common.ValidateCreate(cr, warnings); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
 	mounts := []types.Mount{}
 	for _, m := range in.HostConfig.Mounts {
 		if m.Type != "bind" {
-			klog.Infof("mount '%s:%s' with type '%s' not supported, ignoring", m.Source, m.Target, m.Type)
 			continue
 		}
-		mounts = append(mounts, types.Mount{
+		mount := types.Mount{
 			Type:     m.Type,
 			Source:   m.Source,
 			Target:   m.Target,
 			ReadOnly: m.ReadOnly,
-		})
+		}
+		if m.BindOptions != nil {
+			mount.Propagation = m.BindOptions.Propagation
+		}
+		mounts = append(mounts, mount)
 	}
 
 	tainr := &types.Container{
-		Name:         in.Name,
-		Hostname:     in.Hostname,
-		Image:        in.Image,
-		Entrypoint:   in.Entrypoint,
-		Cmd:          in.Cmd,
-		Env:          in.Env,
-		ExposedPorts: in.ExposedPorts,
-		ImagePorts:   map[string]interface{}{},
-		Labels:       in.Labels,
-		Binds:        in.HostConfig.Binds,
-		Mounts:       mounts,
-		PreArchives:  []types.PreArchive{},
-		Tty:          in.TTY,
-		OpenStdin:    in.OpenStdin,
+		Name:           in.Name,
+		Hostname:       in.Hostname,
+		Image:          in.Image,
+		Entrypoint:     in.Entrypoint,
+		Cmd:            in.Cmd,
+		Env:            in.Env,
+		ExposedPorts:   in.ExposedPorts,
+		ImagePorts:     map[string]interface{}{},
+		Labels:         in.Labels,
+		Binds:          in.HostConfig.Binds,
+		Mounts:         mounts,
+		PreArchives:    []types.PreArchive{},
+		Tty:            in.TTY,
+		OpenStdin:      in.OpenStdin,
+		Sysctls:        sysctls,
+		Ulimits:        limits,
+		Devices:        devices,
+		IdempotencyKey: key,
+		CreateDigest:   digest,
 	}
 
 	if img, err := cr.DB.GetImageByNameOrID(in.Image); err != nil {
@@ -74,8 +130,25 @@ func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
 		}
 	}
 
+	for _, link := range in.HostConfig.Links {
+		if err := common.AddLink(cr, tainr, link); err != nil {
+			httputil.Error(c, http.StatusNotFound, err)
+			return
+		}
+	}
+
 	net := in.HostConfig.NetworkMode
-	if net != "" && net != "default" {
+	if ref, ok := strings.CutPrefix(net, "container:"); ok {
+		if err := common.LinkContainer(cr, tainr, ref); err != nil {
+			httputil.Error(c, http.StatusNotFound, err)
+			return
+		}
+	} else if net == "host" {
+		if err := common.UseHostNetwork(cr, tainr); err != nil {
+			httputil.Error(c, http.StatusBadRequest, err)
+			return
+		}
+	} else if net != "" && net != "default" {
 		klog.V(5).Infof("NetworkMode != '', connecting container to network: %s", net)
 		netw, err := cr.DB.GetNetworkByNameOrID(net)
 		if err != nil {
@@ -85,8 +158,8 @@ func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
 		tainr.ConnectNetwork(netw.ID)
 	}
 
-	for _, endp := range in.NetworkConfig.EndpointsConfig {
-		addNetworkAliases(tainr, endp)
+	for name, endp := range in.NetworkConfig.EndpointsConfig {
+		id := ""
 		if endp.NetworkID != "" {
 			netw, err := cr.DB.GetNetworkByNameOrID(endp.NetworkID)
 			if err != nil {
@@ -94,10 +167,19 @@ func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
 				return
 			}
 			tainr.ConnectNetwork(netw.ID)
+			id = netw.ID
+		} else if netw, err := cr.DB.GetNetworkByNameOrID(name); err == nil {
+			if _, ok := tainr.Networks[netw.ID]; ok {
+				id = netw.ID
+			}
+		}
+		if err := applyEndpointConfig(cr, tainr, id, endp); err != nil {
+			httputil.Error(c, http.StatusNotFound, err)
+			return
 		}
 	}
 
-	if len(tainr.Networks) == 0 {
+	if len(tainr.Networks) == 0 && !tainr.IsLinked() {
 		netw, err := cr.DB.GetNetworkByName("bridge")
 		if err != nil {
 			httputil.Error(c, http.StatusInternalServerError, err)
@@ -106,19 +188,49 @@ func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
 		tainr.ConnectNetwork(netw.ID)
 	}
 
-	if err := cr.DB.SaveContainer(tainr); err != nil {
+	if err := common.ApplyStartTimeout(c, tainr); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if cur, err := common.SaveCreatedContainer(cr, tainr); err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
+	} else if cur != tainr {
+		klog.V(2).Infof("returning container %s for concurrently retried create request", cur.ShortID)
+		c.JSON(http.StatusCreated, gin.H{
+			"Id":       cur.ID,
+			"Warnings": []string{},
+		})
+		return
 	}
 
-	cr.Events.Publish(tainr.ID, events.Container, events.Create)
+	common.PublishContainerEvent(cr, tainr, events.Create)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"Id":       tainr.ID,
-		"Warnings": []string{},
+		"Warnings": warnings,
 	})
 }
 
+// getCreateWarnings will return warnings for all features in the given
+// create request that are not supported and would otherwise be ignored.
+func getCreateWarnings(in *ContainerCreateRequest) []string {
+	warnings := []string{}
+	for _, m := range in.HostConfig.Mounts {
+		if m.Type != "bind" {
+			warnings = append(warnings, fmt.Sprintf("mount '%s:%s' with type '%s' is not supported and is ignored", m.Source, m.Target, m.Type))
+		}
+	}
+	if in.HostConfig.CgroupParent != "" {
+		warnings = append(warnings, "cgroup parent is not supported and is ignored")
+	}
+	if in.HostConfig.UsernsMode != "" && in.HostConfig.UsernsMode != "host" {
+		warnings = append(warnings, fmt.Sprintf("user namespace mode '%s' is not supported and is ignored", in.HostConfig.UsernsMode))
+	}
+	return warnings
+}
+
 // getContainerCreateRequest converts the request body into a ContainerCreateRequest
 func getContainerCreateRequest(c *gin.Context, cr *common.ContextRouter) (*ContainerCreateRequest, error) {
 	in := &ContainerCreateRequest{}
@@ -134,38 +246,50 @@ func getContainerCreateRequest(c *gin.Context, cr *common.ContextRouter) (*Conta
 		in.Labels = map[string]string{}
 	}
 
-	if _, ok := in.Labels[types.LabelRunasUser]; !ok && cr.Config.RunasUser != "" {
-		in.Labels[types.LabelRunasUser] = cr.Config.RunasUser
+	cfg := cr.GetConfig()
+	in.Env = common.MergeEnv(in.Env, common.ApplyImageDefaults(cfg, in.Image, in.Labels))
+
+	if _, ok := in.Labels[types.LabelRunasUser]; !ok && cfg.RunasUser != "" {
+		in.Labels[types.LabelRunasUser] = cfg.RunasUser
 	}
 	if in.User != "" {
 		// The User defined in HTTP request takes precedence over the cli and label.
 		in.Labels[types.LabelRunasUser] = in.User
 	}
-	if _, ok := in.Labels[types.LabelNamePrefix]; !ok && cr.Config.NamePrefix != "" {
-		in.Labels[types.LabelNamePrefix] = cr.Config.NamePrefix
+	if _, ok := in.Labels[types.LabelFSGroup]; !ok && cfg.FSGroup != "" {
+		in.Labels[types.LabelFSGroup] = cfg.FSGroup
 	}
-	if _, ok := in.Labels[types.LabelRequestCPU]; !ok && cr.Config.RequestCPU != "" {
-		in.Labels[types.LabelRequestCPU] = cr.Config.RequestCPU
+	if _, ok := in.Labels[types.LabelNamePrefix]; !ok && cfg.NamePrefix != "" {
+		in.Labels[types.LabelNamePrefix] = cfg.NamePrefix
 	}
-	if _, ok := in.Labels[types.LabelRequestMemory]; !ok && cr.Config.RequestMemory != "" {
-		in.Labels[types.LabelRequestMemory] = cr.Config.RequestMemory
+	if _, ok := in.Labels[types.LabelRequestCPU]; !ok && cfg.RequestCPU != "" {
+		in.Labels[types.LabelRequestCPU] = cfg.RequestCPU
 	}
-	if _, ok := in.Labels[types.LabelPullPolicy]; !ok && cr.Config.PullPolicy != "" {
-		in.Labels[types.LabelPullPolicy] = cr.Config.PullPolicy
+	if _, ok := in.Labels[types.LabelRequestMemory]; !ok && cfg.RequestMemory != "" {
+		in.Labels[types.LabelRequestMemory] = cfg.RequestMemory
 	}
-	if _, ok := in.Labels[types.LabelNodeSelector]; !ok && cr.Config.NodeSelector != "" {
-		in.Labels[types.LabelNodeSelector] = cr.Config.NodeSelector
+	if _, ok := in.Labels[types.LabelPullPolicy]; !ok && cfg.PullPolicy != "" {
+		in.Labels[types.LabelPullPolicy] = cfg.PullPolicy
 	}
-	if _, ok := in.Labels[types.LabelActiveDeadlineSeconds]; !ok && cr.Config.ActiveDeadlineSeconds >= 0 {
-		in.Labels[types.LabelActiveDeadlineSeconds] = fmt.Sprintf("%d", cr.Config.ActiveDeadlineSeconds)
+	if _, ok := in.Labels[types.LabelNodeSelector]; !ok && cfg.NodeSelector != "" {
+		in.Labels[types.LabelNodeSelector] = cfg.NodeSelector
 	}
-	if in.HostConfig.Memory != 0 && !cr.Config.IgnoreContainerMemory {
+	if _, ok := in.Labels[types.LabelReadiness]; !ok && cfg.Readiness != "" {
+		in.Labels[types.LabelReadiness] = cfg.Readiness
+	}
+	if _, ok := in.Labels[types.LabelSeparateStderr]; !ok && cfg.SeparateStderr {
+		in.Labels[types.LabelSeparateStderr] = "true"
+	}
+	if _, ok := in.Labels[types.LabelActiveDeadlineSeconds]; !ok && cfg.ActiveDeadlineSeconds >= 0 {
+		in.Labels[types.LabelActiveDeadlineSeconds] = fmt.Sprintf("%d", cfg.ActiveDeadlineSeconds)
+	}
+	if in.HostConfig.Memory != 0 && !cfg.IgnoreContainerMemory {
 		in.Labels[types.LabelRequestMemory] = fmt.Sprintf("%d", in.HostConfig.Memory)
 	}
 	if in.HostConfig.NanoCpus != 0 {
 		in.Labels[types.LabelRequestCPU] = fmt.Sprintf("%dn", in.HostConfig.NanoCpus)
 	}
-	in.Labels[types.LabelServiceAccount] = cr.Config.ServiceAccount
+	in.Labels[types.LabelServiceAccount] = cfg.ServiceAccount
 	return in, nil
 }
 
@@ -182,12 +306,16 @@ func ContainerWait(cr *common.ContextRouter, c *gin.Context) {
 		case <-ticker.C:
 			tainr, err := cr.DB.GetContainer(id)
 			if err == nil {
-				common.UpdateContainerStatus(cr, tainr)
+				tainr = common.UpdateContainerStatus(cr, tainr)
 			}
-			if err != nil || tainr.Stopped || tainr.Killed || tainr.Completed {
+			if err != nil {
 				c.JSON(http.StatusOK, gin.H{"StatusCode": 0})
 				return
 			}
+			if tainr.Stopped || tainr.Killed || tainr.Completed || tainr.HasExited() {
+				c.JSON(http.StatusOK, gin.H{"StatusCode": tainr.ExitCode()})
+				return
+			}
 		}
 	}
 }
@@ -207,10 +335,15 @@ func ContainerDelete(cr *common.ContextRouter, c *gin.Context) {
 	tainr.SignalStop()
 
 	if !tainr.Stopped && !tainr.Killed {
-		if err := cr.Backend.DeleteContainer(tainr); err != nil {
+		if err := common.DeleteContainer(cr, tainr); err != nil {
 			klog.Warningf("error while deleting k8s container: %s", err)
 		}
-		cr.Events.Publish(tainr.ID, events.Container, events.Die)
+		common.StopLinkedContainers(cr, tainr)
+		common.PublishContainerEvent(cr, tainr, events.Die)
+	}
+
+	if err := cr.Backend.DeleteContainerVolumes(tainr); err != nil {
+		klog.Warningf("error while deleting volumes of container: %s", err)
 	}
 
 	if err := cr.DB.DeleteContainer(tainr); err != nil {
@@ -218,6 +351,8 @@ func ContainerDelete(cr *common.ContextRouter, c *gin.Context) {
 		return
 	}
 
+	common.PublishContainerEvent(cr, tainr, events.Destroy)
+
 	c.Writer.WriteHeader(http.StatusNoContent)
 }
 
@@ -243,7 +378,7 @@ func ContainerList(cr *common.ContextRouter, c *gin.Context) {
 		klog.V(5).Infof("unsupported filter: %s", err)
 	}
 
-	tainrs, err := cr.DB.GetContainers()
+	tainrs, err := common.FilterContainers(cr, filtr)
 	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
@@ -251,30 +386,68 @@ func ContainerList(cr *common.ContextRouter, c *gin.Context) {
 
 	res := []gin.H{}
 	for _, tainr := range tainrs {
-		if filtr.Match(tainr) {
-			res = append(res, getContainerInfo(cr, tainr, false))
-		}
+		res = append(res, getContainerInfo(cr, tainr, false))
 	}
 	c.JSON(http.StatusOK, res)
 }
 
+// ContainerInspectBatch - inspect multiple containers in a single request.
+// The containers are given as a comma separated list of ids or names in
+// ids (all containers if omitted), and full inspect documents are returned
+// if full is set, list entries otherwise. Containers that could not be
+// found are reported in Missing.
+// GET "/kubedock/containers/json"
+func ContainerInspectBatch(cr *common.ContextRouter, c *gin.Context) {
+	full, _ := strconv.ParseBool(c.Query("full"))
+	ids := []string{}
+	for _, v := range c.QueryArray("ids") {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	tainrs := []*types.Container{}
+	missing := []string{}
+	if len(ids) == 0 {
+		all, err := cr.DB.GetContainers()
+		if err != nil {
+			httputil.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		tainrs = all
+	}
+	for _, id := range ids {
+		tainr, err := cr.DB.GetContainerByNameOrID(id)
+		if err != nil {
+			klog.V(3).Infof("container %s not inspected: %s", id, err)
+			missing = append(missing, id)
+			continue
+		}
+		tainrs = append(tainrs, tainr)
+	}
+
+	res := []gin.H{}
+	for _, tainr := range tainrs {
+		res = append(res, getContainerInfo(cr, tainr, full))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"Containers": res,
+		"Missing":    missing,
+	})
+}
+
 // getContainerInfo will return a gin.H containing the details of the
 // given container.
 func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail bool) gin.H {
-	errstr := ""
-	netws, err := cr.DB.GetNetworksByIDs(tainr.Networks)
+	errstr := tainr.Error
+	netdtl, err := common.GetNetworkEndpoints(cr, tainr)
 	if err != nil {
 		errstr += err.Error()
 	}
-	netdtl := gin.H{}
-	for _, netw := range netws {
-		netdtl[netw.Name] = gin.H{
-			"NetworkID": netw.ID,
-			"Aliases":   tainr.NetworkAliases,
-			"IPAddress": "127.0.0.1",
-		}
-	}
 	mounts := []gin.H{}
+	mountpoints := []gin.H{}
 	for _, m := range tainr.Mounts {
 		mounts = append(mounts, gin.H{
 			"Source":   m.Source,
@@ -282,6 +455,14 @@ func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail b
 			"Type":     m.Type,
 			"ReadOnly": m.ReadOnly,
 		})
+		mountpoints = append(mountpoints, gin.H{
+			"Type":        m.Type,
+			"Source":      m.Source,
+			"Destination": m.Target,
+			"Mode":        "",
+			"RW":          !m.ReadOnly,
+			"Propagation": m.Propagation,
+		})
 	}
 	names := getContainerNames(tainr)
 	res := gin.H{
@@ -295,16 +476,23 @@ func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail b
 			"Ports":     getNetworkSettingsPorts(cr, tainr),
 		},
 		"HostConfig": gin.H{
-			"NetworkMode": "bridge",
+			"NetworkMode": getNetworkMode(tainr),
 			"LogConfig": gin.H{
 				"Type":   "json-file",
 				"Config": gin.H{},
 			},
+			"RestartPolicy": gin.H{
+				"Name":              "no",
+				"MaximumRetryCount": 0,
+			},
+			"Binds":  tainr.Binds,
 			"Mounts": mounts,
+			"Links":  getLinks(cr, tainr),
 		},
+		"Mounts": mountpoints,
 	}
 	if detail {
-		common.UpdateContainerStatus(cr, tainr)
+		tainr = common.UpdateContainerStatus(cr, tainr)
 		res["State"] = gin.H{
 			"Health": gin.H{
 				"Status": tainr.StatusString(),
@@ -314,32 +502,79 @@ func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail b
 			"Paused":     false,
 			"Restarting": false,
 			"OOMKilled":  false,
-			"Dead":       tainr.Failed,
-			"StartedAt":  tainr.Created.Format("2006-01-02T15:04:05Z"),
+			"Dead":       tainr.Failed && !tainr.HasExited(),
+			"StartedAt":  tainr.Started.Format("2006-01-02T15:04:05Z"),
 			"FinishedAt": tainr.Finished.Format("2006-01-02T15:04:05Z"),
-			"ExitCode":   0,
+			"ExitCode":   tainr.ExitCode(),
+			"Pid":        0,
 			"Error":      errstr,
 		}
+		res["Kubedock"] = common.GetKubedockInfo(tainr)
 		res["Config"] = gin.H{
 			"Image":        tainr.Image,
 			"Labels":       tainr.Labels,
 			"Env":          tainr.Env,
+			"Entrypoint":   tainr.Entrypoint,
 			"Cmd":          tainr.Cmd,
 			"Hostname":     "localhost",
+			"WorkingDir":   "",
+			"User":         "",
 			"ExposedPorts": getConfigExposedPorts(cr, tainr),
-			"Tty":          false,
+			"Tty":          tainr.Tty,
+			"OpenStdin":    tainr.OpenStdin,
 		}
+		res["Path"], res["Args"] = getContainerCommand(tainr)
 		res["Created"] = tainr.Created.Format("2006-01-02T15:04:05Z")
+		res["RestartCount"] = 0
+		res["Platform"] = "linux"
 	} else {
 		res["Labels"] = tainr.Labels
-		res["State"] = tainr.StatusString()
-		res["Status"] = tainr.StateString()
+		res["State"] = tainr.StateString()
+		res["Status"] = tainr.StatusDescription()
 		res["Created"] = tainr.Created.Unix()
 		res["Ports"] = getContainerPorts(cr, tainr)
+		res["Mounts"] = mountpoints
+		path, args := getContainerCommand(tainr)
+		res["Command"] = strings.TrimSpace(path + " " + strings.Join(args, " "))
 	}
 	return res
 }
 
+// getLinks will return the legacy links of the given container in the
+// /name:/container/alias format.
+func getLinks(cr *common.ContextRouter, tainr *types.Container) []string {
+	links := []string{}
+	for _, alias := range tainr.GetLinkAliases() {
+		linked, err := cr.DB.GetContainer(tainr.Links[alias])
+		if err != nil {
+			continue
+		}
+		links = append(links, fmt.Sprintf("/%s:/%s/%s", linked.Name, tainr.Name, alias))
+	}
+	return links
+}
+
+// getNetworkMode will return the network mode of the given container.
+func getNetworkMode(tainr *types.Container) string {
+	if tainr.IsLinked() {
+		return "container:" + tainr.NetworkOwner
+	}
+	if tainr.HostNetwork {
+		return "host"
+	}
+	return "bridge"
+}
+
+// getContainerCommand will return the path and the arguments of the command
+// that is run in the container, as far as known by kubedock.
+func getContainerCommand(tainr *types.Container) (string, []string) {
+	cmd := append(append([]string{}, tainr.Entrypoint...), tainr.Cmd...)
+	if len(cmd) == 0 {
+		return "", []string{}
+	}
+	return cmd[0], cmd[1:]
+}
+
 // getNetworkSettingsPorts will return the available ports of the container
 // as a gin.H json structure to be used in container details.
 func getNetworkSettingsPorts(cr *common.ContextRouter, tainr *types.Container) gin.H {
@@ -351,6 +586,7 @@ func getNetworkSettingsPorts(cr *common.ContextRouter, tainr *types.Container) g
 	for dst, prts := range ports {
 		pp := []map[string]string{}
 		done := map[int]int{}
+		sort.Ints(prts)
 		for _, src := range prts {
 			if _, ok := done[src]; ok {
 				continue
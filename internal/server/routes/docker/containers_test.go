@@ -303,3 +303,32 @@ func TestGetContainerCreateRequestLabelRequestMemory(t *testing.T) {
 		}
 	}
 }
+
+func TestGetCreateWarnings(t *testing.T) {
+	tests := []struct {
+		in  *ContainerCreateRequest
+		out []string
+	}{
+		{
+			in:  &ContainerCreateRequest{HostConfig: HostConfig{UsernsMode: "host", Mounts: []Mount{{Type: "bind"}}}},
+			out: []string{},
+		},
+		{
+			in: &ContainerCreateRequest{HostConfig: HostConfig{
+				Mounts:       []Mount{{Type: "tmpfs", Target: "/tmp"}},
+				CgroupParent: "/kubedock",
+				UsernsMode:   "private",
+			}},
+			out: []string{
+				"mount ':/tmp' with type 'tmpfs' is not supported and is ignored",
+				"cgroup parent is not supported and is ignored",
+				"user namespace mode 'private' is not supported and is ignored",
+			},
+		},
+	}
+	for i, tst := range tests {
+		if res := getCreateWarnings(tst.in); !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package docker
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -21,20 +23,16 @@ func ImageCreate(cr *common.ContextRouter, c *gin.Context) {
 		from = from + ":" + tag
 	}
 	img := &types.Image{Name: from}
-	if cr.Config.Inspector {
-		pts, err := cr.Backend.GetImageExposedPorts(from)
-		if err != nil {
-			httputil.Error(c, http.StatusInternalServerError, err)
-			return
-		}
-		img.ExposedPorts = pts
+	if err := common.InspectImage(cr, img); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
 	}
 	if err := cr.DB.SaveImage(img); err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	cr.Events.Publish(from, events.Image, events.Pull)
+	cr.Events.PublishWithAttributes(from, events.Image, events.Pull, map[string]string{"name": from})
 
 	c.JSON(http.StatusOK, gin.H{
 		"status": "Download complete",
@@ -45,8 +43,42 @@ func ImageCreate(cr *common.ContextRouter, c *gin.Context) {
 // https://docs.docker.com/engine/api/v1.41/#operation/ImagePrune
 // POST "/images/prune"
 func ImagesPrune(cr *common.ContextRouter, c *gin.Context) {
-	c.JSON(http.StatusCreated, gin.H{
+	c.JSON(http.StatusOK, gin.H{
 		"ImagesDeleted":  []string{},
 		"SpaceReclaimed": 0,
 	})
 }
+
+// DistributionInspect - return image digest and platform information by
+// contacting the registry.
+// https://docs.docker.com/engine/api/v1.41/#operation/DistributionInspect
+// GET "/distribution/:name/json"
+func DistributionInspect(cr *common.ContextRouter, c *gin.Context) {
+	name := strings.TrimPrefix(strings.TrimSuffix(c.Param("name"), "/json"), "/")
+	dist, err := cr.Backend.GetImageDistribution(name)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"Descriptor": dist.Descriptor,
+		"Platforms":  dist.Platforms,
+	})
+}
+
+// ImageDelete - remove an image.
+// https://docs.docker.com/engine/api/v1.41/#operation/ImageDelete
+// DELETE "/images/:name"
+func ImageDelete(cr *common.ContextRouter, c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	force, _ := strconv.ParseBool(c.Query("force"))
+	img, status, err := common.RemoveImage(cr, name, force)
+	if err != nil {
+		httputil.Error(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, []gin.H{
+		{"Untagged": img.Name},
+		{"Deleted": img.ID},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/klog"
@@ -18,15 +19,20 @@ import (
 // https://docs.docker.com/engine/api/v1.41/#operation/NetworkList
 // GET "/networks"
 func NetworksList(cr *common.ContextRouter, c *gin.Context) {
-	netws, err := cr.DB.GetNetworks()
-	if err != nil {
-		httputil.Error(c, http.StatusInternalServerError, err)
-		return
-	}
 	filtr, err := filter.New(c.Query("filters"))
 	if err != nil {
 		klog.V(5).Infof("unsupported filter: %s", err)
 	}
+	var netws []*types.Network
+	if key, val, ok := filtr.Label(); ok {
+		netws, err = cr.DB.GetNetworksByLabel(key, val)
+	} else {
+		netws, err = cr.DB.GetNetworks()
+	}
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
 	res := []gin.H{}
 	for _, netw := range netws {
 		if filtr.Match(netw) {
@@ -37,6 +43,7 @@ func NetworksList(cr *common.ContextRouter, c *gin.Context) {
 				"Driver":     "bridge",
 				"Scope":      "local",
 				"Attachable": true,
+				"IPAM":       getNetworkIPAM(netw),
 				"Containers": tainrs,
 				"Labels":     netw.Labels,
 			})
@@ -62,6 +69,7 @@ func NetworksInfo(cr *common.ContextRouter, c *gin.Context) {
 		"Driver":     "bridge",
 		"Scope":      "local",
 		"Attachable": true,
+		"IPAM":       getNetworkIPAM(netw),
 		"Containers": tainrs,
 		"Labels":     netw.Labels,
 	})
@@ -76,16 +84,22 @@ func NetworksCreate(cr *common.ContextRouter, c *gin.Context) {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
-	netw := &types.Network{
-		Name:   in.Name,
-		Labels: in.Labels,
+	subnet, gateway := "", ""
+	if len(in.IPAM.Config) > 0 {
+		subnet, gateway = in.IPAM.Config[0].Subnet, in.IPAM.Config[0].Gateway
 	}
-	if err := cr.DB.SaveNetwork(netw); err != nil {
+	netw, exists, err := common.CreateNetwork(cr, in.Name, in.Labels, subnet, gateway, in.CheckDuplicate)
+	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
+	warning := ""
+	if exists {
+		warning = fmt.Sprintf("Network with name %s (id : %s) already exists", netw.Name, netw.ID)
+	}
 	c.JSON(http.StatusCreated, gin.H{
-		"Id": netw.ID,
+		"Id":      netw.ID,
+		"Warning": warning,
 	})
 }
 
@@ -138,20 +152,28 @@ func NetworksConnect(cr *common.ContextRouter, c *gin.Context) {
 		return
 	}
 
-	tainr.ConnectNetwork(netw.ID)
-	n := len(tainr.NetworkAliases)
-	addNetworkAliases(tainr, in.EndpointConfig)
-
-	if tainr.Running && n != len(tainr.NetworkAliases) {
-		klog.Warningf("adding networkaliases to a running container, will not create new services...")
-	}
-	if err := cr.DB.SaveContainer(tainr); err != nil {
+	n, links := 0, 0
+	tainr, err = cr.DB.UpdateContainer(tainr.ID, func(tainr *types.Container) error {
+		n, links = len(tainr.NetworkAliases), len(tainr.Links)
+		if err := applyEndpointConfig(cr, tainr, netw.ID, in.EndpointConfig); err != nil {
+			return err
+		}
+		tainr.ConnectNetwork(netw.ID)
+		return nil
+	})
+	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{
-		"ID": netw.ID,
-	})
+	if tainr.Running && n != len(tainr.NetworkAliases) {
+		if err := cr.Backend.UpdateServices(tainr); err != nil {
+			klog.Warningf("error updating services of container %s: %s", tainr.ShortID, err)
+		}
+	}
+	if tainr.Running && links != len(tainr.Links) {
+		klog.Warningf("adding links to a running container, will be applied when restarted...")
+	}
+	c.Writer.WriteHeader(http.StatusOK)
 }
 
 // NetworksDisconnect - connect a container to a network.
@@ -178,15 +200,21 @@ func NetworksDisconnect(cr *common.ContextRouter, c *gin.Context) {
 		httputil.Error(c, http.StatusInternalServerError, fmt.Errorf("can not disconnect from predefined network"))
 		return
 	}
-	if err := tainr.DisconnectNetwork(netw.ID); err != nil {
+	n := 0
+	tainr, err = cr.DB.UpdateContainer(tainr.ID, func(tainr *types.Container) error {
+		n = len(tainr.NetworkAliases)
+		return tainr.DisconnectNetwork(netw.ID)
+	})
+	if err != nil {
 		httputil.Error(c, http.StatusNotFound, err)
 		return
 	}
-	if err := cr.DB.SaveContainer(tainr); err != nil {
-		httputil.Error(c, http.StatusInternalServerError, err)
-		return
+	if tainr.Running && n != len(tainr.NetworkAliases) {
+		if err := cr.Backend.UpdateServices(tainr); err != nil {
+			klog.Warningf("error updating services of container %s: %s", tainr.ShortID, err)
+		}
 	}
-	c.Writer.WriteHeader(http.StatusNoContent)
+	c.Writer.WriteHeader(http.StatusOK)
 }
 
 // NetworksPrune - delete unused networks.
@@ -211,7 +239,7 @@ func NetworksPrune(cr *common.ContextRouter, c *gin.Context) {
 		names = append(names, netw.Name)
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	c.JSON(http.StatusOK, gin.H{
 		"NetworksDeleted": names,
 	})
 }
@@ -224,8 +252,15 @@ func getContainersInNetwork(cr *common.ContextRouter, netw *types.Network) map[s
 	if err == nil {
 		for _, tainr := range tainrs {
 			if _, ok := tainr.Networks[netw.ID]; ok {
+				addr := ""
+				if ip := tainr.IPAddresses[netw.ID]; ip != "" {
+					_, prefix, _ := strings.Cut(netw.Subnet, "/")
+					addr = ip + "/" + prefix
+				}
 				res[tainr.ID] = gin.H{
-					"Name": tainr.Name,
+					"Name":        tainr.Name,
+					"IPv4Address": addr,
+					"IPv6Address": "",
 				}
 			}
 		}
@@ -234,3 +269,18 @@ func getContainersInNetwork(cr *common.ContextRouter, netw *types.Network) map[s
 	}
 	return res
 }
+
+// getNetworkIPAM will return the ip address management configuration of the
+// given network as a gin.H json structure.
+func getNetworkIPAM(netw *types.Network) gin.H {
+	config := []gin.H{}
+	if netw.Subnet != "" {
+		gw, _ := common.GetNetworkGateway(netw)
+		config = append(config, gin.H{"Subnet": netw.Subnet, "Gateway": gw})
+	}
+	return gin.H{
+		"Driver":  "default",
+		"Options": nil,
+		"Config":  config,
+	}
+}
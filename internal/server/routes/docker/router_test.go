@@ -0,0 +1,207 @@
+package docker_test
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/server/servertest"
+)
+
+func TestContainerLifecycle(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{})
+	kub.Logs = []string{"hello world"}
+	id := servertest.CreateContainer(t, router)
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		code   int
+		match  string
+	}{
+		{method: http.MethodGet, url: "/containers/" + id + "/logs?stdout=1", code: http.StatusNotFound},
+		{method: http.MethodPost, url: "/containers/" + id + "/start", code: http.StatusNoContent},
+		{method: http.MethodGet, url: "/containers/" + id + "/json", code: http.StatusOK, match: `"Running":true`},
+		{method: http.MethodGet, url: "/containers/" + id + "/json", code: http.StatusOK, match: `"HostIp":"10.0.0.2"`},
+		{method: http.MethodGet, url: "/containers/" + id + "/logs?stdout=1", code: http.StatusOK, match: "hello world"},
+		{method: http.MethodGet, url: "/libpod/containers/" + id + "/json", code: http.StatusOK, match: `"Running":true`},
+		{method: http.MethodDelete, url: "/containers/" + id + "?force=1", code: http.StatusNoContent},
+		{method: http.MethodGet, url: "/containers/" + id + "/json", code: http.StatusNotFound},
+	}
+
+	for i, tst := range tests {
+		w := servertest.Do(router, tst.method, tst.url, strings.NewReader(tst.body))
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
+
+func TestContainerInClusterProxy(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{ReverseProxy: true, ProxyHostIP: "10.1.0.7"})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","ExposedPorts":{"80/tcp":{}},"HostConfig":{"PortBindings":{"80/tcp":[{"HostPort":"8080"}]}}}`)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+	inspect := servertest.Do(router, http.MethodGet, "/containers/"+id+"/json", nil).Body.String()
+	for i, match := range []string{`"HostIp":"10.1.0.7"`, `"HostPort":"8080"`} {
+		if !strings.Contains(inspect, match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, match, inspect)
+		}
+	}
+}
+
+func TestNetworkConnect(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	if w := servertest.Do(router, http.MethodPost, "/networks/create", strings.NewReader(`{"Name":"connect-net"}`)); w.Code != http.StatusCreated {
+		t.Fatalf("failed creating network - expected %d, but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	netw := struct {
+		IPAM struct{ Config []struct{ Subnet string } }
+	}{}
+	w := servertest.Do(router, http.MethodGet, "/networks/connect-net", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &netw); err != nil || len(netw.IPAM.Config) == 0 {
+		t.Fatalf("failed inspecting network: %s", w.Body.String())
+	}
+	_, subnet, _ := net.ParseCIDR(netw.IPAM.Config[0].Subnet)
+	ip := subnet.IP.To4()
+	ip[3] = 100
+	db := servertest.CreateContainerWithBody(t, router, `{"Image":"postgres:16","name":"connect-db"}`)
+	app := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"connect-app"}`)
+
+	connect := func(id string) string {
+		return `{"Container":"` + id + `","EndpointConfig":{"IPAMConfig":{"IPv4Address":"` + ip.String() + `"},"Aliases":["App-Alias"],"Links":["connect-db:database"]}}`
+	}
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		code   int
+		match  string
+	}{
+		{method: http.MethodPost, url: "/networks/connect-net/connect", body: connect(app), code: http.StatusOK},
+		{method: http.MethodGet, url: "/containers/" + app + "/json", code: http.StatusOK, match: `"IPAMConfig":{"IPv4Address":"` + ip.String() + `"}`},
+		{method: http.MethodGet, url: "/containers/" + app + "/json", code: http.StatusOK, match: `"IPAddress":"` + ip.String() + `"`},
+		{method: http.MethodGet, url: "/containers/" + app + "/json", code: http.StatusOK, match: `"Aliases":["app-alias"]`},
+		{method: http.MethodGet, url: "/containers/" + app + "/json", code: http.StatusOK, match: `"Links":["/connect-db:/connect-app/database"]`},
+		{method: http.MethodPost, url: "/networks/connect-net/connect", body: connect(db), code: http.StatusBadRequest},
+		{method: http.MethodPost, url: "/networks/connect-net/disconnect", body: `{"Container":"` + app + `"}`, code: http.StatusOK},
+		{method: http.MethodGet, url: "/containers/" + app + "/json", code: http.StatusOK, match: `"Aliases":[]`},
+		{method: http.MethodPost, url: "/networks/connect-net/connect", body: connect(db), code: http.StatusOK},
+	}
+	for i, tst := range tests {
+		var body io.Reader
+		if tst.body != "" {
+			body = strings.NewReader(tst.body)
+		}
+		w := servertest.Do(router, tst.method, tst.url, body)
+		if w.Code != tst.code || !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %d with %s, but got %d: %s", i, tst.code, tst.match, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestContainerExit(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"exit-code","Labels":{"exit-test":"true"}}`)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+	kub.Exit(id, 2)
+
+	inspect := ""
+	for i := 0; i < 20 && !strings.Contains(inspect, `"Status":"exited"`); i++ {
+		time.Sleep(50 * time.Millisecond)
+		inspect = servertest.Do(router, http.MethodGet, "/containers/"+id+"/json", nil).Body.String()
+	}
+
+	tests := []struct {
+		body  string
+		match string
+	}{
+		{body: inspect, match: `"Status":"exited"`},
+		{body: inspect, match: `"ExitCode":2`},
+		{body: inspect, match: `"Running":false`},
+		{body: servertest.Do(router, http.MethodGet, `/containers/json?all=true&filters={"label":["exit-test=true"]}`, nil).Body.String(), match: `"State":"exited","Status":"Exited (2)`},
+		{body: servertest.Do(router, http.MethodPost, "/containers/"+id+"/wait", nil).Body.String(), match: `{"StatusCode":2}`},
+	}
+	for i, tst := range tests {
+		if !strings.Contains(tst.body, tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, tst.body)
+		}
+	}
+}
+
+func TestContainerDisrupted(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"evicted"}`)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+	kub.Disrupt(id, "Evicted: node was low on memory")
+
+	inspect := ""
+	for i := 0; i < 20 && !strings.Contains(inspect, `"Status":"exited"`); i++ {
+		time.Sleep(50 * time.Millisecond)
+		inspect = servertest.Do(router, http.MethodGet, "/containers/"+id+"/json", nil).Body.String()
+	}
+
+	for i, match := range []string{`"Status":"exited"`, `"ExitCode":137`, `"Running":false`, `"Error":"Evicted: node was low on memory"`} {
+		if !strings.Contains(inspect, match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, match, inspect)
+		}
+	}
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	body := `{"Name":"web-lifecycle","Labels":{"app":"web"},"TaskTemplate":{"ContainerSpec":{"Image":"nginx:1.25"}},"Mode":{"Replicated":{"Replicas":2}}}`
+	w := servertest.Do(router, http.MethodPost, "/services/create", strings.NewReader(body))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed creating service - expected %d, but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected error parsing create response: %s", err)
+	}
+	id := res["ID"].(string)
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		code   int
+		match  string
+	}{
+		{method: http.MethodPost, url: "/services/create", body: body, code: http.StatusConflict},
+		{method: http.MethodPost, url: "/services/create", body: `{"Name":"web-global","TaskTemplate":{"ContainerSpec":{"Image":"nginx"}},"Mode":{"Global":{}}}`, code: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/services/" + id, code: http.StatusOK, match: `"Replicas":2`},
+		{method: http.MethodGet, url: "/services/web-lifecycle", code: http.StatusOK, match: `"Index":1`},
+		{method: http.MethodGet, url: `/services?status=true&filters={"label":["app=web"]}`, code: http.StatusOK, match: `"RunningTasks":2`},
+		{method: http.MethodGet, url: `/services?filters={"label":["app=db"]}`, code: http.StatusOK, match: `[]`},
+		{method: http.MethodPost, url: "/services/" + id + "/update?version=5", body: body, code: http.StatusBadRequest},
+		{method: http.MethodPost, url: "/services/" + id + "/update?version=1", body: strings.Replace(body, `"Replicas":2`, `"Replicas":4`, 1), code: http.StatusOK},
+		{method: http.MethodGet, url: "/services/" + id, code: http.StatusOK, match: `"Replicas":4`},
+		{method: http.MethodDelete, url: "/services/" + id, code: http.StatusOK},
+		{method: http.MethodGet, url: "/services/" + id, code: http.StatusNotFound},
+	}
+
+	for i, tst := range tests {
+		w := servertest.Do(router, tst.method, tst.url, strings.NewReader(tst.body))
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
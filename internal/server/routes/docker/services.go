@@ -0,0 +1,216 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/filter"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/stringid"
+)
+
+// ServiceCreate - create a service, which is deployed as a deployment.
+// https://docs.docker.com/engine/api/v1.41/#operation/ServiceCreate
+// POST "/services/create"
+func ServiceCreate(cr *common.ContextRouter, c *gin.Context) {
+	in := &ServiceCreateRequest{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if in.Name == "" {
+		in.Name = stringid.TruncateID(stringid.GenerateRandomID())
+	}
+	if _, err := cr.DB.GetServiceByName(in.Name); err == nil {
+		httputil.Error(c, http.StatusConflict, fmt.Errorf("service %s already exists", in.Name))
+		return
+	}
+	svc := &types.Service{}
+	if err := applyServiceSpec(svc, in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := cr.DB.SaveService(svc); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := cr.Backend.DeployService(svc); err != nil {
+		if err := cr.DB.DeleteService(svc); err != nil {
+			klog.Warningf("error removing service %s: %s", svc.ID, err)
+		}
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"ID":       svc.ID,
+		"Warnings": []string{},
+	})
+}
+
+// ServiceUpdate - update a service.
+// https://docs.docker.com/engine/api/v1.41/#operation/ServiceUpdate
+// POST "/services/:id/update"
+func ServiceUpdate(cr *common.ContextRouter, c *gin.Context) {
+	svc, err := cr.DB.GetServiceByNameOrID(c.Param("id"))
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	if version := c.Query("version"); version != "" && version != strconv.FormatUint(svc.Version, 10) {
+		httputil.Error(c, http.StatusBadRequest, fmt.Errorf("update out of sequence"))
+		return
+	}
+	in := &ServiceCreateRequest{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if in.Name != "" && in.Name != svc.Name {
+		httputil.Error(c, http.StatusBadRequest, fmt.Errorf("renaming services is not supported"))
+		return
+	}
+	in.Name = svc.Name
+	upd := *svc
+	if err := applyServiceSpec(&upd, in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := cr.Backend.DeployService(&upd); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := cr.DB.SaveService(&upd); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"Warnings": []string{},
+	})
+}
+
+// ServiceList - list services.
+// https://docs.docker.com/engine/api/v1.41/#operation/ServiceList
+// GET "/services"
+func ServiceList(cr *common.ContextRouter, c *gin.Context) {
+	svcs, err := cr.DB.GetServices()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	filtr, err := filter.New(c.Query("filters"))
+	if err != nil {
+		klog.V(5).Infof("unsupported filter: %s", err)
+	}
+	status, _ := strconv.ParseBool(c.Query("status"))
+	res := []gin.H{}
+	for _, svc := range svcs {
+		if filtr.Match(svc) {
+			res = append(res, getServiceInfo(cr, svc, status))
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ServiceInfo - inspect a service.
+// https://docs.docker.com/engine/api/v1.41/#operation/ServiceInspect
+// GET "/services/:id"
+func ServiceInfo(cr *common.ContextRouter, c *gin.Context) {
+	svc, err := cr.DB.GetServiceByNameOrID(c.Param("id"))
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	c.JSON(http.StatusOK, getServiceInfo(cr, svc, false))
+}
+
+// ServiceDelete - remove a service and its deployment.
+// https://docs.docker.com/engine/api/v1.41/#operation/ServiceDelete
+// DELETE "/services/:id"
+func ServiceDelete(cr *common.ContextRouter, c *gin.Context) {
+	svc, err := cr.DB.GetServiceByNameOrID(c.Param("id"))
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	if err := cr.Backend.DeleteService(svc); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := cr.DB.DeleteService(svc); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// applyServiceSpec will update given service with the specification in
+// the given request. Only replicated services are supported.
+func applyServiceSpec(svc *types.Service, in *ServiceCreateRequest) error {
+	if in.Mode.Global != nil {
+		return fmt.Errorf("global services are not supported")
+	}
+	spec := in.TaskTemplate.ContainerSpec
+	if spec.Image == "" {
+		return fmt.Errorf("no image specified for service %s", in.Name)
+	}
+	replicas := 1
+	if in.Mode.Replicated != nil && in.Mode.Replicated.Replicas != nil {
+		replicas = *in.Mode.Replicated.Replicas
+	}
+	if replicas < 0 {
+		return fmt.Errorf("invalid number of replicas %d", replicas)
+	}
+	svc.Name = in.Name
+	svc.Labels = in.Labels
+	svc.Image = spec.Image
+	svc.Entrypoint = spec.Command
+	svc.Cmd = spec.Args
+	svc.Env = spec.Env
+	svc.Replicas = replicas
+	return nil
+}
+
+// getServiceInfo will return a gin.H containing the details of the
+// given service. If status is set, the number of running tasks is added.
+func getServiceInfo(cr *common.ContextRouter, svc *types.Service, status bool) gin.H {
+	res := gin.H{
+		"ID":        svc.ID,
+		"Version":   gin.H{"Index": svc.Version},
+		"CreatedAt": svc.Created.Format("2006-01-02T15:04:05Z"),
+		"UpdatedAt": svc.Updated.Format("2006-01-02T15:04:05Z"),
+		"Spec": gin.H{
+			"Name":   svc.Name,
+			"Labels": svc.Labels,
+			"TaskTemplate": gin.H{
+				"ContainerSpec": gin.H{
+					"Image":   svc.Image,
+					"Command": svc.Entrypoint,
+					"Args":    svc.Cmd,
+					"Env":     svc.Env,
+				},
+			},
+			"Mode": gin.H{
+				"Replicated": gin.H{"Replicas": svc.Replicas},
+			},
+		},
+		"Endpoint": gin.H{"Spec": gin.H{}},
+	}
+	if status {
+		running, err := cr.Backend.GetServiceReplicas(svc)
+		if err != nil {
+			klog.V(3).Infof("error retrieving replicas of service %s: %s", svc.ID, err)
+		}
+		res["ServiceStatus"] = gin.H{
+			"RunningTasks": running,
+			"DesiredTasks": svc.Replicas,
+		}
+	}
+	return res
+}
@@ -1,14 +1,12 @@
 package docker
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"k8s.io/klog"
 
 	"github.com/joyrex2001/kubedock/internal/config"
-	"github.com/joyrex2001/kubedock/internal/server/filter"
+	"github.com/joyrex2001/kubedock/internal/events"
 	"github.com/joyrex2001/kubedock/internal/server/routes/common"
 )
 
@@ -20,12 +18,22 @@ func Info(cr *common.ContextRouter, c *gin.Context) {
 	for k, v := range config.DefaultLabels {
 		labels = append(labels, k+"="+v)
 	}
+	info := cr.Config.Info.WithDefaults()
+	ncpu, mem := common.GetCapacity(cr, info)
 	c.JSON(http.StatusOK, gin.H{
 		"ID":              config.ID,
 		"Name":            config.Name,
 		"ServerVersion":   config.Version,
-		"OperatingSystem": config.OS,
-		"MemTotal":        0,
+		"OperatingSystem": info.OperatingSystem,
+		"OSType":          "linux",
+		"Architecture":    config.GOARCH,
+		"KernelVersion":   info.KernelVersion,
+		"Driver":          info.StorageDriver,
+		"CgroupVersion":   info.CgroupVersion,
+		"CgroupDriver":    info.CgroupDriver,
+		"SecurityOptions": info.SecurityOptions,
+		"NCPU":            ncpu,
+		"MemTotal":        mem,
 		"Labels":          labels,
 	})
 }
@@ -34,16 +42,17 @@ func Info(cr *common.ContextRouter, c *gin.Context) {
 // https://docs.docker.com/engine/api/v1.41/#operation/SystemVersion
 // GET "/version"
 func Version(cr *common.ContextRouter, c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"Version":       config.DockerVersion,
-		"ApiVersion":    config.DockerAPIVersion,
-		"MinAPIVersion": config.DockerAPIVersion,
-		"GitCommit":     config.Build,
-		"BuildTime":     config.Date,
-		"GoVersion":     config.GoVersion,
-		"Os":            config.GOOS,
-		"Arch":          config.GOARCH,
-	})
+	c.JSON(http.StatusOK, common.GetVersion(common.VersionInfo{
+		Platform:      config.DockerPlatform,
+		Component:     "Engine",
+		Version:       config.DockerVersion,
+		APIVersion:    config.DockerAPIVersion,
+		MinAPIVersion: config.DockerMinAPIVersion,
+		Details: gin.H{
+			"ApiVersion":    config.DockerAPIVersion,
+			"MinAPIVersion": config.DockerMinAPIVersion,
+		},
+	}))
 }
 
 // Ping - dummy endpoint you can use to test if the server is accessible.
@@ -53,6 +62,9 @@ func Version(cr *common.ContextRouter, c *gin.Context) {
 func Ping(cr *common.ContextRouter, c *gin.Context) {
 	w := c.Writer
 	w.Header().Set("API-Version", config.DockerAPIVersion)
+	if cr.Config.BuildkitAddr != "" {
+		w.Header().Set("Builder-Version", "2")
+	}
 	c.String(http.StatusOK, "OK")
 }
 
@@ -60,40 +72,26 @@ func Ping(cr *common.ContextRouter, c *gin.Context) {
 // https://docs.docker.com/engine/api/v1.41/#tag/System/operation/SystemEvents
 // GET "/events"
 func Events(cr *common.ContextRouter, c *gin.Context) {
-	w := c.Writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Flush()
+	common.StreamEvents(cr, c, getEventMessage)
+}
 
-	filtr, err := filter.New(c.Query("filters"))
-	if err != nil {
-		klog.V(5).Infof("unsupported filter: %s", err)
+// getEventMessage will convert the given event to the docker json format.
+func getEventMessage(msg events.Message) gin.H {
+	res := gin.H{
+		"id":     msg.ID,
+		"Type":   msg.Type,
+		"status": msg.Action,
+		"Action": msg.Action,
+		"Actor": gin.H{
+			"ID":         msg.ID,
+			"Attributes": msg.Attributes,
+		},
+		"scope":    "local",
+		"time":     msg.Time,
+		"timeNano": msg.TimeNano,
 	}
-
-	enc := json.NewEncoder(w)
-	el, id := cr.Events.Subscribe()
-	for {
-		select {
-		case <-c.Request.Context().Done():
-			cr.Events.Unsubscribe(id)
-			return
-		case msg := <-el:
-			if filtr.Match(&msg) {
-				klog.V(5).Infof("sending message to %s", id)
-				enc.Encode(gin.H{
-					"id":     msg.ID,
-					"Type":   msg.Type,
-					"Status": msg.Action,
-					"Action": msg.Action,
-					"Actor": gin.H{
-						"ID": msg.ID,
-					},
-					"scope":    "local",
-					"time":     msg.Time,
-					"timeNano": msg.TimeNano,
-				})
-				w.Flush()
-			}
-		}
+	if msg.Type == events.Container {
+		res["from"] = msg.Attributes["image"]
 	}
+	return res
 }
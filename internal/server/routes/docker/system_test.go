@@ -0,0 +1,41 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+)
+
+func TestGetEventMessage(t *testing.T) {
+	tests := []struct {
+		msg  events.Message
+		from interface{}
+		name string
+	}{
+		{
+			msg:  events.Message{ID: "1", Type: events.Container, Action: events.Start, Attributes: map[string]string{"image": "alpine", "name": "tainr"}},
+			from: "alpine",
+			name: "tainr",
+		},
+		{
+			msg:  events.Message{ID: "2", Type: events.Image, Action: events.Pull, Attributes: map[string]string{"name": "alpine"}},
+			from: nil,
+			name: "alpine",
+		},
+	}
+	for i, tst := range tests {
+		res := getEventMessage(tst.msg)
+		if res["status"] != tst.msg.Action {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.msg.Action, res["status"])
+		}
+		if res["from"] != tst.from {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.from, res["from"])
+		}
+		name := res["Actor"].(gin.H)["Attributes"].(map[string]string)["name"]
+		if name != tst.name {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.name, name)
+		}
+	}
+}
@@ -21,8 +21,22 @@ type ContainerCreateRequest struct {
 // NetworkCreateRequest represents the json structure that
 // is used for the /networks/create post endpoint.
 type NetworkCreateRequest struct {
-	Name   string            `json:"Name"`
-	Labels map[string]string `json:"Labels"`
+	Name           string            `json:"Name"`
+	CheckDuplicate bool              `json:"CheckDuplicate"`
+	Labels         map[string]string `json:"Labels"`
+	IPAM           NetworkIPAM       `json:"IPAM"`
+}
+
+// NetworkIPAM contains the ip address management configuration of a
+// network create request.
+type NetworkIPAM struct {
+	Config []NetworkIPAMConfig `json:"Config"`
+}
+
+// NetworkIPAMConfig contains the requested subnet and gateway of a network.
+type NetworkIPAMConfig struct {
+	Subnet  string `json:"Subnet"`
+	Gateway string `json:"Gateway"`
 }
 
 // NetworkConnectRequest represents the json structure that
@@ -38,14 +52,68 @@ type NetworkDisconnectRequest struct {
 	Container string `json:"container"`
 }
 
+// ServiceCreateRequest represents the json structure that
+// is used for the /services/create and /services/:id/update post
+// endpoints.
+type ServiceCreateRequest struct {
+	Name         string            `json:"Name"`
+	Labels       map[string]string `json:"Labels"`
+	TaskTemplate TaskTemplate      `json:"TaskTemplate"`
+	Mode         ServiceMode       `json:"Mode"`
+}
+
+// TaskTemplate contains the specification of the tasks of a service.
+type TaskTemplate struct {
+	ContainerSpec ContainerSpec `json:"ContainerSpec"`
+}
+
+// ContainerSpec contains the specification of the containers of a service.
+type ContainerSpec struct {
+	Image   string   `json:"Image"`
+	Command []string `json:"Command"`
+	Args    []string `json:"Args"`
+	Env     []string `json:"Env"`
+}
+
+// ServiceMode contains the scheduling mode of a service.
+type ServiceMode struct {
+	Replicated *ReplicatedService `json:"Replicated"`
+	Global     *struct{}          `json:"Global"`
+}
+
+// ReplicatedService contains the number of replicas of a service.
+type ReplicatedService struct {
+	Replicas *int `json:"Replicas"`
+}
+
 // HostConfig contains to be mounted files from the host system.
 type HostConfig struct {
 	Binds        []string `json:"Binds"`
 	Mounts       []Mount  `json:"Mounts"`
+	Links        []string `json:"Links"`
 	PortBindings map[string][]PortBinding
-	Memory       int    `json:"Memory"`
-	NanoCpus     int    `json:"NanoCpus"`
-	NetworkMode  string `json:"NetworkMode"`
+	Memory       int               `json:"Memory"`
+	NanoCpus     int               `json:"NanoCpus"`
+	NetworkMode  string            `json:"NetworkMode"`
+	Devices      []DeviceMapping   `json:"Devices"`
+	Sysctls      map[string]string `json:"Sysctls"`
+	CgroupParent string            `json:"CgroupParent"`
+	UsernsMode   string            `json:"UsernsMode"`
+	Ulimits      []Ulimit          `json:"Ulimits"`
+}
+
+// Ulimit represents a resource limit that should be applied on a container.
+type Ulimit struct {
+	Name string `json:"Name"`
+	Soft int64  `json:"Soft"`
+	Hard int64  `json:"Hard"`
+}
+
+// DeviceMapping represents a device that should be mapped into a container.
+type DeviceMapping struct {
+	PathOnHost        string `json:"PathOnHost"`
+	PathInContainer   string `json:"PathInContainer"`
+	CgroupPermissions string `json:"CgroupPermissions"`
 }
 
 // PortBinding represents a binding between to a port
@@ -65,14 +133,29 @@ type NetworkConfig struct {
 
 // EndpointConfig contains information about network endpoints
 type EndpointConfig struct {
-	Aliases   []string `json:"Aliases"`
-	NetworkID string   `json:"NetworkID"`
+	IPAMConfig *EndpointIPAMConfig `json:"IPAMConfig"`
+	Links      []string            `json:"Links"`
+	Aliases    []string            `json:"Aliases"`
+	NetworkID  string              `json:"NetworkID"`
+}
+
+// EndpointIPAMConfig contains the requested (static) ip addresses of a
+// network endpoint.
+type EndpointIPAMConfig struct {
+	IPv4Address string `json:"IPv4Address"`
+	IPv6Address string `json:"IPv6Address"`
 }
 
 // Mount contains information about mounted volumes/bindings
 type Mount struct {
-	Type     string `json:"Type"`
-	Source   string `json:"Source"`
-	Target   string `json:"Target"`
-	ReadOnly bool   `json:"ReadOnly"`
+	Type        string       `json:"Type"`
+	Source      string       `json:"Source"`
+	Target      string       `json:"Target"`
+	ReadOnly    bool         `json:"ReadOnly"`
+	BindOptions *BindOptions `json:"BindOptions"`
+}
+
+// BindOptions contains the options of a bind mount
+type BindOptions struct {
+	Propagation string `json:"Propagation"`
 }
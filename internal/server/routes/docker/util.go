@@ -1,24 +1,40 @@
 package docker
 
 import (
-	"strings"
+	"k8s.io/klog"
 
 	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
 )
 
 // addNetworkAliases will add the networkaliases as defined in the provided
-// EndpointConfig to the container.
-func addNetworkAliases(tainr *types.Container, endp EndpointConfig) {
-	aliases := []string{}
-	done := map[string]string{tainr.ShortID: tainr.ShortID}
-	for _, l := range [][]string{tainr.NetworkAliases, endp.Aliases} {
-		for _, a := range l {
-			if _, ok := done[a]; !ok {
-				alias := strings.ToLower(a)
-				aliases = append(aliases, alias)
-				done[alias] = alias
-			}
+// EndpointConfig to the container, registered for the network with given id.
+func addNetworkAliases(tainr *types.Container, id string, endp EndpointConfig) {
+	tainr.AddNetworkAliases(id, endp.Aliases)
+}
+
+// applyEndpointConfig will apply the provided EndpointConfig of the network
+// with given id to the container; the network aliases are added, the
+// requested ip address is recorded and the legacy links are added. The
+// container is not modified if any of the links can't be resolved.
+func applyEndpointConfig(cr *common.ContextRouter, tainr *types.Container, id string, endp EndpointConfig) error {
+	links := &types.Container{}
+	for _, link := range endp.Links {
+		if err := common.AddLink(cr, links, link); err != nil {
+			return err
+		}
+	}
+	for _, alias := range links.GetLinkAliases() {
+		tainr.AddLink(&types.Container{ID: links.Links[alias]}, alias)
+	}
+	addNetworkAliases(tainr, id, endp)
+	if ipam := endp.IPAMConfig; ipam != nil && id != "" {
+		if ipam.IPv4Address != "" {
+			tainr.RequestIP(id, ipam.IPv4Address)
+		}
+		if ipam.IPv6Address != "" {
+			klog.Warningf("ignoring requested ipv6 address %s, ipv6 is not supported", ipam.IPv6Address)
 		}
 	}
-	tainr.NetworkAliases = aliases
+	return nil
 }
@@ -42,7 +42,7 @@ func TestAddNetworkAliases(t *testing.T) {
 	}
 
 	for i, tst := range tests {
-		addNetworkAliases(tst.tainr, tst.endp)
+		addNetworkAliases(tst.tainr, "", tst.endp)
 		if !reflect.DeepEqual(tst.tainr.NetworkAliases, tst.out) {
 			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, tst.tainr.NetworkAliases)
 		}
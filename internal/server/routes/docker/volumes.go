@@ -12,8 +12,19 @@ import (
 // https://docs.docker.com/engine/api/v1.41/#operation/VolumePrune
 // POST "/volumes/prune"
 func VolumesPrune(cr *common.ContextRouter, c *gin.Context) {
-	c.JSON(http.StatusCreated, gin.H{
+	c.JSON(http.StatusOK, gin.H{
 		"VolumesDeleted": []string{},
 		"SpaceReclaimed": 0,
 	})
 }
+
+// VolumesList - list volumes. Kubedock doesn't manage named volumes (binds
+// are copied into the pod), so the list is always empty.
+// https://docs.docker.com/engine/api/v1.41/#operation/VolumeList
+// GET "/volumes"
+func VolumesList(cr *common.ContextRouter, c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"Volumes":  []gin.H{},
+		"Warnings": []string{},
+	})
+}
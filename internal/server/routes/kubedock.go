@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/metrics"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/server/routes/docker"
+	"github.com/joyrex2001/kubedock/internal/server/routes/kubedock"
+)
+
+// RegisterKubedockRoutes will add all kubedock specific extension routes.
+func RegisterKubedockRoutes(router *gin.Engine, cr *common.ContextRouter) {
+	wrap := func(fn func(*common.ContextRouter, *gin.Context)) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			fn(cr, c)
+		}
+	}
+
+	router.POST("/kubedock/images/prewarm", httputil.NoWriteTimeout, wrap(kubedock.ImagesPrewarm))
+	router.POST("/kubedock/containers/copy", httputil.NoWriteTimeout, wrap(kubedock.ContainersCopy))
+	router.GET("/kubedock/containers/json", wrap(docker.ContainerInspectBatch))
+	router.GET("/kubedock/projects", wrap(kubedock.ProjectsList))
+	router.DELETE("/kubedock/projects/:name", wrap(kubedock.ProjectDelete))
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	if cr.Config.RegistryProxy {
+		router.GET("/v2/*path", wrap(kubedock.Registry))
+		router.HEAD("/v2/*path", wrap(kubedock.Registry))
+	}
+
+	if cr.Config.Dashboard {
+		router.GET("/kubedock/dashboard", wrap(kubedock.Dashboard))
+		router.GET("/kubedock/dashboard/containers", wrap(kubedock.DashboardContainers))
+		router.GET("/kubedock/dashboard/containers/:id/logs", httputil.NoWriteTimeout, wrap(kubedock.DashboardLogs))
+		router.GET("/kubedock/dashboard/containers/:id/events", httputil.NoWriteTimeout, wrap(kubedock.DashboardEvents))
+	}
+
+	if cr.Config.AdminToken != "" {
+		admin := router.Group("/kubedock/admin", kubedock.AdminAuth(cr))
+		admin.GET("/verbosity", wrap(kubedock.AdminGetVerbosity))
+		admin.POST("/verbosity", wrap(kubedock.AdminSetVerbosity))
+		admin.GET("/portforwards", wrap(kubedock.AdminPortForwards))
+		admin.GET("/sessions", wrap(kubedock.AdminSessions))
+		admin.POST("/reaper", wrap(kubedock.AdminReap))
+	}
+}
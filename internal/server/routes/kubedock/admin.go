@@ -0,0 +1,172 @@
+package kubedock
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/reaper"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// sessionLabel is the label that is used by testcontainers to identify the
+// session that created the container.
+const sessionLabel = "org.testcontainers.sessionId"
+
+// AdminAuth is a gin-gonic middleware that will only allow requests that
+// provide the configured admin token as bearer token.
+func AdminAuth(cr *common.ContextRouter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(cr.Config.AdminToken)) != 1 {
+			httputil.Error(c, http.StatusUnauthorized, fmt.Errorf("invalid admin token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminGetVerbosity - return the current log verbosity.
+// GET "/kubedock/admin/verbosity"
+func AdminGetVerbosity(cr *common.ContextRouter, c *gin.Context) {
+	v := flag.Lookup("v")
+	if v == nil {
+		httputil.Error(c, http.StatusInternalServerError, fmt.Errorf("log verbosity is not configurable"))
+		return
+	}
+	level, _ := strconv.Atoi(v.Value.String())
+	c.JSON(http.StatusOK, gin.H{
+		"Verbosity": level,
+	})
+}
+
+// AdminSetVerbosity - change the log verbosity.
+// POST "/kubedock/admin/verbosity"
+func AdminSetVerbosity(cr *common.ContextRouter, c *gin.Context) {
+	in := &AdminVerbosityRequest{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if in.Verbosity < 0 {
+		httputil.Error(c, http.StatusBadRequest, fmt.Errorf("invalid verbosity %d", in.Verbosity))
+		return
+	}
+	if err := flag.Set("v", strconv.Itoa(in.Verbosity)); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	klog.Infof("log verbosity changed to %d", in.Verbosity)
+	c.JSON(http.StatusOK, gin.H{
+		"Verbosity": in.Verbosity,
+	})
+}
+
+// AdminPortForwards - list the active port-forwards and reverse proxies.
+// GET "/kubedock/admin/portforwards"
+func AdminPortForwards(cr *common.ContextRouter, c *gin.Context) {
+	res := []gin.H{}
+	typ := ""
+	if cr.Config.PortForward {
+		typ = "port-forward"
+	} else if cr.Config.ReverseProxy {
+		typ = "reverse-proxy"
+	}
+	if typ == "" {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	for _, tainr := range tainrs {
+		if !tainr.Running {
+			continue
+		}
+		for _, ports := range []map[int]int{tainr.HostPorts, tainr.MappedPorts} {
+			for src, dst := range ports {
+				if src < 0 {
+					continue
+				}
+				res = append(res, gin.H{
+					"Id":        tainr.ID,
+					"Name":      tainr.Name,
+					"Type":      typ,
+					"LocalPort": src,
+					"PodPort":   dst,
+				})
+			}
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i]["LocalPort"].(int) < res[j]["LocalPort"].(int)
+	})
+	c.JSON(http.StatusOK, res)
+}
+
+// AdminSessions - list the testcontainers sessions and their containers.
+// GET "/kubedock/admin/sessions"
+func AdminSessions(cr *common.ContextRouter, c *gin.Context) {
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	type session struct {
+		ID         string    `json:"Id"`
+		Created    time.Time `json:"Created"`
+		Containers []string  `json:"Containers"`
+		Running    int       `json:"Running"`
+	}
+	sessions := map[string]*session{}
+	for _, tainr := range tainrs {
+		id := tainr.Labels[sessionLabel]
+		ses, ok := sessions[id]
+		if !ok {
+			ses = &session{ID: id, Created: tainr.Created, Containers: []string{}}
+			sessions[id] = ses
+		}
+		if tainr.Created.Before(ses.Created) {
+			ses.Created = tainr.Created
+		}
+		ses.Containers = append(ses.Containers, tainr.ID)
+		if tainr.Running {
+			ses.Running++
+		}
+	}
+
+	res := []*session{}
+	for _, ses := range sessions {
+		sort.Strings(ses.Containers)
+		res = append(res, ses)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.Before(res[j].Created)
+	})
+	c.JSON(http.StatusOK, res)
+}
+
+// AdminReap - clean up lingering resources immediately.
+// POST "/kubedock/admin/reaper"
+func AdminReap(cr *common.ContextRouter, c *gin.Context) {
+	if err := reaper.Reap(); err != nil {
+		httputil.Error(c, http.StatusServiceUnavailable, err)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package kubedock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// ContainersCopy - copy a path from one container to another, without
+// transferring the data via the client.
+// POST "/kubedock/containers/copy"
+func ContainersCopy(cr *common.ContextRouter, c *gin.Context) {
+	in := &ContainersCopyRequest{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if in.Source == "" || in.SourcePath == "" || in.Target == "" || in.TargetPath == "" {
+		httputil.Error(c, http.StatusBadRequest, fmt.Errorf("missing required Source, SourcePath, Target or TargetPath"))
+		return
+	}
+
+	src, err := cr.DB.GetContainerByNameOrID(in.Source)
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	dst, err := cr.DB.GetContainerByNameOrID(in.Target)
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	for _, tainr := range []*types.Container{src, dst} {
+		if !tainr.Running {
+			httputil.Error(c, http.StatusConflict, fmt.Errorf("container %s is not running", tainr.ShortID))
+			return
+		}
+	}
+
+	exists, err := cr.Backend.FileExistsInContainer(src, in.SourcePath)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !exists {
+		httputil.Error(c, http.StatusNotFound, fmt.Errorf("file not found"))
+		return
+	}
+
+	klog.Infof("copy %s:%s to %s:%s", src.ShortID, in.SourcePath, dst.ShortID, in.TargetPath)
+	reader, writer := io.Pipe()
+	srcerr := make(chan error, 1)
+	go func() {
+		err := cr.Backend.CopyFromContainer(src, in.SourcePath, writer)
+		writer.CloseWithError(err)
+		srcerr <- err
+	}()
+	err = cr.Backend.CopyToContainer(dst, reader, in.TargetPath, false)
+	reader.CloseWithError(err)
+	if serr := <-srcerr; err == nil && serr != nil {
+		err = serr
+	}
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
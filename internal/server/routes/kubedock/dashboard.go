@@ -0,0 +1,132 @@
+package kubedock
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/stdtag"
+)
+
+// dashboardTail is the default number of log lines shown in the dashboard.
+const dashboardTail = 500
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// Dashboard - serve the dashboard web ui.
+// GET "/kubedock/dashboard"
+func Dashboard(cr *common.ContextRouter, c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", dashboardHTML)
+}
+
+// DashboardContainers - list all tracked containers with their pods and
+// port mappings.
+// GET "/kubedock/dashboard/containers"
+func DashboardContainers(cr *common.ContextRouter, c *gin.Context) {
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	sort.Slice(tainrs, func(i, j int) bool {
+		return tainrs[j].Created.Before(tainrs[i].Created)
+	})
+
+	res := []gin.H{}
+	for _, tainr := range tainrs {
+		if tainr.Running {
+			tainr = common.UpdateContainerStatus(cr, tainr)
+		}
+		ports := []gin.H{}
+		for _, pm := range []map[int]int{tainr.HostPorts, tainr.MappedPorts} {
+			for src, dst := range pm {
+				if src < 0 {
+					continue
+				}
+				ports = append(ports, gin.H{"HostPort": src, "ContainerPort": dst})
+			}
+		}
+		sort.Slice(ports, func(i, j int) bool {
+			return ports[i]["HostPort"].(int) < ports[j]["HostPort"].(int)
+		})
+		res = append(res, gin.H{
+			"Id":      tainr.ID,
+			"Name":    tainr.Name,
+			"Image":   tainr.Image,
+			"Pod":     tainr.GetPodName(),
+			"State":   tainr.StatusString(),
+			"Status":  tainr.StateString(),
+			"Error":   tainr.Error,
+			"HostIP":  tainr.HostIP,
+			"Ports":   ports,
+			"Created": tainr.Created,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// DashboardLogs - return the most recent logs of a container as plain text.
+// GET "/kubedock/dashboard/containers/:id/logs"
+func DashboardLogs(cr *common.ContextRouter, c *gin.Context) {
+	tainr, err := cr.DB.GetContainer(c.Param("id"))
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	if !tainr.Running && !tainr.Completed && !tainr.Failed {
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte{})
+		return
+	}
+
+	tail := uint64(dashboardTail)
+	if n, err := strconv.ParseUint(c.Query("tail"), 10, 64); err == nil {
+		tail = n
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.Writer.WriteHeader(http.StatusOK)
+	stop := make(chan struct{}, 1)
+	defer close(stop)
+	opts := &backend.LogOptions{TailLines: &tail}
+	if tainr.SeparateStderr() {
+		demux := stdtag.NewDemuxer(c.Writer, c.Writer, false)
+		defer demux.Flush()
+		cr.Backend.GetLogsRaw(tainr, opts, stop, demux)
+		return
+	}
+	cr.Backend.GetLogsRaw(tainr, opts, stop, c.Writer)
+}
+
+// DashboardEvents - return the kubernetes events of the pod of a container.
+// GET "/kubedock/dashboard/containers/:id/events"
+func DashboardEvents(cr *common.ContextRouter, c *gin.Context) {
+	tainr, err := cr.DB.GetContainer(c.Param("id"))
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	res := []gin.H{}
+	evts, err := cr.Backend.GetPodEvents(tainr)
+	if err != nil {
+		// the pod doesn't exist (yet) or events can't be listed
+		c.JSON(http.StatusOK, res)
+		return
+	}
+	for _, evt := range evts {
+		res = append(res, gin.H{
+			"Type":    evt.Type,
+			"Reason":  evt.Reason,
+			"Message": evt.Message,
+			"Count":   evt.Count,
+			"Time":    evt.LastTimestamp.Time,
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
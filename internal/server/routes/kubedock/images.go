@@ -0,0 +1,34 @@
+package kubedock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// ImagesPrewarm - pre-pull images on all nodes of the cluster.
+// POST "/kubedock/images/prewarm"
+func ImagesPrewarm(cr *common.ContextRouter, c *gin.Context) {
+	in := &ImagesPrewarmRequest{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if len(in.Images) == 0 {
+		httputil.Error(c, http.StatusBadRequest, fmt.Errorf("no images provided"))
+		return
+	}
+	imgs, err := cr.Backend.PrewarmImages(in.Images)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"Images": imgs,
+	})
+}
@@ -0,0 +1,149 @@
+package kubedock
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// ProjectsList - list the docker compose projects of the tracked containers
+// and networks.
+// GET "/kubedock/projects"
+func ProjectsList(cr *common.ContextRouter, c *gin.Context) {
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	netws, err := cr.DB.GetNetworks()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	projects := map[string]*Project{}
+	get := func(name string) *Project {
+		if _, ok := projects[name]; !ok {
+			projects[name] = &Project{Name: name, Services: []string{}, Containers: []string{}, Networks: []string{}}
+		}
+		return projects[name]
+	}
+	for _, tainr := range tainrs {
+		name := tainr.GetComposeProject()
+		if name == "" {
+			continue
+		}
+		prj := get(name)
+		prj.Containers = append(prj.Containers, tainr.ShortID)
+		if svc := tainr.GetComposeService(); svc != "" && !slices.Contains(prj.Services, svc) {
+			prj.Services = append(prj.Services, svc)
+		}
+		if tainr.Running {
+			prj.Running++
+		}
+	}
+	for _, netw := range netws {
+		if name := netw.Labels[types.LabelComposeProject]; name != "" {
+			prj := get(name)
+			prj.Networks = append(prj.Networks, netw.Name)
+		}
+	}
+
+	res := []*Project{}
+	for _, prj := range projects {
+		sort.Strings(prj.Services)
+		sort.Strings(prj.Containers)
+		sort.Strings(prj.Networks)
+		res = append(res, prj)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	c.JSON(http.StatusOK, res)
+}
+
+// ProjectDelete - remove all containers and networks of a docker compose
+// project, including the kubernetes resources that are not tracked anymore.
+// DELETE "/kubedock/projects/:name"
+func ProjectDelete(cr *common.ContextRouter, c *gin.Context) {
+	name := c.Param("name")
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	klog.Infof("deleting compose project %s", name)
+	deleted := []string{}
+	for _, tainr := range tainrs {
+		if tainr.GetComposeProject() != name {
+			continue
+		}
+		tainr.SignalDetach()
+		tainr.SignalStop()
+		if !tainr.Stopped && !tainr.Killed {
+			if err := common.DeleteContainer(cr, tainr); err != nil {
+				klog.Warningf("error while deleting k8s container: %s", err)
+			}
+			common.StopLinkedContainers(cr, tainr)
+			common.PublishContainerEvent(cr, tainr, events.Die)
+		}
+		if err := cr.DB.DeleteContainer(tainr); err != nil {
+			klog.Warningf("error while deleting container: %s", err)
+			continue
+		}
+		common.PublishContainerEvent(cr, tainr, events.Destroy)
+		deleted = append(deleted, tainr.ShortID)
+	}
+
+	if err := cr.Backend.DeleteProject(name); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	netws, err := cr.DB.GetNetworks()
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	removed := []string{}
+	for _, netw := range netws {
+		if netw.IsPredefined() || netw.Labels[types.LabelComposeProject] != name {
+			continue
+		}
+		if inUse(cr, netw) {
+			klog.Warningf("not deleting network %s of project %s: containers attached", netw.Name, name)
+			continue
+		}
+		if err := cr.DB.DeleteNetwork(netw); err != nil {
+			klog.Warningf("error while deleting network: %s", err)
+			continue
+		}
+		removed = append(removed, netw.Name)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Containers": deleted,
+		"Networks":   removed,
+	})
+}
+
+// inUse will return true if any container is attached to given network.
+func inUse(cr *common.ContextRouter, netw *types.Network) bool {
+	tainrs, err := cr.DB.GetContainers()
+	if err != nil {
+		return true
+	}
+	for _, tainr := range tainrs {
+		if _, ok := tainr.Networks[netw.ID]; ok {
+			return true
+		}
+	}
+	return false
+}
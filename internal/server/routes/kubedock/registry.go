@@ -0,0 +1,98 @@
+package kubedock
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/image"
+)
+
+// Registry - pull-through proxy for the registry v2 api, which fetches
+// manifests and blobs from the upstream registries with the credentials and
+// image rewrite rules of kubedock.
+// https://distribution.github.io/distribution/spec/api/
+// GET,HEAD "/v2/"
+// GET,HEAD "/v2/:name/manifests/:reference"
+// GET,HEAD "/v2/:name/blobs/:digest"
+func Registry(cr *common.ContextRouter, c *gin.Context) {
+	c.Header("Docker-Distribution-API-Version", "registry/2.0")
+	path := strings.Trim(c.Param("path"), "/")
+	if path == "" {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+	if i := strings.LastIndex(path, "/manifests/"); i > 0 {
+		registryManifest(cr, c, path[:i], path[i+len("/manifests/"):])
+		return
+	}
+	if i := strings.LastIndex(path, "/blobs/"); i > 0 {
+		registryBlob(cr, c, path[:i], path[i+len("/blobs/"):])
+		return
+	}
+	registryError(c, http.StatusNotFound, "UNSUPPORTED", fmt.Errorf("unsupported registry request %s", path))
+}
+
+// registryManifest will return the manifest with given reference (a tag or
+// a digest) of given repository.
+func registryManifest(cr *common.ContextRouter, c *gin.Context, repo, ref string) {
+	man, err := cr.Backend.GetImageManifest(repo, ref)
+	if err != nil {
+		upstreamError(c, repo, "MANIFEST_UNKNOWN", err)
+		return
+	}
+	c.Header("Docker-Content-Digest", man.Digest)
+	c.Data(http.StatusOK, man.MediaType, man.Blob)
+}
+
+// registryBlob will return the blob with given digest of given repository.
+func registryBlob(cr *common.ContextRouter, c *gin.Context, repo, dgst string) {
+	blob, size, err := cr.Backend.GetImageBlob(repo, dgst)
+	if err != nil {
+		upstreamError(c, repo, "BLOB_UNKNOWN", err)
+		return
+	}
+	defer blob.Close()
+	if c.Request.Method == http.MethodHead {
+		c.Header("Docker-Content-Digest", dgst)
+		if size >= 0 {
+			c.Header("Content-Length", fmt.Sprintf("%d", size))
+		}
+		c.Status(http.StatusOK)
+		return
+	}
+	c.DataFromReader(http.StatusOK, size, "application/octet-stream", blob, map[string]string{
+		"Docker-Content-Digest": dgst,
+	})
+}
+
+// upstreamError will return the given error of the upstream registry of
+// given repository in the format of the registry v2 api. Errors that
+// indicate that the manifest or blob doesn't exist are returned with given
+// code, denied access as DENIED, and other errors (e.g. network errors) as
+// UNAVAILABLE.
+func upstreamError(c *gin.Context, repo, code string, err error) {
+	err = image.ClassifyPullError(repo, err)
+	var perr *image.PullError
+	if !errors.As(err, &perr) {
+		registryError(c, http.StatusBadGateway, "UNAVAILABLE", err)
+	} else if perr.Denied {
+		registryError(c, http.StatusForbidden, "DENIED", err)
+	} else {
+		registryError(c, http.StatusNotFound, code, err)
+	}
+}
+
+// registryError will return the given error in the format of the registry
+// v2 api.
+func registryError(c *gin.Context, status int, code string, err error) {
+	klog.Errorf("error during registry request[%d]: %s", status, err)
+	c.JSON(status, gin.H{
+		"errors": []gin.H{{"code": code, "message": err.Error()}},
+	})
+}
@@ -0,0 +1,250 @@
+package kubedock_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/server/servertest"
+)
+
+func TestRegistryProxy(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{RegistryProxy: true})
+	kub.Blobs["sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"] = []byte("hello")
+	kub.RegistryErrors = map[string]error{
+		"library/private": errors.New("unauthorized: authentication required"),
+		"library/offline": errors.New("dial tcp: connection refused"),
+		"library/missing": errors.New("manifest unknown"),
+	}
+
+	tests := []struct {
+		method string
+		url    string
+		code   int
+		match  string
+	}{
+		{method: http.MethodGet, url: "/v2/", code: http.StatusOK, match: "{}"},
+		{method: http.MethodGet, url: "/v2/library/redis/manifests/7", code: http.StatusOK, match: `"digest":"sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"`},
+		{method: http.MethodGet, url: "/v2/library/redis/blobs/sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", code: http.StatusOK, match: "hello"},
+		{method: http.MethodHead, url: "/v2/library/redis/blobs/sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", code: http.StatusOK},
+		{method: http.MethodGet, url: "/v2/library/redis/blobs/sha256:1234", code: http.StatusNotFound, match: "BLOB_UNKNOWN"},
+		{method: http.MethodGet, url: "/v2/library/redis/tags/list", code: http.StatusNotFound, match: "UNSUPPORTED"},
+		{method: http.MethodGet, url: "/v2/library/private/manifests/1", code: http.StatusForbidden, match: "DENIED"},
+		{method: http.MethodGet, url: "/v2/library/offline/manifests/1", code: http.StatusBadGateway, match: "UNAVAILABLE"},
+		{method: http.MethodGet, url: "/v2/library/missing/manifests/1", code: http.StatusNotFound, match: "MANIFEST_UNKNOWN"},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, tst.method, tst.url, nil)
+		if w.Code != tst.code || !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %d with %s, but got %d: %s", i, tst.code, tst.match, w.Code, w.Body.String())
+		}
+		if w.Header().Get("Docker-Distribution-API-Version") != "registry/2.0" {
+			t.Errorf("failed test %d - expected %v, but got %v", i, "registry/2.0", w.Header().Get("Docker-Distribution-API-Version"))
+		}
+	}
+
+	router, _ = servertest.NewRouter(t, common.Config{})
+	if w := servertest.Do(router, http.MethodGet, "/v2/", nil); w.Code != http.StatusNotFound {
+		t.Errorf("failed test - expected %d, but got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestComposeProjects(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{})
+	db := servertest.CreateContainerWithBody(t, router, `{"Image":"postgres:16","Labels":{"com.docker.compose.project":"demo","com.docker.compose.service":"db"}}`)
+	web := servertest.CreateContainerWithBody(t, router, `{"Image":"nginx:1.25","Labels":{"com.docker.compose.project":"demo","com.docker.compose.service":"web"}}`)
+	other := servertest.CreateContainerWithBody(t, router, `{"Image":"redis:7","Labels":{"com.docker.compose.project":"other","com.docker.compose.service":"cache"}}`)
+	if w := servertest.Do(router, http.MethodPost, "/networks/create", strings.NewReader(`{"Name":"demo_default","Labels":{"com.docker.compose.project":"demo"}}`)); w.Code != http.StatusCreated {
+		t.Fatalf("failed creating network - expected %d, but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+db+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+
+	tests := []struct {
+		method string
+		url    string
+		code   int
+		match  string
+	}{
+		{method: http.MethodGet, url: "/kubedock/projects", code: http.StatusOK, match: `{"Name":"demo","Services":["db","web"],"Containers":[`},
+		{method: http.MethodGet, url: "/kubedock/projects", code: http.StatusOK, match: `"Networks":["demo_default"],"Running":1}`},
+		{method: http.MethodGet, url: "/kubedock/projects", code: http.StatusOK, match: `{"Name":"other","Services":["cache"],"Containers":["` + other[:12] + `"],"Networks":[],"Running":0}`},
+		{method: http.MethodDelete, url: "/kubedock/projects/demo", code: http.StatusOK, match: `"Networks":["demo_default"]`},
+		{method: http.MethodGet, url: "/kubedock/projects", code: http.StatusOK, match: `[{"Name":"other"`},
+		{method: http.MethodGet, url: "/containers/" + db + "/json", code: http.StatusNotFound},
+		{method: http.MethodGet, url: "/containers/" + web + "/json", code: http.StatusNotFound},
+		{method: http.MethodGet, url: "/containers/" + other + "/json", code: http.StatusOK},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, tst.method, tst.url, nil)
+		if w.Code != tst.code || !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %d with %s, but got %d: %s", i, tst.code, tst.match, w.Code, w.Body.String())
+		}
+	}
+	if len(kub.DeletedProjects) != 1 || kub.DeletedProjects[0] != "demo" {
+		t.Errorf("failed test - expected %v, but got %v", []string{"demo"}, kub.DeletedProjects)
+	}
+}
+
+func TestContainerInspectBatch(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"batch-one"}`)
+	servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"batch-two"}`)
+
+	tests := []struct {
+		url   string
+		count int
+		match string
+	}{
+		{url: "/kubedock/containers/json?ids=" + id + ",batch-two&full=true", count: 2, match: `"Missing":[]`},
+		{url: "/kubedock/containers/json?ids=batch-one&ids=batch-none", count: 1, match: `"Missing":["batch-none"]`},
+		{url: "/kubedock/containers/json?ids=batch-one&full=true", count: 1, match: `"RestartCount":0`},
+	}
+
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodGet, tst.url, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, http.StatusOK, w.Code, w.Body.String())
+		}
+		res := struct{ Containers []map[string]interface{} }{}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if len(res.Containers) != tst.count {
+			t.Errorf("failed test %d - expected %d, but got %d", i, tst.count, len(res.Containers))
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
+
+func TestContainersCopy(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	src := servertest.CreateContainer(t, router)
+	dst := servertest.CreateContainer(t, router)
+	idle := servertest.CreateContainer(t, router)
+	for _, id := range []string{src, dst} {
+		if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+			t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "fixtures/data.csv", Mode: 0644, Size: 5})
+	tw.Write([]byte("1,2,3"))
+	tw.Close()
+	if w := servertest.Do(router, http.MethodPut, "/containers/"+src+"/archive?path=/tmp", buf); w.Code != http.StatusOK {
+		t.Fatalf("failed test - expected %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{body: `{"Source":"` + src + `","SourcePath":"/tmp/fixtures","Target":"` + dst + `","TargetPath":"/data"}`, code: http.StatusNoContent},
+		{body: `{"Source":"` + src + `","SourcePath":"/tmp/missing","Target":"` + dst + `","TargetPath":"/data"}`, code: http.StatusNotFound},
+		{body: `{"Source":"` + src + `","SourcePath":"/tmp/fixtures","Target":"` + idle + `","TargetPath":"/data"}`, code: http.StatusConflict},
+		{body: `{"Source":"unknown","SourcePath":"/tmp/fixtures","Target":"` + dst + `","TargetPath":"/data"}`, code: http.StatusNotFound},
+		{body: `{"Source":"` + src + `","Target":"` + dst + `"}`, code: http.StatusBadRequest},
+	}
+	for i, tst := range tests {
+		if w := servertest.Do(router, http.MethodPost, "/kubedock/containers/copy", strings.NewReader(tst.body)); w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+	}
+
+	w := servertest.Do(router, http.MethodGet, "/containers/"+dst+"/archive?path=/data/fixtures/data.csv", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed test - expected %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	tr := tar.NewReader(w.Body)
+	if _, err := tr.Next(); err != nil {
+		t.Fatalf("unexpected error reading archive: %s", err)
+	}
+	if dat, _ := io.ReadAll(tr); string(dat) != "1,2,3" {
+		t.Errorf("failed test - expected 1,2,3, but got %s", dat)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	if flag.Lookup("v") == nil {
+		klog.InitFlags(nil)
+	}
+	router, _ := servertest.NewRouter(t, common.Config{AdminToken: "secret", PortForward: true})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"nginx","ExposedPorts":{"80/tcp":{}},"HostConfig":{"PortBindings":{"80/tcp":[{"HostPort":"8080"}]}}}`)
+	servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil)
+
+	tests := []struct {
+		method string
+		url    string
+		token  string
+		body   string
+		code   int
+		match  string
+	}{
+		{method: http.MethodGet, url: "/kubedock/admin/sessions", token: "", code: http.StatusUnauthorized},
+		{method: http.MethodGet, url: "/kubedock/admin/sessions", token: "wrong", code: http.StatusUnauthorized},
+		{method: http.MethodGet, url: "/kubedock/admin/sessions", token: "secret", code: http.StatusOK, match: id},
+		{method: http.MethodPost, url: "/kubedock/admin/verbosity", token: "secret", body: `{"Verbosity":4}`, code: http.StatusOK},
+		{method: http.MethodGet, url: "/kubedock/admin/verbosity", token: "secret", code: http.StatusOK, match: `{"Verbosity":4}`},
+		{method: http.MethodPost, url: "/kubedock/admin/verbosity", token: "secret", body: `{"Verbosity":-1}`, code: http.StatusBadRequest},
+		{method: http.MethodGet, url: "/kubedock/admin/portforwards", token: "secret", code: http.StatusOK, match: `"LocalPort":8080`},
+	}
+	for i, tst := range tests {
+		w := servertest.DoWithToken(router, tst.method, tst.url, strings.NewReader(tst.body), tst.token)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+	flag.Set("v", "0")
+}
+
+func TestAdminDisabled(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	if w := servertest.Do(router, http.MethodGet, "/kubedock/admin/sessions", nil); w.Code != http.StatusNotFound {
+		t.Errorf("failed test - expected %d, but got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{Dashboard: true})
+	kub.Logs = []string{"started dashboard test"}
+	id := servertest.CreateContainer(t, router)
+	servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil)
+
+	tests := []struct {
+		url   string
+		code  int
+		match string
+	}{
+		{url: "/kubedock/dashboard", code: http.StatusOK, match: "<title>kubedock dashboard</title>"},
+		{url: "/kubedock/dashboard/containers", code: http.StatusOK, match: `"Id":"` + id + `"`},
+		{url: "/kubedock/dashboard/containers/" + id + "/logs", code: http.StatusOK, match: "started dashboard test"},
+		{url: "/kubedock/dashboard/containers/" + id + "/events", code: http.StatusOK, match: "[]"},
+		{url: "/kubedock/dashboard/containers/unknown/logs", code: http.StatusNotFound},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodGet, tst.url, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package kubedock
+
+// ImagesPrewarmRequest represents the json structure that is
+// used for the /kubedock/images/prewarm post endpoint.
+type ImagesPrewarmRequest struct {
+	Images []string `json:"Images"`
+}
+
+// AdminVerbosityRequest represents the json structure that is
+// used for the /kubedock/admin/verbosity post endpoint.
+type AdminVerbosityRequest struct {
+	Verbosity int `json:"Verbosity"`
+}
+
+// ContainersCopyRequest represents the json structure that is
+// used for the /kubedock/containers/copy post endpoint.
+type ContainersCopyRequest struct {
+	Source     string `json:"Source"`
+	SourcePath string `json:"SourcePath"`
+	Target     string `json:"Target"`
+	TargetPath string `json:"TargetPath"`
+}
+
+// Project represents the json structure that is used for the entries of the
+// /kubedock/projects endpoint.
+type Project struct {
+	Name       string   `json:"Name"`
+	Services   []string `json:"Services"`
+	Containers []string `json:"Containers"`
+	Networks   []string `json:"Networks"`
+	Running    int      `json:"Running"`
+}
@@ -31,40 +31,53 @@ func RegisterLibpodRoutes(router *gin.Engine, cr *common.ContextRouter) {
 
 	router.Use(LibpodHeadersMiddleware())
 
+	router.GET("/libpod/info", wrap(libpod.Info))
 	router.GET("/libpod/version", wrap(libpod.Version))
 	router.GET("/libpod/_ping", wrap(libpod.Ping))
 	router.HEAD("/libpod/_ping", wrap(libpod.Ping))
+	router.GET("/libpod/events", httputil.NoWriteTimeout, wrap(libpod.Events))
 
 	router.POST("/libpod/containers/create", wrap(libpod.ContainerCreate))
-	router.POST("/libpod/containers/:id/start", wrap(common.ContainerStart))
+	router.POST("/libpod/containers/:id/start", httputil.NoWriteTimeout, wrap(common.ContainerStart))
 	router.GET("/libpod/containers/:id/exists", wrap(libpod.ContainerExists))
-	router.POST("/libpod/containers/:id/attach", wrap(common.ContainerAttach))
-	router.POST("/libpod/containers/:id/stop", wrap(common.ContainerStop))
-	router.POST("/libpod/containers/:id/restart", wrap(common.ContainerRestart))
-	router.POST("/libpod/containers/:id/kill", wrap(common.ContainerKill))
-	router.POST("/libpod/containers/:id/wait", wrap(libpod.ContainerWait))
+	router.POST("/libpod/containers/:id/init", httputil.NoWriteTimeout, wrap(libpod.ContainerInit))
+	router.POST("/libpod/containers/:id/mount", wrap(libpod.ContainerMount))
+	router.POST("/libpod/containers/:id/unmount", wrap(libpod.ContainerUnmount))
+	router.POST("/libpod/containers/:id/attach", httputil.NoWriteTimeout, wrap(common.ContainerAttach))
+	router.POST("/libpod/containers/:id/stop", httputil.NoWriteTimeout, wrap(common.ContainerStop))
+	router.POST("/libpod/containers/:id/restart", httputil.NoWriteTimeout, wrap(common.ContainerRestart))
+	router.POST("/libpod/containers/:id/kill", httputil.NoWriteTimeout, wrap(common.ContainerKill))
+	router.POST("/libpod/containers/:id/wait", httputil.NoWriteTimeout, wrap(libpod.ContainerWait))
 	router.POST("/libpod/containers/:id/rename", wrap(common.ContainerRename))
 	router.POST("/libpod/containers/:id/resize", wrap(common.ContainerResize))
 	router.DELETE("/libpod/containers/:id", wrap(libpod.ContainerDelete))
-	router.GET("/libpod/containers/json", wrap(libpod.ContainerList))
+	router.GET("/libpod/containers/json", cr.Cache.Handler(), wrap(libpod.ContainerList))
+	router.GET("/libpod/containers/stats", httputil.NoWriteTimeout, wrap(libpod.ContainersStats))
 	router.GET("/libpod/containers/:id/json", wrap(libpod.ContainerInfo))
-	router.GET("/libpod/containers/:id/logs", wrap(common.ContainerLogs))
+	router.GET("/libpod/containers/:id/logs", httputil.NoWriteTimeout, wrap(common.ContainerLogs))
+	router.POST("/libpod/containers/:id/checkpoint", httputil.NoWriteTimeout, wrap(libpod.ContainerCheckpoint))
+	router.POST("/libpod/containers/:id/restore", httputil.NoWriteTimeout, wrap(libpod.ContainerRestore))
+	router.GET("/libpod/generate/:name/systemd", wrap(libpod.GenerateSystemd))
 
 	router.HEAD("/libpod/containers/:id/archive", wrap(common.HeadArchive))
 	router.GET("/libpod/containers/:id/archive", wrap(common.GetArchive))
 	router.PUT("/libpod/containers/:id/archive", wrap(common.PutArchive))
 
 	router.POST("/libpod/containers/:id/exec", wrap(common.ContainerExec))
-	router.POST("/libpod/exec/:id/start", wrap(common.ExecStart))
+	router.POST("/libpod/exec/:id/start", httputil.NoWriteTimeout, wrap(common.ExecStart))
 	router.GET("/libpod/exec/:id/json", wrap(common.ExecInfo))
 	router.POST("/libpod/exec/:id/resize", wrap(common.ExecResize))
 
-	router.POST("/libpod/images/pull", wrap(libpod.ImagePull))
-	router.GET("/libpod/images/json", wrap(common.ImageList))
-	router.GET("/libpod/images/:image/*json", wrap(common.ImageJSON))
+	router.POST("/libpod/networks/create", wrap(libpod.NetworkCreate))
+
+	router.GET("/libpod/volumes/json", wrap(libpod.VolumesList))
+
+	router.POST("/libpod/images/pull", httputil.NoWriteTimeout, wrap(libpod.ImagePull))
+	router.GET("/libpod/images/json", cr.Cache.Handler(), wrap(common.ImageList))
+	router.GET("/libpod/images/:image/*json", wrap(libpod.ImageGet))
+	router.DELETE("/libpod/images/*name", wrap(libpod.ImageDelete))
 
 	// not supported podman api at the moment
-	router.GET("/libpod/info", httputil.NotImplemented)
 	router.POST("/libpod/build", httputil.NotImplemented)
 	router.POST("/libpod/images/load", httputil.NotImplemented)
 }
@@ -0,0 +1,160 @@
+package libpod
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// ContainerCheckpoint - checkpoint a container. This is emulated by saving
+// the contents of the container's volumes, and removing the pod.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerCheckpointLibpod
+// POST "/libpod/containers/:id/checkpoint"
+func ContainerCheckpoint(cr *common.ContextRouter, c *gin.Context) {
+	id := c.Param("id")
+	tainr, err := cr.DB.GetContainerByNameOrID(id)
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+
+	if export, _ := strconv.ParseBool(c.Query("export")); export {
+		httputil.Error(c, http.StatusNotImplemented, fmt.Errorf("exporting checkpoints is not supported"))
+		return
+	}
+
+	if !tainr.Running {
+		httputil.Error(c, http.StatusConflict, fmt.Errorf("container %s is not running", tainr.ShortID))
+		return
+	}
+
+	start := time.Now()
+
+	checkpoint, err := saveCheckpoint(cr, tainr)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	stop := false
+	if leave, _ := strconv.ParseBool(c.Query("leaveRunning")); !leave {
+		deleted, err := cr.Backend.WatchDeleteContainer(tainr)
+		if err != nil {
+			klog.Warningf("error while watching k8s container delete: %s", err)
+		}
+
+		tainr.SignalDetach()
+		tainr.SignalStop()
+		if err := common.DeleteContainer(cr, tainr); err != nil {
+			klog.Warningf("error while deleting k8s container: %s", err)
+		}
+		common.StopLinkedContainers(cr, tainr)
+		stop = true
+
+		if deleted != nil {
+			<-deleted
+		}
+	}
+
+	var prev types.Checkpoint
+	tainr, err = cr.DB.UpdateContainer(tainr.ID, func(rec *types.Container) error {
+		prev = rec.Checkpoint
+		rec.Checkpoint = checkpoint
+		if stop {
+			rec.Running = false
+			rec.Completed = false
+			rec.Stopped = true
+		}
+		return nil
+	})
+	if err != nil {
+		checkpoint.Remove()
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	prev.Remove()
+
+	common.PublishContainerEvent(cr, tainr, events.Checkpoint)
+
+	c.JSON(http.StatusOK, gin.H{
+		"Id":              tainr.ID,
+		"RuntimeDuration": time.Since(start).Microseconds(),
+	})
+}
+
+// ContainerRestore - restore a checkpointed container. This is emulated by
+// starting a new pod, with the volumes contents that were saved during the
+// checkpoint.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerRestoreLibpod
+// POST "/libpod/containers/:id/restore"
+func ContainerRestore(cr *common.ContextRouter, c *gin.Context) {
+	id := c.Param("id")
+	tainr, err := cr.DB.GetContainerByNameOrID(id)
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+
+	if imp, _ := strconv.ParseBool(c.Query("import")); imp || c.Query("name") != "" {
+		httputil.Error(c, http.StatusNotImplemented, fmt.Errorf("importing checkpoints is not supported"))
+		return
+	}
+
+	if !tainr.HasCheckpoint() {
+		httputil.Error(c, http.StatusConflict, fmt.Errorf("container %s has not been checkpointed", tainr.ShortID))
+		return
+	}
+
+	if tainr.Running {
+		httputil.Error(c, http.StatusConflict, fmt.Errorf("container %s is running", tainr.ShortID))
+		return
+	}
+
+	start := time.Now()
+
+	if err := common.StartContainer(c.Request.Context(), cr, tainr); err != nil {
+		httputil.Error(c, common.StartErrorStatus(err), err)
+		return
+	}
+
+	common.PublishContainerEvent(cr, tainr, events.Restore)
+
+	c.JSON(http.StatusOK, gin.H{
+		"Id":              tainr.ID,
+		"RuntimeDuration": time.Since(start).Microseconds(),
+	})
+}
+
+// saveCheckpoint will save the contents of the volume folders of given
+// container to archives on disk, and returns the checkpoint that refers to
+// these archives.
+func saveCheckpoint(cr *common.ContextRouter, tainr *types.Container) (types.Checkpoint, error) {
+	checkpoint := types.Checkpoint{}
+	for dst := range tainr.GetVolumeFolders() {
+		file, err := os.CreateTemp("", "kubedock-checkpoint-")
+		if err != nil {
+			checkpoint.Remove()
+			return nil, err
+		}
+		checkpoint[dst] = file.Name()
+		err = cr.Backend.CopyFromContainer(tainr, dst, file)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			checkpoint.Remove()
+			return nil, err
+		}
+	}
+	return checkpoint, nil
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,12 +17,19 @@ import (
 	"github.com/joyrex2001/kubedock/internal/server/filter"
 	"github.com/joyrex2001/kubedock/internal/server/httputil"
 	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
 )
 
 // ContainerCreate - create a container.
 // https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerCreateLibpod
 // POST "/libpod/containers/create"
 func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
+	digest, err := common.GetCreateDigest(c)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
 	in := &ContainerCreateRequest{}
 	if err := json.NewDecoder(c.Request.Body).Decode(&in); err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
@@ -31,62 +40,126 @@ func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
 		in.Name = c.Query("name")
 	}
 
+	key := c.GetHeader(common.IdempotencyKeyHeader)
+	if tainr, err := common.FindCreatedContainer(cr, key, in.Name, digest); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	} else if tainr != nil {
+		klog.V(2).Infof("returning container %s for retried create request", tainr.ShortID)
+		c.JSON(http.StatusCreated, gin.H{
+			"Id":       tainr.ID,
+			"Warnings": []string{},
+		})
+		return
+	}
+
+	name, err := common.ContainerName(cr, "", in.Name)
+	if err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	in.Name = name
+
 	if in.Labels == nil {
 		in.Labels = map[string]string{}
 	}
 
-	if _, ok := in.Labels[types.LabelRunasUser]; !ok && cr.Config.RunasUser != "" {
-		in.Labels[types.LabelRunasUser] = cr.Config.RunasUser
+	cfg := cr.GetConfig()
+	if in.Env == nil {
+		in.Env = map[string]string{}
+	}
+	for _, e := range common.ApplyImageDefaults(cfg, in.Image, in.Labels) {
+		k, v, _ := strings.Cut(e, "=")
+		if _, ok := in.Env[k]; !ok {
+			in.Env[k] = v
+		}
+	}
+
+	if _, ok := in.Labels[types.LabelRunasUser]; !ok && cfg.RunasUser != "" {
+		in.Labels[types.LabelRunasUser] = cfg.RunasUser
 	}
 	if in.User != "" {
 		// The User defined in HTTP request takes precedence over the cli and label.
 		in.Labels[types.LabelRunasUser] = in.User
 	}
-	if _, ok := in.Labels[types.LabelNamePrefix]; !ok && cr.Config.NamePrefix != "" {
-		in.Labels[types.LabelNamePrefix] = cr.Config.NamePrefix
+	if _, ok := in.Labels[types.LabelFSGroup]; !ok && cfg.FSGroup != "" {
+		in.Labels[types.LabelFSGroup] = cfg.FSGroup
+	}
+	if _, ok := in.Labels[types.LabelNamePrefix]; !ok && cfg.NamePrefix != "" {
+		in.Labels[types.LabelNamePrefix] = cfg.NamePrefix
+	}
+	if _, ok := in.Labels[types.LabelRequestCPU]; !ok && cfg.RequestCPU != "" {
+		in.Labels[types.LabelRequestCPU] = cfg.RequestCPU
 	}
-	if _, ok := in.Labels[types.LabelRequestCPU]; !ok && cr.Config.RequestCPU != "" {
-		in.Labels[types.LabelRequestCPU] = cr.Config.RequestCPU
+	if _, ok := in.Labels[types.LabelRequestMemory]; !ok && cfg.RequestMemory != "" {
+		in.Labels[types.LabelRequestMemory] = cfg.RequestMemory
 	}
-	if _, ok := in.Labels[types.LabelRequestMemory]; !ok && cr.Config.RequestMemory != "" {
-		in.Labels[types.LabelRequestMemory] = cr.Config.RequestMemory
+	if _, ok := in.Labels[types.LabelPullPolicy]; !ok && cfg.PullPolicy != "" {
+		in.Labels[types.LabelPullPolicy] = cfg.PullPolicy
 	}
-	if _, ok := in.Labels[types.LabelPullPolicy]; !ok && cr.Config.PullPolicy != "" {
-		in.Labels[types.LabelPullPolicy] = cr.Config.PullPolicy
+	if _, ok := in.Labels[types.LabelNodeSelector]; !ok && cfg.NodeSelector != "" {
+		in.Labels[types.LabelNodeSelector] = cfg.NodeSelector
 	}
-	if _, ok := in.Labels[types.LabelNodeSelector]; !ok && cr.Config.NodeSelector != "" {
-		in.Labels[types.LabelNodeSelector] = cr.Config.NodeSelector
+	if _, ok := in.Labels[types.LabelReadiness]; !ok && cfg.Readiness != "" {
+		in.Labels[types.LabelReadiness] = cfg.Readiness
 	}
-	if _, ok := in.Labels[types.LabelActiveDeadlineSeconds]; !ok && cr.Config.ActiveDeadlineSeconds >= 0 {
-		in.Labels[types.LabelActiveDeadlineSeconds] = fmt.Sprintf("%d", cr.Config.ActiveDeadlineSeconds)
+	if _, ok := in.Labels[types.LabelSeparateStderr]; !ok && cfg.SeparateStderr {
+		in.Labels[types.LabelSeparateStderr] = "true"
 	}
-	in.Labels[types.LabelServiceAccount] = cr.Config.ServiceAccount
+	if _, ok := in.Labels[types.LabelActiveDeadlineSeconds]; !ok && cfg.ActiveDeadlineSeconds >= 0 {
+		in.Labels[types.LabelActiveDeadlineSeconds] = fmt.Sprintf("%d", cfg.ActiveDeadlineSeconds)
+	}
+	in.Labels[types.LabelServiceAccount] = cfg.ServiceAccount
 
-	env := []string{}
-	for k, v := range in.Env {
-		env = append(env, k+"="+v)
+	sysctls, swarns := common.GetSysctls(cr, in.Sysctl)
+	warnings := append(getCreateWarnings(in), swarns...)
+	limits := []ulimit.Limit{}
+	for _, l := range in.Rlimits {
+		limits = append(limits, ulimit.Limit{Name: l.Type, Soft: l.Soft, Hard: l.Hard})
+	}
+	limits, uwarns := common.GetUlimits(limits)
+	warnings = append(warnings, uwarns...)
+	devices := []types.Device{}
+	for _, d := range in.Devices {
+		src, dst, _ := strings.Cut(d.Path, ":")
+		dst, _, _ = strings.Cut(dst, ":")
+		devices = append(devices, types.Device{HostPath: src, ContainerPath: dst})
+	}
+	devices, dwarns := common.GetDevices(cr, devices)
+	warnings = append(warnings, dwarns...)
+	if err := common.ValidateCreate(cr, warnings); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
 	}
 
-	tainr := &types.Container{
-		Name:         in.Name,
-		Image:        in.Image,
-		Entrypoint:   in.Entrypoint,
-		Cmd:          in.Command,
-		Env:          env,
-		Binds:        []string{},
-		ExposedPorts: map[string]interface{}{},
-		ImagePorts:   map[string]interface{}{},
-		Labels:       in.Labels,
-		Tty:          in.Terminal,
-		OpenStdin:    in.Stdin,
-	}
-
-	if img, err := cr.DB.GetImageByNameOrID(in.Image); err != nil {
+	img, err := cr.DB.GetImageByNameOrID(in.Image)
+	if err != nil {
 		klog.Warningf("unable to fetch image details: %s", err)
-	} else {
-		for pp := range img.ExposedPorts {
-			tainr.ImagePorts[pp] = pp
-		}
+		img = &types.Image{}
+	}
+
+	tainr := &types.Container{
+		Name:           in.Name,
+		Image:          in.Image,
+		Entrypoint:     in.Entrypoint,
+		Cmd:            in.Command,
+		Env:            getContainerEnv(in, img.Env),
+		SecretEnv:      in.SecretEnv,
+		Binds:          []string{},
+		ExposedPorts:   map[string]interface{}{},
+		ImagePorts:     map[string]interface{}{},
+		Labels:         in.Labels,
+		Tty:            in.Terminal,
+		OpenStdin:      in.Stdin,
+		Sysctls:        sysctls,
+		Ulimits:        limits,
+		Devices:        devices,
+		IdempotencyKey: key,
+		CreateDigest:   digest,
+	}
+
+	for pp := range img.ExposedPorts {
+		tainr.ImagePorts[pp] = pp
 	}
 
 	for _, mapping := range in.PortMappings {
@@ -102,29 +175,70 @@ func ContainerCreate(cr *common.ContextRouter, c *gin.Context) {
 	addNetworkAliases(tainr, in.Network)
 
 	for _, mount := range in.Mounts {
-		tainr.Binds = append(tainr.Binds, mount.Source+":"+mount.Destination)
+		bind := mount.Source + ":" + mount.Destination
+		if len(mount.Options) > 0 {
+			bind += ":" + strings.Join(mount.Options, ",")
+		}
+		tainr.Binds = append(tainr.Binds, bind)
 	}
 
-	netw, err := cr.DB.GetNetworkByName("bridge")
-	if err != nil {
-		httputil.Error(c, http.StatusInternalServerError, err)
+	if in.Netns.NSMode == "container" {
+		if err := common.LinkContainer(cr, tainr, in.Netns.Value); err != nil {
+			httputil.Error(c, http.StatusNotFound, err)
+			return
+		}
+	} else if in.Netns.NSMode == "host" {
+		if err := common.UseHostNetwork(cr, tainr); err != nil {
+			httputil.Error(c, http.StatusBadRequest, err)
+			return
+		}
+	} else {
+		netw, err := cr.DB.GetNetworkByName("bridge")
+		if err != nil {
+			httputil.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		tainr.ConnectNetwork(netw.ID)
+	}
+
+	if err := common.ApplyStartTimeout(c, tainr); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
 		return
 	}
-	tainr.ConnectNetwork(netw.ID)
 
-	if err := cr.DB.SaveContainer(tainr); err != nil {
+	if cur, err := common.SaveCreatedContainer(cr, tainr); err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
+	} else if cur != tainr {
+		klog.V(2).Infof("returning container %s for concurrently retried create request", cur.ShortID)
+		c.JSON(http.StatusCreated, gin.H{
+			"Id":       cur.ID,
+			"Warnings": []string{},
+		})
+		return
 	}
 
-	cr.Events.Publish(tainr.ID, events.Container, events.Create)
+	common.PublishContainerEvent(cr, tainr, events.Create)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"Id":       tainr.ID,
-		"Warnings": []string{},
+		"Warnings": warnings,
 	})
 }
 
+// getCreateWarnings will return warnings for all features in the given
+// create request that are not supported and would otherwise be ignored.
+func getCreateWarnings(in *ContainerCreateRequest) []string {
+	warnings := []string{}
+	if in.CgroupParent != "" {
+		warnings = append(warnings, "cgroup parent is not supported and is ignored")
+	}
+	if in.Userns.NSMode != "" && in.Userns.NSMode != "host" {
+		warnings = append(warnings, fmt.Sprintf("user namespace mode '%s' is not supported and is ignored", in.Userns.NSMode))
+	}
+	return warnings
+}
+
 // ContainerWait - Block until a container stops, then returns the exit code.
 // https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerWaitLibpod
 // POST "/libpod/containers/:id/wait"
@@ -138,12 +252,16 @@ func ContainerWait(cr *common.ContextRouter, c *gin.Context) {
 		case <-ticker.C:
 			tainr, err := cr.DB.GetContainerByNameOrID(id)
 			if err == nil {
-				common.UpdateContainerStatus(cr, tainr)
+				tainr = common.UpdateContainerStatus(cr, tainr)
 			}
-			if err != nil || tainr.Stopped || tainr.Killed || tainr.Completed {
+			if err != nil {
 				c.Data(http.StatusOK, "application/json", []byte("0"))
 				return
 			}
+			if tainr.Stopped || tainr.Killed || tainr.Completed || tainr.HasExited() {
+				c.JSON(http.StatusOK, tainr.ExitCode())
+				return
+			}
 		}
 	}
 }
@@ -163,10 +281,15 @@ func ContainerDelete(cr *common.ContextRouter, c *gin.Context) {
 	tainr.SignalStop()
 
 	if !tainr.Stopped && !tainr.Killed {
-		if err := cr.Backend.DeleteContainer(tainr); err != nil {
+		if err := common.DeleteContainer(cr, tainr); err != nil {
 			klog.Warningf("error while deleting k8s container: %s", err)
 		}
-		cr.Events.Publish(tainr.ID, events.Container, events.Die)
+		common.StopLinkedContainers(cr, tainr)
+		common.PublishContainerEvent(cr, tainr, events.Die)
+	}
+
+	if err := cr.Backend.DeleteContainerVolumes(tainr); err != nil {
+		klog.Warningf("error while deleting volumes of container: %s", err)
 	}
 
 	if err := cr.DB.DeleteContainer(tainr); err != nil {
@@ -178,9 +301,63 @@ func ContainerDelete(cr *common.ContextRouter, c *gin.Context) {
 		return
 	}
 
+	common.PublishContainerEvent(cr, tainr, events.Destroy)
+
 	c.JSON(http.StatusOK, []gin.H{})
 }
 
+// getContainerEnv will return the environment variables of the container
+// as specified in the create request. This includes the environment of the
+// host (env_host), variables that are merged with the environment of the
+// image (envmerge) and unsets variables as requested (unsetenv).
+func getContainerEnv(in *ContainerCreateRequest, imgEnv []string) []string {
+	vars := map[string]string{}
+	parse := func(env []string) map[string]string {
+		res := map[string]string{}
+		for _, e := range env {
+			if key, value, found := strings.Cut(e, "="); found {
+				res[key] = value
+			}
+		}
+		return res
+	}
+
+	if in.EnvHost {
+		vars = parse(os.Environ())
+	}
+	for k, v := range in.Env {
+		vars[k] = v
+	}
+
+	imgVars := parse(imgEnv)
+	for _, e := range in.EnvMerge {
+		k, v, _ := strings.Cut(e, "=")
+		vars[k] = os.Expand(v, func(key string) string {
+			if v, ok := vars[key]; ok {
+				return v
+			}
+			return imgVars[key]
+		})
+	}
+
+	for _, k := range in.UnsetEnv {
+		delete(vars, k)
+		if _, ok := imgVars[k]; ok {
+			// variables of the image can't be removed from the pod, the
+			// closest approximation is to make them empty.
+			klog.Warningf("unable to unset env %s of the image, setting it empty instead", k)
+			vars[k] = ""
+		}
+	}
+
+	env := []string{}
+	for k, v := range vars {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
 // ContainerExists - Check if container exists.
 // https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerExistsLibpod
 // GET "/libpod/containers/:id/exists"
@@ -216,7 +393,7 @@ func ContainerList(cr *common.ContextRouter, c *gin.Context) {
 		klog.V(5).Infof("unsupported filter: %s", err)
 	}
 
-	tainrs, err := cr.DB.GetContainers()
+	tainrs, err := common.FilterContainers(cr, filtr)
 	if err != nil {
 		httputil.Error(c, http.StatusInternalServerError, err)
 		return
@@ -224,9 +401,7 @@ func ContainerList(cr *common.ContextRouter, c *gin.Context) {
 
 	res := []gin.H{}
 	for _, tainr := range tainrs {
-		if filtr.Match(tainr) {
-			res = append(res, getContainerInfo(cr, tainr, false))
-		}
+		res = append(res, getContainerInfo(cr, tainr, false))
 	}
 	c.JSON(http.StatusOK, res)
 }
@@ -234,19 +409,11 @@ func ContainerList(cr *common.ContextRouter, c *gin.Context) {
 // getContainerInfo will return a gin.H containing the details of the
 // given container.
 func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail bool) gin.H {
-	errstr := ""
-	netws, err := cr.DB.GetNetworksByIDs(tainr.Networks)
+	errstr := tainr.Error
+	netdtl, err := common.GetNetworkEndpoints(cr, tainr)
 	if err != nil {
 		errstr += err.Error()
 	}
-	netdtl := gin.H{}
-	for _, netw := range netws {
-		netdtl[netw.Name] = gin.H{
-			"NetworkID": netw.ID,
-			"Aliases":   tainr.NetworkAliases,
-			"IPAddress": "127.0.0.1",
-		}
-	}
 	names := getContainerNames(tainr)
 	res := gin.H{
 		"Id":    tainr.ID,
@@ -263,7 +430,7 @@ func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail b
 		"Ports": getContainerInfoPorts(cr, tainr),
 		"Names": names,
 	}
-	common.UpdateContainerStatus(cr, tainr)
+	tainr = common.UpdateContainerStatus(cr, tainr)
 	if detail {
 		res["State"] = gin.H{
 			"Health": gin.H{
@@ -274,12 +441,13 @@ func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail b
 			"Paused":     false,
 			"Restarting": false,
 			"OOMKilled":  false,
-			"Dead":       tainr.Failed,
-			"StartedAt":  tainr.Created.Format("2006-01-02T15:04:05Z"),
+			"Dead":       tainr.Failed && !tainr.HasExited(),
+			"StartedAt":  tainr.Started.Format("2006-01-02T15:04:05Z"),
 			"FinishedAt": tainr.Finished.Format("2006-01-02T15:04:05Z"),
-			"ExitCode":   0,
+			"ExitCode":   tainr.ExitCode(),
 			"Error":      errstr,
 		}
+		res["Kubedock"] = common.GetKubedockInfo(tainr)
 		res["Config"] = gin.H{
 			"Image":  tainr.Image,
 			"Labels": tainr.Labels,
@@ -288,10 +456,37 @@ func getContainerInfo(cr *common.ContextRouter, tainr *types.Container, detail b
 			"Tty":    false,
 		}
 	} else {
+		networks := []string{}
+		for name := range netdtl {
+			networks = append(networks, name)
+		}
+		sort.Strings(networks)
+		mounts := []string{}
+		for _, m := range tainr.Mounts {
+			mounts = append(mounts, m.Target)
+		}
+		exited := tainr.Completed || tainr.Failed || tainr.Killed || tainr.Stopped
+		exitedAt := int64(0)
+		if exited && !tainr.Finished.IsZero() {
+			exitedAt = tainr.Finished.Unix()
+		}
+		podName, startedAt := "", int64(0)
+		if !tainr.Started.IsZero() {
+			podName = tainr.GetPodName()
+			startedAt = tainr.Started.Unix()
+		}
 		res["Created"] = tainr.Created.Format("2006-01-02T15:04:05Z")
 		res["Labels"] = tainr.Labels
-		res["State"] = tainr.StatusString()
-		res["Status"] = tainr.StateString()
+		res["State"] = tainr.StateString()
+		res["Status"] = tainr.StatusDescription()
+		res["Pod"] = podName
+		res["PodName"] = podName
+		res["Networks"] = networks
+		res["Mounts"] = mounts
+		res["ExitCode"] = tainr.ExitCode()
+		res["Exited"] = exited
+		res["ExitedAt"] = exitedAt
+		res["StartedAt"] = startedAt
 	}
 	return res
 }
@@ -356,7 +551,8 @@ func getContainerInfoPorts(cr *common.ContextRouter, tainr *types.Container) []m
 				"host_ip":        tainr.HostIP,
 				"host_port":      src,
 				"container_port": dst,
-				"protocol":       "TCP",
+				"range":          1,
+				"protocol":       "tcp",
 			})
 			done[src] = 1
 		}
@@ -391,16 +587,7 @@ func getAvailablePorts(cr *common.ContextRouter, tainr *types.Container) map[int
 // addNetworkAliases will add the networkaliases as defined in the provided
 // NetworksProperty to the container.
 func addNetworkAliases(tainr *types.Container, networks map[string]NetworksProperty) {
-	aliases := []string{}
-	done := map[string]string{tainr.ShortID: tainr.ShortID}
 	for _, netwp := range networks {
-		for _, a := range netwp.Aliases {
-			if _, ok := done[a]; !ok {
-				alias := strings.ToLower(a)
-				aliases = append(aliases, alias)
-				done[alias] = alias
-			}
-		}
+		tainr.AddNetworkAliases("", netwp.Aliases)
 	}
-	tainr.NetworkAliases = aliases
 }
@@ -0,0 +1,131 @@
+package libpod
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/pkg/backend/fake"
+)
+
+func TestGetContainerInfoPorts(t *testing.T) {
+	tests := []struct {
+		tainr *types.Container
+		out   []map[string]interface{}
+	}{
+		{
+			tainr: &types.Container{
+				HostIP:      "",
+				MappedPorts: map[int]int{303: 101},
+			},
+			out: []map[string]interface{}{},
+		},
+		{
+			tainr: &types.Container{
+				HostIP:      "127.0.0.1",
+				MappedPorts: map[int]int{303: 101},
+			},
+			out: []map[string]interface{}{
+				{"host_ip": "127.0.0.1", "host_port": 303, "container_port": 101, "range": 1, "protocol": "tcp"},
+			},
+		},
+		{
+			tainr: &types.Container{
+				HostIP:      "127.0.0.1",
+				MappedPorts: map[int]int{303: 101},
+				HostPorts:   map[int]int{303: 101, -1: 102},
+			},
+			out: []map[string]interface{}{
+				{"host_ip": "127.0.0.1", "host_port": 303, "container_port": 101, "range": 1, "protocol": "tcp"},
+			},
+		},
+	}
+	for i, tst := range tests {
+		cr := &common.ContextRouter{Config: common.Config{PortForward: true}}
+		res := getContainerInfoPorts(cr, tst.tainr)
+		if !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestGetContainerInfoStarted(t *testing.T) {
+	started := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tainr   *types.Container
+		pod     string
+		started int64
+		state   string
+	}{
+		{
+			tainr:   &types.Container{ID: "1234567890abcdef", Created: started.Add(-time.Hour), PodName: "tr909"},
+			pod:     "",
+			started: 0,
+			state:   "0001-01-01T00:00:00Z",
+		},
+		{
+			tainr:   &types.Container{ID: "1234567890abcdef", Created: started.Add(-time.Hour), Started: started, PodName: "tr909"},
+			pod:     "tr909",
+			started: started.Unix(),
+			state:   "2024-03-01T10:00:00Z",
+		},
+	}
+	cr, _ := common.NewContextRouter(adapter.Internal(fake.New()), common.Config{})
+	for i, tst := range tests {
+		res := getContainerInfo(cr, tst.tainr, false)
+		if res["Pod"] != tst.pod || res["PodName"] != tst.pod {
+			t.Errorf("failed test %d - expected pod %s, but got %v/%v", i, tst.pod, res["Pod"], res["PodName"])
+		}
+		if res["StartedAt"] != tst.started {
+			t.Errorf("failed test %d - expected started %d, but got %v", i, tst.started, res["StartedAt"])
+		}
+		res = getContainerInfo(cr, tst.tainr, true)
+		state := res["State"].(gin.H)
+		if state["StartedAt"] != tst.state {
+			t.Errorf("failed test %d - expected state started %s, but got %v", i, tst.state, state["StartedAt"])
+		}
+	}
+}
+
+func TestGetContainerEnv(t *testing.T) {
+	t.Setenv("KUBEDOCK_TEST_HOST", "nemesis")
+	tests := []struct {
+		in     *ContainerCreateRequest
+		imgEnv []string
+		out    []string
+	}{
+		{
+			in:  &ContainerCreateRequest{Env: map[string]string{"B": "2", "A": "1"}},
+			out: []string{"A=1", "B=2"},
+		},
+		{
+			in:     &ContainerCreateRequest{EnvMerge: []string{"PATH=/opt/bin:${PATH}", "HOME=$USER_HOME"}, Env: map[string]string{"USER_HOME": "/home/msx"}},
+			imgEnv: []string{"PATH=/usr/bin"},
+			out:    []string{"HOME=/home/msx", "PATH=/opt/bin:/usr/bin", "USER_HOME=/home/msx"},
+		},
+		{
+			in:     &ContainerCreateRequest{Env: map[string]string{"A": "1", "B": "2"}, UnsetEnv: []string{"A", "LANG"}},
+			imgEnv: []string{"LANG=C"},
+			out:    []string{"B=2", "LANG="},
+		},
+	}
+	for i, tst := range tests {
+		if res := getContainerEnv(tst.in, tst.imgEnv); !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+
+	res := getContainerEnv(&ContainerCreateRequest{EnvHost: true}, nil)
+	found := false
+	for _, e := range res {
+		found = found || e == "KUBEDOCK_TEST_HOST=nemesis"
+	}
+	if !found {
+		t.Errorf("failed - expected host env to be included, but got %v", res)
+	}
+}
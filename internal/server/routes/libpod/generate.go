@@ -0,0 +1,139 @@
+package libpod
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// systemdOptions contains the options to generate a systemd unit file.
+type systemdOptions struct {
+	URL           string
+	UseName       bool
+	NoHeader      bool
+	RestartPolicy string
+	RestartSec    int
+	StopTimeout   int
+	Prefix        string
+	Separator     string
+	After         []string
+	Requires      []string
+	Wants         []string
+}
+
+// restartPolicies contains the supported systemd restart policies.
+var restartPolicies = map[string]bool{
+	"no":          true,
+	"on-success":  true,
+	"on-failure":  true,
+	"on-abnormal": true,
+	"on-watchdog": true,
+	"on-abort":    true,
+	"always":      true,
+}
+
+// GenerateSystemd - generate systemd unit files for a container.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/GenerateSystemdLibpod
+// GET "/libpod/generate/:name/systemd"
+func GenerateSystemd(cr *common.ContextRouter, c *gin.Context) {
+	name := c.Param("name")
+	tainr, err := cr.DB.GetContainerByNameOrID(name)
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+
+	if n, _ := strconv.ParseBool(c.Query("new")); n {
+		httputil.Error(c, http.StatusNotImplemented, fmt.Errorf("generating units that create new containers is not supported"))
+		return
+	}
+
+	opts := systemdOptions{
+		URL:           getKubedockURL(cr, c),
+		RestartPolicy: c.DefaultQuery("restartPolicy", "on-failure"),
+		StopTimeout:   10,
+		Prefix:        c.DefaultQuery("containerPrefix", "container"),
+		Separator:     c.DefaultQuery("separator", "-"),
+		After:         c.QueryArray("after"),
+		Requires:      c.QueryArray("requires"),
+		Wants:         c.QueryArray("wants"),
+	}
+	opts.UseName, _ = strconv.ParseBool(c.Query("useName"))
+	opts.NoHeader, _ = strconv.ParseBool(c.Query("noHeader"))
+	if !restartPolicies[opts.RestartPolicy] {
+		httputil.Error(c, http.StatusBadRequest, fmt.Errorf("invalid restart policy: %s", opts.RestartPolicy))
+		return
+	}
+	if v := c.Query("restartSec"); v != "" {
+		if opts.RestartSec, err = strconv.Atoi(v); err != nil {
+			httputil.Error(c, http.StatusBadRequest, fmt.Errorf("invalid restartSec: %s", v))
+			return
+		}
+	}
+	if v := c.Query("stopTimeout"); v != "" {
+		if opts.StopTimeout, err = strconv.Atoi(v); err != nil {
+			httputil.Error(c, http.StatusBadRequest, fmt.Errorf("invalid stopTimeout: %s", v))
+			return
+		}
+	}
+
+	unit, content := generateSystemdUnit(tainr, opts)
+	c.JSON(http.StatusOK, gin.H{unit: content})
+}
+
+// getKubedockURL will return the url that podman clients can use to
+// connect to this kubedock instance, based on the given request.
+func getKubedockURL(cr *common.ContextRouter, c *gin.Context) string {
+	if cr.Config.Socket != "" && (c.Request.RemoteAddr == "" || c.Request.RemoteAddr == "@") {
+		return "unix://" + cr.Config.Socket
+	}
+	return "tcp://" + httputil.ExternalHost(c.Request, cr.Config.TrustForwardedHeaders)
+}
+
+// generateSystemdUnit will return the name and contents of a systemd unit
+// file that starts and stops the given container via podman remote. The
+// service attaches to the container, so it runs as long as the container
+// does, and the restart policy applies when the container exits.
+func generateSystemdUnit(tainr *types.Container, opts systemdOptions) (string, string) {
+	ref := tainr.ID
+	if opts.UseName && tainr.Name != "" {
+		ref = tainr.Name
+	}
+	unit := opts.Prefix + opts.Separator + ref + ".service"
+	podman := fmt.Sprintf("/usr/bin/podman --url %s", opts.URL)
+
+	var b strings.Builder
+	if !opts.NoHeader {
+		fmt.Fprintf(&b, "# %s\n", unit)
+		fmt.Fprintf(&b, "# autogenerated by kubedock\n\n")
+	}
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=Kubedock %s\n", unit)
+	fmt.Fprintf(&b, "Documentation=https://github.com/joyrex2001/kubedock\n")
+	fmt.Fprintf(&b, "Wants=%s\n", strings.Join(append([]string{"network-online.target"}, opts.Wants...), " "))
+	fmt.Fprintf(&b, "After=%s\n", strings.Join(append([]string{"network-online.target"}, opts.After...), " "))
+	if len(opts.Requires) > 0 {
+		fmt.Fprintf(&b, "Requires=%s\n", strings.Join(opts.Requires, " "))
+	}
+	fmt.Fprintf(&b, "\n[Service]\n")
+	fmt.Fprintf(&b, "Environment=PODMAN_SYSTEMD_UNIT=%%n\n")
+	fmt.Fprintf(&b, "Type=simple\n")
+	fmt.Fprintf(&b, "Restart=%s\n", opts.RestartPolicy)
+	if opts.RestartSec > 0 {
+		fmt.Fprintf(&b, "RestartSec=%d\n", opts.RestartSec)
+	}
+	fmt.Fprintf(&b, "TimeoutStopSec=%d\n", opts.StopTimeout+60)
+	fmt.Fprintf(&b, "ExecStart=%s start --attach %s\n", podman, ref)
+	fmt.Fprintf(&b, "ExecStop=%s stop -t %d %s\n", podman, opts.StopTimeout, ref)
+	fmt.Fprintf(&b, "\n[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=default.target\n")
+
+	return unit, b.String()
+}
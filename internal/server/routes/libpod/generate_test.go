@@ -0,0 +1,59 @@
+package libpod
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+)
+
+func TestGenerateSystemdUnit(t *testing.T) {
+	tainr := &types.Container{ID: "abc123", Name: "redis"}
+	tests := []struct {
+		opts     systemdOptions
+		unit     string
+		contains []string
+		missing  []string
+	}{
+		{
+			opts: systemdOptions{URL: "tcp://localhost:2475", RestartPolicy: "on-failure", StopTimeout: 10, Prefix: "container", Separator: "-"},
+			unit: "container-abc123.service",
+			contains: []string{
+				"# container-abc123.service",
+				"Type=simple\n",
+				"Restart=on-failure",
+				"ExecStart=/usr/bin/podman --url tcp://localhost:2475 start --attach abc123\n",
+				"ExecStop=/usr/bin/podman --url tcp://localhost:2475 stop -t 10 abc123\n",
+			},
+			missing: []string{"RestartSec=", "Requires=", "Type=oneshot", "RemainAfterExit="},
+		},
+		{
+			opts: systemdOptions{URL: "unix:///tmp/kubedock.sock", UseName: true, NoHeader: true, RestartPolicy: "always", RestartSec: 5, StopTimeout: 3, Prefix: "kd", Separator: "_", Requires: []string{"db.service"}},
+			unit: "kd_redis.service",
+			contains: []string{
+				"RestartSec=5",
+				"Requires=db.service",
+				"Restart=always",
+				"ExecStart=/usr/bin/podman --url unix:///tmp/kubedock.sock start --attach redis\n",
+				"ExecStop=/usr/bin/podman --url unix:///tmp/kubedock.sock stop -t 3 redis\n",
+			},
+			missing: []string{"# kd_redis.service"},
+		},
+	}
+	for i, tst := range tests {
+		unit, content := generateSystemdUnit(tainr, tst.opts)
+		if unit != tst.unit {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.unit, unit)
+		}
+		for _, s := range tst.contains {
+			if !strings.Contains(content, s) {
+				t.Errorf("failed test %d - expected %s in unit, but got %s", i, s, content)
+			}
+		}
+		for _, s := range tst.missing {
+			if strings.Contains(content, s) {
+				t.Errorf("failed test %d - unexpected %s in unit %s", i, s, content)
+			}
+		}
+	}
+}
@@ -2,6 +2,8 @@ package libpod
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -17,13 +19,9 @@ import (
 func ImagePull(cr *common.ContextRouter, c *gin.Context) {
 	from := c.Query("reference")
 	img := &types.Image{Name: from}
-	if cr.Config.Inspector {
-		pts, err := cr.Backend.GetImageExposedPorts(from)
-		if err != nil {
-			httputil.Error(c, http.StatusInternalServerError, err)
-			return
-		}
-		img.ExposedPorts = pts
+	if err := common.InspectImage(cr, img); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
 	}
 
 	if err := cr.DB.SaveImage(img); err != nil {
@@ -31,9 +29,52 @@ func ImagePull(cr *common.ContextRouter, c *gin.Context) {
 		return
 	}
 
-	cr.Events.Publish(from, events.Image, events.Pull)
+	cr.Events.PublishWithAttributes(from, events.Image, events.Pull, map[string]string{"name": from})
 
 	c.JSON(http.StatusOK, gin.H{
 		"Id": img.ID,
 	})
 }
+
+// ImageGet - dispatch the image inspect and exists requests, which can't be
+// registered as separate routes, as image names may contain slashes.
+// GET "/libpod/images/:image/json"
+// GET "/libpod/images/:image/exists"
+func ImageGet(cr *common.ContextRouter, c *gin.Context) {
+	if strings.HasSuffix(c.Param("json"), "/exists") {
+		ImageExists(cr, c)
+		return
+	}
+	common.ImageJSON(cr, c)
+}
+
+// ImageExists - check if an image exists in local store.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/images/operation/ImageExistsLibpod
+// GET "/libpod/images/:image/exists"
+func ImageExists(cr *common.ContextRouter, c *gin.Context) {
+	name := strings.TrimSuffix(c.Param("image")+c.Param("json"), "/exists")
+	if _, err := common.GetImage(cr, name); err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// ImageDelete - remove an image from the local storage.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/images/operation/ImageDeleteLibpod
+// DELETE "/libpod/images/:name"
+func ImageDelete(cr *common.ContextRouter, c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	force, _ := strconv.ParseBool(c.Query("force"))
+	img, status, err := common.RemoveImage(cr, name, force)
+	if err != nil {
+		httputil.Error(c, status, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"Untagged": []string{img.Name},
+		"Deleted":  []string{img.ID},
+		"Errors":   []string{},
+		"ExitCode": 0,
+	})
+}
@@ -0,0 +1,79 @@
+package libpod
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// ContainerInit - initialize a container, without starting it. As the pod
+// of a container is created when it is started, this only validates that
+// the network owner of a linked container still exists, and marks the
+// container as initialized.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerInitLibpod
+// POST "/libpod/containers/:id/init"
+func ContainerInit(cr *common.ContextRouter, c *gin.Context) {
+	id := c.Param("id")
+	tainr, err := cr.DB.GetContainerByNameOrID(id)
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	if tainr.Running || tainr.Initialized {
+		c.Writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if tainr.IsLinked() {
+		if _, err := cr.DB.GetContainer(tainr.NetworkOwner); err != nil {
+			httputil.Error(c, http.StatusInternalServerError, fmt.Errorf("cannot join network of container %s: %w", tainr.NetworkOwner, err))
+			return
+		}
+	}
+	if _, err := cr.DB.UpdateContainer(tainr.ID, func(tainr *types.Container) error {
+		tainr.Initialized = true
+		return nil
+	}); err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	common.PublishContainerEvent(cr, tainr, events.Init)
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// ContainerMount - mount the filesystem of a container on the host. This is
+// not possible, as the container runs in a pod in the cluster; the archive
+// endpoints (e.g. podman cp) can be used to access its filesystem instead.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerMountLibpod
+// POST "/libpod/containers/:id/mount"
+func ContainerMount(cr *common.ContextRouter, c *gin.Context) {
+	id := c.Param("id")
+	tainr, err := cr.DB.GetContainerByNameOrID(id)
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"cause":    "not supported by kubedock",
+		"message":  fmt.Sprintf("cannot mount container %s: its filesystem is not available on the host, use the archive api (podman cp) instead", tainr.ShortID),
+		"response": http.StatusInternalServerError,
+	})
+}
+
+// ContainerUnmount - unmount the filesystem of a container. As containers
+// are never mounted, this is a no-op.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainerUnmountLibpod
+// POST "/libpod/containers/:id/unmount"
+func ContainerUnmount(cr *common.ContextRouter, c *gin.Context) {
+	id := c.Param("id")
+	if _, err := cr.DB.GetContainerByNameOrID(id); err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package libpod
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// NetworkCreate - create a network.
+// https://docs.podman.io/en/latest/_static/api.html#tag/networks/operation/NetworkCreateLibpod
+// POST "/libpod/networks/create"
+func NetworkCreate(cr *common.ContextRouter, c *gin.Context) {
+	in := &NetworkCreateRequest{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&in); err != nil {
+		httputil.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	subnet, gateway := "", ""
+	if len(in.Subnets) > 0 {
+		subnet, gateway = in.Subnets[0].Subnet, in.Subnets[0].Gateway
+	}
+	netw, _, err := common.CreateNetwork(cr, in.Name, in.Labels, subnet, gateway, !in.Ignore)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, getNetwork(netw))
+}
+
+// getNetwork will return the given network in the format of the libpod
+// network api.
+func getNetwork(netw *types.Network) gin.H {
+	subnets := []gin.H{}
+	if netw.Subnet != "" {
+		gw, _ := common.GetNetworkGateway(netw)
+		subnets = append(subnets, gin.H{"subnet": netw.Subnet, "gateway": gw})
+	}
+	labels := netw.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return gin.H{
+		"name":         netw.Name,
+		"id":           netw.ID,
+		"driver":       "bridge",
+		"created":      netw.Created,
+		"subnets":      subnets,
+		"ipv6_enabled": false,
+		"internal":     false,
+		"dns_enabled":  true,
+		"labels":       labels,
+		"ipam_options": gin.H{"driver": "host-local"},
+	}
+}
@@ -0,0 +1,119 @@
+package libpod_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/server/servertest"
+	pkgbackend "github.com/joyrex2001/kubedock/pkg/backend"
+)
+
+func TestLibpodContainerInit(t *testing.T) {
+	router, _ := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"libpod-init"}`)
+
+	tests := []struct {
+		method string
+		url    string
+		code   int
+		match  string
+	}{
+		{method: http.MethodPost, url: "/libpod/containers/libpod-init/init", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/libpod-init/init", code: http.StatusNotModified},
+		{method: http.MethodPost, url: "/libpod/containers/doesnotexist/init", code: http.StatusNotFound},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/start", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/init", code: http.StatusNotModified},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/mount", code: http.StatusInternalServerError, match: `"response":500`},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/unmount", code: http.StatusNoContent},
+		{method: http.MethodPost, url: "/libpod/containers/doesnotexist/unmount", code: http.StatusNotFound},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, tst.method, tst.url, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
+
+func TestLibpodContainersStats(t *testing.T) {
+	router, kub := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","name":"libpod-stats"}`)
+	kub.Stats[id] = &pkgbackend.ContainerStats{CPUNanoCores: 250000000, MemoryUsage: 64, MemoryLimit: 128}
+
+	tests := []struct {
+		url   string
+		code  int
+		match string
+	}{
+		{url: "/libpod/containers/stats?stream=false&containers=libpod-stats", code: http.StatusOK, match: `"CPU":25,`},
+		{url: "/libpod/containers/stats?stream=false&containers=libpod-stats", code: http.StatusOK, match: `"MemPerc":50,`},
+		{url: "/libpod/containers/stats?stream=false&containers=doesnotexist", code: http.StatusNotFound},
+		{url: "/libpod/containers/stats?stream=false&interval=0", code: http.StatusBadRequest},
+	}
+	for i, tst := range tests {
+		w := servertest.Do(router, http.MethodGet, tst.url, nil)
+		if w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tst.match) {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.match, w.Body.String())
+		}
+	}
+}
+
+func TestContainerCheckpoint(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	router, _ := servertest.NewRouter(t, common.Config{})
+	id := servertest.CreateContainerWithBody(t, router, `{"Image":"alpine:latest","HostConfig":{"Binds":["`+t.TempDir()+`:/data"]}}`)
+	if w := servertest.Do(router, http.MethodPost, "/containers/"+id+"/start", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("failed starting container - expected %d, but got %d", http.StatusNoContent, w.Code)
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "data/saved.txt", Mode: 0644, Size: 5})
+	tw.Write([]byte("hello"))
+	tw.Close()
+	if w := servertest.Do(router, http.MethodPut, "/containers/"+id+"/archive?path=/", buf); w.Code != http.StatusOK {
+		t.Fatalf("failed copying to container - expected %d, but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	spooled := func() int {
+		files, _ := os.ReadDir(tmp)
+		n := 0
+		for _, f := range files {
+			if strings.HasPrefix(f.Name(), "kubedock-checkpoint-") {
+				n++
+			}
+		}
+		return n
+	}
+
+	tests := []struct {
+		method  string
+		url     string
+		code    int
+		spooled int
+	}{
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/checkpoint", code: http.StatusOK, spooled: 1},
+		{method: http.MethodPost, url: "/libpod/containers/" + id + "/restore", code: http.StatusOK, spooled: 1},
+		{method: http.MethodDelete, url: "/containers/" + id + "?force=true", code: http.StatusNoContent, spooled: 0},
+	}
+	for i, tst := range tests {
+		if w := servertest.Do(router, tst.method, tst.url, nil); w.Code != tst.code {
+			t.Errorf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+		if n := spooled(); n != tst.spooled {
+			t.Errorf("failed test %d - expected %d spooled checkpoint archives, but got %d", i, tst.spooled, n)
+		}
+	}
+}
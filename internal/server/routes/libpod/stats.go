@@ -0,0 +1,152 @@
+package libpod
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/server/httputil"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// ContainersStats - stream the resource usage of a set of containers. The
+// usage of all containers is retrieved with a single query on the metrics
+// api per interval. If no containers are given, the stats of all running
+// containers are returned.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/containers/operation/ContainersStatsAllLibpod
+// GET "/libpod/containers/stats"
+func ContainersStats(cr *common.ContextRouter, c *gin.Context) {
+	stream := true
+	if val := c.Query("stream"); val != "" {
+		stream, _ = strconv.ParseBool(val)
+	}
+	interval := 5 * time.Second
+	if val := c.Query("interval"); val != "" {
+		secs, err := strconv.Atoi(val)
+		if err != nil || secs < 1 {
+			httputil.Error(c, http.StatusBadRequest, fmt.Errorf("invalid interval: %s", val))
+			return
+		}
+		interval = time.Duration(secs) * time.Second
+	}
+
+	tainrs, err := getStatsContainers(cr, c.QueryArray("containers"))
+	if err != nil {
+		httputil.Error(c, http.StatusNotFound, err)
+		return
+	}
+	stats, err := cr.Backend.GetContainerStats(tainrs)
+	if err != nil {
+		httputil.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if stream {
+		release, err := cr.AcquireStream()
+		if err != nil {
+			httputil.Error(c, http.StatusTooManyRequests, err)
+			return
+		}
+		defer release()
+	}
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for {
+		res := []gin.H{}
+		for _, tainr := range tainrs {
+			res = append(res, getContainerStats(tainr, stats[tainr.ID]))
+		}
+		var msg interface{}
+		if err != nil {
+			msg = err.Error()
+		}
+		if err := enc.Encode(gin.H{"Error": msg, "Stats": res}); err != nil {
+			klog.V(3).Infof("error writing stats: %s", err)
+			return
+		}
+		w.Flush()
+		if !stream {
+			return
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(interval):
+		}
+		stats, err = cr.Backend.GetContainerStats(tainrs)
+	}
+}
+
+// getStatsContainers will return the containers with given names or ids,
+// or all running containers if no containers are given.
+func getStatsContainers(cr *common.ContextRouter, ids []string) ([]*types.Container, error) {
+	tainrs := []*types.Container{}
+	if len(ids) == 0 {
+		all, err := cr.DB.GetContainers()
+		if err != nil {
+			return nil, err
+		}
+		for _, tainr := range all {
+			if tainr.Running {
+				tainrs = append(tainrs, tainr)
+			}
+		}
+		return tainrs, nil
+	}
+	for _, id := range ids {
+		tainr, err := cr.DB.GetContainerByNameOrID(id)
+		if err != nil {
+			return nil, err
+		}
+		tainrs = append(tainrs, tainr)
+	}
+	return tainrs, nil
+}
+
+// getContainerStats will return the libpod stats report of given container.
+// Usage that is not available via the metrics api (network, block io and
+// pids) is reported as 0, as are containers without metrics.
+func getContainerStats(tainr *types.Container, st *backend.ContainerStats) gin.H {
+	if st == nil {
+		st = &backend.ContainerStats{Timestamp: time.Now()}
+	}
+	cpu := float64(st.CPUNanoCores) / 1e7
+	mem := 0.0
+	if st.MemoryLimit > 0 {
+		mem = float64(st.MemoryUsage) / float64(st.MemoryLimit) * 100
+	}
+	uptime := time.Duration(0)
+	if tainr.Running {
+		uptime = time.Since(tainr.Created)
+	}
+	return gin.H{
+		"ContainerID":   tainr.ID,
+		"Name":          tainr.Name,
+		"PerCPU":        nil,
+		"CPU":           cpu,
+		"AvgCPU":        cpu,
+		"CPUNano":       0,
+		"CPUSystemNano": 0,
+		"SystemNano":    st.Timestamp.UnixNano(),
+		"MemUsage":      st.MemoryUsage,
+		"MemLimit":      st.MemoryLimit,
+		"MemPerc":       mem,
+		"NetInput":      0,
+		"NetOutput":     0,
+		"BlockInput":    0,
+		"BlockOutput":   0,
+		"PIDs":          0,
+		"UpTime":        uptime.Nanoseconds(),
+		"Duration":      uptime.Nanoseconds(),
+	}
+}
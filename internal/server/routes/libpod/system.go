@@ -2,20 +2,82 @@ package libpod
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+
 	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/events"
 	"github.com/joyrex2001/kubedock/internal/server/routes/common"
 )
 
-// Version - get version.
+// eventActions maps the type/action of events to the podman action, in
+// case these differ from the docker actions.
+var eventActions = map[string]string{
+	events.Container + "/" + events.Die:     "died",
+	events.Container + "/" + events.Destroy: "remove",
+	events.Container + "/" + events.ExecDie: "exec_died",
+	events.Image + "/" + events.Delete:      "remove",
+}
+
+// Version - get version. Like podman, the docker compat api version is
+// advertised at the top level, and the libpod api version is advertised in
+// the details of the Podman Engine component, which is used by the podman
+// client.
 // https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/system/operation/SystemVersionLibpod
 // GET "/libpod/version"
 func Version(cr *common.ContextRouter, c *gin.Context) {
+	c.JSON(http.StatusOK, common.GetVersion(common.VersionInfo{
+		Platform:      config.LibpodPlatform,
+		Component:     "Podman Engine",
+		Version:       config.LibpodAPIVersion,
+		APIVersion:    config.DockerAPIVersion,
+		MinAPIVersion: config.DockerMinAPIVersion,
+		Details: gin.H{
+			"APIVersion":    config.LibpodAPIVersion,
+			"MinAPIVersion": config.LibpodMinAPIVersion,
+		},
+	}))
+}
+
+// Info - get system information, in the podman format.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/system/operation/SystemInfoLibpod
+// GET "/libpod/info"
+func Info(cr *common.ContextRouter, c *gin.Context) {
+	info := cr.Config.Info.WithDefaults()
+	ncpu, mem := common.GetCapacity(cr, info)
 	c.JSON(http.StatusOK, gin.H{
-		"GitCommit": config.Build,
-		"Os":        config.OS,
-		"Version":   config.Version,
+		"host": gin.H{
+			"arch":            config.GOARCH,
+			"os":              "linux",
+			"distribution":    gin.H{"distribution": info.OperatingSystem},
+			"kernel":          info.KernelVersion,
+			"hostname":        config.Name,
+			"cpus":            ncpu,
+			"memTotal":        mem,
+			"cgroupVersion":   "v" + info.CgroupVersion,
+			"cgroupManager":   info.CgroupDriver,
+			"serviceIsRemote": true,
+			"security": gin.H{
+				"apparmorEnabled": info.HasSecurityOption("apparmor"),
+				"seccompEnabled":  info.HasSecurityOption("seccomp"),
+				"selinuxEnabled":  info.HasSecurityOption("selinux"),
+				"rootless":        info.HasSecurityOption("rootless"),
+			},
+		},
+		"store": gin.H{
+			"graphDriverName": info.StorageDriver,
+		},
+		"registries": gin.H{},
+		"version": gin.H{
+			"APIVersion": config.LibpodAPIVersion,
+			"Version":    config.LibpodAPIVersion,
+			"GoVersion":  config.GoVersion,
+			"GitCommit":  config.Build,
+			"BuiltTime":  config.Date,
+			"OsArch":     config.GOOS + "/" + config.GOARCH,
+			"Os":         config.GOOS,
+		},
 	})
 }
 
@@ -25,3 +87,38 @@ func Version(cr *common.ContextRouter, c *gin.Context) {
 func Ping(cr *common.ContextRouter, c *gin.Context) {
 	c.String(http.StatusOK, "OK")
 }
+
+// Events - stream real-time events from the server, in the podman format.
+// https://docs.podman.io/en/latest/_static/api.html?version=v4.2#tag/system/operation/SystemEventsLibpod
+// GET "/libpod/events"
+func Events(cr *common.ContextRouter, c *gin.Context) {
+	if stream, err := strconv.ParseBool(c.DefaultQuery("stream", "true")); err == nil && !stream {
+		// past events are not kept, so there is nothing to report
+		c.Writer.Header().Set("Content-Type", "application/json")
+		c.Writer.WriteHeader(http.StatusOK)
+		return
+	}
+	common.StreamEvents(cr, c, getEventMessage)
+}
+
+// getEventMessage will convert the given event to the podman json format.
+func getEventMessage(msg events.Message) gin.H {
+	action := msg.Action
+	if act, ok := eventActions[msg.Type+"/"+action]; ok {
+		action = act
+	}
+	return gin.H{
+		"status": action,
+		"id":     msg.ID,
+		"from":   msg.Attributes["image"],
+		"Type":   msg.Type,
+		"Action": action,
+		"Actor": gin.H{
+			"ID":         msg.ID,
+			"Attributes": msg.Attributes,
+		},
+		"scope":    "local",
+		"time":     msg.Time,
+		"timeNano": msg.TimeNano,
+	}
+}
@@ -0,0 +1,40 @@
+package libpod
+
+import (
+	"testing"
+
+	"github.com/joyrex2001/kubedock/internal/events"
+)
+
+func TestGetEventMessage(t *testing.T) {
+	tests := []struct {
+		msg    events.Message
+		status string
+		from   string
+	}{
+		{
+			msg:    events.Message{ID: "1", Type: events.Container, Action: events.Die, Attributes: map[string]string{"image": "alpine"}},
+			status: "died",
+			from:   "alpine",
+		},
+		{
+			msg:    events.Message{ID: "1", Type: events.Container, Action: events.Start, Attributes: map[string]string{"image": "alpine"}},
+			status: "start",
+			from:   "alpine",
+		},
+		{
+			msg:    events.Message{ID: "2", Type: events.Image, Action: events.Delete, Attributes: map[string]string{"name": "alpine"}},
+			status: "remove",
+			from:   "",
+		},
+	}
+	for i, tst := range tests {
+		res := getEventMessage(tst.msg)
+		if res["status"] != tst.status || res["Action"] != tst.status {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.status, res["status"])
+		}
+		if res["from"] != tst.from {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.from, res["from"])
+		}
+	}
+}
@@ -9,12 +9,49 @@ type ContainerCreateRequest struct {
 	Entrypoint   []string                    `json:"Entrypoint"`
 	Command      []string                    `json:"Command"`
 	Env          map[string]string           `json:"Env"`
+	EnvHost      bool                        `json:"env_host"`
+	EnvMerge     []string                    `json:"envmerge"`
+	UnsetEnv     []string                    `json:"unsetenv"`
+	SecretEnv    map[string]string           `json:"secret_env"`
 	User         string                      `json:"User"`
 	PortMappings []PortMapping               `json:"portmappings"`
 	Network      map[string]NetworksProperty `json:"Networks"`
+	Netns        Namespace                   `json:"netns"`
 	Mounts       []Mount                     `json:"mounts"`
 	Terminal     bool                        `json:"terminal"`
 	Stdin        bool                        `json:"Stdin"`
+	Devices      []Device                    `json:"devices"`
+	Sysctl       map[string]string           `json:"sysctl"`
+	CgroupParent string                      `json:"cgroup_parent"`
+	Userns       Namespace                   `json:"userns"`
+	Rlimits      []Rlimit                    `json:"r_limits"`
+}
+
+// NetworkCreateRequest represents the json structure that
+// is used for the /libpod/networks/create post endpoint.
+type NetworkCreateRequest struct {
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels"`
+	Ignore  bool              `json:"ignore"`
+	Subnets []NetworkSubnet   `json:"subnets"`
+}
+
+// NetworkSubnet contains the requested subnet and gateway of a network.
+type NetworkSubnet struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway"`
+}
+
+// Rlimit describes a resource limit that should be applied on the container.
+type Rlimit struct {
+	Type string `json:"type"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+// Device describes a device that should be mapped into the container.
+type Device struct {
+	Path string `json:"path"`
 }
 
 // PortMapping describes how to map a port into the container.
@@ -26,6 +63,12 @@ type PortMapping struct {
 	Range         int    `json:"range"`
 }
 
+// Namespace describes the namespace a container should join.
+type Namespace struct {
+	NSMode string `json:"nsmode"`
+	Value  string `json:"value"`
+}
+
 // NetworksProperty describes the container networks.
 type NetworksProperty struct {
 	Aliases []string `json:"aliases"`
@@ -33,7 +76,8 @@ type NetworksProperty struct {
 
 // Mount describes how volumes should be mounted.
 type Mount struct {
-	Source      string `json:"source"`
-	Destination string `json:"destination"`
-	Type        string `json:"type"`
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Options     []string `json:"options"`
 }
@@ -0,0 +1,19 @@
+package libpod
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+)
+
+// VolumesList - list volumes. Unlike the compat api, which returns an
+// object with the volumes and warnings, the libpod api returns the list of
+// volumes itself. Kubedock doesn't manage named volumes (binds are copied
+// into the pod), so the list is always empty.
+// https://docs.podman.io/en/latest/_static/api.html#tag/volumes/operation/VolumeListLibpod
+// GET "/libpod/volumes/json"
+func VolumesList(cr *common.ContextRouter, c *gin.Context) {
+	c.JSON(http.StatusOK, []gin.H{})
+}
@@ -0,0 +1,71 @@
+// Package servertest provides helpers to test the api routes of kubedock
+// against the in-memory backend of pkg/backend/fake.
+package servertest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/server"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/pkg/backend/fake"
+)
+
+// NewRouter will return a router that serves all api routes with given
+// config, and the fake backend that is used by the routes.
+func NewRouter(t *testing.T, cfg common.Config) (*gin.Engine, *fake.Backend) {
+	gin.SetMode(gin.TestMode)
+	kub := fake.New()
+	cfg.Readiness = "running"
+	cr, err := common.NewContextRouter(adapter.Internal(kub), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating context: %s", err)
+	}
+	return server.NewRouter(cr), kub
+}
+
+// Do will perform a json request on given router, and return the recorded
+// response.
+func Do(router *gin.Engine, method, url string, body io.Reader) *httptest.ResponseRecorder {
+	return DoWithToken(router, method, url, body, "")
+}
+
+// DoWithToken will perform a json request on given router with given bearer
+// token, and return the recorded response.
+func DoWithToken(router *gin.Engine, method, url string, body io.Reader, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// CreateContainer will create a sleeping alpine container that exposes port
+// 80, and return its id.
+func CreateContainer(t *testing.T, router *gin.Engine) string {
+	return CreateContainerWithBody(t, router, `{"Image":"alpine:latest","Cmd":["sleep","60"],"ExposedPorts":{"80/tcp":{}}}`)
+}
+
+// CreateContainerWithBody will create a container with given create request,
+// and return its id.
+func CreateContainerWithBody(t *testing.T, router *gin.Engine, body string) string {
+	w := Do(router, http.MethodPost, "/containers/create", strings.NewReader(body))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed creating container - expected %d, but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected error parsing create response: %s", err)
+	}
+	return res["Id"].(string)
+}
@@ -3,6 +3,7 @@ package attach
 import (
 	"context"
 	"io"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
@@ -10,6 +11,10 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/util/exec"
+	"github.com/joyrex2001/kubedock/internal/util/watchdog"
 )
 
 // Request is the structure used as argument for RemoteAttach
@@ -30,6 +35,14 @@ type Request struct {
 	Stderr io.Writer
 	// TTY will enable interactive tty mode (requires stdin)
 	TTY bool
+	// TerminalSizeQueue contains an optional queue with terminal sizes for tty sessions
+	TerminalSizeQueue remotecommand.TerminalSizeQueue
+	// IdleTimeout is the max time without any input or output before the
+	// session is terminated (0 = never)
+	IdleTimeout time.Duration
+	// MaxDuration is the max time the session can run before it is
+	// terminated (0 = never)
+	MaxDuration time.Duration
 }
 
 // RemoteAttach attaches to an existing container in a pod.
@@ -49,15 +62,33 @@ func RemoteAttach(req Request) error {
 		TTY:       req.TTY,
 	}, scheme.ParameterCodec)
 
-	exec, err := remotecommand.NewSPDYExecutor(req.RestConfig, "POST", r.URL())
+	ex, err := exec.NewExecutor(req.RestConfig, r.URL())
 	if err != nil {
 		return err
 	}
 
-	return exec.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
-		Stdin:  req.Stdin,
-		Stdout: req.Stdout,
-		Stderr: req.Stderr,
-		Tty:    req.TTY,
+	wd, ctx := watchdog.New(context.Background(), req.IdleTimeout, req.MaxDuration)
+	defer wd.Stop()
+	stdin, stdout, stderr := req.Stdin, req.Stdout, req.Stderr
+	if stdin != nil {
+		stdin = wd.Reader(stdin)
+	}
+	if stdout != nil {
+		stdout = wd.Writer(stdout)
+	}
+	if stderr != nil {
+		stderr = wd.Writer(stderr)
+	}
+	err = ex.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Stderr:            stderr,
+		Tty:               req.TTY,
+		TerminalSizeQueue: req.TerminalSizeQueue,
 	})
+	if werr := wd.Err(); werr != nil {
+		klog.Warningf("terminated attach to %s: %s", req.Pod.Name, werr)
+		return werr
+	}
+	return err
 }
@@ -0,0 +1,512 @@
+package buildkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// sessionMethod is the grpc method of the buildkit control api that tunnels
+// the session of a build client.
+const sessionMethod = "/moby.buildkit.v1.Control/Session"
+
+// solveMethod is the grpc method of the buildkit control api that starts a
+// build.
+const solveMethod = "/moby.buildkit.v1.Control/Solve"
+
+// pruneMethod is the grpc method of the buildkit control api that prunes the
+// build cache.
+const pruneMethod = "/moby.buildkit.v1.Control/Prune"
+
+// mobyExporter is the exporter that stores the built image in the docker
+// daemon, which is requested by the docker driver of buildx if the image
+// is neither pushed, nor exported.
+const mobyExporter = "moby"
+
+// errMobyExporter is returned to build clients that request the moby
+// exporter, as kubedock doesn't store images itself.
+var errMobyExporter = status.Error(codes.FailedPrecondition, "kubedock can't store the built image, push it to a registry with --push, or export it with --output (e.g. --output type=local,dest=out)")
+
+// sessionHeaderPrefix is the prefix of the http headers that describe the
+// session of a build client (uuid, name, shared key and exposed methods).
+const sessionHeaderPrefix = "X-Docker-Expose-Session-"
+
+// Bridge connects the buildkit endpoints of the docker api (/grpc and
+// /session) to a buildkit daemon.
+type Bridge struct {
+	addr string
+}
+
+// New will return a Bridge to the buildkit daemon at given address, which
+// should be in the form of tcp://host:port.
+func New(addr string) (*Bridge, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Scheme != "tcp" || u.Host == "" {
+		return nil, fmt.Errorf("invalid buildkit address %s, expected tcp://host:port", addr)
+	}
+	return &Bridge{addr: u.Host}, nil
+}
+
+// Control will proxy the calls on the given (h2c) connection to the control
+// api of the buildkit daemon, until the client closes the connection. Builds
+// that request the moby exporter (e.g. a plain docker build -t x .) are
+// rejected, as the built image can't be stored.
+func (b *Bridge) Control(conn net.Conn) error {
+	cc, err := grpc.NewClient(b.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, in grpc.ServerStream) error {
+		return proxyStream(cc, in)
+	}))
+	defer srv.Stop()
+	_ = srv.Serve(newConnListener(conn))
+	return nil
+}
+
+// proxyStream will forward the given call to the buildkit daemon, and the
+// responses back to the client.
+func proxyStream(cc *grpc.ClientConn, in grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(in)
+	md, _ := metadata.FromIncomingContext(in.Context())
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(in.Context(), md.Copy()))
+	defer cancel()
+
+	desc := &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+	out, err := cc.NewStream(ctx, desc, method, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+
+	reqs := make(chan error, 1)
+	go func() {
+		for first := true; ; first = false {
+			var dat []byte
+			if err := in.RecvMsg(&dat); err != nil {
+				if err == io.EOF {
+					err = out.CloseSend()
+				}
+				reqs <- err
+				return
+			}
+			if first && method == solveMethod && usesMobyExporter(dat) {
+				reqs <- errMobyExporter
+				return
+			}
+			if err := out.SendMsg(dat); err != nil {
+				reqs <- err
+				return
+			}
+		}
+	}()
+	resps := make(chan error, 1)
+	go func() {
+		resps <- forwardResponses(out, in)
+	}()
+	for {
+		select {
+		case err := <-reqs:
+			if err != nil {
+				return err
+			}
+			reqs = nil
+		case err := <-resps:
+			return err
+		}
+	}
+}
+
+// forwardResponses will forward the headers, messages and trailers of the
+// given call to the buildkit daemon to the client.
+func forwardResponses(out grpc.ClientStream, in grpc.ServerStream) error {
+	hdr, err := out.Header()
+	if err != nil {
+		return err
+	}
+	if err := in.SendHeader(hdr); err != nil {
+		return err
+	}
+	for {
+		var dat []byte
+		if err := out.RecvMsg(&dat); err != nil {
+			in.SetTrailer(out.Trailer())
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := in.SendMsg(dat); err != nil {
+			return err
+		}
+	}
+}
+
+// usesMobyExporter will check if the given SolveRequest requests the moby
+// exporter, either as the (deprecated) Exporter (field 3), or as one of the
+// Exporters (field 13), of which the Type is field 1.
+func usesMobyExporter(msg []byte) bool {
+	for _, exp := range getProtoFields(msg, 3) {
+		if string(exp) == mobyExporter {
+			return true
+		}
+	}
+	for _, exp := range getProtoFields(msg, 13) {
+		for _, typ := range getProtoFields(exp, 1) {
+			if string(typ) == mobyExporter {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getProtoFields will return the values of the length delimited fields with
+// given number in the given protobuf message.
+func getProtoFields(msg []byte, field protowire.Number) [][]byte {
+	res := [][]byte{}
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return res
+		}
+		msg = msg[n:]
+		if num == field && typ == protowire.BytesType {
+			val, n := protowire.ConsumeBytes(msg)
+			if n < 0 {
+				return res
+			}
+			res = append(res, val)
+			msg = msg[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return res
+		}
+		msg = msg[n:]
+	}
+	return res
+}
+
+// connListener is a net.Listener that accepts the given connection once,
+// and is closed when that connection is closed.
+type connListener struct {
+	conn  net.Conn
+	once  sync.Once
+	conns chan net.Conn
+	done  chan struct{}
+}
+
+// newConnListener will return a listener for the given connection.
+func newConnListener(conn net.Conn) *connListener {
+	l := &connListener{conn: conn, done: make(chan struct{}), conns: make(chan net.Conn, 1)}
+	l.conns <- &listenerConn{Conn: conn, close: l.Close}
+	return l
+}
+
+// Accept will return the connection on the first call, and waits until the
+// connection is closed on subsequent calls.
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close will close the listener.
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr will return the local address of the connection.
+func (l *connListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// listenerConn is a connection that closes its listener when it's closed.
+type listenerConn struct {
+	net.Conn
+	close func() error
+}
+
+// Close will close the connection and its listener.
+func (c *listenerConn) Close() error {
+	_ = c.close()
+	return c.Conn.Close()
+}
+
+// PruneOptions contains the options to prune the build cache of the buildkit
+// daemon with, which match the PruneRequest of the control api.
+type PruneOptions struct {
+	// Filters are the buildkit filters of the records to prune (id==abc)
+	Filters []string
+	// All will prune internal and frontend references as well
+	All bool
+	// KeepDuration keeps records that have been used within this duration
+	KeepDuration time.Duration
+	// ReservedSpace is the amount of cache storage (bytes) to keep
+	ReservedSpace int64
+	// MaxUsedSpace is the max amount of cache storage (bytes) to keep
+	MaxUsedSpace int64
+	// MinFreeSpace is the amount of disk space (bytes) to keep free
+	MinFreeSpace int64
+}
+
+// PruneResult contains the ids and the total size of the removed records.
+type PruneResult struct {
+	CachesDeleted  []string
+	SpaceReclaimed int64
+}
+
+// Prune will prune the build cache of the buildkit daemon with given options,
+// and returns the records that have been removed.
+func (b *Bridge) Prune(ctx context.Context, opts PruneOptions) (*PruneResult, error) {
+	cc, err := grpc.NewClient(b.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer cc.Close()
+
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	stream, err := cc.NewStream(ctx, desc, pruneMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(encodePruneRequest(opts)); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	res := &PruneResult{CachesDeleted: []string{}}
+	for {
+		var dat []byte
+		err := stream.RecvMsg(&dat)
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, size, err := decodeUsageRecord(dat)
+		if err != nil {
+			return nil, err
+		}
+		res.CachesDeleted = append(res.CachesDeleted, id)
+		res.SpaceReclaimed += size
+	}
+}
+
+// encodePruneRequest will encode given options as a PruneRequest of the
+// buildkit control api.
+func encodePruneRequest(opts PruneOptions) []byte {
+	msg := []byte{}
+	for _, f := range opts.Filters {
+		msg = protowire.AppendString(protowire.AppendTag(msg, 1, protowire.BytesType), f)
+	}
+	if opts.All {
+		msg = protowire.AppendVarint(protowire.AppendTag(msg, 2, protowire.VarintType), 1)
+	}
+	for num, val := range []int64{3: int64(opts.KeepDuration), 4: opts.ReservedSpace, 5: opts.MaxUsedSpace, 6: opts.MinFreeSpace} {
+		if val != 0 {
+			msg = protowire.AppendVarint(protowire.AppendTag(msg, protowire.Number(num), protowire.VarintType), uint64(val))
+		}
+	}
+	return msg
+}
+
+// decodeUsageRecord will return the id (field 1) and size (field 4) of the
+// given UsageRecord of the buildkit control api.
+func decodeUsageRecord(msg []byte) (string, int64, error) {
+	id, size := "", int64(0)
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return "", 0, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			val, n := protowire.ConsumeString(msg)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			id = val
+			msg = msg[n:]
+			continue
+		case num == 4 && typ == protowire.VarintType:
+			val, n := protowire.ConsumeVarint(msg)
+			if n < 0 {
+				return "", 0, protowire.ParseError(n)
+			}
+			size = int64(val)
+			msg = msg[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return "", 0, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+	}
+	return id, size, nil
+}
+
+// Session will tunnel the given (h2c) stream of a build client session to
+// the buildkit daemon, which will call back the client (e.g. to read the
+// build context) via this session. The session is described by the
+// X-Docker-Expose-Session-* headers.
+func (b *Bridge) Session(ctx context.Context, hdr http.Header, in io.Reader, out io.Writer) error {
+	cc, err := grpc.NewClient(b.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	md := metadata.MD{}
+	for k, v := range hdr {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), sessionHeaderPrefix) {
+			md[strings.ToLower(k)] = v
+		}
+	}
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(ctx, md))
+	defer cancel()
+
+	desc := &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+	stream, err := cc.NewStream(ctx, desc, sessionMethod, grpc.ForceCodec(bytesCodec{}))
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 2)
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				if err := stream.SendMsg(buf[:n]); err != nil {
+					done <- err
+					return
+				}
+			}
+			if err != nil {
+				_ = stream.CloseSend()
+				done <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			var dat []byte
+			if err := stream.RecvMsg(&dat); err != nil {
+				done <- err
+				return
+			}
+			if _, err := out.Write(dat); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	if err := <-done; err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// bytesCodec encodes raw bytes as the BytesMessage of the buildkit control
+// api, which contains the data in field 1.
+type bytesCodec struct{}
+
+// Marshal will encode the given []byte as a BytesMessage.
+func (bytesCodec) Marshal(v interface{}) ([]byte, error) {
+	dat, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message type %T", v)
+	}
+	return protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), dat), nil
+}
+
+// Unmarshal will decode the given BytesMessage into the given *[]byte.
+func (bytesCodec) Unmarshal(msg []byte, v interface{}) error {
+	dat, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unsupported message type %T", v)
+	}
+	*dat = []byte{}
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if num == 1 && typ == protowire.BytesType {
+			val, n := protowire.ConsumeBytes(msg)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			*dat = append(*dat, val...)
+			msg = msg[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+	}
+	return nil
+}
+
+// Name will return the name of the codec; the messages are protobuf
+// messages, so the buildkit daemon will decode them as such.
+func (bytesCodec) Name() string {
+	return "proto"
+}
+
+// rawCodec passes the encoded messages of proxied calls as is.
+type rawCodec struct{}
+
+// Marshal will return the given []byte as is.
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	dat, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message type %T", v)
+	}
+	return dat, nil
+}
+
+// Unmarshal will copy the given message into the given *[]byte.
+func (rawCodec) Unmarshal(msg []byte, v interface{}) error {
+	dat, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unsupported message type %T", v)
+	}
+	*dat = append([]byte{}, msg...)
+	return nil
+}
+
+// Name will return the name of the codec.
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,259 @@
+package buildkit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		addr string
+		out  string
+		suc  bool
+	}{
+		{addr: "tcp://buildkitd:1234", out: "buildkitd:1234", suc: true},
+		{addr: "tcp://10.0.0.1:1234", out: "10.0.0.1:1234", suc: true},
+		{addr: "unix:///run/buildkit/buildkitd.sock", suc: false},
+		{addr: "buildkitd:1234", suc: false},
+		{addr: "", suc: false},
+	}
+	for i, tst := range tests {
+		res, err := New(tst.addr)
+		if (err == nil) != tst.suc {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			continue
+		}
+		if err == nil && res.addr != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res.addr)
+		}
+	}
+}
+
+func TestBytesCodec(t *testing.T) {
+	tests := [][]byte{
+		{},
+		[]byte("hello"),
+		bytes.Repeat([]byte{0xff}, 1024),
+	}
+	codec := bytesCodec{}
+	for i, tst := range tests {
+		msg, err := codec.Marshal(tst)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			continue
+		}
+		var res []byte
+		if err := codec.Unmarshal(msg, &res); err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+			continue
+		}
+		if !bytes.Equal(res, tst) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst, res)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	names := make(chan []string, 1)
+	srv := grpc.NewServer(grpc.ForceServerCodec(bytesCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		names <- md.Get("x-docker-expose-session-name")
+		for {
+			var dat []byte
+			if err := stream.RecvMsg(&dat); err != nil {
+				return nil
+			}
+			if err := stream.SendMsg(dat); err != nil {
+				return err
+			}
+		}
+	}))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	bk, err := New("tcp://" + lis.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	hdr := http.Header{}
+	hdr.Set("X-Docker-Expose-Session-Name", "kubedock")
+	hdr.Set("Authorization", "secret")
+
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- bk.Session(context.Background(), hdr, inr, outw)
+	}()
+
+	if _, err := inw.Write([]byte("ping")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(outr, buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("failed test - expected %s, but got %s", "ping", buf)
+	}
+	if res := <-names; len(res) != 1 || res[0] != "kubedock" {
+		t.Errorf("failed test - expected %v, but got %v", []string{"kubedock"}, res)
+	}
+	inw.Close()
+	if err := <-done; err != nil {
+		t.Errorf("failed test - unexpected error: %s", err)
+	}
+}
+
+func TestControl(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		var dat []byte
+		if err := stream.RecvMsg(&dat); err != nil {
+			return err
+		}
+		return stream.SendMsg(dat)
+	}))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	bk, err := New("tcp://" + lis.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ctl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer ctl.Close()
+	go func() {
+		for {
+			conn, err := ctl.Accept()
+			if err != nil {
+				return
+			}
+			go bk.Control(conn)
+		}
+	}()
+
+	cc, err := grpc.NewClient("passthrough:///"+ctl.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer cc.Close()
+
+	field := func(num protowire.Number, val []byte) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(nil, num, protowire.BytesType), val)
+	}
+	tests := []struct {
+		method string
+		msg    []byte
+		code   codes.Code
+	}{
+		{method: "/moby.buildkit.v1.Control/ListWorkers", msg: field(1, []byte("moby")), code: codes.OK},
+		{method: solveMethod, msg: field(1, []byte("ref")), code: codes.OK},
+		{method: solveMethod, msg: field(13, field(1, []byte("image"))), code: codes.OK},
+		{method: solveMethod, msg: field(3, []byte("moby")), code: codes.FailedPrecondition},
+		{method: solveMethod, msg: append(field(13, field(1, []byte("local"))), field(13, field(1, []byte("moby")))...), code: codes.FailedPrecondition},
+	}
+	for i, tst := range tests {
+		var res []byte
+		err := cc.Invoke(context.Background(), tst.method, tst.msg, &res, grpc.ForceCodec(rawCodec{}))
+		if code := status.Code(err); code != tst.code {
+			t.Errorf("failed test %d - expected %s, but got %s (%v)", i, tst.code, code, err)
+			continue
+		}
+		if err == nil && !bytes.Equal(res, tst.msg) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.msg, res)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	record := func(id string, size uint64) []byte {
+		msg := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), id)
+		msg = protowire.AppendVarint(protowire.AppendTag(msg, 3, protowire.VarintType), 1)
+		return protowire.AppendVarint(protowire.AppendTag(msg, 4, protowire.VarintType), size)
+	}
+	var req []byte
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		if method, _ := grpc.MethodFromServerStream(stream); method != pruneMethod {
+			return status.Errorf(codes.Unimplemented, "unexpected method %s", method)
+		}
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		for _, rec := range [][]byte{record("sha256:msx", 1024), record("sha256:vg8020", 2048)} {
+			if err := stream.SendMsg(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	bk, err := New("tcp://" + lis.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	opts := PruneOptions{Filters: []string{"type==regular"}, All: true, KeepDuration: time.Hour, ReservedSpace: 512}
+	res, err := bk.Prune(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !bytes.Equal(req, encodePruneRequest(opts)) {
+		t.Errorf("expected prune request %v, but got %v", encodePruneRequest(opts), req)
+	}
+	if len(res.CachesDeleted) != 2 || res.CachesDeleted[1] != "sha256:vg8020" || res.SpaceReclaimed != 3072 {
+		t.Errorf("expected 2 removed records of 3072 bytes, but got %v", res)
+	}
+}
+
+func TestEncodePruneRequest(t *testing.T) {
+	msg := encodePruneRequest(PruneOptions{Filters: []string{"id==msx"}, All: true, KeepDuration: time.Second, MinFreeSpace: 10})
+	fields := map[protowire.Number]uint64{}
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		msg = msg[n:]
+		if typ == protowire.BytesType {
+			val, n := protowire.ConsumeString(msg)
+			if num != 1 || val != "id==msx" {
+				t.Errorf("unexpected field %d: %s", num, val)
+			}
+			msg = msg[n:]
+			continue
+		}
+		val, n := protowire.ConsumeVarint(msg)
+		fields[num] = val
+		msg = msg[n:]
+	}
+	exp := map[protowire.Number]uint64{2: 1, 3: uint64(time.Second), 6: 10}
+	if !reflect.DeepEqual(fields, exp) {
+		t.Errorf("expected fields %v, but got %v", exp, fields)
+	}
+}
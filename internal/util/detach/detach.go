@@ -0,0 +1,120 @@
+package detach
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultKeys is the default key sequence to detach from a container.
+const DefaultKeys = "ctrl-p,ctrl-q"
+
+// ParseKeys will parse given docker style key sequence (e.g. ctrl-p,ctrl-q)
+// into the corresponding bytes. An empty sequence results in the default
+// detach keys.
+func ParseKeys(keys string) ([]byte, error) {
+	if keys == "" {
+		keys = DefaultKeys
+	}
+	res := []byte{}
+	for _, key := range strings.Split(keys, ",") {
+		if len(key) == 1 {
+			res = append(res, key[0])
+			continue
+		}
+		code, ok := strings.CutPrefix(strings.ToLower(key), "ctrl-")
+		if !ok || len(code) != 1 {
+			return nil, fmt.Errorf("invalid detach key: %s", key)
+		}
+		switch c := code[0]; {
+		case c >= 'a' && c <= 'z':
+			res = append(res, c-'a'+1)
+		case c == '@':
+			res = append(res, 0)
+		case c >= '[' && c <= '_':
+			res = append(res, c-'['+27)
+		default:
+			return nil, fmt.Errorf("invalid detach key: %s", key)
+		}
+	}
+	return res, nil
+}
+
+// Reader is a reader that passes all data of the underlying reader, until
+// the detach key sequence is read. At that point, it will close the Detached
+// channel and return io.EOF. Partial matches of the key sequence are passed
+// through as soon as they no longer match.
+type Reader struct {
+	in       io.Reader
+	keys     []byte
+	pos      int
+	pending  []byte
+	err      error
+	detached chan struct{}
+}
+
+// NewReader will return a new Reader instance for given key sequence.
+func NewReader(in io.Reader, keys []byte) *Reader {
+	return &Reader{
+		in:       in,
+		keys:     keys,
+		pending:  []byte{},
+		detached: make(chan struct{}),
+	}
+}
+
+// Detached returns a channel that is closed when the key sequence is read.
+func (r *Reader) Detached() <-chan struct{} {
+	return r.detached
+}
+
+// Read will read from the underlying reader, filtering the key sequence.
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.pending) == 0 && r.err == nil {
+		r.fill(len(p))
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	if len(r.pending) > 0 {
+		return n, nil
+	}
+	return n, r.err
+}
+
+// fill will read a chunk of given size from the underlying reader, and adds
+// all data that doesn't match the key sequence to the pending data.
+func (r *Reader) fill(size int) {
+	buf := make([]byte, size)
+	n, err := r.in.Read(buf)
+	for _, b := range buf[:n] {
+		if len(r.keys) == 0 {
+			r.pending = append(r.pending, b)
+			continue
+		}
+		if b == r.keys[r.pos] {
+			r.pos++
+			if r.pos == len(r.keys) {
+				close(r.detached)
+				r.err = io.EOF
+				return
+			}
+			continue
+		}
+		// flush the partially matched keys, and restart matching
+		r.pending = append(r.pending, r.keys[:r.pos]...)
+		r.pos = 0
+		if b == r.keys[0] {
+			r.pos = 1
+			continue
+		}
+		r.pending = append(r.pending, b)
+	}
+	if err != nil {
+		r.pending = append(r.pending, r.keys[:r.pos]...)
+		r.pos = 0
+		r.err = err
+	}
+}
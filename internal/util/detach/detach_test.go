@@ -0,0 +1,68 @@
+package detach
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []byte
+		suc bool
+	}{
+		{in: "", out: []byte{16, 17}, suc: true},
+		{in: "ctrl-p,ctrl-q", out: []byte{16, 17}, suc: true},
+		{in: "ctrl-a,x", out: []byte{1, 'x'}, suc: true},
+		{in: "ctrl-@,ctrl-[,ctrl-_", out: []byte{0, 27, 31}, suc: true},
+		{in: "ctrl-1", suc: false},
+		{in: "alt-x", suc: false},
+	}
+	for i, tst := range tests {
+		res, err := ParseKeys(tst.in)
+		if tst.suc && err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if !tst.suc && err == nil {
+			t.Errorf("failed test %d - expected error, but succeeded instead", i)
+		}
+		if tst.suc && !bytes.Equal(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		in       string
+		out      string
+		detached bool
+	}{
+		{in: "hello", out: "hello", detached: false},
+		{in: "hel\x10\x11lo", out: "hel", detached: true},
+		{in: "hel\x10lo", out: "hel\x10lo", detached: false},
+		{in: "hel\x10\x10\x11lo", out: "hel\x10", detached: true},
+		{in: "hello\x10", out: "hello\x10", detached: false},
+	}
+	for i, tst := range tests {
+		r := NewReader(strings.NewReader(tst.in), []byte{16, 17})
+		res, err := io.ReadAll(r)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if string(res) != tst.out {
+			t.Errorf("failed test %d - expected %q, but got %q", i, tst.out, res)
+		}
+		detached := false
+		select {
+		case <-r.Detached():
+			detached = true
+		default:
+		}
+		if detached != tst.detached {
+			t.Errorf("failed test %d - expected detached %t, but got %t", i, tst.detached, detached)
+		}
+	}
+}
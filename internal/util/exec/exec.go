@@ -3,18 +3,25 @@ package exec
 import (
 	"context"
 	"io"
+	"net/url"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/util/watchdog"
 )
 
 // Request is the structure used as argument for RemoteCmd
 type Request struct {
+	// Context will terminate the session when done (optional)
+	Context context.Context
 	// Client is the kubernetes clientset
 	Client kubernetes.Interface
 	// RestConfig is the kubernetes config
@@ -33,6 +40,14 @@ type Request struct {
 	Stderr io.Writer
 	// TTY will enable interactive tty mode (requires stdin)
 	TTY bool
+	// TerminalSizeQueue contains an optional queue with terminal sizes for tty sessions
+	TerminalSizeQueue remotecommand.TerminalSizeQueue
+	// IdleTimeout is the max time without any input or output before the
+	// session is terminated (0 = never)
+	IdleTimeout time.Duration
+	// MaxDuration is the max time the session can run before it is
+	// terminated (0 = never)
+	MaxDuration time.Duration
 }
 
 // RemoteCmd will execute given exec object in kubernetes.
@@ -42,25 +57,78 @@ func RemoteCmd(req Request) error {
 		Namespace(req.Pod.Namespace).
 		SubResource("exec")
 
+	// stderr is merged into stdout in tty mode
+	tty := req.Stdin != nil && req.TTY
+	stderr := req.Stderr
+	if tty {
+		stderr = nil
+	}
+
 	r.VersionedParams(&corev1.PodExecOptions{
 		Container: req.Container,
 		Command:   req.Cmd,
 		Stdin:     req.Stdin != nil,
 		Stdout:    req.Stdout != nil,
-		Stderr:    req.Stderr != nil,
-		TTY:       req.Stdin != nil && req.TTY,
+		Stderr:    stderr != nil,
+		TTY:       tty,
 	}, scheme.ParameterCodec)
 
-	ex, err := remotecommand.NewSPDYExecutor(req.RestConfig, "POST", r.URL())
+	ex, err := NewExecutor(req.RestConfig, r.URL())
 	if err != nil {
 		return err
 	}
 
 	klog.V(3).Infof("exec %s:%v", req.Pod.Name, req.Cmd)
 
-	return ex.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
-		Stdin:  req.Stdin,
-		Stdout: req.Stdout,
-		Stderr: req.Stderr,
+	parent := req.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	wd, ctx := watchdog.New(parent, req.IdleTimeout, req.MaxDuration)
+	defer wd.Stop()
+	stdin, stdout := req.Stdin, req.Stdout
+	if stdin != nil {
+		stdin = wd.Reader(stdin)
+	}
+	if stdout != nil {
+		stdout = wd.Writer(stdout)
+	}
+	if stderr != nil {
+		stderr = wd.Writer(stderr)
+	}
+	err = ex.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Stderr:            stderr,
+		Tty:               tty,
+		TerminalSizeQueue: req.TerminalSizeQueue,
 	})
+	if werr := wd.Err(); werr != nil {
+		klog.Warningf("terminated exec %s:%v: %s", req.Pod.Name, req.Cmd, werr)
+		return werr
+	}
+	return err
+}
+
+// NewExecutor will return an executor for the given exec or attach url,
+// that uses the websocket protocol and falls back to spdy if the api server
+// doesn't support it (older than kubernetes 1.30). Websockets also work
+// with proxies and gateways in front of the api server that don't allow
+// spdy upgrades.
+func NewExecutor(config *rest.Config, url *url.URL) (remotecommand.Executor, error) {
+	spdy, err := remotecommand.NewSPDYExecutor(config, "POST", url)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := remotecommand.NewWebSocketExecutor(config, "GET", url.String())
+	if err != nil {
+		return nil, err
+	}
+	return remotecommand.NewFallbackExecutor(ws, spdy, ShouldFallback)
+}
+
+// ShouldFallback will return true if the given error indicates that the
+// websocket upgrade failed, and spdy should be used instead.
+func ShouldFallback(err error) bool {
+	return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
 }
@@ -0,0 +1,35 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/rest"
+)
+
+func TestShouldFallback(t *testing.T) {
+	tests := []struct {
+		in  error
+		out bool
+	}{
+		{in: errors.New("container not found"), out: false},
+		{in: &httpstream.UpgradeFailureError{Cause: errors.New("403 Forbidden")}, out: true},
+		{in: fmt.Errorf("exec failed: %w", &httpstream.UpgradeFailureError{Cause: errors.New("bad handshake")}), out: true},
+	}
+	for i, tst := range tests {
+		if res := ShouldFallback(tst.in); res != tst.out {
+			t.Errorf("failed test %d - expected %t, but got %t", i, tst.out, res)
+		}
+	}
+}
+
+func TestNewExecutor(t *testing.T) {
+	u, _ := url.Parse("https://127.0.0.1:6443/api/v1/namespaces/default/pods/kubedock-abc/exec")
+	ex, err := NewExecutor(&rest.Config{Host: "https://127.0.0.1:6443"}, u)
+	if err != nil || ex == nil {
+		t.Errorf("failed test - expected executor, but got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
 	"github.com/containers/image/v5/transports/alltransports"
 	"github.com/containers/image/v5/types"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -36,6 +37,123 @@ func InspectConfig(name string) (*v1.Image, error) {
 	return config, err
 }
 
+// Details contains the configuration of an image, together with the digest
+// of its manifest and the total (compressed) size of its layers.
+type Details struct {
+	Config *v1.Image
+	Digest string
+	Size   int64
+}
+
+// Inspect will return the configuration, manifest digest and size of the
+// specified image. (docker://docker.io/joyrex2001/kubedock:latest)
+func Inspect(name string) (*Details, error) {
+	sys := &types.SystemContext{
+		OSChoice: "linux",
+	}
+
+	ctx := context.Background()
+	src, err := parseImageSource(ctx, sys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	blob, _, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading manifest for image: %w", err)
+	}
+	dgst, err := manifest.Digest(blob)
+	if err != nil {
+		return nil, fmt.Errorf("Error computing manifest digest: %w", err)
+	}
+
+	img, err := image.FromUnparsedImage(ctx, sys, image.UnparsedInstance(src, nil))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing manifest for image: %w", err)
+	}
+
+	config, err := img.OCIConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading OCI-formatted configuration data: %w", err)
+	}
+
+	size := int64(0)
+	for _, layer := range img.LayerInfos() {
+		if layer.Size > 0 {
+			size += layer.Size
+		}
+	}
+
+	return &Details{Config: config, Digest: dgst.String(), Size: size}, nil
+}
+
+// Distribution contains the descriptor of an image manifest in the
+// registry, and the platforms that are supported by the image.
+type Distribution struct {
+	Descriptor v1.Descriptor
+	Platforms  []v1.Platform
+}
+
+// InspectDistribution will return the manifest descriptor and the supported
+// platforms of the specified image. (docker://docker.io/joyrex2001/kubedock:latest)
+func InspectDistribution(name string) (*Distribution, error) {
+	sys := &types.SystemContext{
+		OSChoice: "linux",
+	}
+
+	ctx := context.Background()
+	src, err := parseImageSource(ctx, sys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	blob, mime, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading manifest for image: %w", err)
+	}
+	dgst, err := manifest.Digest(blob)
+	if err != nil {
+		return nil, fmt.Errorf("Error computing manifest digest: %w", err)
+	}
+
+	res := &Distribution{
+		Descriptor: v1.Descriptor{
+			MediaType: mime,
+			Digest:    dgst,
+			Size:      int64(len(blob)),
+		},
+		Platforms: []v1.Platform{},
+	}
+
+	if manifest.MIMETypeIsMultiImage(mime) {
+		list, err := manifest.ListFromBlob(blob, mime)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing manifest list for image: %w", err)
+		}
+		for _, d := range list.Instances() {
+			inst, err := list.Instance(d)
+			if err != nil || inst.ReadOnly.Platform == nil {
+				continue
+			}
+			res.Platforms = append(res.Platforms, *inst.ReadOnly.Platform)
+		}
+		return res, nil
+	}
+
+	img, err := image.FromUnparsedImage(ctx, sys, image.UnparsedInstance(src, nil))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing manifest for image: %w", err)
+	}
+	config, err := img.OCIConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading OCI-formatted configuration data: %w", err)
+	}
+	res.Platforms = append(res.Platforms, config.Platform)
+	return res, nil
+}
+
 // parseImageSource converts image URL-like string to an ImageSource.
 // The caller must call .Close() on the returned ImageSource.
 func parseImageSource(ctx context.Context, sys *types.SystemContext, name string) (types.ImageSource, error) {
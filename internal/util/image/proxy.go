@@ -0,0 +1,195 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/pkg/blobinfocache/none"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	// maxSources is the max number of repositories of which the connection
+	// to the registry is kept.
+	maxSources = 64
+	// sourceTTL is the time the connection to the registry of a repository
+	// is kept after it was last used.
+	sourceTTL = 10 * time.Minute
+)
+
+// Proxy fetches manifests and blobs of images from their registry, using
+// the credentials that are configured for kubedock. Blobs are fetched with
+// the connection to the registry of an earlier fetched manifest of the same
+// repository, or a new connection if there is none. The connections are kept
+// for a limited number of repositories, and are closed when they have not
+// been used for a while and no blobs are being read from them.
+type Proxy struct {
+	lock    sync.Mutex
+	sources map[string]*source
+	max     int
+	ttl     time.Duration
+}
+
+// source is the connection to the registry of a repository. The number of
+// blobs that are being read from the connection is kept in refs; a source
+// that is evicted while in use, is closed when the last blob is closed.
+type source struct {
+	src     types.ImageSource
+	used    time.Time
+	refs    int
+	evicted bool
+}
+
+// blobReader is a blob that is read from a source, which releases the
+// source when it's closed.
+type blobReader struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Manifest is an image manifest as stored in the registry.
+type Manifest struct {
+	Blob      []byte
+	MediaType string
+	Digest    string
+}
+
+// NewProxy will return a new Proxy instance.
+func NewProxy() *Proxy {
+	return &Proxy{sources: map[string]*source{}, max: maxSources, ttl: sourceTTL}
+}
+
+// GetManifest will fetch the manifest of the specified image, and make the
+// blobs of the image available for given repository. The manifest is
+// returned as is, which means it can be a manifest list of a multi-platform
+// image. (docker://docker.io/joyrex2001/kubedock:latest)
+func (p *Proxy) GetManifest(repo, name string) (*Manifest, error) {
+	sys := &types.SystemContext{
+		OSChoice: "linux",
+	}
+
+	ctx := context.Background()
+	src, err := parseImageSource(ctx, sys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, mime, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("Error reading manifest for image: %w", err)
+	}
+	dgst, err := manifest.Digest(blob)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("Error computing manifest digest: %w", err)
+	}
+
+	p.release(p.add(repo, src))
+
+	return &Manifest{Blob: blob, MediaType: mime, Digest: dgst.String()}, nil
+}
+
+// GetBlob will fetch the blob with given digest of given repository, and
+// return it together with its size (-1 if unknown). If there is no
+// connection to the registry of the repository, a new connection is opened
+// with given image name (docker://docker.io/joyrex2001/kubedock). The source
+// is kept in use until the returned blob is closed.
+func (p *Proxy) GetBlob(repo, name, dgst string) (io.ReadCloser, int64, error) {
+	d, err := digest.Parse(dgst)
+	if err != nil {
+		return nil, 0, &PullError{Image: repo, Err: err}
+	}
+
+	p.lock.Lock()
+	p.evict()
+	cur, ok := p.sources[repo]
+	if ok {
+		cur.used = time.Now()
+		cur.refs++
+	}
+	p.lock.Unlock()
+	if !ok {
+		src, err := parseImageSource(context.Background(), &types.SystemContext{OSChoice: "linux"}, name)
+		if err != nil {
+			return nil, 0, &PullError{Image: repo, Err: err}
+		}
+		cur = p.add(repo, src)
+	}
+
+	blob, size, err := cur.src.GetBlob(context.Background(), types.BlobInfo{Digest: d, Size: -1}, none.NoCache)
+	if err != nil {
+		p.release(cur)
+		return nil, 0, err
+	}
+	return &blobReader{ReadCloser: blob, release: func() { p.release(cur) }}, size, nil
+}
+
+// add will add the given connection to the registry of given repository,
+// unless there is a connection for the repository already, in which case
+// the given connection is closed. The source of the repository is returned
+// in use, and should be released by the caller.
+func (p *Proxy) add(repo string, src types.ImageSource) *source {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	cur, ok := p.sources[repo]
+	if ok {
+		src.Close()
+	} else {
+		cur = &source{src: src}
+		p.sources[repo] = cur
+	}
+	cur.used = time.Now()
+	cur.refs++
+	p.evict()
+	return cur
+}
+
+// release will mark given source as no longer in use by the caller, and
+// closes it if it was evicted and is no longer in use at all.
+func (p *Proxy) release(cur *source) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	cur.refs--
+	if cur.refs == 0 && cur.evicted {
+		cur.src.Close()
+	}
+}
+
+// Close will close the blob, and release the source it was read from.
+func (r *blobReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
+
+// evict will remove the connections that have not been used within the
+// ttl, and the least recently used connections if there are more than the
+// max number of connections. Connections that are not in use are closed,
+// the others are closed when they are released. It should be called with
+// the lock held.
+func (p *Proxy) evict() {
+	for len(p.sources) > 0 {
+		oldest := ""
+		for repo, cur := range p.sources {
+			if oldest == "" || cur.used.Before(p.sources[oldest].used) {
+				oldest = repo
+			}
+		}
+		if len(p.sources) <= p.max && time.Since(p.sources[oldest].used) <= p.ttl {
+			return
+		}
+		cur := p.sources[oldest]
+		cur.evicted = true
+		if cur.refs == 0 {
+			cur.src.Close()
+		}
+		delete(p.sources, oldest)
+	}
+}
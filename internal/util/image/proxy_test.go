@@ -0,0 +1,95 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/types"
+)
+
+// testSource is an image source that registers if it's closed.
+type testSource struct {
+	types.ImageSource
+	closed bool
+}
+
+// Close will register the source as closed.
+func (s *testSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+// GetBlob will return a blob, unless the source is closed.
+func (s *testSource) GetBlob(context.Context, types.BlobInfo, types.BlobInfoCache) (io.ReadCloser, int64, error) {
+	if s.closed {
+		return nil, 0, errors.New("source is closed")
+	}
+	return io.NopCloser(strings.NewReader("rx78")), 4, nil
+}
+
+func TestProxyEvict(t *testing.T) {
+	tests := []struct {
+		used   []time.Duration
+		closed []bool
+	}{
+		{used: []time.Duration{0, time.Minute}, closed: []bool{false, false}},
+		{used: []time.Duration{0, time.Hour}, closed: []bool{false, true}},
+		{used: []time.Duration{0, time.Minute, 2 * time.Minute}, closed: []bool{false, false, true}},
+		{used: []time.Duration{time.Hour, time.Hour}, closed: []bool{true, true}},
+	}
+	for i, tst := range tests {
+		p := &Proxy{sources: map[string]*source{}, max: 2, ttl: 10 * time.Minute}
+		srcs := []*testSource{}
+		for j, used := range tst.used {
+			src := &testSource{}
+			srcs = append(srcs, src)
+			p.sources[string(rune('a'+j))] = &source{src: src, used: time.Now().Add(-used)}
+		}
+		p.evict()
+		for j, src := range srcs {
+			if src.closed != tst.closed[j] {
+				t.Errorf("failed test %d - expected closed %v, but got %v", i, tst.closed, srcs)
+				break
+			}
+			if _, ok := p.sources[string(rune('a'+j))]; ok == src.closed {
+				t.Errorf("failed test %d - unexpected source %d in cache", i, j)
+			}
+		}
+	}
+}
+
+func TestProxyGetBlobEvicted(t *testing.T) {
+	dgst := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	src := &testSource{}
+	p := &Proxy{sources: map[string]*source{"rx78": {src: src, used: time.Now()}}, max: 1, ttl: 10 * time.Minute}
+	blob, size, err := p.GetBlob("rx78", "docker://rx78", dgst)
+	if err != nil || size != 4 {
+		t.Fatalf("expected blob of 4 bytes, but got %d, %v", size, err)
+	}
+	p.add("zaku", &testSource{})
+	if _, ok := p.sources["rx78"]; ok || src.closed {
+		t.Errorf("expected source to be evicted, but not closed while in use")
+	}
+	if dat, err := io.ReadAll(blob); err != nil || string(dat) != "rx78" {
+		t.Errorf("expected blob to be readable after eviction, but got %s, %v", dat, err)
+	}
+	blob.Close()
+	blob.Close()
+	if !src.closed {
+		t.Errorf("expected evicted source to be closed when the blob is closed")
+	}
+}
+
+func TestProxyGetBlobMiss(t *testing.T) {
+	dgst := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	p := NewProxy()
+	_, _, err := p.GetBlob("rx78", "nope://rx78", dgst)
+	var perr *PullError
+	if !errors.As(err, &perr) || strings.Contains(err.Error(), "no manifest") {
+		t.Errorf("expected a new source to be opened for an unknown repository, but got %v", err)
+	}
+}
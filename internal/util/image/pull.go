@@ -0,0 +1,84 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+)
+
+// notFoundErrors are the (lowercase) fragments of registry and kubelet
+// error messages that indicate an image doesn't exist.
+var notFoundErrors = []string{"manifest unknown", "name unknown", "not found"}
+
+// deniedErrors are the (lowercase) fragments of registry and kubelet error
+// messages that indicate access to an image is denied.
+var deniedErrors = []string{"denied", "unauthorized", "authentication required", "authorization failed", "forbidden"}
+
+// PullError is the error returned when an image can't be pulled because it
+// doesn't exist, or because access to it is denied. The messages are the
+// same as docker uses, and docker reports both as not found (404), so
+// clients don't retry pulls that will never succeed.
+type PullError struct {
+	Image  string
+	Denied bool
+	Err    error
+}
+
+// Error will return the error message.
+func (e *PullError) Error() string {
+	if e.Denied {
+		return fmt.Sprintf("pull access denied for %s, repository does not exist or may require 'docker login': %s", getRepository(e.Image), e.Err)
+	}
+	return fmt.Sprintf("manifest for %s not found: %s", e.Image, e.Err)
+}
+
+// Unwrap will return the original error.
+func (e *PullError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus will return the http status that should be used when this
+// error is returned to a client.
+func (e *PullError) HTTPStatus() int {
+	return http.StatusNotFound
+}
+
+// ClassifyPullError will return a PullError for given image if the given
+// error indicates that the image doesn't exist or that access is denied,
+// otherwise the error itself is returned (e.g. for transient errors).
+func ClassifyPullError(img string, err error) error {
+	var perr *PullError
+	if err == nil || errors.As(err, &perr) {
+		return err
+	}
+	var uerr docker.ErrUnauthorizedForCredentials
+	if errors.As(err, &uerr) {
+		return &PullError{Image: img, Denied: true, Err: err}
+	}
+	msg := strings.ToLower(err.Error())
+	for _, frag := range notFoundErrors {
+		if strings.Contains(msg, frag) {
+			return &PullError{Image: img, Err: err}
+		}
+	}
+	for _, frag := range deniedErrors {
+		if strings.Contains(msg, frag) {
+			return &PullError{Image: img, Denied: true, Err: err}
+		}
+	}
+	return err
+}
+
+// getRepository will return the repository of given image, which is the
+// image without tag or digest.
+func getRepository(img string) string {
+	ref, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return img
+	}
+	return reference.FamiliarName(ref)
+}
@@ -0,0 +1,37 @@
+package image
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/containers/image/v5/docker"
+)
+
+func TestClassifyPullError(t *testing.T) {
+	tests := []struct {
+		img string
+		in  error
+		out string
+	}{
+		{img: "alpine:nope", in: nil, out: ""},
+		{img: "alpine:nope", in: errors.New("reading manifest nope in docker.io/library/alpine: manifest unknown"), out: "manifest for alpine:nope not found: reading manifest nope in docker.io/library/alpine: manifest unknown"},
+		{img: "joyrex2001/secret:1.0", in: errors.New("requested access to the resource is denied"), out: "pull access denied for joyrex2001/secret, repository does not exist or may require 'docker login': requested access to the resource is denied"},
+		{img: "registry.local:5000/app:1.0", in: docker.ErrUnauthorizedForCredentials{Err: errors.New("bad credentials")}, out: "pull access denied for registry.local:5000/app, repository does not exist or may require 'docker login': unable to retrieve auth token: invalid username/password: bad credentials"},
+		{img: "alpine:latest", in: errors.New("dial tcp: i/o timeout"), out: "dial tcp: i/o timeout"},
+	}
+	for i, tst := range tests {
+		err := ClassifyPullError(tst.img, tst.in)
+		res := ""
+		if err != nil {
+			res = err.Error()
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+		var perr *PullError
+		if errors.As(err, &perr) && perr.HTTPStatus() != http.StatusNotFound {
+			t.Errorf("failed test %d - expected status %d, but got %d", i, http.StatusNotFound, perr.HTTPStatus())
+		}
+	}
+}
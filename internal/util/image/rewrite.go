@@ -0,0 +1,117 @@
+package image
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// RewriteRule describes a rule to rewrite an image reference that matches
+// From to To. Both From and To can end with a '*' wildcard, in which case
+// the remainder of the matched reference is appended to To.
+type RewriteRule struct {
+	From string
+	To   string
+}
+
+// ParseRewriteRules will parse a comma separated list of rewrite rules in
+// the form of from=to (e.g. docker.io/library/*=mirror.local/dockerhub/*).
+func ParseRewriteRules(rules string) ([]RewriteRule, error) {
+	res := []RewriteRule{}
+	for _, rule := range strings.Split(strings.ReplaceAll(rules, " ", ""), ",") {
+		if rule == "" {
+			continue
+		}
+		from, to, found := strings.Cut(rule, "=")
+		if !found || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid image rewrite rule %s, expected from=to", rule)
+		}
+		if strings.HasSuffix(to, "*") && !strings.HasSuffix(from, "*") {
+			return nil, fmt.Errorf("invalid image rewrite rule %s, wildcard in target requires wildcard in source", rule)
+		}
+		res = append(res, RewriteRule{From: from, To: to})
+	}
+	return res, nil
+}
+
+// Rewrite will rewrite the given image reference with the first matching
+// rule. Rules are matched against the fully qualified reference (e.g. redis
+// is matched as docker.io/library/redis). If no rule matches, the original
+// reference is returned.
+func Rewrite(name string, rules []RewriteRule) string {
+	if len(rules) == 0 {
+		return name
+	}
+	full := Normalize(name)
+	for _, rule := range rules {
+		if !strings.HasSuffix(rule.From, "*") {
+			if rule.From == full || rule.From == name {
+				return rule.To
+			}
+			continue
+		}
+		prefix := strings.TrimSuffix(rule.From, "*")
+		rest, ok := strings.CutPrefix(full, prefix)
+		if !ok {
+			if rest, ok = strings.CutPrefix(name, prefix); !ok {
+				continue
+			}
+		}
+		return strings.TrimSuffix(rule.To, "*") + rest
+	}
+	return name
+}
+
+// Normalize will return the fully qualified reference of the given image,
+// adding the default docker.io registry and library namespace when these
+// are omitted.
+func Normalize(name string) string {
+	domain, rest, found := strings.Cut(name, "/")
+	if !found || (!strings.ContainsAny(domain, ".:") && domain != "localhost") {
+		domain, rest = "docker.io", name
+	}
+	if domain == "docker.io" && !strings.Contains(rest, "/") {
+		rest = "library/" + rest
+	}
+	return domain + "/" + rest
+}
+
+// Pin will return the given image reference with its tag replaced by the
+// given digest (e.g. redis:7 becomes redis@sha256:...).
+func Pin(name, digest string) string {
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name + "@" + digest
+}
+
+// Match will return true if the given image reference matches the given
+// glob pattern (e.g. elasticsearch:*). The pattern is matched against the
+// reference as given and the normalized forms of the reference, so
+// postgres:16, library/postgres:16 and docker.io/library/postgres:16 are
+// matched alike.
+func Match(pattern, name string) bool {
+	for _, ref := range getForms(name) {
+		if ok, _ := path.Match(pattern, ref); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getForms will return the given image reference, its fully qualified
+// reference, and for docker hub images, the reference without registry and
+// without library namespace.
+func getForms(name string) []string {
+	full := Normalize(name)
+	res := []string{name, full}
+	if rest, ok := strings.CutPrefix(full, "docker.io/"); ok {
+		res = append(res, rest)
+		if short, ok := strings.CutPrefix(rest, "library/"); ok {
+			res = append(res, short)
+		}
+	}
+	return res
+}
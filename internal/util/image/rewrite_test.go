@@ -0,0 +1,126 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "redis", out: "docker.io/library/redis"},
+		{in: "redis:7", out: "docker.io/library/redis:7"},
+		{in: "joyrex2001/kubedock:0.18", out: "docker.io/joyrex2001/kubedock:0.18"},
+		{in: "docker.io/redis", out: "docker.io/library/redis"},
+		{in: "quay.io/prometheus/node-exporter", out: "quay.io/prometheus/node-exporter"},
+		{in: "localhost/alpine", out: "localhost/alpine"},
+		{in: "registry:5000/alpine", out: "registry:5000/alpine"},
+	}
+	for i, tst := range tests {
+		if res := Normalize(tst.in); res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "redis", out: "redis@sha256:1234"},
+		{in: "redis:7", out: "redis@sha256:1234"},
+		{in: "registry:5000/alpine", out: "registry:5000/alpine@sha256:1234"},
+		{in: "registry:5000/alpine:3", out: "registry:5000/alpine@sha256:1234"},
+		{in: "redis@sha256:abcd", out: "redis@sha256:1234"},
+	}
+	for i, tst := range tests {
+		if res := Pin(tst.in, "sha256:1234"); res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
+
+func TestParseRewriteRules(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []RewriteRule
+		suc bool
+	}{
+		{in: "", out: []RewriteRule{}, suc: true},
+		{
+			in:  "docker.io/library/*=mirror.local/dockerhub/*, quay.io/*=mirror.local/quay/*",
+			out: []RewriteRule{{"docker.io/library/*", "mirror.local/dockerhub/*"}, {"quay.io/*", "mirror.local/quay/*"}},
+			suc: true,
+		},
+		{in: "redis:7=mirror.local/redis:7", out: []RewriteRule{{"redis:7", "mirror.local/redis:7"}}, suc: true},
+		{in: "docker.io/library/*", suc: false},
+		{in: "redis=mirror.local/*", suc: false},
+	}
+	for i, tst := range tests {
+		res, err := ParseRewriteRules(tst.in)
+		if tst.suc && err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if !tst.suc && err == nil {
+			t.Errorf("failed test %d - expected error, but succeeded instead", i)
+		}
+		if tst.suc && !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	rules := []RewriteRule{
+		{"docker.io/library/*", "mirror.local/dockerhub/library/*"},
+		{"docker.io/*", "mirror.local/dockerhub/*"},
+		{"quay.io/keycloak/keycloak:21", "mirror.local/keycloak:21"},
+	}
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "redis:7", out: "mirror.local/dockerhub/library/redis:7"},
+		{in: "docker.io/library/postgres", out: "mirror.local/dockerhub/library/postgres"},
+		{in: "testcontainers/ryuk:0.5.1", out: "mirror.local/dockerhub/testcontainers/ryuk:0.5.1"},
+		{in: "quay.io/keycloak/keycloak:21", out: "mirror.local/keycloak:21"},
+		{in: "quay.io/keycloak/keycloak:22", out: "quay.io/keycloak/keycloak:22"},
+		{in: "ghcr.io/foo/bar", out: "ghcr.io/foo/bar"},
+	}
+	for i, tst := range tests {
+		if res := Rewrite(tst.in, rules); res != tst.out {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+	if res := Rewrite("redis", nil); res != "redis" {
+		t.Errorf("failed test without rules - expected redis, but got %s", res)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		out     bool
+	}{
+		{pattern: "elasticsearch:*", name: "elasticsearch:8.11.1", out: true},
+		{pattern: "elasticsearch:*", name: "elasticsearch", out: false},
+		{pattern: "postgres*", name: "postgres", out: true},
+		{pattern: "postgres*", name: "docker.io/library/postgres:16", out: true},
+		{pattern: "postgres:*", name: "library/postgres:16", out: true},
+		{pattern: "library/postgres:*", name: "postgres:16", out: true},
+		{pattern: "postgres:*", name: "quay.io/library/postgres:16", out: false},
+		{pattern: "bitnami/*", name: "docker.io/bitnami/redis:7", out: true},
+		{pattern: "docker.io/library/postgres*", name: "postgres:16", out: true},
+		{pattern: "quay.io/*/*", name: "quay.io/prometheus/node-exporter", out: true},
+		{pattern: "[", name: "redis", out: false},
+	}
+	for i, tst := range tests {
+		if res := Match(tst.pattern, tst.name); res != tst.out {
+			t.Errorf("failed test %d - expected %t, but got %t", i, tst.out, res)
+		}
+	}
+}
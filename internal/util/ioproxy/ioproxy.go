@@ -24,11 +24,12 @@ const (
 // IoProxy is a proxy writer which adds the output prefix before writing data.
 type IoProxy struct {
 	io.Writer
-	out     io.Writer
-	prefix  StdType
-	buf     []byte
-	flusher bool
-	lock    *sync.Mutex
+	out        io.Writer
+	prefix     StdType
+	buf        []byte
+	flusher    bool
+	unbuffered bool
+	lock       *sync.Mutex
 }
 
 // New will return a new IoProxy instance.
@@ -41,12 +42,32 @@ func New(w io.Writer, prefix StdType, lock *sync.Mutex) *IoProxy {
 	}
 }
 
+// NewUnbuffered will return a new IoProxy instance that writes every chunk
+// of data as a single frame immediately, and flushes the underlying writer
+// if supported. This is intended for interactive streams, where latency
+// matters more than the number of frames.
+func NewUnbuffered(w io.Writer, prefix StdType, lock *sync.Mutex) *IoProxy {
+	iop := New(w, prefix, lock)
+	iop.unbuffered = true
+	return iop
+}
+
 // Write will write given data to the an internal buffer, which will be
 // flushed if a newline is encountered, of when the maximum size of the
 // buffer has been reached.
 func (w *IoProxy) Write(p []byte) (int, error) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
+	if w.unbuffered {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		if err := w.write(p); err != nil {
+			return 0, err
+		}
+		flush(w.out)
+		return len(p), nil
+	}
 	w.buf = append(w.buf, p...)
 	for w.process() != 0 {
 	}
@@ -87,31 +108,39 @@ func (w *IoProxy) process() int {
 }
 
 // write will write data to the configured writer, using the correct header.
+// The header and data are written at once, to prevent them from being sent
+// as separate packets.
 func (w *IoProxy) write(p []byte) error {
-	header := [8]byte{}
-	header[0] = byte(w.prefix)
-	binary.BigEndian.PutUint32(header[4:], uint32(len(p)))
-	err := w.writeAll(w.out, header[:])
-	if err != nil {
-		klog.Errorf("Error when writing docker log header: %v", err)
-		return err
-	}
-	err = w.writeAll(w.out, p)
+	frame := make([]byte, 8+len(p))
+	frame[0] = byte(w.prefix)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(p)))
+	copy(frame[8:], p)
+	err := w.writeAll(w.out, frame)
 	if err != nil {
-		klog.Errorf("Error ehen writing docker log content: %v", err)
+		klog.Errorf("Error when writing docker stream frame: %v", err)
 	}
 	return err
 }
 
+// flush will flush given writer, if it supports flushing.
+func flush(out io.Writer) {
+	switch f := out.(type) {
+	case interface{ Flush() error }:
+		f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+}
+
 // Flush will write all buffer data still present.
 func (w *IoProxy) Flush() error {
 	w.lock.Lock()
 	defer w.lock.Unlock()
+	w.flusher = false
 	if len(w.buf) == 0 {
 		return nil
 	}
 	err := w.write(w.buf)
 	w.buf = []byte{}
-	w.flusher = false
 	return err
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"sync"
 	"testing"
+	"time"
 )
 
 type ShortWriteBuffer struct {
@@ -96,3 +97,41 @@ func TestLargeLine(t *testing.T) {
 		t.Errorf("failed large line test - buffer size was not linesize + header (%d) but %d", 1350+8, len(buf.Bytes()))
 	}
 }
+
+func TestDelayedFlush(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lock := &sync.Mutex{}
+	iop := New(buf, Stderr, lock)
+
+	// a pending delayed flush on an already processed buffer should not
+	// prevent subsequent partial writes from being flushed
+	iop.Write([]byte("foo"))
+	iop.Write([]byte("\n"))
+	time.Sleep(200 * time.Millisecond)
+	iop.Write([]byte("bar"))
+	time.Sleep(200 * time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	exp := []byte{
+		0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x4, 0x66, 0x6f, 0x6f, 0xa,
+		0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x3, 0x62, 0x61, 0x72,
+	}
+	if !bytes.Equal(buf.Bytes(), exp) {
+		t.Errorf("failed delayed flush - expected %v, but got %v", exp, buf.Bytes())
+	}
+}
+
+func TestUnbuffered(t *testing.T) {
+	buf := &ShortWriteBuffer{}
+	iop := NewUnbuffered(buf, Stdout, &sync.Mutex{})
+	iop.Write([]byte("psql> "))
+	exp := []byte{0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x6, 0x70, 0x73, 0x71, 0x6c, 0x3e, 0x20}
+	if !bytes.Equal(buf.Bytes(), exp) {
+		t.Errorf("failed unbuffered write - expected %v, but got %v", exp, buf.Bytes())
+	}
+	iop.Flush()
+	if !bytes.Equal(buf.Bytes(), exp) {
+		t.Errorf("failed unbuffered flush - expected %v, but got %v", exp, buf.Bytes())
+	}
+}
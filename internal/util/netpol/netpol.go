@@ -0,0 +1,84 @@
+package netpol
+
+import (
+	"bytes"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+// Request is the structure used as argument for Generate
+type Request struct {
+	// Name is the name of the service and network policy
+	Name string
+	// Namespace is the namespace kubedock is running in
+	Namespace string
+	// Port is the port the kubedock api is listening on
+	Port int
+	// Selector contains the labels that select the kubedock pod
+	Selector map[string]string
+	// CIDRs contains the networks of the clients that are allowed to reach
+	// the kubedock api; if empty, only pods in the same namespace are allowed
+	CIDRs []*net.IPNet
+}
+
+// Generate will return a yaml document with a service that exposes the
+// kubedock api, and a network policy that only allows the given clients to
+// reach it.
+func Generate(req Request) ([]byte, error) {
+	port := intstr.FromInt(req.Port)
+	tcp := corev1.ProtocolTCP
+	svc := corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: req.Selector,
+			Ports: []corev1.ServicePort{
+				{Name: "docker", Protocol: tcp, Port: int32(req.Port), TargetPort: port},
+			},
+		},
+	}
+
+	peers := []networkingv1.NetworkPolicyPeer{}
+	for _, cidr := range req.CIDRs {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr.String()}})
+	}
+	if len(peers) == 0 {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}})
+	}
+	pol := networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: req.Selector},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From:  peers,
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	for i, obj := range []interface{}{svc, pol} {
+		dat, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(dat)
+	}
+	return buf.Bytes(), nil
+}
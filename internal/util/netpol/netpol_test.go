@@ -0,0 +1,36 @@
+package netpol
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	_, cidr, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		in    Request
+		match []string
+	}{
+		{
+			in:    Request{Name: "kubedock", Namespace: "ci", Port: 2475, Selector: map[string]string{"app": "kubedock"}},
+			match: []string{"kind: Service", "kind: NetworkPolicy", "namespace: ci", "targetPort: 2475", "podSelector: {}", "app: kubedock"},
+		},
+		{
+			in:    Request{Name: "docker", Namespace: "ci", Port: 2375, Selector: map[string]string{"app": "kubedock"}, CIDRs: []*net.IPNet{cidr}},
+			match: []string{"name: docker", "cidr: 10.0.0.0/8", "port: 2375"},
+		},
+	}
+	for i, tst := range tests {
+		res, err := Generate(tst.in)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+			continue
+		}
+		for _, m := range tst.match {
+			if !strings.Contains(string(res), m) {
+				t.Errorf("failed test %d - expected %s, but got %s", i, m, res)
+			}
+		}
+	}
+}
@@ -4,15 +4,21 @@ package portforward
 
 import (
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/util/exec"
 )
 
 // Request is the structure used as argument for ToPod
@@ -29,16 +35,30 @@ type Request struct {
 	StopCh <-chan struct{}
 	// ReadyCh communicates when the tunnel is ready to receive traffic
 	ReadyCh chan struct{}
+	// Out is the optional writer that receives the output of the port
+	// forward (e.g. handled connections); defaults to the klog logger.
+	Out io.Writer
+	// Opened is an optional function that is called when a connection is
+	// accepted.
+	Opened func()
+	// Closed is an optional function that is called when an accepted
+	// connection is closed.
+	Closed func()
 }
 
-// ToPod will portforward to given pod.
+// ToPod will portforward to given pod. The port-forward itself listens on an
+// ephemeral loopback port, and connections on the local port are piped to it
+// so it's known when connections are closed.
 func ToPod(req Request) error {
 	transport, upgrader, err := spdy.RoundTripperFor(req.RestConfig)
 	if err != nil {
 		return err
 	}
 
-	logr := NewLogger()
+	logr := req.Out
+	if logr == nil {
+		logr = NewLogger()
+	}
 	klog.Infof("start port-forward %d->%d", req.LocalPort, req.PodPort)
 
 	url, err := getURLScheme(req)
@@ -46,13 +66,112 @@ func ToPod(req Request) error {
 		return err
 	}
 
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
-	fw, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", req.LocalPort, req.PodPort)}, req.StopCh, req.ReadyCh, logr, logr)
+	// use spdy over websockets, and fall back to plain spdy if the api
+	// server doesn't support it
+	var dialer httpstream.Dialer = spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+	wsdialer, err := portforward.NewSPDYOverWebsocketDialer(url, req.RestConfig)
 	if err != nil {
 		return err
 	}
+	dialer = portforward.NewFallbackDialer(wsdialer, dialer, exec.ShouldFallback)
+	ready := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", req.PodPort)}, req.StopCh, ready, logr, logr)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fw.ForwardPorts()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ready:
+	}
 
-	return fw.ForwardPorts()
+	ports, err := fw.GetPorts()
+	if err != nil || len(ports) == 0 {
+		fw.Close()
+		return fmt.Errorf("error getting forwarded port: %v", err)
+	}
+	listeners, err := listen(req.LocalPort)
+	if err != nil {
+		fw.Close()
+		return err
+	}
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	remote := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(ports[0].Local)))
+	for _, l := range listeners {
+		go req.serve(l, remote)
+	}
+	if req.ReadyCh != nil {
+		close(req.ReadyCh)
+	}
+
+	return <-done
+}
+
+// listen will listen on the given port on both the ipv4 and ipv6 loopback
+// address, and only fails if neither is available.
+func listen(port int) ([]net.Listener, error) {
+	res := []net.Listener{}
+	var err error
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		l, lerr := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if lerr != nil {
+			err = lerr
+			continue
+		}
+		res = append(res, l)
+	}
+	if len(res) == 0 {
+		return nil, err
+	}
+	return res, nil
+}
+
+// serve will accept connections on given listener and pipe them to given
+// remote address, until the listener is closed.
+func (req Request) serve(l net.Listener, remote string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			if req.Opened != nil {
+				req.Opened()
+			}
+			if err := pipe(conn, remote); err != nil {
+				klog.Errorf("error dialing port-forward %s: %s", remote, err)
+			}
+			if req.Closed != nil {
+				req.Closed()
+			}
+		}()
+	}
+}
+
+// pipe will copy the data between given connection and a new connection to
+// given remote address, and closes both when either side is done.
+func pipe(conn net.Conn, remote string) error {
+	defer conn.Close()
+	conn2, err := net.Dial("tcp", remote)
+	if err != nil {
+		return err
+	}
+	defer conn2.Close()
+	go func() {
+		io.Copy(conn2, conn)
+		conn2.Close()
+	}()
+	io.Copy(conn, conn2)
+	return nil
 }
 
 // getURLScheme will take given request and create a valid url scheme for use
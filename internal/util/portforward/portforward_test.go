@@ -1,9 +1,13 @@
 package portforward
 
 import (
+	"io"
+	"net"
 	"net/url"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -62,3 +66,55 @@ func TestGetURLScheme(t *testing.T) {
 		}
 	}
 }
+
+func TestServe(t *testing.T) {
+	remote, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %s", err)
+	}
+	defer remote.Close()
+	go func() {
+		for {
+			conn, err := remote.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	local, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %s", err)
+	}
+	defer local.Close()
+	var conns atomic.Int64
+	req := Request{
+		Opened: func() { conns.Add(1) },
+		Closed: func() { conns.Add(-1) },
+	}
+	go req.serve(local, remote.Addr().String())
+
+	conn, err := net.Dial("tcp", local.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %s", err)
+	}
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("expected ping, but got %s (%v)", buf, err)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("expected 1 open connection, but got %d", n)
+	}
+	conn.Close()
+	for i := 0; i < 50 && conns.Load() != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := conns.Load(); n != 0 {
+		t.Errorf("expected 0 open connections, but got %d", n)
+	}
+}
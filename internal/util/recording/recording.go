@@ -0,0 +1,375 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted is the value that replaces secrets in recordings.
+const Redacted = "<redacted>"
+
+// MaxBodySize is the max number of bytes of a request or response body that
+// is recorded.
+const MaxBodySize = 64 * 1024
+
+// secretKey matches the names of headers, query parameters and json fields
+// that contain secrets.
+var secretKey = regexp.MustCompile(`(?i)(passw|secret|token|auth|credential|apikey|api_key|private)`)
+
+// argvKeys are the names of json fields with command lines, of which the
+// arguments can contain secrets.
+var argvKeys = []string{"cmd", "entrypoint", "command", "args"}
+
+// secretHeaders are the headers that always contain secrets.
+var secretHeaders = []string{"Cookie", "Set-Cookie", "X-Registry-Auth", "X-Registry-Config"}
+
+// plainFilters are the filters of list requests of which the values are
+// fixed keywords (e.g. status=running), and are recorded as is; the values of
+// other filters (e.g. labels and names) are redacted.
+var plainFilters = []string{"status", "type", "dangling", "health", "exited", "is-task", "driver", "scope", "desired-state"}
+
+// sessionIdle is the time after which the recording of a client session is
+// closed if the client made no requests; later requests of the client are
+// recorded in a new recording file.
+const sessionIdle = 10 * time.Minute
+
+// Exchange is a recorded request and its response.
+type Exchange struct {
+	Time           time.Time     `json:"time"`
+	Duration       time.Duration `json:"duration"`
+	Method         string        `json:"method"`
+	Path           string        `json:"path"`
+	Query          string        `json:"query,omitempty"`
+	RequestHeader  http.Header   `json:"requestHeader,omitempty"`
+	RequestBody    string        `json:"requestBody,omitempty"`
+	RequestSize    int64         `json:"requestSize"`
+	Status         int           `json:"status"`
+	ResponseHeader http.Header   `json:"responseHeader,omitempty"`
+	ResponseBody   string        `json:"responseBody,omitempty"`
+	ResponseSize   int64         `json:"responseSize"`
+}
+
+// Recorder writes sanitized exchanges to a recording file per client
+// session.
+type Recorder struct {
+	dir      string
+	idle     time.Duration
+	sessions map[string]*session
+	files    []string
+	lock     sync.Mutex
+}
+
+// session is the recording of the requests of a client.
+type session struct {
+	file *os.File
+	used time.Time
+}
+
+// New will return a Recorder that writes the recordings of the client
+// sessions to the given directory.
+func New(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, idle: sessionIdle, sessions: map[string]*session{}}, nil
+}
+
+// Dir will return the directory the recordings are written to.
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// Files will return the names of the recording files that have been written,
+// in the order the sessions started.
+func (r *Recorder) Files() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.files...)
+}
+
+// Record will sanitize given exchange and append it to the recording of the
+// session of given client (e.g. its address and user agent). A session ends
+// when the client made no requests for a while.
+func (r *Recorder) Record(client string, ex *Exchange) error {
+	ex.Sanitize()
+	data, err := json.Marshal(ex)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.expire()
+	ses, ok := r.sessions[client]
+	if !ok {
+		ses, err = r.open(client)
+		if err != nil {
+			return err
+		}
+		r.sessions[client] = ses
+	}
+	ses.used = time.Now()
+	_, err = ses.file.Write(append(data, '\n'))
+	return err
+}
+
+// open will create a new recording file for the session of given client,
+// named after the start of the session and a hash of the client.
+func (r *Recorder) open(client string) (*session, error) {
+	sum := sha256.Sum256([]byte(client))
+	name := fmt.Sprintf("kubedock-%s-%d-%s.jsonl", time.Now().Format("20060102-150405"), os.Getpid(), hex.EncodeToString(sum[:])[:8])
+	file, err := os.OpenFile(filepath.Join(r.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r.files = append(r.files, file.Name())
+	return &session{file: file}, nil
+}
+
+// expire will close the recordings of the sessions that have been idle for
+// longer than the idle time. It should be called with the lock held.
+func (r *Recorder) expire() {
+	for client, ses := range r.sessions {
+		if time.Since(ses.used) > r.idle {
+			ses.file.Close()
+			delete(r.sessions, client)
+		}
+	}
+}
+
+// Close will close the recording files of all sessions.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var res error
+	for client, ses := range r.sessions {
+		if err := ses.file.Close(); err != nil {
+			res = err
+		}
+		delete(r.sessions, client)
+	}
+	return res
+}
+
+// Read will return all exchanges in the given recording file.
+func Read(file string) ([]Exchange, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res := []Exchange{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 4*MaxBodySize)
+	for line := 1; scanner.Scan(); line++ {
+		ex := Exchange{}
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("invalid exchange on line %d: %w", line, err)
+		}
+		res = append(res, ex)
+	}
+	return res, scanner.Err()
+}
+
+// Sanitize will remove secrets from the exchange: headers, query parameters
+// and json fields with a name that indicates a secret, the values of
+// environment variables and labels, the values of filters (except keywords
+// such as status=running), and command line arguments (except the names of
+// flags) are redacted. Bodies that are not json (e.g. tar
+// archives and log streams), or that are truncated, are removed; only their
+// size is kept.
+func (ex *Exchange) Sanitize() {
+	ex.RequestHeader = sanitizeHeader(ex.RequestHeader)
+	ex.ResponseHeader = sanitizeHeader(ex.ResponseHeader)
+	ex.Query = sanitizeQuery(ex.Query)
+	ex.RequestBody = sanitizeBody(ex.RequestBody, ex.RequestSize)
+	ex.ResponseBody = sanitizeBody(ex.ResponseBody, ex.ResponseSize)
+}
+
+// sanitizeHeader will return a copy of given header with secrets redacted.
+func sanitizeHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	res := header.Clone()
+	for key := range res {
+		if secretKey.MatchString(key) {
+			res[key] = []string{Redacted}
+		}
+	}
+	for _, key := range secretHeaders {
+		if _, ok := res[key]; ok {
+			res[key] = []string{Redacted}
+		}
+	}
+	return res
+}
+
+// sanitizeQuery will return given query string with secrets redacted.
+func sanitizeQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return Redacted
+	}
+	for key := range values {
+		if secretKey.MatchString(key) {
+			values[key] = []string{Redacted}
+		} else if key == "filters" {
+			for i, val := range values[key] {
+				values[key][i] = sanitizeFilters(val)
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// sanitizeFilters will return the given json encoded filters of a list
+// request with their values redacted. Labels keep their key (label=app), as
+// do the values of plain filters. Both the map ({"label":{"app=x":true}})
+// and the list ({"label":["app=x"]}) notation are supported.
+func sanitizeFilters(val string) string {
+	filters := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(val), &filters); err != nil {
+		return Redacted
+	}
+	for typ, vals := range filters {
+		switch v := vals.(type) {
+		case map[string]interface{}:
+			res := map[string]interface{}{}
+			for k, e := range v {
+				res[redactFilter(typ, k)] = e
+			}
+			filters[typ] = res
+		case []interface{}:
+			for i, e := range v {
+				s, _ := e.(string)
+				v[i] = redactFilter(typ, s)
+			}
+		default:
+			filters[typ] = Redacted
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(filters); err != nil {
+		return Redacted
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactFilter will return given value of the filter with given name
+// redacted.
+func redactFilter(typ, val string) string {
+	for _, f := range plainFilters {
+		if f == typ {
+			return val
+		}
+	}
+	if typ == "label" || typ == "label!" {
+		if name, _, ok := strings.Cut(val, "="); ok {
+			return name + "=" + Redacted
+		}
+		return val
+	}
+	return Redacted
+}
+
+// sanitizeBody will return given body, with given total size, with secrets
+// redacted. A body that consists of multiple json documents (e.g. a stream
+// of events) is sanitized per document.
+func sanitizeBody(body string, size int64) string {
+	if body == "" || int64(len(body)) != size {
+		return ""
+	}
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	docs := []string{}
+	for dec.More() {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return ""
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(redact("", doc)); err != nil {
+			return ""
+		}
+		docs = append(docs, strings.TrimSuffix(buf.String(), "\n"))
+	}
+	return strings.Join(docs, "\n")
+}
+
+// redact will return given json value with secrets redacted, where key is
+// the name of the field that contains the value.
+func redact(key string, val interface{}) interface{} {
+	if secretKey.MatchString(key) {
+		return Redacted
+	}
+	env := strings.EqualFold(key, "env")
+	argv := isArgv(key)
+	switch v := val.(type) {
+	case string:
+		if argv {
+			return redactArg(v)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			if env || strings.EqualFold(key, "labels") {
+				v[k] = Redacted
+			} else {
+				v[k] = redact(k, e)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if s, ok := e.(string); ok && env {
+				name, _, _ := strings.Cut(s, "=")
+				v[i] = name + "=" + Redacted
+			} else if ok && argv {
+				v[i] = redactArg(s)
+			} else {
+				v[i] = redact("", e)
+			}
+		}
+	}
+	return val
+}
+
+// isArgv will return true if the json field with given name contains a
+// command line.
+func isArgv(key string) bool {
+	for _, k := range argvKeys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactArg will return given command line argument redacted; only the name
+// of a flag (e.g. --user of --user=joe) is kept.
+func redactArg(arg string) string {
+	if !strings.HasPrefix(arg, "-") {
+		return Redacted
+	}
+	if name, _, ok := strings.Cut(arg, "="); ok {
+		return name + "=" + Redacted
+	}
+	return arg
+}
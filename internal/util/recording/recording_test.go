@@ -0,0 +1,157 @@
+package recording
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanitize(t *testing.T) {
+	createBody := `{"Image":"alpine","Env":["USER=joe","PASSWORD=s3cr3t"],"Labels":{"db.password":"s3cr3t","app":"tb303"},"Entrypoint":"sh","Cmd":["psql","--password=s3cr3t","-p","s3cr3t"]}`
+	execBody := `{"Cmd":["sh","-c","echo s3cr3t"],"AttachStdout":true}`
+	podmanBody := `{"env":{"HOME":"/root"},"secret_env":{"TOKEN":"abc"}}`
+	eventsBody := "{\"Id\":\"tb303\",\"Size\":12345678901234567}\n{\"Id\":\"tr808\"}\n"
+	tests := []struct {
+		in  Exchange
+		out Exchange
+	}{
+		{
+			in: Exchange{
+				Query:         "fromImage=alpine&registryToken=s3cr3t",
+				RequestHeader: http.Header{"X-Registry-Auth": {"abc"}, "Authorization": {"Bearer abc"}, "Content-Type": {"application/json"}},
+				RequestBody:   createBody,
+				RequestSize:   int64(len(createBody)),
+			},
+			out: Exchange{
+				Query:         "fromImage=alpine&registryToken=%3Credacted%3E",
+				RequestHeader: http.Header{"X-Registry-Auth": {Redacted}, "Authorization": {Redacted}, "Content-Type": {"application/json"}},
+				RequestBody:   `{"Cmd":["<redacted>","--password=<redacted>","-p","<redacted>"],"Entrypoint":"<redacted>","Env":["USER=<redacted>","PASSWORD=<redacted>"],"Image":"alpine","Labels":{"app":"<redacted>","db.password":"<redacted>"}}`,
+				RequestSize:   int64(len(createBody)),
+			},
+		},
+		{
+			in:  Exchange{Query: `all=1&filters={"label":{"db.password=s3cr3t":true,"app":true},"status":{"running":true},"name":{"f1spirit":true}}`},
+			out: Exchange{Query: "all=1&filters=%7B%22label%22%3A%7B%22app%22%3Atrue%2C%22db.password%3D%3Credacted%3E%22%3Atrue%7D%2C%22name%22%3A%7B%22%3Credacted%3E%22%3Atrue%7D%2C%22status%22%3A%7B%22running%22%3Atrue%7D%7D"},
+		},
+		{
+			in:  Exchange{Query: `filters={"label":["app=tb303"],"id":["abc"]}`},
+			out: Exchange{Query: "filters=%7B%22id%22%3A%5B%22%3Credacted%3E%22%5D%2C%22label%22%3A%5B%22app%3D%3Credacted%3E%22%5D%7D"},
+		},
+		{
+			in:  Exchange{RequestBody: podmanBody, RequestSize: int64(len(podmanBody))},
+			out: Exchange{RequestBody: `{"env":{"HOME":"<redacted>"},"secret_env":"<redacted>"}`, RequestSize: int64(len(podmanBody))},
+		},
+		{
+			in:  Exchange{RequestBody: execBody, RequestSize: int64(len(execBody))},
+			out: Exchange{RequestBody: `{"AttachStdout":true,"Cmd":["<redacted>","-c","<redacted>"]}`, RequestSize: int64(len(execBody))},
+		},
+		{
+			in:  Exchange{ResponseBody: eventsBody, ResponseSize: int64(len(eventsBody))},
+			out: Exchange{ResponseBody: "{\"Id\":\"tb303\",\"Size\":12345678901234567}\n{\"Id\":\"tr808\"}", ResponseSize: int64(len(eventsBody))},
+		},
+		{
+			in:  Exchange{RequestBody: "\x1f\x8b\x08binary", RequestSize: 9},
+			out: Exchange{RequestSize: 9},
+		},
+		{
+			in:  Exchange{ResponseBody: `{"Id":"tb3`, ResponseSize: 1000},
+			out: Exchange{ResponseSize: 1000},
+		},
+	}
+
+	for i, tst := range tests {
+		tst.in.Sanitize()
+		if fmt.Sprintf("%v", tst.in) != fmt.Sprintf("%v", tst.out) {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, tst.in)
+		}
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := New(dir)
+	if err != nil {
+		t.Fatalf("unexpected error creating recorder: %s", err)
+	}
+	exs := []Exchange{
+		{Method: http.MethodPost, Path: "/containers/create", RequestBody: `{"Image":"alpine"}`, RequestSize: 18, Status: http.StatusCreated, ResponseBody: `{"Id":"aaaaaaaaaaaaaaaaaaaa"}`, ResponseSize: 29},
+		{Method: http.MethodPost, Path: "/containers/aaaaaaaaaaaaaaaaaaaa/start", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/containers/aaaaaaaaaaaa/json", Status: http.StatusOK},
+		{Method: http.MethodGet, Path: "/containers/unknown/json", Status: http.StatusOK},
+	}
+	for i := range exs {
+		if err := rec.Record("127.0.0.1 tc-java", &exs[i]); err != nil {
+			t.Fatalf("unexpected error recording: %s", err)
+		}
+	}
+	rec.Close()
+
+	if len(rec.Files()) != 1 {
+		t.Fatalf("expected a single recording, but got %v", rec.Files())
+	}
+	read, err := Read(rec.Files()[0])
+	if err != nil || len(read) != len(exs) {
+		t.Fatalf("failed reading recording - expected %d exchanges, but got %d: %v", len(exs), len(read), err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/containers/create":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"Id":"bbbbbbbbbbbbbbbbbbbb"}`)
+		case strings.HasPrefix(r.URL.Path, "/containers/bbbbbbbbbbbbbbbbbbbb/"):
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(r.URL.Path, "/containers/bbbbbbbbbbbb/"):
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	rp, err := NewReplayer(srv.URL, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating replayer: %s", err)
+	}
+	for i, match := range []bool{true, true, true, false} {
+		res := rp.Replay(read[i])
+		if res.Match() != match {
+			t.Errorf("failed test %d - expected %v, but got %v (%d, %v)", i, match, res.Match(), res.Status, res.Err)
+		}
+	}
+
+	if _, err := NewReplayer("ftp://localhost", 0); err == nil {
+		t.Errorf("expected error for unsupported address")
+	}
+}
+
+func TestRecordSessions(t *testing.T) {
+	rec, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error creating recorder: %s", err)
+	}
+	defer rec.Close()
+	for _, client := range []string{"10.0.0.1 tc-java", "10.0.0.2 tc-go", "10.0.0.1 tc-java"} {
+		if err := rec.Record(client, &Exchange{Method: http.MethodGet, Path: "/_ping"}); err != nil {
+			t.Fatalf("unexpected error recording: %s", err)
+		}
+	}
+	if len(rec.Files()) != 2 {
+		t.Fatalf("expected a recording per client, but got %v", rec.Files())
+	}
+	if exs, err := Read(rec.Files()[0]); err != nil || len(exs) != 2 {
+		t.Errorf("expected 2 exchanges in the first session, but got %d: %v", len(exs), err)
+	}
+
+	rec.idle = 0
+	time.Sleep(time.Millisecond)
+	if err := rec.Record("10.0.0.1 tc-java", &Exchange{Method: http.MethodGet, Path: "/_ping"}); err != nil {
+		t.Fatalf("unexpected error recording: %s", err)
+	}
+	if len(rec.Files()) != 3 {
+		t.Errorf("expected a new recording after the session was idle, but got %v", rec.Files())
+	}
+}
@@ -0,0 +1,160 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// skipHeaders are the recorded request headers that are not replayed, as
+// they are set by the http client.
+var skipHeaders = []string{"Content-Length", "Host", "Connection", "Upgrade"}
+
+// Result is the outcome of replaying a recorded exchange.
+type Result struct {
+	// Exchange is the recorded exchange
+	Exchange Exchange
+	// Status is the status of the replayed request
+	Status int
+	// Err is the error that occured while replaying the request, if any
+	Err error
+}
+
+// Match will return true if the replayed request resulted in the recorded
+// status.
+func (r *Result) Match() bool {
+	return r.Err == nil && r.Status == r.Exchange.Status
+}
+
+// Replayer will replay recorded exchanges to an api server.
+type Replayer struct {
+	client *http.Client
+	url    string
+	ids    map[string]string
+}
+
+// NewReplayer will return a Replayer that sends requests to the api server
+// at given address, which is either a url (e.g. http://localhost:2475), or
+// a unix socket (e.g. unix:///var/run/docker.sock). Requests that take
+// longer than given timeout (e.g. attach requests) are aborted.
+func NewReplayer(addr string, timeout time.Duration) (*Replayer, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	tr := &http.Transport{}
+	switch u.Scheme {
+	case "unix":
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", u.Path)
+		}
+		addr = "http://localhost"
+	case "tcp":
+		addr = "http://" + u.Host
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported address %s", addr)
+	}
+	return &Replayer{
+		client: &http.Client{Transport: tr, Timeout: timeout},
+		url:    strings.TrimSuffix(addr, "/"),
+		ids:    map[string]string{},
+	}, nil
+}
+
+// Replay will send the request of given exchange. Ids of objects that were
+// created in the recorded session are replaced with the ids of the objects
+// that are created while replaying.
+func (r *Replayer) Replay(ex Exchange) Result {
+	res := Result{Exchange: ex}
+	target := r.url + r.mapIDs(ex.Path)
+	if ex.Query != "" {
+		target += "?" + r.mapIDs(ex.Query)
+	}
+	req, err := http.NewRequest(ex.Method, target, strings.NewReader(r.mapIDs(ex.RequestBody)))
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	for key, vals := range ex.RequestHeader {
+		if containsFold(skipHeaders, key) {
+			continue
+		}
+		for _, val := range vals {
+			if val != Redacted {
+				req.Header.Add(key, val)
+			}
+		}
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+	res.Status = resp.StatusCode
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxBodySize))
+	if err == nil {
+		r.addID(ex.ResponseBody, string(body))
+	}
+	return res
+}
+
+// addID will register the id in given replayed response as the replacement
+// of the id in given recorded response.
+func (r *Replayer) addID(recorded, replayed string) {
+	old, cur := getID(recorded), getID(replayed)
+	if old == "" || cur == "" || old == cur {
+		return
+	}
+	r.ids[old] = cur
+	if len(old) > 12 && len(cur) > 12 {
+		r.ids[old[:12]] = cur[:12]
+	}
+}
+
+// mapIDs will replace all registered ids in given string. Longer ids are
+// replaced first, so full ids take precedence over short ids.
+func (r *Replayer) mapIDs(s string) string {
+	olds := make([]string, 0, len(r.ids))
+	for old := range r.ids {
+		olds = append(olds, old)
+	}
+	sort.Slice(olds, func(i, j int) bool { return len(olds[i]) > len(olds[j]) })
+	for _, old := range olds {
+		s = strings.ReplaceAll(s, old, r.ids[old])
+	}
+	return s
+}
+
+// getID will return the id in given json response body, if any.
+func getID(body string) string {
+	res := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return ""
+	}
+	for _, key := range []string{"Id", "ID", "id"} {
+		if id, ok := res[key].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// containsFold will return true if given list contains given string,
+// ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, e := range list {
+		if strings.EqualFold(e, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -25,6 +25,12 @@ type Request struct {
 	// MaxRetry is the maximum number of retries (equals to seconds) upon error
 	// and initial connection.
 	MaxRetry int
+	// Opened is an optional function that is called when a connection is
+	// accepted.
+	Opened func()
+	// Closed is an optional function that is called when an accepted
+	// connection is closed.
+	Closed func()
 }
 
 // Proxy will open a reverse tcp proxy, listening to the provided
@@ -72,7 +78,15 @@ func Proxy(req Request) error {
 				}
 				continue
 			}
-			go handleConnection(conn, local, remote, req.MaxRetry)
+			go func() {
+				if req.Opened != nil {
+					req.Opened()
+				}
+				handleConnection(conn, local, remote, req.MaxRetry)
+				if req.Closed != nil {
+					req.Closed()
+				}
+			}()
 		}
 		return
 	}()
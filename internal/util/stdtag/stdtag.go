@@ -0,0 +1,149 @@
+package stdtag
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Marker is the prefix that is added to every line written to stderr. It is
+// an OSC escape sequence, so it is ignored by terminals if it's displayed
+// without being demultiplexed.
+const Marker = "\x1b]kubedock;stderr\x07"
+
+// Run will run the given command, and prefixes every line the command
+// writes to stderr with the Marker, so stderr can be separated from stdout
+// when the streams are merged (e.g. in kubernetes pod logs). Signals are
+// forwarded to the command, and the exit code of the command is returned.
+func Run(args []string) int {
+	if len(args) == 0 {
+		return 127
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return 127
+	}
+	if err := cmd.Start(); err != nil {
+		os.Stderr.WriteString(Marker + err.Error() + "\n")
+		return 127
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigs)
+	go func() {
+		for sig := range sigs {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	tag(stderr, os.Stderr)
+
+	err = cmd.Wait()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode()
+	}
+	if err != nil {
+		return 127
+	}
+	return 0
+}
+
+// tag will copy all lines read from given reader to given writer,
+// prefixed with the Marker.
+func tag(r io.Reader, w io.Writer) {
+	rd := bufio.NewReader(r)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(line) > 0 {
+			w.Write(append([]byte(Marker), line...))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Demuxer is a writer that separates tagged stderr lines from stdout lines
+// and writes them to the respective writer.
+type Demuxer struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	timestamps bool
+	buf        []byte
+	lock       sync.Mutex
+}
+
+// NewDemuxer will return a new Demuxer instance. If timestamps is true, the
+// lines are expected to be prefixed with a timestamp, followed by a space,
+// before the Marker.
+func NewDemuxer(stdout, stderr io.Writer, timestamps bool) *Demuxer {
+	return &Demuxer{
+		stdout:     stdout,
+		stderr:     stderr,
+		timestamps: timestamps,
+		buf:        []byte{},
+	}
+}
+
+// Write will write all complete lines in given data to stdout or stderr,
+// and buffers the remaining incomplete line.
+func (d *Demuxer) Write(p []byte) (int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.buf = append(d.buf, p...)
+	for {
+		pos := bytes.IndexByte(d.buf, '\n')
+		if pos < 0 {
+			break
+		}
+		line := d.buf[:pos+1]
+		d.buf = d.buf[pos+1:]
+		if err := d.write(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush will write the remaining incomplete line, if any.
+func (d *Demuxer) Flush() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(d.buf) == 0 {
+		return nil
+	}
+	err := d.write(d.buf)
+	d.buf = []byte{}
+	return err
+}
+
+// write will write given line to the correct writer, removing the Marker
+// if present.
+func (d *Demuxer) write(line []byte) error {
+	prefix := []byte{}
+	rest := line
+	if d.timestamps {
+		if pos := bytes.IndexByte(line, ' '); pos >= 0 {
+			prefix, rest = line[:pos+1], line[pos+1:]
+		}
+	}
+	if !bytes.HasPrefix(rest, []byte(Marker)) {
+		_, err := d.stdout.Write(line)
+		return err
+	}
+	out := append(append([]byte{}, prefix...), rest[len(Marker):]...)
+	_, err := d.stderr.Write(out)
+	return err
+}
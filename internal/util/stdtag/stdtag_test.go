@@ -0,0 +1,60 @@
+package stdtag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTag(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tag(strings.NewReader("foo\nbar"), buf)
+	exp := Marker + "foo\n" + Marker + "bar"
+	if buf.String() != exp {
+		t.Errorf("failed tag - expected %q, but got %q", exp, buf.String())
+	}
+}
+
+func TestDemuxer(t *testing.T) {
+	tests := []struct {
+		in         []string
+		timestamps bool
+		stdout     string
+		stderr     string
+	}{
+		{
+			in:     []string{"hello\n", Marker + "oops\n", "world"},
+			stdout: "hello\nworld",
+			stderr: "oops\n",
+		},
+		{
+			in:     []string{"hel", "lo\n" + Marker[:4], Marker[4:] + "oops\n"},
+			stdout: "hello\n",
+			stderr: "oops\n",
+		},
+		{
+			in:         []string{"2024-01-01T00:00:00Z hello\n", "2024-01-01T00:00:01Z " + Marker + "oops\n"},
+			timestamps: true,
+			stdout:     "2024-01-01T00:00:00Z hello\n",
+			stderr:     "2024-01-01T00:00:01Z oops\n",
+		},
+		{
+			in:     []string{"2024-01-01T00:00:01Z " + Marker + "oops\n"},
+			stdout: "2024-01-01T00:00:01Z " + Marker + "oops\n",
+		},
+	}
+	for i, tst := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		d := NewDemuxer(stdout, stderr, tst.timestamps)
+		for _, in := range tst.in {
+			d.Write([]byte(in))
+		}
+		d.Flush()
+		if stdout.String() != tst.stdout {
+			t.Errorf("failed test %d - expected stdout %q, but got %q", i, tst.stdout, stdout.String())
+		}
+		if stderr.String() != tst.stderr {
+			t.Errorf("failed test %d - expected stderr %q, but got %q", i, tst.stderr, stderr.String())
+		}
+	}
+}
@@ -3,9 +3,11 @@ package tar
 import (
 	"archive/tar"
 	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"k8s.io/klog"
 )
@@ -88,6 +90,70 @@ func UnpackFile(dst, fname string, archive io.Reader, dest io.Writer) error {
 	}
 }
 
+// Unpack will extract all directories, files and symlinks in the given
+// archive to the given dst folder. Entries that would be extracted outside
+// the dst folder are rejected.
+func Unpack(dst string, archive io.Reader) error {
+	tr, err := NewReader(archive)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+	for {
+		header, err := tr.Next()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		case header == nil:
+			continue
+		}
+		target := filepath.Join(dst, header.Name)
+		if rel, err := filepath.Rel(dst, target); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("invalid path %s in archive", header.Name)
+		}
+		mode := header.FileInfo().Mode().Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := unpackFile(target, mode, tr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			klog.V(2).Infof("skipping unsupported tar entry %s", header.Name)
+		}
+	}
+}
+
+// unpackFile will write the contents of the given reader to the given
+// target file, creating the parent folders if required.
+func unpackFile(target string, mode os.FileMode, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // GetTargetFolderNames will return all affected folders in the archive
 // provided.
 func GetTargetFolderNames(dst string, archive io.Reader) ([]string, error) {
@@ -1,8 +1,11 @@
 package tar
 
 import (
+	"archive/tar"
 	"bufio"
 	"bytes"
+	"os"
+	"path/filepath"
 	"slices"
 	"testing"
 )
@@ -59,3 +62,39 @@ func TestPackFolder(t *testing.T) {
 		t.Error("IsCompressed returns that archive is compressed, expected uncompressed")
 	}
 }
+
+func TestUnpack(t *testing.T) {
+	archive := func(names ...string) []byte {
+		var b bytes.Buffer
+		tw := tar.NewWriter(&b)
+		for _, name := range names {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0640, Size: int64(len(name)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(name))
+		}
+		tw.Close()
+		return b.Bytes()
+	}
+
+	tests := []struct {
+		archive []byte
+		files   []string
+		err     bool
+	}{
+		{archive: archive("a.txt", "sub/b.txt"), files: []string{"a.txt", "sub/b.txt"}},
+		{archive: archive("../escape.txt"), err: true},
+	}
+	for i, tst := range tests {
+		dst := t.TempDir()
+		err := Unpack(dst, bytes.NewReader(tst.archive))
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+			continue
+		}
+		for _, f := range tst.files {
+			dat, err := os.ReadFile(filepath.Join(dst, f))
+			if err != nil || string(dat) != f {
+				t.Errorf("failed test %d - expected %s with contents %s, but got %s (%v)", i, f, f, dat, err)
+			}
+		}
+	}
+}
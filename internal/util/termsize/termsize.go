@@ -0,0 +1,100 @@
+package termsize
+
+import (
+	"sync"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Queue is a remotecommand.TerminalSizeQueue that is fed with the sizes
+// that are requested via resize api calls.
+type Queue struct {
+	sizes chan remotecommand.TerminalSize
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewQueue will return a new Queue instance.
+func NewQueue() *Queue {
+	return &Queue{
+		sizes: make(chan remotecommand.TerminalSize, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// Next will return the next terminal size, and blocks until a new size is
+// available. It returns nil when the queue is closed.
+func (q *Queue) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-q.sizes:
+		return &size
+	case <-q.done:
+		return nil
+	}
+}
+
+// Resize will queue given terminal size. If a previous size has not been
+// consumed yet, it is replaced, as only the latest size is relevant.
+func (q *Queue) Resize(size remotecommand.TerminalSize) {
+	for {
+		select {
+		case <-q.done:
+			return
+		case q.sizes <- size:
+			return
+		default:
+		}
+		select {
+		case <-q.sizes:
+		default:
+		}
+	}
+}
+
+// Close will close the queue.
+func (q *Queue) Close() {
+	q.once.Do(func() { close(q.done) })
+}
+
+// Broadcaster will distribute resize requests to all queues of the active
+// tty sessions.
+type Broadcaster struct {
+	lock   sync.Mutex
+	queues map[*Queue]struct{}
+	last   *remotecommand.TerminalSize
+}
+
+// Add will return a new queue that receives all subsequent resize requests.
+// The queue starts with the last requested size, if any.
+func (b *Broadcaster) Add() *Queue {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.queues == nil {
+		b.queues = map[*Queue]struct{}{}
+	}
+	q := NewQueue()
+	if b.last != nil {
+		q.Resize(*b.last)
+	}
+	b.queues[q] = struct{}{}
+	return q
+}
+
+// Remove will close and remove given queue.
+func (b *Broadcaster) Remove(q *Queue) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.queues, q)
+	q.Close()
+}
+
+// Resize will send given size to all active queues.
+func (b *Broadcaster) Resize(width, height uint16) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	size := remotecommand.TerminalSize{Width: width, Height: height}
+	b.last = &size
+	for q := range b.queues {
+		q.Resize(size)
+	}
+}
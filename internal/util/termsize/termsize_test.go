@@ -0,0 +1,41 @@
+package termsize
+
+import (
+	"testing"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+func TestQueue(t *testing.T) {
+	q := NewQueue()
+	q.Resize(remotecommand.TerminalSize{Width: 80, Height: 24})
+	q.Resize(remotecommand.TerminalSize{Width: 120, Height: 40})
+	if res := q.Next(); res == nil || res.Width != 120 || res.Height != 40 {
+		t.Errorf("failed latest size - expected 120x40, but got %v", res)
+	}
+	q.Close()
+	if res := q.Next(); res != nil {
+		t.Errorf("failed closed queue - expected nil, but got %v", res)
+	}
+	q.Resize(remotecommand.TerminalSize{Width: 80, Height: 24})
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := &Broadcaster{}
+	b.Resize(80, 24)
+	q1 := b.Add()
+	if res := q1.Next(); res == nil || res.Width != 80 || res.Height != 24 {
+		t.Errorf("failed initial size - expected 80x24, but got %v", res)
+	}
+	q2 := b.Add()
+	b.Resize(132, 43)
+	for i, q := range []*Queue{q1, q2} {
+		if res := q.Next(); res == nil || res.Width != 132 || res.Height != 43 {
+			t.Errorf("failed queue %d - expected 132x43, but got %v", i, res)
+		}
+	}
+	b.Remove(q1)
+	if res := q1.Next(); res != nil {
+		t.Errorf("failed removed queue - expected nil, but got %v", res)
+	}
+}
@@ -0,0 +1,79 @@
+package ulimit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Names contains the supported ulimit names.
+var Names = map[string]bool{
+	"as":      true,
+	"core":    true,
+	"cpu":     true,
+	"data":    true,
+	"fsize":   true,
+	"locks":   true,
+	"memlock": true,
+	"nofile":  true,
+	"nproc":   true,
+	"stack":   true,
+}
+
+// Limit describes a soft and hard resource limit.
+type Limit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// String will return the limit in the name=soft:hard format.
+func (l Limit) String() string {
+	return fmt.Sprintf("%s=%d:%d", l.Name, l.Soft, l.Hard)
+}
+
+// Parse will parse given limit in the name=soft:hard format.
+func Parse(val string) (Limit, error) {
+	name, limits, found := strings.Cut(val, "=")
+	if !found || !Names[name] {
+		return Limit{}, fmt.Errorf("invalid ulimit: %s", val)
+	}
+	soft, hard, found := strings.Cut(limits, ":")
+	if !found {
+		hard = soft
+	}
+	l := Limit{Name: name}
+	var err error
+	if l.Soft, err = strconv.ParseInt(soft, 10, 64); err != nil {
+		return Limit{}, fmt.Errorf("invalid ulimit: %s", val)
+	}
+	if l.Hard, err = strconv.ParseInt(hard, 10, 64); err != nil {
+		return Limit{}, fmt.Errorf("invalid ulimit: %s", val)
+	}
+	return l, nil
+}
+
+// Run will apply given limits (name=soft:hard) and execute the given
+// command. If a limit can't be raised to the requested value, because the
+// process lacks the CAP_SYS_RESOURCE capability, the limit is raised as far
+// as allowed, and a warning is written to stderr.
+func Run(limits []string, args []string) int {
+	if len(args) == 0 {
+		return 127
+	}
+	for _, val := range limits {
+		l, err := Parse(val)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kubedock: %s\n", err)
+			continue
+		}
+		if err := set(l); err != nil {
+			fmt.Fprintf(os.Stderr, "kubedock: unable to set ulimit %s: %s\n", l, err)
+		}
+	}
+	if err := execute(args); err != nil {
+		fmt.Fprintf(os.Stderr, "kubedock: %s\n", err)
+	}
+	return 127
+}
@@ -0,0 +1,57 @@
+package ulimit
+
+import (
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+// resources maps the ulimit names to the linux resource identifiers.
+var resources = map[string]int{
+	"as":      unix.RLIMIT_AS,
+	"core":    unix.RLIMIT_CORE,
+	"cpu":     unix.RLIMIT_CPU,
+	"data":    unix.RLIMIT_DATA,
+	"fsize":   unix.RLIMIT_FSIZE,
+	"locks":   unix.RLIMIT_LOCKS,
+	"memlock": unix.RLIMIT_MEMLOCK,
+	"nofile":  unix.RLIMIT_NOFILE,
+	"nproc":   unix.RLIMIT_NPROC,
+	"stack":   unix.RLIMIT_STACK,
+}
+
+// set will apply the given limit on the current process. If the limit
+// can't be raised above the current hard limit, the soft limit is raised
+// up to the current hard limit instead.
+func set(l Limit) error {
+	res := resources[l.Name]
+	lim := &unix.Rlimit{Cur: uint64(l.Soft), Max: uint64(l.Hard)}
+	err := unix.Setrlimit(res, lim)
+	if err == nil {
+		return nil
+	}
+	cur := &unix.Rlimit{}
+	if err := unix.Getrlimit(res, cur); err != nil {
+		return err
+	}
+	if lim.Cur > cur.Max {
+		lim.Cur = cur.Max
+	}
+	if lim.Max > cur.Max {
+		lim.Max = cur.Max
+	}
+	if err2 := unix.Setrlimit(res, lim); err2 != nil {
+		return err2
+	}
+	return err
+}
+
+// execute will replace the current process with the given command.
+func execute(args []string) error {
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	return unix.Exec(path, args, os.Environ())
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package ulimit
+
+import (
+	"fmt"
+)
+
+// set is not supported on platforms other than linux.
+func set(l Limit) error {
+	return fmt.Errorf("not supported on this platform")
+}
+
+// execute is not supported on platforms other than linux.
+func execute(args []string) error {
+	return fmt.Errorf("running commands with ulimits is not supported on this platform")
+}
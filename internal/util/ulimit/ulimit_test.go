@@ -0,0 +1,32 @@
+package ulimit
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in  string
+		out Limit
+		err bool
+	}{
+		{in: "nofile=1024:2048", out: Limit{Name: "nofile", Soft: 1024, Hard: 2048}},
+		{in: "nproc=512", out: Limit{Name: "nproc", Soft: 512, Hard: 512}},
+		{in: "core=-1:-1", out: Limit{Name: "core", Soft: -1, Hard: -1}},
+		{in: "nofile", err: true},
+		{in: "rtprio=1:1", err: true},
+		{in: "nofile=abc:1", err: true},
+	}
+	for i, tst := range tests {
+		res, err := Parse(tst.in)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error %v", i, err)
+		}
+		if res != tst.out {
+			t.Errorf("failed test %d - expected %v, but got %v", i, tst.out, res)
+		}
+		if err == nil && res.String() != tst.out.String() {
+			t.Errorf("failed test %d - expected %s, but got %s", i, tst.out, res)
+		}
+	}
+}
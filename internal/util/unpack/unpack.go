@@ -0,0 +1,40 @@
+package unpack
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/joyrex2001/kubedock/internal/util/tar"
+	"github.com/joyrex2001/kubedock/internal/util/ulimit"
+)
+
+// Run will extract the given archives (archive=target) and replaces the
+// current process with the given command afterwards. Archives that can't
+// be extracted are reported on stderr, but won't prevent the command from
+// being started. It only returns if the command could not be executed.
+func Run(archives []string, args []string) int {
+	for _, val := range archives {
+		if err := extract(val); err != nil {
+			fmt.Fprintf(os.Stderr, "kubedock: %s\n", err)
+		}
+	}
+	return ulimit.Run(nil, args)
+}
+
+// extract will extract the given archive=target archive.
+func extract(val string) error {
+	archive, target, ok := strings.Cut(val, "=")
+	if !ok || archive == "" || target == "" {
+		return fmt.Errorf("invalid archive %s, expected archive=target", val)
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := tar.Unpack(target, f); err != nil {
+		return fmt.Errorf("unable to extract archive to %s: %w", target, err)
+	}
+	return nil
+}
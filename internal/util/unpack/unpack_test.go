@@ -0,0 +1,29 @@
+package unpack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.tar")
+	if err := os.WriteFile(empty, make([]byte, 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in  string
+		err bool
+	}{
+		{in: empty + "=" + dir, err: false},
+		{in: empty, err: true},
+		{in: "=" + dir, err: true},
+		{in: filepath.Join(dir, "missing.tar") + "=" + dir, err: true},
+	}
+	for i, tst := range tests {
+		if err := extract(tst.in); (err != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Watchdog will cancel a context when the session it guards has been idle
+// for longer than the idle timeout, or has been running for longer than the
+// maximum duration.
+type Watchdog struct {
+	idle   time.Duration
+	max    time.Duration
+	start  time.Time
+	active atomic.Int64
+	cancel context.CancelFunc
+	err    error
+	lock   sync.Mutex
+}
+
+// New will return a watchdog and a context derived from the given context
+// that is cancelled when the session times out, or when Stop is called. A
+// zero idle timeout or maximum duration disables that timeout.
+func New(parent context.Context, idle, max time.Duration) (*Watchdog, context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	wd := &Watchdog{idle: idle, max: max, start: time.Now(), cancel: cancel}
+	wd.Touch()
+	if idle > 0 || max > 0 {
+		go wd.run(ctx)
+	}
+	return wd, ctx
+}
+
+// run will check the timeouts at a steady interval until the context is
+// done.
+func (wd *Watchdog) run(ctx context.Context) {
+	tkr := time.NewTicker(wd.interval())
+	defer tkr.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tkr.C:
+			if err := wd.check(); err != nil {
+				wd.lock.Lock()
+				wd.err = err
+				wd.lock.Unlock()
+				wd.cancel()
+				return
+			}
+		}
+	}
+}
+
+// interval will return the interval in which the timeouts are checked.
+func (wd *Watchdog) interval() time.Duration {
+	interval := time.Second
+	for _, d := range []time.Duration{wd.idle, wd.max} {
+		if d > 0 && d/4 < interval {
+			interval = d / 4
+		}
+	}
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+// check will return an error if one of the timeouts has expired.
+func (wd *Watchdog) check() error {
+	if wd.max > 0 && time.Since(wd.start) > wd.max {
+		return fmt.Errorf("session exceeded the maximum duration of %s", wd.max)
+	}
+	if wd.idle > 0 && time.Since(time.Unix(0, wd.active.Load())) > wd.idle {
+		return fmt.Errorf("session was idle for more than %s", wd.idle)
+	}
+	return nil
+}
+
+// Touch will register activity on the session.
+func (wd *Watchdog) Touch() {
+	wd.active.Store(time.Now().UnixNano())
+}
+
+// Stop will stop the watchdog and cancel its context.
+func (wd *Watchdog) Stop() {
+	wd.cancel()
+}
+
+// Err will return the reason the session timed out, or nil if it didn't.
+func (wd *Watchdog) Err() error {
+	wd.lock.Lock()
+	defer wd.lock.Unlock()
+	return wd.err
+}
+
+// Reader will return a reader that registers activity when data is read
+// from given reader.
+func (wd *Watchdog) Reader(r io.Reader) io.Reader {
+	return &reader{r: r, wd: wd}
+}
+
+// Writer will return a writer that registers activity when data is written
+// to given writer.
+func (wd *Watchdog) Writer(w io.Writer) io.Writer {
+	return &writer{w: w, wd: wd}
+}
+
+// reader is an io.Reader that registers activity on the watchdog.
+type reader struct {
+	r  io.Reader
+	wd *Watchdog
+}
+
+// Read will read from the underlying reader and register the activity.
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.wd.Touch()
+	}
+	return n, err
+}
+
+// writer is an io.Writer that registers activity on the watchdog.
+type writer struct {
+	w  io.Writer
+	wd *Watchdog
+}
+
+// Write will write to the underlying writer and register the activity.
+func (w *writer) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.wd.Touch()
+	}
+	return w.w.Write(p)
+}
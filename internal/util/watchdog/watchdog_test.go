@@ -0,0 +1,58 @@
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	tests := []struct {
+		idle   time.Duration
+		max    time.Duration
+		active bool
+		err    bool
+	}{
+		{idle: 0, max: 0, active: false, err: false},
+		{idle: 20 * time.Millisecond, max: 0, active: false, err: true},
+		{idle: 20 * time.Millisecond, max: 0, active: true, err: false},
+		{idle: 0, max: 20 * time.Millisecond, active: true, err: true},
+	}
+	for i, tst := range tests {
+		wd, ctx := New(context.Background(), tst.idle, tst.max)
+		w := wd.Writer(&bytes.Buffer{})
+		end := time.After(100 * time.Millisecond)
+		done := false
+		for !done {
+			select {
+			case <-end:
+				done = true
+			case <-ctx.Done():
+				done = true
+			case <-time.After(5 * time.Millisecond):
+				if tst.active {
+					w.Write([]byte("x"))
+				}
+			}
+		}
+		if (wd.Err() != nil) != tst.err {
+			t.Errorf("failed test %d - expected error %t, but got %v", i, tst.err, wd.Err())
+		}
+		if (ctx.Err() != nil) != tst.err {
+			t.Errorf("failed test %d - expected cancelled %t, but got %v", i, tst.err, ctx.Err())
+		}
+		wd.Stop()
+	}
+}
+
+func TestReader(t *testing.T) {
+	wd, _ := New(context.Background(), 0, 0)
+	defer wd.Stop()
+	wd.active.Store(0)
+	r := wd.Reader(bytes.NewBufferString("x"))
+	r.Read(make([]byte, 1))
+	if wd.check() != nil || time.Since(time.Unix(0, wd.active.Load())) > time.Second {
+		t.Errorf("expected activity to be registered")
+	}
+}
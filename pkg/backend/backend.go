@@ -0,0 +1,97 @@
+// Package backend exports the interface of the backends that orchestrate
+// the containers of kubedock, so a backend can be injected in an in-process
+// kubedock instance (see kubedock.Config). The interface only refers to the
+// types in this package, so it can be implemented outside of kubedock.
+package backend
+
+import (
+	"context"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+)
+
+// Backend is the interface of a backend that orchestrates the containers.
+// The kubernetes backend is created by kubedock itself, and the in-memory
+// backend is available in pkg/backend/fake.
+type Backend interface {
+	StartContainer(context.Context, *Container) (DeployState, error)
+	AttachContainer(*Container, io.Reader, io.Writer, io.Writer, bool) error
+	GetContainerStatus(*Container) (DeployState, error)
+	CreatePortForwards(*Container)
+	CreateReverseProxies(*Container)
+	UpdateServices(*Container) error
+	CleanForwards([]*Container, time.Duration) int
+	GetPodIP(*Container) (string, error)
+	DeleteAll() error
+	DeleteWithKubedockID(string) error
+	DeleteProject(string) error
+	DeleteContainer(*Container) error
+	RetainContainer(*Container) (bool, error)
+	DeleteOlderThan(time.Duration) error
+	WatchDeleteContainer(*Container) (chan struct{}, error)
+	WatchContainerExit(*Container) (chan ContainerExit, error)
+	CopyFromContainer(*Container, string, io.Writer) error
+	CopyToContainer(*Container, io.Reader, string, bool) error
+	GetFileStatInContainer(tainr *Container, path string) (*FileStat, error)
+	FileExistsInContainer(tainr *Container, path string) (bool, error)
+	StartArchiveHelper(*Container) (*Container, error)
+	StageArchive(*Container, io.Reader) (string, error)
+	DeleteContainerVolumes(*Container) error
+	ExecContainer(context.Context, *Container, *Exec, io.Reader, io.Writer) (int, error)
+	GetLogs(*Container, *LogOptions, chan struct{}, io.Writer) error
+	GetLogsRaw(*Container, *LogOptions, chan struct{}, io.Writer) error
+	InspectImage(string) (*ImageDetails, error)
+	GetImageDistribution(string) (*ImageDistribution, error)
+	GetImageManifest(string, string) (*ImageManifest, error)
+	GetImageBlob(string, string) (io.ReadCloser, int64, error)
+	PrewarmImages([]string) ([]string, error)
+	SetImageRewrites([]RewriteRule)
+	GetPodEvents(*Container) ([]corev1.Event, error)
+	GetContainerStats([]*Container) (map[string]*ContainerStats, error)
+	GetCapacity() (*Capacity, error)
+	DeployService(*Service) error
+	GetServiceReplicas(*Service) (int, error)
+	DeleteService(*Service) error
+	CheckFeature(string) error
+}
+
+// DeployState describes the state of a deployment.
+type DeployState int
+
+const (
+	// DeployPending represents a pending deployment
+	DeployPending = DeployState(backend.DeployPending)
+	// DeployFailed represents a failed deployment
+	DeployFailed = DeployState(backend.DeployFailed)
+	// DeployRunning represents a running deployment
+	DeployRunning = DeployState(backend.DeployRunning)
+	// DeployCompleted represents a completed deployment
+	DeployCompleted = DeployState(backend.DeployCompleted)
+)
+
+const (
+	// FeatureServices is the creation of services for the containers
+	FeatureServices = backend.FeatureServices
+	// FeatureExec is executing commands in, and copying files to and from,
+	// running containers
+	FeatureExec = backend.FeatureExec
+	// FeatureAttach is attaching to running containers
+	FeatureAttach = backend.FeatureAttach
+	// FeaturePortForward is port-forwarding published ports of containers
+	FeaturePortForward = backend.FeaturePortForward
+	// FeatureMetrics is reporting the resource usage of containers
+	FeatureMetrics = backend.FeatureMetrics
+	// FeatureEvents is reporting the kubernetes events of containers
+	FeatureEvents = backend.FeatureEvents
+	// FeatureDeployments is deploying (swarm) services as deployments
+	FeatureDeployments = backend.FeatureDeployments
+	// FeaturePrewarm is pre-pulling images on all nodes with a daemonset
+	FeaturePrewarm = backend.FeaturePrewarm
+	// FeatureArchiveHelper is copying archives to and from containers that
+	// are not running, using persistent volume claims
+	FeatureArchiveHelper = backend.FeatureArchiveHelper
+)
@@ -0,0 +1,625 @@
+// Package fake provides an in-memory backend that doesn't require a
+// kubernetes cluster. It can be injected in an in-process kubedock instance
+// (see kubedock.Config) to test docker clients against the kubedock api.
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/util/ioproxy"
+	"github.com/joyrex2001/kubedock/pkg/backend"
+)
+
+// ExecFunc is the function that is called when a command is executed in a
+// container. It returns the exit code of the command.
+type ExecFunc func(tainr *backend.Container, exec *backend.Exec, stdin io.Reader, stdout io.Writer) (int, error)
+
+// Backend is an in-memory implementation of backend.Backend that doesn't
+// require a kubernetes cluster. It keeps track of the started containers and
+// the files that are copied into them, which makes it suitable for unit
+// testing the api routes.
+type Backend struct {
+	// StartState is the state returned when a container is started; it
+	// defaults to backend.DeployRunning.
+	StartState backend.DeployState
+	// StartError is the error returned when a container is started.
+	StartError error
+	// DeleteError is the error returned when a container is deleted.
+	DeleteError error
+	// DeletedProjects contains the docker compose projects that have been
+	// deleted.
+	DeletedProjects []string
+	// Logs are the log lines written for every container.
+	Logs []string
+	// Exec is called when a command is executed in a container; if not set,
+	// all commands will succeed without output.
+	Exec ExecFunc
+	// Images contains the details of images that can be inspected; images
+	// that are not present return an empty (linux/amd64) configuration.
+	Images map[string]*backend.ImageDetails
+	// InspectError is returned when inspecting images, if set.
+	InspectError error
+	// Blobs contains the blobs that can be fetched from the registry, keyed
+	// by digest.
+	Blobs map[string][]byte
+	// RegistryErrors contains the errors that are returned when fetching
+	// manifests from the registry, keyed by repository.
+	RegistryErrors map[string]error
+	// Stats contains the resource usage that is reported for containers,
+	// keyed by container id; other containers don't report any usage.
+	Stats map[string]*backend.ContainerStats
+	// Capacity is the capacity that is reported for the namespace.
+	Capacity backend.Capacity
+	// Disabled contains the errors that are returned by CheckFeature,
+	// keyed by feature.
+	Disabled map[string]error
+
+	lock     sync.Mutex
+	states   map[string]backend.DeployState
+	ips      map[string]string
+	files    map[string]map[string]file
+	staged   map[string][]byte
+	watchers map[string][]chan struct{}
+	exits    map[string][]chan backend.ContainerExit
+	services map[string]int
+}
+
+// file is an in-memory representation of a file in a container.
+type file struct {
+	mode  fs.FileMode
+	data  []byte
+	mtime time.Time
+}
+
+var _ backend.Backend = &Backend{}
+
+// New will return a new, empty, fake Backend instance.
+func New() *Backend {
+	return &Backend{
+		StartState: backend.DeployRunning,
+		Images:     map[string]*backend.ImageDetails{},
+		Blobs:      map[string][]byte{},
+		Stats:      map[string]*backend.ContainerStats{},
+		states:     map[string]backend.DeployState{},
+		ips:        map[string]string{},
+		files:      map[string]map[string]file{},
+		staged:     map[string][]byte{},
+		watchers:   map[string][]chan struct{}{},
+		exits:      map[string][]chan backend.ContainerExit{},
+		services:   map[string]int{},
+	}
+}
+
+// StartContainer will mark given container as started, or as failed if the
+// given context is already done.
+func (in *Backend) StartContainer(ctx context.Context, tainr *backend.Container) (backend.DeployState, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	if in.StartError != nil {
+		in.states[tainr.ID] = backend.DeployFailed
+		return backend.DeployFailed, in.StartError
+	}
+	if err := ctx.Err(); err != nil {
+		in.states[tainr.ID] = backend.DeployFailed
+		return backend.DeployFailed, err
+	}
+	in.states[tainr.ID] = in.StartState
+	for _, pa := range tainr.StagedArchives {
+		dat := in.staged[pa.File]
+		compressed := len(dat) > 2 && dat[0] == 0x1f && dat[1] == 0x8b
+		if err := in.extract(tainr.ID, bytes.NewReader(dat), pa.Path, compressed); err != nil {
+			return backend.DeployFailed, err
+		}
+	}
+	if _, ok := in.ips[tainr.ID]; !ok {
+		n := len(in.ips) + 2
+		in.ips[tainr.ID] = fmt.Sprintf("10.0.%d.%d", n/256, n%256)
+	}
+	return in.StartState, nil
+}
+
+// AttachContainer will write the configured logs to stdout.
+func (in *Backend) AttachContainer(tainr *backend.Container, stdin io.Reader, stdout io.Writer, stderr io.Writer, tty bool) error {
+	return in.writeLogs(stdout)
+}
+
+// GetContainerStatus will return the state of given container, which is
+// pending if the container was not started.
+func (in *Backend) GetContainerStatus(tainr *backend.Container) (backend.DeployState, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	if state, ok := in.states[tainr.ID]; ok {
+		return state, nil
+	}
+	return backend.DeployPending, nil
+}
+
+// CreatePortForwards is a no-op.
+func (in *Backend) CreatePortForwards(tainr *backend.Container) {}
+
+// CreateReverseProxies is a no-op.
+func (in *Backend) CreateReverseProxies(tainr *backend.Container) {}
+
+// UpdateServices is a no-op.
+func (in *Backend) UpdateServices(tainr *backend.Container) error {
+	return nil
+}
+
+// CleanForwards is a no-op.
+func (in *Backend) CleanForwards(tainrs []*backend.Container, idle time.Duration) int {
+	return 0
+}
+
+// GetPodIP will return the ip assigned to given container when it was
+// started.
+func (in *Backend) GetPodIP(tainr *backend.Container) (string, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	if ip, ok := in.ips[tainr.ID]; ok {
+		return ip, nil
+	}
+	return "", fmt.Errorf("container %s is not running", tainr.ShortID)
+}
+
+// DeleteAll will remove all containers.
+func (in *Backend) DeleteAll() error {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	for id := range in.states {
+		in.delete(id)
+	}
+	return nil
+}
+
+// DeleteWithKubedockID will remove all containers.
+func (in *Backend) DeleteWithKubedockID(id string) error {
+	return in.DeleteAll()
+}
+
+// DeleteProject will record given project as deleted; the containers of the
+// project are removed individually.
+func (in *Backend) DeleteProject(project string) error {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	in.DeletedProjects = append(in.DeletedProjects, project)
+	return nil
+}
+
+// DeleteContainer will remove given container, or return DeleteError if set.
+func (in *Backend) DeleteContainer(tainr *backend.Container) error {
+	if in.DeleteError != nil {
+		return in.DeleteError
+	}
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	in.delete(tainr.ID)
+	return nil
+}
+
+// RetainContainer will return true if given container is labelled with
+// retain-on-failure and failed to start; the container is kept as is, but
+// the watchers of its deletion are notified, as the container is released.
+func (in *Backend) RetainContainer(tainr *backend.Container) (bool, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	retain, _ := strconv.ParseBool(tainr.Labels[types.LabelRetainOnFailure])
+	if !retain || in.states[tainr.ID] != backend.DeployFailed {
+		return false, nil
+	}
+	for _, ch := range in.watchers[tainr.ID] {
+		close(ch)
+	}
+	delete(in.watchers, tainr.ID)
+	return true, nil
+}
+
+// DeleteOlderThan is a no-op.
+func (in *Backend) DeleteOlderThan(keepmax time.Duration) error {
+	return nil
+}
+
+// delete will remove the container with given id and notify the watchers
+// of this container. The caller should hold the lock.
+func (in *Backend) delete(id string) {
+	delete(in.states, id)
+	delete(in.ips, id)
+	delete(in.files, id)
+	for _, ch := range in.watchers[id] {
+		close(ch)
+	}
+	delete(in.watchers, id)
+	for _, ch := range in.exits[id] {
+		close(ch)
+	}
+	delete(in.exits, id)
+}
+
+// WatchDeleteContainer will return a channel that is closed when given
+// container is deleted.
+func (in *Backend) WatchDeleteContainer(tainr *backend.Container) (chan struct{}, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	ch := make(chan struct{})
+	in.watchers[tainr.ID] = append(in.watchers[tainr.ID], ch)
+	return ch, nil
+}
+
+// WatchContainerExit will return a channel that receives the exit code
+// of given container when Exit or Disrupt is called for it.
+func (in *Backend) WatchContainerExit(tainr *backend.Container) (chan backend.ContainerExit, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	ch := make(chan backend.ContainerExit, 1)
+	in.exits[tainr.ID] = append(in.exits[tainr.ID], ch)
+	return ch, nil
+}
+
+// Exit will mark the container with given id as terminated with given exit
+// code, and notifies the exit watchers of this container.
+func (in *Backend) Exit(id string, code int) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	if code == 0 {
+		in.states[id] = backend.DeployCompleted
+	} else {
+		in.states[id] = backend.DeployFailed
+	}
+	for _, ch := range in.exits[id] {
+		ch <- backend.ContainerExit{Code: code}
+		close(ch)
+	}
+	delete(in.exits, id)
+}
+
+// Disrupt will mark the container with given id as terminated by kubernetes
+// (e.g. evicted) with given reason, and notifies the exit watchers of this
+// container.
+func (in *Backend) Disrupt(id string, reason string) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	in.states[id] = backend.DeployFailed
+	for _, ch := range in.exits[id] {
+		ch <- backend.ContainerExit{Code: 137, Reason: reason}
+		close(ch)
+	}
+	delete(in.exits, id)
+}
+
+// GetCapacity will return the configured capacity.
+func (in *Backend) GetCapacity() (*backend.Capacity, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	res := in.Capacity
+	return &res, nil
+}
+
+// GetContainerStats will return the configured stats of given containers.
+func (in *Backend) GetContainerStats(tainrs []*backend.Container) (map[string]*backend.ContainerStats, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	res := map[string]*backend.ContainerStats{}
+	for _, tainr := range tainrs {
+		if st, ok := in.Stats[tainr.ID]; ok {
+			res[tainr.ID] = st
+		}
+	}
+	return res, nil
+}
+
+// CopyFromContainer will write a tar archive of the given path in given
+// container to given writer.
+func (in *Backend) CopyFromContainer(tainr *backend.Container, target string, w io.Writer) error {
+	buf, err := in.archive(tainr, target)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, buf)
+	return err
+}
+
+// archive will return a tar archive of given path in given container. The
+// archive is created in memory, so the lock is not held while the archive
+// is written to a (possibly blocking) writer.
+func (in *Backend) archive(tainr *backend.Container, target string) (*bytes.Buffer, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	target = path.Clean(target)
+	base := path.Dir(target)
+	paths := []string{}
+	for p := range in.files[tainr.ID] {
+		if p == target || strings.HasPrefix(p, target+"/") {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s: no such file or directory", target)
+	}
+	sort.Strings(paths)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, p := range paths {
+		f := in.files[tainr.ID][p]
+		hdr := &tar.Header{
+			Name: strings.TrimPrefix(strings.TrimPrefix(p, base), "/"),
+			Mode: int64(f.mode.Perm()),
+			Size: int64(len(f.data)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	return buf, tw.Close()
+}
+
+// CopyToContainer will extract given (optionally gzip compressed) tar
+// archive in given container.
+func (in *Backend) CopyToContainer(tainr *backend.Container, archive io.Reader, target string, compressed bool) error {
+	// read the archive before locking, as it might be streamed from
+	// another container
+	dat, err := io.ReadAll(archive)
+	if err != nil {
+		return err
+	}
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	return in.extract(tainr.ID, bytes.NewReader(dat), target, compressed)
+}
+
+// extract will extract given tar archive in the container with given id.
+// The caller should hold the lock.
+func (in *Backend) extract(id string, archive io.Reader, target string, compressed bool) error {
+	if in.files[id] == nil {
+		in.files[id] = map[string]file{}
+	}
+	if compressed {
+		gz, err := gzip.NewReader(archive)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		archive = gz
+	}
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		p := path.Join("/", target, hdr.Name)
+		buf := &bytes.Buffer{}
+		if _, err := io.Copy(buf, tr); err != nil {
+			return err
+		}
+		in.files[id][p] = file{mode: fs.FileMode(hdr.Mode).Perm(), data: buf.Bytes(), mtime: hdr.ModTime}
+	}
+}
+
+// StartArchiveHelper will return a started copy of given container with a
+// new id, which contains the files of given container and its staged
+// archives.
+func (in *Backend) StartArchiveHelper(tainr *backend.Container) (*backend.Container, error) {
+	helper := backend.Container{
+		ID:             tainr.ID + "-archive",
+		ShortID:        tainr.ShortID + "-archive",
+		Image:          tainr.Image,
+		Labels:         tainr.Labels,
+		StagedArchives: tainr.StagedArchives,
+	}
+	in.lock.Lock()
+	in.files[helper.ID] = map[string]file{}
+	for p, f := range in.files[tainr.ID] {
+		in.files[helper.ID][p] = f
+	}
+	in.lock.Unlock()
+	if _, err := in.StartContainer(context.Background(), &helper); err != nil {
+		return nil, err
+	}
+	return &helper, nil
+}
+
+// StageArchive will keep the given archive in memory, and returns the name
+// that refers to it.
+func (in *Backend) StageArchive(helper *backend.Container, reader io.Reader) (string, error) {
+	dat, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	name := fmt.Sprintf("archive-%d", len(in.staged))
+	in.staged[name] = dat
+	return name, nil
+}
+
+// DeleteContainerVolumes is a no-op.
+func (in *Backend) DeleteContainerVolumes(tainr *backend.Container) error {
+	return nil
+}
+
+// GetFileStatInContainer will return the details of given path in given
+// container. Paths that contain copied files are reported as directories.
+func (in *Backend) GetFileStatInContainer(tainr *backend.Container, target string) (*backend.FileStat, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	target = path.Clean(target)
+	if f, ok := in.files[tainr.ID][target]; ok {
+		return &backend.FileStat{Name: path.Base(target), Size: int64(len(f.data)), Mode: f.mode, ModTime: f.mtime}, nil
+	}
+	for p := range in.files[tainr.ID] {
+		if target == "/" || strings.HasPrefix(p, target+"/") {
+			return &backend.FileStat{Name: path.Base(target), Size: 4096, Mode: fs.ModeDir | 0755}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", target, fs.ErrNotExist)
+}
+
+// FileExistsInContainer will return true if given path exists in given
+// container.
+func (in *Backend) FileExistsInContainer(tainr *backend.Container, target string) (bool, error) {
+	_, err := in.GetFileStatInContainer(tainr, target)
+	return err == nil, nil
+}
+
+// ExecContainer will execute given command using the configured Exec
+// function.
+func (in *Backend) ExecContainer(ctx context.Context, tainr *backend.Container, exec *backend.Exec, stdin io.Reader, stdout io.Writer) (int, error) {
+	if in.Exec == nil {
+		return 0, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return in.Exec(tainr, exec, stdin, stdout)
+}
+
+// GetLogs will write the configured logs to given writer using stdout/stderr
+// multiplexing.
+func (in *Backend) GetLogs(tainr *backend.Container, opts *backend.LogOptions, stop chan struct{}, w io.Writer) error {
+	out := ioproxy.New(w, ioproxy.Stdout, &sync.Mutex{})
+	defer out.Flush()
+	return in.writeLogs(out)
+}
+
+// GetLogsRaw will write the configured logs to given writer.
+func (in *Backend) GetLogsRaw(tainr *backend.Container, opts *backend.LogOptions, stop chan struct{}, w io.Writer) error {
+	return in.writeLogs(w)
+}
+
+// writeLogs will write the configured logs to given writer.
+func (in *Backend) writeLogs(w io.Writer) error {
+	for _, line := range in.Logs {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InspectImage will return the configured details of given image.
+func (in *Backend) InspectImage(name string) (*backend.ImageDetails, error) {
+	if in.InspectError != nil {
+		return nil, in.InspectError
+	}
+	if dtl, ok := in.Images[name]; ok {
+		return dtl, nil
+	}
+	return &backend.ImageDetails{
+		Config: &v1.Image{Platform: v1.Platform{OS: "linux", Architecture: "amd64"}},
+		Digest: digest.FromString(name).String(),
+	}, nil
+}
+
+// GetImageDistribution will return a linux/amd64 distribution for given
+// image.
+func (in *Backend) GetImageDistribution(name string) (*backend.ImageDistribution, error) {
+	dtl, err := in.InspectImage(name)
+	if err != nil {
+		return nil, err
+	}
+	return &backend.ImageDistribution{
+		Descriptor: v1.Descriptor{MediaType: v1.MediaTypeImageManifest, Digest: digest.Digest(dtl.Digest), Size: dtl.Size},
+		Platforms:  []v1.Platform{{OS: "linux", Architecture: "amd64"}},
+	}, nil
+}
+
+// GetImageManifest will return an oci manifest of given image, which refers
+// to the blobs that can be fetched from the registry.
+func (in *Backend) GetImageManifest(repo, ref string) (*backend.ImageManifest, error) {
+	if err, ok := in.RegistryErrors[repo]; ok {
+		return nil, err
+	}
+	man := v1.Manifest{MediaType: v1.MediaTypeImageManifest, Layers: []v1.Descriptor{}}
+	man.SchemaVersion = 2
+	for dgst, blob := range in.Blobs {
+		man.Layers = append(man.Layers, v1.Descriptor{MediaType: v1.MediaTypeImageLayerGzip, Digest: digest.Digest(dgst), Size: int64(len(blob))})
+	}
+	sort.Slice(man.Layers, func(i, j int) bool { return man.Layers[i].Digest < man.Layers[j].Digest })
+	blob, err := json.Marshal(man)
+	if err != nil {
+		return nil, err
+	}
+	return &backend.ImageManifest{Blob: blob, MediaType: man.MediaType, Digest: digest.FromBytes(blob).String()}, nil
+}
+
+// GetImageBlob will return the blob with given digest.
+func (in *Backend) GetImageBlob(repo, dgst string) (io.ReadCloser, int64, error) {
+	blob, ok := in.Blobs[dgst]
+	if !ok {
+		return nil, 0, fmt.Errorf("blob %s not found", dgst)
+	}
+	return io.NopCloser(bytes.NewReader(blob)), int64(len(blob)), nil
+}
+
+// PrewarmImages will return the given images as prewarmed.
+func (in *Backend) PrewarmImages(images []string) ([]string, error) {
+	return images, nil
+}
+
+// SetImageRewrites is a no-op, as images are not pulled.
+func (in *Backend) SetImageRewrites(rules []backend.RewriteRule) {}
+
+// GetPodEvents will return no events.
+func (in *Backend) GetPodEvents(tainr *backend.Container) ([]corev1.Event, error) {
+	return []corev1.Event{}, nil
+}
+
+// DeployService will record the number of replicas of given service, which
+// are all considered to be ready.
+func (in *Backend) DeployService(svc *backend.Service) error {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	in.services[svc.ID] = svc.Replicas
+	return nil
+}
+
+// GetServiceReplicas will return the number of replicas of given service.
+func (in *Backend) GetServiceReplicas(svc *backend.Service) (int, error) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	replicas, ok := in.services[svc.ID]
+	if !ok {
+		return 0, fmt.Errorf("service %s is not deployed", svc.ShortID)
+	}
+	return replicas, nil
+}
+
+// DeleteService will remove given service.
+func (in *Backend) DeleteService(svc *backend.Service) error {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	delete(in.services, svc.ID)
+	return nil
+}
+
+// CheckFeature will return the error in Disabled for the given feature, or
+// nil if the feature is not disabled.
+func (in *Backend) CheckFeature(feature string) error {
+	return in.Disabled[feature]
+}
@@ -0,0 +1,227 @@
+package backend
+
+import (
+	"io/fs"
+	"time"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Container describes the details of a container that is orchestrated by
+// a backend. The backend stores the details of the deployment (PodName,
+// HostIP, MappedPorts, LinkEnv, LinkHosts and StartTimings) in the
+// container when it's started, or when its ports are forwarded.
+type Container struct {
+	ID              string
+	ShortID         string
+	Name            string
+	Hostname        string
+	Image           string
+	Labels          map[string]string
+	Entrypoint      []string
+	Cmd             []string
+	Env             []string
+	SecretEnv       map[string]string
+	Binds           []string
+	Mounts          []Mount
+	PreArchives     []PreArchive
+	StagedArchives  []PreArchive
+	HostIP          string
+	ExposedPorts    map[string]interface{}
+	ImagePorts      map[string]interface{}
+	HostPorts       map[int]int
+	MappedPorts     map[int]int
+	Networks        map[string]interface{}
+	IPAddresses     map[string]string
+	StaticIPs       map[string]string
+	NetworkAliases  []string
+	EndpointAliases map[string][]string
+	NetworkOwner    string
+	NetworkPod      string
+	PodName         string
+	HostNetwork     bool
+	Sysctls         map[string]string
+	Ulimits         []Ulimit
+	Devices         []Device
+	Links           map[string]string
+	LinkEnv         []string
+	LinkHosts       map[string][]string
+	Initialized     bool
+	Running         bool
+	Completed       bool
+	Failed          bool
+	Stopped         bool
+	Killed          bool
+	Error           string
+	ExitStatus      int
+	Tty             bool
+	OpenStdin       bool
+	Created         time.Time
+	Started         time.Time
+	Finished        time.Time
+	StartTimings    map[string]time.Duration
+	// TerminalSizes contains the terminal sizes of the client while the
+	// container is attached with a tty, and is nil otherwise.
+	TerminalSizes TerminalSizeQueue
+}
+
+// Mount contains the details of a mounted volume/binding.
+type Mount struct {
+	Type        string
+	Source      string
+	Target      string
+	ReadOnly    bool
+	Propagation string
+}
+
+// PreArchive contains the path and contents of archives (tar) that need to be
+// copied over to the container before it has been started. Staged archives
+// are not kept in memory, but refer to the File returned by StageArchive
+// instead.
+type PreArchive struct {
+	Path    string
+	Archive []byte
+	File    string
+}
+
+// Device contains the details of a device that is passed through to the
+// container, either as a hostPath volume, or as a device plugin resource.
+type Device struct {
+	HostPath      string
+	ContainerPath string
+	Resource      string
+}
+
+// Ulimit contains the soft and hard limit of a resource of a container.
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// Exec describes the details of an execute command.
+type Exec struct {
+	ID          string
+	ContainerID string
+	Cmd         []string
+	TTY         bool
+	Stdin       bool
+	Stdout      bool
+	Stderr      bool
+	DetachKeys  string
+	ExitCode    int
+	Created     time.Time
+	// TerminalSizes contains the terminal sizes of the client while the
+	// command is executed with a tty, and is nil otherwise.
+	TerminalSizes TerminalSizeQueue
+}
+
+// TerminalSizeQueue returns the terminal sizes of a tty session. Next will
+// block until the terminal is resized, and returns nil when the session has
+// ended.
+type TerminalSizeQueue interface {
+	Next() *remotecommand.TerminalSize
+}
+
+// Service describes the details of a swarm service.
+type Service struct {
+	ID         string
+	ShortID    string
+	Name       string
+	Image      string
+	Labels     map[string]string
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	Replicas   int
+	Created    time.Time
+	Updated    time.Time
+}
+
+// ContainerExit contains the details of a container that has terminated.
+type ContainerExit struct {
+	// Code is the exit code of the container
+	Code int
+	// Reason is the reason why the backend terminated the container (e.g.
+	// the pod was evicted), empty if the container exited by itself
+	Reason string
+}
+
+// FileStat contains the details of a file in a container.
+type FileStat struct {
+	// Name is the base name of the file
+	Name string
+	// Size is the size of the file in bytes
+	Size int64
+	// Mode contains the file mode, including the type of the file
+	Mode fs.FileMode
+	// ModTime is the last modification time of the file
+	ModTime time.Time
+	// LinkTarget contains the target if the file is a symbolic link
+	LinkTarget string
+}
+
+// LogOptions describe the supported log options
+type LogOptions struct {
+	// Keep connection after returning logs.
+	Follow bool
+	// Only return logs since this time, as a UNIX timestamp
+	SinceTime *time.Time
+	// Add timestamps to every log line
+	Timestamps bool
+	// Number of lines to show from the end of the logs
+	TailLines *uint64
+}
+
+// ContainerStats contains the resource usage of a container.
+type ContainerStats struct {
+	// CPUNanoCores is the cpu usage in billionths of a core
+	CPUNanoCores int64
+	// MemoryUsage is the working set of the container in bytes
+	MemoryUsage int64
+	// MemoryLimit is the memory limit of the container in bytes, or 0 if
+	// the container has no memory limit
+	MemoryLimit int64
+	// Timestamp is the time at which the usage was measured
+	Timestamp time.Time
+}
+
+// Capacity contains the amount of cpu and memory that is available to the
+// containers that are deployed by the backend.
+type Capacity struct {
+	// NCPU is the number of cpus, rounded up
+	NCPU int
+	// MemTotal is the amount of memory in bytes
+	MemTotal int64
+}
+
+// ImageDetails contains the configuration of an image, together with the
+// digest of its manifest and the total (compressed) size of its layers.
+type ImageDetails struct {
+	Config *v1.Image
+	Digest string
+	Size   int64
+}
+
+// ImageDistribution contains the descriptor of an image manifest in the
+// registry, and the platforms that are supported by the image.
+type ImageDistribution struct {
+	Descriptor v1.Descriptor
+	Platforms  []v1.Platform
+}
+
+// ImageManifest is an image manifest as stored in the registry.
+type ImageManifest struct {
+	Blob      []byte
+	MediaType string
+	Digest    string
+}
+
+// RewriteRule describes a rule to rewrite an image reference that matches
+// From to To. Both From and To can end with a '*' wildcard, in which case
+// the remainder of the matched reference is appended to To.
+type RewriteRule struct {
+	From string
+	To   string
+}
@@ -0,0 +1,107 @@
+package kubedock
+
+import (
+	"time"
+
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/model"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	pkgbackend "github.com/joyrex2001/kubedock/pkg/backend"
+)
+
+// DB gives read access to the containers and networks that are created with
+// the api of a kubedock instance. The returned records are copies, so they
+// can't be used to modify the state of kubedock.
+type DB struct {
+	db *model.Database
+}
+
+// Network describes the details of a network.
+type Network struct {
+	ID      string
+	ShortID string
+	Name    string
+	Subnet  string
+	Gateway string
+	Labels  map[string]string
+	Created time.Time
+}
+
+// GetContainer will return the container with given id or name, or an error
+// if the container does not exist.
+func (db *DB) GetContainer(id string) (*pkgbackend.Container, error) {
+	tainr, err := db.db.GetContainerByNameOrID(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.PublicContainer(tainr.Clone()), nil
+}
+
+// GetContainers will return all containers.
+func (db *DB) GetContainers() ([]*pkgbackend.Container, error) {
+	tainrs, err := db.db.GetContainers()
+	if err != nil {
+		return nil, err
+	}
+	return publicContainers(tainrs), nil
+}
+
+// GetContainersByLabel will return all containers that have a label with
+// given key, and given value if the value is not empty.
+func (db *DB) GetContainersByLabel(key, val string) ([]*pkgbackend.Container, error) {
+	tainrs, err := db.db.GetContainersByLabel(key, val)
+	if err != nil {
+		return nil, err
+	}
+	return publicContainers(tainrs), nil
+}
+
+// GetNetwork will return the network with given id or name, or an error if
+// the network does not exist.
+func (db *DB) GetNetwork(id string) (*Network, error) {
+	netw, err := db.db.GetNetworkByNameOrID(id)
+	if err != nil {
+		return nil, err
+	}
+	return publicNetwork(netw), nil
+}
+
+// GetNetworks will return all networks, including the pre-defined networks
+// (bridge, host and null).
+func (db *DB) GetNetworks() ([]*Network, error) {
+	netws, err := db.db.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Network, 0, len(netws))
+	for _, netw := range netws {
+		res = append(res, publicNetwork(netw))
+	}
+	return res, nil
+}
+
+// publicContainers will return copies of given containers.
+func publicContainers(tainrs []*types.Container) []*pkgbackend.Container {
+	res := make([]*pkgbackend.Container, 0, len(tainrs))
+	for _, tainr := range tainrs {
+		res = append(res, adapter.PublicContainer(tainr.Clone()))
+	}
+	return res
+}
+
+// publicNetwork will return a copy of given network.
+func publicNetwork(netw *types.Network) *Network {
+	labels := map[string]string{}
+	for k, v := range netw.Labels {
+		labels[k] = v
+	}
+	return &Network{
+		ID:      netw.ID,
+		ShortID: netw.ShortID,
+		Name:    netw.Name,
+		Subnet:  netw.Subnet,
+		Gateway: netw.GetGateway(),
+		Labels:  labels,
+		Created: netw.Created,
+	}
+}
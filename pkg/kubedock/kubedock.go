@@ -0,0 +1,341 @@
+// Package kubedock provides an api to run kubedock in-process, which allows
+// go test suites to start a kubedock api server against a kubernetes cluster
+// (e.g. envtest or kind) without running the kubedock binary.
+package kubedock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+
+	"github.com/joyrex2001/kubedock/internal/backend"
+	"github.com/joyrex2001/kubedock/internal/backend/adapter"
+	"github.com/joyrex2001/kubedock/internal/config"
+	"github.com/joyrex2001/kubedock/internal/model/types"
+	"github.com/joyrex2001/kubedock/internal/reaper"
+	"github.com/joyrex2001/kubedock/internal/server"
+	"github.com/joyrex2001/kubedock/internal/server/routes/common"
+	"github.com/joyrex2001/kubedock/internal/util/myip"
+	pkgbackend "github.com/joyrex2001/kubedock/pkg/backend"
+)
+
+// Config is the structure to instantiate a Kubedock object. Only Client or
+// RestConfig is required (or Backend), all other settings default to the
+// defaults of the kubedock server command.
+type Config struct {
+	// Backend is the backend that orchestrates the containers; if not set,
+	// the kubernetes backend is created using Client or RestConfig. This
+	// can be used to inject the in-memory backend of pkg/backend/fake.
+	Backend pkgbackend.Backend
+	// RestConfig is the kubernetes config; it is required to exec into,
+	// copy from, or port-forward to containers.
+	RestConfig *rest.Config
+	// Client is the kubernetes clientset; if not set, it will be created
+	// using RestConfig.
+	Client kubernetes.Interface
+	// Namespace is the namespace in which all actions are performed
+	// (default "default").
+	Namespace string
+	// ListenAddr is the address the api server listens on (default
+	// "127.0.0.1:0", which selects a free port).
+	ListenAddr string
+	// KubedockURL is the url that is used by docker-in-docker sidecars to
+	// reach this kubedock instance (default based on the ip of this host).
+	KubedockURL string
+	// InitImage is the image that is used as init container to prepare vols.
+	InitImage string
+	// DindImage is the image that is used as a sidecar container to
+	// support docker-in-docker.
+	DindImage string
+	// DisableDind will disable docker-in-docker support when set to true.
+	DisableDind bool
+	// SELinuxRelabel will set the SELinux level of pods that have volumes
+	// with a z or Z option.
+	SELinuxRelabel bool
+	// SecurityProfile is the security profile that is applied to the pods;
+	// privileged (default), baseline or openshift-restricted.
+	SecurityProfile string
+	// QuotaPolicy is the policy for containers that don't fit in the
+	// resource quotas of the namespace; reject (default) or queue.
+	QuotaPolicy string
+	// DisableSidecarInjection will disable service mesh (istio, linkerd)
+	// sidecar injection in the pods.
+	DisableSidecarInjection bool
+	// ImagePullSecrets is an optional list of image pull secrets that need
+	// to be added to the used pod templates.
+	ImagePullSecrets []string
+	// PodTemplate refers to an optional file containing a pod resource that
+	// should be used as the base for creating pod resources.
+	PodTemplate string
+	// Timeout is the max amount of time to wait until a container started
+	// or deleted (default 1m).
+	Timeout time.Duration
+	// ReapMax is the maximum age of containers before they are reaped
+	// (default 60m).
+	ReapMax time.Duration
+	// RetainFailed is the time failed containers that are labelled with
+	// retain-on-failure are kept after removal (default 60m).
+	RetainFailed time.Duration
+	// ForwardIdleTimeout is the max time a port-forward or reverse proxy can
+	// be idle before it is closed (default 0, never).
+	ForwardIdleTimeout time.Duration
+	// ImageCacheTTL is the time an inspected image reference is resolved to
+	// the same digest (default 0, only concurrent inspects are deduplicated).
+	ImageCacheTTL time.Duration
+	// PinDigests will resolve image tags to a digest at first use, and use
+	// that digest for all subsequent containers of the image.
+	PinDigests bool
+	// ExecIdleTimeout is the max time an exec or attach session can be idle
+	// before it is terminated (default 0, never).
+	ExecIdleTimeout time.Duration
+	// ExecMaxDuration is the max time an exec or attach session can run
+	// before it is terminated (default 0, never).
+	ExecMaxDuration time.Duration
+	// PortForward will create port-forwards for all mapped ports.
+	PortForward bool
+	// ReverseProxy will create reverse proxies for all mapped ports.
+	ReverseProxy bool
+	// ProxyHostIP is the ip that is reported as host ip of reverse proxied
+	// ports, e.g. the ip of the pod kubedock runs in (optional).
+	ProxyHostIP string
+	// DisableServices will disable the creation of services for networking.
+	DisableServices bool
+	// ScopedRBAC will probe the permissions of the service account, and
+	// disable the features that are not permitted.
+	ScopedRBAC bool
+	// PreArchive will enable copying files without starting containers.
+	PreArchive bool
+	// ArchiveHelper will enable archive operations on containers that are
+	// not running.
+	ArchiveHelper bool
+	// MaxStreams is the maximum number of simultaneous log and event
+	// streams (default 0, unlimited).
+	MaxStreams int
+	// ListCacheTTL is the duration container, image and network list
+	// responses are cached (default 0, disabled).
+	ListCacheTTL time.Duration
+	// Inspector will enable inspecting images in the registry.
+	Inspector bool
+	// RequestCPU contains the default cpu request for containers.
+	RequestCPU string
+	// RequestMemory contains the default memory request for containers.
+	RequestMemory string
+	// PullPolicy contains the default pull policy for images (default
+	// "ifnotpresent").
+	PullPolicy string
+	// Readiness contains the default condition for a container to be
+	// considered started (default "running").
+	Readiness string
+}
+
+// Kubedock is a kubedock instance that runs in-process. The state of the
+// containers is kept per process, so only one instance should run at a time.
+type Kubedock struct {
+	kub    backend.Backend
+	cr     *common.ContextRouter
+	router *gin.Engine
+	lis    net.Listener
+	reap   time.Duration
+	idle   time.Duration
+}
+
+// New will instantiate a Kubedock object and open the listener of the api
+// server, so Host can be used before Run is called.
+func New(cfg Config) (*Kubedock, error) {
+	if err := setDefaults(&cfg); err != nil {
+		return nil, err
+	}
+
+	lis, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.KubedockURL == "" {
+		ip, _ := myip.Get()
+		cfg.KubedockURL = fmt.Sprintf("http://%s:%d", ip, lis.Addr().(*net.TCPAddr).Port)
+	}
+
+	kub, err := newBackend(cfg)
+	if err != nil {
+		lis.Close()
+		return nil, err
+	}
+
+	cr, err := common.NewContextRouter(kub, common.Config{
+		Inspector:        cfg.Inspector,
+		PinDigests:       cfg.PinDigests,
+		RequestCPU:       cfg.RequestCPU,
+		RequestMemory:    cfg.RequestMemory,
+		PullPolicy:       cfg.PullPolicy,
+		PortForward:      cfg.PortForward,
+		ReverseProxy:     cfg.ReverseProxy && !cfg.PortForward,
+		ProxyHostIP:      cfg.ProxyHostIP,
+		PreArchive:       cfg.PreArchive,
+		ArchiveHelper:    cfg.ArchiveHelper,
+		MaxStreams:       cfg.MaxStreams,
+		ListCacheTTL:     cfg.ListCacheTTL,
+		Readiness:        cfg.Readiness,
+		ReadinessTimeout: cfg.Timeout,
+	})
+	if err != nil {
+		lis.Close()
+		return nil, err
+	}
+
+	return &Kubedock{
+		kub:    kub,
+		cr:     cr,
+		router: server.NewRouter(cr),
+		lis:    lis,
+		reap:   cfg.ReapMax,
+		idle:   cfg.ForwardIdleTimeout,
+	}, nil
+}
+
+// newBackend will return the configured backend, or instantiate the
+// kubernetes backend if no backend is configured.
+func newBackend(cfg Config) (backend.Backend, error) {
+	if cfg.Backend != nil {
+		return adapter.Internal(cfg.Backend), nil
+	}
+	return backend.New(backend.Config{
+		Client:                  cfg.Client,
+		RestConfig:              cfg.RestConfig,
+		Namespace:               cfg.Namespace,
+		InitImage:               cfg.InitImage,
+		DindImage:               cfg.DindImage,
+		DisableDind:             cfg.DisableDind,
+		SELinuxRelabel:          cfg.SELinuxRelabel,
+		SecurityProfile:         cfg.SecurityProfile,
+		QuotaPolicy:             cfg.QuotaPolicy,
+		DisableSidecarInjection: cfg.DisableSidecarInjection,
+		ImagePullSecrets:        cfg.ImagePullSecrets,
+		ImageCacheTTL:           cfg.ImageCacheTTL,
+		PinDigests:              cfg.PinDigests,
+		PodTemplate:             cfg.PodTemplate,
+		KubedockURL:             cfg.KubedockURL,
+		TimeOut:                 cfg.Timeout,
+		DisableServices:         cfg.DisableServices,
+		ArchiveHelper:           cfg.ArchiveHelper,
+		ScopedRBAC:              cfg.ScopedRBAC,
+		RetainFailed:            cfg.RetainFailed,
+		ExecIdleTimeout:         cfg.ExecIdleTimeout,
+		ExecMaxDuration:         cfg.ExecMaxDuration,
+	})
+}
+
+// setDefaults will validate given config and set the defaults for all
+// settings that are not configured.
+func setDefaults(cfg *Config) error {
+	if cfg.Client == nil && cfg.Backend == nil {
+		if cfg.RestConfig == nil {
+			return errors.New("either a kubernetes client or rest config is required")
+		}
+		cli, err := kubernetes.NewForConfig(cfg.RestConfig)
+		if err != nil {
+			return err
+		}
+		cfg.Client = cli
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = "default"
+	}
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = "127.0.0.1:0"
+	}
+	if cfg.InitImage == "" {
+		cfg.InitImage = config.Image
+	}
+	if cfg.DindImage == "" {
+		cfg.DindImage = config.Image
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Minute
+	}
+	if cfg.ReapMax == 0 {
+		cfg.ReapMax = 60 * time.Minute
+	}
+	if cfg.RetainFailed == 0 {
+		cfg.RetainFailed = 60 * time.Minute
+	}
+	if cfg.PullPolicy == "" {
+		cfg.PullPolicy = "ifnotpresent"
+	}
+	if err := types.ValidatePullPolicy(cfg.PullPolicy); err != nil {
+		return err
+	}
+	if cfg.Readiness == "" {
+		cfg.Readiness = types.ReadinessRunning
+	}
+	return types.ValidateReadiness(cfg.Readiness)
+}
+
+// Host will return the docker host that can be used to connect to this
+// kubedock instance (e.g. tcp://127.0.0.1:2475).
+func (k *Kubedock) Host() string {
+	return "tcp://" + k.lis.Addr().String()
+}
+
+// Handler will return the http handler that serves the kubedock api.
+func (k *Kubedock) Handler() http.Handler {
+	return k.router
+}
+
+// DB will return the database that contains the containers and networks
+// that are created with the api of this kubedock instance.
+func (k *Kubedock) DB() *DB {
+	return &DB{db: k.cr.DB}
+}
+
+// Backend will return the backend that orchestrates the containers of this
+// kubedock instance; the injected backend if Config.Backend was set.
+func (k *Kubedock) Backend() pkgbackend.Backend {
+	return adapter.Public(k.kub, k.cr.DB.GetContainer)
+}
+
+// Run will serve the kubedock api until given context is cancelled. When
+// stopped, all resources created by this instance are removed.
+func (k *Kubedock) Run(ctx context.Context) error {
+	rpr, err := reaper.New(reaper.Config{KeepMax: k.reap, Backend: k.kub, ForwardIdle: k.idle})
+	if err != nil {
+		return err
+	}
+	rpr.Start()
+	defer rpr.Stop()
+	if err := reaper.AddUploads(k.cr.Uploads); err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: k.router}
+	errch := make(chan error, 1)
+	go func() {
+		errch <- srv.Serve(k.lis)
+	}()
+	klog.Infof("api server started listening on %s", k.lis.Addr())
+
+	select {
+	case err = <-errch:
+	case <-ctx.Done():
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = srv.Shutdown(sctx)
+	}
+
+	klog.Info("removing pods, configmaps and services")
+	if derr := k.kub.DeleteWithKubedockID(config.InstanceID); derr != nil {
+		klog.Errorf("error pruning resources: %s", derr)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
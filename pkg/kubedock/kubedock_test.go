@@ -0,0 +1,122 @@
+package kubedock
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joyrex2001/kubedock/pkg/backend"
+	bfake "github.com/joyrex2001/kubedock/pkg/backend/fake"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		cfg Config
+		err bool
+	}{
+		{cfg: Config{}, err: true},
+		{cfg: Config{Client: fake.NewSimpleClientset()}, err: false},
+		{cfg: Config{Client: fake.NewSimpleClientset(), PullPolicy: "sometimes"}, err: true},
+		{cfg: Config{Client: fake.NewSimpleClientset(), Readiness: "eventually"}, err: true},
+		{cfg: Config{Backend: bfake.New()}, err: false},
+	}
+	for i, tst := range tests {
+		kd, err := New(tst.cfg)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error %s", i, err)
+		}
+		if kd != nil {
+			kd.lis.Close()
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	kd, err := New(Config{Client: fake.NewSimpleClientset()})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(kd.Host(), "tcp://127.0.0.1:") {
+		t.Errorf("failed test - expected tcp://127.0.0.1:<port>, but got %s", kd.Host())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- kd.Run(ctx)
+	}()
+
+	res, err := http.Get(strings.Replace(kd.Host(), "tcp://", "http://", 1) + "/_ping")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("failed test - expected %d, but got %d", http.StatusOK, res.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("failed test - unexpected error %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Errorf("failed test - run didn't stop after cancel")
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	kub := bfake.New()
+	kd, err := New(Config{Backend: kub})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer kd.lis.Close()
+	if kd.Backend() != kub {
+		t.Errorf("failed test - expected the injected backend, but got %#v", kd.Backend())
+	}
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		code   int
+	}{
+		{method: http.MethodPost, url: "/containers/create?name=accessor401", body: `{"Image":"alpine:latest","Labels":{"suite":"accessor401"}}`, code: http.StatusCreated},
+		{method: http.MethodPost, url: "/networks/create", body: `{"Name":"accessor401-net"}`, code: http.StatusCreated},
+		{method: http.MethodPost, url: "/containers/accessor401/start", code: http.StatusNoContent},
+	}
+	for i, tst := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tst.method, tst.url, strings.NewReader(tst.body))
+		req.Header.Set("Content-Type", "application/json")
+		kd.Handler().ServeHTTP(w, req)
+		if w.Code != tst.code {
+			t.Fatalf("failed test %d - expected %d, but got %d: %s", i, tst.code, w.Code, w.Body.String())
+		}
+	}
+
+	tainr, err := kd.DB().GetContainer("accessor401")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !tainr.Running || tainr.Labels["suite"] != "accessor401" {
+		t.Errorf("failed test - expected running container with label, but got %#v", tainr)
+	}
+	tainrs, err := kd.DB().GetContainersByLabel("suite", "accessor401")
+	if err != nil || len(tainrs) != 1 || tainrs[0].ID != tainr.ID {
+		t.Errorf("failed test - expected %s, but got %v (%v)", tainr.ID, tainrs, err)
+	}
+	if state, err := kd.Backend().GetContainerStatus(tainr); err != nil || state != backend.DeployRunning {
+		t.Errorf("failed test - expected %v, but got %v (%v)", backend.DeployRunning, state, err)
+	}
+	if netw, err := kd.DB().GetNetwork("accessor401-net"); err != nil || netw.Subnet == "" {
+		t.Errorf("failed test - expected network with subnet, but got %#v (%v)", netw, err)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// service is the subset of the docker compose ps output used in the tests.
+type service struct {
+	Service string
+	State   string
+}
+
+func TestComposeUpDown(t *testing.T) {
+	requireClient(t, "docker", "compose", "version")
+
+	compose := func(args ...string) string {
+		t.Helper()
+		args = append([]string{"compose", "-p", "kubedock-e2e", "-f", "testdata/docker-compose.yaml"}, args...)
+		return runCmd(t, "", "docker", args...)
+	}
+	t.Cleanup(func() {
+		compose("down", "--remove-orphans")
+	})
+
+	compose("up", "-d", "--wait")
+
+	states := map[string]string{}
+	for _, svc := range composePs(t, compose("ps", "--format", "json")) {
+		states[svc.Service] = svc.State
+	}
+	for _, svc := range []string{"web", "client"} {
+		if states[svc] != "running" {
+			t.Errorf("expected service %s to be running, but got %s", svc, states[svc])
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:18081", 5*time.Second)
+	if err != nil {
+		t.Errorf("published port of web service is not reachable: %s", err)
+	} else {
+		conn.Close()
+	}
+
+	logs := ""
+	for i := 0; i < 60 && !strings.Contains(logs, "ok"); i++ {
+		time.Sleep(time.Second)
+		logs = compose("logs", "client")
+	}
+	if !strings.Contains(logs, "ok") {
+		t.Errorf("expected client to reach web service, but got logs:\n%s", logs)
+	}
+
+	compose("down")
+	if out := strings.TrimSpace(compose("ps", "-a", "-q")); out != "" {
+		t.Errorf("expected no containers after down, but got:\n%s", out)
+	}
+}
+
+// composePs will parse the given output of docker compose ps, which is
+// either a json array, or one json object per line, depending on the
+// compose version.
+func composePs(t *testing.T, out string) []service {
+	t.Helper()
+	out = strings.TrimSpace(out)
+	res := []service{}
+	if strings.HasPrefix(out, "[") {
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("failed parsing compose ps output: %s\n%s", err, out)
+		}
+		return res
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		svc := service{}
+		if err := json.Unmarshal([]byte(line), &svc); err != nil {
+			t.Fatalf("failed parsing compose ps output: %s\n%s", err, out)
+		}
+		res = append(res, svc)
+	}
+	return res
+}
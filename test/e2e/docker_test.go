@@ -0,0 +1,48 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDockerRun(t *testing.T) {
+	requireClient(t, "docker", "--version")
+
+	out := runCmd(t, "", "docker", "run", "--rm", "busybox:latest", "echo", "hello kubedock")
+	if !strings.Contains(out, "hello kubedock") {
+		t.Errorf("expected output of container, but got:\n%s", out)
+	}
+}
+
+func TestDockerLifecycle(t *testing.T) {
+	requireClient(t, "docker", "--version")
+
+	name := "kubedock-e2e-lifecycle"
+	runCmd(t, "", "docker", "create", "--name", name, "busybox:latest", "sleep", "600")
+	t.Cleanup(func() {
+		runCmd(t, "", "docker", "rm", "-f", name)
+	})
+
+	src := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(src, []byte("copied"), 0644); err != nil {
+		t.Fatalf("failed writing test file: %s", err)
+	}
+	runCmd(t, "", "docker", "cp", src, name+":/tmp/data.txt")
+	runCmd(t, "", "docker", "start", name)
+
+	if out := runCmd(t, "", "docker", "exec", name, "cat", "/tmp/data.txt"); !strings.Contains(out, "copied") {
+		t.Errorf("expected copied file in container, but got:\n%s", out)
+	}
+	if out := runCmd(t, "", "docker", "ps", "--filter", "name="+name, "--format", "{{.Names}}"); !strings.Contains(out, name) {
+		t.Errorf("expected %s in running containers, but got:\n%s", name, out)
+	}
+
+	runCmd(t, "", "docker", "stop", name)
+	if out := runCmd(t, "", "docker", "ps", "--filter", "name="+name, "--format", "{{.Names}}"); strings.Contains(out, name) {
+		t.Errorf("expected %s not to be running after stop, but got:\n%s", name, out)
+	}
+}
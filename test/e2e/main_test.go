@@ -0,0 +1,180 @@
+//go:build e2e
+
+// Package e2e contains an end-to-end test suite that verifies kubedock with
+// the clients it is used with (docker cli, docker compose, podman-remote and
+// testcontainers-go). The suite creates a kind cluster, builds and starts
+// kubedock against it, and runs the clients against the running instance.
+// Tests of clients that are not installed are skipped, unless
+// E2E_REQUIRE_CLIENTS is set. The suite is run with:
+//
+//	make test-e2e
+//
+// The suite can be configured with the following environment variables:
+//
+//	E2E_KIND_CLUSTER  name of the kind cluster (default kubedock-e2e); an
+//	                  existing cluster with this name is reused
+//	E2E_KEEP_CLUSTER  if set, the kind cluster is not deleted afterwards
+//	E2E_KUBECONFIG    use the cluster of this kubeconfig instead of kind
+//	E2E_REQUIRE_CLIENTS  if set, tests of clients that are not installed
+//	                  fail instead of being skipped
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const addr = "127.0.0.1:2501"
+
+// dockerHost is the docker host of the kubedock instance under test.
+var dockerHost = "tcp://" + addr
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run will set up the cluster and kubedock, run the tests and clean up
+// afterwards. It returns the exit code of the test run.
+func run(m *testing.M) int {
+	tmp, err := os.MkdirTemp("", "kubedock-e2e")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed creating temp dir: %s\n", err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+
+	kubeconfig := os.Getenv("E2E_KUBECONFIG")
+	if kubeconfig == "" {
+		cluster := getEnv("E2E_KIND_CLUSTER", "kubedock-e2e")
+		kubeconfig = filepath.Join(tmp, "kubeconfig")
+		created, err := setupKind(cluster, kubeconfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed setting up kind cluster: %s\n", err)
+			return 1
+		}
+		if created && os.Getenv("E2E_KEEP_CLUSTER") == "" {
+			defer func() {
+				if out, err := exec.Command("kind", "delete", "cluster", "--name", cluster).CombinedOutput(); err != nil {
+					fmt.Fprintf(os.Stderr, "failed deleting kind cluster: %s\n%s", err, out)
+				}
+			}()
+		}
+	}
+
+	stop, err := startKubedock(tmp, kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed starting kubedock: %s\n", err)
+		return 1
+	}
+	defer stop()
+
+	return m.Run()
+}
+
+// setupKind will create a kind cluster with given name, or reuse it if it
+// already exists, and writes its kubeconfig to given path. It returns true
+// if the cluster was created.
+func setupKind(cluster, kubeconfig string) (bool, error) {
+	out, err := exec.Command("kind", "get", "clusters").Output()
+	if err != nil {
+		return false, fmt.Errorf("kind is required to run the e2e tests: %w", err)
+	}
+	for _, name := range strings.Fields(string(out)) {
+		if name == cluster {
+			out, err := exec.Command("kind", "export", "kubeconfig", "--name", cluster, "--kubeconfig", kubeconfig).CombinedOutput()
+			if err != nil {
+				return false, fmt.Errorf("%w\n%s", err, out)
+			}
+			return false, nil
+		}
+	}
+	cmd := exec.Command("kind", "create", "cluster", "--name", cluster, "--kubeconfig", kubeconfig, "--wait", "120s")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return true, cmd.Run()
+}
+
+// startKubedock will build kubedock and start it against the cluster of the
+// given kubeconfig. It returns a function that stops the instance.
+func startKubedock(tmp, kubeconfig string) (func(), error) {
+	bin := filepath.Join(tmp, "kubedock")
+	build := exec.Command("go", "build", "-o", bin, "../..")
+	build.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if out, err := build.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed building kubedock: %w\n%s", err, out)
+	}
+
+	srv := exec.Command(bin, "server", "--port-forward", "--listen-addr", addr, "--kubeconfig", kubeconfig, "-v", "2")
+	srv.Stdout = os.Stdout
+	srv.Stderr = os.Stderr
+	if err := srv.Start(); err != nil {
+		return nil, err
+	}
+	stop := func() {
+		_ = srv.Process.Signal(os.Interrupt)
+		_ = srv.Wait()
+	}
+
+	for i := 0; i < 60; i++ {
+		res, err := http.Get("http://" + addr + "/_ping")
+		if err == nil {
+			res.Body.Close()
+			return stop, nil
+		}
+		time.Sleep(time.Second)
+	}
+	stop()
+	return nil, fmt.Errorf("kubedock did not become available on %s", addr)
+}
+
+// requireClient will skip the test if given client is not available, which
+// is checked by running it with given arguments (e.g. compose version). If
+// E2E_REQUIRE_CLIENTS is set, the test fails instead.
+func requireClient(t *testing.T, name string, args ...string) {
+	t.Helper()
+	err := exec.Command(name, args...).Run()
+	if _, lerr := exec.LookPath(name); lerr != nil {
+		err = lerr
+	}
+	if err == nil {
+		return
+	}
+	client := strings.Join(append([]string{name}, args...), " ")
+	if os.Getenv("E2E_REQUIRE_CLIENTS") != "" {
+		t.Fatalf("%s is required, but not available: %s", client, err)
+	}
+	t.Skipf("skipping, %s is not available: %s", client, err)
+}
+
+// runCmd will run given command with the docker host set to the kubedock
+// instance under test, and returns its combined output.
+func runCmd(t *testing.T, dir string, name string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"DOCKER_HOST="+dockerHost,
+		"CONTAINER_HOST="+dockerHost,
+		"TESTCONTAINERS_RYUK_DISABLED=true",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s %s failed: %s\n%s", name, strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// getEnv will return the value of given environment variable, or the given
+// default if it is not set.
+func getEnv(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
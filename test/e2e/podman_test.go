@@ -0,0 +1,17 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPodmanRemoteRun(t *testing.T) {
+	requireClient(t, "podman", "--version")
+
+	out := runCmd(t, "", "podman", "--remote", "--url", dockerHost, "run", "--rm", "busybox:latest", "echo", "hello podman")
+	if !strings.Contains(out, "hello podman") {
+		t.Errorf("expected output of container, but got:\n%s", out)
+	}
+}
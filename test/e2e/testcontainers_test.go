@@ -0,0 +1,33 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testcontainersVersion is the version of testcontainers-go that is tested.
+const testcontainersVersion = "v0.34.0"
+
+// TestTestcontainers will run the testcontainers-go test in testdata against
+// kubedock. The test is run as a separate module, so testcontainers-go is
+// not a dependency of kubedock itself.
+func TestTestcontainers(t *testing.T) {
+	requireClient(t, "go", "version")
+
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "testcontainers", "nginx_test.go"))
+	if err != nil {
+		t.Fatalf("failed reading testcontainers test: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nginx_test.go"), src, 0644); err != nil {
+		t.Fatalf("failed writing testcontainers test: %s", err)
+	}
+
+	runCmd(t, dir, "go", "mod", "init", "kubedock/e2e/testcontainers")
+	runCmd(t, dir, "go", "get", "github.com/testcontainers/testcontainers-go@"+testcontainersVersion)
+	runCmd(t, dir, "go", "mod", "tidy")
+	runCmd(t, dir, "go", "test", "-count=1", "-v", "./...")
+}
@@ -0,0 +1,39 @@
+package testcontainers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestNginx(t *testing.T) {
+	ctx := context.Background()
+	ctr, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "nginx:alpine",
+			ExposedPorts: []string{"80/tcp"},
+			WaitingFor:   wait.ForHTTP("/"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("failed starting container: %s", err)
+	}
+	defer ctr.Terminate(ctx)
+
+	endpoint, err := ctr.Endpoint(ctx, "http")
+	if err != nil {
+		t.Fatalf("failed getting endpoint: %s", err)
+	}
+	res, err := http.Get(endpoint)
+	if err != nil {
+		t.Fatalf("failed requesting %s: %s", endpoint, err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, but got %d", res.StatusCode)
+	}
+}